@@ -13,15 +13,18 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
+	oidcjwt "github.com/router-for-me/CLIProxyAPI/v6/internal/access/oidc_jwt"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/cmd"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/confighistory"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
@@ -64,6 +67,15 @@ func setKiroIncognitoMode(cfg *config.Config, useIncognito, noIncognito bool) {
 	}
 }
 
+// bundlePassphraseOrEnv falls back to CLIPROXY_BUNDLE_PASSPHRASE so the passphrase
+// need not appear in the process list.
+func bundlePassphraseOrEnv(passphrase string) string {
+	if passphrase != "" {
+		return passphrase
+	}
+	return os.Getenv("CLIPROXY_BUNDLE_PASSPHRASE")
+}
+
 // main is the entry point of the application.
 // It parses command-line flags, loads configuration, and starts the appropriate
 // service based on the provided flags (login, codex-login, or server mode).
@@ -107,6 +119,13 @@ func main() {
 	var noIncognito bool
 	var useIncognito bool
 	var localModel bool
+	var sqliteImport string
+	var sqliteExport string
+	var authImport string
+	var authExport string
+	var bundlePassphrase string
+	var authImportDryRun bool
+	var validateConfig bool
 
 	// Define command-line flags for different operation modes.
 	flag.BoolVar(&login, "login", false, "Login Google Account")
@@ -145,6 +164,13 @@ func main() {
 	flag.BoolVar(&tuiMode, "tui", false, "Start with terminal management UI")
 	flag.BoolVar(&standalone, "standalone", false, "In TUI mode, start an embedded local server")
 	flag.BoolVar(&localModel, "local-model", false, "Use embedded model catalog only, skip remote model fetching")
+	flag.StringVar(&sqliteImport, "sqlite-import", "", "Migrate an auth directory (and its config.yaml) into the SQLite store")
+	flag.StringVar(&sqliteExport, "sqlite-export", "", "Export auth files and config from the SQLite store into a directory")
+	flag.StringVar(&authImport, "auth-import", "", "Import auth files from a bundle, a Codex/Gemini/Claude Code/Kiro/Copilot credential file, or a directory of them")
+	flag.StringVar(&authExport, "auth-export", "", "Export auth files to a zip or .jsonl bundle")
+	flag.StringVar(&bundlePassphrase, "bundle-passphrase", "", "Passphrase encrypting -auth-export or decrypting -auth-import bundles (default: CLIPROXY_BUNDLE_PASSPHRASE)")
+	flag.BoolVar(&authImportDryRun, "auth-import-dry-run", false, "Report what -auth-import would change without writing")
+	flag.BoolVar(&validateConfig, "validate-config", false, "Validate the config file (-config) and exit with a non-zero status on errors")
 
 	flag.CommandLine.Usage = func() {
 		out := flag.CommandLine.Output()
@@ -176,6 +202,21 @@ func main() {
 	// Parse the command-line flags.
 	flag.Parse()
 
+	// Validation mode only inspects the local config file and never starts the server.
+	if validateConfig {
+		path := configPath
+		if path == "" {
+			// Same default as normal startup: config.yaml in the working directory.
+			wd, errWd := os.Getwd()
+			if errWd != nil {
+				log.Errorf("failed to get working directory: %v", errWd)
+				os.Exit(1)
+			}
+			path = filepath.Join(wd, "config.yaml")
+		}
+		os.Exit(cmd.DoValidateConfig(path))
+	}
+
 	// Core application variables.
 	var err error
 	var cfg *config.Config
@@ -186,6 +227,13 @@ func main() {
 		pgStoreSchema        string
 		pgStoreLocalPath     string
 		pgStoreInst          *store.PostgresStore
+		pgClusterEnabled     bool
+		pgClusterNodeID      string
+		pgClusterInst        *store.PostgresCluster
+		useSQLiteStore       bool
+		sqliteStorePath      string
+		sqliteStoreLocalPath string
+		sqliteStoreInst      *store.SQLiteStore
 		useGitStore          bool
 		gitStoreRemoteURL    string
 		gitStoreUser         string
@@ -238,6 +286,12 @@ func main() {
 		if value, ok := lookupEnv("PGSTORE_LOCAL_PATH", "pgstore_local_path"); ok {
 			pgStoreLocalPath = value
 		}
+		if value, ok := lookupEnv("PGSTORE_CLUSTER", "pgstore_cluster"); ok {
+			pgClusterEnabled, _ = strconv.ParseBool(value)
+		}
+		if value, ok := lookupEnv("PGSTORE_NODE_ID", "pgstore_node_id"); ok {
+			pgClusterNodeID = value
+		}
 		if pgStoreLocalPath == "" {
 			if writableBase != "" {
 				pgStoreLocalPath = writableBase
@@ -247,6 +301,13 @@ func main() {
 		}
 		useGitStore = false
 	}
+	if value, ok := lookupEnv("SQLITESTORE_PATH", "sqlitestore_path"); ok && !usePostgresStore {
+		useSQLiteStore = true
+		sqliteStorePath = value
+		if value, ok = lookupEnv("SQLITESTORE_LOCAL_PATH", "sqlitestore_local_path"); ok {
+			sqliteStoreLocalPath = value
+		}
+	}
 	if value, ok := lookupEnv("GITSTORE_GIT_URL", "gitstore_git_url"); ok {
 		useGitStore = true
 		gitStoreRemoteURL = value
@@ -314,12 +375,51 @@ func main() {
 			return
 		}
 		cancel()
+		if pgClusterEnabled {
+			pgClusterInst, err = store.NewPostgresCluster(pgStoreInst, store.PostgresClusterConfig{NodeID: pgClusterNodeID})
+			if err == nil {
+				ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+				err = pgClusterInst.Start(ctx)
+				cancel()
+			}
+			if err != nil {
+				log.Errorf("failed to start postgres cluster mode: %v", err)
+				return
+			}
+		}
 		configFilePath = pgStoreInst.ConfigPath()
 		cfg, err = config.LoadConfigOptional(configFilePath, isCloudDeploy)
 		if err == nil {
 			cfg.AuthDir = pgStoreInst.AuthDir()
 			log.Infof("postgres-backed token store enabled, workspace path: %s", pgStoreInst.WorkDir())
 		}
+	} else if useSQLiteStore {
+		if sqliteStoreLocalPath == "" {
+			if writableBase != "" {
+				sqliteStoreLocalPath = writableBase
+			} else {
+				sqliteStoreLocalPath = wd
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		sqliteStoreInst, err = store.NewSQLiteStore(ctx, store.SQLiteStoreConfig{
+			Path:     sqliteStorePath,
+			SpoolDir: filepath.Join(sqliteStoreLocalPath, "sqlitestore"),
+		})
+		if err == nil {
+			err = sqliteStoreInst.Bootstrap(ctx, filepath.Join(wd, "config.example.yaml"))
+		}
+		cancel()
+		if err != nil {
+			log.Errorf("failed to initialize sqlite token store: %v", err)
+			return
+		}
+		configFilePath = sqliteStoreInst.ConfigPath()
+		cfg, err = config.LoadConfigOptional(configFilePath, isCloudDeploy)
+		if err == nil {
+			cfg.AuthDir = sqliteStoreInst.AuthDir()
+			log.Infof("sqlite-backed token store enabled, database: %s", sqliteStorePath)
+		}
 	} else if useObjectStore {
 		if objectStoreLocalPath == "" {
 			if writableBase != "" {
@@ -499,8 +599,12 @@ func main() {
 	}
 
 	// Register the shared token store once so all components use the same persistence backend.
-	if usePostgresStore {
+	if pgClusterInst != nil {
+		sdkAuth.RegisterTokenStore(pgClusterInst)
+	} else if usePostgresStore {
 		sdkAuth.RegisterTokenStore(pgStoreInst)
+	} else if useSQLiteStore {
+		sdkAuth.RegisterTokenStore(sqliteStoreInst)
 	} else if useObjectStore {
 		sdkAuth.RegisterTokenStore(objectStoreInst)
 	} else if useGitStore {
@@ -508,15 +612,28 @@ func main() {
 	} else {
 		sdkAuth.RegisterTokenStore(sdkAuth.NewFileTokenStore())
 	}
+	// Keep the config history with the token store when it can hold it.
+	if backend, ok := sdkAuth.GetTokenStore().(confighistory.Backend); ok {
+		confighistory.SetBackend(backend)
+	}
 
 	// Register built-in access providers before constructing services.
 	configaccess.Register(&cfg.SDKConfig)
+	oidcjwt.Register(&cfg.SDKConfig)
 
 	// Handle different command modes based on the provided flags.
 
 	if vertexImport != "" {
 		// Handle Vertex service account import
 		cmd.DoVertexImport(cfg, vertexImport, vertexImportPrefix)
+	} else if authImport != "" {
+		cmd.DoAuthBundleImport(cfg, authImport, bundlePassphraseOrEnv(bundlePassphrase), authImportDryRun)
+	} else if authExport != "" {
+		cmd.DoAuthBundleExport(cfg, authExport, bundlePassphraseOrEnv(bundlePassphrase))
+	} else if sqliteImport != "" {
+		cmd.DoSQLiteImport(sqliteStoreInst, sqliteImport)
+	} else if sqliteExport != "" {
+		cmd.DoSQLiteExport(sqliteStoreInst, sqliteExport)
 	} else if login {
 		// Handle Google/Gemini login
 		cmd.DoLogin(cfg, projectID, options)
@@ -0,0 +1,288 @@
+package oidcjwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// minRefreshInterval throttles refreshes triggered by unknown key IDs so a
+// stream of forged tokens cannot hammer the identity provider.
+const minRefreshInterval = 30 * time.Second
+
+const maxDocumentSize = 1 << 20
+
+// jsonWebKey is the subset of RFC 7517 fields needed to build verification keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type cachedKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// keySet caches an issuer's JWKS and refreshes it on expiry or key rotation.
+// Fetches run outside mu and are shared by concurrent callers, so a slow issuer
+// never blocks lookups that the cached keys can answer.
+type keySet struct {
+	issuer  string
+	jwksURL string
+	ttl     time.Duration
+	client  *http.Client
+
+	fetches singleflight.Group
+
+	mu          sync.Mutex
+	resolvedURL string
+	keys        []cachedKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func newKeySet(issuer, jwksURL string, ttl time.Duration, client *http.Client) *keySet {
+	return &keySet{issuer: issuer, jwksURL: jwksURL, ttl: ttl, client: client}
+}
+
+// lookup returns candidate keys for the token header. An expired set is refreshed
+// in the background while its keys keep answering; callers only wait for a fetch
+// when nothing is cached yet or the key ID is unknown (signalling rotation).
+func (ks *keySet) lookup(ctx context.Context, header tokenHeader) ([]crypto.PublicKey, error) {
+	ks.mu.Lock()
+	now := time.Now()
+	cached := len(ks.keys) > 0
+	stale := ks.fetchedAt.IsZero() || now.Sub(ks.fetchedAt) > ks.ttl
+	throttled := now.Sub(ks.lastAttempt) < minRefreshInterval
+	matches := ks.matchLocked(header)
+	ks.mu.Unlock()
+
+	switch {
+	case !cached:
+		if err := ks.refresh(ctx); err != nil {
+			return nil, err
+		}
+	case len(matches) > 0:
+		if stale && !throttled {
+			go func() {
+				if err := ks.refresh(context.WithoutCancel(ctx)); err != nil {
+					log.Warnf("oidc-jwt: refreshing JWKS for %s failed, using cached keys: %v", ks.issuer, err)
+				}
+			}()
+		}
+		return matches, nil
+	case (stale || header.Kid != "") && !throttled:
+		if err := ks.refresh(ctx); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.matchLocked(header), nil
+}
+
+func (ks *keySet) matchLocked(header tokenHeader) []crypto.PublicKey {
+	var out []crypto.PublicKey
+	for _, k := range ks.keys {
+		if header.Kid != "" && k.kid != header.Kid {
+			continue
+		}
+		if k.alg != "" && k.alg != header.Alg {
+			continue
+		}
+		out = append(out, k.key)
+	}
+	return out
+}
+
+// refresh fetches the key set, joining a fetch already in flight. The fetch is
+// detached from the caller so one client disconnecting does not fail the others.
+func (ks *keySet) refresh(ctx context.Context) error {
+	ch := ks.fetches.DoChan("jwks", func() (any, error) {
+		return nil, ks.fetch(context.WithoutCancel(ctx))
+	})
+	select {
+	case res := <-ch:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (ks *keySet) fetch(ctx context.Context) error {
+	ks.mu.Lock()
+	ks.lastAttempt = time.Now()
+	ks.mu.Unlock()
+
+	jwksURL, err := ks.resolveURL(ctx)
+	if err != nil {
+		return err
+	}
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err = ks.getJSON(ctx, jwksURL, &doc); err != nil {
+		return fmt.Errorf("fetch JWKS: %w", err)
+	}
+	keys := make([]cachedKey, 0, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		pub, errKey := jwk.publicKey()
+		if errKey != nil {
+			log.Debugf("oidc-jwt: skipping JWK %q from %s: %v", jwk.Kid, jwksURL, errKey)
+			continue
+		}
+		keys = append(keys, cachedKey{kid: jwk.Kid, alg: jwk.Alg, key: pub})
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS contains no usable signing keys")
+	}
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+	ks.mu.Unlock()
+	return nil
+}
+
+// resolveURL returns the configured JWKS URL or discovers it from the issuer's
+// OpenID configuration document.
+func (ks *keySet) resolveURL(ctx context.Context) (string, error) {
+	if ks.jwksURL != "" {
+		return ks.jwksURL, nil
+	}
+	ks.mu.Lock()
+	resolved := ks.resolvedURL
+	ks.mu.Unlock()
+	if resolved != "" {
+		return resolved, nil
+	}
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := ks.getJSON(ctx, ks.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return "", fmt.Errorf("discover OIDC configuration: %w", err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != ks.issuer {
+		return "", fmt.Errorf("discovery issuer %q does not match %q", discovery.Issuer, ks.issuer)
+	}
+	if discovery.JWKSURI == "" {
+		return "", errors.New("discovery document has no jwks_uri")
+	}
+	ks.mu.Lock()
+	ks.resolvedURL = discovery.JWKSURI
+	ks.mu.Unlock()
+	return discovery.JWKSURI, nil
+}
+
+func (ks *keySet) getJSON(ctx context.Context, url string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("oidc-jwt: close response body: %v", errClose)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(dst)
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		if _, errPoint := pub.ECDH(); errPoint != nil {
+			return nil, fmt.Errorf("invalid EC point: %w", errPoint)
+		}
+		return pub, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(raw) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,158 @@
+// Package oidcjwt implements the built-in "oidc-jwt" access provider, which
+// authenticates clients presenting bearer JWTs issued by an OpenID Connect
+// identity provider.
+package oidcjwt
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const fetchTimeout = 10 * time.Second
+
+var (
+	registeredMu sync.Mutex
+	// registered keeps provider instances across config reloads so unchanged
+	// entries retain their JWKS cache and compare equal during reconciliation.
+	registered = make(map[string]*provider)
+)
+
+// Register installs one access provider per configured OIDC issuer and removes
+// providers whose configuration disappeared.
+func Register(cfg *sdkconfig.SDKConfig) {
+	var entries []config.OIDCJWTConfig
+	if cfg != nil {
+		entries = cfg.OIDCJWT
+	}
+
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+
+	keep := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		key := registryKey(entry.Name)
+		keep[key] = struct{}{}
+		if existing, ok := registered[key]; ok && reflect.DeepEqual(existing.cfg, entry) {
+			continue
+		}
+		p := newProvider(entry, &http.Client{Timeout: fetchTimeout})
+		registered[key] = p
+		sdkaccess.RegisterProvider(key, p)
+	}
+	for key := range registered {
+		if _, ok := keep[key]; ok {
+			continue
+		}
+		delete(registered, key)
+		sdkaccess.UnregisterProvider(key)
+	}
+}
+
+func registryKey(name string) string {
+	return sdkaccess.AccessProviderTypeOIDCJWT + ":" + name
+}
+
+type provider struct {
+	cfg  config.OIDCJWTConfig
+	keys *keySet
+	now  func() time.Time
+}
+
+func newProvider(cfg config.OIDCJWTConfig, client *http.Client) *provider {
+	ttl := time.Duration(cfg.JWKSCacheSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = time.Duration(config.DefaultOIDCJWTJWKSCacheSeconds) * time.Second
+	}
+	return &provider{
+		cfg:  cfg,
+		keys: newKeySet(strings.TrimRight(cfg.Issuer, "/"), cfg.JWKSURL, ttl, client),
+		now:  time.Now,
+	}
+}
+
+func (p *provider) Identifier() string {
+	return p.cfg.Name
+}
+
+func (p *provider) Authenticate(ctx context.Context, r *http.Request) (*sdkaccess.Result, *sdkaccess.AuthError) {
+	if p == nil || r == nil {
+		return nil, sdkaccess.NewNotHandledError()
+	}
+	authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
+	if authHeader == "" {
+		return nil, sdkaccess.NewNoCredentialsError()
+	}
+	scheme, token, found := strings.Cut(authHeader, " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "bearer") || !looksLikeJWT(token) {
+		// Static API keys and other schemes belong to other providers.
+		return nil, sdkaccess.NewNotHandledError()
+	}
+
+	tok, err := parseToken(token)
+	if err != nil {
+		return nil, sdkaccess.NewInvalidCredentialError()
+	}
+	if tok.header.Alg == "" || strings.EqualFold(tok.header.Alg, "none") || strings.HasPrefix(tok.header.Alg, "HS") {
+		log.Debugf("oidc-jwt %s: rejected token with algorithm %q", p.cfg.Name, tok.header.Alg)
+		return nil, sdkaccess.NewInvalidCredentialError()
+	}
+	// Only consult this issuer's keys for tokens it minted; other issuers may be
+	// handled by sibling providers.
+	if iss, _ := tok.claims["iss"].(string); strings.TrimRight(iss, "/") != p.keys.issuer {
+		return nil, sdkaccess.NewNotHandledError()
+	}
+
+	candidates, err := p.keys.lookup(ctx, tok.header)
+	if err != nil {
+		return nil, sdkaccess.NewInternalAuthError("oidc-jwt: unable to load signing keys", err)
+	}
+	verified := false
+	for _, key := range candidates {
+		if verifySignature(tok, key) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		log.Debugf("oidc-jwt %s: signature verification failed (kid=%q alg=%s)", p.cfg.Name, tok.header.Kid, tok.header.Alg)
+		return nil, sdkaccess.NewInvalidCredentialError()
+	}
+
+	skew := time.Duration(p.cfg.ClockSkewSeconds) * time.Second
+	if err = validateClaims(tok.claims, p.keys.issuer, p.cfg.Audiences, skew, p.now()); err != nil {
+		log.Debugf("oidc-jwt %s: %v", p.cfg.Name, err)
+		return nil, sdkaccess.NewInvalidCredentialError()
+	}
+
+	principal := claimString(tok.claims, p.cfg.PrincipalClaim)
+	if principal == "" {
+		log.Debugf("oidc-jwt %s: token has no %q claim", p.cfg.Name, p.cfg.PrincipalClaim)
+		return nil, sdkaccess.NewInvalidCredentialError()
+	}
+	metadata := map[string]string{
+		"source":  sdkaccess.AccessProviderTypeOIDCJWT,
+		"issuer":  p.keys.issuer,
+		"subject": claimString(tok.claims, "sub"),
+	}
+	if email := claimString(tok.claims, p.cfg.EmailClaim); email != "" {
+		metadata["email"] = email
+	}
+	if groups := claimStrings(tok.claims, p.cfg.GroupsClaim); len(groups) > 0 {
+		metadata["groups"] = strings.Join(groups, ",")
+	}
+	return &sdkaccess.Result{
+		Provider:  p.Identifier(),
+		Principal: principal,
+		Metadata:  metadata,
+	}, nil
+}
@@ -0,0 +1,327 @@
+package oidcjwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+)
+
+type testIssuer struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	jwks     []map[string]string
+	fetches  atomic.Int32
+	stall    chan struct{}
+	rsaKey   *rsa.PrivateKey
+	rsaKeyID string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	iss := &testIssuer{rsaKey: key, rsaKeyID: "k1"}
+	iss.jwks = []map[string]string{rsaJWK("k1", &key.PublicKey)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   iss.server.URL,
+			"jwks_uri": iss.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, _ *http.Request) {
+		iss.fetches.Add(1)
+		iss.mu.Lock()
+		stall := iss.stall
+		iss.mu.Unlock()
+		if stall != nil {
+			<-stall
+		}
+		iss.mu.Lock()
+		defer iss.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": iss.jwks})
+	})
+	iss.server = httptest.NewServer(mux)
+	t.Cleanup(iss.server.Close)
+	return iss
+}
+
+func rsaJWK(kid string, pub *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": "ES256", "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (iss *testIssuer) claims(overrides map[string]any) map[string]any {
+	claims := map[string]any{
+		"iss":    iss.server.URL,
+		"aud":    []string{"cliproxy"},
+		"sub":    "user-123",
+		"email":  "dev@example.com",
+		"groups": []string{"eng", "ci"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"iat":    time.Now().Unix(),
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	return claims
+}
+
+func (iss *testIssuer) provider(jwksURL string) *provider {
+	cfg := &config.Config{}
+	cfg.OIDCJWT = []config.OIDCJWTConfig{{Name: "corp", Issuer: iss.server.URL, JWKSURL: jwksURL, Audiences: []string{"cliproxy"}}}
+	cfg.SanitizeOIDCJWT()
+	return newProvider(cfg.OIDCJWT[0], iss.server.Client())
+}
+
+func bearerRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestAuthenticateValidToken(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider("")
+
+	result, authErr := p.Authenticate(context.Background(), bearerRequest(signRS256(t, iss.rsaKey, "k1", iss.claims(nil))))
+	if authErr != nil {
+		t.Fatalf("unexpected auth error: %v", authErr)
+	}
+	if result.Provider != "corp" || result.Principal != "user-123" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Metadata["email"] != "dev@example.com" || result.Metadata["groups"] != "eng,ci" || result.Metadata["subject"] != "user-123" {
+		t.Fatalf("unexpected metadata: %+v", result.Metadata)
+	}
+
+	// A second request must be served from the cached key set.
+	if _, authErr = p.Authenticate(context.Background(), bearerRequest(signRS256(t, iss.rsaKey, "k1", iss.claims(nil)))); authErr != nil {
+		t.Fatalf("unexpected auth error: %v", authErr)
+	}
+	if got := iss.fetches.Load(); got != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", got)
+	}
+}
+
+func TestAuthenticateRejectsInvalidTokens(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider(iss.server.URL + "/keys")
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		token string
+		code  sdkaccess.AuthErrorCode
+	}{
+		{"wrong audience", signRS256(t, iss.rsaKey, "k1", iss.claims(map[string]any{"aud": "other"})), sdkaccess.AuthErrorCodeInvalidCredential},
+		{"expired", signRS256(t, iss.rsaKey, "k1", iss.claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})), sdkaccess.AuthErrorCodeInvalidCredential},
+		{"missing exp", signRS256(t, iss.rsaKey, "k1", iss.claims(map[string]any{"exp": nil})), sdkaccess.AuthErrorCodeInvalidCredential},
+		{"not yet valid", signRS256(t, iss.rsaKey, "k1", iss.claims(map[string]any{"nbf": time.Now().Add(time.Hour).Unix()})), sdkaccess.AuthErrorCodeInvalidCredential},
+		{"bad signature", signRS256(t, otherKey, "k1", iss.claims(nil)), sdkaccess.AuthErrorCodeInvalidCredential},
+		{"foreign issuer", signRS256(t, iss.rsaKey, "k1", iss.claims(map[string]any{"iss": "https://elsewhere.example"})), sdkaccess.AuthErrorCodeNotHandled},
+		{"static api key", "sk-static-key", sdkaccess.AuthErrorCodeNotHandled},
+		{"no credentials", "", sdkaccess.AuthErrorCodeNoCredentials},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, authErr := p.Authenticate(context.Background(), bearerRequest(tc.token))
+			if authErr == nil {
+				t.Fatalf("expected %s, got result %+v", tc.code, result)
+			}
+			if authErr.Code != tc.code {
+				t.Fatalf("error code = %s, want %s", authErr.Code, tc.code)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRejectsUnsignedAlgorithms(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider("")
+	token := encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, iss.claims(nil)) + "."
+	if _, authErr := p.Authenticate(context.Background(), bearerRequest(token)); authErr == nil || authErr.Code != sdkaccess.AuthErrorCodeInvalidCredential {
+		t.Fatalf("expected invalid credential for alg=none, got %v", authErr)
+	}
+}
+
+func TestAuthenticateKeyRotation(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider("")
+	if _, authErr := p.Authenticate(context.Background(), bearerRequest(signRS256(t, iss.rsaKey, "k1", iss.claims(nil)))); authErr != nil {
+		t.Fatalf("unexpected auth error: %v", authErr)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate EC key: %v", err)
+	}
+	iss.mu.Lock()
+	iss.jwks = append(iss.jwks, map[string]string{
+		"kty": "EC",
+		"kid": "k2",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+	})
+	iss.mu.Unlock()
+
+	token := signES256(t, ecKey, "k2", iss.claims(nil))
+	// Unknown key IDs only trigger a refresh once the throttle window has passed.
+	if _, authErr := p.Authenticate(context.Background(), bearerRequest(token)); authErr == nil {
+		t.Fatal("expected rejection while refresh is throttled")
+	}
+	p.keys.mu.Lock()
+	p.keys.lastAttempt = time.Now().Add(-minRefreshInterval)
+	p.keys.mu.Unlock()
+
+	result, authErr := p.Authenticate(context.Background(), bearerRequest(token))
+	if authErr != nil {
+		t.Fatalf("unexpected auth error after rotation: %v", authErr)
+	}
+	if result.Principal != "user-123" {
+		t.Fatalf("unexpected principal %q", result.Principal)
+	}
+	if got := iss.fetches.Load(); got != 2 {
+		t.Fatalf("JWKS fetched %d times, want 2", got)
+	}
+}
+
+func TestAuthenticateNotBlockedBySlowRefresh(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider("")
+	known := bearerRequest(signRS256(t, iss.rsaKey, "k1", iss.claims(nil)))
+	if _, authErr := p.Authenticate(context.Background(), known); authErr != nil {
+		t.Fatalf("unexpected auth error: %v", authErr)
+	}
+
+	stall := make(chan struct{})
+	iss.mu.Lock()
+	iss.stall = stall
+	iss.mu.Unlock()
+	p.keys.mu.Lock()
+	p.keys.lastAttempt = time.Now().Add(-minRefreshInterval)
+	p.keys.mu.Unlock()
+
+	// An unknown key ID starts a refresh against the stalled issuer.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = p.Authenticate(context.Background(), bearerRequest(signRS256(t, iss.rsaKey, "k9", iss.claims(nil))))
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for iss.fetches.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("refresh did not start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	start := time.Now()
+	if _, authErr := p.Authenticate(context.Background(), bearerRequest(signRS256(t, iss.rsaKey, "k1", iss.claims(nil)))); authErr != nil {
+		t.Fatalf("cached key rejected during refresh: %v", authErr)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("cached lookup waited %v for the refresh", elapsed)
+	}
+	close(stall)
+	<-done
+}
+
+func TestRegisterTracksConfiguration(t *testing.T) {
+	iss := newTestIssuer(t)
+	cfg := &config.Config{}
+	cfg.OIDCJWT = []config.OIDCJWTConfig{{Name: "corp", Issuer: iss.server.URL, Audiences: []string{"cliproxy"}}}
+	cfg.SanitizeOIDCJWT()
+
+	Register(&cfg.SDKConfig)
+	first := findProvider("corp")
+	if first == nil {
+		t.Fatal("provider not registered")
+	}
+	Register(&cfg.SDKConfig)
+	if findProvider("corp") != first {
+		t.Fatal("unchanged configuration should reuse the provider instance")
+	}
+
+	Register(&config.SDKConfig{})
+	if findProvider("corp") != nil {
+		t.Fatal("provider not unregistered after removal")
+	}
+}
+
+func findProvider(name string) sdkaccess.Provider {
+	for _, p := range sdkaccess.RegisteredProviders() {
+		if p.Identifier() == name {
+			return p
+		}
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/fxamacker/cbor/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/copilot"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/geminicli"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
@@ -53,10 +54,10 @@ type apiCallRequest struct {
 }
 
 type apiCallResponse struct {
-	StatusCode int                 `json:"status_code"`
-	Header     map[string][]string `json:"header"`
-	Body       string              `json:"body"`
-	Quota      *QuotaSnapshots     `json:"quota,omitempty"`
+	StatusCode int                     `json:"status_code"`
+	Header     map[string][]string     `json:"header"`
+	Body       string                  `json:"body"`
+	Quota      *copilot.QuotaSnapshots `json:"quota,omitempty"`
 }
 
 // APICall makes a generic HTTP request on behalf of the management API caller.
@@ -931,39 +932,6 @@ func cborValueToJSONCompatible(value any) any {
 	}
 }
 
-// QuotaDetail represents quota information for a specific resource type
-type QuotaDetail struct {
-	Entitlement      float64 `json:"entitlement"`
-	OverageCount     float64 `json:"overage_count"`
-	OveragePermitted bool    `json:"overage_permitted"`
-	PercentRemaining float64 `json:"percent_remaining"`
-	QuotaID          string  `json:"quota_id"`
-	QuotaRemaining   float64 `json:"quota_remaining"`
-	Remaining        float64 `json:"remaining"`
-	Unlimited        bool    `json:"unlimited"`
-}
-
-// QuotaSnapshots contains quota details for different resource types
-type QuotaSnapshots struct {
-	Chat                QuotaDetail `json:"chat"`
-	Completions         QuotaDetail `json:"completions"`
-	PremiumInteractions QuotaDetail `json:"premium_interactions"`
-}
-
-// CopilotUsageResponse represents the GitHub Copilot usage information
-type CopilotUsageResponse struct {
-	AccessTypeSKU         string         `json:"access_type_sku"`
-	AnalyticsTrackingID   string         `json:"analytics_tracking_id"`
-	AssignedDate          string         `json:"assigned_date"`
-	CanSignupForLimited   bool           `json:"can_signup_for_limited"`
-	ChatEnabled           bool           `json:"chat_enabled"`
-	CopilotPlan           string         `json:"copilot_plan"`
-	OrganizationLoginList []interface{}  `json:"organization_login_list"`
-	OrganizationList      []interface{}  `json:"organization_list"`
-	QuotaResetDate        string         `json:"quota_reset_date"`
-	QuotaSnapshots        QuotaSnapshots `json:"quota_snapshots"`
-}
-
 type copilotQuotaRequest struct {
 	AuthIndexSnake  *string `json:"auth_index"`
 	AuthIndexCamel  *string `json:"authIndex"`
@@ -1014,8 +982,7 @@ func (h *Handler) GetCopilotQuota(c *gin.Context) {
 		return
 	}
 
-	apiURL := "https://api.github.com/copilot_internal/user"
-	req, errNewRequest := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, apiURL, nil)
+	req, errNewRequest := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, copilot.UserInfoURL, nil)
 	if errNewRequest != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build request"})
 		return
@@ -1057,8 +1024,8 @@ func (h *Handler) GetCopilotQuota(c *gin.Context) {
 		return
 	}
 
-	var usage CopilotUsageResponse
-	if errUnmarshal := json.Unmarshal(respBody, &usage); errUnmarshal != nil {
+	usage, errParse := copilot.ParseUsage(respBody)
+	if errParse != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse response"})
 		return
 	}
@@ -1169,95 +1136,16 @@ func (h *Handler) enrichCopilotTokenResponse(ctx context.Context, response apiCa
 		return response
 	}
 
-	// Parse the quota response
-	var quotaData CopilotUsageResponse
-	if err := json.Unmarshal(quotaBody, &quotaData); err != nil {
-		log.WithError(err).Debug("enrichCopilotTokenResponse: failed to parse response")
+	quotaData, errParse := copilot.ParseUsage(quotaBody)
+	if errParse != nil {
+		log.WithError(errParse).Debug("enrichCopilotTokenResponse: failed to parse response")
 		return response
 	}
-
-	// Check if this is an enterprise account by looking for quota_snapshots in the response
-	// Enterprise accounts have quota_snapshots, non-enterprise have limited_user_quotas
-	var quotaRaw map[string]interface{}
-	if err := json.Unmarshal(quotaBody, &quotaRaw); err == nil {
-		if _, hasQuotaSnapshots := quotaRaw["quota_snapshots"]; hasQuotaSnapshots {
-			// Enterprise account - has quota_snapshots
-			tokenResp["quota_snapshots"] = quotaData.QuotaSnapshots
-			tokenResp["access_type_sku"] = quotaData.AccessTypeSKU
-			tokenResp["copilot_plan"] = quotaData.CopilotPlan
-
-			// Add quota reset date for enterprise (quota_reset_date_utc)
-			if quotaResetDateUTC, ok := quotaRaw["quota_reset_date_utc"]; ok {
-				tokenResp["quota_reset_date"] = quotaResetDateUTC
-			} else if quotaData.QuotaResetDate != "" {
-				tokenResp["quota_reset_date"] = quotaData.QuotaResetDate
-			}
-		} else {
-			// Non-enterprise account - build quota from limited_user_quotas and monthly_quotas
-			var quotaSnapshots QuotaSnapshots
-
-			// Get monthly quotas (total entitlement) and limited_user_quotas (remaining)
-			monthlyQuotas, hasMonthly := quotaRaw["monthly_quotas"].(map[string]interface{})
-			limitedQuotas, hasLimited := quotaRaw["limited_user_quotas"].(map[string]interface{})
-
-			// Process chat quota
-			if hasMonthly && hasLimited {
-				if chatTotal, ok := monthlyQuotas["chat"].(float64); ok {
-					chatRemaining := chatTotal // default to full if no limited quota
-					if chatLimited, ok := limitedQuotas["chat"].(float64); ok {
-						chatRemaining = chatLimited
-					}
-					percentRemaining := 0.0
-					if chatTotal > 0 {
-						percentRemaining = (chatRemaining / chatTotal) * 100.0
-					}
-					quotaSnapshots.Chat = QuotaDetail{
-						Entitlement:      chatTotal,
-						Remaining:        chatRemaining,
-						QuotaRemaining:   chatRemaining,
-						PercentRemaining: percentRemaining,
-						QuotaID:          "chat",
-						Unlimited:        false,
-					}
-				}
-
-				// Process completions quota
-				if completionsTotal, ok := monthlyQuotas["completions"].(float64); ok {
-					completionsRemaining := completionsTotal // default to full if no limited quota
-					if completionsLimited, ok := limitedQuotas["completions"].(float64); ok {
-						completionsRemaining = completionsLimited
-					}
-					percentRemaining := 0.0
-					if completionsTotal > 0 {
-						percentRemaining = (completionsRemaining / completionsTotal) * 100.0
-					}
-					quotaSnapshots.Completions = QuotaDetail{
-						Entitlement:      completionsTotal,
-						Remaining:        completionsRemaining,
-						QuotaRemaining:   completionsRemaining,
-						PercentRemaining: percentRemaining,
-						QuotaID:          "completions",
-						Unlimited:        false,
-					}
-				}
-			}
-
-			// Premium interactions don't exist for non-enterprise, leave as zero values
-			quotaSnapshots.PremiumInteractions = QuotaDetail{
-				QuotaID:   "premium_interactions",
-				Unlimited: false,
-			}
-
-			// Add quota_snapshots to the token response
-			tokenResp["quota_snapshots"] = quotaSnapshots
-			tokenResp["access_type_sku"] = quotaData.AccessTypeSKU
-			tokenResp["copilot_plan"] = quotaData.CopilotPlan
-
-			// Add quota reset date for non-enterprise (limited_user_reset_date)
-			if limitedResetDate, ok := quotaRaw["limited_user_reset_date"]; ok {
-				tokenResp["quota_reset_date"] = limitedResetDate
-			}
-		}
+	tokenResp["quota_snapshots"] = quotaData.QuotaSnapshots
+	tokenResp["access_type_sku"] = quotaData.AccessTypeSKU
+	tokenResp["copilot_plan"] = quotaData.CopilotPlan
+	if quotaData.QuotaResetDate != "" {
+		tokenResp["quota_reset_date"] = quotaData.QuotaResetDate
 	}
 
 	// Re-serialize the enriched response
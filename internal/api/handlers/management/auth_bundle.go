@@ -0,0 +1,167 @@
+package management
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
+)
+
+// maxAuthBundleSize caps an uploaded import bundle.
+const maxAuthBundleSize = 64 << 20
+
+// ExportAuthFiles returns the auth files as a zip or JSONL bundle, encrypted when
+// a passphrase is given.
+//
+// Endpoint:
+//
+//	POST /v0/management/auth-files/export
+//
+// Body: {"format": "zip|jsonl", "passphrase": "", "names": []}
+func (h *Handler) ExportAuthFiles(c *gin.Context) {
+	var req struct {
+		Format     string   `json:"format"`
+		Passphrase string   `json:"passphrase"`
+		Names      []string `json:"names"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	format := strings.ToLower(strings.TrimSpace(req.Format))
+	if format == "" {
+		format = sdkAuth.BundleFormatZip
+	}
+	if format != sdkAuth.BundleFormatZip && format != sdkAuth.BundleFormatJSONL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be zip or jsonl"})
+		return
+	}
+	entries, err := sdkAuth.ReadAuthDirEntries(h.cfg.AuthDir, req.Names)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	bundle, err := sdkAuth.EncodeBundle(entries, format, req.Passphrase)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	name := fmt.Sprintf("auth-bundle-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	contentType := "application/zip"
+	if format == sdkAuth.BundleFormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	if req.Passphrase != "" {
+		name += ".enc"
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
+	c.Header("X-Auth-Files-Count", strconv.Itoa(len(entries)))
+	c.Data(http.StatusOK, contentType, bundle)
+}
+
+// ImportAuthFiles imports credentials from a bundle produced by ExportAuthFiles or
+// from credential files of other tools, such as the Codex CLI auth.json or the
+// GitHub Copilot hosts.json. Accounts already present under another file name are
+// skipped. With ?dry_run=true nothing is written and the report shows the plan.
+//
+// Endpoint:
+//
+//	POST /v0/management/auth-files/import
+//
+// Body: multipart files with an optional "passphrase" field, or the raw bundle
+// with the passphrase in the X-Bundle-Passphrase header and ?name= for single files.
+func (h *Handler) ImportAuthFiles(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAuthBundleSize)
+
+	entries, err := h.readImportEntries(c)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, sdkAuth.ErrBundlePassphraseRequired) || errors.Is(err, sdkAuth.ErrBundleDecrypt) {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if len(entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no credentials found"})
+		return
+	}
+
+	report := sdkAuth.PlanImport(entries, h.authManager.List())
+	report.DryRun = dryRun
+	ctx := c.Request.Context()
+	report.Apply(func(entry sdkAuth.BundleEntry) error {
+		return h.writeAuthFile(ctx, entry.Name, entry.Data)
+	})
+	status := http.StatusOK
+	if report.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, report)
+}
+
+func (h *Handler) readImportEntries(c *gin.Context) ([]sdkAuth.BundleEntry, error) {
+	fileHeaders, err := h.multipartAuthFileHeaders(c)
+	if err != nil {
+		return nil, fmt.Errorf("invalid multipart form: %w", err)
+	}
+	if len(fileHeaders) == 0 {
+		if c.ContentType() == "multipart/form-data" {
+			return nil, fmt.Errorf("no files uploaded")
+		}
+		data, errRead := io.ReadAll(c.Request.Body)
+		if errRead != nil {
+			return nil, fmt.Errorf("failed to read body: %w", errRead)
+		}
+		return sdkAuth.DecodeBundle(strings.TrimSpace(c.Query("name")), data, c.GetHeader("X-Bundle-Passphrase"))
+	}
+
+	passphrase := c.PostForm("passphrase")
+	var entries []sdkAuth.BundleEntry
+	// Plain credential files are converted together so a Kiro IDE token can find
+	// its device registration among the other uploads.
+	files := make(map[string][]byte)
+	order := make([]string, 0, len(fileHeaders))
+	for _, header := range fileHeaders {
+		file, errOpen := header.Open()
+		if errOpen != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", header.Filename, errOpen)
+		}
+		data, errRead := io.ReadAll(file)
+		_ = file.Close()
+		if errRead != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", header.Filename, errRead)
+		}
+		name := filepath.Base(strings.TrimSpace(header.Filename))
+		if strings.HasSuffix(strings.ToLower(name), ".json") {
+			files[name] = data
+			order = append(order, name)
+			continue
+		}
+		decoded, errDecode := sdkAuth.DecodeBundle(name, data, passphrase)
+		if errDecode != nil {
+			return nil, fmt.Errorf("%s: %w", name, errDecode)
+		}
+		entries = append(entries, decoded...)
+	}
+	converted, err := sdkAuth.ConvertCredentialFiles(order, files)
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, converted...), nil
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kimi"
 	kiroauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/qwen"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
@@ -257,8 +258,12 @@ func (h *Handler) ListAuthFiles(c *gin.Context) {
 		return
 	}
 	auths := h.authManager.List()
+	visible := h.tenantAuthFilter(c.Query("tenant"))
 	files := make([]gin.H, 0, len(auths))
 	for _, auth := range auths {
+		if visible != nil && !visible(auth) {
+			continue
+		}
 		if entry := h.buildAuthFileEntry(auth); entry != nil {
 			files = append(files, entry)
 		}
@@ -271,6 +276,22 @@ func (h *Handler) ListAuthFiles(c *gin.Context) {
 	c.JSON(200, gin.H{"files": files})
 }
 
+// tenantAuthFilter returns a predicate matching the credentials the named tenant
+// may use, or nil when no tenant filter was requested.
+func (h *Handler) tenantAuthFilter(tenant string) func(*coreauth.Auth) bool {
+	tenant = strings.TrimSpace(tenant)
+	if tenant == "" {
+		return nil
+	}
+	scope := coreauth.TenantScope{Name: tenant}
+	if h.cfg != nil {
+		if cfgTenant := h.cfg.TenantByName(tenant); cfgTenant != nil {
+			scope.AllowShared = cfgTenant.AllowShared
+		}
+	}
+	return func(auth *coreauth.Auth) bool { return auth.VisibleToTenant(scope) }
+}
+
 // GetAuthFileModels returns the models supported by a specific auth file
 func (h *Handler) GetAuthFileModels(c *gin.Context) {
 	name := c.Query("name")
@@ -424,6 +445,18 @@ func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
 	if !auth.NextRetryAfter.IsZero() {
 		entry["next_retry_after"] = auth.NextRetryAfter
 	}
+	now := time.Now()
+	if schedule, ok := auth.Schedule(); ok {
+		entry["schedule"] = schedule
+	}
+	if limits, ok := auth.UsageLimits(); ok {
+		entry["limits"] = limits
+		entry["usage"] = auth.UsageCounters(now)
+	}
+	if reason, until := auth.UsageBlock(now); reason != "" {
+		entry["limit_reason"] = reason
+		entry["limit_until"] = until
+	}
 	if path != "" {
 		entry["path"] = path
 		entry["source"] = "file"
@@ -474,6 +507,9 @@ func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
 			}
 		}
 	}
+	if tenants := auth.Tenants(); len(tenants) > 0 {
+		entry["tenants"] = tenants
+	}
 	return entry
 }
 
@@ -1130,7 +1166,7 @@ func (h *Handler) PatchAuthFileStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "disabled": *req.Disabled})
 }
 
-// PatchAuthFileFields updates editable fields (prefix, proxy_url, headers, priority, note) of an auth file.
+// PatchAuthFileFields updates editable fields (prefix, proxy_url, headers, priority, note, tenants) of an auth file.
 func (h *Handler) PatchAuthFileFields(c *gin.Context) {
 	if h.authManager == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
@@ -1138,12 +1174,15 @@ func (h *Handler) PatchAuthFileFields(c *gin.Context) {
 	}
 
 	var req struct {
-		Name     string            `json:"name"`
-		Prefix   *string           `json:"prefix"`
-		ProxyURL *string           `json:"proxy_url"`
-		Headers  map[string]string `json:"headers"`
-		Priority *int              `json:"priority"`
-		Note     *string           `json:"note"`
+		Name     string                `json:"name"`
+		Prefix   *string               `json:"prefix"`
+		ProxyURL *string               `json:"proxy_url"`
+		Headers  map[string]string     `json:"headers"`
+		Priority *int                  `json:"priority"`
+		Note     *string               `json:"note"`
+		Tenants  *[]string             `json:"tenants"`
+		Schedule *coreauth.Schedule    `json:"schedule"`
+		Limits   *coreauth.UsageLimits `json:"limits"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
@@ -1309,6 +1348,40 @@ func (h *Handler) PatchAuthFileFields(c *gin.Context) {
 		}
 		changed = true
 	}
+	if req.Tenants != nil {
+		if targetAuth.Metadata == nil {
+			targetAuth.Metadata = make(map[string]any)
+		}
+		if targetAuth.Attributes == nil {
+			targetAuth.Attributes = make(map[string]string)
+		}
+		tenants := config.NormalizeTenantNames(*req.Tenants)
+		if len(tenants) == 0 {
+			delete(targetAuth.Metadata, coreauth.TenantsAttributeKey)
+			delete(targetAuth.Attributes, coreauth.TenantsAttributeKey)
+		} else {
+			targetAuth.Metadata[coreauth.TenantsAttributeKey] = tenants
+			targetAuth.Attributes[coreauth.TenantsAttributeKey] = strings.Join(tenants, ",")
+		}
+		changed = true
+	}
+
+	if req.Schedule != nil {
+		if err := req.Schedule.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		setMetadataObject(targetAuth, coreauth.ScheduleMetadataKey, *req.Schedule, len(req.Schedule.Windows) == 0)
+		changed = true
+	}
+	if req.Limits != nil {
+		if err := req.Limits.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		setMetadataObject(targetAuth, coreauth.LimitsMetadataKey, *req.Limits, *req.Limits == coreauth.UsageLimits{})
+		changed = true
+	}
 
 	if !changed {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
@@ -1325,6 +1398,22 @@ func (h *Handler) PatchAuthFileFields(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// setMetadataObject stores value in the auth metadata as a plain JSON object, or
+// removes the key when clear is set.
+func setMetadataObject(auth *coreauth.Auth, key string, value any, clear bool) {
+	if auth.Metadata == nil {
+		auth.Metadata = make(map[string]any)
+	}
+	if clear {
+		delete(auth.Metadata, key)
+		return
+	}
+	var object map[string]any
+	if raw, err := json.Marshal(value); err == nil && json.Unmarshal(raw, &object) == nil {
+		auth.Metadata[key] = object
+	}
+}
+
 func (h *Handler) disableAuth(ctx context.Context, id string) {
 	if h == nil || h.authManager == nil {
 		return
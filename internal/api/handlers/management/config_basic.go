@@ -12,7 +12,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/confighistory"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -114,29 +116,39 @@ func (h *Handler) PutConfigYAML(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_yaml", "message": "cannot read request body"})
 		return
 	}
+	if !h.applyConfigYAML(c, body, confighistory.SourceManagement, "") {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "changed": []string{"config"}})
+}
+
+// applyConfigYAML validates body, writes it as the active config, reloads it into the
+// handler and records the result in the config history. It writes the error response
+// and returns false when any step fails.
+func (h *Handler) applyConfigYAML(c *gin.Context, body []byte, source, note string) bool {
 	var cfg config.Config
-	if err = yaml.Unmarshal(body, &cfg); err != nil {
+	if err := yaml.Unmarshal(body, &cfg); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_yaml", "message": err.Error()})
-		return
+		return false
 	}
 	// Validate config using LoadConfigOptional with optional=false to enforce parsing
 	tmpDir := filepath.Dir(h.configFilePath)
 	tmpFile, err := os.CreateTemp(tmpDir, "config-validate-*.yaml")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "write_failed", "message": err.Error()})
-		return
+		return false
 	}
 	tempFile := tmpFile.Name()
 	if _, errWrite := tmpFile.Write(body); errWrite != nil {
 		_ = tmpFile.Close()
 		_ = os.Remove(tempFile)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "write_failed", "message": errWrite.Error()})
-		return
+		return false
 	}
 	if errClose := tmpFile.Close(); errClose != nil {
 		_ = os.Remove(tempFile)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "write_failed", "message": errClose.Error()})
-		return
+		return false
 	}
 	defer func() {
 		_ = os.Remove(tempFile)
@@ -144,22 +156,23 @@ func (h *Handler) PutConfigYAML(c *gin.Context) {
 	_, err = config.LoadConfigOptional(tempFile, false)
 	if err != nil {
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_config", "message": err.Error()})
-		return
+		return false
 	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	if WriteConfig(h.configFilePath, body) != nil {
+	errWrite := h.writeConfigVersion(c, source, note, func() error { return WriteConfig(h.configFilePath, body) })
+	if errWrite != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "write_failed", "message": "failed to write config"})
-		return
+		return false
 	}
 	// Reload into handler to keep memory in sync
 	newCfg, err := config.LoadConfig(h.configFilePath)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "reload_failed", "message": err.Error()})
-		return
+		return false
 	}
 	h.cfg = newCfg
-	c.JSON(http.StatusOK, gin.H{"ok": true, "changed": []string{"config"}})
+	return true
 }
 
 // GetConfigYAML returns the raw config.yaml file bytes without re-encoding.
@@ -326,3 +339,57 @@ func (h *Handler) DeleteProxyURL(c *gin.Context) {
 	h.cfg.ProxyURL = ""
 	h.persist(c)
 }
+
+// ValidateConfigYAML dry-runs a candidate config.yaml without applying it. The body is
+// the candidate document; an empty body validates the config currently on disk. The
+// response lists errors and warnings with positions, the changes relative to the
+// running config and the models exposed by config-declared credentials.
+func (h *Handler) ValidateConfigYAML(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_yaml", "message": "cannot read request body"})
+		return
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		if body, err = os.ReadFile(h.configFilePath); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "message": err.Error()})
+			return
+		}
+	}
+
+	candidate, issues := config.ValidateConfig(body)
+	errs := make([]config.ValidationIssue, 0, len(issues))
+	warnings := make([]config.ValidationIssue, 0, len(issues))
+	for _, issue := range issues {
+		if issue.Severity == config.SeverityError {
+			errs = append(errs, issue)
+		} else {
+			warnings = append(warnings, issue)
+		}
+	}
+
+	changes := []string{}
+	models := []config.ConfiguredModel{}
+	if candidate != nil {
+		// Compare with the auth dir resolved the same way the running config was.
+		if resolved, errResolve := util.ResolveAuthDir(candidate.AuthDir); errResolve == nil {
+			candidate.AuthDir = resolved
+		}
+		h.mu.Lock()
+		current := h.cfg
+		h.mu.Unlock()
+		if details := diff.BuildConfigChangeDetails(current, candidate); len(details) > 0 {
+			changes = details
+		}
+		if configured := config.ConfiguredModels(candidate); len(configured) > 0 {
+			models = configured
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"valid":    len(errs) == 0,
+		"errors":   errs,
+		"warnings": warnings,
+		"changes":  changes,
+		"models":   models,
+	})
+}
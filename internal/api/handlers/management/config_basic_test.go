@@ -0,0 +1,66 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestValidateConfigYAMLDoesNotApply(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "")
+	gin.SetMode(gin.TestMode)
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	original := "port: 8317\n"
+	if err := os.WriteFile(configPath, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(&config.Config{Port: 8317}, configPath, nil)
+
+	candidate := "port: 9000\nroutng:\n  strategy: fill-first\nclaude-api-key:\n  - api-key: k\n    models:\n      - name: claude-sonnet-4-5\n        alias: sonnet\n"
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v0/management/config/validate", strings.NewReader(candidate))
+	h.ValidateConfigYAML(ctx)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ValidateConfigYAML() status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Valid   bool                     `json:"valid"`
+		Errors  []config.ValidationIssue `json:"errors"`
+		Changes []string                 `json:"changes"`
+		Models  []config.ConfiguredModel `json:"models"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Valid || len(resp.Errors) != 1 || resp.Errors[0].Path != "routng" || resp.Errors[0].Line != 2 {
+		t.Fatalf("errors = %+v, valid = %v", resp.Errors, resp.Valid)
+	}
+	if !containsString(resp.Changes, "port: 8317 -> 9000") {
+		t.Fatalf("changes = %v", resp.Changes)
+	}
+	if len(resp.Models) != 1 || resp.Models[0].ID != "sonnet" {
+		t.Fatalf("models = %+v", resp.Models)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != original || h.cfg.Port != 8317 {
+		t.Fatalf("validation applied the candidate: file %q, port %d", data, h.cfg.Port)
+	}
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
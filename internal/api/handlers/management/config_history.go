@@ -0,0 +1,171 @@
+package management
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/confighistory"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
+	"gopkg.in/yaml.v3"
+)
+
+// configAuthorHeader lets clients such as the TUI label the versions they write.
+const configAuthorHeader = "X-Config-Author"
+
+const maxConfigAuthorLen = 64
+
+// configHistory returns the history store for the active config file.
+func (h *Handler) configHistory() *confighistory.History {
+	return confighistory.For(h.cfg, h.configFilePath)
+}
+
+// writeConfigVersion runs write, which updates the config file, and records the
+// result in the history under the caller's identity. Callers must hold h.mu.
+func (h *Handler) writeConfigVersion(c *gin.Context, source, note string, write func() error) error {
+	return h.configHistory().RecordWrite(h.configFilePath, configAuthor(c), source, note, write)
+}
+
+func configAuthor(c *gin.Context) string {
+	author := strings.TrimSpace(c.GetHeader(configAuthorHeader))
+	if author == "" {
+		author = "management"
+	}
+	if len(author) > maxConfigAuthorLen {
+		author = author[:maxConfigAuthorLen]
+	}
+	if ip := c.ClientIP(); ip != "" {
+		author += "@" + ip
+	}
+	return author
+}
+
+// GetConfigHistory lists recorded config versions, newest first.
+// Query: limit (optional) caps the number of entries returned.
+func (h *Handler) GetConfigHistory(c *gin.Context) {
+	limit, err := parseLimit(c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit", "message": err.Error()})
+		return
+	}
+	h.mu.Lock()
+	history := h.configHistory()
+	h.mu.Unlock()
+	versions, err := history.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "message": err.Error()})
+		return
+	}
+	total := len(versions)
+	if limit > 0 && len(versions) > limit {
+		versions = versions[:limit]
+	}
+	if versions == nil {
+		versions = []confighistory.Version{}
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": versions, "total": total})
+}
+
+// GetConfigHistoryDiff describes the changes a version introduced.
+// Query: against (optional) is another version id or "current"; by default the
+// version is compared with the one recorded before it.
+func (h *Handler) GetConfigHistoryDiff(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	h.mu.Lock()
+	history := h.configHistory()
+	h.mu.Unlock()
+
+	version, content, err := history.Get(id)
+	if err != nil {
+		writeConfigHistoryError(c, err)
+		return
+	}
+
+	against := strings.TrimSpace(c.Query("against"))
+	var base []byte
+	baseID := ""
+	switch against {
+	case "":
+		prev, prevContent, ok, errPrev := history.Previous(id)
+		if errPrev != nil {
+			writeConfigHistoryError(c, errPrev)
+			return
+		}
+		if ok {
+			base, baseID = prevContent, prev.ID
+		}
+	case "current":
+		base, err = os.ReadFile(h.configFilePath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "message": err.Error()})
+			return
+		}
+		baseID = "current"
+	default:
+		var other confighistory.Version
+		if other, base, err = history.Get(against); err != nil {
+			writeConfigHistoryError(c, err)
+			return
+		}
+		baseID = other.ID
+	}
+
+	newCfg, err := parseConfigVersion(content)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_config", "message": err.Error()})
+		return
+	}
+	oldCfg, err := parseConfigVersion(base)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_config", "message": err.Error()})
+		return
+	}
+	changes := diff.BuildConfigChangeDetails(oldCfg, newCfg)
+	if changes == nil {
+		changes = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"version": version, "against": baseID, "changes": changes})
+}
+
+// RollbackConfig restores a recorded version as the active config. The restore is
+// validated like PUT /config.yaml and is itself recorded as a new version.
+func (h *Handler) RollbackConfig(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	h.mu.Lock()
+	history := h.configHistory()
+	h.mu.Unlock()
+
+	version, content, err := history.Get(id)
+	if err != nil {
+		writeConfigHistoryError(c, err)
+		return
+	}
+	if !h.applyConfigYAML(c, content, confighistory.SourceRollback, "rollback of "+version.ID) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "changed": []string{"config"}, "restored": version.ID})
+}
+
+// parseConfigVersion decodes stored YAML for diffing. An empty document yields an
+// empty config so the first recorded version diffs against defaults.
+func parseConfigVersion(content []byte) (*config.Config, error) {
+	cfg := &config.Config{}
+	if len(content) == 0 {
+		return cfg, nil
+	}
+	if err := yaml.Unmarshal(content, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func writeConfigHistoryError(c *gin.Context, err error) {
+	if errors.Is(err, confighistory.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "message": err.Error()})
+}
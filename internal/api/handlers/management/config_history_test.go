@@ -0,0 +1,93 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/confighistory"
+)
+
+func TestConfigHistoryDiffAndRollback(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "")
+	t.Setenv("WRITABLE_PATH", "")
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("port: 8317\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{ConfigHistory: config.ConfigHistoryConfig{Dir: filepath.Join(dir, "history")}}
+	h := NewHandler(cfg, configPath, nil)
+
+	put := func(body string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = httptest.NewRequest(http.MethodPut, "/v0/management/config.yaml", strings.NewReader(body))
+		ctx.Request.Header.Set(configAuthorHeader, "tui")
+		h.PutConfigYAML(ctx)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("PutConfigYAML() status = %d, body %s", rec.Code, rec.Body.String())
+		}
+	}
+	put("port: 8317\nconfig-history:\n  dir: " + cfg.ConfigHistory.Dir + "\n")
+	put("port: 9000\nconfig-history:\n  dir: " + cfg.ConfigHistory.Dir + "\n")
+
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v0/management/config/history", nil)
+	h.GetConfigHistory(ctx)
+	var listed struct {
+		Versions []confighistory.Version `json:"versions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil || len(listed.Versions) != 2 {
+		t.Fatalf("GetConfigHistory() = %s, %v", rec.Body.String(), err)
+	}
+	latest, original := listed.Versions[0], listed.Versions[1]
+	if !strings.HasPrefix(latest.Author, "tui") || latest.Source != confighistory.SourceManagement {
+		t.Fatalf("latest version = %+v", latest)
+	}
+
+	rec = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(rec)
+	ctx.Params = gin.Params{{Key: "id", Value: latest.ID}}
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v0/management/config/history/"+latest.ID+"/diff", nil)
+	h.GetConfigHistoryDiff(ctx)
+	var diffed struct {
+		Against string   `json:"against"`
+		Changes []string `json:"changes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &diffed); err != nil || diffed.Against != original.ID || len(diffed.Changes) != 1 || diffed.Changes[0] != "port: 8317 -> 9000" {
+		t.Fatalf("GetConfigHistoryDiff() = %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(rec)
+	ctx.Params = gin.Params{{Key: "id", Value: original.ID}}
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v0/management/config/rollback/"+original.ID, nil)
+	h.RollbackConfig(ctx)
+	if rec.Code != http.StatusOK || h.cfg.Port != 8317 {
+		t.Fatalf("RollbackConfig() = %d %s, port %d", rec.Code, rec.Body.String(), h.cfg.Port)
+	}
+	versions, _ := h.configHistory().List()
+	if len(versions) != 3 || versions[0].Source != confighistory.SourceRollback || versions[0].Note != "rollback of "+original.ID {
+		t.Fatalf("history after rollback = %+v", versions)
+	}
+
+	rec = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(rec)
+	ctx.Params = gin.Params{{Key: "id", Value: "../config"}}
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v0/management/config/rollback/x", nil)
+	h.RollbackConfig(ctx)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("RollbackConfig(invalid) status = %d", rec.Code)
+	}
+}
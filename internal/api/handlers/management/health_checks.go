@@ -0,0 +1,125 @@
+package management
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// GetHealthChecks returns the latest health check result and quarantine state
+// of every OAuth credential.
+//
+// Endpoint:
+//
+//	GET /v0/management/health-checks
+//
+// Query Parameters (optional):
+//   - refresh: "true" checks every credential before answering.
+//   - tenant: only list credentials visible to the named tenant.
+func (h *Handler) GetHealthChecks(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	if refresh, _ := strconv.ParseBool(c.Query("refresh")); refresh {
+		h.authManager.CheckHealth(c.Request.Context(), true)
+	}
+	visible := h.tenantAuthFilter(c.Query("tenant"))
+	entries := make([]gin.H, 0)
+	for _, auth := range h.authManager.List() {
+		if auth == nil || (visible != nil && !visible(auth)) {
+			continue
+		}
+		accountType, account := auth.AccountInfo()
+		if accountType == "api_key" {
+			continue
+		}
+		auth.EnsureIndex()
+		entry := gin.H{
+			"id":             auth.ID,
+			"auth_index":     auth.Index,
+			"provider":       auth.Provider,
+			"label":          auth.Label,
+			"status":         auth.Status,
+			"status_message": auth.StatusMessage,
+			"disabled":       auth.Disabled,
+			"health":         auth.Health,
+		}
+		if account != "" {
+			entry["account"] = account
+		}
+		if q, ok := auth.Quarantine(); ok {
+			entry["quarantine"] = q
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		pi, _ := entries[i]["provider"].(string)
+		pj, _ := entries[j]["provider"].(string)
+		if pi != pj {
+			return pi < pj
+		}
+		ii, _ := entries[i]["id"].(string)
+		ij, _ := entries[j]["id"].(string)
+		return ii < ij
+	})
+	settings := gin.H{}
+	if h.cfg != nil {
+		settings["enabled"] = h.cfg.HealthChecks.Enabled
+		settings["interval-seconds"] = h.cfg.HealthChecks.IntervalSeconds
+		settings["failure-threshold"] = h.cfg.HealthChecks.FailureThreshold
+		settings["report-only"] = h.cfg.HealthChecks.ReportOnly
+	}
+	c.JSON(http.StatusOK, gin.H{"health-checks": settings, "credentials": entries})
+}
+
+// ReleaseQuarantine puts a quarantined credential back into scheduling.
+//
+// Endpoint:
+//
+//	POST /v0/management/health-checks/release
+//
+// Body: {"name": "<auth id or file name>"}
+func (h *Handler) ReleaseQuarantine(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	var target *coreauth.Auth
+	if auth, ok := h.authManager.GetByID(name); ok {
+		target = auth
+	} else {
+		for _, auth := range h.authManager.List() {
+			if auth.FileName == name {
+				target = auth
+				break
+			}
+		}
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "auth file not found"})
+		return
+	}
+	updated, err := h.authManager.ReleaseQuarantine(c.Request.Context(), target.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "id": updated.ID, "auth_status": updated.Status})
+}
@@ -0,0 +1,221 @@
+package management
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// loginSessionResponse is a login session as returned by the management API.
+type loginSessionResponse struct {
+	sdkAuth.LoginSession
+	// CallbackPath is the public path on this proxy that completes the session
+	// when the provider's callback query string is appended to it.
+	CallbackPath string `json:"callback_path,omitempty"`
+}
+
+func newLoginSessionResponse(session sdkAuth.LoginSession) loginSessionResponse {
+	resp := loginSessionResponse{LoginSession: session}
+	if !session.Done() {
+		resp.CallbackPath = "/logins/" + session.ID + "/callback"
+	}
+	return resp
+}
+
+// loginBroker returns the broker behind the login endpoints, creating it on first
+// use. Records pass through the post-auth hook and are saved to the token store.
+func (h *Handler) loginBroker() *sdkAuth.LoginBroker {
+	h.loginBrokerOnce.Do(func() {
+		manager := sdkAuth.NewManager(h.tokenStoreWithBaseDir(), sdkAuth.DefaultAuthenticators()...)
+		broker := sdkAuth.NewLoginBroker(manager)
+		broker.BeforeSave = func(ctx context.Context, record *coreauth.Auth) error {
+			if h.postAuthHook != nil {
+				return h.postAuthHook(ctx, record)
+			}
+			return nil
+		}
+		h.loginBrokerInstance = broker
+	})
+	return h.loginBrokerInstance
+}
+
+// StartLogin starts a headless login session for a provider. The response carries
+// the authorize URL or the device code to present to the user.
+//
+// Endpoint:
+//
+//	POST /v0/management/logins
+//
+// Body: {"provider": "claude", "project_id": "", "metadata": {}}
+func (h *Handler) StartLogin(c *gin.Context) {
+	var req struct {
+		Provider  string            `json:"provider"`
+		ProjectID string            `json:"project_id"`
+		Metadata  map[string]string `json:"metadata"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if strings.TrimSpace(req.Provider) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider is required"})
+		return
+	}
+	metadata := req.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	session, err := h.loginBroker().Start(req.Provider, h.cfg, &sdkAuth.LoginOptions{
+		ProjectID: strings.TrimSpace(req.ProjectID),
+		Metadata:  metadata,
+	})
+	if err != nil {
+		if errors.Is(err, sdkAuth.ErrLoginProviderUnsupported) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, newLoginSessionResponse(session))
+}
+
+// ListLogins returns the running and recently finished login sessions.
+//
+// Endpoint:
+//
+//	GET /v0/management/logins
+func (h *Handler) ListLogins(c *gin.Context) {
+	sessions := h.loginBroker().List()
+	out := make([]loginSessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		out = append(out, newLoginSessionResponse(session))
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": out})
+}
+
+// GetLogin returns one login session. With ?stream=true the response is a
+// server-sent event stream of session snapshots that ends with the final state.
+//
+// Endpoint:
+//
+//	GET /v0/management/logins/:id
+func (h *Handler) GetLogin(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	broker := h.loginBroker()
+	if stream, _ := strconv.ParseBool(c.Query("stream")); stream {
+		updates, unsubscribe, err := broker.Subscribe(id)
+		if err != nil {
+			writeLoginError(c, err)
+			return
+		}
+		defer unsubscribe()
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.Stream(func(_ io.Writer) bool {
+			select {
+			case session, ok := <-updates:
+				if !ok {
+					return false
+				}
+				c.SSEvent("session", newLoginSessionResponse(session))
+				return !session.Done()
+			case <-c.Request.Context().Done():
+				return false
+			}
+		})
+		return
+	}
+	session, err := broker.Get(id)
+	if err != nil {
+		writeLoginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newLoginSessionResponse(session))
+}
+
+// SubmitLoginCallback completes a browser login with the callback the provider
+// redirected to, for example after copying the failed localhost URL from the
+// browser's address bar.
+//
+// Endpoint:
+//
+//	POST /v0/management/logins/:id/callback
+//
+// Body: {"redirect_url": "..."}, {"code": "...", "state": "..."} or {"input": "..."}
+func (h *Handler) SubmitLoginCallback(c *gin.Context) {
+	var req struct {
+		RedirectURL string `json:"redirect_url"`
+		Code        string `json:"code"`
+		State       string `json:"state"`
+		Error       string `json:"error"`
+		Input       string `json:"input"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	value := strings.TrimSpace(req.RedirectURL)
+	if value == "" && (req.Code != "" || req.Error != "") {
+		query := url.Values{}
+		for key, v := range map[string]string{"code": req.Code, "state": req.State, "error": req.Error} {
+			if v = strings.TrimSpace(v); v != "" {
+				query.Set(key, v)
+			}
+		}
+		value = "?" + query.Encode()
+	}
+	if value == "" {
+		value = strings.TrimSpace(req.Input)
+	}
+	if value == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "redirect_url, code or input is required"})
+		return
+	}
+	id := strings.TrimSpace(c.Param("id"))
+	if err := h.SubmitLoginInput(id, value); err != nil {
+		writeLoginError(c, err)
+		return
+	}
+	session, _ := h.loginBroker().Get(id)
+	c.JSON(http.StatusAccepted, newLoginSessionResponse(session))
+}
+
+// SubmitLoginInput feeds input into a waiting login session. The public callback
+// route uses it to complete sessions from the provider redirect.
+func (h *Handler) SubmitLoginInput(id, value string) error {
+	return h.loginBroker().Submit(id, value)
+}
+
+// CancelLogin aborts a running login session.
+//
+// Endpoint:
+//
+//	DELETE /v0/management/logins/:id
+func (h *Handler) CancelLogin(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	if err := h.loginBroker().Cancel(id); err != nil {
+		writeLoginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func writeLoginError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sdkAuth.ErrLoginSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "login session not found"})
+	case errors.Is(err, sdkAuth.ErrLoginSessionDone), errors.Is(err, sdkAuth.ErrLoginInputPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,51 @@
+package management
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/notify"
+)
+
+// TestNotifications sends a test event to the configured notification sinks and
+// reports the outcome per sink. It works while notifications are disabled so
+// sinks can be verified first.
+//
+// Endpoint:
+//
+//	POST /v0/management/notifications/test
+//
+// Body (optional): {"sink": "<name>"} limits the test to one sink.
+func (h *Handler) TestNotifications(c *gin.Context) {
+	var req struct {
+		Sink string `json:"sink"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	name := strings.TrimSpace(req.Sink)
+	results := notify.Default().SendTest(c.Request.Context(), name)
+	if len(results) == 0 {
+		if name != "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "notification sink not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no notification sinks configured"})
+		return
+	}
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	status := http.StatusOK
+	if failed == len(results) {
+		status = http.StatusBadGateway
+	}
+	c.JSON(status, gin.H{"results": results, "failed": failed})
+}
@@ -0,0 +1,80 @@
+package management
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor/helps"
+	"github.com/tidwall/gjson"
+)
+
+// payloadPreviewRequest is the body accepted by PreviewPayloadRules.
+type payloadPreviewRequest struct {
+	Model          string                `json:"model"`
+	RequestedModel string                `json:"requested-model"`
+	Protocol       string                `json:"protocol"`
+	Root           string                `json:"root"`
+	APIKey         string                `json:"api-key"`
+	Tenant         string                `json:"tenant"`
+	Headers        map[string]string     `json:"headers"`
+	Stream         bool                  `json:"stream"`
+	Payload        json.RawMessage       `json:"payload"`
+	Rules          *config.PayloadConfig `json:"rules"`
+}
+
+// PreviewPayloadRules shows how the payload rules would rewrite a request body without
+// sending anything upstream. The running rules are used unless the body supplies its
+// own "rules" block, which makes it possible to try edits before saving them.
+func (h *Handler) PreviewPayloadRules(c *gin.Context) {
+	var body payloadPreviewRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body", "message": err.Error()})
+		return
+	}
+	model := strings.TrimSpace(body.Model)
+	if model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "model is required"})
+		return
+	}
+	payload := bytes.TrimSpace(body.Payload)
+	if !json.Valid(payload) || !gjson.ParseBytes(payload).IsObject() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payload must be a JSON object"})
+		return
+	}
+
+	cfg := &config.Config{}
+	if body.Rules != nil {
+		cfg.Payload = *body.Rules
+		cfg.SanitizePayloadRules()
+	} else {
+		h.mu.Lock()
+		if h.cfg != nil {
+			cfg.Payload = h.cfg.Payload
+		}
+		h.mu.Unlock()
+	}
+
+	req := helps.PayloadRequest{
+		APIKey: strings.TrimSpace(body.APIKey),
+		Tenant: strings.TrimSpace(body.Tenant),
+		Stream: body.Stream,
+	}
+	if len(body.Headers) > 0 {
+		req.Headers = make(http.Header, len(body.Headers))
+		for name, value := range body.Headers {
+			req.Headers.Set(name, value)
+		}
+	}
+
+	out, applied := helps.PreviewPayloadConfig(cfg, req, model, strings.TrimSpace(body.Protocol), strings.TrimSpace(body.Root), payload, strings.TrimSpace(body.RequestedModel))
+	c.JSON(http.StatusOK, gin.H{
+		"before":  json.RawMessage(payload),
+		"after":   json.RawMessage(out),
+		"applied": applied,
+		"changed": !bytes.Equal(payload, out),
+	})
+}
@@ -0,0 +1,52 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestPreviewPayloadRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Payload.Override = []config.PayloadRule{{
+		When:   &config.PayloadCondition{Headers: map[string]string{"X-Client": "cursor*"}},
+		Params: map[string]any{"temperature": 0.2},
+	}}
+	h := NewHandler(cfg, "", nil)
+
+	preview := func(body string) (int, map[string]json.RawMessage) {
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/v0/management/payload/preview", strings.NewReader(body))
+		h.PreviewPayloadRules(ctx)
+		var resp map[string]json.RawMessage
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	code, resp := preview(`{"model":"gpt-5","protocol":"openai","headers":{"X-Client":"cursor/1"},"payload":{"temperature":1}}`)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, body %v", code, resp)
+	}
+	if string(resp["after"]) != `{"temperature":0.2}` || string(resp["changed"]) != "true" || string(resp["applied"]) != `["override[0]"]` {
+		t.Fatalf("running rules preview = %v", resp)
+	}
+
+	code, resp = preview(`{"model":"gpt-5","payload":{"temperature":1},"rules":{"clamp":[{"models":[{"name":"*"}],"params":{"temperature":{"max":0.5}}}]}}`)
+	if code != http.StatusOK || string(resp["after"]) != `{"temperature":0.5}` {
+		t.Fatalf("candidate rules preview = %d %v", code, resp)
+	}
+
+	if code, _ = preview(`{"model":"gpt-5","payload":"text"}`); code != http.StatusBadRequest {
+		t.Fatalf("non-object payload status = %d", code)
+	}
+	if code, _ = preview(`{"payload":{}}`); code != http.StatusBadRequest {
+		t.Fatalf("missing model status = %d", code)
+	}
+}
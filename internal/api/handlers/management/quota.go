@@ -1,6 +1,12 @@
 package management
 
-import "github.com/gin-gonic/gin"
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
 
 // Quota exceeded toggles
 func (h *Handler) GetSwitchProject(c *gin.Context) {
@@ -16,3 +22,68 @@ func (h *Handler) GetSwitchPreviewModel(c *gin.Context) {
 func (h *Handler) PutSwitchPreviewModel(c *gin.Context) {
 	h.updateBoolField(c, func(v bool) { h.cfg.QuotaExceeded.SwitchPreviewModel = v })
 }
+
+// GetQuota returns one quota view across all OAuth credentials: the latest probe
+// reading where the provider supports one, plus the 429 cooldown state.
+//
+// Endpoint:
+//
+//	GET /v0/management/quota
+//
+// Query Parameters (optional):
+//   - refresh: "true" probes every supported credential before answering.
+//   - tenant: only list credentials visible to the named tenant.
+func (h *Handler) GetQuota(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	if refresh, _ := strconv.ParseBool(c.Query("refresh")); refresh {
+		h.authManager.PollQuota(c.Request.Context(), true)
+	}
+	visible := h.tenantAuthFilter(c.Query("tenant"))
+	entries := make([]gin.H, 0)
+	for _, auth := range h.authManager.List() {
+		if auth == nil || (visible != nil && !visible(auth)) {
+			continue
+		}
+		accountType, account := auth.AccountInfo()
+		if accountType == "api_key" {
+			continue
+		}
+		auth.EnsureIndex()
+		_, probe := h.authManager.QuotaProbeFor(auth)
+		entry := gin.H{
+			"id":         auth.ID,
+			"auth_index": auth.Index,
+			"provider":   auth.Provider,
+			"label":      auth.Label,
+			"status":     auth.Status,
+			"disabled":   auth.Disabled,
+			"probe":      probe,
+			"quota":      auth.Quota,
+		}
+		if account != "" {
+			entry["account"] = account
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		pi, _ := entries[i]["provider"].(string)
+		pj, _ := entries[j]["provider"].(string)
+		if pi != pj {
+			return pi < pj
+		}
+		ii, _ := entries[i]["id"].(string)
+		ij, _ := entries[j]["id"].(string)
+		return ii < ij
+	})
+	polling := gin.H{}
+	if h.cfg != nil {
+		polling["enabled"] = h.cfg.QuotaPolling.Enabled
+		polling["interval-seconds"] = h.cfg.QuotaPolling.IntervalSeconds
+		polling["reserve-percent"] = h.cfg.QuotaPolling.ReservePercent
+		polling["skip-low"] = h.cfg.QuotaPolling.SkipLow
+	}
+	c.JSON(http.StatusOK, gin.H{"polling": polling, "credentials": entries})
+}
@@ -0,0 +1,41 @@
+package management
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// GetTrafficShadow returns the configured traffic routes and the comparison of
+// every shadow provider against the primary responses it mirrored.
+//
+// Endpoint:
+//
+//	GET /v0/management/traffic-routing/shadow
+func (h *Handler) GetTrafficShadow(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	routes := []config.TrafficRoute{}
+	if h.cfg != nil && len(h.cfg.TrafficRouting) > 0 {
+		routes = h.cfg.TrafficRouting
+	}
+	c.JSON(http.StatusOK, gin.H{"routes": routes, "shadow": h.authManager.ShadowStats()})
+}
+
+// ResetTrafficShadow clears the recorded shadow comparisons, e.g. after changing
+// the candidate provider's configuration.
+//
+// Endpoint:
+//
+//	DELETE /v0/management/traffic-routing/shadow
+func (h *Handler) ResetTrafficShadow(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	h.authManager.ResetShadowStats()
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
@@ -0,0 +1,110 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/transcript"
+	log "github.com/sirupsen/logrus"
+)
+
+// SearchTranscripts returns archived transcripts matching the query filters, newest first.
+//
+// Query parameters: key, tenant, session, model, from, to (RFC 3339 or unix seconds),
+// q (case-insensitive text) and limit.
+func (h *Handler) SearchTranscripts(c *gin.Context) {
+	store := h.transcriptStore(c)
+	if store == nil {
+		return
+	}
+	query, err := transcriptQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	results, err := store.Search(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to search transcripts: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"transcripts": results, "count": len(results)})
+}
+
+// ExportTranscripts streams every matching transcript as newline-delimited JSON in
+// chronological order. It accepts the same filters as SearchTranscripts except limit.
+func (h *Handler) ExportTranscripts(c *gin.Context) {
+	store := h.transcriptStore(c)
+	if store == nil {
+		return
+	}
+	query, err := transcriptQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := fmt.Sprintf("transcripts-%s.jsonl", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	if err = store.Export(query, func(t *transcript.Transcript) error {
+		return encoder.Encode(t)
+	}); err != nil {
+		// Headers are already sent; the truncated body is the only signal left.
+		log.WithError(err).Warn("management: transcript export aborted")
+	}
+}
+
+func (h *Handler) transcriptStore(c *gin.Context) *transcript.Store {
+	if h.cfg == nil || !h.cfg.Transcripts.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transcript archive disabled"})
+		return nil
+	}
+	archiver := transcript.ForConfig(&h.cfg.Transcripts)
+	if archiver == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "transcript archive unavailable"})
+		return nil
+	}
+	return archiver.Store()
+}
+
+func transcriptQuery(c *gin.Context) (transcript.Query, error) {
+	query := transcript.Query{
+		APIKey:  strings.TrimSpace(c.Query("key")),
+		Tenant:  strings.TrimSpace(c.Query("tenant")),
+		Session: strings.TrimSpace(c.Query("session")),
+		Model:   strings.TrimSpace(c.Query("model")),
+		Text:    strings.TrimSpace(c.Query("q")),
+	}
+	var err error
+	if query.From, err = parseTranscriptTime(c.Query("from")); err != nil {
+		return query, fmt.Errorf("invalid from: %w", err)
+	}
+	if query.To, err = parseTranscriptTime(c.Query("to")); err != nil {
+		return query, fmt.Errorf("invalid to: %w", err)
+	}
+	if query.Limit, err = parseLimit(c.Query("limit")); err != nil {
+		return query, fmt.Errorf("invalid limit: %w", err)
+	}
+	return query, nil
+}
+
+func parseTranscriptTime(raw string) (time.Time, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 or unix seconds")
+	}
+	return ts, nil
+}
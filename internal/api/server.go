@@ -28,6 +28,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/notify"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -262,6 +263,7 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	}
 	managementasset.SetCurrentConfig(cfg)
 	auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
+	notify.SetConfig(cfg.Notifications)
 	// Initialize management handler
 	s.mgmt = managementHandlers.NewHandler(cfg, configFilePath, authManager)
 	if optionState.localPassword != "" {
@@ -379,6 +381,21 @@ func (s *Server) setupRoutes() {
 	// OAuth callback endpoints (reuse main server port)
 	// These endpoints receive provider redirects and persist
 	// the short-lived code/state for the waiting goroutine.
+	// Completes a brokered login session: the provider's callback query string
+	// is appended to the session's callback path on this server's public URL.
+	s.engine.GET("/logins/:id/callback", func(c *gin.Context) {
+		if s.mgmt == nil || c.Request.URL.RawQuery == "" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if err := s.mgmt.SubmitLoginInput(c.Param("id"), "?"+c.Request.URL.RawQuery); err != nil {
+			c.String(http.StatusNotFound, "Login session not found or already completed.")
+			return
+		}
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.String(http.StatusOK, oauthCallbackSuccessHTML)
+	})
+
 	s.engine.GET("/anthropic/callback", func(c *gin.Context) {
 		code := c.Query("code")
 		state := c.Query("state")
@@ -533,9 +550,16 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/usage", s.mgmt.GetUsageStatistics)
 		mgmt.GET("/usage/export", s.mgmt.ExportUsageStatistics)
 		mgmt.POST("/usage/import", s.mgmt.ImportUsageStatistics)
+		mgmt.GET("/transcripts", s.mgmt.SearchTranscripts)
+		mgmt.GET("/transcripts/export", s.mgmt.ExportTranscripts)
 		mgmt.GET("/config", s.mgmt.GetConfig)
 		mgmt.GET("/config.yaml", s.mgmt.GetConfigYAML)
 		mgmt.PUT("/config.yaml", s.mgmt.PutConfigYAML)
+		mgmt.POST("/config/validate", s.mgmt.ValidateConfigYAML)
+		mgmt.GET("/config/history", s.mgmt.GetConfigHistory)
+		mgmt.GET("/config/history/:id/diff", s.mgmt.GetConfigHistoryDiff)
+		mgmt.POST("/config/rollback/:id", s.mgmt.RollbackConfig)
+		mgmt.POST("/payload/preview", s.mgmt.PreviewPayloadRules)
 		mgmt.GET("/latest-version", s.mgmt.GetLatestVersion)
 
 		mgmt.GET("/debug", s.mgmt.GetDebug)
@@ -574,6 +598,17 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PATCH("/quota-exceeded/switch-preview-model", s.mgmt.PutSwitchPreviewModel)
 
 		mgmt.GET("/copilot-quota", s.mgmt.GetCopilotQuota)
+		mgmt.GET("/quota", s.mgmt.GetQuota)
+		mgmt.GET("/health-checks", s.mgmt.GetHealthChecks)
+		mgmt.POST("/health-checks/release", s.mgmt.ReleaseQuarantine)
+		mgmt.GET("/traffic-routing/shadow", s.mgmt.GetTrafficShadow)
+		mgmt.DELETE("/traffic-routing/shadow", s.mgmt.ResetTrafficShadow)
+		mgmt.POST("/notifications/test", s.mgmt.TestNotifications)
+		mgmt.POST("/logins", s.mgmt.StartLogin)
+		mgmt.GET("/logins", s.mgmt.ListLogins)
+		mgmt.GET("/logins/:id", s.mgmt.GetLogin)
+		mgmt.POST("/logins/:id/callback", s.mgmt.SubmitLoginCallback)
+		mgmt.DELETE("/logins/:id", s.mgmt.CancelLogin)
 
 		mgmt.GET("/api-keys", s.mgmt.GetAPIKeys)
 		mgmt.PUT("/api-keys", s.mgmt.PutAPIKeys)
@@ -671,6 +706,8 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/model-definitions/:channel", s.mgmt.GetStaticModelDefinitions)
 		mgmt.GET("/auth-files/download", s.mgmt.DownloadAuthFile)
 		mgmt.POST("/auth-files", s.mgmt.UploadAuthFile)
+		mgmt.POST("/auth-files/import", s.mgmt.ImportAuthFiles)
+		mgmt.POST("/auth-files/export", s.mgmt.ExportAuthFiles)
 		mgmt.DELETE("/auth-files", s.mgmt.DeleteAuthFile)
 		mgmt.PATCH("/auth-files/status", s.mgmt.PatchAuthFileStatus)
 		mgmt.PATCH("/auth-files/fields", s.mgmt.PatchAuthFileFields)
@@ -1014,6 +1051,7 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		s.wsAuthChanged(oldCfg.WebsocketAuth, cfg.WebsocketAuth)
 	}
 	managementasset.SetCurrentConfig(cfg)
+	notify.SetConfig(cfg.Notifications)
 	// Save YAML snapshot for next comparison
 	s.oldConfigYaml, _ = yaml.Marshal(cfg)
 
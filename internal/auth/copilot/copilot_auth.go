@@ -233,6 +233,9 @@ type CopilotModelEntry struct {
 	Name         string         `json:"name,omitempty"`
 	Version      string         `json:"version,omitempty"`
 	Capabilities map[string]any `json:"capabilities,omitempty"`
+	// SupportedEndpoints lists the API routes the model is served on, e.g.
+	// "/chat/completions", "/responses" or "/v1/messages".
+	SupportedEndpoints []string `json:"supported_endpoints,omitempty"`
 }
 
 // CopilotModelLimits holds the token limits returned by the Copilot /models API
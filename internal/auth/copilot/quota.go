@@ -0,0 +1,121 @@
+package copilot
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// UserInfoURL serves the account's plan and quota snapshots.
+const UserInfoURL = "https://api.github.com/copilot_internal/user"
+
+// QuotaDetail represents quota information for a specific resource type
+type QuotaDetail struct {
+	Entitlement      float64 `json:"entitlement"`
+	OverageCount     float64 `json:"overage_count"`
+	OveragePermitted bool    `json:"overage_permitted"`
+	PercentRemaining float64 `json:"percent_remaining"`
+	QuotaID          string  `json:"quota_id"`
+	QuotaRemaining   float64 `json:"quota_remaining"`
+	Remaining        float64 `json:"remaining"`
+	Unlimited        bool    `json:"unlimited"`
+}
+
+// QuotaSnapshots contains quota details for different resource types
+type QuotaSnapshots struct {
+	Chat                QuotaDetail `json:"chat"`
+	Completions         QuotaDetail `json:"completions"`
+	PremiumInteractions QuotaDetail `json:"premium_interactions"`
+}
+
+// CopilotUsageResponse represents the GitHub Copilot usage information
+type CopilotUsageResponse struct {
+	AccessTypeSKU         string         `json:"access_type_sku"`
+	AnalyticsTrackingID   string         `json:"analytics_tracking_id"`
+	AssignedDate          string         `json:"assigned_date"`
+	CanSignupForLimited   bool           `json:"can_signup_for_limited"`
+	ChatEnabled           bool           `json:"chat_enabled"`
+	CopilotPlan           string         `json:"copilot_plan"`
+	OrganizationLoginList []interface{}  `json:"organization_login_list"`
+	OrganizationList      []interface{}  `json:"organization_list"`
+	QuotaResetDate        string         `json:"quota_reset_date"`
+	QuotaSnapshots        QuotaSnapshots `json:"quota_snapshots"`
+}
+
+// copilotUsageExtras holds the fields that only some plans report.
+type copilotUsageExtras struct {
+	QuotaSnapshots       json.RawMessage `json:"quota_snapshots"`
+	QuotaResetDateUTC    string          `json:"quota_reset_date_utc"`
+	MonthlyQuotas        map[string]any  `json:"monthly_quotas"`
+	LimitedUserQuotas    map[string]any  `json:"limited_user_quotas"`
+	LimitedUserResetDate string          `json:"limited_user_reset_date"`
+}
+
+// ParseUsage decodes a copilot_internal/user response. Paid plans report
+// quota_snapshots directly; free and limited plans report monthly_quotas and
+// limited_user_quotas instead, which are converted into the same snapshots.
+// QuotaResetDate is taken from whichever reset field the plan reports.
+func ParseUsage(body []byte) (*CopilotUsageResponse, error) {
+	var usage CopilotUsageResponse
+	if err := json.Unmarshal(body, &usage); err != nil {
+		return nil, err
+	}
+	var extras copilotUsageExtras
+	if err := json.Unmarshal(body, &extras); err != nil {
+		return nil, err
+	}
+	if len(extras.QuotaSnapshots) > 0 {
+		if extras.QuotaResetDateUTC != "" {
+			usage.QuotaResetDate = extras.QuotaResetDateUTC
+		}
+		return &usage, nil
+	}
+
+	if extras.MonthlyQuotas != nil && extras.LimitedUserQuotas != nil {
+		usage.QuotaSnapshots.Chat = limitedQuotaDetail("chat", extras.MonthlyQuotas, extras.LimitedUserQuotas)
+		usage.QuotaSnapshots.Completions = limitedQuotaDetail("completions", extras.MonthlyQuotas, extras.LimitedUserQuotas)
+	}
+	// Premium interactions don't exist for non-enterprise, leave as zero values
+	usage.QuotaSnapshots.PremiumInteractions = QuotaDetail{QuotaID: "premium_interactions"}
+	if extras.LimitedUserResetDate != "" {
+		usage.QuotaResetDate = extras.LimitedUserResetDate
+	}
+	return &usage, nil
+}
+
+// limitedQuotaDetail builds a snapshot from the monthly total and the remaining
+// limited quota, which defaults to the full total when absent.
+func limitedQuotaDetail(id string, monthly, limited map[string]any) QuotaDetail {
+	total, ok := monthly[id].(float64)
+	if !ok {
+		return QuotaDetail{}
+	}
+	remaining := total
+	if left, okLeft := limited[id].(float64); okLeft {
+		remaining = left
+	}
+	percentRemaining := 0.0
+	if total > 0 {
+		percentRemaining = (remaining / total) * 100.0
+	}
+	return QuotaDetail{
+		Entitlement:      total,
+		Remaining:        remaining,
+		QuotaRemaining:   remaining,
+		PercentRemaining: percentRemaining,
+		QuotaID:          id,
+	}
+}
+
+// ResetTime parses QuotaResetDate, which is either a date or an RFC 3339 time.
+func (u *CopilotUsageResponse) ResetTime() (time.Time, bool) {
+	if u == nil || u.QuotaResetDate == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse("2006-01-02", u.QuotaResetDate); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.RFC3339, u.QuotaResetDate); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
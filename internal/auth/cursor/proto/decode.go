@@ -557,6 +557,16 @@ func decodeVarintField(data []byte, targetField protowire.Number) int64 {
 	return 0
 }
 
+// DecodeCheckpointTokenDetails reads the server's context accounting from a
+// conversation checkpoint (ConversationStateStructure.token_details).
+func DecodeCheckpointTokenDetails(checkpoint []byte) (used, maxTokens int64, ok bool) {
+	details := decodeBytesField(checkpoint, CSS_TokenDetails)
+	if details == nil {
+		return 0, 0, false
+	}
+	return decodeVarintField(details, CTD_UsedTokens), decodeVarintField(details, CTD_MaxTokens), true
+}
+
 // BlobIdHex returns the hex string of a blob ID for use as a map key.
 func BlobIdHex(blobId []byte) string {
 	return hex.EncodeToString(blobId)
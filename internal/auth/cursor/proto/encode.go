@@ -38,9 +38,30 @@ type ImageData struct {
 	Data     []byte
 }
 
+// TurnData is one completed exchange of the conversation history: the user
+// message and the steps the assistant took in response.
 type TurnData struct {
 	UserText      string
-	AssistantText string
+	Images        []ImageData
+	AssistantText string     // used when Steps is empty
+	Steps         []StepData // assistant text, thinking and tool calls in order
+}
+
+// StepData is one assistant step. Exactly one of Text, Thinking or ToolCall is set.
+type StepData struct {
+	Text     string
+	Thinking string
+	ToolCall *ToolCallData
+}
+
+// ToolCallData is an MCP tool invocation from history together with its result.
+type ToolCallData struct {
+	ID        string
+	Name      string
+	Arguments string // JSON object
+	Result    string
+	HasResult bool
+	IsError   bool
 }
 
 type McpToolDef struct {
@@ -117,37 +138,9 @@ func EncodeRunRequest(p *RunRequestParams) []byte {
 
 	// --- Conversation turns ---
 	// Each turn is serialized as bytes (ConversationTurnStructure → bytes)
-	var turnBytes [][]byte
+	turnBytes := make([][]byte, 0, len(p.Turns))
 	for _, turn := range p.Turns {
-		// UserMessage for this turn
-		um := newMsg("UserMessage")
-		setStr(um, "text", turn.UserText)
-		setStr(um, "message_id", generateId())
-		umBytes := marshal(um)
-
-		// Steps (assistant response)
-		var stepBytes [][]byte
-		if turn.AssistantText != "" {
-			am := newMsg("AssistantMessage")
-			setStr(am, "text", turn.AssistantText)
-			step := newMsg("ConversationStep")
-			setMsg(step, "assistant_message", am)
-			stepBytes = append(stepBytes, marshal(step))
-		}
-
-		// AgentConversationTurnStructure (fields are bytes, not submessages)
-		agentTurn := newMsg("AgentConversationTurnStructure")
-		setBytes(agentTurn, "user_message", umBytes)
-		for _, sb := range stepBytes {
-			stepsField := field(agentTurn, "steps")
-			list := agentTurn.Mutable(stepsField).List()
-			list.Append(protoreflect.ValueOfBytes(sb))
-		}
-
-		// ConversationTurnStructure (oneof turn → agentConversationTurn)
-		cts := newMsg("ConversationTurnStructure")
-		setMsg(cts, "agent_conversation_turn", agentTurn)
-		turnBytes = append(turnBytes, marshal(cts))
+		turnBytes = append(turnBytes, encodeTurn(turn))
 	}
 
 	// --- System prompt blob ---
@@ -176,24 +169,7 @@ func EncodeRunRequest(p *RunRequestParams) []byte {
 	}
 
 	// --- UserMessage (current) ---
-	userMessage := newMsg("UserMessage")
-	setStr(userMessage, "text", p.UserText)
-	setStr(userMessage, "message_id", p.MessageId)
-
-	// Images via SelectedContext
-	if len(p.Images) > 0 {
-		sc := newMsg("SelectedContext")
-		imgsField := field(sc, "selected_images")
-		imgsList := sc.Mutable(imgsField).List()
-		for _, img := range p.Images {
-			si := newMsg("SelectedImage")
-			setStr(si, "uuid", generateId())
-			setStr(si, "mime_type", img.MimeType)
-			setBytes(si, "data", img.Data)
-			imgsList.Append(protoreflect.ValueOfMessage(si.ProtoReflect()))
-		}
-		setMsg(userMessage, "selected_context", sc)
-	}
+	userMessage := encodeUserMessage(p.UserText, p.MessageId, p.Images)
 
 	// --- UserMessageAction ---
 	uma := newMsg("UserMessageAction")
@@ -242,18 +218,16 @@ func EncodeRunRequest(p *RunRequestParams) []byte {
 	return marshal(acm)
 }
 
-// encodeRunRequestWithCheckpoint builds an AgentClientMessage using a raw checkpoint
-// as conversation_state. The checkpoint bytes are embedded directly without deserialization.
-func encodeRunRequestWithCheckpoint(p *RunRequestParams) []byte {
-	// Build UserMessage
+// encodeUserMessage builds a UserMessage, attaching images via SelectedContext.
+func encodeUserMessage(text, messageId string, images []ImageData) *dynamicpb.Message {
 	userMessage := newMsg("UserMessage")
-	setStr(userMessage, "text", p.UserText)
-	setStr(userMessage, "message_id", p.MessageId)
-	if len(p.Images) > 0 {
+	setStr(userMessage, "text", text)
+	setStr(userMessage, "message_id", messageId)
+	if len(images) > 0 {
 		sc := newMsg("SelectedContext")
 		imgsField := field(sc, "selected_images")
 		imgsList := sc.Mutable(imgsField).List()
-		for _, img := range p.Images {
+		for _, img := range images {
 			si := newMsg("SelectedImage")
 			setStr(si, "uuid", generateId())
 			setStr(si, "mime_type", img.MimeType)
@@ -262,6 +236,92 @@ func encodeRunRequestWithCheckpoint(p *RunRequestParams) []byte {
 		}
 		setMsg(userMessage, "selected_context", sc)
 	}
+	return userMessage
+}
+
+// encodeTurn serializes a history turn as ConversationTurnStructure bytes.
+// Mirrors the agentConversationTurn shape the server writes into checkpoints.
+func encodeTurn(turn TurnData) []byte {
+	umBytes := marshal(encodeUserMessage(turn.UserText, generateId(), turn.Images))
+
+	steps := turn.Steps
+	if len(steps) == 0 && turn.AssistantText != "" {
+		steps = []StepData{{Text: turn.AssistantText}}
+	}
+
+	// AgentConversationTurnStructure (fields are bytes, not submessages)
+	agentTurn := newMsg("AgentConversationTurnStructure")
+	setBytes(agentTurn, "user_message", umBytes)
+	stepsList := agentTurn.Mutable(field(agentTurn, "steps")).List()
+	for _, step := range steps {
+		if sb := encodeStep(step); sb != nil {
+			stepsList.Append(protoreflect.ValueOfBytes(sb))
+		}
+	}
+
+	// ConversationTurnStructure (oneof turn → agentConversationTurn)
+	cts := newMsg("ConversationTurnStructure")
+	setMsg(cts, "agent_conversation_turn", agentTurn)
+	return marshal(cts)
+}
+
+func encodeStep(step StepData) []byte {
+	cs := newMsg("ConversationStep")
+	switch {
+	case step.ToolCall != nil:
+		tc := newMsg("ToolCall")
+		setMsg(tc, "mcp_tool_call", encodeMcpToolCall(step.ToolCall))
+		setMsg(cs, "tool_call", tc)
+	case step.Thinking != "":
+		tm := newMsg("ThinkingMessage")
+		setStr(tm, "text", step.Thinking)
+		setMsg(cs, "thinking_message", tm)
+	case step.Text != "":
+		am := newMsg("AssistantMessage")
+		setStr(am, "text", step.Text)
+		setMsg(cs, "assistant_message", am)
+	default:
+		return nil
+	}
+	return marshal(cs)
+}
+
+func encodeMcpToolCall(call *ToolCallData) *dynamicpb.Message {
+	args := newMsg("McpArgs")
+	setStr(args, "name", call.Name)
+	setStr(args, "tool_name", call.Name)
+	setStr(args, "tool_call_id", call.ID)
+	setStr(args, "provider_identifier", "proxy")
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(call.Arguments), &decoded); err == nil && len(decoded) > 0 {
+		argsMap := args.Mutable(field(args, "args")).Map()
+		for k, v := range decoded {
+			argsMap.Set(protoreflect.ValueOfString(k).MapKey(), protoreflect.ValueOfBytes(jsonToProtobufValueBytes(v)))
+		}
+	}
+
+	mcpCall := newMsg("McpToolCall")
+	setMsg(mcpCall, "args", args)
+	if call.HasResult {
+		textContent := newMsg("McpTextContent")
+		setStr(textContent, "text", call.Result)
+		contentItem := newMsg("McpToolResultContentItem")
+		setMsg(contentItem, "text", textContent)
+		success := newMsg("McpSuccess")
+		success.Mutable(field(success, "content")).List().Append(protoreflect.ValueOfMessage(contentItem.ProtoReflect()))
+		setBool(success, "is_error", call.IsError)
+		result := newMsg("McpToolResult")
+		setMsg(result, "success", success)
+		setMsg(mcpCall, "result", result)
+	}
+	return mcpCall
+}
+
+// encodeRunRequestWithCheckpoint builds an AgentClientMessage using a raw checkpoint
+// as conversation_state. The checkpoint bytes are embedded directly without deserialization.
+func encodeRunRequestWithCheckpoint(p *RunRequestParams) []byte {
+	// Build UserMessage
+	userMessage := encodeUserMessage(p.UserText, p.MessageId, p.Images)
 
 	// Build ConversationAction with UserMessageAction
 	uma := newMsg("UserMessageAction")
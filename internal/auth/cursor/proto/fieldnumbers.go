@@ -38,12 +38,19 @@ const (
 	CSS_TurnsOld               = 2  // repeated bytes (deprecated)
 	CSS_Todos                  = 3  // repeated bytes
 	CSS_PendingToolCalls       = 4  // repeated string
+	CSS_TokenDetails           = 5  // ConversationTokenDetails
 	CSS_Turns                  = 8  // repeated bytes (CURRENT field for turns)
 	CSS_PreviousWorkspaceUris  = 9  // repeated string
 	CSS_SelfSummaryCount       = 17 // uint32
 	CSS_ReadPaths              = 18 // repeated string
 )
 
+// ConversationTokenDetails
+const (
+	CTD_UsedTokens = 1 // uint32
+	CTD_MaxTokens  = 2 // uint32
+)
+
 // ConversationAction (msg 54) oneof "action"
 const (
 	CA_UserMessageAction = 1 // UserMessageAction
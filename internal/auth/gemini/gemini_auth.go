@@ -257,6 +257,7 @@ func (g *GeminiAuth) getTokenFromWeb(ctx context.Context, config *oauth2.Config,
 
 	// Open the authorization URL in the user's browser.
 	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{AuthURL: authURL})
 
 	noBrowser := false
 	if opts != nil {
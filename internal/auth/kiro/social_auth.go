@@ -22,6 +22,7 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/term"
@@ -371,6 +372,7 @@ func (c *SocialAuthClient) LoginWithSocial(ctx context.Context, provider SocialP
 	fmt.Printf("  Opening browser for %s authentication...\n", providerName)
 	fmt.Println("════════════════════════════════════════════════════════════")
 	fmt.Printf("\n  URL: %s\n\n", authURL)
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{AuthURL: authURL})
 
 	if err := browser.OpenURL(authURL); err != nil {
 		log.Warnf("Could not open browser automatically: %v", err)
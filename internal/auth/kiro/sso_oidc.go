@@ -21,6 +21,7 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	log "github.com/sirupsen/logrus"
 )
@@ -390,6 +391,7 @@ func (c *SSOOIDCClient) LoginWithIDC(ctx context.Context, startURL, region strin
 	fmt.Printf("  Code: %s\n", authResp.UserCode)
 	fmt.Println("════════════════════════════════════════════════════════════")
 	fmt.Printf("\n  Open this URL: %s\n\n", authResp.VerificationURIComplete)
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{VerificationURL: authResp.VerificationURIComplete, UserCode: authResp.UserCode})
 
 	// Set incognito mode based on config
 	if c.cfg != nil {
@@ -797,6 +799,7 @@ func (c *SSOOIDCClient) LoginWithBuilderID(ctx context.Context) (*KiroTokenData,
 	fmt.Println("════════════════════════════════════════════════════════════")
 	fmt.Printf("\n  Or go to: %s\n", authResp.VerificationURI)
 	fmt.Printf("  And enter code: %s\n\n", authResp.UserCode)
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{VerificationURL: authResp.VerificationURIComplete, UserCode: authResp.UserCode})
 
 	// Set incognito mode based on config (defaults to true for Kiro, can be overridden with --no-incognito)
 	// Incognito mode enables multi-account support by bypassing cached sessions
@@ -1408,6 +1411,7 @@ func (c *SSOOIDCClient) LoginWithBuilderIDAuthCode(ctx context.Context) (*KiroTo
 	fmt.Println("  Opening browser for authentication...")
 	fmt.Println("════════════════════════════════════════════════════════════")
 	fmt.Printf("\n  URL: %s\n\n", authURL)
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{AuthURL: authURL})
 
 	// Set incognito mode
 	if c.cfg != nil {
@@ -1520,6 +1524,7 @@ func (c *SSOOIDCClient) LoginWithIDCAuthCode(ctx context.Context, startURL, regi
 	fmt.Println("  Opening browser for authentication...")
 	fmt.Println("════════════════════════════════════════════════════════════")
 	fmt.Printf("\n  URL: %s\n\n", authURL)
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{AuthURL: authURL})
 
 	if c.cfg != nil {
 		browser.SetIncognitoMode(c.cfg.IncognitoBrowser)
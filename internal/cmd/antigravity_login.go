@@ -20,7 +20,7 @@ func DoAntigravityLogin(cfg *config.Config, options *LoginOptions) {
 		promptFn = defaultProjectPrompt()
 	}
 
-	manager := newAuthManager()
+	broker := newLoginBroker()
 	authOpts := &sdkAuth.LoginOptions{
 		NoBrowser:    options.NoBrowser,
 		CallbackPort: options.CallbackPort,
@@ -28,7 +28,7 @@ func DoAntigravityLogin(cfg *config.Config, options *LoginOptions) {
 		Prompt:       promptFn,
 	}
 
-	record, savedPath, err := manager.Login(context.Background(), "antigravity", cfg, authOpts)
+	record, savedPath, err := broker.Login(context.Background(), "antigravity", cfg, authOpts)
 	if err != nil {
 		log.Errorf("Antigravity authentication failed: %v", err)
 		return
@@ -0,0 +1,170 @@
+// Package cmd contains CLI helpers. This file implements importing and exporting
+// auth records as zip or JSONL bundles.
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// DoAuthBundleImport imports credentials from a bundle, a credential file of another
+// tool, or a directory of such files, then prints the import report. Accounts that
+// already exist under another file name are skipped.
+func DoAuthBundleImport(cfg *config.Config, source, passphrase string, dryRun bool) {
+	store := bundleTokenStore(cfg)
+	source = strings.TrimSpace(source)
+	if source == "" {
+		log.Errorf("auth-import: missing source path")
+		return
+	}
+	entries, err := readBundleSource(source, passphrase)
+	if err != nil {
+		log.Errorf("auth-import: %v", err)
+		return
+	}
+	if len(entries) == 0 {
+		log.Errorf("auth-import: no credentials found in %s", source)
+		return
+	}
+	ctx := context.Background()
+	existing, err := store.List(ctx)
+	if err != nil {
+		log.Errorf("auth-import: list existing auths: %v", err)
+		return
+	}
+
+	report := sdkAuth.PlanImport(entries, existing)
+	report.DryRun = dryRun
+	report.Apply(func(entry sdkAuth.BundleEntry) error {
+		metadata := make(map[string]any)
+		if errUnmarshal := json.Unmarshal(entry.Data, &metadata); errUnmarshal != nil {
+			return errUnmarshal
+		}
+		provider, _ := metadata["type"].(string)
+		_, errSave := store.Save(ctx, &coreauth.Auth{
+			ID:       entry.Name,
+			Provider: provider,
+			FileName: entry.Name,
+			Metadata: metadata,
+		})
+		return errSave
+	})
+	printImportReport(report)
+}
+
+// DoAuthBundleExport writes the auth files as a bundle. A target ending in .jsonl
+// produces JSON lines, anything else a zip archive.
+func DoAuthBundleExport(cfg *config.Config, target, passphrase string) {
+	bundleTokenStore(cfg)
+	target = strings.TrimSpace(target)
+	if target == "" {
+		log.Errorf("auth-export: missing target path")
+		return
+	}
+	format := sdkAuth.BundleFormatZip
+	if strings.HasSuffix(strings.ToLower(strings.TrimSuffix(target, ".enc")), ".jsonl") {
+		format = sdkAuth.BundleFormatJSONL
+	}
+	entries, err := sdkAuth.ReadAuthDirEntries(cfg.AuthDir, nil)
+	if err != nil {
+		log.Errorf("auth-export: %v", err)
+		return
+	}
+	bundle, err := sdkAuth.EncodeBundle(entries, format, passphrase)
+	if err != nil {
+		log.Errorf("auth-export: %v", err)
+		return
+	}
+	if err = os.WriteFile(target, bundle, 0o600); err != nil {
+		log.Errorf("auth-export: write bundle: %v", err)
+		return
+	}
+	encrypted := ""
+	if passphrase != "" {
+		encrypted = ", encrypted"
+	}
+	fmt.Printf("Exported %d auth file(s) to %s (%s%s)\n", len(entries), target, format, encrypted)
+}
+
+func bundleTokenStore(cfg *config.Config) coreauth.Store {
+	if resolved, errResolve := util.ResolveAuthDir(cfg.AuthDir); errResolve == nil {
+		cfg.AuthDir = resolved
+	}
+	store := sdkAuth.GetTokenStore()
+	if setter, ok := store.(interface{ SetBaseDir(string) }); ok {
+		setter.SetBaseDir(cfg.AuthDir)
+	}
+	return store
+}
+
+// readBundleSource decodes a bundle file, or converts every JSON file of a
+// directory such as ~/.aws/sso/cache together.
+func readBundleSource(source, passphrase string) ([]sdkAuth.BundleEntry, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		data, errRead := os.ReadFile(source)
+		if errRead != nil {
+			return nil, errRead
+		}
+		return sdkAuth.DecodeBundle(filepath.Base(source), data, passphrase)
+	}
+	dirEntries, err := os.ReadDir(source)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	order := make([]string, 0, len(dirEntries))
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(strings.ToLower(name), ".json") {
+			continue
+		}
+		data, errRead := os.ReadFile(filepath.Join(source, name))
+		if errRead != nil {
+			return nil, errRead
+		}
+		files[name] = data
+		order = append(order, name)
+	}
+	sort.Strings(order)
+	return sdkAuth.ConvertCredentialFiles(order, files)
+}
+
+func printImportReport(report *sdkAuth.ImportReport) {
+	for _, item := range report.Items {
+		line := fmt.Sprintf("  %-9s %s", item.Action, item.Name)
+		if item.Account != "" {
+			line += fmt.Sprintf(" [%s %s]", item.Provider, item.Account)
+		}
+		if item.Source != sdkAuth.CredentialSourceNative {
+			line += " from " + item.Source
+		}
+		if item.DuplicateOf != "" {
+			line += " (already present as " + item.DuplicateOf + ")"
+		}
+		if item.Error != "" {
+			line += ": " + item.Error
+		}
+		fmt.Println(line)
+	}
+	prefix := "Imported"
+	if report.DryRun {
+		prefix = "Dry run, would import"
+	}
+	fmt.Printf("%s: %d created, %d overwritten, %d duplicate(s) skipped, %d failed\n",
+		prefix, report.Created, report.Overwritten, report.Duplicates, report.Failed)
+}
@@ -11,20 +11,12 @@ import (
 //   - *sdkAuth.Manager: A configured authentication manager instance
 func newAuthManager() *sdkAuth.Manager {
 	store := sdkAuth.GetTokenStore()
-	manager := sdkAuth.NewManager(store,
-		sdkAuth.NewGeminiAuthenticator(),
-		sdkAuth.NewCodexAuthenticator(),
-		sdkAuth.NewClaudeAuthenticator(),
-		sdkAuth.NewQwenAuthenticator(),
-		sdkAuth.NewIFlowAuthenticator(),
-		sdkAuth.NewAntigravityAuthenticator(),
-		sdkAuth.NewKimiAuthenticator(),
-		sdkAuth.NewKiroAuthenticator(),
-		sdkAuth.NewGitHubCopilotAuthenticator(),
-		sdkAuth.NewKiloAuthenticator(),
-		sdkAuth.NewGitLabAuthenticator(),
-		sdkAuth.NewCodeBuddyAuthenticator(),
-		sdkAuth.NewCursorAuthenticator(),
-	)
+	manager := sdkAuth.NewManager(store, sdkAuth.DefaultAuthenticators()...)
 	return manager
 }
+
+// newLoginBroker wraps the authentication manager in a login broker so the CLI
+// login flags run the same sessions as the management login API.
+func newLoginBroker() *sdkAuth.LoginBroker {
+	return sdkAuth.NewLoginBroker(newAuthManager())
+}
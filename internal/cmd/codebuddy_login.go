@@ -21,13 +21,13 @@ func DoCodeBuddyLogin(cfg *config.Config, options *LoginOptions) {
 		options = &LoginOptions{}
 	}
 
-	manager := newAuthManager()
+	broker := newLoginBroker()
 	authOpts := &sdkAuth.LoginOptions{
 		NoBrowser: options.NoBrowser,
 		Metadata:  map[string]string{},
 	}
 
-	record, savedPath, err := manager.Login(context.Background(), "codebuddy", cfg, authOpts)
+	record, savedPath, err := broker.Login(context.Background(), "codebuddy", cfg, authOpts)
 	if err != nil {
 		log.Errorf("CodeBuddy authentication failed: %v", err)
 		return
@@ -14,14 +14,14 @@ func DoCursorLogin(cfg *config.Config, options *LoginOptions) {
 		options = &LoginOptions{}
 	}
 
-	manager := newAuthManager()
+	broker := newLoginBroker()
 	authOpts := &sdkAuth.LoginOptions{
 		NoBrowser: options.NoBrowser,
 		Metadata:  map[string]string{},
 		Prompt:    options.Prompt,
 	}
 
-	record, savedPath, err := manager.Login(context.Background(), "cursor", cfg, authOpts)
+	record, savedPath, err := broker.Login(context.Background(), "cursor", cfg, authOpts)
 	if err != nil {
 		log.Errorf("Cursor authentication failed: %v", err)
 		return
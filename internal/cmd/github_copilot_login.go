@@ -21,14 +21,14 @@ func DoGitHubCopilotLogin(cfg *config.Config, options *LoginOptions) {
 		options = &LoginOptions{}
 	}
 
-	manager := newAuthManager()
+	broker := newLoginBroker()
 	authOpts := &sdkAuth.LoginOptions{
 		NoBrowser: options.NoBrowser,
 		Metadata:  map[string]string{},
 		Prompt:    options.Prompt,
 	}
 
-	record, savedPath, err := manager.Login(context.Background(), "github-copilot", cfg, authOpts)
+	record, savedPath, err := broker.Login(context.Background(), "github-copilot", cfg, authOpts)
 	if err != nil {
 		log.Errorf("GitHub Copilot authentication failed: %v", err)
 		return
@@ -18,7 +18,7 @@ func DoGitLabLogin(cfg *config.Config, options *LoginOptions) {
 		promptFn = defaultProjectPrompt()
 	}
 
-	manager := newAuthManager()
+	broker := newLoginBroker()
 	authOpts := &sdkAuth.LoginOptions{
 		NoBrowser:    options.NoBrowser,
 		CallbackPort: options.CallbackPort,
@@ -28,7 +28,7 @@ func DoGitLabLogin(cfg *config.Config, options *LoginOptions) {
 		Prompt: promptFn,
 	}
 
-	_, savedPath, err := manager.Login(context.Background(), "gitlab", cfg, authOpts)
+	_, savedPath, err := broker.Login(context.Background(), "gitlab", cfg, authOpts)
 	if err != nil {
 		fmt.Printf("GitLab Duo authentication failed: %v\n", err)
 		return
@@ -49,7 +49,7 @@ func DoGitLabTokenLogin(cfg *config.Config, options *LoginOptions) {
 		promptFn = defaultProjectPrompt()
 	}
 
-	manager := newAuthManager()
+	broker := newLoginBroker()
 	authOpts := &sdkAuth.LoginOptions{
 		Metadata: map[string]string{
 			"login_mode": "pat",
@@ -57,7 +57,7 @@ func DoGitLabTokenLogin(cfg *config.Config, options *LoginOptions) {
 		Prompt: promptFn,
 	}
 
-	_, savedPath, err := manager.Login(context.Background(), "gitlab", cfg, authOpts)
+	_, savedPath, err := broker.Login(context.Background(), "gitlab", cfg, authOpts)
 	if err != nil {
 		fmt.Printf("GitLab Duo PAT authentication failed: %v\n", err)
 		return
@@ -16,7 +16,7 @@ func DoIFlowLogin(cfg *config.Config, options *LoginOptions) {
 		options = &LoginOptions{}
 	}
 
-	manager := newAuthManager()
+	broker := newLoginBroker()
 
 	promptFn := options.Prompt
 	if promptFn == nil {
@@ -30,7 +30,7 @@ func DoIFlowLogin(cfg *config.Config, options *LoginOptions) {
 		Prompt:       promptFn,
 	}
 
-	_, savedPath, err := manager.Login(context.Background(), "iflow", cfg, authOpts)
+	_, savedPath, err := broker.Login(context.Background(), "iflow", cfg, authOpts)
 	if err != nil {
 		if emailErr, ok := errors.AsType[*sdkAuth.EmailRequiredError](err); ok {
 			log.Error(emailErr.Error())
@@ -21,7 +21,7 @@ func DoKiloLogin(cfg *config.Config, options *LoginOptions) {
 		options = &LoginOptions{}
 	}
 
-	manager := newAuthManager()
+	broker := newLoginBroker()
 
 	promptFn := options.Prompt
 	if promptFn == nil {
@@ -40,7 +40,7 @@ func DoKiloLogin(cfg *config.Config, options *LoginOptions) {
 		Prompt:       promptFn,
 	}
 
-	_, savedPath, err := manager.Login(context.Background(), "kilo", cfg, authOpts)
+	_, savedPath, err := broker.Login(context.Background(), "kilo", cfg, authOpts)
 	if err != nil {
 		fmt.Printf("Kilo authentication failed: %v\n", err)
 		return
@@ -29,7 +29,7 @@ func DoCodexDeviceLogin(cfg *config.Config, options *LoginOptions) {
 		promptFn = defaultProjectPrompt()
 	}
 
-	manager := newAuthManager()
+	broker := newLoginBroker()
 
 	authOpts := &sdkAuth.LoginOptions{
 		NoBrowser:    options.NoBrowser,
@@ -40,7 +40,7 @@ func DoCodexDeviceLogin(cfg *config.Config, options *LoginOptions) {
 		Prompt: promptFn,
 	}
 
-	_, savedPath, err := manager.Login(context.Background(), "codex", cfg, authOpts)
+	_, savedPath, err := broker.Login(context.Background(), "codex", cfg, authOpts)
 	if err != nil {
 		if authErr, ok := errors.AsType[*codex.AuthenticationError](err); ok {
 			log.Error(codex.GetUserFriendlyMessage(authErr))
@@ -22,7 +22,7 @@ func DoQwenLogin(cfg *config.Config, options *LoginOptions) {
 		options = &LoginOptions{}
 	}
 
-	manager := newAuthManager()
+	broker := newLoginBroker()
 
 	promptFn := options.Prompt
 	if promptFn == nil {
@@ -42,7 +42,7 @@ func DoQwenLogin(cfg *config.Config, options *LoginOptions) {
 		Prompt:       promptFn,
 	}
 
-	_, savedPath, err := manager.Login(context.Background(), "qwen", cfg, authOpts)
+	_, savedPath, err := broker.Login(context.Background(), "qwen", cfg, authOpts)
 	if err != nil {
 		if emailErr, ok := errors.AsType[*sdkAuth.EmailRequiredError](err); ok {
 			log.Error(emailErr.Error())
@@ -0,0 +1,49 @@
+// Package cmd contains CLI helpers. This file implements migrating auth files into
+// and out of the SQLite-backed store.
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// DoSQLiteImport migrates an existing auth directory (and its config.yaml, if any)
+// into the SQLite store.
+func DoSQLiteImport(s *store.SQLiteStore, dir string) {
+	if s == nil {
+		log.Errorf("sqlite-import: SQLite store is not enabled; set SQLITESTORE_PATH")
+		return
+	}
+	if strings.TrimSpace(dir) == "" {
+		log.Errorf("sqlite-import: missing source directory")
+		return
+	}
+	count, err := s.ImportDirectory(context.Background(), dir)
+	if err != nil {
+		log.Errorf("sqlite-import: imported %d auth file(s) before failing: %v", count, err)
+		return
+	}
+	log.Infof("sqlite-import: imported %d auth file(s) from %s", count, dir)
+}
+
+// DoSQLiteExport writes the auth records and config held by the SQLite store into a
+// directory usable by the file-based store.
+func DoSQLiteExport(s *store.SQLiteStore, dir string) {
+	if s == nil {
+		log.Errorf("sqlite-export: SQLite store is not enabled; set SQLITESTORE_PATH")
+		return
+	}
+	if strings.TrimSpace(dir) == "" {
+		log.Errorf("sqlite-export: missing target directory")
+		return
+	}
+	count, err := s.ExportDirectory(context.Background(), dir)
+	if err != nil {
+		log.Errorf("sqlite-export: exported %d auth file(s) before failing: %v", count, err)
+		return
+	}
+	log.Infof("sqlite-export: exported %d auth file(s) to %s", count, dir)
+}
@@ -0,0 +1,42 @@
+// Package cmd contains CLI helpers. This file implements the --validate-config mode
+// used to check a config file in CI without starting the server.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// DoValidateConfig validates the config file at path, prints every issue prefixed
+// with the file name and returns the process exit code: 0 when the config has no
+// errors (warnings allowed), 1 otherwise.
+func DoValidateConfig(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return 1
+	}
+	cfg, issues := config.ValidateConfig(data)
+	errorCount := 0
+	for _, issue := range issues {
+		if issue.Severity == config.SeverityError {
+			errorCount++
+		}
+		if issue.Line > 0 {
+			fmt.Printf("%s:%s\n", path, issue)
+		} else {
+			fmt.Printf("%s: %s\n", path, issue)
+		}
+	}
+	fmt.Printf("%s: %d error(s), %d warning(s)", path, errorCount, len(issues)-errorCount)
+	if cfg != nil {
+		fmt.Printf(", %d configured model(s)", len(config.ConfiguredModels(cfg)))
+	}
+	fmt.Println()
+	if errorCount > 0 {
+		return 1
+	}
+	return 0
+}
@@ -138,11 +138,37 @@ type Config struct {
 	// Payload defines default and override rules for provider payload parameters.
 	Payload PayloadConfig `yaml:"payload" json:"payload"`
 
+	// CursorSessions controls persistence of Cursor conversation checkpoints across restarts.
+	CursorSessions CursorSessionsConfig `yaml:"cursor-sessions,omitempty" json:"cursor-sessions,omitempty"`
+
+	// QuotaPolling controls background quota probing for OAuth credentials.
+	QuotaPolling QuotaPollingConfig `yaml:"quota-polling,omitempty" json:"quota-polling,omitempty"`
+
+	// HealthChecks controls scheduled health checks and quarantine for OAuth credentials.
+	HealthChecks HealthChecksConfig `yaml:"health-checks,omitempty" json:"health-checks,omitempty"`
+
+	// Notifications configures outbound webhook, Slack and email alerts for operational events.
+	Notifications NotificationsConfig `yaml:"notifications,omitempty" json:"notifications,omitempty"`
+
+	// ToolEmulation opts executors without native function calling into prompt-based
+	// tool calling per provider and model.
+	ToolEmulation []ToolEmulationRule `yaml:"tool-emulation,omitempty" json:"tool-emulation,omitempty"`
+
+	// TrafficRouting splits requests for a model alias between providers by weight and
+	// mirrors samples to candidate providers.
+	TrafficRouting []TrafficRoute `yaml:"traffic-routing,omitempty" json:"traffic-routing,omitempty"`
+
 	// IncognitoBrowser enables opening OAuth URLs in incognito/private browsing mode.
 	// This is useful when you want to login with a different account without logging out
 	// from your current session. Default: false.
 	IncognitoBrowser bool `yaml:"incognito-browser" json:"incognito-browser"`
 
+	// Plugins declares out-of-process plugins supervised by the service.
+	Plugins []PluginConfig `yaml:"plugins,omitempty" json:"plugins,omitempty"`
+
+	// ConfigHistory configures the version history kept for config writes.
+	ConfigHistory ConfigHistoryConfig `yaml:"config-history,omitempty" json:"config-history,omitempty"`
+
 	legacyMigrationPending bool `yaml:"-" json:"-"`
 }
 
@@ -303,12 +329,22 @@ type PayloadConfig struct {
 	OverrideRaw []PayloadRule `yaml:"override-raw" json:"override-raw"`
 	// Filter defines rules that remove parameters from the payload by JSON path.
 	Filter []PayloadFilterRule `yaml:"filter" json:"filter"`
+	// Rename defines rules that move a parameter to a new JSON path.
+	Rename []PayloadRenameRule `yaml:"rename,omitempty" json:"rename,omitempty"`
+	// Append defines rules that append values to array parameters, creating the array when missing.
+	Append []PayloadRule `yaml:"append,omitempty" json:"append,omitempty"`
+	// DeleteItems defines rules that remove array items matching a predicate.
+	DeleteItems []PayloadDeleteItemsRule `yaml:"delete-items,omitempty" json:"delete-items,omitempty"`
+	// Clamp defines rules that keep numeric parameters within bounds.
+	Clamp []PayloadClampRule `yaml:"clamp,omitempty" json:"clamp,omitempty"`
 }
 
 // PayloadFilterRule describes a rule to remove specific JSON paths from matching model payloads.
 type PayloadFilterRule struct {
 	// Models lists model entries with name pattern and protocol constraint.
 	Models []PayloadModelRule `yaml:"models" json:"models"`
+	// When optionally restricts the rule to requests matching these conditions.
+	When *PayloadCondition `yaml:"when,omitempty" json:"when,omitempty"`
 	// Params lists JSON paths (gjson/sjson syntax) to remove from the payload.
 	Params []string `yaml:"params" json:"params"`
 }
@@ -317,6 +353,8 @@ type PayloadFilterRule struct {
 type PayloadRule struct {
 	// Models lists model entries with name pattern and protocol constraint.
 	Models []PayloadModelRule `yaml:"models" json:"models"`
+	// When optionally restricts the rule to requests matching these conditions.
+	When *PayloadCondition `yaml:"when,omitempty" json:"when,omitempty"`
 	// Params maps JSON paths (gjson/sjson syntax) to values written into the payload.
 	// For *-raw rules, values are treated as raw JSON fragments (strings are used as-is).
 	Params map[string]any `yaml:"params" json:"params"`
@@ -738,6 +776,57 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 	// Validate raw payload rules and drop invalid entries.
 	cfg.SanitizePayloadRules()
 
+	// Apply defaults to Cursor session persistence.
+	cfg.SanitizeCursorSessions()
+
+	// Apply defaults to quota polling.
+	cfg.SanitizeQuotaPolling()
+
+	// Apply defaults to credential health checks.
+	cfg.SanitizeHealthChecks()
+
+	// Apply notification defaults and drop incomplete sinks.
+	cfg.SanitizeNotifications()
+
+	// Normalize tool emulation rules and drop entries without a provider.
+	cfg.SanitizeToolEmulation()
+
+	// Normalize traffic routes and drop entries that cannot apply.
+	cfg.SanitizeTrafficRouting()
+
+	// Normalize guardrail rules and drop entries with invalid patterns.
+	cfg.SanitizeGuardrails()
+
+	// Normalize system prompt policies and drop entries that cannot apply.
+	cfg.SanitizeSystemPrompts()
+
+	// Apply context window defaults and drop rules without models.
+	cfg.SanitizeContextWindow()
+
+	// Normalize tool call repair settings.
+	cfg.SanitizeToolCallRepair()
+
+	// Normalize structured output enforcement settings.
+	cfg.SanitizeStructuredOutput()
+
+	// Normalize the token counting mode.
+	cfg.SanitizeTokenCounting()
+
+	// Normalize plugin declarations and drop unusable entries.
+	cfg.SanitizePlugins()
+
+	// Normalize OIDC JWT access providers and drop incomplete entries.
+	cfg.SanitizeOIDCJWT()
+
+	// Normalize tenant declarations and resolve conflicting key assignments.
+	cfg.SanitizeTenants()
+
+	// Apply transcript archive defaults.
+	cfg.SanitizeTranscripts()
+
+	// Normalize config history retention settings.
+	cfg.SanitizeConfigHistory()
+
 	// NOTE: Legacy migration persistence is intentionally disabled together with
 	// startup legacy migration to keep startup read-only for config.yaml.
 	// Re-enable the block below if automatic startup migration is needed again.
@@ -0,0 +1,38 @@
+package config
+
+import "strings"
+
+// DefaultConfigHistoryMaxVersions is the number of config versions kept when unset.
+const DefaultConfigHistoryMaxVersions = 100
+
+// ConfigHistoryConfig controls the version history recorded for every config write.
+type ConfigHistoryConfig struct {
+	// Dir holds recorded versions when the token store does not keep them. When
+	// empty, a "config-history" directory under WRITABLE_PATH, or next to the
+	// config file, is used.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// MaxVersions bounds how many versions are retained. Zero selects
+	// DefaultConfigHistoryMaxVersions.
+	MaxVersions int `yaml:"max-versions,omitempty" json:"max-versions,omitempty"`
+}
+
+// Limit returns the effective retention limit.
+func (c ConfigHistoryConfig) Limit() int {
+	if c.MaxVersions <= 0 {
+		return DefaultConfigHistoryMaxVersions
+	}
+	return c.MaxVersions
+}
+
+// SanitizeConfigHistory normalizes the config history settings. Defaults are resolved
+// through Limit so they are not written back into config.yaml.
+func (cfg *Config) SanitizeConfigHistory() {
+	if cfg == nil {
+		return
+	}
+	cfg.ConfigHistory.Dir = strings.TrimSpace(cfg.ConfigHistory.Dir)
+	if cfg.ConfigHistory.MaxVersions < 0 {
+		cfg.ConfigHistory.MaxVersions = 0
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Context window strategies supported by ContextWindowRule.Strategies.
+const (
+	ContextStrategyTrimToolOutputs = "trim-tool-outputs"
+	ContextStrategySummarize       = "summarize"
+	ContextStrategyDropOldest      = "drop-oldest"
+)
+
+const (
+	// DefaultContextKeepRecentTurns is the number of latest turns never dropped or summarized.
+	DefaultContextKeepRecentTurns = 2
+	// DefaultContextToolOutputMaxChars is the length tool outputs are trimmed to.
+	DefaultContextToolOutputMaxChars = 2000
+)
+
+// ContextWindowRule shrinks oversized conversations for matching models before they
+// are sent upstream. Rules are opt-in; the first rule whose models match is used.
+type ContextWindowRule struct {
+	// Models lists model name patterns the rule applies to; supports "*" wildcards.
+	Models []string `yaml:"models" json:"models"`
+
+	// Strategies are tried in order until the request fits:
+	// "trim-tool-outputs", "summarize" and "drop-oldest".
+	// Defaults to trim-tool-outputs followed by drop-oldest.
+	Strategies []string `yaml:"strategies,omitempty" json:"strategies,omitempty"`
+
+	// MaxInputTokens overrides the model's input limit from the registry.
+	MaxInputTokens int `yaml:"max-input-tokens,omitempty" json:"max-input-tokens,omitempty"`
+
+	// ReserveTokens is kept free below the limit for the model's output.
+	ReserveTokens int `yaml:"reserve-tokens,omitempty" json:"reserve-tokens,omitempty"`
+
+	// KeepRecentTurns is the number of latest turns that are never dropped, trimmed or summarized.
+	KeepRecentTurns int `yaml:"keep-recent-turns,omitempty" json:"keep-recent-turns,omitempty"`
+
+	// ToolOutputMaxChars is the length older tool outputs are trimmed to.
+	ToolOutputMaxChars int `yaml:"tool-output-max-chars,omitempty" json:"tool-output-max-chars,omitempty"`
+
+	// SummaryModel is the model asked to summarize older turns for the "summarize" strategy.
+	SummaryModel string `yaml:"summary-model,omitempty" json:"summary-model,omitempty"`
+}
+
+// SanitizeContextWindow normalizes context window rules and drops unusable entries.
+func (cfg *Config) SanitizeContextWindow() {
+	if cfg == nil || len(cfg.ContextWindow) == 0 {
+		return
+	}
+	out := make([]ContextWindowRule, 0, len(cfg.ContextWindow))
+	for i := range cfg.ContextWindow {
+		rule := cfg.ContextWindow[i]
+		rule.Models = normalizeGuardrailList(rule.Models, false)
+		if len(rule.Models) == 0 {
+			log.WithField("rule_index", i+1).Warn("context window rule dropped: no models")
+			continue
+		}
+		rule.SummaryModel = strings.TrimSpace(rule.SummaryModel)
+		strategies := normalizeGuardrailList(rule.Strategies, true)
+		rule.Strategies = make([]string, 0, len(strategies))
+		for _, strategy := range strategies {
+			switch strategy {
+			case ContextStrategyTrimToolOutputs, ContextStrategyDropOldest:
+			case ContextStrategySummarize:
+				if rule.SummaryModel == "" {
+					log.WithField("rule_index", i+1).Warn("context window: summarize ignored without summary-model")
+					continue
+				}
+			default:
+				log.WithField("rule_index", i+1).Warnf("context window: ignoring unknown strategy %q", strategy)
+				continue
+			}
+			rule.Strategies = append(rule.Strategies, strategy)
+		}
+		if len(rule.Strategies) == 0 {
+			rule.Strategies = []string{ContextStrategyTrimToolOutputs, ContextStrategyDropOldest}
+		}
+		if rule.MaxInputTokens < 0 {
+			rule.MaxInputTokens = 0
+		}
+		if rule.ReserveTokens < 0 {
+			rule.ReserveTokens = 0
+		}
+		if rule.KeepRecentTurns <= 0 {
+			rule.KeepRecentTurns = DefaultContextKeepRecentTurns
+		}
+		if rule.ToolOutputMaxChars <= 0 {
+			rule.ToolOutputMaxChars = DefaultContextToolOutputMaxChars
+		}
+		out = append(out, rule)
+	}
+	cfg.ContextWindow = out
+}
@@ -0,0 +1,37 @@
+package config
+
+import "strings"
+
+// DefaultCursorSessionTTLHours is how long persisted Cursor sessions are kept.
+const DefaultCursorSessionTTLHours = 24
+
+// CursorSessionsConfig controls persistence of Cursor conversation checkpoints so
+// conversations resume with the server-side state after a proxy restart, and how
+// history is sent when no checkpoint can be used.
+type CursorSessionsConfig struct {
+	// Persist writes conversation checkpoints to disk.
+	Persist bool `yaml:"persist" json:"persist"`
+
+	// Dir is the directory holding persisted sessions. When empty, a "cursor-sessions"
+	// directory under WRITABLE_PATH (or the working directory) is used.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// TTLHours is how long a persisted session is kept after its last update. Defaults to 24.
+	TTLHours int `yaml:"ttl-hours,omitempty" json:"ttl-hours,omitempty"`
+
+	// FlattenHistory sends the history of conversations without a usable checkpoint
+	// as plain text in the user message instead of as structured turns.
+	FlattenHistory bool `yaml:"flatten-history,omitempty" json:"flatten-history,omitempty"`
+}
+
+// SanitizeCursorSessions applies defaults to the Cursor session settings.
+func (cfg *Config) SanitizeCursorSessions() {
+	if cfg == nil {
+		return
+	}
+	s := &cfg.CursorSessions
+	s.Dir = strings.TrimSpace(s.Dir)
+	if s.TTLHours <= 0 {
+		s.TTLHours = DefaultCursorSessionTTLHours
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Guardrail actions supported by GuardrailRule.Action.
+const (
+	GuardrailActionBlock  = "block"
+	GuardrailActionRedact = "redact"
+	GuardrailActionFlag   = "flag"
+)
+
+// Guardrail directions supported by GuardrailRule.Direction.
+const (
+	GuardrailDirectionRequest  = "request"
+	GuardrailDirectionResponse = "response"
+	GuardrailDirectionBoth     = "both"
+)
+
+// GuardrailsConfig configures content inspection for inbound prompts and outbound completions.
+type GuardrailsConfig struct {
+	// Enabled toggles guardrail evaluation. Rules are ignored while disabled.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Rules lists the guardrail rules evaluated in declaration order.
+	Rules []GuardrailRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// GuardrailRule describes a set of detectors and the action taken when any of them matches.
+type GuardrailRule struct {
+	// Name identifies the rule in audit logs and error messages.
+	Name string `yaml:"name" json:"name"`
+
+	// Direction selects which side of the exchange is inspected.
+	// Supported values: "request", "response", "both" (default).
+	Direction string `yaml:"direction,omitempty" json:"direction,omitempty"`
+
+	// Detectors lists built-in detectors to enable for this rule.
+	// Supported values: "credit-card", "iban", "cloud-key", "email".
+	Detectors []string `yaml:"detectors,omitempty" json:"detectors,omitempty"`
+
+	// Patterns lists additional regular expressions matched against text content.
+	Patterns []string `yaml:"patterns,omitempty" json:"patterns,omitempty"`
+
+	// Keywords lists case-insensitive literal terms matched against text content.
+	Keywords []string `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+
+	// Action controls what happens on a match: "block", "redact" or "flag" (default).
+	Action string `yaml:"action,omitempty" json:"action,omitempty"`
+
+	// Replacement is the text substituted for redacted matches.
+	// Defaults to "[REDACTED:<detector>]" when empty.
+	Replacement string `yaml:"replacement,omitempty" json:"replacement,omitempty"`
+
+	// APIKeys restricts the rule to the listed client API keys. Empty applies to all clients.
+	APIKeys []string `yaml:"api-keys,omitempty" json:"api-keys,omitempty"`
+
+	// Models restricts the rule to matching model names; supports "*" wildcards.
+	// Empty applies to all models.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+}
+
+// SanitizeGuardrails normalizes guardrail rules and drops entries that cannot be evaluated.
+func (cfg *Config) SanitizeGuardrails() {
+	if cfg == nil || len(cfg.Guardrails.Rules) == 0 {
+		return
+	}
+	out := make([]GuardrailRule, 0, len(cfg.Guardrails.Rules))
+	for i := range cfg.Guardrails.Rules {
+		rule := cfg.Guardrails.Rules[i]
+		rule.Name = strings.TrimSpace(rule.Name)
+		rule.Direction = strings.ToLower(strings.TrimSpace(rule.Direction))
+		switch rule.Direction {
+		case GuardrailDirectionRequest, GuardrailDirectionResponse, GuardrailDirectionBoth:
+		default:
+			rule.Direction = GuardrailDirectionBoth
+		}
+		rule.Action = strings.ToLower(strings.TrimSpace(rule.Action))
+		switch rule.Action {
+		case GuardrailActionBlock, GuardrailActionRedact, GuardrailActionFlag:
+		default:
+			rule.Action = GuardrailActionFlag
+		}
+		rule.Detectors = normalizeGuardrailList(rule.Detectors, true)
+		rule.Keywords = normalizeGuardrailList(rule.Keywords, false)
+		rule.APIKeys = normalizeGuardrailList(rule.APIKeys, false)
+		rule.Models = normalizeGuardrailList(rule.Models, false)
+
+		patterns := make([]string, 0, len(rule.Patterns))
+		for _, pattern := range rule.Patterns {
+			if strings.TrimSpace(pattern) == "" {
+				continue
+			}
+			if _, errCompile := regexp.Compile(pattern); errCompile != nil {
+				log.WithFields(log.Fields{
+					"rule_index": i + 1,
+					"pattern":    pattern,
+				}).Warnf("guardrail pattern dropped: %v", errCompile)
+				continue
+			}
+			patterns = append(patterns, pattern)
+		}
+		rule.Patterns = patterns
+
+		if len(rule.Detectors) == 0 && len(rule.Patterns) == 0 && len(rule.Keywords) == 0 {
+			continue
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		out = append(out, rule)
+	}
+	cfg.Guardrails.Rules = out
+}
+
+func normalizeGuardrailList(values []string, lower bool) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(values))
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		trimmed := strings.TrimSpace(value)
+		if lower {
+			trimmed = strings.ToLower(trimmed)
+		}
+		if trimmed == "" {
+			continue
+		}
+		if _, exists := seen[trimmed]; exists {
+			continue
+		}
+		seen[trimmed] = struct{}{}
+		out = append(out, trimmed)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+package config
+
+// DefaultHealthCheckIntervalSeconds is how often each credential is health-checked.
+const DefaultHealthCheckIntervalSeconds = 3600
+
+// DefaultHealthCheckFailureThreshold is how many consecutive dead results quarantine a credential.
+const DefaultHealthCheckFailureThreshold = 2
+
+// HealthChecksConfig controls the scheduled credential health checker, which
+// probes OAuth credentials and quarantines the ones that can no longer serve.
+type HealthChecksConfig struct {
+	// Enabled turns on scheduled health checks.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// IntervalSeconds is the delay between checks of the same credential. Defaults to 3600.
+	IntervalSeconds int `yaml:"interval-seconds,omitempty" json:"interval-seconds,omitempty"`
+
+	// FailureThreshold is the number of consecutive needs-relogin or suspended
+	// results before a credential is quarantined. Defaults to 2.
+	FailureThreshold int `yaml:"failure-threshold,omitempty" json:"failure-threshold,omitempty"`
+
+	// ReportOnly classifies credentials without quarantining them.
+	ReportOnly bool `yaml:"report-only,omitempty" json:"report-only,omitempty"`
+}
+
+// SanitizeHealthChecks applies defaults to the health check settings.
+func (cfg *Config) SanitizeHealthChecks() {
+	if cfg == nil {
+		return
+	}
+	h := &cfg.HealthChecks
+	if h.IntervalSeconds <= 0 {
+		h.IntervalSeconds = DefaultHealthCheckIntervalSeconds
+	}
+	if h.FailureThreshold <= 0 {
+		h.FailureThreshold = DefaultHealthCheckFailureThreshold
+	}
+}
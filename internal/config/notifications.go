@@ -0,0 +1,197 @@
+package config
+
+import "strings"
+
+// Notification defaults applied by SanitizeNotifications.
+const (
+	DefaultNotificationThrottleSeconds = 900
+	DefaultErrorRateWindowSeconds      = 300
+	DefaultErrorRateMinRequests        = 20
+	DefaultErrorRateThresholdPercent   = 50
+	DefaultSMTPPort                    = 587
+)
+
+// Notification sink types.
+const (
+	NotificationSinkWebhook = "webhook"
+	NotificationSinkSlack   = "slack"
+	NotificationSinkEmail   = "email"
+)
+
+// NotificationsConfig configures outbound notifications for operational events
+// such as dead credentials, exhausted provider pools and reload failures.
+type NotificationsConfig struct {
+	// Enabled turns on event delivery.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// ThrottleSeconds suppresses repeats of the same event for this long. Defaults to 900.
+	ThrottleSeconds int `yaml:"throttle-seconds,omitempty" json:"throttle-seconds,omitempty"`
+
+	// Sinks lists the destinations events are delivered to.
+	Sinks []NotificationSink `yaml:"sinks,omitempty" json:"sinks,omitempty"`
+
+	// Events overrides delivery per event type, keyed by event name.
+	Events map[string]NotificationEventConfig `yaml:"events,omitempty" json:"events,omitempty"`
+
+	// ErrorRate configures the per-model error-rate spike detector.
+	ErrorRate ErrorRateAlertConfig `yaml:"error-rate,omitempty" json:"error-rate,omitempty"`
+
+	// UsageBudgets raises an event when usage crosses a share of a budget.
+	UsageBudgets []UsageBudget `yaml:"usage-budgets,omitempty" json:"usage-budgets,omitempty"`
+}
+
+// NotificationSink is a single delivery destination.
+type NotificationSink struct {
+	// Name identifies the sink in logs and the test-send endpoint.
+	Name string `yaml:"name" json:"name"`
+
+	// Type is one of "webhook", "slack" or "email".
+	Type string `yaml:"type" json:"type"`
+
+	// URL is the webhook or Slack incoming-webhook URL.
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+
+	// Headers are added to webhook requests.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// Events restricts the sink to matching event names; "*" matches any characters.
+	// Empty delivers every event.
+	Events []string `yaml:"events,omitempty" json:"events,omitempty"`
+
+	// Template is a Go text/template rendering the message text. For webhooks it
+	// replaces the JSON body.
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
+
+	// Subject is a Go text/template rendering the email subject.
+	Subject string `yaml:"subject,omitempty" json:"subject,omitempty"`
+
+	// SMTP settings for email sinks.
+	SMTPHost string   `yaml:"smtp-host,omitempty" json:"smtp-host,omitempty"`
+	SMTPPort int      `yaml:"smtp-port,omitempty" json:"smtp-port,omitempty"`
+	Username string   `yaml:"username,omitempty" json:"username,omitempty"`
+	Password string   `yaml:"password,omitempty" json:"password,omitempty"`
+	From     string   `yaml:"from,omitempty" json:"from,omitempty"`
+	To       []string `yaml:"to,omitempty" json:"to,omitempty"`
+}
+
+// NotificationEventConfig overrides delivery of one event type.
+type NotificationEventConfig struct {
+	// Disabled drops the event.
+	Disabled bool `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+
+	// ThrottleSeconds replaces the global throttle window for the event.
+	ThrottleSeconds int `yaml:"throttle-seconds,omitempty" json:"throttle-seconds,omitempty"`
+}
+
+// ErrorRateAlertConfig configures the per-model error-rate spike detector.
+type ErrorRateAlertConfig struct {
+	// WindowSeconds is the sliding window requests are counted over. Defaults to 300.
+	WindowSeconds int `yaml:"window-seconds,omitempty" json:"window-seconds,omitempty"`
+
+	// MinRequests is the request count a window needs before it is judged. Defaults to 20.
+	MinRequests int `yaml:"min-requests,omitempty" json:"min-requests,omitempty"`
+
+	// ThresholdPercent is the failure share that counts as a spike. Defaults to 50.
+	ThresholdPercent float64 `yaml:"threshold-percent,omitempty" json:"threshold-percent,omitempty"`
+}
+
+// UsageBudget is a token or request allowance tracked per calendar period. Its
+// counters are persisted with the usage counters of the credentials that served it.
+type UsageBudget struct {
+	// Name identifies the budget in events.
+	Name string `yaml:"name" json:"name"`
+
+	// Period is "day" or "month", in server local time. Defaults to "day".
+	Period string `yaml:"period,omitempty" json:"period,omitempty"`
+
+	// APIKey and Model restrict the budget to matching usage. Empty matches all.
+	APIKey string `yaml:"api-key,omitempty" json:"api-key,omitempty"`
+	Model  string `yaml:"model,omitempty" json:"model,omitempty"`
+
+	// MaxTokens and MaxRequests are the budget; zero leaves that dimension untracked.
+	MaxTokens   int64 `yaml:"max-tokens,omitempty" json:"max-tokens,omitempty"`
+	MaxRequests int64 `yaml:"max-requests,omitempty" json:"max-requests,omitempty"`
+
+	// Thresholds are the budget shares, in percent, that raise an event. Defaults to [80, 100].
+	Thresholds []float64 `yaml:"thresholds,omitempty" json:"thresholds,omitempty"`
+}
+
+// SanitizeNotifications applies defaults and drops unusable sinks and budgets.
+func (cfg *Config) SanitizeNotifications() {
+	if cfg == nil {
+		return
+	}
+	n := &cfg.Notifications
+	if n.ThrottleSeconds <= 0 {
+		n.ThrottleSeconds = DefaultNotificationThrottleSeconds
+	}
+	sinks := make([]NotificationSink, 0, len(n.Sinks))
+	for i := range n.Sinks {
+		sink := n.Sinks[i]
+		sink.Name = strings.TrimSpace(sink.Name)
+		sink.Type = strings.ToLower(strings.TrimSpace(sink.Type))
+		sink.URL = strings.TrimSpace(sink.URL)
+		sink.SMTPHost = strings.TrimSpace(sink.SMTPHost)
+		sink.From = strings.TrimSpace(sink.From)
+		to := make([]string, 0, len(sink.To))
+		for _, addr := range sink.To {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				to = append(to, addr)
+			}
+		}
+		sink.To = to
+		switch sink.Type {
+		case NotificationSinkWebhook, NotificationSinkSlack:
+			if sink.URL == "" {
+				continue
+			}
+		case NotificationSinkEmail:
+			if sink.SMTPHost == "" || sink.From == "" || len(sink.To) == 0 {
+				continue
+			}
+			if sink.SMTPPort <= 0 {
+				sink.SMTPPort = DefaultSMTPPort
+			}
+		default:
+			continue
+		}
+		if sink.Name == "" {
+			sink.Name = sink.Type
+		}
+		sinks = append(sinks, sink)
+	}
+	n.Sinks = sinks
+
+	r := &n.ErrorRate
+	if r.WindowSeconds <= 0 {
+		r.WindowSeconds = DefaultErrorRateWindowSeconds
+	}
+	if r.MinRequests <= 0 {
+		r.MinRequests = DefaultErrorRateMinRequests
+	}
+	if r.ThresholdPercent <= 0 || r.ThresholdPercent > 100 {
+		r.ThresholdPercent = DefaultErrorRateThresholdPercent
+	}
+
+	budgets := make([]UsageBudget, 0, len(n.UsageBudgets))
+	for _, budget := range n.UsageBudgets {
+		budget.Name = strings.TrimSpace(budget.Name)
+		budget.Period = strings.ToLower(strings.TrimSpace(budget.Period))
+		if budget.Period != "month" {
+			budget.Period = "day"
+		}
+		budget.APIKey = strings.TrimSpace(budget.APIKey)
+		budget.Model = strings.TrimSpace(budget.Model)
+		if budget.MaxTokens <= 0 && budget.MaxRequests <= 0 {
+			continue
+		}
+		if len(budget.Thresholds) == 0 {
+			budget.Thresholds = []float64{80, 100}
+		}
+		if budget.Name == "" {
+			budget.Name = budget.Period + "-budget"
+		}
+		budgets = append(budgets, budget)
+	}
+	n.UsageBudgets = budgets
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// OIDC JWT access defaults applied by SanitizeOIDCJWT.
+const (
+	DefaultOIDCJWTName             = "oidc-jwt"
+	DefaultOIDCJWTPrincipalClaim   = "sub"
+	DefaultOIDCJWTEmailClaim       = "email"
+	DefaultOIDCJWTGroupsClaim      = "groups"
+	DefaultOIDCJWTClockSkew        = 60
+	DefaultOIDCJWTJWKSCacheSeconds = 3600
+)
+
+// OIDCJWTConfig configures an access provider that accepts bearer JWTs issued
+// by an OpenID Connect identity provider.
+type OIDCJWTConfig struct {
+	// Name identifies the provider instance. Defaults to "oidc-jwt".
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Issuer is the expected "iss" claim.
+	Issuer string `yaml:"issuer" json:"issuer"`
+
+	// JWKSURL points at the issuer's JSON Web Key Set. When empty it is
+	// discovered from "<issuer>/.well-known/openid-configuration".
+	JWKSURL string `yaml:"jwks-url,omitempty" json:"jwks-url,omitempty"`
+
+	// Audiences lists accepted "aud" values; a token must carry at least one of them.
+	Audiences []string `yaml:"audiences" json:"audiences"`
+
+	// PrincipalClaim selects the claim used as the request principal. Defaults to "sub".
+	// The principal is what usage statistics and per-key policies see as the client key.
+	PrincipalClaim string `yaml:"principal-claim,omitempty" json:"principal-claim,omitempty"`
+
+	// EmailClaim selects the claim exposed as "email" metadata. Defaults to "email".
+	EmailClaim string `yaml:"email-claim,omitempty" json:"email-claim,omitempty"`
+
+	// GroupsClaim selects the claim exposed as comma-separated "groups" metadata. Defaults to "groups".
+	GroupsClaim string `yaml:"groups-claim,omitempty" json:"groups-claim,omitempty"`
+
+	// ClockSkewSeconds is the tolerance applied to "exp" and "nbf". Defaults to 60.
+	ClockSkewSeconds int `yaml:"clock-skew-seconds,omitempty" json:"clock-skew-seconds,omitempty"`
+
+	// JWKSCacheSeconds controls how long fetched keys are trusted before the set
+	// is refreshed. Unknown key IDs trigger an earlier refresh. Defaults to 3600.
+	JWKSCacheSeconds int `yaml:"jwks-cache-seconds,omitempty" json:"jwks-cache-seconds,omitempty"`
+}
+
+// SanitizeOIDCJWT trims OIDC JWT provider entries, applies defaults and drops
+// entries missing an issuer or audience.
+func (cfg *Config) SanitizeOIDCJWT() {
+	if cfg == nil || len(cfg.OIDCJWT) == 0 {
+		return
+	}
+	seen := make(map[string]struct{}, len(cfg.OIDCJWT))
+	out := make([]OIDCJWTConfig, 0, len(cfg.OIDCJWT))
+	for i := range cfg.OIDCJWT {
+		entry := cfg.OIDCJWT[i]
+		entry.Name = strings.TrimSpace(entry.Name)
+		if entry.Name == "" {
+			entry.Name = DefaultOIDCJWTName
+		}
+		entry.Issuer = strings.TrimRight(strings.TrimSpace(entry.Issuer), "/")
+		entry.JWKSURL = strings.TrimSpace(entry.JWKSURL)
+		if entry.Issuer == "" {
+			log.WithField("oidc_provider", entry.Name).Warn("oidc-jwt provider dropped: missing issuer")
+			continue
+		}
+		audiences := make([]string, 0, len(entry.Audiences))
+		for _, aud := range entry.Audiences {
+			if trimmed := strings.TrimSpace(aud); trimmed != "" {
+				audiences = append(audiences, trimmed)
+			}
+		}
+		if len(audiences) == 0 {
+			log.WithField("oidc_provider", entry.Name).Warn("oidc-jwt provider dropped: at least one audience is required")
+			continue
+		}
+		entry.Audiences = audiences
+		if _, exists := seen[entry.Name]; exists {
+			log.WithField("oidc_provider", entry.Name).Warn("oidc-jwt provider dropped: duplicate name")
+			continue
+		}
+		seen[entry.Name] = struct{}{}
+		entry.PrincipalClaim = defaultString(strings.TrimSpace(entry.PrincipalClaim), DefaultOIDCJWTPrincipalClaim)
+		entry.EmailClaim = defaultString(strings.TrimSpace(entry.EmailClaim), DefaultOIDCJWTEmailClaim)
+		entry.GroupsClaim = defaultString(strings.TrimSpace(entry.GroupsClaim), DefaultOIDCJWTGroupsClaim)
+		if entry.ClockSkewSeconds <= 0 {
+			entry.ClockSkewSeconds = DefaultOIDCJWTClockSkew
+		}
+		if entry.JWKSCacheSeconds <= 0 {
+			entry.JWKSCacheSeconds = DefaultOIDCJWTJWKSCacheSeconds
+		}
+		out = append(out, entry)
+	}
+	cfg.OIDCJWT = out
+}
+
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
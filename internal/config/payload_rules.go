@@ -0,0 +1,74 @@
+package config
+
+// PayloadCondition restricts a payload rule to matching requests. All populated
+// fields must match. A rule with conditions but without models applies to every model.
+type PayloadCondition struct {
+	// APIKeys lists client API keys (wildcards allowed) the rule applies to.
+	APIKeys []string `yaml:"api-keys,omitempty" json:"api-keys,omitempty"`
+	// Tenants lists tenant names the rule applies to.
+	Tenants []string `yaml:"tenants,omitempty" json:"tenants,omitempty"`
+	// Headers maps request header names to wildcard patterns the header value must match.
+	// An empty pattern only requires the header to be present.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// Stream restricts the rule to streaming (true) or non-streaming (false) requests.
+	Stream *bool `yaml:"stream,omitempty" json:"stream,omitempty"`
+	// Body lists JSON path predicates evaluated against the translated payload.
+	Body []PayloadPredicate `yaml:"body,omitempty" json:"body,omitempty"`
+}
+
+// PayloadPredicate tests the value found at a JSON path (gjson syntax). Every populated
+// comparison must hold. Paths such as "tools.#.name" yield arrays, which Contains
+// searches element by element.
+type PayloadPredicate struct {
+	// Path is the gjson path relative to the payload root; empty means the value itself.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Exists requires the path to be present (true) or absent (false).
+	Exists *bool `yaml:"exists,omitempty" json:"exists,omitempty"`
+	// Equals requires the value to equal this scalar.
+	Equals any `yaml:"equals,omitempty" json:"equals,omitempty"`
+	// Contains requires an array to hold an element equal to this scalar, or a string
+	// to contain it as a substring.
+	Contains any `yaml:"contains,omitempty" json:"contains,omitempty"`
+	// GT, GTE, LT and LTE compare numeric values.
+	GT  *float64 `yaml:"gt,omitempty" json:"gt,omitempty"`
+	GTE *float64 `yaml:"gte,omitempty" json:"gte,omitempty"`
+	LT  *float64 `yaml:"lt,omitempty" json:"lt,omitempty"`
+	LTE *float64 `yaml:"lte,omitempty" json:"lte,omitempty"`
+}
+
+// PayloadRenameRule moves parameters to new JSON paths, overwriting the destination.
+type PayloadRenameRule struct {
+	// Models lists model entries with name pattern and protocol constraint.
+	Models []PayloadModelRule `yaml:"models" json:"models"`
+	// When optionally restricts the rule to requests matching these conditions.
+	When *PayloadCondition `yaml:"when,omitempty" json:"when,omitempty"`
+	// Params maps source JSON paths to destination JSON paths.
+	Params map[string]string `yaml:"params" json:"params"`
+}
+
+// PayloadDeleteItemsRule removes array elements that match a predicate.
+type PayloadDeleteItemsRule struct {
+	// Models lists model entries with name pattern and protocol constraint.
+	Models []PayloadModelRule `yaml:"models" json:"models"`
+	// When optionally restricts the rule to requests matching these conditions.
+	When *PayloadCondition `yaml:"when,omitempty" json:"when,omitempty"`
+	// Params maps array JSON paths to the predicate evaluated against each element.
+	Params map[string]PayloadPredicate `yaml:"params" json:"params"`
+}
+
+// PayloadClampRule keeps numeric parameters within bounds. Missing or non-numeric
+// parameters are left untouched.
+type PayloadClampRule struct {
+	// Models lists model entries with name pattern and protocol constraint.
+	Models []PayloadModelRule `yaml:"models" json:"models"`
+	// When optionally restricts the rule to requests matching these conditions.
+	When *PayloadCondition `yaml:"when,omitempty" json:"when,omitempty"`
+	// Params maps JSON paths to the allowed range.
+	Params map[string]PayloadRange `yaml:"params" json:"params"`
+}
+
+// PayloadRange bounds a numeric value; either side may be omitted.
+type PayloadRange struct {
+	Min *float64 `yaml:"min,omitempty" json:"min,omitempty"`
+	Max *float64 `yaml:"max,omitempty" json:"max,omitempty"`
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Plugin supervision defaults applied by SanitizePlugins.
+const (
+	DefaultPluginHealthCheckInterval = 30
+	DefaultPluginStartupTimeout      = 15
+	DefaultPluginRequestTimeout      = 300
+)
+
+// PluginConfig declares an out-of-process plugin reachable over a local socket.
+// Plugins speak JSON-RPC 2.0 and may provide request authentication, a provider
+// executor, and request/response transforms; the capabilities are discovered
+// from the plugin itself at startup.
+type PluginConfig struct {
+	// Name uniquely identifies the plugin.
+	Name string `yaml:"name" json:"name"`
+
+	// Command is the executable started and supervised by the service.
+	// When empty, the plugin is assumed to be managed externally and only Socket is dialed.
+	Command string `yaml:"command,omitempty" json:"command,omitempty"`
+
+	// Args are passed to Command.
+	Args []string `yaml:"args,omitempty" json:"args,omitempty"`
+
+	// Env adds environment variables to the plugin process.
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+
+	// Socket is the Unix socket path the plugin listens on.
+	// Defaults to "<auth-dir>/plugins/<name>.sock" for supervised plugins.
+	Socket string `yaml:"socket,omitempty" json:"socket,omitempty"`
+
+	// Disabled keeps the declaration without starting the plugin.
+	Disabled bool `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+
+	// HealthCheckInterval is the number of seconds between health probes. Defaults to 30.
+	HealthCheckInterval int `yaml:"health-check-interval,omitempty" json:"health-check-interval,omitempty"`
+
+	// StartupTimeout is the number of seconds to wait for the plugin socket to answer. Defaults to 15.
+	StartupTimeout int `yaml:"startup-timeout,omitempty" json:"startup-timeout,omitempty"`
+
+	// RequestTimeout bounds a single plugin call in seconds. Defaults to 300.
+	RequestTimeout int `yaml:"request-timeout,omitempty" json:"request-timeout,omitempty"`
+}
+
+// SanitizePlugins trims plugin declarations, applies defaults and drops
+// entries without a name or without any way to reach the plugin.
+func (cfg *Config) SanitizePlugins() {
+	if cfg == nil || len(cfg.Plugins) == 0 {
+		return
+	}
+	seen := make(map[string]struct{}, len(cfg.Plugins))
+	out := make([]PluginConfig, 0, len(cfg.Plugins))
+	for i := range cfg.Plugins {
+		entry := cfg.Plugins[i]
+		entry.Name = strings.TrimSpace(entry.Name)
+		entry.Command = strings.TrimSpace(entry.Command)
+		entry.Socket = strings.TrimSpace(entry.Socket)
+		if entry.Name == "" {
+			log.WithField("plugin_index", i+1).Warn("plugin dropped: missing name")
+			continue
+		}
+		if entry.Command == "" && entry.Socket == "" {
+			log.WithField("plugin", entry.Name).Warn("plugin dropped: either command or socket is required")
+			continue
+		}
+		key := strings.ToLower(entry.Name)
+		if _, exists := seen[key]; exists {
+			log.WithField("plugin", entry.Name).Warn("plugin dropped: duplicate name")
+			continue
+		}
+		seen[key] = struct{}{}
+		if entry.HealthCheckInterval <= 0 {
+			entry.HealthCheckInterval = DefaultPluginHealthCheckInterval
+		}
+		if entry.StartupTimeout <= 0 {
+			entry.StartupTimeout = DefaultPluginStartupTimeout
+		}
+		if entry.RequestTimeout <= 0 {
+			entry.RequestTimeout = DefaultPluginRequestTimeout
+		}
+		out = append(out, entry)
+	}
+	cfg.Plugins = out
+}
@@ -0,0 +1,38 @@
+package config
+
+// DefaultQuotaPollIntervalSeconds is how often each credential's quota is probed.
+const DefaultQuotaPollIntervalSeconds = 300
+
+// QuotaPollingConfig controls the background quota poller, which reads remaining
+// quota for credentials whose executor implements a quota probe.
+type QuotaPollingConfig struct {
+	// Enabled turns on background polling.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// IntervalSeconds is the delay between probes of the same credential. Defaults to 300.
+	IntervalSeconds int `yaml:"interval-seconds,omitempty" json:"interval-seconds,omitempty"`
+
+	// ReservePercent marks a credential as low once its remaining quota falls to this
+	// share of the limit. Zero marks it low only when the quota is used up.
+	ReservePercent float64 `yaml:"reserve-percent,omitempty" json:"reserve-percent,omitempty"`
+
+	// SkipLow keeps the scheduler off low credentials until their quota resets.
+	SkipLow bool `yaml:"skip-low,omitempty" json:"skip-low,omitempty"`
+}
+
+// SanitizeQuotaPolling applies defaults and clamps the quota polling settings.
+func (cfg *Config) SanitizeQuotaPolling() {
+	if cfg == nil {
+		return
+	}
+	q := &cfg.QuotaPolling
+	if q.IntervalSeconds <= 0 {
+		q.IntervalSeconds = DefaultQuotaPollIntervalSeconds
+	}
+	if q.ReservePercent < 0 {
+		q.ReservePercent = 0
+	}
+	if q.ReservePercent > 100 {
+		q.ReservePercent = 100
+	}
+}
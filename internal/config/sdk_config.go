@@ -34,6 +34,37 @@ type SDKConfig struct {
 	// NonStreamKeepAliveInterval controls how often blank lines are emitted for non-streaming responses.
 	// <= 0 disables keep-alives. Value is in seconds.
 	NonStreamKeepAliveInterval int `yaml:"nonstream-keepalive-interval,omitempty" json:"nonstream-keepalive-interval,omitempty"`
+
+	// Guardrails configures content inspection for inbound prompts and outbound completions.
+	Guardrails GuardrailsConfig `yaml:"guardrails,omitempty" json:"guardrails,omitempty"`
+
+	// SystemPrompts lists policies that inject, replace or strip system prompt text
+	// per client key, model and request format. Policies apply in declaration order.
+	SystemPrompts []SystemPromptPolicy `yaml:"system-prompts,omitempty" json:"system-prompts,omitempty"`
+
+	// ContextWindow lists opt-in rules that shrink conversations exceeding a model's
+	// input limit by trimming tool outputs, summarizing or dropping older turns.
+	ContextWindow []ContextWindowRule `yaml:"context-window,omitempty" json:"context-window,omitempty"`
+
+	// ToolCallRepair validates tool calls in responses against the request's tool
+	// schemas and repairs malformed arguments and near-miss tool names.
+	ToolCallRepair ToolCallRepairConfig `yaml:"tool-call-repair,omitempty" json:"tool-call-repair,omitempty"`
+
+	// StructuredOutput enforces JSON schema response formats for backends that ignore them.
+	StructuredOutput StructuredOutputConfig `yaml:"structured-output,omitempty" json:"structured-output,omitempty"`
+
+	// TokenCounting controls whether count_tokens endpoints may call upstream providers.
+	TokenCounting TokenCountingConfig `yaml:"token-counting,omitempty" json:"token-counting,omitempty"`
+
+	// OIDCJWT configures access providers that authenticate clients with OIDC-issued bearer JWTs.
+	OIDCJWT []OIDCJWTConfig `yaml:"oidc-jwt,omitempty" json:"oidc-jwt,omitempty"`
+
+	// Tenants partitions client keys and credentials into isolated workspaces.
+	// When empty, every client key may use every credential.
+	Tenants []TenantConfig `yaml:"tenants,omitempty" json:"tenants,omitempty"`
+
+	// Transcripts configures the per-client conversation transcript archive.
+	Transcripts TranscriptsConfig `yaml:"transcripts,omitempty" json:"transcripts,omitempty"`
 }
 
 // StreamingConfig holds server streaming behavior configuration.
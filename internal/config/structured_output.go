@@ -0,0 +1,63 @@
+package config
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Structured output modes supported by StructuredOutputConfig.Mode.
+const (
+	// StructuredOutputModeInstruction describes the schema in a system instruction.
+	StructuredOutputModeInstruction = "instruction"
+	// StructuredOutputModeTool forces a synthetic tool whose parameters are the schema.
+	// It applies to chat completions requests that declare no tools of their own.
+	StructuredOutputModeTool = "tool"
+)
+
+// DefaultStructuredOutputMaxRetries is the number of corrective follow-ups sent when
+// the output does not validate.
+const DefaultStructuredOutputMaxRetries = 2
+
+// StructuredOutputConfig enforces response_format / text.format JSON schemas for
+// backends that ignore them.
+type StructuredOutputConfig struct {
+	// Enabled toggles structured output enforcement.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Models restricts enforcement to matching model names; supports "*" wildcards.
+	// Empty applies to all models.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// Mode selects how the schema reaches the model: "instruction" (default) or "tool".
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+
+	// MaxRetries is the number of corrective follow-ups sent when the output does not
+	// validate. Zero uses the default; negative disables retries.
+	MaxRetries int `yaml:"max-retries,omitempty" json:"max-retries,omitempty"`
+}
+
+// SanitizeStructuredOutput normalizes the structured output settings.
+func (cfg *Config) SanitizeStructuredOutput() {
+	if cfg == nil {
+		return
+	}
+	so := &cfg.StructuredOutput
+	so.Models = normalizeGuardrailList(so.Models, false)
+	mode := strings.ToLower(strings.TrimSpace(so.Mode))
+	switch mode {
+	case "", StructuredOutputModeInstruction:
+		mode = StructuredOutputModeInstruction
+	case StructuredOutputModeTool:
+	default:
+		log.Warnf("structured-output: unknown mode %q, using %s", so.Mode, StructuredOutputModeInstruction)
+		mode = StructuredOutputModeInstruction
+	}
+	so.Mode = mode
+	switch {
+	case so.MaxRetries == 0:
+		so.MaxRetries = DefaultStructuredOutputMaxRetries
+	case so.MaxRetries < 0:
+		so.MaxRetries = 0
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// System prompt policy modes supported by SystemPromptPolicy.Mode.
+const (
+	SystemPromptModePrepend = "prepend"
+	SystemPromptModeAppend  = "append"
+	SystemPromptModeReplace = "replace"
+	SystemPromptModeStrip   = "strip"
+)
+
+// SystemPromptPolicy rewrites the system prompt of matching requests before they are
+// translated for the upstream provider.
+type SystemPromptPolicy struct {
+	// Name identifies the policy in logs.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Mode selects how Text is combined with the client's system prompt:
+	// "prepend" (default), "append", "replace" or "strip".
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+
+	// Text is the policy text. It may reference {{date}}, {{time}}, {{model}},
+	// {{client}}, {{tenant}} and {{format}}. Ignored by "strip".
+	Text string `yaml:"text,omitempty" json:"text,omitempty"`
+
+	// APIKeys restricts the policy to the listed client API keys; supports "*" wildcards.
+	// Empty applies to all clients.
+	APIKeys []string `yaml:"api-keys,omitempty" json:"api-keys,omitempty"`
+
+	// Models restricts the policy to matching model names; supports "*" wildcards.
+	// Empty applies to all models.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// Formats restricts the policy to client request formats:
+	// "openai", "openai-response", "claude", "gemini" or "gemini-cli". Empty applies to all.
+	Formats []string `yaml:"formats,omitempty" json:"formats,omitempty"`
+}
+
+var systemPromptFormats = map[string]struct{}{
+	"openai":          {},
+	"openai-response": {},
+	"claude":          {},
+	"gemini":          {},
+	"gemini-cli":      {},
+}
+
+// SanitizeSystemPrompts normalizes system prompt policies and drops entries that cannot apply.
+func (cfg *Config) SanitizeSystemPrompts() {
+	if cfg == nil || len(cfg.SystemPrompts) == 0 {
+		return
+	}
+	out := make([]SystemPromptPolicy, 0, len(cfg.SystemPrompts))
+	for i := range cfg.SystemPrompts {
+		policy := cfg.SystemPrompts[i]
+		policy.Name = strings.TrimSpace(policy.Name)
+		if policy.Name == "" {
+			policy.Name = fmt.Sprintf("policy-%d", i+1)
+		}
+		policy.Mode = strings.ToLower(strings.TrimSpace(policy.Mode))
+		switch policy.Mode {
+		case "":
+			policy.Mode = SystemPromptModePrepend
+		case SystemPromptModePrepend, SystemPromptModeAppend, SystemPromptModeReplace, SystemPromptModeStrip:
+		default:
+			log.WithField("policy", policy.Name).Warnf("system prompt policy dropped: unknown mode %q", policy.Mode)
+			continue
+		}
+		if policy.Mode != SystemPromptModeStrip && strings.TrimSpace(policy.Text) == "" {
+			log.WithField("policy", policy.Name).Warn("system prompt policy dropped: text is empty")
+			continue
+		}
+		policy.APIKeys = normalizeGuardrailList(policy.APIKeys, false)
+		policy.Models = normalizeGuardrailList(policy.Models, false)
+		formats := normalizeGuardrailList(policy.Formats, true)
+		policy.Formats = formats[:0]
+		for _, format := range formats {
+			if _, ok := systemPromptFormats[format]; !ok {
+				log.WithField("policy", policy.Name).Warnf("system prompt policy: ignoring unknown format %q", format)
+				continue
+			}
+			policy.Formats = append(policy.Formats, format)
+		}
+		if len(formats) > 0 && len(policy.Formats) == 0 {
+			log.WithField("policy", policy.Name).Warn("system prompt policy dropped: no supported formats")
+			continue
+		}
+		out = append(out, policy)
+	}
+	cfg.SystemPrompts = out
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TenantConfig groups client keys into a workspace with its own credential pool.
+// Credentials are assigned to tenants through their "tenants" field; credentials
+// tagged "*" form the shared pool that tenants may opt into.
+type TenantConfig struct {
+	// Name identifies the tenant and matches the tags on credentials.
+	Name string `yaml:"name" json:"name"`
+
+	// APIKeys lists the client keys (access principals) belonging to the tenant.
+	APIKeys []string `yaml:"api-keys,omitempty" json:"api-keys,omitempty"`
+
+	// Groups maps identity-provider groups (e.g. from oidc-jwt) to the tenant.
+	Groups []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+
+	// AllowShared lets the tenant use credentials tagged with the shared pool "*".
+	AllowShared bool `yaml:"allow-shared,omitempty" json:"allow-shared,omitempty"`
+}
+
+// SanitizeTenants trims tenant declarations, drops unnamed or duplicate tenants
+// and removes client keys already claimed by an earlier tenant.
+func (cfg *Config) SanitizeTenants() {
+	if cfg == nil || len(cfg.Tenants) == 0 {
+		return
+	}
+	seenNames := make(map[string]struct{}, len(cfg.Tenants))
+	claimedKeys := make(map[string]string)
+	out := make([]TenantConfig, 0, len(cfg.Tenants))
+	for i := range cfg.Tenants {
+		tenant := cfg.Tenants[i]
+		tenant.Name = strings.TrimSpace(tenant.Name)
+		if tenant.Name == "" || tenant.Name == "*" {
+			log.WithField("tenant_index", i+1).Warn("tenant dropped: missing or reserved name")
+			continue
+		}
+		if _, exists := seenNames[tenant.Name]; exists {
+			log.WithField("tenant", tenant.Name).Warn("tenant dropped: duplicate name")
+			continue
+		}
+		seenNames[tenant.Name] = struct{}{}
+
+		keys := make([]string, 0, len(tenant.APIKeys))
+		for _, key := range tenant.APIKeys {
+			key = strings.TrimSpace(key)
+			if key == "" {
+				continue
+			}
+			if owner, claimed := claimedKeys[key]; claimed {
+				log.WithField("tenant", tenant.Name).Warnf("tenant api key ignored: already assigned to tenant %s", owner)
+				continue
+			}
+			claimedKeys[key] = tenant.Name
+			keys = append(keys, key)
+		}
+		tenant.APIKeys = keys
+		tenant.Groups = NormalizeTenantNames(tenant.Groups)
+		out = append(out, tenant)
+	}
+	cfg.Tenants = out
+}
+
+// TenantForPrincipal returns the tenant owning the authenticated principal. Explicit
+// key assignments take precedence over group membership. It returns nil when the
+// caller does not belong to any tenant.
+func (cfg *SDKConfig) TenantForPrincipal(principal string, groups []string) *TenantConfig {
+	if cfg == nil || len(cfg.Tenants) == 0 {
+		return nil
+	}
+	principal = strings.TrimSpace(principal)
+	if principal != "" {
+		for i := range cfg.Tenants {
+			for _, key := range cfg.Tenants[i].APIKeys {
+				if key == principal {
+					return &cfg.Tenants[i]
+				}
+			}
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	for i := range cfg.Tenants {
+		for _, want := range cfg.Tenants[i].Groups {
+			for _, group := range groups {
+				if strings.EqualFold(strings.TrimSpace(group), want) {
+					return &cfg.Tenants[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// TenantByName returns the tenant with the given name, or nil.
+func (cfg *SDKConfig) TenantByName(name string) *TenantConfig {
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.Tenants {
+		if cfg.Tenants[i].Name == name {
+			return &cfg.Tenants[i]
+		}
+	}
+	return nil
+}
+
+// NormalizeTenantNames trims, de-duplicates and drops empty tenant names while preserving order.
+func NormalizeTenantNames(names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(names))
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, exists := seen[name]; exists {
+			continue
+		}
+		seen[name] = struct{}{}
+		out = append(out, name)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
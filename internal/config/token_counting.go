@@ -0,0 +1,44 @@
+package config
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Token counting modes supported by TokenCountingConfig.Mode.
+const (
+	// TokenCountingModeAuto lets each provider decide: upstream count endpoints where
+	// the provider offers one, the local estimator otherwise.
+	TokenCountingModeAuto = "auto"
+	// TokenCountingModeLocal answers every count_tokens request offline.
+	TokenCountingModeLocal = "local"
+)
+
+// TokenCountingConfig controls how count_tokens endpoints are answered.
+type TokenCountingConfig struct {
+	// Mode is "auto" (default) or "local".
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+}
+
+// Local reports whether count_tokens requests are answered without calling upstream.
+func (c TokenCountingConfig) Local() bool {
+	return c.Mode == TokenCountingModeLocal
+}
+
+// SanitizeTokenCounting normalizes the token counting mode.
+func (cfg *Config) SanitizeTokenCounting() {
+	if cfg == nil {
+		return
+	}
+	mode := strings.ToLower(strings.TrimSpace(cfg.TokenCounting.Mode))
+	switch mode {
+	case "", TokenCountingModeAuto:
+		mode = TokenCountingModeAuto
+	case TokenCountingModeLocal:
+	default:
+		log.Warnf("token-counting: unknown mode %q, using %s", cfg.TokenCounting.Mode, TokenCountingModeAuto)
+		mode = TokenCountingModeAuto
+	}
+	cfg.TokenCounting.Mode = mode
+}
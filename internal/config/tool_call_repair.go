@@ -0,0 +1,62 @@
+package config
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Tool call repair failure modes supported by ToolCallRepairConfig.OnFailure.
+const (
+	// ToolRepairOnFailureErrorResult replaces an unrepairable tool call with text
+	// describing why it was dropped.
+	ToolRepairOnFailureErrorResult = "error-result"
+	// ToolRepairOnFailureRetry re-executes the request once and falls back to
+	// error-result when the retry is still unrepairable.
+	ToolRepairOnFailureRetry = "retry"
+)
+
+// DefaultToolRepairMaxNameDistance is the edit distance tolerated when mapping an
+// undeclared tool name onto a declared one.
+const DefaultToolRepairMaxNameDistance = 2
+
+// ToolCallRepairConfig configures the optional validation and repair stage applied
+// to tool calls in model responses.
+type ToolCallRepairConfig struct {
+	// Enabled toggles tool call validation and repair.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Models restricts repair to matching model names; supports "*" wildcards.
+	// Empty applies to all models.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// OnFailure controls what happens when a call cannot be repaired:
+	// "error-result" (default) or "retry".
+	OnFailure string `yaml:"on-failure,omitempty" json:"on-failure,omitempty"`
+
+	// MaxNameDistance is the largest edit distance between an emitted and a declared
+	// tool name that is still mapped onto the declared name.
+	MaxNameDistance int `yaml:"max-name-distance,omitempty" json:"max-name-distance,omitempty"`
+}
+
+// SanitizeToolCallRepair normalizes the tool call repair settings.
+func (cfg *Config) SanitizeToolCallRepair() {
+	if cfg == nil {
+		return
+	}
+	repair := &cfg.ToolCallRepair
+	repair.Models = normalizeGuardrailList(repair.Models, false)
+	mode := strings.ToLower(strings.TrimSpace(repair.OnFailure))
+	switch mode {
+	case "", ToolRepairOnFailureErrorResult:
+		mode = ToolRepairOnFailureErrorResult
+	case ToolRepairOnFailureRetry:
+	default:
+		log.Warnf("tool-call-repair: unknown on-failure %q, using %s", repair.OnFailure, ToolRepairOnFailureErrorResult)
+		mode = ToolRepairOnFailureErrorResult
+	}
+	repair.OnFailure = mode
+	if repair.MaxNameDistance <= 0 {
+		repair.MaxNameDistance = DefaultToolRepairMaxNameDistance
+	}
+}
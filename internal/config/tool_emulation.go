@@ -0,0 +1,38 @@
+package config
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ToolEmulationRule opts an executor into prompt-based tool calling for matching
+// models. Executors without native function calling render the declared tools into
+// the prompt and parse tool invocations back out of the model's text.
+type ToolEmulationRule struct {
+	// Provider is the executor identifier the rule applies to, e.g. "gitlab" or "cursor".
+	Provider string `yaml:"provider" json:"provider"`
+
+	// Models lists model name patterns the rule applies to; supports "*" wildcards.
+	// Empty applies to every model of the provider.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+}
+
+// SanitizeToolEmulation normalizes tool emulation rules and drops entries without a provider.
+func (cfg *Config) SanitizeToolEmulation() {
+	if cfg == nil || len(cfg.ToolEmulation) == 0 {
+		return
+	}
+	out := make([]ToolEmulationRule, 0, len(cfg.ToolEmulation))
+	for i := range cfg.ToolEmulation {
+		rule := cfg.ToolEmulation[i]
+		rule.Provider = strings.ToLower(strings.TrimSpace(rule.Provider))
+		if rule.Provider == "" {
+			log.WithField("rule_index", i+1).Warn("tool emulation rule dropped: no provider")
+			continue
+		}
+		rule.Models = normalizeGuardrailList(rule.Models, false)
+		out = append(out, rule)
+	}
+	cfg.ToolEmulation = out
+}
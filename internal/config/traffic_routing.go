@@ -0,0 +1,120 @@
+package config
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultShadowTimeoutSeconds bounds a mirrored shadow request.
+const DefaultShadowTimeoutSeconds = 120
+
+// TrafficRoute splits the requests for one model alias between the providers
+// serving it, e.g. 90% to claude and 10% to kiro, and can mirror a sample of
+// them to a candidate provider.
+type TrafficRoute struct {
+	// Model is the client-facing model name or alias the route applies to.
+	Model string `yaml:"model" json:"model"`
+
+	// Targets lists the providers to split between by weight. Providers serving the
+	// model but not listed only receive requests when every target is unavailable.
+	Targets []TrafficTarget `yaml:"targets,omitempty" json:"targets,omitempty"`
+
+	// Sticky keeps a client API key on the same target instead of drawing per request.
+	Sticky bool `yaml:"sticky,omitempty" json:"sticky,omitempty"`
+
+	// Shadow mirrors a sample of requests to a candidate provider.
+	Shadow *TrafficShadow `yaml:"shadow,omitempty" json:"shadow,omitempty"`
+}
+
+// TrafficTarget is one weighted provider of a TrafficRoute.
+type TrafficTarget struct {
+	// Provider is the provider key, e.g. "claude", "kiro" or an openai-compatibility name.
+	Provider string `yaml:"provider" json:"provider"`
+
+	// Weight is the relative share of requests; zero takes the target out of rotation.
+	Weight int `yaml:"weight" json:"weight"`
+}
+
+// TrafficShadow mirrors requests to a provider whose responses are discarded,
+// recording latency and errors next to the primary response for comparison.
+type TrafficShadow struct {
+	// Provider is the candidate provider key.
+	Provider string `yaml:"provider" json:"provider"`
+
+	// SamplePercent is the share of requests mirrored, between 0 and 100.
+	SamplePercent float64 `yaml:"sample-percent" json:"sample-percent"`
+
+	// TimeoutSeconds bounds each shadow request. Defaults to 120.
+	TimeoutSeconds int `yaml:"timeout-seconds,omitempty" json:"timeout-seconds,omitempty"`
+}
+
+// TrafficRouteFor returns the route configured for model, matched case-insensitively.
+func (cfg *Config) TrafficRouteFor(model string) *TrafficRoute {
+	if cfg == nil || len(cfg.TrafficRouting) == 0 {
+		return nil
+	}
+	model = strings.TrimSpace(model)
+	for i := range cfg.TrafficRouting {
+		if strings.EqualFold(cfg.TrafficRouting[i].Model, model) {
+			return &cfg.TrafficRouting[i]
+		}
+	}
+	return nil
+}
+
+// SanitizeTrafficRouting normalizes traffic routes and drops entries that cannot apply.
+func (cfg *Config) SanitizeTrafficRouting() {
+	if cfg == nil || len(cfg.TrafficRouting) == 0 {
+		return
+	}
+	out := make([]TrafficRoute, 0, len(cfg.TrafficRouting))
+	seen := make(map[string]struct{}, len(cfg.TrafficRouting))
+	for i := range cfg.TrafficRouting {
+		route := cfg.TrafficRouting[i]
+		route.Model = strings.TrimSpace(route.Model)
+		entry := log.WithField("rule_index", i+1)
+		if route.Model == "" {
+			entry.Warn("traffic route dropped: no model")
+			continue
+		}
+		key := strings.ToLower(route.Model)
+		if _, dup := seen[key]; dup {
+			entry.WithField("model", route.Model).Warn("traffic route dropped: duplicate model")
+			continue
+		}
+		targets := make([]TrafficTarget, 0, len(route.Targets))
+		for _, target := range route.Targets {
+			target.Provider = strings.ToLower(strings.TrimSpace(target.Provider))
+			if target.Provider == "" || target.Weight < 0 {
+				entry.WithField("model", route.Model).Warn("traffic target dropped: no provider or negative weight")
+				continue
+			}
+			targets = append(targets, target)
+		}
+		route.Targets = targets
+		if route.Shadow != nil {
+			shadow := *route.Shadow
+			shadow.Provider = strings.ToLower(strings.TrimSpace(shadow.Provider))
+			switch {
+			case shadow.Provider == "" || shadow.SamplePercent <= 0:
+				route.Shadow = nil
+			default:
+				if shadow.SamplePercent > 100 {
+					shadow.SamplePercent = 100
+				}
+				if shadow.TimeoutSeconds <= 0 {
+					shadow.TimeoutSeconds = DefaultShadowTimeoutSeconds
+				}
+				route.Shadow = &shadow
+			}
+		}
+		if len(route.Targets) == 0 && route.Shadow == nil {
+			entry.WithField("model", route.Model).Warn("traffic route dropped: no targets or shadow")
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, route)
+	}
+	cfg.TrafficRouting = out
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strings"
+)
+
+// Transcript archive defaults applied by SanitizeTranscripts.
+const (
+	DefaultTranscriptRetentionDays = 90
+	DefaultTranscriptSegmentMaxMB  = 16
+)
+
+// TranscriptsConfig configures the conversation transcript archive. Unlike request
+// logs, transcripts hold normalized conversations meant for long-term retention.
+type TranscriptsConfig struct {
+	// Enabled toggles transcript archiving.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Dir is the directory holding transcript segments. When empty, a "transcripts"
+	// directory under WRITABLE_PATH (or the working directory) is used.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// RetentionDays is how long transcripts are kept. Defaults to 90.
+	RetentionDays int `yaml:"retention-days,omitempty" json:"retention-days,omitempty"`
+
+	// SegmentMaxMB caps the size of a segment before it is sealed and compressed. Defaults to 16.
+	SegmentMaxMB int `yaml:"segment-max-mb,omitempty" json:"segment-max-mb,omitempty"`
+
+	// APIKeys restricts archiving to the listed client keys. Empty archives every client.
+	APIKeys []string `yaml:"api-keys,omitempty" json:"api-keys,omitempty"`
+}
+
+// SanitizeTranscripts applies defaults to the transcript archive settings.
+func (cfg *Config) SanitizeTranscripts() {
+	if cfg == nil {
+		return
+	}
+	t := &cfg.Transcripts
+	t.Dir = strings.TrimSpace(t.Dir)
+	if t.RetentionDays <= 0 {
+		t.RetentionDays = DefaultTranscriptRetentionDays
+	}
+	if t.SegmentMaxMB <= 0 {
+		t.SegmentMaxMB = DefaultTranscriptSegmentMaxMB
+	}
+	keys := make([]string, 0, len(t.APIKeys))
+	for _, key := range t.APIKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	t.APIKeys = keys
+}
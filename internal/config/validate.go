@@ -0,0 +1,805 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/proxyutil"
+	"github.com/tidwall/sjson"
+	"gopkg.in/yaml.v3"
+)
+
+// Validation issue severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue describes one problem found in a candidate config. Line and Column
+// are 1-based positions in the YAML document and are zero when unknown.
+type ValidationIssue struct {
+	Severity string `json:"severity"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+// String formats the issue as "line:column: severity: path: message".
+func (i ValidationIssue) String() string {
+	var b strings.Builder
+	if i.Line > 0 {
+		b.WriteString(strconv.Itoa(i.Line))
+		b.WriteByte(':')
+		if i.Column > 0 {
+			b.WriteString(strconv.Itoa(i.Column))
+			b.WriteByte(':')
+		}
+		b.WriteByte(' ')
+	}
+	b.WriteString(i.Severity)
+	b.WriteString(": ")
+	if i.Path != "" {
+		b.WriteString(i.Path)
+		b.WriteString(": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// HasValidationErrors reports whether issues contains at least one error.
+func HasValidationErrors(issues []ValidationIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// legacyTopLevelKeys lists keys that older releases accepted and that are no longer
+// applied, mapped to their replacement.
+var legacyTopLevelKeys = map[string]string{
+	"generative-language-api-key":          "gemini-api-key",
+	"amp-upstream-url":                     "ampcode.upstream-url",
+	"amp-upstream-api-key":                 "ampcode.upstream-api-key",
+	"amp-restrict-management-to-localhost": "ampcode.restrict-management-to-localhost",
+	"amp-model-mappings":                   "ampcode.model-mappings",
+}
+
+var knownRoutingStrategies = map[string]struct{}{
+	"": {}, "round-robin": {}, "roundrobin": {}, "rr": {}, "fill-first": {}, "fillfirst": {}, "ff": {},
+}
+
+var knownPayloadProtocols = map[string]struct{}{
+	"openai": {}, "openai-response": {}, "claude": {}, "gemini": {}, "gemini-cli": {}, "codex": {}, "antigravity": {},
+}
+
+var yamlLinePattern = regexp.MustCompile(`line (\d+)(?:, column (\d+))?: ?`)
+
+// ValidateConfig checks candidate config.yaml content without applying it. Besides
+// YAML syntax and type errors it reports unknown keys and semantic problems that
+// LoadConfig would otherwise ignore or drop silently. The returned config is what
+// LoadConfig produces for the candidate; it is nil when loading fails.
+func ValidateConfig(data []byte) (*Config, []ValidationIssue) {
+	v := &configValidator{nodes: make(map[string]*yaml.Node)}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		v.addYAMLError(err)
+		return nil, v.issues
+	}
+	if len(doc.Content) > 0 {
+		v.checkSchema(doc.Content[0], reflect.TypeOf(Config{}), "")
+	}
+
+	var raw Config
+	decodeErr := yaml.Unmarshal(data, &raw)
+	if decodeErr != nil {
+		v.addYAMLError(decodeErr)
+		v.fillColumns()
+	}
+	v.checkSemantics(&raw)
+
+	cfg, err := loadCandidate(data)
+	// Decode failures are already reported with positions; LoadConfig repeats them.
+	if err != nil && decodeErr == nil {
+		v.issues = append(v.issues, ValidationIssue{Severity: SeverityError, Message: err.Error()})
+	}
+	sortIssues(v.issues)
+	return cfg, v.issues
+}
+
+// loadCandidate runs data through LoadConfig from a scratch file so that side effects
+// such as hashing the management key never touch the real config.
+func loadCandidate(data []byte) (*Config, error) {
+	tmp, err := os.CreateTemp("", "config-validate-*.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("create scratch config: %w", err)
+	}
+	path := tmp.Name()
+	defer func() { _ = os.Remove(path) }()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return nil, fmt.Errorf("write scratch config: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return nil, fmt.Errorf("write scratch config: %w", err)
+	}
+	return LoadConfig(path)
+}
+
+type configValidator struct {
+	nodes  map[string]*yaml.Node
+	issues []ValidationIssue
+}
+
+func (v *configValidator) add(severity, path string, node *yaml.Node, format string, args ...any) {
+	issue := ValidationIssue{Severity: severity, Path: path, Message: fmt.Sprintf(format, args...)}
+	if node == nil {
+		node = v.nearestNode(path)
+	}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	v.issues = append(v.issues, issue)
+}
+
+func (v *configValidator) errorf(path, format string, args ...any) {
+	v.add(SeverityError, path, nil, format, args...)
+}
+
+func (v *configValidator) warnf(path, format string, args ...any) {
+	v.add(SeverityWarning, path, nil, format, args...)
+}
+
+// nearestNode returns the node recorded for path or its closest recorded ancestor.
+func (v *configValidator) nearestNode(path string) *yaml.Node {
+	for path != "" {
+		if node, ok := v.nodes[path]; ok {
+			return node
+		}
+		cut := strings.LastIndexAny(path, ".[")
+		if cut < 0 {
+			break
+		}
+		path = path[:cut]
+	}
+	return nil
+}
+
+// addYAMLError converts yaml.v3 parse and type errors into issues, keeping the line
+// numbers embedded in the messages.
+func (v *configValidator) addYAMLError(err error) {
+	var typeErr *yaml.TypeError
+	messages := []string{err.Error()}
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+	for _, msg := range messages {
+		msg = strings.TrimPrefix(msg, "yaml: ")
+		issue := ValidationIssue{Severity: SeverityError, Message: msg}
+		if m := yamlLinePattern.FindStringSubmatchIndex(msg); m != nil {
+			issue.Line, _ = strconv.Atoi(msg[m[2]:m[3]])
+			if m[4] >= 0 {
+				issue.Column, _ = strconv.Atoi(msg[m[4]:m[5]])
+			}
+			issue.Message = msg[:m[0]] + msg[m[1]:]
+		}
+		v.issues = append(v.issues, issue)
+	}
+}
+
+// fillColumns completes decoder issues, which only carry a line, with the column
+// of the value recorded on that line.
+func (v *configValidator) fillColumns() {
+	columns := make(map[int]int, len(v.nodes))
+	for _, node := range v.nodes {
+		if node.Kind == yaml.ScalarNode && node.Column > columns[node.Line] {
+			columns[node.Line] = node.Column
+		}
+	}
+	for i := range v.issues {
+		if issue := &v.issues[i]; issue.Line > 0 && issue.Column == 0 {
+			issue.Column = columns[issue.Line]
+		}
+	}
+}
+
+var yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// checkSchema walks node against t and reports keys that no field accepts.
+// Type mismatches are left to the decoder, which reports them with positions.
+func (v *configValidator) checkSchema(node *yaml.Node, t reflect.Type, path string) {
+	if node == nil {
+		return
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if path != "" {
+		v.nodes[path] = node
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Implements(yamlUnmarshalerType) || reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields, inlineMap := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			key := keyNode.Value
+			if key == "<<" {
+				v.checkSchema(valueNode, t, path)
+				continue
+			}
+			childPath := joinConfigPath(path, key)
+			field, ok := fields[key]
+			if !ok {
+				if inlineMap != nil {
+					v.checkSchema(valueNode, inlineMap.Elem(), childPath)
+					continue
+				}
+				v.reportUnknownKey(keyNode, path, childPath, key, fields)
+				continue
+			}
+			v.checkSchema(valueNode, field, childPath)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.checkSchema(node.Content[i+1], t.Elem(), joinConfigPath(path, node.Content[i].Value))
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			v.checkSchema(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (v *configValidator) reportUnknownKey(keyNode *yaml.Node, parentPath, path, key string, fields map[string]reflect.Type) {
+	if parentPath == "" {
+		if replacement, ok := legacyTopLevelKeys[key]; ok {
+			v.add(SeverityWarning, path, keyNode, "legacy key is no longer applied; use %s", replacement)
+			return
+		}
+	}
+	if strings.HasSuffix(parentPath, "]") && strings.HasPrefix(parentPath, "openai-compatibility[") && key == "api-keys" {
+		v.add(SeverityWarning, path, keyNode, "legacy key is no longer applied; use api-key-entries")
+		return
+	}
+	if suggestion := closestKey(key, fields); suggestion != "" {
+		v.add(SeverityError, path, keyNode, "unknown key %q (did you mean %q?)", key, suggestion)
+		return
+	}
+	v.add(SeverityError, path, keyNode, "unknown key %q", key)
+}
+
+// yamlFields maps the YAML keys accepted by struct t to their field types, following
+// inline embedded structs. inlineMap is set when t inlines a map that absorbs unknown keys.
+func yamlFields(t reflect.Type) (fields map[string]reflect.Type, inlineMap reflect.Type) {
+	fields = make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(","+opts+",", ",inline,") {
+			ft := field.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			switch ft.Kind() {
+			case reflect.Struct:
+				nested, nestedMap := yamlFields(ft)
+				for k, typ := range nested {
+					fields[k] = typ
+				}
+				if nestedMap != nil {
+					inlineMap = nestedMap
+				}
+			case reflect.Map:
+				inlineMap = ft
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields, inlineMap
+}
+
+func joinConfigPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// closestKey suggests the known key within a small edit distance of key.
+func closestKey(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for candidate := range fields {
+		if d := editDistance(key, candidate); d < bestDist || (d == bestDist && best != "" && candidate < best) {
+			best, bestDist = candidate, d
+		}
+	}
+	if bestDist >= 3 || bestDist >= len(key) {
+		return ""
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// checkSemantics inspects values that decode fine but cannot work as written.
+func (v *configValidator) checkSemantics(cfg *Config) {
+	if _, ok := knownRoutingStrategies[strings.ToLower(strings.TrimSpace(cfg.Routing.Strategy))]; !ok {
+		v.errorf("routing.strategy", "unknown strategy %q; supported values are round-robin and fill-first", cfg.Routing.Strategy)
+	}
+	v.checkProxyURLs(cfg)
+	v.checkPrefixes(cfg)
+	v.checkModelAliases(cfg)
+	v.checkPayloadRules(cfg)
+}
+
+func (v *configValidator) checkProxyURL(path, raw string) {
+	if _, err := proxyutil.Parse(raw); err != nil {
+		v.errorf(path, "invalid proxy-url %q: %v", raw, err)
+	}
+}
+
+func (v *configValidator) checkProxyURLs(cfg *Config) {
+	v.checkProxyURL("proxy-url", cfg.ProxyURL)
+	for i, entry := range cfg.GeminiKey {
+		v.checkProxyURL(fmt.Sprintf("gemini-api-key[%d].proxy-url", i), entry.ProxyURL)
+	}
+	for i, entry := range cfg.ClaudeKey {
+		v.checkProxyURL(fmt.Sprintf("claude-api-key[%d].proxy-url", i), entry.ProxyURL)
+	}
+	for i, entry := range cfg.CodexKey {
+		v.checkProxyURL(fmt.Sprintf("codex-api-key[%d].proxy-url", i), entry.ProxyURL)
+	}
+	for i, entry := range cfg.VertexCompatAPIKey {
+		v.checkProxyURL(fmt.Sprintf("vertex-api-key[%d].proxy-url", i), entry.ProxyURL)
+	}
+	for i, entry := range cfg.KiroKey {
+		v.checkProxyURL(fmt.Sprintf("kiro[%d].proxy-url", i), entry.ProxyURL)
+	}
+	for i, compat := range cfg.OpenAICompatibility {
+		for j, entry := range compat.APIKeyEntries {
+			v.checkProxyURL(fmt.Sprintf("openai-compatibility[%d].api-key-entries[%d].proxy-url", i, j), entry.ProxyURL)
+		}
+	}
+}
+
+// checkPrefixes flags prefixes that normalize away and prefixes shared by different
+// providers, where "prefix/model" no longer identifies a single upstream.
+func (v *configValidator) checkPrefixes(cfg *Config) {
+	type owner struct{ provider, path string }
+	owners := make(map[string]owner)
+	check := func(provider, path, prefix string) {
+		trimmed := strings.TrimSpace(prefix)
+		if trimmed == "" {
+			return
+		}
+		normalized := normalizeModelPrefix(trimmed)
+		if normalized == "" {
+			v.errorf(path, "prefix %q must not contain '/' and is ignored", prefix)
+			return
+		}
+		if first, ok := owners[normalized]; ok && first.provider != provider {
+			v.warnf(path, "prefix %q is also used by %s (%s)", normalized, first.provider, first.path)
+			return
+		}
+		if _, ok := owners[normalized]; !ok {
+			owners[normalized] = owner{provider: provider, path: path}
+		}
+	}
+	for i, entry := range cfg.GeminiKey {
+		check("gemini-api-key", fmt.Sprintf("gemini-api-key[%d].prefix", i), entry.Prefix)
+	}
+	for i, entry := range cfg.ClaudeKey {
+		check("claude-api-key", fmt.Sprintf("claude-api-key[%d].prefix", i), entry.Prefix)
+	}
+	for i, entry := range cfg.CodexKey {
+		check("codex-api-key", fmt.Sprintf("codex-api-key[%d].prefix", i), entry.Prefix)
+	}
+	for i, entry := range cfg.VertexCompatAPIKey {
+		check("vertex-api-key", fmt.Sprintf("vertex-api-key[%d].prefix", i), entry.Prefix)
+	}
+	for i, compat := range cfg.OpenAICompatibility {
+		check("openai-compatibility "+strconv.Quote(strings.TrimSpace(compat.Name)), fmt.Sprintf("openai-compatibility[%d].prefix", i), compat.Prefix)
+	}
+}
+
+// checkModelAliases verifies that alias targets name models the proxy can serve.
+// Upstream model lists change, so unknown targets are warnings rather than errors.
+func (v *configValidator) checkModelAliases(cfg *Config) {
+	for channel, aliases := range cfg.OAuthModelAlias {
+		channelKey := strings.ToLower(strings.TrimSpace(channel))
+		statics := registry.GetStaticModelDefinitionsByChannel(channelKey)
+		if len(statics) == 0 {
+			v.warnf(joinConfigPath("oauth-model-alias", channel), "channel %q has no known models", channel)
+			continue
+		}
+		known := make(map[string]struct{}, len(statics))
+		for _, model := range statics {
+			known[strings.ToLower(model.ID)] = struct{}{}
+		}
+		for i, alias := range aliases {
+			name := strings.TrimSpace(alias.Name)
+			path := fmt.Sprintf("oauth-model-alias.%s[%d].name", channel, i)
+			if name == "" {
+				v.errorf(path, "alias %q has no target model", alias.Alias)
+				continue
+			}
+			if _, ok := known[strings.ToLower(name)]; !ok {
+				v.warnf(path, "model %q is not a known %s model", name, channelKey)
+			}
+		}
+	}
+
+	served := make(map[string]struct{})
+	for _, model := range ConfiguredModels(cfg) {
+		served[strings.ToLower(model.ID)] = struct{}{}
+	}
+	for i, mapping := range cfg.AmpCode.ModelMappings {
+		path := fmt.Sprintf("ampcode.model-mappings[%d]", i)
+		if mapping.Regex {
+			if _, err := regexp.Compile(mapping.From); err != nil {
+				v.errorf(path+".from", "invalid regular expression: %v", err)
+			}
+		}
+		to := strings.TrimSpace(mapping.To)
+		if to == "" {
+			v.errorf(path+".to", "model mapping has no target")
+			continue
+		}
+		if _, ok := served[strings.ToLower(to)]; ok {
+			continue
+		}
+		if registry.LookupStaticModelInfo(to) == nil {
+			v.warnf(path+".to", "target model %q is not a known model", to)
+		}
+	}
+}
+
+func (v *configValidator) checkPayloadRules(cfg *Config) {
+	sections := []struct {
+		name  string
+		rules []PayloadRule
+		raw   bool
+	}{
+		{"default", cfg.Payload.Default, false},
+		{"default-raw", cfg.Payload.DefaultRaw, true},
+		{"override", cfg.Payload.Override, false},
+		{"override-raw", cfg.Payload.OverrideRaw, true},
+		{"append", cfg.Payload.Append, false},
+	}
+	for _, section := range sections {
+		for i, rule := range section.rules {
+			base := fmt.Sprintf("payload.%s[%d]", section.name, i)
+			v.checkPayloadModels(cfg, base, rule.Models, rule.When)
+			for _, path := range sortedMapKeys(rule.Params) {
+				paramPath := joinConfigPath(base+".params", path)
+				if !v.checkPayloadPath(paramPath, path) || !section.raw {
+					continue
+				}
+				if raw, ok := payloadRawString(rule.Params[path]); ok {
+					if trimmed := bytes.TrimSpace(raw); len(trimmed) == 0 || !json.Valid(trimmed) {
+						v.errorf(paramPath, "value is not valid JSON; the rule is dropped")
+					}
+				}
+			}
+		}
+	}
+	for i, rule := range cfg.Payload.Filter {
+		base := fmt.Sprintf("payload.filter[%d]", i)
+		v.checkPayloadModels(cfg, base, rule.Models, rule.When)
+		for j, path := range rule.Params {
+			v.checkPayloadPath(fmt.Sprintf("%s.params[%d]", base, j), path)
+		}
+	}
+	for i, rule := range cfg.Payload.Rename {
+		base := fmt.Sprintf("payload.rename[%d]", i)
+		v.checkPayloadModels(cfg, base, rule.Models, rule.When)
+		for _, from := range sortedMapKeys(rule.Params) {
+			paramPath := joinConfigPath(base+".params", from)
+			if v.checkPayloadPath(paramPath, from) {
+				v.checkPayloadPath(paramPath, rule.Params[from])
+			}
+		}
+	}
+	for i, rule := range cfg.Payload.DeleteItems {
+		base := fmt.Sprintf("payload.delete-items[%d]", i)
+		v.checkPayloadModels(cfg, base, rule.Models, rule.When)
+		for _, path := range sortedMapKeys(rule.Params) {
+			v.checkPayloadPath(joinConfigPath(base+".params", path), path)
+		}
+	}
+	for i, rule := range cfg.Payload.Clamp {
+		base := fmt.Sprintf("payload.clamp[%d]", i)
+		v.checkPayloadModels(cfg, base, rule.Models, rule.When)
+		for _, path := range sortedMapKeys(rule.Params) {
+			paramPath := joinConfigPath(base+".params", path)
+			if !v.checkPayloadPath(paramPath, path) {
+				continue
+			}
+			bounds := rule.Params[path]
+			if bounds.Min == nil && bounds.Max == nil {
+				v.warnf(paramPath, "clamp has neither min nor max")
+			} else if bounds.Min != nil && bounds.Max != nil && *bounds.Min > *bounds.Max {
+				v.errorf(paramPath, "min %v is greater than max %v", *bounds.Min, *bounds.Max)
+			}
+		}
+	}
+}
+
+func (v *configValidator) checkPayloadModels(cfg *Config, base string, models []PayloadModelRule, when *PayloadCondition) {
+	if when != nil {
+		v.checkPayloadCondition(cfg, base+".when", when)
+	}
+	if len(models) == 0 {
+		if when == nil {
+			v.warnf(base, "rule has no models or conditions and never applies")
+		}
+		return
+	}
+	for i, model := range models {
+		path := fmt.Sprintf("%s.models[%d]", base, i)
+		if strings.TrimSpace(model.Name) == "" {
+			v.warnf(path+".name", "model entry has no name and never matches")
+		}
+		protocol := strings.ToLower(strings.TrimSpace(model.Protocol))
+		if _, ok := knownPayloadProtocols[protocol]; protocol != "" && !ok {
+			v.warnf(path+".protocol", "unknown protocol %q", model.Protocol)
+		}
+	}
+}
+
+// checkPayloadCondition flags conditions that can never match.
+func (v *configValidator) checkPayloadCondition(cfg *Config, base string, when *PayloadCondition) {
+	if len(when.Tenants) > 0 {
+		known := make(map[string]struct{}, len(cfg.Tenants))
+		for _, tenant := range cfg.Tenants {
+			known[strings.ToLower(strings.TrimSpace(tenant.Name))] = struct{}{}
+		}
+		for i, tenant := range when.Tenants {
+			if _, ok := known[strings.ToLower(strings.TrimSpace(tenant))]; !ok {
+				v.warnf(fmt.Sprintf("%s.tenants[%d]", base, i), "tenant %q is not declared under tenants", tenant)
+			}
+		}
+	}
+	for name := range when.Headers {
+		if strings.TrimSpace(name) == "" {
+			v.errorf(base+".headers", "empty header name")
+		}
+	}
+	for i, predicate := range when.Body {
+		if strings.TrimSpace(predicate.Path) == "" {
+			v.errorf(fmt.Sprintf("%s.body[%d].path", base, i), "body condition has no path")
+		}
+	}
+}
+
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkPayloadPath reports JSON paths that sjson cannot write or delete. sjson.Set
+// silently ignores wildcards, queries and modifiers, so Delete is used for both
+// cases because it rejects such complex paths explicitly.
+func (v *configValidator) checkPayloadPath(configPath, jsonPath string) bool {
+	trimmed := strings.TrimSpace(jsonPath)
+	if trimmed == "" {
+		v.errorf(configPath, "empty JSON path")
+		return false
+	}
+	if hasEmptyPathSegment(trimmed) {
+		v.errorf(configPath, "invalid JSON path %q: empty path segment", jsonPath)
+		return false
+	}
+	if _, err := sjson.Delete(`{}`, trimmed); err != nil {
+		v.errorf(configPath, "invalid JSON path %q: wildcards, queries and modifiers are not supported", jsonPath)
+		return false
+	}
+	return true
+}
+
+// hasEmptyPathSegment reports leading, trailing or doubled unescaped dots.
+func hasEmptyPathSegment(path string) bool {
+	segmentLen := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++
+			segmentLen++
+		case '.':
+			if segmentLen == 0 {
+				return true
+			}
+			segmentLen = 0
+		default:
+			segmentLen++
+		}
+	}
+	return segmentLen == 0
+}
+
+func sortIssues(issues []ValidationIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		li, lj := issues[i].Line, issues[j].Line
+		if li == 0 || lj == 0 {
+			return li != 0 && lj == 0
+		}
+		if li != lj {
+			return li < lj
+		}
+		return issues[i].Column < issues[j].Column
+	})
+}
+
+// ConfiguredModel is a model exposed by a credential declared in the config file.
+type ConfiguredModel struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+	Source   string `json:"source"`
+}
+
+// ConfiguredModels lists the model IDs that API key credentials in cfg expose, with
+// prefixes applied. Models of OAuth credentials depend on the auth files and are not
+// included.
+func ConfiguredModels(cfg *Config) []ConfiguredModel {
+	if cfg == nil {
+		return nil
+	}
+	var out []ConfiguredModel
+	seen := make(map[string]struct{})
+	add := func(provider, source, prefix, id string) {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			return
+		}
+		ids := []string{id}
+		if p := normalizeModelPrefix(prefix); p != "" {
+			ids = []string{p + "/" + id}
+			if !cfg.ForceModelPrefix {
+				ids = append(ids, id)
+			}
+		}
+		for _, modelID := range ids {
+			key := provider + "\x00" + strings.ToLower(modelID)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			out = append(out, ConfiguredModel{ID: modelID, Provider: provider, Source: source})
+		}
+	}
+	addStatic := func(provider, source, prefix string, models []*registry.ModelInfo, excluded []string) {
+		for _, model := range models {
+			if model != nil && !matchesAnyPattern(excluded, model.ID) {
+				add(provider, source, prefix, model.ID)
+			}
+		}
+	}
+
+	for i, entry := range cfg.GeminiKey {
+		source := fmt.Sprintf("gemini-api-key[%d]", i)
+		if len(entry.Models) == 0 {
+			addStatic("gemini", source, entry.Prefix, registry.GetGeminiModels(), entry.ExcludedModels)
+		}
+		for _, model := range entry.Models {
+			add("gemini", source, entry.Prefix, aliasOrName(model.Alias, model.Name))
+		}
+	}
+	for i, entry := range cfg.VertexCompatAPIKey {
+		source := fmt.Sprintf("vertex-api-key[%d]", i)
+		if len(entry.Models) == 0 {
+			addStatic("vertex", source, entry.Prefix, registry.GetGeminiVertexModels(), entry.ExcludedModels)
+		}
+		for _, model := range entry.Models {
+			add("vertex", source, entry.Prefix, aliasOrName(model.Alias, model.Name))
+		}
+	}
+	for i, entry := range cfg.ClaudeKey {
+		source := fmt.Sprintf("claude-api-key[%d]", i)
+		if len(entry.Models) == 0 {
+			addStatic("claude", source, entry.Prefix, registry.GetClaudeModels(), entry.ExcludedModels)
+		}
+		for _, model := range entry.Models {
+			add("claude", source, entry.Prefix, aliasOrName(model.Alias, model.Name))
+		}
+	}
+	for i, entry := range cfg.CodexKey {
+		source := fmt.Sprintf("codex-api-key[%d]", i)
+		if len(entry.Models) == 0 {
+			addStatic("codex", source, entry.Prefix, registry.GetCodexProModels(), entry.ExcludedModels)
+		}
+		for _, model := range entry.Models {
+			add("codex", source, entry.Prefix, aliasOrName(model.Alias, model.Name))
+		}
+	}
+	for i, compat := range cfg.OpenAICompatibility {
+		source := fmt.Sprintf("openai-compatibility[%d]", i)
+		provider := strings.ToLower(strings.TrimSpace(compat.Name))
+		for _, model := range compat.Models {
+			add(provider, source, compat.Prefix, aliasOrName(model.Alias, model.Name))
+		}
+	}
+	return out
+}
+
+func aliasOrName(alias, name string) string {
+	if alias = strings.TrimSpace(alias); alias != "" {
+		return alias
+	}
+	return strings.TrimSpace(name)
+}
+
+// matchesAnyPattern reports whether id matches one of the '*' wildcard patterns.
+func matchesAnyPattern(patterns []string, id string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		if matched, _ := regexp.MatchString(expr, strings.ToLower(id)); matched {
+			return true
+		}
+	}
+	return false
+}
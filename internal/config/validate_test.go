@@ -0,0 +1,142 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigReportsPositionedIssues(t *testing.T) {
+	data := []byte(`port: 8317
+routing:
+  strategy: fastest
+proxy-urll: socks5://127.0.0.1:1080
+claude-api-key:
+  - api-key: k1
+    prefix: team
+    proxy-url: ftp://proxy.example.com
+openai-compatibility:
+  - name: kimi
+    prefix: team
+    base-url: https://api.example.com/v1
+    models:
+      - name: kimi-k2
+        alias: k2
+payload:
+  override:
+    - models:
+        - name: gpt-*
+      params:
+        "reasoning..effort": high
+  filter:
+    - models:
+        - name: gpt-*
+      params:
+        - "tools.#(name==x)"
+ampcode:
+  model-mappings:
+    - from: claude-opus
+      to: team/k2
+    - from: "("
+      to: k2
+      regex: true
+request-retry: many
+`)
+	cfg, issues := ValidateConfig(data)
+	if cfg != nil {
+		t.Fatalf("ValidateConfig() returned a config despite decode errors")
+	}
+	want := []struct {
+		severity, path string
+		line, column   int
+		contains       string
+	}{
+		{SeverityError, "routing.strategy", 3, 13, "fastest"},
+		{SeverityError, "proxy-urll", 4, 1, `did you mean "proxy-url"`},
+		{SeverityError, "claude-api-key[0].proxy-url", 8, 16, "unsupported proxy scheme"},
+		{SeverityWarning, "openai-compatibility[0].prefix", 11, 13, "also used by claude-api-key"},
+		{SeverityError, "payload.override[0].params.reasoning..effort", 21, 30, "empty path segment"},
+		{SeverityError, "payload.filter[0].params[0]", 26, 11, "not supported"},
+		{SeverityError, "ampcode.model-mappings[1].from", 31, 13, "invalid regular expression"},
+		{SeverityError, "", 34, 16, "cannot unmarshal"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("ValidateConfig() returned %d issues, want %d:\n%v", len(issues), len(want), issues)
+	}
+	for i, w := range want {
+		got := issues[i]
+		if got.Severity != w.severity || got.Path != w.path || got.Line != w.line || got.Column != w.column || !strings.Contains(got.Message, w.contains) {
+			t.Errorf("issue %d = %+v, want %s %s at %d:%d containing %q", i, got, w.severity, w.path, w.line, w.column, w.contains)
+		}
+	}
+}
+
+func TestValidateConfigAcceptsValidConfigAndListsModels(t *testing.T) {
+	data := []byte(`force-model-prefix: true
+generative-language-api-key: ["legacy"]
+openai-compatibility:
+  - name: kimi
+    prefix: team
+    base-url: https://api.example.com/v1
+    models:
+      - name: kimi-k2
+        alias: k2
+ampcode:
+  model-mappings:
+    - from: claude-opus
+      to: team/k2
+`)
+	cfg, issues := ValidateConfig(data)
+	if cfg == nil || HasValidationErrors(issues) {
+		t.Fatalf("ValidateConfig() = %v, %v", cfg, issues)
+	}
+	if len(issues) != 1 || issues[0].Path != "generative-language-api-key" || issues[0].Severity != SeverityWarning {
+		t.Fatalf("issues = %v, want a single legacy-key warning", issues)
+	}
+	models := ConfiguredModels(cfg)
+	if len(models) != 1 || models[0].ID != "team/k2" || models[0].Provider != "kimi" {
+		t.Fatalf("ConfiguredModels() = %+v", models)
+	}
+}
+
+func TestValidateConfigChecksConditionalPayloadRules(t *testing.T) {
+	data := []byte(`payload:
+  override:
+    - when:
+        tenants: [ghost]
+      params:
+        temperature: 0.2
+  clamp:
+    - models:
+        - name: "*"
+      params:
+        temperature: { min: 2, max: 1 }
+  rename:
+    - when:
+        stream: true
+      params:
+        max_tokens: "a..b"
+`)
+	_, issues := ValidateConfig(data)
+	want := []struct{ severity, path, contains string }{
+		{SeverityWarning, "payload.override[0].when.tenants[0]", "not declared"},
+		{SeverityError, "payload.rename[0].params.max_tokens", "empty path segment"},
+		{SeverityError, "payload.clamp[0].params.temperature", "greater than max"},
+	}
+	for _, w := range want {
+		found := false
+		for _, got := range issues {
+			if got.Severity == w.severity && got.Path == w.path && strings.Contains(got.Message, w.contains) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing %s %s %q in %v", w.severity, w.path, w.contains, issues)
+		}
+	}
+	for _, got := range issues {
+		if strings.Contains(got.Message, "never applies") {
+			t.Errorf("conditional rule reported as never applying: %v", got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package confighistory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// ResolveDir returns the directory holding versions for the config at configPath
+// when no token store keeps them.
+func ResolveDir(cfg config.ConfigHistoryConfig, configPath string) string {
+	if cfg.Dir != "" {
+		return cfg.Dir
+	}
+	if base := util.WritablePath(); base != "" {
+		return filepath.Join(base, "config-history")
+	}
+	return filepath.Join(filepath.Dir(configPath), "config-history")
+}
+
+// dirBackend stores versions in a directory as <id>.yaml with <id>.json metadata.
+type dirBackend string
+
+// DirBackend returns a Backend keeping versions in dir. Stores that mirror a
+// directory elsewhere, such as the git store, wrap it.
+func DirBackend(dir string) Backend { return dirBackend(dir) }
+
+func (d dirBackend) SaveConfigVersion(_ context.Context, version Version, content []byte) error {
+	meta, err := json.MarshalIndent(version, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(string(d), 0o700); err != nil {
+		return fmt.Errorf("create config history directory: %w", err)
+	}
+	if err = os.WriteFile(d.contentPath(version.ID), content, 0o600); err != nil {
+		return fmt.Errorf("write config version: %w", err)
+	}
+	if err = os.WriteFile(d.metaPath(version.ID), meta, 0o600); err != nil {
+		_ = os.Remove(d.contentPath(version.ID))
+		return fmt.Errorf("write config version metadata: %w", err)
+	}
+	return nil
+}
+
+func (d dirBackend) ListConfigVersions(context.Context) ([]Version, error) {
+	entries, err := os.ReadDir(string(d))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config history: %w", err)
+	}
+	versions := make([]Version, 0, len(entries))
+	for _, entry := range entries {
+		id, isMeta := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !isMeta || !ValidID(id) {
+			continue
+		}
+		raw, errRead := os.ReadFile(filepath.Join(string(d), entry.Name()))
+		if errRead != nil {
+			continue
+		}
+		var version Version
+		if json.Unmarshal(raw, &version) != nil || version.ID != id {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func (d dirBackend) ConfigVersion(_ context.Context, id string) (Version, []byte, error) {
+	raw, err := os.ReadFile(d.metaPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Version{}, nil, ErrNotFound
+	}
+	if err != nil {
+		return Version{}, nil, err
+	}
+	var version Version
+	if err = json.Unmarshal(raw, &version); err != nil {
+		return Version{}, nil, fmt.Errorf("decode config version %s: %w", id, err)
+	}
+	content, err := os.ReadFile(d.contentPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Version{}, nil, ErrNotFound
+	}
+	if err != nil {
+		return Version{}, nil, err
+	}
+	return version, content, nil
+}
+
+func (d dirBackend) DeleteConfigVersion(_ context.Context, id string) error {
+	errContent := os.Remove(d.contentPath(id))
+	errMeta := os.Remove(d.metaPath(id))
+	for _, err := range []error{errContent, errMeta} {
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d dirBackend) contentPath(id string) string { return filepath.Join(string(d), id+".yaml") }
+
+func (d dirBackend) metaPath(id string) string { return filepath.Join(string(d), id+".json") }
@@ -0,0 +1,308 @@
+// Package confighistory records every config.yaml write as a version so edits can be
+// reviewed, diffed and rolled back. Versions are kept by the active token store when
+// it implements Backend, and on the local filesystem next to the config otherwise.
+package confighistory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Sources describing what produced a version.
+const (
+	SourceManagement = "management"
+	SourceFile       = "file"
+	SourceRollback   = "rollback"
+	SourceStartup    = "startup"
+)
+
+const idLayout = "20060102T150405.000000000"
+
+// backendTimeout bounds a single call into a remote backend.
+const backendTimeout = 30 * time.Second
+
+// ErrNotFound is returned when a version id does not exist.
+var ErrNotFound = errors.New("config version not found")
+
+var validID = regexp.MustCompile(`^[0-9]{8}T[0-9]{6}\.[0-9]{9}$`)
+
+// ValidID reports whether id has the format of a version id. Backends use it to
+// reject ids before turning them into keys or paths.
+func ValidID(id string) bool { return validID.MatchString(id) }
+
+// Version describes one recorded config.
+type Version struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Author    string    `json:"author"`
+	Source    string    `json:"source"`
+	Note      string    `json:"note,omitempty"`
+	SHA256    string    `json:"sha256"`
+	Size      int       `json:"size"`
+}
+
+// Backend persists versions. Token stores implement it so the history lives with
+// the rest of their data; ConfigVersion returns ErrNotFound for unknown ids.
+type Backend interface {
+	SaveConfigVersion(ctx context.Context, version Version, content []byte) error
+	ListConfigVersions(ctx context.Context) ([]Version, error)
+	ConfigVersion(ctx context.Context, id string) (Version, []byte, error)
+	DeleteConfigVersion(ctx context.Context, id string) error
+}
+
+// History records config versions into a Backend, retaining at most limit of them.
+// Writes are serialized by mu; reads go straight to the backend.
+type History struct {
+	backend Backend
+	dir     string
+	limit   int
+	mu      sync.Mutex
+	last    time.Time
+	// retained mirrors the stored versions, newest first, once loaded is set, so a
+	// save does not have to list the backend to dedupe and trim.
+	retained []Version
+	loaded   bool
+}
+
+var histories struct {
+	mu      sync.Mutex
+	backend Backend
+	shared  *History
+	byDir   map[string]*History
+}
+
+// SetBackend routes the shared history into b, typically the active token store.
+// A nil b restores the filesystem default.
+func SetBackend(b Backend) {
+	histories.mu.Lock()
+	defer histories.mu.Unlock()
+	histories.backend = b
+	histories.shared = nil
+}
+
+// For returns the shared history for cfg and configPath so the management API and
+// the watcher record into the same place. The retention limit follows cfg.
+func For(cfg *config.Config, configPath string) *History {
+	var settings config.ConfigHistoryConfig
+	if cfg != nil {
+		settings = cfg.ConfigHistory
+	}
+	histories.mu.Lock()
+	defer histories.mu.Unlock()
+	var h *History
+	if histories.backend != nil {
+		if histories.shared == nil {
+			histories.shared = New(histories.backend, settings.Limit())
+		}
+		h = histories.shared
+	} else {
+		dir := ResolveDir(settings, configPath)
+		if histories.byDir == nil {
+			histories.byDir = make(map[string]*History)
+		}
+		var ok bool
+		if h, ok = histories.byDir[dir]; !ok {
+			h = Open(dir, settings.Limit())
+			histories.byDir[dir] = h
+		}
+	}
+	h.mu.Lock()
+	h.limit = settings.Limit()
+	h.mu.Unlock()
+	return h
+}
+
+// New returns a history stored in backend retaining at most limit versions.
+func New(backend Backend, limit int) *History {
+	if limit <= 0 {
+		limit = config.DefaultConfigHistoryMaxVersions
+	}
+	return &History{backend: backend, limit: limit}
+}
+
+// Open returns a history rooted at dir on the local filesystem.
+func Open(dir string, limit int) *History {
+	h := New(dirBackend(dir), limit)
+	h.dir = dir
+	return h
+}
+
+// Dir returns the directory holding the versions, or "" when a store keeps them.
+func (h *History) Dir() string {
+	if h == nil {
+		return ""
+	}
+	return h.dir
+}
+
+// Record stores content as a new version unless it matches the latest one, in which
+// case the latest version is returned with recorded=false.
+func (h *History) Record(content []byte, author, source, note string) (Version, bool, error) {
+	if h == nil {
+		return Version{}, false, fmt.Errorf("config history unavailable")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.recordLocked(content, author, source, note)
+}
+
+// RecordFile reads the config at path and records it. Reading under the history lock
+// means a write in progress through RecordWrite is recorded first, with its author.
+func (h *History) RecordFile(path, author, source, note string) (Version, bool, error) {
+	if h == nil {
+		return Version{}, false, fmt.Errorf("config history unavailable")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Version{}, false, nil
+	}
+	if err != nil {
+		return Version{}, false, fmt.Errorf("read config for versioning: %w", err)
+	}
+	if len(content) == 0 {
+		return Version{}, false, nil
+	}
+	return h.recordLocked(content, author, source, note)
+}
+
+// RecordWrite runs write, which updates the config at path, and records the result
+// as one version attributed to author. The watcher notices the same change later;
+// it finds the content already recorded and does not add a version of its own.
+// Only the error of write is returned; recording failures are logged.
+func (h *History) RecordWrite(path, author, source, note string, write func() error) error {
+	if h == nil {
+		return write()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := write(); err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err == nil {
+		_, _, err = h.recordLocked(content, author, source, note)
+	}
+	if err != nil {
+		log.WithError(err).Warn("config history: failed to record version")
+	}
+	return nil
+}
+
+func (h *History) recordLocked(content []byte, author, source, note string) (Version, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), backendTimeout)
+	defer cancel()
+
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+	if !h.loaded {
+		versions, err := h.list(ctx)
+		if err != nil {
+			return Version{}, false, err
+		}
+		h.retained, h.loaded = versions, true
+	}
+	versions := h.retained
+	if len(versions) > 0 && versions[0].SHA256 == digest {
+		return versions[0], false, nil
+	}
+
+	now := time.Now().UTC()
+	// Keep ids strictly increasing even when two writes share a clock reading.
+	if len(versions) > 0 && versions[0].Timestamp.After(h.last) {
+		h.last = versions[0].Timestamp
+	}
+	if !now.After(h.last) {
+		now = h.last.Add(time.Nanosecond)
+	}
+	h.last = now
+	version := Version{
+		ID:        now.Format(idLayout),
+		Timestamp: now,
+		Author:    strings.TrimSpace(author),
+		Source:    source,
+		Note:      note,
+		SHA256:    digest,
+		Size:      len(content),
+	}
+	if err := h.backend.SaveConfigVersion(ctx, version, content); err != nil {
+		return Version{}, false, err
+	}
+
+	versions = append([]Version{version}, versions...)
+	keep := min(len(versions), h.limit)
+	for _, expired := range versions[keep:] {
+		if errDelete := h.backend.DeleteConfigVersion(ctx, expired.ID); errDelete != nil {
+			log.WithError(errDelete).Warnf("config history: failed to drop version %s", expired.ID)
+		}
+	}
+	h.retained = versions[:keep]
+	return version, true, nil
+}
+
+// List returns the recorded versions, newest first.
+func (h *History) List() ([]Version, error) {
+	if h == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backendTimeout)
+	defer cancel()
+	return h.list(ctx)
+}
+
+// Get returns the version metadata and content for id.
+func (h *History) Get(id string) (Version, []byte, error) {
+	if h == nil || !ValidID(id) {
+		return Version{}, nil, ErrNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backendTimeout)
+	defer cancel()
+	return h.backend.ConfigVersion(ctx, id)
+}
+
+// Previous returns the version recorded immediately before id. ok is false when id
+// is the oldest retained version.
+func (h *History) Previous(id string) (version Version, content []byte, ok bool, err error) {
+	if h == nil || !ValidID(id) {
+		return Version{}, nil, false, ErrNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backendTimeout)
+	defer cancel()
+	versions, err := h.list(ctx)
+	if err != nil {
+		return Version{}, nil, false, err
+	}
+	for i, v := range versions {
+		if v.ID != id {
+			continue
+		}
+		if i+1 >= len(versions) {
+			return Version{}, nil, false, nil
+		}
+		version, content, err = h.backend.ConfigVersion(ctx, versions[i+1].ID)
+		return version, content, err == nil, err
+	}
+	return Version{}, nil, false, ErrNotFound
+}
+
+func (h *History) list(ctx context.Context) ([]Version, error) {
+	versions, err := h.backend.ListConfigVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].ID > versions[j].ID })
+	return versions, nil
+}
@@ -0,0 +1,134 @@
+package confighistory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryRecordDedupesAndTrims(t *testing.T) {
+	h := Open(t.TempDir(), 2)
+
+	first, recorded, err := h.Record([]byte("port: 1\n"), "tui", SourceManagement, "")
+	if err != nil || !recorded {
+		t.Fatalf("Record(first) = %v, %v", recorded, err)
+	}
+	if _, recorded, _ = h.Record([]byte("port: 1\n"), "file", SourceFile, ""); recorded {
+		t.Fatal("unchanged content was recorded again")
+	}
+	second, _, _ := h.Record([]byte("port: 2\n"), "file", SourceFile, "")
+	third, _, _ := h.Record([]byte("port: 3\n"), "management", SourceRollback, "rollback of "+second.ID)
+
+	versions, err := h.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(versions) != 2 || versions[0].ID != third.ID || versions[1].ID != second.ID {
+		t.Fatalf("List() = %+v, want the two newest versions", versions)
+	}
+	if _, _, err = h.Get(first.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(trimmed) error = %v, want ErrNotFound", err)
+	}
+
+	version, content, err := h.Get(third.ID)
+	if err != nil || string(content) != "port: 3\n" || version.Note != "rollback of "+second.ID {
+		t.Fatalf("Get() = %+v, %q, %v", version, content, err)
+	}
+	prev, prevContent, ok, err := h.Previous(third.ID)
+	if err != nil || !ok || prev.ID != second.ID || string(prevContent) != "port: 2\n" {
+		t.Fatalf("Previous() = %+v, %q, %v, %v", prev, prevContent, ok, err)
+	}
+	if _, _, ok, err = h.Previous(second.ID); err != nil || ok {
+		t.Fatalf("Previous(oldest) = %v, %v", ok, err)
+	}
+}
+
+func TestHistoryRejectsInvalidIDs(t *testing.T) {
+	h := Open(t.TempDir(), 0)
+	if _, _, err := h.Record([]byte("port: 1\n"), "tui", SourceManagement, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"../escape", "", "20260101T000000"} {
+		if _, _, err := h.Get(id); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Get(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestHistoryRecordWriteKeepsCallerAuthor(t *testing.T) {
+	h := Open(t.TempDir(), 0)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	written := make(chan struct{})
+	watched := make(chan error)
+	err := h.RecordWrite(path, "tui@127.0.0.1", SourceManagement, "", func() error {
+		if errWrite := os.WriteFile(path, []byte("port: 1\n"), 0o600); errWrite != nil {
+			return errWrite
+		}
+		// The watcher sees the change while the write path still holds the history.
+		go func() {
+			close(written)
+			_, _, errRecord := h.RecordFile(path, "file", SourceFile, "")
+			watched <- errRecord
+		}()
+		<-written
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RecordWrite() error = %v", err)
+	}
+	if err = <-watched; err != nil {
+		t.Fatalf("RecordFile() error = %v", err)
+	}
+
+	versions, err := h.List()
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("List() = %+v, %v; want a single version", versions, err)
+	}
+	if versions[0].Author != "tui@127.0.0.1" || versions[0].Source != SourceManagement {
+		t.Fatalf("version = %+v, want it attributed to the write path", versions[0])
+	}
+}
+
+type countingBackend struct {
+	Backend
+	lists int
+}
+
+func (b *countingBackend) ListConfigVersions(ctx context.Context) ([]Version, error) {
+	b.lists++
+	return b.Backend.ListConfigVersions(ctx)
+}
+
+func TestHistoryRecordListsBackendOnceAndReadsWithoutLock(t *testing.T) {
+	backend := &countingBackend{Backend: dirBackend(t.TempDir())}
+	h := New(backend, 2)
+	for _, content := range []string{"port: 1\n", "port: 2\n", "port: 2\n", "port: 3\n"} {
+		if _, _, err := h.Record([]byte(content), "tui", SourceManagement, ""); err != nil {
+			t.Fatalf("Record(%q) error = %v", content, err)
+		}
+	}
+	if backend.lists != 1 {
+		t.Fatalf("ListConfigVersions called %d times across records, want 1", backend.lists)
+	}
+
+	// A slow write holding the history must not stall readers.
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	listed := make(chan []Version, 1)
+	go func() {
+		versions, _ := h.List()
+		listed <- versions
+	}()
+	select {
+	case versions := <-listed:
+		if len(versions) != 2 {
+			t.Fatalf("List() = %+v, want the two retained versions", versions)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("List() blocked on the write lock")
+	}
+}
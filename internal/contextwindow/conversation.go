@@ -0,0 +1,437 @@
+package contextwindow
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// itemKind classifies one conversation entry.
+type itemKind struct {
+	// pinned entries (system prompts) are never dropped.
+	pinned bool
+	// turnStart marks a user message that opens a new turn.
+	turnStart bool
+	// calls and results hold the tool call identifiers an entry opens and closes.
+	calls   []string
+	results []string
+}
+
+// dialect maps a request format onto a flat list of conversation entries.
+type dialect struct {
+	path     string
+	classify func(item gjson.Result) itemKind
+	// trim shortens the tool outputs in item to max characters and reports how many it trimmed.
+	trim   func(raw string, max int) (string, int)
+	render func(item gjson.Result) string
+}
+
+func dialectFor(format string) *dialect {
+	switch format {
+	case "openai":
+		return &dialect{path: "messages", classify: classifyOpenAI, trim: trimOpenAI, render: renderOpenAI}
+	case "openai-response":
+		return &dialect{path: "input", classify: classifyResponses, trim: trimResponses, render: renderResponses}
+	case "claude":
+		return &dialect{path: "messages", classify: classifyClaude, trim: trimClaude, render: renderClaude}
+	case "gemini":
+		return &dialect{path: "contents", classify: classifyGemini, trim: trimGemini, render: renderGemini}
+	case "gemini-cli":
+		return &dialect{path: "request.contents", classify: classifyGemini, trim: trimGemini, render: renderGemini}
+	default:
+		return nil
+	}
+}
+
+type entry struct {
+	raw  string
+	kind itemKind
+}
+
+// conversation is a parsed payload split into turns. A turn starts at a user message
+// and runs until the next one; a user message only opens a turn once every tool call
+// made so far has received its result, so calls and results always share a turn.
+type conversation struct {
+	dialect *dialect
+	payload []byte
+	entries []entry
+	// turns holds the entry indexes of each turn, oldest first. Pinned entries belong to none.
+	turns [][]int
+}
+
+func parseConversation(format string, payload []byte) *conversation {
+	d := dialectFor(format)
+	if d == nil {
+		return nil
+	}
+	array := gjson.GetBytes(payload, d.path)
+	if !array.IsArray() {
+		return nil
+	}
+	conv := &conversation{dialect: d, payload: payload}
+	pending := make(map[string]int)
+	open := 0
+	for _, item := range array.Array() {
+		kind := d.classify(item)
+		idx := len(conv.entries)
+		conv.entries = append(conv.entries, entry{raw: item.Raw, kind: kind})
+		if kind.pinned {
+			continue
+		}
+		if len(conv.turns) == 0 || (kind.turnStart && open == 0) {
+			conv.turns = append(conv.turns, nil)
+		}
+		last := len(conv.turns) - 1
+		conv.turns[last] = append(conv.turns[last], idx)
+		for _, id := range kind.results {
+			if pending[id] > 0 {
+				pending[id]--
+				open--
+			}
+		}
+		for _, id := range kind.calls {
+			pending[id]++
+			open++
+		}
+	}
+	return conv
+}
+
+// olderTurns returns how many turns precede the keep most recent ones.
+func (c *conversation) olderTurns(keep int) int {
+	return max(0, len(c.turns)-keep)
+}
+
+// trimToolOutputs shortens tool outputs in every turn except the keep most recent.
+func (c *conversation) trimToolOutputs(keep, maxChars int) int {
+	trimmed := 0
+	for _, turn := range c.turns[:c.olderTurns(keep)] {
+		for _, idx := range turn {
+			raw, n := c.dialect.trim(c.entries[idx].raw, maxChars)
+			if n > 0 {
+				c.entries[idx].raw = raw
+				trimmed += n
+			}
+		}
+	}
+	return trimmed
+}
+
+// dropOldest removes the n oldest turns.
+func (c *conversation) dropOldest(n int) {
+	n = min(n, len(c.turns))
+	if n <= 0 {
+		return
+	}
+	drop := make(map[int]struct{})
+	for _, turn := range c.turns[:n] {
+		for _, idx := range turn {
+			drop[idx] = struct{}{}
+		}
+	}
+	kept := make([]entry, 0, len(c.entries)-len(drop))
+	remap := make(map[int]int, len(c.entries))
+	for i, e := range c.entries {
+		if _, ok := drop[i]; ok {
+			continue
+		}
+		remap[i] = len(kept)
+		kept = append(kept, e)
+	}
+	turns := make([][]int, 0, len(c.turns)-n)
+	for _, turn := range c.turns[n:] {
+		mapped := make([]int, len(turn))
+		for i, idx := range turn {
+			mapped[i] = remap[idx]
+		}
+		turns = append(turns, mapped)
+	}
+	c.entries, c.turns = kept, turns
+}
+
+// renderTurns renders the n oldest turns as plain text for summarization.
+func (c *conversation) renderTurns(n int) string {
+	var b strings.Builder
+	for _, turn := range c.turns[:min(n, len(c.turns))] {
+		for _, idx := range turn {
+			if text := strings.TrimSpace(c.dialect.render(gjson.Parse(c.entries[idx].raw))); text != "" {
+				b.WriteString(text)
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// turnText returns the raw JSON of the turn, used for size estimates.
+func (c *conversation) turnText(turn int) string {
+	var b strings.Builder
+	for _, idx := range c.turns[turn] {
+		b.WriteString(c.entries[idx].raw)
+	}
+	return b.String()
+}
+
+func (c *conversation) build() []byte {
+	raws := make([]string, len(c.entries))
+	for i, e := range c.entries {
+		raws[i] = e.raw
+	}
+	out, err := sjson.SetRawBytes(c.payload, c.dialect.path, []byte("["+strings.Join(raws, ",")+"]"))
+	if err != nil {
+		return c.payload
+	}
+	return out
+}
+
+// truncate shortens s to max characters, noting how much was removed.
+func truncate(s string, maxChars int) (string, bool) {
+	if utf8.RuneCountInString(s) <= maxChars {
+		return s, false
+	}
+	runes := []rune(s)
+	return string(runes[:maxChars]) + fmt.Sprintf("\n[... %d characters trimmed]", len(runes)-maxChars), true
+}
+
+// trimStringAt truncates the string at path, or the text fields of the array of parts
+// at path, inside raw.
+func trimStringAt(raw, path, textField string, maxChars int) (string, int) {
+	value := gjson.Get(raw, path)
+	switch {
+	case value.Type == gjson.String:
+		if short, ok := truncate(value.Str, maxChars); ok {
+			if out, err := sjson.Set(raw, path, short); err == nil {
+				return out, 1
+			}
+		}
+	case value.IsArray():
+		trimmed := 0
+		for i, part := range value.Array() {
+			text := part.Get(textField)
+			if text.Type != gjson.String {
+				continue
+			}
+			if short, ok := truncate(text.Str, maxChars); ok {
+				if out, err := sjson.Set(raw, fmt.Sprintf("%s.%d.%s", path, i, textField), short); err == nil {
+					raw = out
+					trimmed++
+				}
+			}
+		}
+		if trimmed > 0 {
+			return raw, 1
+		}
+	}
+	return raw, 0
+}
+
+// contentText flattens a string or an array of text parts.
+func contentText(content gjson.Result, textField string) string {
+	if content.Type == gjson.String {
+		return content.Str
+	}
+	var parts []string
+	for _, part := range content.Array() {
+		if text := part.Get(textField); text.Type == gjson.String {
+			parts = append(parts, text.Str)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func classifyOpenAI(item gjson.Result) itemKind {
+	switch item.Get("role").String() {
+	case "system", "developer":
+		return itemKind{pinned: true}
+	case "user":
+		return itemKind{turnStart: true}
+	case "assistant":
+		var kind itemKind
+		for _, call := range item.Get("tool_calls").Array() {
+			kind.calls = append(kind.calls, call.Get("id").String())
+		}
+		return kind
+	case "tool":
+		return itemKind{results: []string{item.Get("tool_call_id").String()}}
+	}
+	return itemKind{}
+}
+
+func trimOpenAI(raw string, maxChars int) (string, int) {
+	if gjson.Get(raw, "role").String() != "tool" {
+		return raw, 0
+	}
+	return trimStringAt(raw, "content", "text", maxChars)
+}
+
+func renderOpenAI(item gjson.Result) string {
+	role := item.Get("role").String()
+	text := contentText(item.Get("content"), "text")
+	for _, call := range item.Get("tool_calls").Array() {
+		text += fmt.Sprintf("\n[tool call %s %s]", call.Get("function.name").String(), call.Get("function.arguments").String())
+	}
+	return role + ": " + text
+}
+
+func classifyResponses(item gjson.Result) itemKind {
+	switch item.Get("type").String() {
+	case "function_call", "custom_tool_call":
+		return itemKind{calls: []string{item.Get("call_id").String()}}
+	case "function_call_output", "custom_tool_call_output":
+		return itemKind{results: []string{item.Get("call_id").String()}}
+	case "", "message":
+		switch item.Get("role").String() {
+		case "system", "developer":
+			return itemKind{pinned: true}
+		case "user":
+			return itemKind{turnStart: true}
+		}
+	}
+	return itemKind{}
+}
+
+func trimResponses(raw string, maxChars int) (string, int) {
+	switch gjson.Get(raw, "type").String() {
+	case "function_call_output", "custom_tool_call_output":
+		return trimStringAt(raw, "output", "text", maxChars)
+	}
+	return raw, 0
+}
+
+func renderResponses(item gjson.Result) string {
+	switch item.Get("type").String() {
+	case "function_call", "custom_tool_call":
+		return fmt.Sprintf("assistant: [tool call %s %s]", item.Get("name").String(), item.Get("arguments").String())
+	case "function_call_output", "custom_tool_call_output":
+		return "tool: " + contentText(item.Get("output"), "text")
+	case "", "message":
+		return item.Get("role").String() + ": " + contentText(item.Get("content"), "text")
+	}
+	return ""
+}
+
+func classifyClaude(item gjson.Result) itemKind {
+	var kind itemKind
+	content := item.Get("content")
+	switch item.Get("role").String() {
+	case "user":
+		onlyResults := content.IsArray() && len(content.Array()) > 0
+		for _, block := range content.Array() {
+			if block.Get("type").String() == "tool_result" {
+				kind.results = append(kind.results, block.Get("tool_use_id").String())
+			} else {
+				onlyResults = false
+			}
+		}
+		kind.turnStart = !onlyResults
+	case "assistant":
+		for _, block := range content.Array() {
+			if block.Get("type").String() == "tool_use" {
+				kind.calls = append(kind.calls, block.Get("id").String())
+			}
+		}
+	}
+	return kind
+}
+
+func trimClaude(raw string, maxChars int) (string, int) {
+	trimmed := 0
+	for i, block := range gjson.Get(raw, "content").Array() {
+		if block.Get("type").String() != "tool_result" {
+			continue
+		}
+		var n int
+		raw, n = trimStringAt(raw, fmt.Sprintf("content.%d.content", i), "text", maxChars)
+		trimmed += n
+	}
+	return raw, trimmed
+}
+
+func renderClaude(item gjson.Result) string {
+	content := item.Get("content")
+	if content.Type == gjson.String {
+		return item.Get("role").String() + ": " + content.Str
+	}
+	var parts []string
+	for _, block := range content.Array() {
+		switch block.Get("type").String() {
+		case "text":
+			parts = append(parts, block.Get("text").String())
+		case "tool_use":
+			parts = append(parts, fmt.Sprintf("[tool call %s %s]", block.Get("name").String(), block.Get("input").Raw))
+		case "tool_result":
+			parts = append(parts, "[tool result] "+contentText(block.Get("content"), "text"))
+		}
+	}
+	return item.Get("role").String() + ": " + strings.Join(parts, "\n")
+}
+
+// geminiCallID pairs function calls with responses by id, falling back to the name.
+func geminiCallID(value gjson.Result) string {
+	if id := value.Get("id").String(); id != "" {
+		return id
+	}
+	return value.Get("name").String()
+}
+
+func classifyGemini(item gjson.Result) itemKind {
+	var kind itemKind
+	parts := item.Get("parts").Array()
+	switch item.Get("role").String() {
+	case "model":
+		for _, part := range parts {
+			if call := part.Get("functionCall"); call.Exists() {
+				kind.calls = append(kind.calls, geminiCallID(call))
+			}
+		}
+	default:
+		onlyResults := len(parts) > 0
+		for _, part := range parts {
+			if resp := part.Get("functionResponse"); resp.Exists() {
+				kind.results = append(kind.results, geminiCallID(resp))
+			} else {
+				onlyResults = false
+			}
+		}
+		kind.turnStart = !onlyResults
+	}
+	return kind
+}
+
+func trimGemini(raw string, maxChars int) (string, int) {
+	trimmed := 0
+	for i, part := range gjson.Get(raw, "parts").Array() {
+		response := part.Get("functionResponse.response")
+		if !response.Exists() {
+			continue
+		}
+		short, ok := truncate(response.Raw, maxChars)
+		if !ok {
+			continue
+		}
+		replacement, _ := sjson.Set(`{}`, "result", short)
+		if out, err := sjson.SetRaw(raw, fmt.Sprintf("parts.%d.functionResponse.response", i), replacement); err == nil {
+			raw = out
+			trimmed++
+		}
+	}
+	return raw, trimmed
+}
+
+func renderGemini(item gjson.Result) string {
+	var parts []string
+	for _, part := range item.Get("parts").Array() {
+		switch {
+		case part.Get("text").Exists():
+			parts = append(parts, part.Get("text").String())
+		case part.Get("functionCall").Exists():
+			parts = append(parts, fmt.Sprintf("[tool call %s %s]", part.Get("functionCall.name").String(), part.Get("functionCall.args").Raw))
+		case part.Get("functionResponse").Exists():
+			parts = append(parts, "[tool result] "+part.Get("functionResponse.response").Raw)
+		}
+	}
+	return item.Get("role").String() + ": " + strings.Join(parts, "\n")
+}
@@ -0,0 +1,174 @@
+// Package contextwindow keeps oversized conversations within a model's input limit.
+// Requests are measured with the local tokenizers and, when they exceed the limit,
+// shrunk with the configured strategies: trimming old tool outputs, summarizing older
+// turns through a cheaper model, or dropping the oldest turns. Turns are always
+// removed whole so tool calls stay paired with their results.
+package contextwindow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor/helps"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/systemprompt"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencount"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// HeaderName is the response header describing what the manager removed.
+const HeaderName = "X-CPA-Context-Trimmed"
+
+// summaryPrefix introduces the summary injected into the system prompt.
+const summaryPrefix = "Summary of the earlier conversation, which was condensed to fit the context window:\n"
+
+// Summarizer condenses a plain-text transcript of older turns.
+type Summarizer func(ctx context.Context, transcript string) (string, error)
+
+// Report describes what Fit changed.
+type Report struct {
+	Limit              int64
+	OriginalTokens     int64
+	FinalTokens        int64
+	TrimmedToolOutputs int
+	SummarizedTurns    int
+	DroppedTurns       int
+}
+
+// Changed reports whether the payload was modified.
+func (r Report) Changed() bool {
+	return r.TrimmedToolOutputs > 0 || r.SummarizedTurns > 0 || r.DroppedTurns > 0
+}
+
+// Header renders the report for the HeaderName response header.
+func (r Report) Header() string {
+	return fmt.Sprintf("tokens=%d->%d; limit=%d; dropped-turns=%d; summarized-turns=%d; trimmed-tool-outputs=%d",
+		r.OriginalTokens, r.FinalTokens, r.Limit, r.DroppedTurns, r.SummarizedTurns, r.TrimmedToolOutputs)
+}
+
+// RuleFor returns the first rule whose models match model, or nil.
+func RuleFor(rules []config.ContextWindowRule, model string) *config.ContextWindowRule {
+	for i := range rules {
+		for _, pattern := range rules[i].Models {
+			if util.MatchWildcardFold(pattern, model) {
+				return &rules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Limit returns the input token limit applied to model: the rule override, else the
+// registry's input limit, else its context length. Zero means unknown.
+func Limit(rule *config.ContextWindowRule, model string) int64 {
+	if rule != nil && rule.MaxInputTokens > 0 {
+		return int64(rule.MaxInputTokens)
+	}
+	info := registry.LookupModelInfo(model)
+	if info == nil {
+		return 0
+	}
+	if info.InputTokenLimit > 0 {
+		return int64(info.InputTokenLimit)
+	}
+	return int64(info.ContextLength)
+}
+
+// Fit shrinks payload, a request in the given client format, until it fits within
+// limit minus the rule's reserve. Strategies run in the rule's order and stop as soon
+// as the request fits; a payload that still does not fit is returned in its smallest
+// form. summarize may be nil, in which case the summarize strategy is skipped.
+func Fit(ctx context.Context, rule *config.ContextWindowRule, format, model string, payload []byte, limit int64, summarize Summarizer) ([]byte, Report) {
+	report := Report{Limit: limit}
+	budget := limit
+	if rule != nil {
+		budget -= int64(rule.ReserveTokens)
+	}
+	if rule == nil || budget <= 0 || dialectFor(format) == nil {
+		return payload, report
+	}
+	enc, err := helps.TokenizerForModel(model)
+	if err != nil {
+		log.WithError(err).Debug("context window: tokenizer unavailable")
+		return payload, report
+	}
+	tokens := countTokens(model, format, payload)
+	report.OriginalTokens, report.FinalTokens = tokens, tokens
+	if tokens <= budget {
+		return payload, report
+	}
+
+	for _, strategy := range rule.Strategies {
+		conv := parseConversation(format, payload)
+		if conv == nil {
+			break
+		}
+		switch strategy {
+		case config.ContextStrategyTrimToolOutputs:
+			if n := conv.trimToolOutputs(rule.KeepRecentTurns, rule.ToolOutputMaxChars); n > 0 {
+				payload = conv.build()
+				report.TrimmedToolOutputs += n
+			}
+		case config.ContextStrategySummarize:
+			older := conv.olderTurns(rule.KeepRecentTurns)
+			if older == 0 || summarize == nil {
+				continue
+			}
+			summary, errSummarize := summarize(ctx, conv.renderTurns(older))
+			if errSummarize != nil || strings.TrimSpace(summary) == "" {
+				log.WithError(errSummarize).Warn("context window: summarization failed")
+				continue
+			}
+			conv.dropOldest(older)
+			payload = systemprompt.AppendText(format, conv.build(), summaryPrefix+strings.TrimSpace(summary))
+			report.SummarizedTurns += older
+		case config.ContextStrategyDropOldest:
+			payload, tokens = dropUntilFits(enc, model, format, conv, rule.KeepRecentTurns, tokens, budget, &report)
+		}
+		tokens = countTokens(model, format, payload)
+		report.FinalTokens = tokens
+		if tokens <= budget {
+			break
+		}
+	}
+	return payload, report
+}
+
+// dropUntilFits removes the oldest turns, using per-turn estimates to pick how many
+// to drop before each exact recount.
+func dropUntilFits(enc tokenizer.Codec, model, format string, conv *conversation, keep int, tokens, budget int64, report *Report) ([]byte, int64) {
+	payload := conv.payload
+	for tokens > budget {
+		older := conv.olderTurns(keep)
+		if older == 0 {
+			break
+		}
+		excess := tokens - budget
+		n := 0
+		var estimate int64
+		for n < older && estimate < excess {
+			count, _ := enc.Count(conv.turnText(n))
+			estimate += int64(count)
+			n++
+		}
+		conv.dropOldest(n)
+		report.DroppedTurns += n
+		payload = conv.build()
+		tokens = countTokens(model, format, payload)
+	}
+	return payload, tokens
+}
+
+// countTokens estimates prompt tokens with the shared local counter, falling back to
+// a bytes-per-token heuristic when the payload cannot be tokenized.
+func countTokens(model, format string, payload []byte) int64 {
+	result, err := tokencount.Count(model, format, payload)
+	if err != nil {
+		return int64(len(payload) / 4)
+	}
+	return result.Tokens
+}
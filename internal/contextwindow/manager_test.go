@@ -0,0 +1,127 @@
+package contextwindow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// openAIConversation builds turns of user message, assistant tool call, tool result and
+// assistant reply, each carrying filler words.
+func openAIConversation(turns, words int) []byte {
+	payload := []byte(`{"model":"gpt-4o","messages":[{"role":"system","content":"be brief"}]}`)
+	filler := strings.Repeat("lorem ipsum ", words)
+	for i := 0; i < turns; i++ {
+		id := fmt.Sprintf("call_%d", i)
+		payload, _ = sjson.SetBytes(payload, "messages.-1", map[string]any{"role": "user", "content": fmt.Sprintf("question %d %s", i, filler)})
+		payload, _ = sjson.SetRawBytes(payload, "messages.-1", []byte(`{"role":"assistant","tool_calls":[{"id":"`+id+`","type":"function","function":{"name":"read","arguments":"{}"}}]}`))
+		payload, _ = sjson.SetBytes(payload, "messages.-1", map[string]any{"role": "tool", "tool_call_id": id, "content": "output " + filler + filler})
+		payload, _ = sjson.SetBytes(payload, "messages.-1", map[string]any{"role": "assistant", "content": "answer " + filler})
+	}
+	return payload
+}
+
+func newRule(strategies ...string) *config.ContextWindowRule {
+	return &config.ContextWindowRule{
+		Models:             []string{"*"},
+		Strategies:         strategies,
+		KeepRecentTurns:    2,
+		ToolOutputMaxChars: 20,
+		SummaryModel:       "cheap",
+	}
+}
+
+func TestFitDropsWholeTurns(t *testing.T) {
+	payload := openAIConversation(10, 50)
+	rule := newRule(config.ContextStrategyDropOldest)
+	out, report := Fit(context.Background(), rule, "openai", "gpt-4o", payload, 1200, nil)
+
+	if report.DroppedTurns == 0 || report.FinalTokens > 1200 || report.OriginalTokens <= 1200 {
+		t.Fatalf("report = %+v", report)
+	}
+	messages := gjson.GetBytes(out, "messages").Array()
+	if messages[0].Get("role").String() != "system" || messages[1].Get("role").String() != "user" {
+		t.Fatalf("unexpected head after drop: %s", out)
+	}
+	if (len(messages)-1)%4 != 0 {
+		t.Fatalf("turns were split: %d messages", len(messages))
+	}
+	if last := messages[len(messages)-1].Get("content").String(); !strings.HasPrefix(last, "answer") {
+		t.Fatalf("latest turn lost: %q", last)
+	}
+	if !strings.Contains(report.Header(), fmt.Sprintf("dropped-turns=%d", report.DroppedTurns)) {
+		t.Fatalf("header = %q", report.Header())
+	}
+}
+
+func TestFitTrimsToolOutputsBeforeDropping(t *testing.T) {
+	payload := openAIConversation(4, 200)
+	rule := newRule(config.ContextStrategyTrimToolOutputs, config.ContextStrategyDropOldest)
+	before := countTokens("gpt-4o", "openai", payload)
+	out, report := Fit(context.Background(), rule, "openai", "gpt-4o", payload, before-500, nil)
+
+	if report.TrimmedToolOutputs != 2 || report.DroppedTurns != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	first := gjson.GetBytes(out, "messages.3.content").String()
+	if !strings.Contains(first, "characters trimmed") {
+		t.Fatalf("old tool output not trimmed: %q", first)
+	}
+	if recent := gjson.GetBytes(out, "messages.15.content").String(); strings.Contains(recent, "characters trimmed") {
+		t.Fatal("recent tool output was trimmed")
+	}
+}
+
+func TestFitSummarizesOlderTurns(t *testing.T) {
+	payload := openAIConversation(6, 50)
+	rule := newRule(config.ContextStrategySummarize)
+	var transcript string
+	summarize := func(_ context.Context, text string) (string, error) {
+		transcript = text
+		return "they asked six questions", nil
+	}
+	out, report := Fit(context.Background(), rule, "openai", "gpt-4o", payload, 1200, summarize)
+
+	if report.SummarizedTurns != 4 {
+		t.Fatalf("report = %+v", report)
+	}
+	if !strings.Contains(transcript, "question 0") || strings.Contains(transcript, "question 4") {
+		t.Fatalf("transcript covered the wrong turns: %.120q", transcript)
+	}
+	messages := gjson.GetBytes(out, "messages").Array()
+	if messages[1].Get("role").String() != "system" || !strings.Contains(messages[1].Get("content").String(), "they asked six questions") {
+		t.Fatalf("summary not injected: %s", messages[1].Raw)
+	}
+	if !strings.HasPrefix(messages[2].Get("content").String(), "question 4") {
+		t.Fatalf("kept turns = %s", messages[2].Raw)
+	}
+}
+
+func TestParseConversationKeepsClaudeToolResultsInTurn(t *testing.T) {
+	payload := []byte(`{"messages":[
+		{"role":"user","content":"a"},
+		{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"x","input":{}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"r"}]},
+		{"role":"user","content":"b"},
+		{"role":"assistant","content":[{"type":"tool_use","id":"t2","name":"x","input":{}}]},
+		{"role":"user","content":[{"type":"text","text":"interrupt"}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"t2","content":"r"}]}
+	]}`)
+	conv := parseConversation("claude", payload)
+	if len(conv.turns) != 2 || len(conv.turns[0]) != 3 || len(conv.turns[1]) != 4 {
+		t.Fatalf("turns = %v", conv.turns)
+	}
+}
+
+func TestFitLeavesSmallRequestsAlone(t *testing.T) {
+	payload := openAIConversation(1, 5)
+	out, report := Fit(context.Background(), newRule(config.ContextStrategyDropOldest), "openai", "gpt-4o", payload, 100000, nil)
+	if report.Changed() || string(out) != string(payload) {
+		t.Fatalf("small request modified: %+v", report)
+	}
+}
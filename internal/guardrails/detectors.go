@@ -0,0 +1,149 @@
+package guardrails
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Built-in detector names accepted by GuardrailRule.Detectors.
+const (
+	DetectorCreditCard = "credit-card"
+	DetectorIBAN       = "iban"
+	DetectorCloudKey   = "cloud-key"
+	DetectorEmail      = "email"
+
+	detectorPattern = "pattern"
+	detectorKeyword = "keyword"
+)
+
+// detector finds sensitive spans in plain text.
+// When validate is set, regex candidates are only reported if validate accepts them.
+type detector struct {
+	name     string
+	re       *regexp.Regexp
+	validate func(string) bool
+}
+
+// span is a half-open byte range [start, end) reported by a detector.
+type span struct {
+	start    int
+	end      int
+	detector string
+}
+
+func (d detector) find(text string) []span {
+	if d.re == nil || text == "" {
+		return nil
+	}
+	locs := d.re.FindAllStringIndex(text, -1)
+	if len(locs) == 0 {
+		return nil
+	}
+	out := make([]span, 0, len(locs))
+	for _, loc := range locs {
+		if d.validate != nil && !d.validate(text[loc[0]:loc[1]]) {
+			continue
+		}
+		out = append(out, span{start: loc[0], end: loc[1], detector: d.name})
+	}
+	return out
+}
+
+var (
+	creditCardPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	ibanPattern       = regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`)
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+	cloudKeyPattern   = regexp.MustCompile(strings.Join([]string{
+		`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,           // AWS access key IDs
+		`\bAIza[0-9A-Za-z_\-]{35}`,                // Google API keys
+		`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,          // GitHub tokens
+		`\bgithub_pat_[A-Za-z0-9_]{22,}\b`,        // GitHub fine-grained tokens
+		`\bxox[abposr]-[A-Za-z0-9\-]{10,}`,        // Slack tokens
+		`\bsk-(?:ant-|proj-)?[A-Za-z0-9_\-]{20,}`, // OpenAI / Anthropic secret keys
+		`\bglpat-[A-Za-z0-9_\-]{20,}`,             // GitLab personal access tokens
+	}, "|"))
+)
+
+// builtinDetector returns the detector registered under name.
+func builtinDetector(name string) (detector, bool) {
+	switch name {
+	case DetectorCreditCard:
+		return detector{name: DetectorCreditCard, re: creditCardPattern, validate: validLuhn}, true
+	case DetectorIBAN:
+		return detector{name: DetectorIBAN, re: ibanPattern, validate: validIBAN}, true
+	case DetectorCloudKey:
+		return detector{name: DetectorCloudKey, re: cloudKeyPattern}, true
+	case DetectorEmail:
+		return detector{name: DetectorEmail, re: emailPattern}, true
+	default:
+		return detector{}, false
+	}
+}
+
+// keywordDetector compiles keywords into a single case-insensitive alternation.
+func keywordDetector(keywords []string) (detector, bool) {
+	if len(keywords) == 0 {
+		return detector{}, false
+	}
+	quoted := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			quoted = append(quoted, regexp.QuoteMeta(keyword))
+		}
+	}
+	if len(quoted) == 0 {
+		return detector{}, false
+	}
+	re, err := regexp.Compile(`(?i)` + strings.Join(quoted, "|"))
+	if err != nil {
+		return detector{}, false
+	}
+	return detector{name: detectorKeyword, re: re}, true
+}
+
+// validLuhn reports whether the digits in value form a 13-19 digit number passing the Luhn checksum.
+func validLuhn(value string) bool {
+	digits := make([]int, 0, len(value))
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validIBAN reports whether value passes the ISO 13616 mod-97 check.
+func validIBAN(value string) bool {
+	compact := strings.ReplaceAll(value, " ", "")
+	if len(compact) < 15 || len(compact) > 34 {
+		return false
+	}
+	remainder := 0
+	for _, r := range compact[4:] + compact[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			remainder = (remainder*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			remainder = (remainder*100 + int(r-'A'+10)) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
@@ -0,0 +1,345 @@
+// Package guardrails inspects prompts and completions flowing through the proxy.
+// Rules combine built-in detectors (credit cards, IBANs, cloud keys, emails) with
+// custom regular expressions and keyword lists, and either block the exchange,
+// redact the matched text, or flag it for audit. Rules can be scoped to client
+// API keys and model patterns.
+package guardrails
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// Direction identifies which side of an exchange is being inspected.
+type Direction string
+
+const (
+	// DirectionRequest inspects the client prompt before it is sent upstream.
+	DirectionRequest Direction = config.GuardrailDirectionRequest
+	// DirectionResponse inspects the completion before it reaches the client.
+	DirectionResponse Direction = config.GuardrailDirectionResponse
+)
+
+// Scope describes the exchange being inspected and is used to select applicable rules.
+type Scope struct {
+	Direction Direction
+	APIKey    string
+	Model     string
+}
+
+// Finding records a single detector match and the action taken for it.
+type Finding struct {
+	Rule     string
+	Detector string
+	Action   string
+	// Spanning is true when a streaming match crossed a chunk boundary and
+	// part of it had already been delivered to the client.
+	Spanning bool
+}
+
+// Result summarizes the guardrail decision for a piece of content.
+type Result struct {
+	Blocked   bool
+	BlockRule string
+	Findings  []Finding
+}
+
+// Redacted reports whether any finding rewrote the content.
+func (r Result) Redacted() bool {
+	for _, f := range r.Findings {
+		if f.Action == config.GuardrailActionRedact {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Result) merge(other Result) {
+	r.Findings = append(r.Findings, other.Findings...)
+	if other.Blocked && !r.Blocked {
+		r.Blocked = true
+		r.BlockRule = other.BlockRule
+	}
+}
+
+// BlockedError is returned when a guardrail rule with the block action matches.
+type BlockedError struct {
+	Rule      string
+	Direction Direction
+}
+
+// Error implements the error interface.
+func (e *BlockedError) Error() string {
+	if e.Direction == DirectionResponse {
+		return fmt.Sprintf("response blocked by guardrail rule %q", e.Rule)
+	}
+	return fmt.Sprintf("request blocked by guardrail rule %q", e.Rule)
+}
+
+// StatusCode returns the HTTP status reported to clients for blocked content.
+func (e *BlockedError) StatusCode() int { return http.StatusBadRequest }
+
+type compiledRule struct {
+	name        string
+	direction   string
+	action      string
+	replacement string
+	detectors   []detector
+	apiKeys     map[string]struct{}
+	models      []string
+}
+
+func (r *compiledRule) applies(scope Scope) bool {
+	if r.direction != config.GuardrailDirectionBoth && r.direction != string(scope.Direction) {
+		return false
+	}
+	if len(r.apiKeys) > 0 {
+		if _, ok := r.apiKeys[scope.APIKey]; !ok {
+			return false
+		}
+	}
+	if len(r.models) > 0 {
+		matched := false
+		for _, pattern := range r.models {
+			if util.MatchWildcardFold(pattern, scope.Model) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *compiledRule) find(text string) []span {
+	var spans []span
+	for _, d := range r.detectors {
+		spans = append(spans, d.find(text)...)
+	}
+	return spans
+}
+
+// Engine evaluates compiled guardrail rules. A nil Engine allows all content.
+type Engine struct {
+	rules []*compiledRule
+}
+
+// New compiles the guardrail configuration. It returns nil when guardrails are
+// disabled or no usable rules are configured.
+func New(cfg *config.GuardrailsConfig) *Engine {
+	if cfg == nil || !cfg.Enabled || len(cfg.Rules) == 0 {
+		return nil
+	}
+	engine := &Engine{}
+	for i := range cfg.Rules {
+		rule := cfg.Rules[i]
+		compiled := &compiledRule{
+			name:        rule.Name,
+			direction:   rule.Direction,
+			action:      rule.Action,
+			replacement: rule.Replacement,
+			models:      rule.Models,
+		}
+		if compiled.direction == "" {
+			compiled.direction = config.GuardrailDirectionBoth
+		}
+		if compiled.action == "" {
+			compiled.action = config.GuardrailActionFlag
+		}
+		for _, name := range rule.Detectors {
+			d, ok := builtinDetector(name)
+			if !ok {
+				log.Warnf("guardrails: rule %q references unknown detector %q", rule.Name, name)
+				continue
+			}
+			compiled.detectors = append(compiled.detectors, d)
+		}
+		for _, pattern := range rule.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				log.Warnf("guardrails: rule %q pattern %q ignored: %v", rule.Name, pattern, err)
+				continue
+			}
+			compiled.detectors = append(compiled.detectors, detector{name: detectorPattern, re: re})
+		}
+		if d, ok := keywordDetector(rule.Keywords); ok {
+			compiled.detectors = append(compiled.detectors, d)
+		}
+		if len(compiled.detectors) == 0 {
+			continue
+		}
+		if len(rule.APIKeys) > 0 {
+			compiled.apiKeys = make(map[string]struct{}, len(rule.APIKeys))
+			for _, key := range rule.APIKeys {
+				compiled.apiKeys[key] = struct{}{}
+			}
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+	if len(engine.rules) == 0 {
+		return nil
+	}
+	return engine
+}
+
+var engineCache struct {
+	mu     sync.Mutex
+	src    *config.GuardrailsConfig
+	engine *Engine
+}
+
+// ForConfig returns the engine compiled from cfg, reusing the previous compilation
+// while the same configuration instance stays active.
+func ForConfig(cfg *config.GuardrailsConfig) *Engine {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	engineCache.mu.Lock()
+	defer engineCache.mu.Unlock()
+	if engineCache.src != cfg {
+		engineCache.src = cfg
+		engineCache.engine = New(cfg)
+	}
+	return engineCache.engine
+}
+
+// Active reports whether any rule applies to the given scope.
+func (e *Engine) Active(scope Scope) bool {
+	if e == nil {
+		return false
+	}
+	for _, rule := range e.rules {
+		if rule.applies(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// InspectText evaluates applicable rules against text and returns the possibly
+// redacted text together with the decision. Rules run in declaration order and
+// evaluation stops at the first blocking match.
+func (e *Engine) InspectText(scope Scope, text string) (string, Result) {
+	var result Result
+	if e == nil || text == "" {
+		return text, result
+	}
+	for _, rule := range e.rules {
+		if !rule.applies(scope) {
+			continue
+		}
+		spans := rule.find(text)
+		if len(spans) == 0 {
+			continue
+		}
+		for _, s := range spans {
+			result.Findings = append(result.Findings, Finding{Rule: rule.name, Detector: s.detector, Action: rule.action})
+		}
+		switch rule.action {
+		case config.GuardrailActionBlock:
+			result.Blocked = true
+			result.BlockRule = rule.name
+			return text, result
+		case config.GuardrailActionRedact:
+			text = redact(text, spans, rule.replacement)
+		}
+	}
+	return text, result
+}
+
+// InspectPayload evaluates applicable rules against every text value in a JSON
+// (or SSE-framed JSON) payload. Redactions are written back in place so the
+// payload shape and key order are preserved.
+func (e *Engine) InspectPayload(scope Scope, payload []byte) ([]byte, Result) {
+	return e.inspectPayload(scope, payload, true)
+}
+
+// inspectPayload is InspectPayload with the option to leave text values to the
+// stream scanner, which checks them across chunk boundaries.
+func (e *Engine) inspectPayload(scope Scope, payload []byte, includeText bool) ([]byte, Result) {
+	var result Result
+	if e == nil || len(payload) == 0 || !e.Active(scope) {
+		return payload, result
+	}
+	out := rewriteJSONStrings(payload, func(key, value string) (string, bool) {
+		if skipValue(key, value) || result.Blocked || (!includeText && isTextKey(key)) {
+			return value, false
+		}
+		rewritten, res := e.InspectText(scope, value)
+		result.merge(res)
+		return rewritten, rewritten != value
+	})
+	return out, result
+}
+
+// redact replaces the given spans with the replacement text, merging overlaps.
+func redact(text string, spans []span, replacement string) string {
+	if len(spans) == 0 {
+		return text
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].start == spans[j].start {
+			return spans[i].end > spans[j].end
+		}
+		return spans[i].start < spans[j].start
+	})
+	var b strings.Builder
+	b.Grow(len(text))
+	cursor := 0
+	for _, s := range spans {
+		if s.start < cursor {
+			if s.end > cursor {
+				cursor = s.end
+			}
+			continue
+		}
+		b.WriteString(text[cursor:s.start])
+		if replacement != "" {
+			b.WriteString(replacement)
+		} else {
+			b.WriteString("[REDACTED:" + s.detector + "]")
+		}
+		cursor = s.end
+	}
+	b.WriteString(text[cursor:])
+	return b.String()
+}
+
+// Audit writes one log entry per finding so every guardrail decision is traceable.
+func Audit(scope Scope, result Result) {
+	if len(result.Findings) == 0 {
+		return
+	}
+	for _, finding := range result.Findings {
+		entry := log.WithFields(log.Fields{
+			"component": "guardrails",
+			"direction": string(scope.Direction),
+			"model":     scope.Model,
+			"api_key":   util.HideAPIKey(scope.APIKey),
+			"rule":      finding.Rule,
+			"detector":  finding.Detector,
+			"action":    finding.Action,
+		})
+		if finding.Spanning {
+			entry = entry.WithField("spanning", true)
+		}
+		switch finding.Action {
+		case config.GuardrailActionBlock:
+			entry.Warn("guardrail blocked content")
+		case config.GuardrailActionRedact:
+			entry.Info("guardrail redacted content")
+		default:
+			entry.Info("guardrail flagged content")
+		}
+	}
+}
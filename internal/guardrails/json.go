@@ -0,0 +1,119 @@
+package guardrails
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// skippedKeys lists JSON keys whose values carry opaque data (base64 blobs,
+// signatures) that must never be rewritten and only produce false positives.
+var skippedKeys = map[string]struct{}{
+	"data":              {},
+	"signature":         {},
+	"thoughtSignature":  {},
+	"thought_signature": {},
+	"encrypted_content": {},
+	"image_url":         {},
+	"file_data":         {},
+}
+
+// textKeys lists JSON keys that carry model-generated or user-authored text.
+// Streaming scanners only track these values across chunk boundaries.
+var textKeys = map[string]struct{}{
+	"text":              {},
+	"content":           {},
+	"delta":             {},
+	"partial_json":      {},
+	"arguments":         {},
+	"thinking":          {},
+	"reasoning_content": {},
+	"output_text":       {},
+}
+
+func skipValue(key, value string) bool {
+	if _, ok := skippedKeys[key]; ok {
+		return true
+	}
+	return strings.HasPrefix(value, "data:")
+}
+
+func isTextKey(key string) bool {
+	_, ok := textKeys[key]
+	return ok
+}
+
+// rewriteJSONStrings walks every string literal in data and passes string values
+// (not object keys) to fn together with the closest preceding object key. When fn
+// reports a change the literal is re-encoded in place. Bytes outside string
+// literals, such as SSE framing, are copied unchanged.
+func rewriteJSONStrings(data []byte, fn func(key, value string) (string, bool)) []byte {
+	var out []byte
+	copied := 0
+	lastKey := ""
+	for i := 0; i < len(data); i++ {
+		if data[i] != '"' {
+			continue
+		}
+		end := scanStringEnd(data, i)
+		if end < 0 {
+			break
+		}
+		literal := data[i : end+1]
+		next := end + 1
+		for next < len(data) && (data[next] == ' ' || data[next] == '\t' || data[next] == '\r' || data[next] == '\n') {
+			next++
+		}
+		var value string
+		if errUnmarshal := json.Unmarshal(literal, &value); errUnmarshal != nil {
+			i = end
+			continue
+		}
+		if next < len(data) && data[next] == ':' {
+			lastKey = value
+			i = end
+			continue
+		}
+		rewritten, changed := fn(lastKey, value)
+		if changed {
+			encoded, ok := encodeJSONString(rewritten)
+			if ok {
+				if out == nil {
+					out = make([]byte, 0, len(data))
+				}
+				out = append(out, data[copied:i]...)
+				out = append(out, encoded...)
+				copied = end + 1
+			}
+		}
+		i = end
+	}
+	if out == nil {
+		return data
+	}
+	return append(out, data[copied:]...)
+}
+
+// scanStringEnd returns the index of the closing quote for the string literal
+// starting at start, or -1 when the literal is unterminated.
+func scanStringEnd(data []byte, start int) int {
+	for j := start + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			j++
+		case '"':
+			return j
+		}
+	}
+	return -1
+}
+
+func encodeJSONString(value string) ([]byte, bool) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, false
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), true
+}
@@ -0,0 +1,371 @@
+package guardrails
+
+import (
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// streamWindowSize bounds how much text is held back for patterns without a
+// maximum match length (e.g. `sk-[A-Za-z0-9]{20,}`), and how much already
+// delivered text is kept to report matches that straddle it.
+const streamWindowSize = 256
+
+// StreamScanner inspects streaming output chunk by chunk. Chunks are held back
+// while their text could still be the start of a match completed by a later
+// chunk, so redact rules can rewrite secrets split across several chunks before
+// any part of them reaches the client. Chunks without text (stop events, tool
+// call framing) end the current text run and release everything held.
+type StreamScanner struct {
+	engine *Engine
+	scope  Scope
+	// holdback is the longest match of any redact or block rule in scope.
+	holdback int
+	// tail keeps the most recent delivered text for spanning detection.
+	tail    string
+	pending []pendingChunk
+}
+
+// pendingChunk is a scanned chunk that has not been released yet.
+type pendingChunk struct {
+	data []byte
+	text string
+}
+
+// NewStreamScanner returns a scanner for a single streaming response.
+// It returns nil when no rule applies to the scope.
+func (e *Engine) NewStreamScanner(scope Scope) *StreamScanner {
+	scope.Direction = DirectionResponse
+	if !e.Active(scope) {
+		return nil
+	}
+	s := &StreamScanner{engine: e, scope: scope}
+	for _, rule := range e.rules {
+		if !rule.applies(scope) || rule.action == config.GuardrailActionFlag {
+			continue
+		}
+		if n := rule.maxMatchLen(); n > s.holdback {
+			s.holdback = n
+		}
+	}
+	return s
+}
+
+// Scope returns the scope the scanner evaluates rules against.
+func (s *StreamScanner) Scope() Scope {
+	if s == nil {
+		return Scope{}
+	}
+	return s.scope
+}
+
+// Scan inspects a single chunk and returns the chunks that can be forwarded,
+// which may be none while text is held back or several when a run is released.
+// Findings are only reported as spanning when part of the match had already
+// been delivered; block rules still stop the stream for them.
+func (s *StreamScanner) Scan(chunk []byte) ([][]byte, Result) {
+	var result Result
+	if s == nil {
+		return [][]byte{chunk}, result
+	}
+	if len(chunk) == 0 {
+		return nil, result
+	}
+	// Text values are checked across chunks below; other values stand alone.
+	out, chunkResult := s.engine.inspectPayload(s.scope, chunk, false)
+	result.merge(chunkResult)
+	if result.Blocked {
+		return nil, result
+	}
+
+	text := payloadText(out)
+	if text == "" {
+		flushed, flushResult := s.Flush()
+		result.merge(flushResult)
+		if result.Blocked {
+			return nil, result
+		}
+		return append(flushed, out), result
+	}
+	s.pending = append(s.pending, pendingChunk{data: out, text: text})
+	if s.evaluate(&result, false) {
+		return nil, result
+	}
+	return s.release(), result
+}
+
+// Flush evaluates and releases every held chunk. Call it when the upstream
+// stream ends or before reporting an error to the client.
+func (s *StreamScanner) Flush() ([][]byte, Result) {
+	var result Result
+	if s == nil || len(s.pending) == 0 {
+		return nil, result
+	}
+	if s.evaluate(&result, true) {
+		return nil, result
+	}
+	out := make([][]byte, 0, len(s.pending))
+	for _, p := range s.pending {
+		out = append(out, p.data)
+		s.tail = trimWindow(s.tail+p.text, streamWindowSize)
+	}
+	s.pending = nil
+	return out, result
+}
+
+// Reset drops held chunks without releasing them, e.g. when the stream is retried
+// before anything reached the client.
+func (s *StreamScanner) Reset() {
+	if s == nil {
+		return
+	}
+	s.pending = nil
+	s.tail = ""
+}
+
+// evaluate runs the rules over the delivered tail and the held text and reports
+// whether a block rule matched. Matches ending before the newest chunk were
+// decided on an earlier call. A match reaching the end of the held text may
+// still grow with the next chunk, so it is decided later unless final is set.
+func (s *StreamScanner) evaluate(result *Result, final bool) bool {
+	for _, rule := range s.engine.rules {
+		if !rule.applies(s.scope) {
+			continue
+		}
+		window, starts := s.window()
+		decided := starts[len(starts)-1]
+		if final {
+			decided = len(window)
+		}
+		var redactions []span
+		for _, sp := range rule.find(window) {
+			if sp.end < decided || (!final && sp.end == len(window)) {
+				continue
+			}
+			result.Findings = append(result.Findings, Finding{
+				Rule:     rule.name,
+				Detector: sp.detector,
+				Action:   rule.action,
+				Spanning: sp.start < len(s.tail),
+			})
+			switch rule.action {
+			case config.GuardrailActionBlock:
+				result.Blocked = true
+				result.BlockRule = rule.name
+				return true
+			case config.GuardrailActionRedact:
+				redactions = append(redactions, sp)
+			}
+		}
+		if len(redactions) > 0 {
+			s.redactPending(redactions, starts, rule.replacement)
+		}
+	}
+	return false
+}
+
+// release returns the leading held chunks whose text ends before the holdback
+// window, i.e. text that can no longer be part of a future match.
+func (s *StreamScanner) release() [][]byte {
+	total := 0
+	for _, p := range s.pending {
+		total += len(p.text)
+	}
+	safe := total - s.holdback
+	var out [][]byte
+	consumed := 0
+	n := 0
+	for _, p := range s.pending {
+		if consumed+len(p.text) > safe {
+			break
+		}
+		consumed += len(p.text)
+		out = append(out, p.data)
+		s.tail = trimWindow(s.tail+p.text, streamWindowSize)
+		n++
+	}
+	s.pending = s.pending[n:]
+	return out
+}
+
+// window returns the delivered tail followed by the held text, and the offset at
+// which each held chunk's text starts.
+func (s *StreamScanner) window() (string, []int) {
+	var b strings.Builder
+	b.WriteString(s.tail)
+	starts := make([]int, len(s.pending))
+	for i, p := range s.pending {
+		starts[i] = b.Len()
+		b.WriteString(p.text)
+	}
+	return b.String(), starts
+}
+
+// redactPending rewrites held chunks so each span is replaced by a single marker
+// in the chunk where the held part of the span starts; the remaining parts of
+// the span are removed from the following chunks.
+func (s *StreamScanner) redactPending(spans []span, starts []int, replacement string) {
+	for i := range s.pending {
+		chunkStart := starts[i]
+		chunkEnd := chunkStart + len(s.pending[i].text)
+		var cuts []span
+		for _, sp := range spans {
+			start := max(sp.start, starts[0])
+			if sp.end <= chunkStart || start >= chunkEnd {
+				continue
+			}
+			cut := span{start: max(start, chunkStart) - chunkStart, end: min(sp.end, chunkEnd) - chunkStart}
+			if start >= chunkStart {
+				cut.detector = sp.detector
+			}
+			cuts = append(cuts, cut)
+		}
+		if len(cuts) == 0 {
+			continue
+		}
+		sort.Slice(cuts, func(a, b int) bool { return cuts[a].start < cuts[b].start })
+		s.pending[i] = rewritePendingText(s.pending[i], cuts, replacement)
+	}
+}
+
+// rewritePendingText applies chunk-local cuts to the text values of a held chunk.
+// Cuts with a detector name receive the replacement marker; the others are removed.
+func rewritePendingText(p pendingChunk, cuts []span, replacement string) pendingChunk {
+	offset := 0
+	var text strings.Builder
+	data := rewriteJSONStrings(p.data, func(key, value string) (string, bool) {
+		if !isTextKey(key) || skipValue(key, value) {
+			return value, false
+		}
+		valueStart, valueEnd := offset, offset+len(value)
+		offset = valueEnd
+		var b strings.Builder
+		cursor := valueStart
+		for _, cut := range cuts {
+			if cut.end <= valueStart || cut.start >= valueEnd {
+				continue
+			}
+			start, end := max(cut.start, cursor), min(cut.end, valueEnd)
+			if end <= cursor {
+				continue
+			}
+			b.WriteString(value[cursor-valueStart : start-valueStart])
+			// Overlapping cuts share the marker of the first one.
+			if cut.detector != "" && cut.start >= cursor {
+				if replacement != "" {
+					b.WriteString(replacement)
+				} else {
+					b.WriteString("[REDACTED:" + cut.detector + "]")
+				}
+			}
+			cursor = max(cursor, end)
+		}
+		b.WriteString(value[cursor-valueStart:])
+		rewritten := b.String()
+		text.WriteString(rewritten)
+		return rewritten, rewritten != value
+	})
+	return pendingChunk{data: data, text: text.String()}
+}
+
+// payloadText concatenates the text values of a payload in order.
+func payloadText(payload []byte) string {
+	var text strings.Builder
+	rewriteJSONStrings(payload, func(key, value string) (string, bool) {
+		if isTextKey(key) && !skipValue(key, value) {
+			text.WriteString(value)
+		}
+		return value, false
+	})
+	return text.String()
+}
+
+// maxMatchLen returns the longest match in bytes any detector of the rule can
+// produce, using streamWindowSize for unbounded patterns.
+func (r *compiledRule) maxMatchLen() int {
+	longest := 0
+	for _, d := range r.detectors {
+		if d.re == nil {
+			continue
+		}
+		n := streamWindowSize
+		if parsed, err := syntax.Parse(d.re.String(), syntax.Perl); err == nil {
+			if bounded := maxRegexpLen(parsed.Simplify()); bounded >= 0 {
+				n = bounded
+			}
+		}
+		longest = max(longest, n)
+	}
+	return longest
+}
+
+// maxRegexpLen returns the maximum match length of re in bytes, or -1 when unbounded.
+func maxRegexpLen(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		n := 0
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 {
+				n += utf8.UTFMax
+				continue
+			}
+			n += utf8.RuneLen(r)
+		}
+		return n
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return 0
+		}
+		return max(utf8.RuneLen(re.Rune[len(re.Rune)-1]), 1)
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return utf8.UTFMax
+	case syntax.OpCapture, syntax.OpQuest:
+		return maxRegexpLen(re.Sub[0])
+	case syntax.OpStar, syntax.OpPlus:
+		return -1
+	case syntax.OpRepeat:
+		sub := maxRegexpLen(re.Sub[0])
+		if re.Max < 0 || sub < 0 {
+			return -1
+		}
+		return re.Max * sub
+	case syntax.OpConcat:
+		n := 0
+		for _, sub := range re.Sub {
+			m := maxRegexpLen(sub)
+			if m < 0 {
+				return -1
+			}
+			n += m
+		}
+		return n
+	case syntax.OpAlternate:
+		n := 0
+		for _, sub := range re.Sub {
+			m := maxRegexpLen(sub)
+			if m < 0 {
+				return -1
+			}
+			n = max(n, m)
+		}
+		return n
+	default:
+		// Empty-width assertions and empty matches.
+		return 0
+	}
+}
+
+// trimWindow keeps at most limit trailing bytes of text without splitting a rune.
+func trimWindow(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := len(text) - limit
+	for cut < len(text) && !utf8.RuneStart(text[cut]) {
+		cut++
+	}
+	return text[cut:]
+}
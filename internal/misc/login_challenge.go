@@ -0,0 +1,33 @@
+package misc
+
+import "context"
+
+// LoginChallenge describes what a user must do to complete an interactive login:
+// open an authorize URL, or enter a user code at a verification URL.
+type LoginChallenge struct {
+	AuthURL         string
+	VerificationURL string
+	UserCode        string
+}
+
+type loginChallengeKey struct{}
+
+// WithLoginChallengeHandler returns a context that reports login challenges to fn.
+// Authenticators still print challenges to the terminal; the handler lets callers
+// without a terminal, such as the management login broker, relay them.
+func WithLoginChallengeHandler(ctx context.Context, fn func(LoginChallenge)) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, loginChallengeKey{}, fn)
+}
+
+// AnnounceLoginChallenge reports a login challenge to the handler registered on ctx, if any.
+func AnnounceLoginChallenge(ctx context.Context, challenge LoginChallenge) {
+	if ctx == nil {
+		return
+	}
+	if fn, ok := ctx.Value(loginChallengeKey{}).(func(LoginChallenge)); ok {
+		fn(challenge)
+	}
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func init() {
+	coreusage.RegisterPlugin(defaultNotifier)
+}
+
+// errorRateBucket is the width of the buckets the error-rate window is counted in.
+const errorRateBucket = 10 * time.Second
+
+type rateBucket struct {
+	start  time.Time
+	total  int
+	failed int
+}
+
+// usageMonitor turns usage records into error-rate events. Usage budgets are
+// counted by the auth manager, next to the persisted per-credential usage counters.
+type usageMonitor struct {
+	mu    sync.Mutex
+	cfg   config.NotificationsConfig
+	rates map[string][]rateBucket
+}
+
+func newUsageMonitor() *usageMonitor {
+	return &usageMonitor{rates: make(map[string][]rateBucket)}
+}
+
+func (m *usageMonitor) setConfig(cfg config.NotificationsConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+}
+
+// HandleUsage implements coreusage.Plugin.
+func (n *Notifier) HandleUsage(_ context.Context, record coreusage.Record) {
+	if !n.enabled.Load() {
+		return
+	}
+	for _, event := range n.monitor.observe(record, n.now()) {
+		n.Emit(event)
+	}
+}
+
+func (m *usageMonitor) observe(record coreusage.Record, now time.Time) []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if event, ok := m.observeErrorRate(record, now); ok {
+		return []Event{event}
+	}
+	return nil
+}
+
+func (m *usageMonitor) observeErrorRate(record coreusage.Record, now time.Time) (Event, bool) {
+	model := strings.TrimSpace(record.Model)
+	if model == "" {
+		return Event{}, false
+	}
+	cfg := m.cfg.ErrorRate
+	window := time.Duration(cfg.WindowSeconds) * time.Second
+	buckets := m.rates[model]
+	kept := buckets[:0]
+	for _, bucket := range buckets {
+		if now.Sub(bucket.start) < window {
+			kept = append(kept, bucket)
+		}
+	}
+	buckets = kept
+	start := now.Truncate(errorRateBucket)
+	if len(buckets) == 0 || !buckets[len(buckets)-1].start.Equal(start) {
+		buckets = append(buckets, rateBucket{start: start})
+	}
+	last := &buckets[len(buckets)-1]
+	last.total++
+	if record.Failed {
+		last.failed++
+	}
+	m.rates[model] = buckets
+
+	total, failed := 0, 0
+	for _, bucket := range buckets {
+		total += bucket.total
+		failed += bucket.failed
+	}
+	if total < cfg.MinRequests {
+		return Event{}, false
+	}
+	rate := float64(failed) / float64(total) * 100
+	if rate < cfg.ThresholdPercent {
+		return Event{}, false
+	}
+	return Event{
+		Type:     EventErrorRate,
+		Severity: SeverityCritical,
+		Key:      model,
+		Title:    "Error rate spike on " + model,
+		Message:  fmt.Sprintf("%d of the last %d requests to %s failed (%.0f%%) within %s.", failed, total, model, rate, window),
+		Fields: map[string]string{
+			"model":    model,
+			"provider": record.Provider,
+			"failed":   fmt.Sprint(failed),
+			"total":    fmt.Sprint(total),
+			"window":   window.String(),
+		},
+	}, true
+}
@@ -0,0 +1,272 @@
+// Package notify delivers operational events, such as dead credentials or
+// exhausted provider pools, to webhook, Slack and email sinks.
+package notify
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// Event names.
+const (
+	EventCredentialNeedsRelogin = "credential.needs-relogin"
+	EventCredentialDisabled     = "credential.disabled"
+	EventCredentialRecovered    = "credential.recovered"
+	EventRefreshFailed          = "credential.refresh-failed"
+	EventProviderCooldown       = "provider.cooldown"
+	EventConfigReloadFailed     = "config.reload-failed"
+	EventUsageBudget            = "usage.budget"
+	EventErrorRate              = "model.error-rate"
+	EventTest                   = "test"
+)
+
+// Severity grades how urgent an event is.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+const (
+	queueSize       = 256
+	deliveryTimeout = 15 * time.Second
+)
+
+// Event is a single operational notification.
+type Event struct {
+	Type     string            `json:"type"`
+	Severity Severity          `json:"severity"`
+	Title    string            `json:"title"`
+	Message  string            `json:"message"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	Time     time.Time         `json:"time"`
+	// Suppressed counts repeats of this event dropped by throttling since the last delivery.
+	Suppressed int `json:"suppressed,omitempty"`
+	// Key distinguishes events of the same type for throttling, e.g. an auth ID.
+	Key string `json:"-"`
+}
+
+// SinkResult reports the outcome of delivering an event to one sink.
+type SinkResult struct {
+	Sink  string `json:"sink"`
+	Type  string `json:"type"`
+	Error string `json:"error,omitempty"`
+}
+
+type throttleEntry struct {
+	sentAt     time.Time
+	suppressed int
+}
+
+type sinkTemplates struct {
+	body    *template.Template
+	subject *template.Template
+}
+
+// Notifier throttles events and delivers them to the configured sinks from a
+// background goroutine so emitters never block on the network.
+type Notifier struct {
+	enabled atomic.Bool
+
+	mu        sync.Mutex
+	cfg       config.NotificationsConfig
+	templates map[string]sinkTemplates
+	sent      map[string]*throttleEntry
+	monitor   *usageMonitor
+
+	queue     chan Event
+	startOnce sync.Once
+	now       func() time.Time
+}
+
+// New returns a notifier with no sinks configured.
+func New() *Notifier {
+	return &Notifier{
+		templates: make(map[string]sinkTemplates),
+		sent:      make(map[string]*throttleEntry),
+		monitor:   newUsageMonitor(),
+		queue:     make(chan Event, queueSize),
+		now:       time.Now,
+	}
+}
+
+var defaultNotifier = New()
+
+// Default returns the process-wide notifier.
+func Default() *Notifier { return defaultNotifier }
+
+// SetConfig applies notification settings to the default notifier.
+func SetConfig(cfg config.NotificationsConfig) { defaultNotifier.SetConfig(cfg) }
+
+// Emit queues an event on the default notifier.
+func Emit(event Event) { defaultNotifier.Emit(event) }
+
+// SetConfig replaces the notifier settings and recompiles sink templates.
+func (n *Notifier) SetConfig(cfg config.NotificationsConfig) {
+	templates := make(map[string]sinkTemplates, len(cfg.Sinks))
+	for _, sink := range cfg.Sinks {
+		var compiled sinkTemplates
+		if sink.Template != "" {
+			tmpl, err := template.New(sink.Name).Parse(sink.Template)
+			if err != nil {
+				log.Errorf("notifications: invalid template for sink %s, using default: %v", sink.Name, err)
+			} else {
+				compiled.body = tmpl
+			}
+		}
+		if sink.Subject != "" {
+			tmpl, err := template.New(sink.Name + "-subject").Parse(sink.Subject)
+			if err != nil {
+				log.Errorf("notifications: invalid subject for sink %s, using default: %v", sink.Name, err)
+			} else {
+				compiled.subject = tmpl
+			}
+		}
+		templates[sink.Name] = compiled
+	}
+	n.mu.Lock()
+	n.cfg = cfg
+	n.templates = templates
+	n.mu.Unlock()
+	n.monitor.setConfig(cfg)
+	n.enabled.Store(cfg.Enabled && len(cfg.Sinks) > 0)
+}
+
+// Enabled reports whether events are currently delivered.
+func (n *Notifier) Enabled() bool { return n.enabled.Load() }
+
+// Emit throttles the event and queues it for delivery. It never blocks; events
+// are dropped when notifications are off or the queue is full.
+func (n *Notifier) Emit(event Event) {
+	if !n.enabled.Load() {
+		return
+	}
+	now := n.now()
+	if event.Time.IsZero() {
+		event.Time = now
+	}
+	if event.Severity == "" {
+		event.Severity = SeverityWarning
+	}
+	n.mu.Lock()
+	override := n.cfg.Events[event.Type]
+	if override.Disabled {
+		n.mu.Unlock()
+		return
+	}
+	window := time.Duration(n.cfg.ThrottleSeconds) * time.Second
+	if override.ThrottleSeconds > 0 {
+		window = time.Duration(override.ThrottleSeconds) * time.Second
+	}
+	key := event.Type + "|" + event.Key
+	entry := n.sent[key]
+	if entry != nil && now.Sub(entry.sentAt) < window {
+		entry.suppressed++
+		n.mu.Unlock()
+		return
+	}
+	if entry == nil {
+		entry = &throttleEntry{}
+		n.sent[key] = entry
+		n.pruneLocked(now)
+	}
+	event.Suppressed = entry.suppressed
+	entry.sentAt = now
+	entry.suppressed = 0
+	n.mu.Unlock()
+
+	n.startOnce.Do(func() { go n.run() })
+	select {
+	case n.queue <- event:
+	default:
+		log.Warnf("notifications: queue full, dropping %s event", event.Type)
+	}
+}
+
+// pruneLocked drops throttle entries that no longer suppress anything.
+func (n *Notifier) pruneLocked(now time.Time) {
+	if len(n.sent) < 1024 {
+		return
+	}
+	maxWindow := time.Duration(n.cfg.ThrottleSeconds) * time.Second
+	for _, override := range n.cfg.Events {
+		if w := time.Duration(override.ThrottleSeconds) * time.Second; w > maxWindow {
+			maxWindow = w
+		}
+	}
+	for key, entry := range n.sent {
+		if now.Sub(entry.sentAt) >= maxWindow {
+			delete(n.sent, key)
+		}
+	}
+}
+
+func (n *Notifier) run() {
+	for event := range n.queue {
+		n.deliver(context.Background(), event, "")
+	}
+}
+
+// SendTest delivers a test event synchronously to the named sink, or to every
+// sink when name is empty. It ignores the enabled flag and throttling so sinks
+// can be verified before notifications are switched on.
+func (n *Notifier) SendTest(ctx context.Context, name string) []SinkResult {
+	event := Event{
+		Type:     EventTest,
+		Severity: SeverityInfo,
+		Title:    "Test notification",
+		Message:  "This is a test notification from CLIProxyAPI.",
+		Time:     n.now(),
+	}
+	return n.deliver(ctx, event, name)
+}
+
+func (n *Notifier) deliver(ctx context.Context, event Event, only string) []SinkResult {
+	n.mu.Lock()
+	sinks := append([]config.NotificationSink(nil), n.cfg.Sinks...)
+	templates := n.templates
+	n.mu.Unlock()
+
+	results := make([]SinkResult, 0, len(sinks))
+	for _, sink := range sinks {
+		if only != "" && sink.Name != only {
+			continue
+		}
+		if only == "" && !sinkAccepts(sink, event.Type) {
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		err := send(sendCtx, sink, templates[sink.Name], event)
+		cancel()
+		result := SinkResult{Sink: sink.Name, Type: sink.Type}
+		if err != nil {
+			result.Error = err.Error()
+			log.Warnf("notifications: %s delivery to %s failed: %v", event.Type, sink.Name, err)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// sinkAccepts reports whether the sink subscribes to the event type. Patterns
+// may use "*" wildcards, as model patterns do.
+func sinkAccepts(sink config.NotificationSink, eventType string) bool {
+	if len(sink.Events) == 0 || eventType == EventTest {
+		return true
+	}
+	for _, pattern := range sink.Events {
+		if util.MatchWildcard(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
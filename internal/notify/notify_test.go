@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+type capturedRequest struct {
+	path        string
+	contentType string
+	body        string
+}
+
+func newCaptureServer(t *testing.T) (*httptest.Server, chan capturedRequest) {
+	t.Helper()
+	requests := make(chan capturedRequest, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- capturedRequest{path: r.URL.Path, contentType: r.Header.Get("Content-Type"), body: string(body)}
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func receive(t *testing.T, requests chan capturedRequest) capturedRequest {
+	t.Helper()
+	select {
+	case req := <-requests:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification was not delivered")
+		return capturedRequest{}
+	}
+}
+
+func TestEmitThrottlesRepeatsAndRoutesBySinkFilter(t *testing.T) {
+	server, requests := newCaptureServer(t)
+	cfg := &config.Config{Notifications: config.NotificationsConfig{
+		Enabled:         true,
+		ThrottleSeconds: 60,
+		Sinks: []config.NotificationSink{
+			{Name: "all", Type: "webhook", URL: server.URL + "/all"},
+			{Name: "slack", Type: "slack", URL: server.URL + "/slack", Events: []string{"credential.*"}, Template: "{{.Title}} ({{.Suppressed}} suppressed)"},
+		},
+	}}
+	cfg.SanitizeNotifications()
+	n := New()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	n.now = func() time.Time { return now }
+	n.SetConfig(cfg.Notifications)
+
+	event := Event{Type: EventRefreshFailed, Key: "auth-1", Title: "Refresh failed", Message: "invalid_grant"}
+	n.Emit(event)
+	first := []capturedRequest{receive(t, requests), receive(t, requests)}
+	var sawWebhook, sawSlack bool
+	for _, req := range first {
+		switch req.path {
+		case "/all":
+			sawWebhook = true
+			var payload Event
+			if err := json.Unmarshal([]byte(req.body), &payload); err != nil || payload.Type != EventRefreshFailed || payload.Message != "invalid_grant" {
+				t.Fatalf("webhook payload = %s (%v)", req.body, err)
+			}
+		case "/slack":
+			sawSlack = true
+			if req.body != `{"text":"Refresh failed (0 suppressed)"}` {
+				t.Fatalf("slack payload = %s", req.body)
+			}
+		}
+	}
+	if !sawWebhook || !sawSlack {
+		t.Fatalf("deliveries = %+v, want webhook and slack", first)
+	}
+
+	// Repeats inside the window are counted, not delivered.
+	n.Emit(event)
+	n.Emit(event)
+	// A different key is a different event.
+	n.Emit(Event{Type: EventProviderCooldown, Key: "claude|m", Title: "Cooldown"})
+	if req := receive(t, requests); req.path != "/all" || !json.Valid([]byte(req.body)) {
+		t.Fatalf("cooldown delivery = %+v, want webhook only", req)
+	}
+
+	now = now.Add(61 * time.Second)
+	n.Emit(event)
+	for i := 0; i < 2; i++ {
+		if req := receive(t, requests); req.path == "/slack" && req.body != `{"text":"Refresh failed (2 suppressed)"}` {
+			t.Fatalf("slack payload after window = %s", req.body)
+		}
+	}
+}
+
+func TestEmitHonorsDisabledEventsAndSendTestIgnoresEnabled(t *testing.T) {
+	server, requests := newCaptureServer(t)
+	n := New()
+	n.SetConfig(config.NotificationsConfig{
+		Enabled:         false,
+		ThrottleSeconds: 60,
+		Sinks:           []config.NotificationSink{{Name: "hook", Type: "webhook", URL: server.URL}},
+	})
+	n.Emit(Event{Type: EventRefreshFailed, Title: "ignored"})
+
+	results := n.SendTest(context.Background(), "hook")
+	if len(results) != 1 || results[0].Error != "" {
+		t.Fatalf("SendTest results = %+v", results)
+	}
+	if req := receive(t, requests); !json.Valid([]byte(req.body)) {
+		t.Fatalf("test payload = %s", req.body)
+	}
+	if results = n.SendTest(context.Background(), "missing"); len(results) != 0 {
+		t.Fatalf("SendTest(missing) = %+v, want no results", results)
+	}
+
+	n.SetConfig(config.NotificationsConfig{
+		Enabled:         true,
+		ThrottleSeconds: 60,
+		Sinks:           []config.NotificationSink{{Name: "hook", Type: "webhook", URL: server.URL}},
+		Events:          map[string]config.NotificationEventConfig{EventRefreshFailed: {Disabled: true}},
+	})
+	n.Emit(Event{Type: EventRefreshFailed, Title: "disabled"})
+	n.Emit(Event{Type: EventConfigReloadFailed, Title: "delivered"})
+	if req := receive(t, requests); !json.Valid([]byte(req.body)) || !strings.Contains(req.body, "delivered") {
+		t.Fatalf("delivered payload = %s, want the config reload event", req.body)
+	}
+}
+
+func TestUsageMonitorDetectsErrorRate(t *testing.T) {
+	cfg := &config.Config{Notifications: config.NotificationsConfig{
+		Enabled:   true,
+		ErrorRate: config.ErrorRateAlertConfig{WindowSeconds: 60, MinRequests: 4, ThresholdPercent: 50},
+	}}
+	cfg.SanitizeNotifications()
+	m := newUsageMonitor()
+	m.setConfig(cfg.Notifications)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+
+	record := func(failed bool) []Event {
+		return m.observe(coreusage.Record{Model: "m", APIKey: "k1", Failed: failed}, now)
+	}
+	for _, failed := range []bool{false, true, true} {
+		if events := record(failed); len(events) != 0 {
+			t.Fatalf("events = %+v, want none below min-requests", events)
+		}
+	}
+	events := record(false)
+	if len(events) != 1 || events[0].Type != EventErrorRate || events[0].Fields["failed"] != "2" || events[0].Fields["total"] != "4" {
+		t.Fatalf("events = %+v, want error-rate spike", events)
+	}
+	// The failure share is back under the threshold at 2 of 5.
+	if events = record(false); len(events) != 0 {
+		t.Fatalf("events = %+v, want none", events)
+	}
+	// Buckets older than the window are dropped.
+	now = now.Add(2 * time.Minute)
+	if events = record(true); len(events) != 0 {
+		t.Fatalf("events = %+v, want none after the window moved on", events)
+	}
+}
@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+var httpClient = &http.Client{Timeout: deliveryTimeout}
+
+func send(ctx context.Context, sink config.NotificationSink, templates sinkTemplates, event Event) error {
+	switch sink.Type {
+	case config.NotificationSinkWebhook:
+		return sendWebhook(ctx, sink, templates, event)
+	case config.NotificationSinkSlack:
+		return sendSlack(ctx, sink, templates, event)
+	case config.NotificationSinkEmail:
+		return sendEmail(ctx, sink, templates, event)
+	default:
+		return fmt.Errorf("unsupported sink type %q", sink.Type)
+	}
+}
+
+// sendWebhook posts the event as JSON, or the rendered template when one is set.
+func sendWebhook(ctx context.Context, sink config.NotificationSink, templates sinkTemplates, event Event) error {
+	var body []byte
+	contentType := "application/json"
+	if templates.body != nil {
+		text, err := render(templates.body, event)
+		if err != nil {
+			return err
+		}
+		body = []byte(text)
+		if !json.Valid(body) {
+			contentType = "text/plain; charset=utf-8"
+		}
+	} else {
+		var err error
+		if body, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+	return post(ctx, sink.URL, contentType, sink.Headers, body)
+}
+
+// sendSlack posts the rendered text in the incoming-webhook payload format,
+// which Mattermost and Discord's /slack endpoints also accept.
+func sendSlack(ctx context.Context, sink config.NotificationSink, templates sinkTemplates, event Event) error {
+	text, err := render(templates.body, event)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	return post(ctx, sink.URL, "application/json", sink.Headers, body)
+}
+
+func post(ctx context.Context, url, contentType string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// sendEmail delivers the event over SMTP. The connection is upgraded with
+// STARTTLS when the server offers it; implicit TLS on port 465 is not supported.
+func sendEmail(ctx context.Context, sink config.NotificationSink, templates sinkTemplates, event Event) error {
+	subject := "[CLIProxyAPI] " + event.Title
+	if templates.subject != nil {
+		rendered, err := render(templates.subject, event)
+		if err != nil {
+			return err
+		}
+		subject = strings.TrimSpace(rendered)
+	}
+	text, err := render(templates.body, event)
+	if err != nil {
+		return err
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", sink.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(sink.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.ReplaceAll(subject, "\n", " "))
+	fmt.Fprintf(&msg, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	addr := net.JoinHostPort(sink.SMTPHost, strconv.Itoa(sink.SMTPPort))
+	var auth smtp.Auth
+	if sink.Username != "" {
+		auth = smtp.PlainAuth("", sink.Username, sink.Password, sink.SMTPHost)
+	}
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(addr, auth, sink.From, sink.To, msg.Bytes()) }()
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// render executes the sink template, falling back to a plain summary with the
+// event fields listed in a stable order.
+func render(tmpl *template.Template, event Event) (string, error) {
+	if tmpl != nil {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, event); err != nil {
+			return "", fmt.Errorf("render template: %w", err)
+		}
+		return buf.String(), nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s] %s\n%s", strings.ToUpper(string(event.Severity)), event.Title, event.Message)
+	keys := make([]string, 0, len(event.Fields))
+	for key := range event.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "\n%s: %s", key, event.Fields[key])
+	}
+	if event.Suppressed > 0 {
+		fmt.Fprintf(&sb, "\n(%d similar events suppressed)", event.Suppressed)
+	}
+	return sb.String(), nil
+}
@@ -10,6 +10,7 @@ import (
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
 	"github.com/tidwall/gjson"
 	"github.com/tiktoken-go/tokenizer"
 )
@@ -46,6 +47,11 @@ func countClaudeChatTokens(enc tokenizer.Codec, payload []byte) (int64, error) {
 	return helps.CountClaudeChatTokens(enc, payload)
 }
 
+func localTokenCountResponse(ctx context.Context, model string, from sdktranslator.Format, payload []byte) ([]byte, error) {
+	translated, _, err := helps.LocalTokenCountResponse(ctx, model, from, payload)
+	return translated, err
+}
+
 func buildOpenAIUsageJSON(count int64) []byte {
 	return helps.BuildOpenAIUsageJSON(count)
 }
@@ -72,8 +78,12 @@ func payloadRequestedModel(opts cliproxyexecutor.Options, fallback string) strin
 	return helps.PayloadRequestedModel(opts, fallback)
 }
 
-func applyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string, payload, original []byte, requestedModel string) []byte {
-	return helps.ApplyPayloadConfigWithRoot(cfg, model, protocol, root, payload, original, requestedModel)
+func newPayloadRequest(ctx context.Context, opts cliproxyexecutor.Options) helps.PayloadRequest {
+	return helps.NewPayloadRequest(ctx, opts)
+}
+
+func applyPayloadConfigWithRoot(cfg *config.Config, req helps.PayloadRequest, model, protocol, root string, payload, original []byte, requestedModel string) []byte {
+	return helps.ApplyPayloadConfigWithRoot(cfg, req, model, protocol, root, payload, original, requestedModel)
 }
 
 func summarizeErrorBody(contentType string, body []byte) string {
@@ -21,6 +21,9 @@ import (
 	cursorproto "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/cursor/proto"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencount"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/toolemulation"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
@@ -46,6 +49,7 @@ type CursorExecutor struct {
 	mu          sync.Mutex
 	sessions    map[string]*cursorSession
 	checkpoints map[string]*savedCheckpoint // keyed by conversationId
+	store       *cursorCheckpointStore      // nil unless cursor-sessions.persist is set
 }
 
 // savedCheckpoint stores the server's conversation_checkpoint_update for reuse.
@@ -83,6 +87,7 @@ func NewCursorExecutor(cfg *config.Config) *CursorExecutor {
 		cfg:         cfg,
 		sessions:    make(map[string]*cursorSession),
 		checkpoints: make(map[string]*savedCheckpoint),
+		store:       newCursorCheckpointStore(cfg),
 	}
 	go e.cleanupLoop()
 	return e
@@ -125,6 +130,9 @@ func (e *CursorExecutor) cleanupLoop() {
 			}
 		}
 		e.mu.Unlock()
+		if e.store != nil {
+			e.store.prune()
+		}
 	}
 }
 
@@ -216,35 +224,18 @@ func (e *CursorExecutor) HttpRequest(ctx context.Context, auth *cliproxyauth.Aut
 	return http.DefaultClient.Do(req)
 }
 
-// CountTokens estimates token count locally using tiktoken.
-func (e *CursorExecutor) CountTokens(_ context.Context, _ *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
-	defer func() {
-		if err != nil {
-			log.Warnf("cursor CountTokens error: %v", err)
-		} else {
-			log.Debugf("cursor CountTokens: model=%s result=%s", req.Model, string(resp.Payload))
-		}
-	}()
+// CountTokens estimates token count locally with the tokencount estimator, since
+// Cursor has no count endpoint.
+func (e *CursorExecutor) CountTokens(ctx context.Context, _ *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	model := gjson.GetBytes(req.Payload, "model").String()
 	if model == "" {
 		model = req.Model
 	}
-
-	enc, err := getTokenizer(model)
+	translated, err := localTokenCountResponse(ctx, model, opts.SourceFormat, req.Payload)
 	if err != nil {
-		// Fallback: return zero tokens rather than error (avoids 502)
-		return cliproxyexecutor.Response{Payload: buildOpenAIUsageJSON(0)}, nil
-	}
-
-	// Detect format: Claude (/v1/messages) vs OpenAI (/v1/chat/completions)
-	var count int64
-	if gjson.GetBytes(req.Payload, "system").Exists() || opts.SourceFormat.String() == "claude" {
-		count, _ = countClaudeChatTokens(enc, req.Payload)
-	} else {
-		count, _ = countOpenAIChatTokens(enc, req.Payload)
+		return cliproxyexecutor.Response{}, fmt.Errorf("cursor: token counting failed: %w", err)
 	}
-
-	return cliproxyexecutor.Response{Payload: buildOpenAIUsageJSON(count)}, nil
+	return cliproxyexecutor.Response{Payload: translated}, nil
 }
 
 // Refresh attempts to refresh the Cursor access token.
@@ -293,9 +284,13 @@ func (e *CursorExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 		payload = sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(payload), false)
 	}
 
-	parsed := parseOpenAIRequest(payload)
+	emulateTools := e.emulatesTools(req.Model, payload)
+	parsed := parseOpenAIRequest(cursorRequestSource(payload, emulateTools))
 	ccSessId := extractClaudeCodeSessionId(req.Payload)
 	conversationId := deriveConversationId(apiKeyFromContext(ctx), ccSessId, parsed.SystemPrompt)
+	if e.flattensHistory() && (len(parsed.Turns) > 0 || len(parsed.ToolResults) > 0) {
+		flattenConversationIntoUserText(parsed)
+	}
 	params := buildRunRequestParams(parsed, conversationId)
 
 	requestBytes := cursorproto.EncodeRunRequest(params)
@@ -319,25 +314,30 @@ func (e *CursorExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 
 	// Collect full text from streaming response
 	var fullText strings.Builder
+	usage := newCursorTokenUsage(parsed.Model, payload)
 	if streamErr := processH2SessionFrames(sessionCtx, stream, params.BlobStore, nil,
 		func(text string, isThinking bool) {
 			fullText.WriteString(text)
 		},
 		nil,
 		nil,
-		nil, // tokenUsage - non-streaming
-		nil, // onCheckpoint - non-streaming doesn't persist
+		usage,
+		usage.setCheckpoint, // non-streaming doesn't persist, only reads token accounting
 	); streamErr != nil && fullText.Len() == 0 {
 		return resp, classifyCursorError(fmt.Errorf("cursor: stream error: %w", streamErr))
 	}
 
 	id := "chatcmpl-" + uuid.New().String()[:28]
 	created := time.Now().Unix()
-	openaiResp := fmt.Sprintf(`{"id":"%s","object":"chat.completion","created":%d,"model":"%s","choices":[{"index":0,"message":{"role":"assistant","content":%s},"finish_reason":"stop"}],"usage":{"prompt_tokens":0,"completion_tokens":0,"total_tokens":0}}`,
-		id, created, parsed.Model, jsonString(fullText.String()))
+	inputTok, outputTok := usage.get()
+	openaiResp := fmt.Sprintf(`{"id":"%s","object":"chat.completion","created":%d,"model":"%s","choices":[{"index":0,"message":{"role":"assistant","content":%s},"finish_reason":"stop"}],"usage":{"prompt_tokens":%d,"completion_tokens":%d,"total_tokens":%d}}`,
+		id, created, parsed.Model, jsonString(fullText.String()), inputTok, outputTok, inputTok+outputTok)
 
 	// Translate response back to source format if needed
 	result := []byte(openaiResp)
+	if emulateTools {
+		result = toolemulation.ApplyToResponse(result)
+	}
 	if from.String() != "" && from.String() != "openai" {
 		var param any
 		result = sdktranslator.TranslateNonStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), payload, result, &param)
@@ -387,7 +387,10 @@ func (e *CursorExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 		log.Debugf("cursor: translated payload len=%d", len(payload))
 	}
 
-	parsed := parseOpenAIRequest(payload)
+	// With tool emulation the tools travel in the prompt, so there are no MCP
+	// tools or tool results and no session to resume.
+	emulateTools := e.emulatesTools(req.Model, payload)
+	parsed := parseOpenAIRequest(cursorRequestSource(payload, emulateTools))
 	log.Debugf("cursor: parsed request: model=%s userText=%d chars, turns=%d, tools=%d, toolResults=%d",
 		parsed.Model, len(parsed.UserText), len(parsed.Turns), len(parsed.Tools), len(parsed.ToolResults))
 
@@ -450,42 +453,37 @@ func (e *CursorExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 
 	// Look up saved checkpoint for this conversation (keyed by conversationId only).
 	// Checkpoint is auth-specific: if auth changed (e.g. quota exhaustion failover),
-	// the old checkpoint is useless on the new account — discard and flatten.
-	e.mu.Lock()
-	saved, hasCheckpoint := e.checkpoints[checkpointKey]
-	e.mu.Unlock()
-
+	// the old checkpoint is useless on the new account — discard it and send the
+	// structured history instead.
+	saved := e.loadCheckpoint(checkpointKey)
 	params := buildRunRequestParams(parsed, conversationId)
 
-	if hasCheckpoint && saved.data != nil && saved.authID == authID {
+	switch {
+	case saved == nil:
+		// No checkpoint (new conversation, expired, or proxy restart without a
+		// persisted session): the full structured history is sent as turns.
+		log.Debugf("cursor: no checkpoint, sending %d structured turns", len(parsed.Turns))
+	case saved.authID != authID:
+		// Auth changed (quota failover) — checkpoint is not portable across accounts.
+		log.Infof("cursor: auth migrated (%s → %s) for conv=%s, discarding checkpoint and sending structured history", saved.authID, authID, checkpointKey)
+		e.dropCheckpoint(checkpointKey)
+	case parsed.AwaitingToolResults:
+		// The checkpoint ends with tool calls whose results belong to a stream that
+		// no longer exists; the structured history carries them instead.
+		log.Debugf("cursor: checkpoint for conv=%s has unresolved tool calls, sending structured history", checkpointKey)
+	default:
 		// Same auth — use checkpoint normally
 		log.Debugf("cursor: using saved checkpoint (%d bytes) for conv=%s auth=%s", len(saved.data), checkpointKey, authID)
 		params.RawCheckpoint = saved.data
 		// Merge saved blobStore into params
-		if params.BlobStore == nil {
-			params.BlobStore = make(map[string][]byte)
-		}
 		for k, v := range saved.blobStore {
 			if _, exists := params.BlobStore[k]; !exists {
 				params.BlobStore[k] = v
 			}
 		}
-	} else if hasCheckpoint && saved.data != nil && saved.authID != authID {
-		// Auth changed (quota failover) — checkpoint is not portable across accounts.
-		// Discard and flatten conversation history into userText.
-		log.Infof("cursor: auth migrated (%s → %s) for conv=%s, discarding checkpoint and flattening context", saved.authID, authID, checkpointKey)
-		e.mu.Lock()
-		delete(e.checkpoints, checkpointKey)
-		e.mu.Unlock()
-		if len(parsed.ToolResults) > 0 || len(parsed.Turns) > 0 {
-			flattenConversationIntoUserText(parsed)
-			params = buildRunRequestParams(parsed, conversationId)
-		}
-	} else if len(parsed.ToolResults) > 0 || len(parsed.Turns) > 0 {
-		// Fallback: no checkpoint available (cold resume / proxy restart).
-		// Flatten the full conversation history (including tool interactions) into userText.
-		// Cursor's turns encoding is not reliably read by the model, but userText always works.
-		log.Debugf("cursor: no checkpoint, flattening %d turns + %d tool results into userText", len(parsed.Turns), len(parsed.ToolResults))
+	}
+	if params.RawCheckpoint == nil && e.flattensHistory() && (len(parsed.Turns) > 0 || len(parsed.ToolResults) > 0) {
+		log.Debugf("cursor: flattening %d turns + %d tool results into userText", len(parsed.Turns), len(parsed.ToolResults))
 		flattenConversationIntoUserText(parsed)
 		params = buildRunRequestParams(parsed, conversationId)
 	}
@@ -513,6 +511,10 @@ func (e *CursorExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 	created := time.Now().Unix()
 
 	var streamParam any
+	var converter *toolemulation.StreamConverter
+	if emulateTools {
+		converter = toolemulation.NewStreamConverter()
+	}
 
 	// Tool result channel for inline mode. processH2SessionFrames blocks on it
 	// when mcpArgs is received, while continuing to handle KV/heartbeat.
@@ -534,6 +536,26 @@ func (e *CursorExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 		}
 	}
 
+	// emitOpenAIChunk passes an OpenAI chunk through tool emulation, when enabled,
+	// and translates it to the client format.
+	emitOpenAIChunk := func(openaiJSON string) {
+		items := [][]byte{[]byte(openaiJSON)}
+		if converter != nil {
+			items = converter.Process(items[0])
+		}
+		for _, item := range items {
+			if needsTranslate {
+				sseLine := append(append([]byte("data: "), item...), '\n')
+				translated := sdktranslator.TranslateStream(ctx, to, from, req.Model, originalPayload, payload, sseLine, &streamParam)
+				for _, t := range translated {
+					emitToOut(cliproxyexecutor.StreamChunk{Payload: bytes.Clone(t)})
+				}
+			} else {
+				emitToOut(cliproxyexecutor.StreamChunk{Payload: item})
+			}
+		}
+	}
+
 	// Wrap sendChunk/sendDone to use emitToOut
 	sendChunkSwitchable := func(delta string, finishReason string) {
 		fr := "null"
@@ -542,16 +564,7 @@ func (e *CursorExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 		}
 		openaiJSON := fmt.Sprintf(`{"id":"%s","object":"chat.completion.chunk","created":%d,"model":"%s","choices":[{"index":0,"delta":%s,"finish_reason":%s}]}`,
 			chatId, created, parsed.Model, delta, fr)
-		sseLine := []byte("data: " + openaiJSON + "\n")
-
-		if needsTranslate {
-			translated := sdktranslator.TranslateStream(ctx, to, from, req.Model, originalPayload, payload, sseLine, &streamParam)
-			for _, t := range translated {
-				emitToOut(cliproxyexecutor.StreamChunk{Payload: bytes.Clone(t)})
-			}
-		} else {
-			emitToOut(cliproxyexecutor.StreamChunk{Payload: []byte(openaiJSON)})
-		}
+		emitOpenAIChunk(openaiJSON)
 	}
 
 	sendDoneSwitchable := func() {
@@ -585,8 +598,7 @@ func (e *CursorExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 		_ = resumeOutCh
 		thinkingActive := false
 		toolCallIndex := 0
-		usage := &cursorTokenUsage{}
-		usage.setInputEstimate(len(payload))
+		usage := newCursorTokenUsage(parsed.Model, payload)
 
 		streamErr := processH2SessionFrames(sessionCtx, stream, params.BlobStore, params.McpTools,
 			func(text string, isThinking bool) {
@@ -659,15 +671,14 @@ func (e *CursorExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 			toolResultCh,
 			usage,
 			func(cpData []byte) {
+				usage.setCheckpoint(cpData)
 				// Save checkpoint keyed by conversationId, tagged with authID for migration detection
-				e.mu.Lock()
-				e.checkpoints[checkpointKey] = &savedCheckpoint{
+				e.saveCheckpoint(checkpointKey, &savedCheckpoint{
 					data:      cpData,
 					blobStore: params.BlobStore,
 					authID:    authID,
 					updatedAt: time.Now(),
-				}
-				e.mu.Unlock()
+				})
 				log.Debugf("cursor: saved checkpoint (%d bytes) for conv=%s auth=%s", len(cpData), checkpointKey, authID)
 			},
 		)
@@ -707,15 +718,7 @@ func (e *CursorExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 		fr := `"stop"`
 		openaiJSON := fmt.Sprintf(`{"id":"%s","object":"chat.completion.chunk","created":%d,"model":"%s","choices":[{"index":0,"delta":{},"finish_reason":%s}],"usage":{"prompt_tokens":%d,"completion_tokens":%d,"total_tokens":%d}}`,
 			chatId, created, parsed.Model, fr, inputTok, outputTok, inputTok+outputTok)
-		sseLine := []byte("data: " + openaiJSON + "\n")
-		if needsTranslate {
-			translated := sdktranslator.TranslateStream(ctx, to, from, req.Model, originalPayload, payload, sseLine, &streamParam)
-			for _, t := range translated {
-				emitToOut(cliproxyexecutor.StreamChunk{Payload: bytes.Clone(t)})
-			}
-		} else {
-			emitToOut(cliproxyexecutor.StreamChunk{Payload: []byte(openaiJSON)})
-		}
+		emitOpenAIChunk(openaiJSON)
 		sendDoneSwitchable()
 		_ = stopDelta // unused
 
@@ -742,6 +745,22 @@ func (e *CursorExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 	}
 }
 
+// emulatesTools reports whether tool calling is emulated through the prompt for
+// this model and request instead of Cursor's MCP tools.
+func (e *CursorExecutor) emulatesTools(model string, payload []byte) bool {
+	if e.cfg == nil || !toolemulation.Enabled(e.cfg.ToolEmulation, cursorAuthType, thinking.ParseSuffix(model).ModelName) {
+		return false
+	}
+	return toolemulation.HasTools(payload)
+}
+
+func cursorRequestSource(payload []byte, emulateTools bool) []byte {
+	if !emulateTools {
+		return payload
+	}
+	return toolemulation.RewriteRequest(payload)
+}
+
 // resumeWithToolResults injects tool results into the running processH2SessionFrames
 // via the toolResultCh channel. The original goroutine from ExecuteStream is still alive,
 // blocking on toolResultCh. Once we send the results, it sends the MCP result to Cursor
@@ -817,11 +836,22 @@ func cursorH2Heartbeat(ctx context.Context, stream *cursorproto.H2Stream) {
 
 // --- Response processing ---
 
-// cursorTokenUsage tracks token counts from Cursor's TokenDeltaUpdate messages.
+// cursorTokenUsage tracks token counts from Cursor's own accounting: output
+// tokens from TokenDeltaUpdate messages and the conversation's context size from
+// the token_details of checkpoint updates.
 type cursorTokenUsage struct {
-	mu             sync.Mutex
-	outputTokens   int64
-	inputTokensEst int64 // estimated from request payload size
+	mu            sync.Mutex
+	outputTokens  int64
+	contextTokens int64 // used_tokens of the latest checkpoint, 0 until one arrives
+	inputFallback int64 // local tokenizer count, used when no checkpoint reports usage
+}
+
+func newCursorTokenUsage(model string, payload []byte) *cursorTokenUsage {
+	u := &cursorTokenUsage{}
+	if result, err := tokencount.Count(model, "openai", payload); err == nil {
+		u.inputFallback = result.Tokens
+	}
+	return u
 }
 
 func (u *cursorTokenUsage) addOutput(delta int64) {
@@ -830,20 +860,27 @@ func (u *cursorTokenUsage) addOutput(delta int64) {
 	u.outputTokens += delta
 }
 
-func (u *cursorTokenUsage) setInputEstimate(payloadBytes int) {
+// setCheckpoint records the context size reported by a checkpoint update.
+func (u *cursorTokenUsage) setCheckpoint(checkpoint []byte) {
+	used, _, ok := cursorproto.DecodeCheckpointTokenDetails(checkpoint)
+	if !ok || used <= 0 {
+		return
+	}
 	u.mu.Lock()
 	defer u.mu.Unlock()
-	// Rough estimate: ~4 bytes per token for mixed content
-	u.inputTokensEst = int64(payloadBytes / 4)
-	if u.inputTokensEst < 1 {
-		u.inputTokensEst = 1
-	}
+	u.contextTokens = used
 }
 
+// get returns input and output tokens. The checkpoint's context size covers the
+// prompt and the reply, so input is what remains after the streamed output.
 func (u *cursorTokenUsage) get() (input, output int64) {
 	u.mu.Lock()
 	defer u.mu.Unlock()
-	return u.inputTokensEst, u.outputTokens
+	input = u.inputFallback
+	if u.contextTokens > u.outputTokens {
+		input = u.contextTokens - u.outputTokens
+	}
+	return input, u.outputTokens
 }
 
 func processH2SessionFrames(
@@ -1080,15 +1117,18 @@ func processH2SessionFrames(
 // --- OpenAI request parsing ---
 
 type parsedOpenAIRequest struct {
-	Model       string
-	Messages    []gjson.Result
-	Tools       []gjson.Result
-	Stream      bool
+	Model        string
+	Messages     []gjson.Result
+	Tools        []gjson.Result
+	Stream       bool
 	SystemPrompt string
-	UserText    string
-	Images      []cursorproto.ImageData
-	Turns       []cursorproto.TurnData
-	ToolResults []toolResultInfo
+	UserText     string
+	Images       []cursorproto.ImageData
+	Turns        []cursorproto.TurnData
+	ToolResults  []toolResultInfo
+	// AwaitingToolResults is set when the conversation ends with tool results
+	// for the assistant's last tool calls rather than with a user message.
+	AwaitingToolResults bool
 }
 
 type toolResultInfo struct {
@@ -1096,6 +1136,14 @@ type toolResultInfo struct {
 	Content    string
 }
 
+// cursorContinueText is sent as the user message when the conversation ends
+// with tool results and there is no live session to deliver them to.
+const cursorContinueText = "Continue based on the tool results above."
+
+// parseOpenAIRequest maps an OpenAI chat request onto Cursor's conversation
+// model: every user message opens a turn, and the assistant text, reasoning and
+// tool calls that follow become the steps of that turn, with tool results
+// attached to the calls that produced them.
 func parseOpenAIRequest(payload []byte) *parsedOpenAIRequest {
 	p := &parsedOpenAIRequest{
 		Model:  gjson.GetBytes(payload, "model").String(),
@@ -1118,51 +1166,68 @@ func parseOpenAIRequest(payload []byte) *parsedOpenAIRequest {
 		p.SystemPrompt = "You are a helpful assistant."
 	}
 
-	// Extract turns, tool results, and last user message
-	var pendingUser string
+	var current *cursorproto.TurnData
+	calls := make(map[string]*cursorproto.ToolCallData)
+	lastRole := ""
 	for _, msg := range messages {
 		role := msg.Get("role").String()
 		switch role {
-		case "system":
+		case "system", "developer":
 			continue
 		case "tool":
-			p.ToolResults = append(p.ToolResults, toolResultInfo{
-				ToolCallId: msg.Get("tool_call_id").String(),
-				Content:    extractTextContent(msg.Get("content")),
-			})
+			id := msg.Get("tool_call_id").String()
+			content := extractTextContent(msg.Get("content"))
+			p.ToolResults = append(p.ToolResults, toolResultInfo{ToolCallId: id, Content: content})
+			if call := calls[id]; call != nil {
+				call.Result = content
+				call.HasResult = true
+			}
 		case "user":
-			if pendingUser != "" {
-				p.Turns = append(p.Turns, cursorproto.TurnData{UserText: pendingUser})
+			if current != nil {
+				p.Turns = append(p.Turns, *current)
+			}
+			current = &cursorproto.TurnData{
+				UserText: extractTextContent(msg.Get("content")),
+				Images:   extractImages(msg.Get("content")),
 			}
-			pendingUser = extractTextContent(msg.Get("content"))
-			p.Images = extractImages(msg.Get("content"))
 		case "assistant":
-			assistantText := extractTextContent(msg.Get("content"))
-			if pendingUser != "" {
-				p.Turns = append(p.Turns, cursorproto.TurnData{
-					UserText:      pendingUser,
-					AssistantText: assistantText,
-				})
-				pendingUser = ""
-			} else if len(p.Turns) > 0 && assistantText != "" {
-				// Assistant message after tool results (no pending user) —
-				// append to the last turn's assistant text to preserve context.
-				last := &p.Turns[len(p.Turns)-1]
-				if last.AssistantText != "" {
-					last.AssistantText += "\n" + assistantText
-				} else {
-					last.AssistantText = assistantText
+			if current == nil {
+				current = &cursorproto.TurnData{}
+			}
+			if reasoning := msg.Get("reasoning_content").String(); reasoning != "" {
+				current.Steps = append(current.Steps, cursorproto.StepData{Thinking: reasoning})
+			}
+			if text := extractTextContent(msg.Get("content")); text != "" {
+				current.Steps = append(current.Steps, cursorproto.StepData{Text: text})
+			}
+			for _, tc := range msg.Get("tool_calls").Array() {
+				call := &cursorproto.ToolCallData{
+					ID:        tc.Get("id").String(),
+					Name:      tc.Get("function.name").String(),
+					Arguments: tc.Get("function.arguments").String(),
 				}
+				calls[call.ID] = call
+				current.Steps = append(current.Steps, cursorproto.StepData{ToolCall: call})
 			}
+		default:
+			continue
 		}
+		lastRole = role
 	}
 
-	if pendingUser != "" {
-		p.UserText = pendingUser
-	} else if len(p.Turns) > 0 && len(p.ToolResults) == 0 {
-		last := p.Turns[len(p.Turns)-1]
-		p.Turns = p.Turns[:len(p.Turns)-1]
-		p.UserText = last.UserText
+	switch {
+	case current == nil:
+	case lastRole == "tool":
+		// Mid-turn: the assistant called tools and the client sent the results.
+		// Keep the turn in history so the results stay attached to their calls.
+		p.Turns = append(p.Turns, *current)
+		p.UserText = cursorContinueText
+		p.AwaitingToolResults = true
+	default:
+		// The conversation ends with the user (or with an assistant prefill, which
+		// Cursor cannot continue): that user message is the new request.
+		p.UserText = current.UserText
+		p.Images = current.Images
 	}
 
 	// Extract tools
@@ -1171,42 +1236,68 @@ func parseOpenAIRequest(payload []byte) *parsedOpenAIRequest {
 	return p
 }
 
-// bakeToolResultsIntoTurns merges tool results into the last turn's assistant text
-// when there's no active H2 session to resume. This ensures the model sees the
-// full tool interaction context in a new conversation.
+// flattensHistory reports whether history without a usable checkpoint is sent as
+// plain text rather than as structured turns (cursor-sessions.flatten-history).
+func (e *CursorExecutor) flattensHistory() bool {
+	return e.cfg != nil && e.cfg.CursorSessions.FlattenHistory
+}
+
 // flattenConversationIntoUserText flattens the full conversation history
-// (turns + tool results) into the UserText field as plain text.
-// This is the fallback for cold resume when no checkpoint is available.
-// Cursor reliably reads UserText but ignores structured turns.
+// (turns, tool calls and their results) into the UserText field as plain text.
+// It is the fallback to the structured turns when flatten-history is set.
 func flattenConversationIntoUserText(parsed *parsedOpenAIRequest) {
 	var buf strings.Builder
+	writeResult := func(callID, content string) {
+		buf.WriteString("TOOL_RESULT (call_id: ")
+		buf.WriteString(callID)
+		buf.WriteString("): ")
+		// Truncate very large tool results to avoid overwhelming the context
+		if len(content) > 8000 {
+			content = content[:8000] + "\n... [truncated]"
+		}
+		buf.WriteString(content)
+		buf.WriteString("\n\n")
+	}
 
-	// Flatten turns into readable context
+	rendered := make(map[string]struct{})
 	for _, turn := range parsed.Turns {
 		if turn.UserText != "" {
 			buf.WriteString("USER: ")
 			buf.WriteString(turn.UserText)
 			buf.WriteString("\n\n")
 		}
-		if turn.AssistantText != "" {
+		if len(turn.Steps) == 0 && turn.AssistantText != "" {
 			buf.WriteString("ASSISTANT: ")
 			buf.WriteString(turn.AssistantText)
 			buf.WriteString("\n\n")
 		}
+		for _, step := range turn.Steps {
+			switch {
+			case step.Text != "":
+				buf.WriteString("ASSISTANT: ")
+				buf.WriteString(step.Text)
+				buf.WriteString("\n\n")
+			case step.ToolCall != nil:
+				call := step.ToolCall
+				buf.WriteString("TOOL_CALL (call_id: ")
+				buf.WriteString(call.ID)
+				buf.WriteString("): ")
+				buf.WriteString(call.Name)
+				buf.WriteString(" ")
+				buf.WriteString(call.Arguments)
+				buf.WriteString("\n\n")
+				if call.HasResult {
+					writeResult(call.ID, call.Result)
+					rendered[call.ID] = struct{}{}
+				}
+			}
+		}
 	}
-
-	// Flatten tool results
+	// Results whose call is not part of the history
 	for _, tr := range parsed.ToolResults {
-		buf.WriteString("TOOL_RESULT (call_id: ")
-		buf.WriteString(tr.ToolCallId)
-		buf.WriteString("): ")
-		// Truncate very large tool results to avoid overwhelming the context
-		content := tr.Content
-		if len(content) > 8000 {
-			content = content[:8000] + "\n... [truncated]"
+		if _, ok := rendered[tr.ToolCallId]; !ok {
+			writeResult(tr.ToolCallId, tr.Content)
 		}
-		buf.WriteString(content)
-		buf.WriteString("\n\n")
 	}
 
 	if buf.Len() > 0 {
@@ -1224,6 +1315,7 @@ func flattenConversationIntoUserText(parsed *parsedOpenAIRequest) {
 	// Clear turns and tool results since they're now in UserText
 	parsed.Turns = nil
 	parsed.ToolResults = nil
+	parsed.AwaitingToolResults = false
 }
 
 func extractTextContent(content gjson.Result) string {
@@ -0,0 +1,151 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	cursorproto "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/cursor/proto"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+const cursorHistoryRequest = `{"model":"claude-4-sonnet","messages":[
+	{"role":"system","content":"Be terse."},
+	{"role":"user","content":[{"type":"text","text":"what is in this image?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,aGVsbG8="}}]},
+	{"role":"assistant","content":"A cat."},
+	{"role":"user","content":"read a.txt"},
+	{"role":"assistant","content":"Reading.","reasoning_content":"need the file","tool_calls":[{"id":"call_1","type":"function","function":{"name":"read_file","arguments":"{\"path\":\"a.txt\"}"}}]},
+	{"role":"tool","tool_call_id":"call_1","content":"hello"}
+]}`
+
+func TestParseOpenAIRequestStructuredHistory(t *testing.T) {
+	parsed := parseOpenAIRequest([]byte(cursorHistoryRequest))
+	if parsed.SystemPrompt != "Be terse." {
+		t.Fatalf("system prompt = %q", parsed.SystemPrompt)
+	}
+	if len(parsed.Turns) != 2 {
+		t.Fatalf("turns = %d, want 2", len(parsed.Turns))
+	}
+	first := parsed.Turns[0]
+	if first.UserText != "what is in this image?" || len(first.Images) != 1 || string(first.Images[0].Data) != "hello" {
+		t.Fatalf("first turn lost its user message or image: %+v", first)
+	}
+	if len(first.Steps) != 1 || first.Steps[0].Text != "A cat." {
+		t.Fatalf("first turn steps = %+v", first.Steps)
+	}
+	second := parsed.Turns[1]
+	if len(second.Steps) != 3 || second.Steps[0].Thinking != "need the file" || second.Steps[1].Text != "Reading." {
+		t.Fatalf("second turn steps = %+v", second.Steps)
+	}
+	call := second.Steps[2].ToolCall
+	if call == nil || call.Name != "read_file" || call.Arguments != `{"path":"a.txt"}` || !call.HasResult || call.Result != "hello" {
+		t.Fatalf("tool call = %+v", call)
+	}
+	if !parsed.AwaitingToolResults || parsed.UserText != cursorContinueText {
+		t.Fatalf("expected continuation request, got %q", parsed.UserText)
+	}
+	if len(parsed.ToolResults) != 1 || parsed.ToolResults[0].ToolCallId != "call_1" {
+		t.Fatalf("tool results = %+v", parsed.ToolResults)
+	}
+	if encoded := cursorproto.EncodeRunRequest(buildRunRequestParams(parsed, "conv")); len(encoded) == 0 {
+		t.Fatal("empty run request")
+	}
+}
+
+func TestParseOpenAIRequestEndsWithUser(t *testing.T) {
+	parsed := parseOpenAIRequest([]byte(`{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"},{"role":"user","content":"again"}]}`))
+	if parsed.UserText != "again" || parsed.AwaitingToolResults || len(parsed.Turns) != 1 {
+		t.Fatalf("unexpected parse: %+v", parsed)
+	}
+}
+
+func TestFlattenConversationIntoUserText(t *testing.T) {
+	parsed := parseOpenAIRequest([]byte(cursorHistoryRequest))
+	flattenConversationIntoUserText(parsed)
+	if len(parsed.Turns) != 0 || len(parsed.ToolResults) != 0 || parsed.AwaitingToolResults {
+		t.Fatalf("flattened request kept structured history: %+v", parsed)
+	}
+	for _, want := range []string{
+		"USER: what is in this image?",
+		"ASSISTANT: A cat.",
+		`TOOL_CALL (call_id: call_1): read_file {"path":"a.txt"}`,
+		"TOOL_RESULT (call_id: call_1): hello",
+		"Current request: " + cursorContinueText,
+	} {
+		if !strings.Contains(parsed.UserText, want) {
+			t.Fatalf("flattened text missing %q:\n%s", want, parsed.UserText)
+		}
+	}
+	if strings.Count(parsed.UserText, "TOOL_RESULT") != 1 {
+		t.Fatalf("tool result rendered more than once:\n%s", parsed.UserText)
+	}
+}
+
+func TestCursorToolEmulationRendersToolsIntoPrompt(t *testing.T) {
+	e := &CursorExecutor{cfg: &config.Config{ToolEmulation: []config.ToolEmulationRule{{Provider: "cursor", Models: []string{"claude-4-*"}}}}}
+	payload := []byte(cursorHistoryRequest)
+	if !e.emulatesTools("claude-4-sonnet(high)", payload) {
+		t.Fatal("emulatesTools = false for an opted-in model")
+	}
+	if e.emulatesTools("gpt-5", payload) {
+		t.Fatal("emulatesTools = true for a model without a rule")
+	}
+
+	parsed := parseOpenAIRequest(cursorRequestSource(payload, true))
+	if len(parsed.Tools) != 0 || len(parsed.ToolResults) != 0 || parsed.AwaitingToolResults {
+		t.Fatalf("emulated request kept native tool state: %+v", parsed)
+	}
+	if !strings.Contains(parsed.UserText, "<tool_result") || !strings.Contains(parsed.UserText, "hello") {
+		t.Fatalf("tool result not rendered into the user message: %q", parsed.UserText)
+	}
+	last := parsed.Turns[len(parsed.Turns)-1].Steps
+	if len(last) == 0 || !strings.Contains(last[len(last)-1].Text, "<tool_call>") {
+		t.Fatalf("tool call not rendered into the assistant text: %+v", last)
+	}
+}
+
+func TestCursorCheckpointStorePersists(t *testing.T) {
+	cfg := &config.Config{CursorSessions: config.CursorSessionsConfig{Persist: true, Dir: t.TempDir(), TTLHours: 1}}
+	first := NewCursorExecutor(cfg)
+	first.saveCheckpoint("conv-1", &savedCheckpoint{
+		data:      []byte{1, 2, 3},
+		blobStore: map[string][]byte{"ab": []byte("blob")},
+		authID:    "cursor.json",
+		updatedAt: time.Now(),
+	})
+
+	restarted := NewCursorExecutor(cfg)
+	saved := restarted.loadCheckpoint("conv-1")
+	if saved == nil || string(saved.data) != "\x01\x02\x03" || saved.authID != "cursor.json" || string(saved.blobStore["ab"]) != "blob" {
+		t.Fatalf("restored checkpoint = %+v", saved)
+	}
+
+	restarted.dropCheckpoint("conv-1")
+	if NewCursorExecutor(cfg).loadCheckpoint("conv-1") != nil {
+		t.Fatal("dropped checkpoint was restored")
+	}
+	if restarted.loadCheckpoint("../escape") != nil {
+		t.Fatal("path traversal id should not resolve")
+	}
+}
+
+func TestCursorCountTokensAnswersInSourceFormat(t *testing.T) {
+	e := &CursorExecutor{}
+	body := []byte(`{"model":"claude-4-sonnet","messages":[{"role":"user","content":"Tell me a joke"}],"max_tokens":1024}`)
+	resp, err := e.CountTokens(context.Background(), nil, cliproxyexecutor.Request{
+		Model:   "claude-4-sonnet",
+		Payload: body,
+	}, cliproxyexecutor.Options{
+		SourceFormat: sdktranslator.FromString("claude"),
+	})
+	if err != nil {
+		t.Fatalf("CountTokens() error: %v", err)
+	}
+	if tokens := gjson.GetBytes(resp.Payload, "input_tokens").Int(); tokens <= 0 {
+		t.Fatalf("expected claude input_tokens, got payload: %s", resp.Payload)
+	}
+}
@@ -0,0 +1,193 @@
+package executor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// cursorCheckpointStore persists conversation checkpoints on disk so Cursor
+// conversations resume with their server-side state after a proxy restart.
+// Checkpoints hold conversation content, so files are written owner-only.
+type cursorCheckpointStore struct {
+	mu  sync.Mutex
+	dir string
+	ttl time.Duration
+}
+
+// cursorCheckpointFile is the on-disk form of a savedCheckpoint.
+type cursorCheckpointFile struct {
+	AuthID     string            `json:"auth_id"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Checkpoint []byte            `json:"checkpoint"`
+	Blobs      map[string][]byte `json:"blobs,omitempty"`
+}
+
+// newCursorCheckpointStore returns nil when session persistence is disabled.
+func newCursorCheckpointStore(cfg *config.Config) *cursorCheckpointStore {
+	if cfg == nil || !cfg.CursorSessions.Persist {
+		return nil
+	}
+	dir := cfg.CursorSessions.Dir
+	if dir == "" {
+		dir = "cursor-sessions"
+		if base := util.WritablePath(); base != "" {
+			dir = filepath.Join(base, "cursor-sessions")
+		}
+	}
+	ttlHours := cfg.CursorSessions.TTLHours
+	if ttlHours <= 0 {
+		ttlHours = config.DefaultCursorSessionTTLHours
+	}
+	return &cursorCheckpointStore{dir: dir, ttl: time.Duration(ttlHours) * time.Hour}
+}
+
+func (s *cursorCheckpointStore) path(conversationID string) (string, bool) {
+	name := filepath.Base(conversationID)
+	if name != conversationID || name == "." || name == "" || strings.HasPrefix(name, ".") {
+		return "", false
+	}
+	return filepath.Join(s.dir, name+".json"), true
+}
+
+func (s *cursorCheckpointStore) load(conversationID string) *savedCheckpoint {
+	path, ok := s.path(conversationID)
+	if !ok {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("cursor: failed to read persisted session %s: %v", conversationID, err)
+		}
+		return nil
+	}
+	var file cursorCheckpointFile
+	if err = json.Unmarshal(data, &file); err != nil || len(file.Checkpoint) == 0 {
+		log.Warnf("cursor: discarding unreadable persisted session %s", conversationID)
+		_ = os.Remove(path)
+		return nil
+	}
+	if time.Since(file.UpdatedAt) > s.ttl {
+		_ = os.Remove(path)
+		return nil
+	}
+	if file.Blobs == nil {
+		file.Blobs = make(map[string][]byte)
+	}
+	return &savedCheckpoint{
+		data:      file.Checkpoint,
+		blobStore: file.Blobs,
+		authID:    file.AuthID,
+		updatedAt: file.UpdatedAt,
+	}
+}
+
+func (s *cursorCheckpointStore) save(conversationID string, cp *savedCheckpoint) {
+	path, ok := s.path(conversationID)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(cursorCheckpointFile{
+		AuthID:     cp.authID,
+		UpdatedAt:  cp.updatedAt,
+		Checkpoint: cp.data,
+		Blobs:      cp.blobStore,
+	})
+	if err != nil {
+		log.Warnf("cursor: failed to encode session %s: %v", conversationID, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = os.MkdirAll(s.dir, 0o700); err != nil {
+		log.Warnf("cursor: failed to create session dir: %v", err)
+		return
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		log.Warnf("cursor: failed to persist session %s: %v", conversationID, err)
+		return
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		log.Warnf("cursor: failed to persist session %s: %v", conversationID, err)
+	}
+}
+
+func (s *cursorCheckpointStore) remove(conversationID string) {
+	if path, ok := s.path(conversationID); ok {
+		s.mu.Lock()
+		_ = os.Remove(path)
+		s.mu.Unlock()
+	}
+}
+
+// prune deletes persisted sessions older than the TTL.
+func (s *cursorCheckpointStore) prune() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, errInfo := entry.Info()
+		if errInfo == nil && time.Since(info.ModTime()) > s.ttl {
+			_ = os.Remove(filepath.Join(s.dir, entry.Name()))
+		}
+	}
+}
+
+// loadCheckpoint returns the checkpoint for a conversation from memory, falling
+// back to the persisted session store.
+func (e *CursorExecutor) loadCheckpoint(conversationID string) *savedCheckpoint {
+	e.mu.Lock()
+	saved := e.checkpoints[conversationID]
+	e.mu.Unlock()
+	if saved != nil && saved.data != nil {
+		return saved
+	}
+	if e.store == nil {
+		return nil
+	}
+	saved = e.store.load(conversationID)
+	if saved == nil {
+		return nil
+	}
+	log.Debugf("cursor: restored persisted checkpoint (%d bytes) for conv=%s", len(saved.data), conversationID)
+	e.mu.Lock()
+	e.checkpoints[conversationID] = saved
+	e.mu.Unlock()
+	return saved
+}
+
+func (e *CursorExecutor) saveCheckpoint(conversationID string, cp *savedCheckpoint) {
+	e.mu.Lock()
+	e.checkpoints[conversationID] = cp
+	e.mu.Unlock()
+	if e.store != nil {
+		e.store.save(conversationID, cp)
+	}
+}
+
+func (e *CursorExecutor) dropCheckpoint(conversationID string) {
+	e.mu.Lock()
+	delete(e.checkpoints, conversationID)
+	e.mu.Unlock()
+	if e.store != nil {
+		e.store.remove(conversationID)
+	}
+}
@@ -30,6 +30,7 @@ const (
 	githubCopilotBaseURL       = "https://api.githubcopilot.com"
 	githubCopilotChatPath      = "/chat/completions"
 	githubCopilotResponsesPath = "/responses"
+	githubCopilotMessagesPath  = "/v1/messages"
 	githubCopilotAuthType      = "github-copilot"
 	githubCopilotTokenCacheTTL = 25 * time.Minute
 	// tokenExpiryBuffer is the time before expiry when we should refresh the token.
@@ -115,6 +116,9 @@ func (e *GitHubCopilotExecutor) Execute(ctx context.Context, auth *cliproxyauth.
 	defer reporter.trackFailure(ctx, &err)
 
 	from := opts.SourceFormat
+	if useGitHubCopilotMessagesEndpoint(from, req.Model) {
+		return e.executeMessages(ctx, auth, apiToken, baseURL, req, opts, reporter)
+	}
 	useResponses := useGitHubCopilotResponsesEndpoint(from, req.Model)
 	to := sdktranslator.FromString("openai")
 	if useResponses {
@@ -148,9 +152,12 @@ func (e *GitHubCopilotExecutor) Execute(ctx context.Context, auth *cliproxyauth.
 		body = applyGitHubCopilotResponsesDefaults(body)
 	} else {
 		body = normalizeGitHubCopilotChatTools(body)
+		if from.String() == "claude" {
+			body = applyGitHubCopilotClaudeChatExtensions(req.Payload, body)
+		}
 	}
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	body = applyPayloadConfigWithRoot(e.cfg, req.Model, to.String(), "", body, originalTranslated, requestedModel)
+	body = applyPayloadConfigWithRoot(e.cfg, newPayloadRequest(ctx, opts), req.Model, to.String(), "", body, originalTranslated, requestedModel)
 	body, _ = sjson.SetBytes(body, "stream", false)
 
 	path := githubCopilotChatPath
@@ -231,6 +238,9 @@ func (e *GitHubCopilotExecutor) Execute(ctx context.Context, auth *cliproxyauth.
 	} else {
 		data = normalizeGitHubCopilotReasoningField(data)
 		converted = sdktranslator.TranslateNonStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), body, data, &param)
+		if from.String() == "claude" {
+			converted = applyGitHubCopilotReasoningSignature(data, converted)
+		}
 	}
 	resp = cliproxyexecutor.Response{Payload: converted, Headers: httpResp.Header.Clone()}
 	reporter.ensurePublished(ctx)
@@ -248,6 +258,9 @@ func (e *GitHubCopilotExecutor) ExecuteStream(ctx context.Context, auth *cliprox
 	defer reporter.trackFailure(ctx, &err)
 
 	from := opts.SourceFormat
+	if useGitHubCopilotMessagesEndpoint(from, req.Model) {
+		return e.executeMessagesStream(ctx, auth, apiToken, baseURL, req, opts, reporter)
+	}
 	useResponses := useGitHubCopilotResponsesEndpoint(from, req.Model)
 	to := sdktranslator.FromString("openai")
 	if useResponses {
@@ -281,9 +294,12 @@ func (e *GitHubCopilotExecutor) ExecuteStream(ctx context.Context, auth *cliprox
 		body = applyGitHubCopilotResponsesDefaults(body)
 	} else {
 		body = normalizeGitHubCopilotChatTools(body)
+		if from.String() == "claude" {
+			body = applyGitHubCopilotClaudeChatExtensions(req.Payload, body)
+		}
 	}
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	body = applyPayloadConfigWithRoot(e.cfg, req.Model, to.String(), "", body, originalTranslated, requestedModel)
+	body = applyPayloadConfigWithRoot(e.cfg, newPayloadRequest(ctx, opts), req.Model, to.String(), "", body, originalTranslated, requestedModel)
 	body, _ = sjson.SetBytes(body, "stream", true)
 	// Enable stream options for usage stats in stream
 	if !useResponses {
@@ -361,6 +377,7 @@ func (e *GitHubCopilotExecutor) ExecuteStream(ctx context.Context, auth *cliprox
 		scanner := bufio.NewScanner(httpResp.Body)
 		scanner.Buffer(nil, maxScannerBufferSize)
 		var param any
+		signatures := newGitHubCopilotSignatureInjector()
 
 		for scanner.Scan() {
 			line := scanner.Bytes()
@@ -399,6 +416,10 @@ func (e *GitHubCopilotExecutor) ExecuteStream(ctx context.Context, auth *cliprox
 					}
 				}
 				chunks = sdktranslator.TranslateStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), body, normalizedLine, &param)
+				if from.String() == "claude" {
+					signatures.observe(line)
+					chunks = signatures.inject(chunks)
+				}
 			}
 			for i := range chunks {
 				out <- cliproxyexecutor.StreamChunk{Payload: bytes.Clone(chunks[i])}
@@ -424,23 +445,10 @@ func (e *GitHubCopilotExecutor) ExecuteStream(ctx context.Context, auth *cliprox
 // Copilot API does not expose a dedicated token counting endpoint.
 func (e *GitHubCopilotExecutor) CountTokens(ctx context.Context, _ *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	baseModel := thinking.ParseSuffix(req.Model).ModelName
-
-	from := opts.SourceFormat
-	to := sdktranslator.FromString("openai")
-	translated := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, false)
-
-	enc, err := helps.TokenizerForModel(baseModel)
-	if err != nil {
-		return cliproxyexecutor.Response{}, fmt.Errorf("github copilot executor: tokenizer init failed: %w", err)
-	}
-
-	count, err := helps.CountOpenAIChatTokens(enc, translated)
+	translatedUsage, _, err := helps.LocalTokenCountResponse(ctx, baseModel, opts.SourceFormat, req.Payload)
 	if err != nil {
 		return cliproxyexecutor.Response{}, fmt.Errorf("github copilot executor: token counting failed: %w", err)
 	}
-
-	usageJSON := helps.BuildOpenAIUsageJSON(count)
-	translatedUsage := sdktranslator.TranslateTokenCount(ctx, to, from, count, usageJSON)
 	return cliproxyexecutor.Response{Payload: translatedUsage}, nil
 }
 
@@ -1626,6 +1634,18 @@ func FetchGitHubCopilotModels(ctx context.Context, auth *cliproxyauth.Auth, cfg
 			m.MaxCompletionTokens = defaultCopilotMaxCompletionTokens
 		}
 
+		// The API advertises a native Anthropic Messages route for models that
+		// accept Claude payloads unchanged; record it so Claude requests skip
+		// the chat translation. Other endpoint hints keep the static values.
+		if containsEndpoint(normalizeGitHubCopilotEndpoints(entry.SupportedEndpoints), githubCopilotMessagesPath) {
+			if len(m.SupportedEndpoints) == 0 {
+				m.SupportedEndpoints = []string{githubCopilotChatPath}
+			}
+			if !containsEndpoint(m.SupportedEndpoints, githubCopilotMessagesPath) {
+				m.SupportedEndpoints = append(slices.Clone(m.SupportedEndpoints), githubCopilotMessagesPath)
+			}
+		}
+
 		// Override with real limits from the Copilot API when available.
 		// The API returns per-account limits (individual vs business) under
 		// capabilities.limits, which are more accurate than our static
@@ -0,0 +1,476 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor/helps"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/common"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// githubCopilotAnthropicVersion is sent when the client did not pin one.
+const githubCopilotAnthropicVersion = "2023-06-01"
+
+// normalizeGitHubCopilotEndpoints canonicalises the supported_endpoints list
+// returned by the Copilot /models API so entries such as "v1/messages" or
+// "/Chat/Completions" compare equal to the path constants.
+func normalizeGitHubCopilotEndpoints(endpoints []string) []string {
+	out := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		endpoint = strings.ToLower(strings.TrimSpace(endpoint))
+		if endpoint == "" {
+			continue
+		}
+		if !strings.HasPrefix(endpoint, "/") {
+			endpoint = "/" + endpoint
+		}
+		out = append(out, strings.TrimSuffix(endpoint, "/"))
+	}
+	return out
+}
+
+// useGitHubCopilotMessagesEndpoint reports whether a Claude request for model
+// can be sent to Copilot's native Anthropic Messages route. Only models whose
+// endpoint metadata lists the route qualify; everything else keeps using the
+// chat or responses translation.
+func useGitHubCopilotMessagesEndpoint(sourceFormat sdktranslator.Format, model string) bool {
+	if sourceFormat.String() != "claude" {
+		return false
+	}
+	baseModel := strings.ToLower(thinking.ParseSuffix(model).ModelName)
+	if info := registry.GetGlobalRegistry().GetModelInfo(baseModel, githubCopilotAuthType); info != nil {
+		return containsEndpoint(info.SupportedEndpoints, githubCopilotMessagesPath)
+	}
+	if info := lookupGitHubCopilotStaticModelInfo(baseModel); info != nil {
+		return containsEndpoint(info.SupportedEndpoints, githubCopilotMessagesPath)
+	}
+	return false
+}
+
+// prepareGitHubCopilotMessagesBody keeps the Claude payload intact apart from
+// the model name, unsupported betas, thinking normalisation and payload rules.
+func (e *GitHubCopilotExecutor) prepareGitHubCopilotMessagesBody(ctx context.Context, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, stream bool) ([]byte, error) {
+	body := e.normalizeModel(req.Model, bytes.Clone(req.Payload))
+	body = stripUnsupportedBetas(body)
+	body, err := thinking.ApplyThinking(body, req.Model, "claude", "claude", e.Identifier())
+	if err != nil {
+		return nil, err
+	}
+	originalPayload := bytes.Clone(req.Payload)
+	if len(opts.OriginalRequest) > 0 {
+		originalPayload = bytes.Clone(opts.OriginalRequest)
+	}
+	requestedModel := payloadRequestedModel(opts, req.Model)
+	body = applyPayloadConfigWithRoot(e.cfg, newPayloadRequest(ctx, opts), req.Model, "claude", "", body, originalPayload, requestedModel)
+	body, _ = sjson.SetBytes(body, "stream", stream)
+	return body, nil
+}
+
+// newGitHubCopilotMessagesRequest builds the upstream request for the native
+// Messages route, forwarding the client's Anthropic version and betas.
+func (e *GitHubCopilotExecutor) newGitHubCopilotMessagesRequest(ctx context.Context, auth *cliproxyauth.Auth, apiToken, baseURL string, body []byte, stream bool) (*http.Request, error) {
+	url := baseURL + githubCopilotMessagesPath
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	e.applyHeaders(httpReq, apiToken, body)
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	var ginHeaders http.Header
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
+		ginHeaders = ginCtx.Request.Header
+	}
+	misc.EnsureHeader(httpReq.Header, ginHeaders, "Anthropic-Version", githubCopilotAnthropicVersion)
+	if betas := filterGitHubCopilotBetaHeader(ginHeaders.Get("Anthropic-Beta")); betas != "" {
+		httpReq.Header.Set("Anthropic-Beta", betas)
+	}
+	if detectVisionContent(body) {
+		httpReq.Header.Set("Copilot-Vision-Request", "true")
+	}
+
+	var authID, authLabel, authType, authValue string
+	if auth != nil {
+		authID = auth.ID
+		authLabel = auth.Label
+		authType, authValue = auth.AccountInfo()
+	}
+	recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
+		URL:       url,
+		Method:    http.MethodPost,
+		Headers:   httpReq.Header.Clone(),
+		Body:      body,
+		Provider:  e.Identifier(),
+		AuthID:    authID,
+		AuthLabel: authLabel,
+		AuthType:  authType,
+		AuthValue: authValue,
+	})
+	return httpReq, nil
+}
+
+// filterGitHubCopilotBetaHeader drops betas Copilot rejects from a
+// comma-separated Anthropic-Beta header value.
+func filterGitHubCopilotBetaHeader(value string) string {
+	var kept []string
+	for _, beta := range strings.Split(value, ",") {
+		beta = strings.TrimSpace(beta)
+		if beta == "" || isCopilotUnsupportedBeta(beta) {
+			continue
+		}
+		kept = append(kept, beta)
+	}
+	return strings.Join(kept, ",")
+}
+
+// executeMessages sends a Claude request to the native Messages route and
+// returns the upstream body unchanged.
+func (e *GitHubCopilotExecutor) executeMessages(ctx context.Context, auth *cliproxyauth.Auth, apiToken, baseURL string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, reporter *usageReporter) (resp cliproxyexecutor.Response, err error) {
+	body, err := e.prepareGitHubCopilotMessagesBody(ctx, req, opts, false)
+	if err != nil {
+		return resp, err
+	}
+	httpReq, err := e.newGitHubCopilotMessagesRequest(ctx, auth, apiToken, baseURL, body, false)
+	if err != nil {
+		return resp, err
+	}
+
+	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		recordAPIResponseError(ctx, e.cfg, err)
+		return resp, err
+	}
+	defer func() {
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("github-copilot executor: close response body error: %v", errClose)
+		}
+	}()
+
+	recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		recordAPIResponseError(ctx, e.cfg, err)
+		return resp, err
+	}
+	appendAPIResponseChunk(ctx, e.cfg, data)
+	if !isHTTPSuccess(httpResp.StatusCode) {
+		log.Debugf("github-copilot executor: upstream error status: %d, body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), data))
+		return resp, statusErr{code: httpResp.StatusCode, msg: string(data)}
+	}
+
+	if detail := helps.ParseClaudeUsage(data); detail.TotalTokens > 0 {
+		reporter.publish(ctx, detail)
+	}
+	reporter.ensurePublished(ctx)
+	return cliproxyexecutor.Response{Payload: data, Headers: httpResp.Header.Clone()}, nil
+}
+
+// executeMessagesStream streams the native Messages route back line by line
+// so SSE events, including signature and citation deltas, reach the client as
+// Copilot sent them.
+func (e *GitHubCopilotExecutor) executeMessagesStream(ctx context.Context, auth *cliproxyauth.Auth, apiToken, baseURL string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, reporter *usageReporter) (*cliproxyexecutor.StreamResult, error) {
+	body, err := e.prepareGitHubCopilotMessagesBody(ctx, req, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := e.newGitHubCopilotMessagesRequest(ctx, auth, apiToken, baseURL, body, true)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		recordAPIResponseError(ctx, e.cfg, err)
+		return nil, err
+	}
+
+	recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+
+	if !isHTTPSuccess(httpResp.StatusCode) {
+		data, readErr := io.ReadAll(httpResp.Body)
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("github-copilot executor: close response body error: %v", errClose)
+		}
+		if readErr != nil {
+			recordAPIResponseError(ctx, e.cfg, readErr)
+			return nil, readErr
+		}
+		appendAPIResponseChunk(ctx, e.cfg, data)
+		log.Debugf("github-copilot executor: upstream error status: %d, body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), data))
+		return nil, statusErr{code: httpResp.StatusCode, msg: string(data)}
+	}
+
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		defer func() {
+			if errClose := httpResp.Body.Close(); errClose != nil {
+				log.Errorf("github-copilot executor: close response body error: %v", errClose)
+			}
+		}()
+
+		scanner := bufio.NewScanner(httpResp.Body)
+		scanner.Buffer(nil, maxScannerBufferSize)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			appendAPIResponseChunk(ctx, e.cfg, line)
+			if detail, ok := helps.ParseClaudeStreamUsage(line); ok {
+				reporter.publish(ctx, detail)
+			}
+			cloned := make([]byte, len(line)+1)
+			copy(cloned, line)
+			cloned[len(line)] = '\n'
+			out <- cliproxyexecutor.StreamChunk{Payload: cloned}
+		}
+		if errScan := scanner.Err(); errScan != nil {
+			recordAPIResponseError(ctx, e.cfg, errScan)
+			reporter.publishFailure(ctx)
+			out <- cliproxyexecutor.StreamChunk{Err: errScan}
+		} else {
+			reporter.ensurePublished(ctx)
+		}
+	}()
+
+	return &cliproxyexecutor.StreamResult{
+		Headers: httpResp.Header.Clone(),
+		Chunks:  out,
+	}, nil
+}
+
+// Lossless mapping for Claude requests on the chat completions route.
+//
+// Models without the Messages route still receive Claude requests through the
+// OpenAI chat translator, which drops thinking signatures and cache_control.
+// Copilot's chat API accepts both under its own field names, so they are
+// restored on the translated body:
+//
+//   - The thinking blocks of the k-th emitted assistant message are copied to
+//     reasoning_text and the last non-empty signature to reasoning_opaque.
+//   - cache_control on system blocks marks the system message, on user or
+//     assistant blocks marks the message they were emitted into, and on a
+//     tool_result block marks the tool message with the same tool_call_id.
+//     Each marked message carries copilot_cache_control.
+//   - On the way back, reasoning_opaque becomes the signature of the thinking
+//     block, both in full responses and as a signature_delta before the
+//     thinking block's content_block_stop in streams.
+//
+// Redacted thinking and document blocks have no chat equivalent; clients that
+// depend on them need a model that exposes the Messages route.
+
+// githubCopilotCacheControl is the marker Copilot's chat API accepts in place
+// of Anthropic cache_control.
+const githubCopilotCacheControl = `{"type":"ephemeral"}`
+
+// applyGitHubCopilotClaudeChatExtensions restores thinking signatures and
+// cache_control from the original Claude request onto the translated chat body.
+func applyGitHubCopilotClaudeChatExtensions(claudeReq, body []byte) []byte {
+	messages := gjson.GetBytes(body, "messages")
+	if !messages.IsArray() {
+		return body
+	}
+	chat := messages.Array()
+	indexOfRole := func(from int, role string) int {
+		if from >= 0 && from < len(chat) && chat[from].Get("role").String() == role {
+			return from
+		}
+		return -1
+	}
+	mark := func(idx int) {
+		if idx >= 0 {
+			body, _ = sjson.SetRawBytes(body, fmt.Sprintf("messages.%d.copilot_cache_control", idx), []byte(githubCopilotCacheControl))
+		}
+	}
+
+	pos := 0
+	if system := gjson.GetBytes(claudeReq, "system"); system.IsArray() {
+		hasContent, cached := false, false
+		for _, block := range system.Array() {
+			if githubCopilotChatEmitsPart(block) {
+				hasContent = true
+			}
+			cached = cached || block.Get("cache_control").Exists()
+		}
+		if hasContent {
+			if cached {
+				mark(indexOfRole(0, "system"))
+			}
+			pos = 1
+		}
+	} else if system.Type == gjson.String && system.String() != "" {
+		pos = 1
+	}
+
+	gjson.GetBytes(claudeReq, "messages").ForEach(func(_, message gjson.Result) bool {
+		role := message.Get("role").String()
+		content := message.Get("content")
+		if content.Type == gjson.String {
+			pos++
+			return true
+		}
+		if !content.IsArray() {
+			return true
+		}
+
+		var reasoning []string
+		signature := ""
+		emits, cached := false, false
+		for _, part := range content.Array() {
+			switch part.Get("type").String() {
+			case "tool_result":
+				idx := indexOfRole(pos, "tool")
+				if idx >= 0 && part.Get("cache_control").Exists() && chat[idx].Get("tool_call_id").String() == part.Get("tool_use_id").String() {
+					mark(idx)
+				}
+				pos++
+			case "thinking":
+				if role != "assistant" {
+					continue
+				}
+				if text := thinking.GetThinkingText(part); strings.TrimSpace(text) != "" {
+					reasoning = append(reasoning, text)
+					emits = true
+				}
+				if sig := part.Get("signature").String(); sig != "" {
+					signature = sig
+				}
+			case "tool_use":
+				emits = emits || role == "assistant"
+				cached = cached || part.Get("cache_control").Exists()
+			case "text", "image":
+				if githubCopilotChatEmitsPart(part) {
+					emits = true
+				}
+				cached = cached || part.Get("cache_control").Exists()
+			}
+		}
+		if !emits {
+			return true
+		}
+		idx := indexOfRole(pos, role)
+		pos++
+		if idx < 0 {
+			return true
+		}
+		if cached {
+			mark(idx)
+		}
+		if role == "assistant" {
+			if len(reasoning) > 0 {
+				body, _ = sjson.SetBytes(body, fmt.Sprintf("messages.%d.reasoning_text", idx), strings.Join(reasoning, "\n\n"))
+			}
+			if signature != "" {
+				body, _ = sjson.SetBytes(body, fmt.Sprintf("messages.%d.reasoning_opaque", idx), signature)
+			}
+		}
+		return true
+	})
+	return body
+}
+
+// githubCopilotChatEmitsPart mirrors the translator's rule for which text and
+// image blocks survive as chat content parts.
+func githubCopilotChatEmitsPart(part gjson.Result) bool {
+	switch part.Get("type").String() {
+	case "text":
+		return strings.TrimSpace(part.Get("text").String()) != ""
+	case "image":
+		return part.Get("source.data").String() != "" || part.Get("source.url").String() != "" || part.Get("url").String() != ""
+	}
+	return false
+}
+
+// applyGitHubCopilotReasoningSignature copies reasoning_opaque from a chat
+// completion onto the thinking block of the translated Claude response.
+func applyGitHubCopilotReasoningSignature(chatResp, claudeResp []byte) []byte {
+	opaque := gjson.GetBytes(chatResp, "choices.0.message.reasoning_opaque").String()
+	if opaque == "" {
+		return claudeResp
+	}
+	for i, block := range gjson.GetBytes(claudeResp, "content").Array() {
+		if block.Get("type").String() == "thinking" {
+			claudeResp, _ = sjson.SetBytes(claudeResp, fmt.Sprintf("content.%d.signature", i), opaque)
+			break
+		}
+	}
+	return claudeResp
+}
+
+// githubCopilotSignatureInjector adds a signature_delta to translated Claude
+// stream events once Copilot has sent reasoning_opaque for the thinking block.
+type githubCopilotSignatureInjector struct {
+	opaque        string
+	thinkingIndex int64
+	done          bool
+}
+
+func newGitHubCopilotSignatureInjector() *githubCopilotSignatureInjector {
+	return &githubCopilotSignatureInjector{thinkingIndex: -1}
+}
+
+// observe records reasoning_opaque from a raw upstream chat stream line.
+func (s *githubCopilotSignatureInjector) observe(line []byte) {
+	if !bytes.HasPrefix(line, dataTag) {
+		return
+	}
+	if opaque := gjson.GetBytes(bytes.TrimSpace(line[len(dataTag):]), "choices.0.delta.reasoning_opaque").String(); opaque != "" {
+		s.opaque = opaque
+	}
+}
+
+// inject returns chunks with the signature_delta placed before the thinking
+// block's content_block_stop event.
+func (s *githubCopilotSignatureInjector) inject(chunks [][]byte) [][]byte {
+	if s.done {
+		return chunks
+	}
+	out := make([][]byte, 0, len(chunks)+1)
+	for _, chunk := range chunks {
+		payload := githubCopilotSSEData(chunk)
+		switch gjson.GetBytes(payload, "type").String() {
+		case "content_block_start":
+			if gjson.GetBytes(payload, "content_block.type").String() == "thinking" {
+				s.thinkingIndex = gjson.GetBytes(payload, "index").Int()
+			}
+		case "content_block_stop":
+			if s.opaque != "" && s.thinkingIndex >= 0 && gjson.GetBytes(payload, "index").Int() == s.thinkingIndex {
+				delta := []byte(`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":""}}`)
+				delta, _ = sjson.SetBytes(delta, "index", s.thinkingIndex)
+				delta, _ = sjson.SetBytes(delta, "delta.signature", s.opaque)
+				out = append(out, translatorcommon.AppendSSEEventBytes(nil, "content_block_delta", delta, 2))
+				s.done = true
+			}
+		}
+		out = append(out, chunk)
+	}
+	return out
+}
+
+// githubCopilotSSEData extracts the data payload from a single SSE event.
+func githubCopilotSSEData(event []byte) []byte {
+	for _, line := range bytes.Split(event, []byte("\n")) {
+		if bytes.HasPrefix(line, dataTag) {
+			return bytes.TrimSpace(line[len(dataTag):])
+		}
+	}
+	return nil
+}
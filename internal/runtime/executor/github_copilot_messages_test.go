@@ -0,0 +1,205 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// newGitHubCopilotMessagesTestExecutor returns an executor whose API token is
+// pre-cached against serverURL, plus an auth that resolves to it.
+func newGitHubCopilotMessagesTestExecutor(serverURL string) (*GitHubCopilotExecutor, *cliproxyauth.Auth) {
+	e := NewGitHubCopilotExecutor(&config.Config{})
+	e.cache["gh-token"] = &cachedAPIToken{token: "copilot-token", apiEndpoint: serverURL, expiresAt: time.Now().Add(time.Hour)}
+	auth := &cliproxyauth.Auth{ID: "copilot-test", Provider: githubCopilotAuthType, Metadata: map[string]any{"access_token": "gh-token"}}
+	return e, auth
+}
+
+func registerGitHubCopilotMessagesModel(t *testing.T, model string, endpoints ...string) {
+	t.Helper()
+	reg := registry.GetGlobalRegistry()
+	clientID := "github-copilot-messages-" + t.Name()
+	reg.RegisterClient(clientID, githubCopilotAuthType, []*registry.ModelInfo{{ID: model, SupportedEndpoints: endpoints}})
+	t.Cleanup(func() { reg.UnregisterClient(clientID) })
+}
+
+func TestNormalizeGitHubCopilotEndpoints(t *testing.T) {
+	t.Parallel()
+
+	got := normalizeGitHubCopilotEndpoints([]string{" /Chat/Completions ", "v1/messages/", ""})
+	want := []string{"/chat/completions", "/v1/messages"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("normalizeGitHubCopilotEndpoints() = %v, want %v", got, want)
+	}
+}
+
+func TestUseGitHubCopilotMessagesEndpoint(t *testing.T) {
+	registerGitHubCopilotMessagesModel(t, "claude-messages-probe", githubCopilotChatPath, githubCopilotMessagesPath)
+
+	if !useGitHubCopilotMessagesEndpoint(sdktranslator.FromString("claude"), "claude-messages-probe(high)") {
+		t.Fatal("expected claude source with /v1/messages metadata to use the Messages route")
+	}
+	if useGitHubCopilotMessagesEndpoint(sdktranslator.FromString("openai"), "claude-messages-probe") {
+		t.Fatal("expected openai source to keep the chat route")
+	}
+	if useGitHubCopilotMessagesEndpoint(sdktranslator.FromString("claude"), "claude-sonnet-4.5") {
+		t.Fatal("expected models without /v1/messages metadata to keep the chat route")
+	}
+}
+
+// TestGitHubCopilotMessagesConformance runs every request in the corpus
+// through the native route and checks that the blocks the chat translation
+// loses reach Copilot byte for byte.
+func TestGitHubCopilotMessagesConformance(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "github_copilot_messages", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("load corpus: %v (%d files)", err, len(files))
+	}
+	registerGitHubCopilotMessagesModel(t, "claude-sonnet-4.5", githubCopilotChatPath, githubCopilotMessagesPath)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			payload, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("read %s: %v", file, err)
+			}
+			var gotPath, gotVersion string
+			var gotBody []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotVersion = r.Header.Get("Anthropic-Version")
+				gotBody, _ = io.ReadAll(r.Body)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"thinking","thinking":"ok","signature":"sig-out"},{"type":"text","text":"done"}],"usage":{"input_tokens":10,"output_tokens":2}}`))
+			}))
+			defer server.Close()
+
+			e, auth := newGitHubCopilotMessagesTestExecutor(server.URL)
+			resp, err := e.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "claude-sonnet-4.5", Payload: payload}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude")})
+			if err != nil {
+				t.Fatalf("Execute() error: %v", err)
+			}
+			if gotPath != githubCopilotMessagesPath {
+				t.Fatalf("upstream path = %q, want %q", gotPath, githubCopilotMessagesPath)
+			}
+			if gotVersion != githubCopilotAnthropicVersion {
+				t.Fatalf("Anthropic-Version = %q, want %q", gotVersion, githubCopilotAnthropicVersion)
+			}
+			for _, field := range []string{"system", "messages", "tools", "tool_choice"} {
+				want := gjson.GetBytes(payload, field)
+				got := gjson.GetBytes(gotBody, field)
+				if want.Exists() && compactJSON(got.Raw) != compactJSON(want.Raw) {
+					t.Fatalf("%s changed in transit:\n got %s\nwant %s", field, got.Raw, want.Raw)
+				}
+			}
+			if sig := gjson.GetBytes(resp.Payload, "content.0.signature").String(); sig != "sig-out" {
+				t.Fatalf("response signature = %q, want sig-out", sig)
+			}
+		})
+	}
+}
+
+func TestGitHubCopilotMessagesStreamPassthrough(t *testing.T) {
+	registerGitHubCopilotMessagesModel(t, "claude-sonnet-4.5", githubCopilotMessagesPath)
+
+	events := strings.Join([]string{
+		"event: content_block_delta",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-stream"}}`,
+		"",
+		"event: message_delta",
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"input_tokens":5,"output_tokens":3}}`,
+		"",
+	}, "\n")
+	var gotBeta string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBeta = r.Header.Get("Anthropic-Beta")
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(events))
+	}))
+	defer server.Close()
+
+	e, auth := newGitHubCopilotMessagesTestExecutor(server.URL)
+	payload := []byte(`{"model":"claude-sonnet-4.5","max_tokens":64,"betas":["context-1m-2025-08-07"],"messages":[{"role":"user","content":"hi"}]}`)
+	result, err := e.ExecuteStream(context.Background(), auth, cliproxyexecutor.Request{Model: "claude-sonnet-4.5", Payload: payload}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude")})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error: %v", err)
+	}
+	var got strings.Builder
+	for chunk := range result.Chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream error: %v", chunk.Err)
+		}
+		got.Write(chunk.Payload)
+	}
+	if got.String() != events {
+		t.Fatalf("stream was altered:\n got %q\nwant %q", got.String(), events)
+	}
+	if gotBeta != "" {
+		t.Fatalf("Anthropic-Beta = %q, want none", gotBeta)
+	}
+}
+
+func TestApplyGitHubCopilotClaudeChatExtensions(t *testing.T) {
+	t.Parallel()
+
+	claudeReq := []byte(`{
+		"system":[{"type":"text","text":"sys","cache_control":{"type":"ephemeral"}}],
+		"messages":[
+			{"role":"user","content":[{"type":"text","text":"q","cache_control":{"type":"ephemeral"}}]},
+			{"role":"assistant","content":[{"type":"redacted_thinking","data":"x"}]},
+			{"role":"assistant","content":[{"type":"thinking","thinking":"plan","signature":"sig-1"},{"type":"tool_use","id":"t1","name":"f","input":{}}]},
+			{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"r","cache_control":{"type":"ephemeral"}},{"type":"text","text":"next"}]}
+		]}`)
+	body := sdktranslator.TranslateRequest(sdktranslator.FromString("claude"), sdktranslator.FromString("openai"), "claude-sonnet-4.5", claudeReq, false)
+	body = applyGitHubCopilotClaudeChatExtensions(claudeReq, body)
+
+	checks := map[string]string{
+		"messages.0.copilot_cache_control.type": "ephemeral",
+		"messages.1.copilot_cache_control.type": "ephemeral",
+		"messages.2.reasoning_text":             "plan",
+		"messages.2.reasoning_opaque":           "sig-1",
+		"messages.3.copilot_cache_control.type": "ephemeral",
+	}
+	for path, want := range checks {
+		if got := gjson.GetBytes(body, path).String(); got != want {
+			t.Fatalf("%s = %q, want %q; body=%s", path, got, want, body)
+		}
+	}
+	if gjson.GetBytes(body, "messages.4.copilot_cache_control").Exists() {
+		t.Fatalf("trailing user text should not be marked; body=%s", body)
+	}
+}
+
+func TestGitHubCopilotSignatureInjector(t *testing.T) {
+	t.Parallel()
+
+	s := newGitHubCopilotSignatureInjector()
+	start := []byte("event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"thinking\",\"thinking\":\"\"}}\n\n")
+	stop := []byte("event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n")
+	s.inject([][]byte{start})
+	s.observe([]byte(`data: {"choices":[{"delta":{"reasoning_opaque":"sig-2"}}]}`))
+	out := s.inject([][]byte{stop})
+	if len(out) != 2 {
+		t.Fatalf("expected signature delta before stop, got %d chunks", len(out))
+	}
+	if sig := gjson.GetBytes(githubCopilotSSEData(out[0]), "delta.signature").String(); sig != "sig-2" {
+		t.Fatalf("signature = %q, want sig-2", sig)
+	}
+}
+
+func compactJSON(raw string) string {
+	return gjson.Parse(raw).Get("@ugly").Raw
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencount"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/toolemulation"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
@@ -97,7 +99,8 @@ func (e *GitLabExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 	if err != nil {
 		return resp, err
 	}
-	prompt := buildGitLabPrompt(translated)
+	emulateTools := e.emulatesTools(baseModel, translated)
+	prompt := buildGitLabPrompt(gitLabPromptSource(translated, emulateTools))
 	if strings.TrimSpace(prompt.Instruction) == "" && strings.TrimSpace(prompt.ContentAboveCursor) == "" {
 		err = statusErr{code: http.StatusBadRequest, msg: "gitlab duo executor: request has no usable text content"}
 		return resp, err
@@ -110,6 +113,9 @@ func (e *GitLabExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 
 	responseModel := gitLabResolvedModel(auth, req.Model)
 	openAIResponse := buildGitLabOpenAIResponse(responseModel, text, translated)
+	if emulateTools {
+		openAIResponse = toolemulation.ApplyToResponse(openAIResponse)
+	}
 	reporter.publish(ctx, parseOpenAIUsage(openAIResponse))
 	reporter.ensurePublished(ctx)
 
@@ -140,12 +146,13 @@ func (e *GitLabExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 	if err != nil {
 		return nil, err
 	}
-	prompt := buildGitLabPrompt(translated)
+	emulateTools := e.emulatesTools(baseModel, translated)
+	prompt := buildGitLabPrompt(gitLabPromptSource(translated, emulateTools))
 	if strings.TrimSpace(prompt.Instruction) == "" && strings.TrimSpace(prompt.ContentAboveCursor) == "" {
 		return nil, statusErr{code: http.StatusBadRequest, msg: "gitlab duo executor: request has no usable text content"}
 	}
 
-	if result, streamErr := e.requestCodeSuggestionsStream(ctx, auth, prompt, translated, req, opts, reporter); streamErr == nil {
+	if result, streamErr := e.requestCodeSuggestionsStream(ctx, auth, prompt, translated, req, opts, reporter, emulateTools); streamErr == nil {
 		return result, nil
 	} else if !shouldFallbackToCodeSuggestions(streamErr) {
 		return nil, streamErr
@@ -164,8 +171,15 @@ func (e *GitLabExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 	go func() {
 		defer close(out)
 		var param any
-		lines := buildGitLabOpenAIStream(responseModel, text)
-		for _, line := range lines {
+		var converter *toolemulation.StreamConverter
+		if emulateTools {
+			converter = toolemulation.NewStreamConverter()
+		}
+		lines := make([][]byte, 0, 4)
+		for _, line := range buildGitLabOpenAIStream(responseModel, text) {
+			lines = append(lines, []byte(line))
+		}
+		for _, line := range gitLabEmulateStream(converter, lines) {
 			chunks := sdktranslator.TranslateStream(
 				ctx,
 				sdktranslator.FromString("openai"),
@@ -173,7 +187,7 @@ func (e *GitLabExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 				req.Model,
 				opts.OriginalRequest,
 				translated,
-				[]byte(line),
+				line,
 				&param,
 			)
 			for i := range chunks {
@@ -236,16 +250,11 @@ func (e *GitLabExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Aut
 		return nativeExec.CountTokens(ctx, nativeAuth, nativeReq, opts)
 	}
 	baseModel := thinking.ParseSuffix(req.Model).ModelName
-	translated := sdktranslator.TranslateRequest(opts.SourceFormat, sdktranslator.FromString("openai"), baseModel, req.Payload, false)
-	enc, err := tokenizerForModel(baseModel)
+	result, err := tokencount.Count(baseModel, opts.SourceFormat.String(), req.Payload)
 	if err != nil {
-		return cliproxyexecutor.Response{}, fmt.Errorf("gitlab duo executor: tokenizer init failed: %w", err)
+		return cliproxyexecutor.Response{}, fmt.Errorf("gitlab duo executor: token counting failed: %w", err)
 	}
-	count, err := countOpenAIChatTokens(enc, translated)
-	if err != nil {
-		return cliproxyexecutor.Response{}, err
-	}
-	return cliproxyexecutor.Response{Payload: buildOpenAIUsageJSON(count), Headers: make(http.Header)}, nil
+	return cliproxyexecutor.Response{Payload: buildOpenAIUsageJSON(result.Tokens), Headers: make(http.Header)}, nil
 }
 
 func (e *GitLabExecutor) HttpRequest(ctx context.Context, auth *cliproxyauth.Auth, req *http.Request) (*http.Response, error) {
@@ -347,6 +356,7 @@ func (e *GitLabExecutor) requestCodeSuggestionsStream(
 	req cliproxyexecutor.Request,
 	opts cliproxyexecutor.Options,
 	reporter *usageReporter,
+	emulateTools bool,
 ) (*cliproxyexecutor.StreamResult, error) {
 	contentAbove := strings.TrimSpace(prompt.ContentAboveCursor)
 	if contentAbove == "" {
@@ -395,7 +405,11 @@ func (e *GitLabExecutor) requestCodeSuggestionsStream(
 			param     any
 			eventName string
 			state     gitLabOpenAIStreamState
+			converter *toolemulation.StreamConverter
 		)
+		if emulateTools {
+			converter = toolemulation.NewStreamConverter()
+		}
 		for scanner.Scan() {
 			line := bytes.Clone(scanner.Bytes())
 			appendAPIResponseChunk(ctx, e.cfg, line)
@@ -413,7 +427,7 @@ func (e *GitLabExecutor) requestCodeSuggestionsStream(
 			payload := bytes.TrimSpace(trimmed[len("data:"):])
 			normalized := normalizeGitLabStreamChunk(eventName, payload, responseModel, &state)
 			eventName = ""
-			for _, item := range normalized {
+			for _, item := range gitLabEmulateStream(converter, normalized) {
 				if detail, ok := parseOpenAIStreamUsage(item); ok {
 					reporter.publish(ctx, detail)
 				}
@@ -438,21 +452,26 @@ func (e *GitLabExecutor) requestCodeSuggestionsStream(
 			out <- cliproxyexecutor.StreamChunk{Err: errScan}
 			return
 		}
+		var tail [][]byte
 		if !state.Finished {
-			for _, item := range finalizeGitLabStream(responseModel, &state) {
-				chunks := sdktranslator.TranslateStream(
-					ctx,
-					sdktranslator.FromString("openai"),
-					opts.SourceFormat,
-					req.Model,
-					opts.OriginalRequest,
-					translated,
-					item,
-					&param,
-				)
-				for i := range chunks {
-					out <- cliproxyexecutor.StreamChunk{Payload: []byte(chunks[i])}
-				}
+			tail = gitLabEmulateStream(converter, finalizeGitLabStream(responseModel, &state))
+		}
+		if converter != nil {
+			tail = append(tail, converter.Flush()...)
+		}
+		for _, item := range tail {
+			chunks := sdktranslator.TranslateStream(
+				ctx,
+				sdktranslator.FromString("openai"),
+				opts.SourceFormat,
+				req.Model,
+				opts.OriginalRequest,
+				translated,
+				item,
+				&param,
+			)
+			for i := range chunks {
+				out <- cliproxyexecutor.StreamChunk{Payload: []byte(chunks[i])}
 			}
 		}
 		reporter.ensurePublished(ctx)
@@ -571,6 +590,33 @@ func (e *GitLabExecutor) refreshOAuthToken(ctx context.Context, client *gitlab.A
 	)
 }
 
+// emulatesTools reports whether tool calling is emulated through the prompt for
+// this model and request.
+func (e *GitLabExecutor) emulatesTools(model string, translated []byte) bool {
+	if e.cfg == nil || !toolemulation.Enabled(e.cfg.ToolEmulation, gitLabProviderKey, model) {
+		return false
+	}
+	return toolemulation.HasTools(translated)
+}
+
+func gitLabPromptSource(translated []byte, emulateTools bool) []byte {
+	if !emulateTools {
+		return translated
+	}
+	return toolemulation.RewriteRequest(translated)
+}
+
+func gitLabEmulateStream(converter *toolemulation.StreamConverter, items [][]byte) [][]byte {
+	if converter == nil {
+		return items
+	}
+	out := make([][]byte, 0, len(items))
+	for _, item := range items {
+		out = append(out, converter.Process(item)...)
+	}
+	return out
+}
+
 func buildGitLabPrompt(payload []byte) gitLabPrompt {
 	root := gjson.ParseBytes(payload)
 	prompt := gitLabPrompt{
@@ -0,0 +1,321 @@
+package helps
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// PayloadRequest describes the inbound request that payload rule conditions match.
+type PayloadRequest struct {
+	// APIKey is the client API key that authenticated the request.
+	APIKey string
+	// Tenant is the caller's tenant name, empty when tenants are not configured.
+	Tenant string
+	// Headers are the inbound request headers.
+	Headers http.Header
+	// Stream reports whether the client asked for a streaming response.
+	Stream bool
+}
+
+// NewPayloadRequest collects the client key, tenant, headers and streaming mode of
+// the request being executed.
+func NewPayloadRequest(ctx context.Context, opts cliproxyexecutor.Options) PayloadRequest {
+	req := PayloadRequest{
+		APIKey:  APIKeyFromContext(ctx),
+		Headers: opts.Headers,
+		Stream:  opts.Stream,
+	}
+	if ctx != nil {
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
+			req.Headers = ginCtx.Request.Header
+		}
+	}
+	switch scope := opts.Metadata[cliproxyexecutor.TenantMetadataKey].(type) {
+	case cliproxyauth.TenantScope:
+		req.Tenant = scope.Name
+	case *cliproxyauth.TenantScope:
+		if scope != nil {
+			req.Tenant = scope.Name
+		}
+	}
+	return req
+}
+
+// payloadMatcher decides whether a rule applies to the current request.
+type payloadMatcher struct {
+	protocol   string
+	root       string
+	candidates []string
+	req        PayloadRequest
+	source     []byte
+}
+
+// matches reports whether the model list and conditions select the request. Rules
+// without models only apply when they carry conditions.
+func (m *payloadMatcher) matches(models []config.PayloadModelRule, when *config.PayloadCondition) bool {
+	if len(models) == 0 {
+		if when == nil {
+			return false
+		}
+	} else if !payloadModelRulesMatch(models, m.protocol, m.candidates) {
+		return false
+	}
+	return when == nil || m.conditionMatches(when)
+}
+
+func (m *payloadMatcher) conditionMatches(when *config.PayloadCondition) bool {
+	if len(when.APIKeys) > 0 && !matchAnyPattern(when.APIKeys, m.req.APIKey) {
+		return false
+	}
+	if len(when.Tenants) > 0 {
+		found := false
+		for _, tenant := range when.Tenants {
+			if m.req.Tenant != "" && strings.EqualFold(strings.TrimSpace(tenant), m.req.Tenant) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for name, pattern := range when.Headers {
+		values := m.req.Headers.Values(name)
+		if len(values) == 0 {
+			return false
+		}
+		if pattern = strings.TrimSpace(pattern); pattern != "" && !matchAnyValue(pattern, values) {
+			return false
+		}
+	}
+	if when.Stream != nil && *when.Stream != m.req.Stream {
+		return false
+	}
+	for _, predicate := range when.Body {
+		path := buildPayloadPath(m.root, predicate.Path)
+		value := gjson.ParseBytes(m.source)
+		if path != "" {
+			value = gjson.GetBytes(m.source, path)
+		}
+		if !PayloadPredicateMatches(predicate, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAnyPattern(patterns []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if util.MatchWildcard(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyValue(pattern string, values []string) bool {
+	for _, value := range values {
+		if util.MatchWildcard(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// PayloadPredicateMatches evaluates predicate against value. A predicate without
+// comparisons only requires the value to exist.
+func PayloadPredicateMatches(predicate config.PayloadPredicate, value gjson.Result) bool {
+	if predicate.Exists != nil {
+		if value.Exists() != *predicate.Exists {
+			return false
+		}
+	}
+	hasComparison := predicate.Equals != nil || predicate.Contains != nil ||
+		predicate.GT != nil || predicate.GTE != nil || predicate.LT != nil || predicate.LTE != nil
+	if !hasComparison {
+		return predicate.Exists != nil || value.Exists()
+	}
+	if !value.Exists() {
+		return false
+	}
+	if predicate.Equals != nil && !payloadScalarEquals(value, predicate.Equals) {
+		return false
+	}
+	if predicate.Contains != nil {
+		switch {
+		case value.IsArray():
+			found := false
+			value.ForEach(func(_, item gjson.Result) bool {
+				found = payloadScalarEquals(item, predicate.Contains)
+				return !found
+			})
+			if !found {
+				return false
+			}
+		case value.Type == gjson.String:
+			if !strings.Contains(value.Str, fmt.Sprint(predicate.Contains)) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	if predicate.GT != nil || predicate.GTE != nil || predicate.LT != nil || predicate.LTE != nil {
+		if value.Type != gjson.Number {
+			return false
+		}
+		n := value.Num
+		if (predicate.GT != nil && !(n > *predicate.GT)) || (predicate.GTE != nil && !(n >= *predicate.GTE)) ||
+			(predicate.LT != nil && !(n < *predicate.LT)) || (predicate.LTE != nil && !(n <= *predicate.LTE)) {
+			return false
+		}
+	}
+	return true
+}
+
+// payloadScalarEquals compares a JSON value with a scalar decoded from YAML.
+func payloadScalarEquals(value gjson.Result, want any) bool {
+	switch w := want.(type) {
+	case string:
+		return value.Type == gjson.String && value.Str == w
+	case bool:
+		return (value.Type == gjson.True && w) || (value.Type == gjson.False && !w)
+	case int:
+		return value.Type == gjson.Number && value.Num == float64(w)
+	case int64:
+		return value.Type == gjson.Number && value.Num == float64(w)
+	case uint64:
+		return value.Type == gjson.Number && value.Num == float64(w)
+	case float64:
+		return value.Type == gjson.Number && value.Num == w
+	case nil:
+		return value.Type == gjson.Null
+	default:
+		return value.String() == fmt.Sprint(w)
+	}
+}
+
+// renamePayloadPath moves the value at from to to, replacing any existing value.
+func renamePayloadPath(payload []byte, from, to string) []byte {
+	if from == "" || to == "" || from == to {
+		return payload
+	}
+	value := gjson.GetBytes(payload, from)
+	if !value.Exists() {
+		return payload
+	}
+	updated, err := sjson.SetRawBytes(payload, to, []byte(value.Raw))
+	if err != nil {
+		return payload
+	}
+	updated, err = sjson.DeleteBytes(updated, from)
+	if err != nil {
+		return payload
+	}
+	return updated
+}
+
+// appendPayloadValue appends value to the array at path, creating the array when the
+// path is missing. Non-array values are left untouched.
+func appendPayloadValue(payload []byte, path string, value any) []byte {
+	if path == "" {
+		return payload
+	}
+	if existing := gjson.GetBytes(payload, path); existing.Exists() && !existing.IsArray() {
+		return payload
+	}
+	updated, err := sjson.SetBytes(payload, path+".-1", value)
+	if err != nil {
+		return payload
+	}
+	return updated
+}
+
+// deletePayloadItems removes the elements of the array at path for which predicate
+// holds; the predicate path is relative to each element.
+func deletePayloadItems(payload []byte, path string, predicate config.PayloadPredicate) []byte {
+	if path == "" {
+		return payload
+	}
+	array := gjson.GetBytes(payload, path)
+	if !array.IsArray() {
+		return payload
+	}
+	kept := make([]string, 0)
+	removed := false
+	array.ForEach(func(_, item gjson.Result) bool {
+		value := item
+		if p := strings.TrimSpace(predicate.Path); p != "" {
+			value = item.Get(p)
+		}
+		if PayloadPredicateMatches(predicate, value) {
+			removed = true
+		} else {
+			kept = append(kept, item.Raw)
+		}
+		return true
+	})
+	if !removed {
+		return payload
+	}
+	updated, err := sjson.SetRawBytes(payload, path, []byte("["+strings.Join(kept, ",")+"]"))
+	if err != nil {
+		return payload
+	}
+	return updated
+}
+
+// clampPayloadValue bounds the number at path, keeping integers integral.
+func clampPayloadValue(payload []byte, path string, bounds config.PayloadRange) []byte {
+	if path == "" {
+		return payload
+	}
+	value := gjson.GetBytes(payload, path)
+	if value.Type != gjson.Number {
+		return payload
+	}
+	n := value.Num
+	if bounds.Min != nil && n < *bounds.Min {
+		n = *bounds.Min
+	}
+	if bounds.Max != nil && n > *bounds.Max {
+		n = *bounds.Max
+	}
+	if n == value.Num {
+		return payload
+	}
+	raw := strconv.FormatFloat(n, 'f', -1, 64)
+	if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+		raw = strconv.FormatInt(int64(n), 10)
+	}
+	updated, err := sjson.SetRawBytes(payload, path, []byte(raw))
+	if err != nil {
+		return payload
+	}
+	return updated
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,156 @@
+package helps
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func boolPtr(v bool) *bool { return &v }
+
+func TestApplyPayloadConfigConditions(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Payload.Override = []config.PayloadRule{
+		{
+			When:   &config.PayloadCondition{APIKeys: []string{"team-a-*"}},
+			Params: map[string]any{"by_key": true},
+		},
+		{
+			When:   &config.PayloadCondition{Headers: map[string]string{"X-Client": "cursor*"}},
+			Params: map[string]any{"by_header": true},
+		},
+		{
+			Models: []config.PayloadModelRule{{Name: "gpt-*"}},
+			When:   &config.PayloadCondition{Stream: boolPtr(true)},
+			Params: map[string]any{"by_stream": true},
+		},
+		{
+			When: &config.PayloadCondition{Body: []config.PayloadPredicate{
+				{Path: "messages.#", GT: floatPtr(1)},
+				{Path: "messages.#.role", Contains: "system"},
+			}},
+			Params: map[string]any{"by_body": true},
+		},
+		{
+			When:   &config.PayloadCondition{Tenants: []string{"acme"}},
+			Params: map[string]any{"by_tenant": true},
+		},
+	}
+	payload := []byte(`{"model":"gpt-5","messages":[{"role":"system"},{"role":"user"}]}`)
+	headers := http.Header{}
+	headers.Set("X-Client", "cursor/1.2")
+	req := PayloadRequest{APIKey: "team-a-1", Headers: headers, Stream: true}
+
+	out, applied := PreviewPayloadConfig(cfg, req, "gpt-5", "openai", "", payload, "")
+	for _, key := range []string{"by_key", "by_header", "by_stream", "by_body"} {
+		if !gjson.GetBytes(out, key).Bool() {
+			t.Fatalf("expected %s to be set, got %s", key, out)
+		}
+	}
+	if gjson.GetBytes(out, "by_tenant").Exists() {
+		t.Fatalf("tenant rule applied without a tenant: %s", out)
+	}
+	if len(applied) != 4 || applied[0] != "override[0]" {
+		t.Fatalf("applied = %v", applied)
+	}
+
+	out = ApplyPayloadConfigWithRoot(cfg, PayloadRequest{APIKey: "team-b"}, "gpt-5", "openai", "", []byte(`{"messages":[]}`), nil, "")
+	if string(out) != `{"messages":[]}` {
+		t.Fatalf("expected no rules to match, got %s", out)
+	}
+}
+
+func TestApplyPayloadConfigRuleWithoutModelsOrConditions(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Payload.Override = []config.PayloadRule{{Params: map[string]any{"x": 1}}}
+	out := ApplyPayloadConfigWithRoot(cfg, PayloadRequest{}, "gpt-5", "openai", "", []byte(`{}`), nil, "")
+	if string(out) != `{}` {
+		t.Fatalf("rule without models applied: %s", out)
+	}
+}
+
+func TestApplyPayloadConfigActions(t *testing.T) {
+	all := []config.PayloadModelRule{{Name: "*"}}
+	cfg := &config.Config{}
+	cfg.Payload.Rename = []config.PayloadRenameRule{{
+		Models: all,
+		Params: map[string]string{"max_tokens": "max_completion_tokens"},
+	}}
+	cfg.Payload.Append = []config.PayloadRule{{
+		Models: all,
+		Params: map[string]any{"stop": "END", "tags": "new"},
+	}}
+	cfg.Payload.DeleteItems = []config.PayloadDeleteItemsRule{{
+		Models: all,
+		Params: map[string]config.PayloadPredicate{"tools": {Path: "type", Equals: "web_search"}},
+	}}
+	cfg.Payload.Clamp = []config.PayloadClampRule{{
+		Models: all,
+		Params: map[string]config.PayloadRange{
+			"temperature": {Min: floatPtr(0), Max: floatPtr(1)},
+			"top_k":       {Max: floatPtr(40)},
+			"missing":     {Max: floatPtr(1)},
+		},
+	}}
+	payload := []byte(`{"max_tokens":100,"stop":["a"],"tools":[{"type":"function"},{"type":"web_search"}],"temperature":1.7,"top_k":64}`)
+
+	out, applied := PreviewPayloadConfig(cfg, PayloadRequest{}, "gpt-5", "openai", "", payload, "")
+	if gjson.GetBytes(out, "max_tokens").Exists() || gjson.GetBytes(out, "max_completion_tokens").Int() != 100 {
+		t.Fatalf("rename failed: %s", out)
+	}
+	if got := gjson.GetBytes(out, "stop").Raw; got != `["a","END"]` {
+		t.Fatalf("stop = %s", got)
+	}
+	if got := gjson.GetBytes(out, "tags").Raw; got != `["new"]` {
+		t.Fatalf("tags = %s", got)
+	}
+	if got := gjson.GetBytes(out, "tools").Raw; got != `[{"type":"function"}]` {
+		t.Fatalf("tools = %s", got)
+	}
+	if got := gjson.GetBytes(out, "temperature").Raw; got != "1" {
+		t.Fatalf("temperature = %s", got)
+	}
+	if got := gjson.GetBytes(out, "top_k").Raw; got != "40" {
+		t.Fatalf("top_k = %s", got)
+	}
+	if gjson.GetBytes(out, "missing").Exists() {
+		t.Fatalf("clamp created a missing value: %s", out)
+	}
+	want := []string{"rename[0]", "append[0]", "delete-items[0]", "clamp[0]"}
+	if len(applied) != len(want) {
+		t.Fatalf("applied = %v, want %v", applied, want)
+	}
+	for i := range want {
+		if applied[i] != want[i] {
+			t.Fatalf("applied = %v, want %v", applied, want)
+		}
+	}
+}
+
+func TestPayloadPredicateMatches(t *testing.T) {
+	value := gjson.Parse(`{"n":3,"s":"hello world","flag":true}`)
+	cases := []struct {
+		name      string
+		predicate config.PayloadPredicate
+		want      bool
+	}{
+		{"exists", config.PayloadPredicate{Path: "n"}, true},
+		{"missing", config.PayloadPredicate{Path: "x"}, false},
+		{"absent", config.PayloadPredicate{Path: "x", Exists: boolPtr(false)}, true},
+		{"equals bool", config.PayloadPredicate{Path: "flag", Equals: true}, true},
+		{"equals int", config.PayloadPredicate{Path: "n", Equals: 3}, true},
+		{"substring", config.PayloadPredicate{Path: "s", Contains: "world"}, true},
+		{"range", config.PayloadPredicate{Path: "n", GTE: floatPtr(3), LT: floatPtr(4)}, true},
+		{"out of range", config.PayloadPredicate{Path: "n", GT: floatPtr(3)}, false},
+		{"non numeric", config.PayloadPredicate{Path: "s", LT: floatPtr(10)}, false},
+	}
+	for _, tc := range cases {
+		if got := PayloadPredicateMatches(tc.predicate, value.Get(tc.predicate.Path)); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package helps
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -16,12 +19,31 @@ import (
 // and restricts matches to the given protocol when supplied. Defaults are checked
 // against the original payload when provided. requestedModel carries the client-visible
 // model name before alias resolution so payload rules can target aliases precisely.
-func ApplyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string, payload, original []byte, requestedModel string) []byte {
+// req describes the inbound request for rules with "when" conditions.
+func ApplyPayloadConfigWithRoot(cfg *config.Config, req PayloadRequest, model, protocol, root string, payload, original []byte, requestedModel string) []byte {
+	return applyPayloadRules(cfg, req, model, protocol, root, payload, original, requestedModel, nil)
+}
+
+// PreviewPayloadConfig applies the payload rules like ApplyPayloadConfigWithRoot and
+// also returns the labels (for example "override[0]") of the rules that changed the payload.
+func PreviewPayloadConfig(cfg *config.Config, req PayloadRequest, model, protocol, root string, payload []byte, requestedModel string) ([]byte, []string) {
+	applied := make([]string, 0)
+	out := applyPayloadRules(cfg, req, model, protocol, root, payload, payload, requestedModel, func(label string) {
+		applied = append(applied, label)
+	})
+	return out, applied
+}
+
+// applyPayloadRules runs the rule sections in order: default, default-raw, override,
+// override-raw, rename, append, delete-items, clamp and finally filter. trace, when
+// set, receives the label of every rule that changed the payload.
+func applyPayloadRules(cfg *config.Config, req PayloadRequest, model, protocol, root string, payload, original []byte, requestedModel string, trace func(string)) []byte {
 	if cfg == nil || len(payload) == 0 {
 		return payload
 	}
 	rules := cfg.Payload
-	if len(rules.Default) == 0 && len(rules.DefaultRaw) == 0 && len(rules.Override) == 0 && len(rules.OverrideRaw) == 0 && len(rules.Filter) == 0 {
+	if len(rules.Default) == 0 && len(rules.DefaultRaw) == 0 && len(rules.Override) == 0 && len(rules.OverrideRaw) == 0 && len(rules.Filter) == 0 &&
+		len(rules.Rename) == 0 && len(rules.Append) == 0 && len(rules.DeleteItems) == 0 && len(rules.Clamp) == 0 {
 		return payload
 	}
 	model = strings.TrimSpace(model)
@@ -29,19 +51,31 @@ func ApplyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string
 	if model == "" && requestedModel == "" {
 		return payload
 	}
-	candidates := payloadModelCandidates(model, requestedModel)
 	out := payload
 	source := original
 	if len(source) == 0 {
 		source = payload
 	}
+	matcher := &payloadMatcher{
+		protocol:   protocol,
+		root:       root,
+		candidates: payloadModelCandidates(model, requestedModel),
+		req:        req,
+		source:     source,
+	}
+	track := func(section string, index int, before []byte) {
+		if trace != nil && !bytes.Equal(before, out) {
+			trace(fmt.Sprintf("%s[%d]", section, index))
+		}
+	}
 	appliedDefaults := make(map[string]struct{})
 	// Apply default rules: first write wins per field across all matching rules.
 	for i := range rules.Default {
 		rule := &rules.Default[i]
-		if !payloadModelRulesMatch(rule.Models, protocol, candidates) {
+		if !matcher.matches(rule.Models, rule.When) {
 			continue
 		}
+		before := out
 		for path, value := range rule.Params {
 			fullPath := buildPayloadPath(root, path)
 			if fullPath == "" {
@@ -60,13 +94,15 @@ func ApplyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string
 			out = updated
 			appliedDefaults[fullPath] = struct{}{}
 		}
+		track("default", i, before)
 	}
 	// Apply default raw rules: first write wins per field across all matching rules.
 	for i := range rules.DefaultRaw {
 		rule := &rules.DefaultRaw[i]
-		if !payloadModelRulesMatch(rule.Models, protocol, candidates) {
+		if !matcher.matches(rule.Models, rule.When) {
 			continue
 		}
+		before := out
 		for path, value := range rule.Params {
 			fullPath := buildPayloadPath(root, path)
 			if fullPath == "" {
@@ -89,13 +125,15 @@ func ApplyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string
 			out = updated
 			appliedDefaults[fullPath] = struct{}{}
 		}
+		track("default-raw", i, before)
 	}
 	// Apply override rules: last write wins per field across all matching rules.
 	for i := range rules.Override {
 		rule := &rules.Override[i]
-		if !payloadModelRulesMatch(rule.Models, protocol, candidates) {
+		if !matcher.matches(rule.Models, rule.When) {
 			continue
 		}
+		before := out
 		for path, value := range rule.Params {
 			fullPath := buildPayloadPath(root, path)
 			if fullPath == "" {
@@ -107,13 +145,15 @@ func ApplyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string
 			}
 			out = updated
 		}
+		track("override", i, before)
 	}
 	// Apply override raw rules: last write wins per field across all matching rules.
 	for i := range rules.OverrideRaw {
 		rule := &rules.OverrideRaw[i]
-		if !payloadModelRulesMatch(rule.Models, protocol, candidates) {
+		if !matcher.matches(rule.Models, rule.When) {
 			continue
 		}
+		before := out
 		for path, value := range rule.Params {
 			fullPath := buildPayloadPath(root, path)
 			if fullPath == "" {
@@ -129,13 +169,63 @@ func ApplyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string
 			}
 			out = updated
 		}
+		track("override-raw", i, before)
+	}
+	// Apply rename rules: move values to their new path.
+	for i := range rules.Rename {
+		rule := &rules.Rename[i]
+		if !matcher.matches(rule.Models, rule.When) {
+			continue
+		}
+		before := out
+		for _, from := range sortedKeys(rule.Params) {
+			out = renamePayloadPath(out, buildPayloadPath(root, from), buildPayloadPath(root, rule.Params[from]))
+		}
+		track("rename", i, before)
+	}
+	// Apply append rules: add values to the end of arrays.
+	for i := range rules.Append {
+		rule := &rules.Append[i]
+		if !matcher.matches(rule.Models, rule.When) {
+			continue
+		}
+		before := out
+		for _, path := range sortedKeys(rule.Params) {
+			out = appendPayloadValue(out, buildPayloadPath(root, path), rule.Params[path])
+		}
+		track("append", i, before)
+	}
+	// Apply delete-items rules: drop array elements matching the predicate.
+	for i := range rules.DeleteItems {
+		rule := &rules.DeleteItems[i]
+		if !matcher.matches(rule.Models, rule.When) {
+			continue
+		}
+		before := out
+		for _, path := range sortedKeys(rule.Params) {
+			out = deletePayloadItems(out, buildPayloadPath(root, path), rule.Params[path])
+		}
+		track("delete-items", i, before)
+	}
+	// Apply clamp rules: keep numbers within the configured bounds.
+	for i := range rules.Clamp {
+		rule := &rules.Clamp[i]
+		if !matcher.matches(rule.Models, rule.When) {
+			continue
+		}
+		before := out
+		for _, path := range sortedKeys(rule.Params) {
+			out = clampPayloadValue(out, buildPayloadPath(root, path), rule.Params[path])
+		}
+		track("clamp", i, before)
 	}
 	// Apply filter rules: remove matching paths from payload.
 	for i := range rules.Filter {
 		rule := &rules.Filter[i]
-		if !payloadModelRulesMatch(rule.Models, protocol, candidates) {
+		if !matcher.matches(rule.Models, rule.When) {
 			continue
 		}
+		before := out
 		for _, path := range rule.Params {
 			fullPath := buildPayloadPath(root, path)
 			if fullPath == "" {
@@ -147,6 +237,7 @@ func ApplyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string
 			}
 			out = updated
 		}
+		track("filter", i, before)
 	}
 	return out
 }
@@ -164,7 +255,7 @@ func payloadModelRulesMatch(rules []config.PayloadModelRule, protocol string, mo
 			if ep := strings.TrimSpace(entry.Protocol); ep != "" && protocol != "" && !strings.EqualFold(ep, protocol) {
 				continue
 			}
-			if matchModelPattern(name, model) {
+			if util.MatchWildcard(name, model) {
 				return true
 			}
 		}
@@ -272,48 +363,3 @@ func PayloadRequestedModel(opts cliproxyexecutor.Options, fallback string) strin
 		return fallback
 	}
 }
-
-// matchModelPattern performs simple wildcard matching where '*' matches zero or more characters.
-// Examples:
-//
-//	"*-5" matches "gpt-5"
-//	"gpt-*" matches "gpt-5" and "gpt-4"
-//	"gemini-*-pro" matches "gemini-2.5-pro" and "gemini-3-pro".
-func matchModelPattern(pattern, model string) bool {
-	pattern = strings.TrimSpace(pattern)
-	model = strings.TrimSpace(model)
-	if pattern == "" {
-		return false
-	}
-	if pattern == "*" {
-		return true
-	}
-	// Iterative glob-style matcher supporting only '*' wildcard.
-	pi, si := 0, 0
-	starIdx := -1
-	matchIdx := 0
-	for si < len(model) {
-		if pi < len(pattern) && (pattern[pi] == model[si]) {
-			pi++
-			si++
-			continue
-		}
-		if pi < len(pattern) && pattern[pi] == '*' {
-			starIdx = pi
-			matchIdx = si
-			pi++
-			continue
-		}
-		if starIdx != -1 {
-			pi = starIdx + 1
-			matchIdx++
-			si = matchIdx
-			continue
-		}
-		return false
-	}
-	for pi < len(pattern) && pattern[pi] == '*' {
-		pi++
-	}
-	return pi == len(pattern)
-}
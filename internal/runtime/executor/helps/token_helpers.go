@@ -1,10 +1,13 @@
 package helps
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencount"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
 	"github.com/tidwall/gjson"
 	"github.com/tiktoken-go/tokenizer"
 )
@@ -402,3 +405,14 @@ func addIfNotEmpty(segments *[]string, value string) {
 		*segments = append(*segments, trimmed)
 	}
 }
+
+// LocalTokenCountResponse answers a count_tokens request offline with the tokencount
+// estimator, rendering the result in the client's source format.
+func LocalTokenCountResponse(ctx context.Context, model string, from sdktranslator.Format, payload []byte) ([]byte, tokencount.Result, error) {
+	result, err := tokencount.Count(model, from.String(), payload)
+	if err != nil {
+		return nil, result, err
+	}
+	usageJSON := BuildOpenAIUsageJSON(result.Tokens)
+	return sdktranslator.TranslateTokenCount(ctx, sdktranslator.FromString("openai"), from, result.Tokens, usageJSON), result, nil
+}
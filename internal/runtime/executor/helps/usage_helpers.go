@@ -3,12 +3,11 @@ package helps
 import (
 	"bytes"
 	"context"
-	"fmt"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	"github.com/tidwall/gjson"
@@ -119,25 +118,9 @@ func (r *UsageReporter) latency() time.Duration {
 	return latency
 }
 
+// APIKeyFromContext returns the authenticated client key of the request.
 func APIKeyFromContext(ctx context.Context) string {
-	if ctx == nil {
-		return ""
-	}
-	ginCtx, ok := ctx.Value("gin").(*gin.Context)
-	if !ok || ginCtx == nil {
-		return ""
-	}
-	if v, exists := ginCtx.Get("apiKey"); exists {
-		switch value := v.(type) {
-		case string:
-			return value
-		case fmt.Stringer:
-			return value.String()
-		default:
-			return fmt.Sprintf("%v", value)
-		}
-	}
-	return ""
+	return util.APIKeyFromContext(ctx)
 }
 
 func resolveUsageSource(auth *cliproxyauth.Auth, ctxAPIKey string) string {
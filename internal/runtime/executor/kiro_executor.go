@@ -3574,45 +3574,18 @@ func (e *KiroExecutor) streamToChannel(ctx context.Context, body io.Reader, out
 // CountTokens counts tokens locally using tiktoken since Kiro API doesn't expose a token counting endpoint.
 // This provides approximate token counts for client requests.
 func (e *KiroExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
-	// Use tiktoken for local token counting
-	enc, err := getTokenizer(req.Model)
+	// Kiro has no count endpoint; answer locally with the Claude-family estimator.
+	translated, err := localTokenCountResponse(ctx, req.Model, opts.SourceFormat, req.Payload)
 	if err != nil {
-		log.Warnf("kiro: CountTokens failed to get tokenizer: %v, falling back to estimate", err)
 		// Fallback: estimate from payload size (roughly 4 chars per token)
-		estimatedTokens := len(req.Payload) / 4
+		log.Warnf("kiro: CountTokens failed to count locally: %v, falling back to estimate", err)
+		estimatedTokens := int64(len(req.Payload) / 4)
 		if estimatedTokens == 0 && len(req.Payload) > 0 {
 			estimatedTokens = 1
 		}
-		return cliproxyexecutor.Response{
-			Payload: []byte(fmt.Sprintf(`{"count":%d}`, estimatedTokens)),
-		}, nil
-	}
-
-	// Try to count tokens from the request payload
-	var totalTokens int64
-
-	// Try OpenAI chat format first
-	if tokens, countErr := countOpenAIChatTokens(enc, req.Payload); countErr == nil && tokens > 0 {
-		totalTokens = tokens
-		log.Debugf("kiro: CountTokens counted %d tokens using OpenAI chat format", totalTokens)
-	} else {
-		// Fallback: count raw payload tokens
-		if tokenCount, countErr := enc.Count(string(req.Payload)); countErr == nil {
-			totalTokens = int64(tokenCount)
-			log.Debugf("kiro: CountTokens counted %d tokens from raw payload", totalTokens)
-		} else {
-			// Final fallback: estimate from payload size
-			totalTokens = int64(len(req.Payload) / 4)
-			if totalTokens == 0 && len(req.Payload) > 0 {
-				totalTokens = 1
-			}
-			log.Debugf("kiro: CountTokens estimated %d tokens from payload size", totalTokens)
-		}
+		translated = sdktranslator.TranslateTokenCount(ctx, sdktranslator.FromString("openai"), opts.SourceFormat, estimatedTokens, buildOpenAIUsageJSON(estimatedTokens))
 	}
-
-	return cliproxyexecutor.Response{
-		Payload: []byte(fmt.Sprintf(`{"count":%d}`, totalTokens)),
-	}, nil
+	return cliproxyexecutor.Response{Payload: translated}, nil
 }
 
 // Refresh refreshes the Kiro OAuth token.
@@ -0,0 +1,445 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	copilotauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/copilot"
+	kiroauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+// githubCopilotUserURL serves the account's plan and quota snapshots.
+var githubCopilotUserURL = copilotauth.UserInfoURL
+
+// ProbeQuota reads the premium request allowance from GitHub's Copilot user API.
+func (e *GitHubCopilotExecutor) ProbeQuota(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.QuotaReport, error) {
+	if auth == nil {
+		return nil, fmt.Errorf("github-copilot quota: missing auth")
+	}
+	accessToken := metaStringValue(auth.Metadata, "access_token")
+	if accessToken == "" {
+		return nil, fmt.Errorf("github-copilot quota: missing github access token")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubCopilotUserURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", copilotUserAgent)
+
+	body, err := fetchQuota(newProxyAwareHTTPClient(ctx, e.cfg, auth, 0), req, "github-copilot")
+	if err != nil {
+		return nil, err
+	}
+	return parseGitHubCopilotQuota(body)
+}
+
+// parseGitHubCopilotQuota maps the premium_interactions snapshot, which is the
+// allowance that runs out on paid plans where chat and completions are unlimited.
+// Free plans have no premium allowance and are limited by their chat quota.
+func parseGitHubCopilotQuota(body []byte) (*cliproxyauth.QuotaReport, error) {
+	usage, err := copilotauth.ParseUsage(body)
+	if err != nil {
+		return nil, fmt.Errorf("github-copilot quota: parse response: %w", err)
+	}
+	report := &cliproxyauth.QuotaReport{Unit: "premium-requests", PlanTier: usage.CopilotPlan}
+	if reset, ok := usage.ResetTime(); ok {
+		report.ResetAt = reset
+	}
+	snapshot := usage.QuotaSnapshots.PremiumInteractions
+	if snapshot.Entitlement <= 0 && !snapshot.Unlimited && usage.QuotaSnapshots.Chat.Entitlement > 0 {
+		snapshot = usage.QuotaSnapshots.Chat
+		report.Unit = "chat-requests"
+	}
+	if snapshot.Unlimited || snapshot.Entitlement <= 0 {
+		return report, nil
+	}
+	report.Limit = snapshot.Entitlement
+	report.Remaining = snapshot.Remaining
+	report.Exhausted = report.Remaining <= 0 && !snapshot.OveragePermitted
+	return report, nil
+}
+
+// ProbeHealth verifies the GitHub token with the Copilot user API, which reports
+// revoked tokens and accounts without Copilot access.
+func (e *GitHubCopilotExecutor) ProbeHealth(ctx context.Context, auth *cliproxyauth.Auth) error {
+	_, err := e.ProbeQuota(ctx, auth)
+	return err
+}
+
+// ProbeQuota reads the agentic request allowance from Kiro's usage limits API.
+func (e *KiroExecutor) ProbeQuota(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.QuotaReport, error) {
+	accessToken, profileArn := kiroCredentials(auth)
+	if accessToken == "" {
+		return nil, fmt.Errorf("kiro quota: missing access token")
+	}
+	checker := kiroauth.NewUsageCheckerWithClient(newProxyAwareHTTPClient(ctx, e.cfg, auth, 0))
+	usage, err := checker.CheckUsageByAccessToken(ctx, accessToken, profileArn)
+	if err != nil {
+		return nil, err
+	}
+	report := &cliproxyauth.QuotaReport{
+		Unit:      "requests",
+		Remaining: kiroauth.GetRemainingQuota(usage),
+		Exhausted: kiroauth.IsQuotaExhausted(usage),
+	}
+	for _, breakdown := range usage.UsageBreakdownList {
+		report.Limit += breakdown.UsageLimitWithPrecision
+		if breakdown.FreeTrialInfo != nil {
+			report.Limit += breakdown.FreeTrialInfo.UsageLimitWithPrecision
+		}
+		if report.Unit == "requests" && breakdown.ResourceType != "" {
+			report.Unit = strings.ToLower(breakdown.ResourceType)
+		}
+	}
+	if usage.SubscriptionInfo != nil {
+		report.PlanTier = usage.SubscriptionInfo.SubscriptionTitle
+		if report.PlanTier == "" {
+			report.PlanTier = usage.SubscriptionInfo.Type
+		}
+	}
+	if usage.NextDateReset > 0 {
+		report.ResetAt = time.UnixMilli(int64(usage.NextDateReset))
+	}
+	return report, nil
+}
+
+// ProbeHealth verifies the access token with Kiro's usage limits API, which
+// reports expired sessions and suspended accounts.
+func (e *KiroExecutor) ProbeHealth(ctx context.Context, auth *cliproxyauth.Auth) error {
+	_, err := e.ProbeQuota(ctx, auth)
+	return err
+}
+
+// Quota endpoints of the OAuth providers. They are variables so tests can point
+// them at a local server.
+var (
+	claudeOAuthUsageURL = "https://api.anthropic.com/api/oauth/usage"
+	codexUsageURL       = "https://chatgpt.com/backend-api/wham/usage"
+	geminiCLIQuotaURL   = codeAssistEndpoint + "/" + codeAssistVersion + ":retrieveUserQuota"
+)
+
+const antigravityModelsPath = "/v1internal:fetchAvailableModels"
+
+// quotaAccessToken returns the stored access token without refreshing it, so a
+// probe never rotates single-use refresh tokens. Expired tokens are left to the
+// refresh loop and reported as a probe error.
+func quotaAccessToken(provider string, metadata map[string]any) (string, error) {
+	token := metaStringValue(metadata, "access_token")
+	if token == "" {
+		return "", fmt.Errorf("%s quota: missing access token", provider)
+	}
+	expiry := tokenExpiry(metadata)
+	if expiry.IsZero() {
+		if raw := metaStringValue(metadata, "expiry"); raw != "" {
+			expiry, _ = time.Parse(time.RFC3339, raw)
+		}
+	}
+	if !expiry.IsZero() && !expiry.After(time.Now()) {
+		return "", fmt.Errorf("%s quota: access token expired", provider)
+	}
+	return token, nil
+}
+
+// fetchQuota sends a quota request and returns the response body of a successful reply.
+func fetchQuota(client *http.Client, req *http.Request, provider string) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("%s quota: close response body error: %v", provider, errClose)
+		}
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if !isHTTPSuccess(resp.StatusCode) {
+		return nil, statusErr{code: resp.StatusCode, msg: string(body)}
+	}
+	return body, nil
+}
+
+// usageWindowReport reports the most used of a provider's rolling usage windows
+// as the percentage left, since whichever window fills first blocks requests.
+func usageWindowReport(windows []usageWindow) *cliproxyauth.QuotaReport {
+	report := &cliproxyauth.QuotaReport{Unit: "percent"}
+	var worst *usageWindow
+	for i := range windows {
+		if !windows[i].present {
+			continue
+		}
+		if worst == nil || windows[i].usedPercent > worst.usedPercent {
+			worst = &windows[i]
+		}
+	}
+	if worst == nil {
+		return report
+	}
+	report.Limit = 100
+	report.Remaining = max(0, 100-worst.usedPercent)
+	report.ResetAt = worst.resetAt
+	report.Exhausted = report.Remaining <= 0
+	return report
+}
+
+type usageWindow struct {
+	present     bool
+	usedPercent float64
+	resetAt     time.Time
+}
+
+// ProbeQuota reads the five-hour and weekly usage windows of a Claude
+// subscription. API key credentials have no such limits and report nothing.
+func (e *ClaudeExecutor) ProbeQuota(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.QuotaReport, error) {
+	if auth == nil {
+		return nil, fmt.Errorf("claude quota: missing auth")
+	}
+	if apiKey, _ := claudeCreds(auth); !isClaudeOAuthToken(apiKey) {
+		return nil, nil
+	}
+	accessToken, err := quotaAccessToken("claude", auth.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, claudeOAuthUsageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Anthropic-Beta", "oauth-2025-04-20")
+	body, err := fetchQuota(newProxyAwareHTTPClient(ctx, e.cfg, auth, 0), req, "claude")
+	if err != nil {
+		return nil, err
+	}
+	return parseClaudeUsage(body), nil
+}
+
+// ProbeHealth verifies the OAuth access token with the subscription usage API
+// without refreshing it.
+func (e *ClaudeExecutor) ProbeHealth(ctx context.Context, auth *cliproxyauth.Auth) error {
+	_, err := e.ProbeQuota(ctx, auth)
+	return err
+}
+
+// parseClaudeUsage maps the utilization windows, e.g.
+// {"five_hour":{"utilization":42,"resets_at":"..."},"seven_day":{...}}.
+func parseClaudeUsage(body []byte) *cliproxyauth.QuotaReport {
+	root := gjson.ParseBytes(body)
+	var windows []usageWindow
+	root.ForEach(func(_, value gjson.Result) bool {
+		utilization := value.Get("utilization")
+		if !value.IsObject() || utilization.Type != gjson.Number {
+			return true
+		}
+		w := usageWindow{present: true, usedPercent: utilization.Float()}
+		if reset, errParse := time.Parse(time.RFC3339, value.Get("resets_at").String()); errParse == nil {
+			w.resetAt = reset
+		}
+		windows = append(windows, w)
+		return true
+	})
+	return usageWindowReport(windows)
+}
+
+// ProbeQuota reads the primary and secondary rate limit windows of a ChatGPT
+// plan. API key credentials report nothing.
+func (e *CodexExecutor) ProbeQuota(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.QuotaReport, error) {
+	if auth == nil {
+		return nil, fmt.Errorf("codex quota: missing auth")
+	}
+	if auth.Attributes != nil && strings.TrimSpace(auth.Attributes["api_key"]) != "" {
+		return nil, nil
+	}
+	accessToken, err := quotaAccessToken("codex", auth.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, codexUsageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", codexUserAgent)
+	if accountID := metaStringValue(auth.Metadata, "account_id"); accountID != "" {
+		req.Header.Set("Chatgpt-Account-Id", accountID)
+	}
+	body, err := fetchQuota(newProxyAwareHTTPClient(ctx, e.cfg, auth, 0), req, "codex")
+	if err != nil {
+		return nil, err
+	}
+	return parseCodexUsage(body), nil
+}
+
+// ProbeHealth verifies the ChatGPT access token with the usage API without
+// refreshing it.
+func (e *CodexExecutor) ProbeHealth(ctx context.Context, auth *cliproxyauth.Auth) error {
+	_, err := e.ProbeQuota(ctx, auth)
+	return err
+}
+
+// parseCodexUsage maps {"plan_type":"plus","rate_limit":{"limit_reached":false,
+// "primary_window":{"used_percent":12,"reset_at":1760000000},"secondary_window":{...}}}.
+func parseCodexUsage(body []byte) *cliproxyauth.QuotaReport {
+	root := gjson.ParseBytes(body)
+	limits := root.Get("rate_limit")
+	var windows []usageWindow
+	for _, key := range []string{"primary_window", "secondary_window"} {
+		window := limits.Get(key)
+		if !window.Get("used_percent").Exists() {
+			continue
+		}
+		w := usageWindow{present: true, usedPercent: window.Get("used_percent").Float()}
+		if resetAt := window.Get("reset_at").Int(); resetAt > 0 {
+			w.resetAt = time.Unix(resetAt, 0)
+		}
+		windows = append(windows, w)
+	}
+	report := usageWindowReport(windows)
+	report.PlanTier = root.Get("plan_type").String()
+	if limits.Get("limit_reached").Bool() {
+		report.Exhausted = true
+	}
+	return report
+}
+
+// modelQuota is the share of a per-model allowance left, as reported by the
+// Cloud Code quota endpoints.
+type modelQuota struct {
+	fraction float64
+	resetAt  time.Time
+}
+
+// modelQuotaReport reports the model with the most quota left, so the credential
+// only counts as low once every model it serves is used up.
+func modelQuotaReport(models []modelQuota) *cliproxyauth.QuotaReport {
+	report := &cliproxyauth.QuotaReport{Unit: "percent"}
+	if len(models) == 0 {
+		return report
+	}
+	best := models[0]
+	for _, q := range models[1:] {
+		if q.fraction > best.fraction || (q.fraction == best.fraction && !q.resetAt.IsZero() && (best.resetAt.IsZero() || q.resetAt.Before(best.resetAt))) {
+			best = q
+		}
+	}
+	report.Limit = 100
+	report.Remaining = max(0, best.fraction*100)
+	report.ResetAt = best.resetAt
+	report.Exhausted = report.Remaining <= 0
+	return report
+}
+
+func parseModelQuota(value gjson.Result) (modelQuota, bool) {
+	fraction := value.Get("remainingFraction")
+	reset := value.Get("resetTime")
+	if !fraction.Exists() && !reset.Exists() {
+		return modelQuota{}, false
+	}
+	// A missing fraction next to a reset time means the allowance is used up.
+	q := modelQuota{fraction: fraction.Float()}
+	if t, err := time.Parse(time.RFC3339, reset.String()); err == nil {
+		q.resetAt = t
+	}
+	return q, true
+}
+
+// ProbeQuota reads the per-model request buckets of the Code Assist project.
+func (e *GeminiCLIExecutor) ProbeQuota(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.QuotaReport, error) {
+	if auth == nil {
+		return nil, fmt.Errorf("gemini-cli quota: missing auth")
+	}
+	accessToken, err := quotaAccessToken("gemini-cli", geminiOAuthMetadata(auth))
+	if err != nil {
+		return nil, err
+	}
+	projectID := resolveGeminiProjectID(auth)
+	if projectID == "" {
+		return nil, fmt.Errorf("gemini-cli quota: missing project id")
+	}
+	payload := []byte(fmt.Sprintf(`{"project":%q}`, projectID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, geminiCLIQuotaURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	body, err := fetchQuota(newHTTPClient(ctx, e.cfg, auth, 0), req, "gemini-cli")
+	if err != nil {
+		return nil, err
+	}
+	var models []modelQuota
+	for _, bucket := range gjson.GetBytes(body, "buckets").Array() {
+		if q, ok := parseModelQuota(bucket); ok {
+			models = append(models, q)
+		}
+	}
+	return modelQuotaReport(models), nil
+}
+
+// ProbeHealth verifies the access token and project with retrieveUserQuota.
+func (e *GeminiCLIExecutor) ProbeHealth(ctx context.Context, auth *cliproxyauth.Auth) error {
+	_, err := e.ProbeQuota(ctx, auth)
+	return err
+}
+
+// ProbeQuota reads the per-model quota of fetchAvailableModels. When every model
+// is used up but the Google One AI credits fallback is enabled and the credits
+// have not run out, the credential keeps serving on credits and is not reported
+// as exhausted.
+func (e *AntigravityExecutor) ProbeQuota(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.QuotaReport, error) {
+	if auth == nil {
+		return nil, fmt.Errorf("antigravity quota: missing auth")
+	}
+	accessToken, err := quotaAccessToken("antigravity", auth.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	payload := []byte(`{}`)
+	if projectID := metaStringValue(auth.Metadata, "project_id"); projectID != "" {
+		payload = []byte(fmt.Sprintf(`{"project":%q}`, projectID))
+	}
+	base := strings.TrimSuffix(buildBaseURL(auth), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+antigravityModelsPath, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", resolveUserAgent(auth))
+	body, err := fetchQuota(newAntigravityHTTPClient(ctx, e.cfg, auth, 0), req, "antigravity")
+	if err != nil {
+		return nil, err
+	}
+	var models []modelQuota
+	gjson.GetBytes(body, "models").ForEach(func(_, model gjson.Result) bool {
+		if q, ok := parseModelQuota(model.Get("quotaInfo")); ok {
+			models = append(models, q)
+		}
+		return true
+	})
+	report := modelQuotaReport(models)
+	if report.Exhausted && antigravityCreditsRetryEnabled(e.cfg) && !antigravityCreditsExhausted(auth, time.Now()) {
+		return &cliproxyauth.QuotaReport{Unit: "google-one-ai-credits", ResetAt: report.ResetAt}, nil
+	}
+	return report, nil
+}
+
+// ProbeHealth verifies the access token with fetchAvailableModels.
+func (e *AntigravityExecutor) ProbeHealth(ctx context.Context, auth *cliproxyauth.Auth) error {
+	_, err := e.ProbeQuota(ctx, auth)
+	return err
+}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func TestParseGitHubCopilotQuota(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"copilot_plan":"individual","quota_reset_date":"2026-11-01","quota_snapshots":{"chat":{"unlimited":true},"premium_interactions":{"entitlement":300,"remaining":0,"overage_permitted":false,"unlimited":false}}}`)
+	report, err := parseGitHubCopilotQuota(body)
+	if err != nil {
+		t.Fatalf("parseGitHubCopilotQuota() error = %v", err)
+	}
+	if report.PlanTier != "individual" || report.Limit != 300 || report.Remaining != 0 || !report.Exhausted {
+		t.Fatalf("report = %+v", report)
+	}
+	if want := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC); !report.ResetAt.Equal(want) {
+		t.Fatalf("ResetAt = %v, want %v", report.ResetAt, want)
+	}
+
+	unlimited, _ := parseGitHubCopilotQuota([]byte(`{"copilot_plan":"business","quota_snapshots":{"premium_interactions":{"unlimited":true}}}`))
+	if unlimited.Limit != 0 || unlimited.Exhausted {
+		t.Fatalf("unlimited report = %+v", unlimited)
+	}
+
+	free, _ := parseGitHubCopilotQuota([]byte(`{"copilot_plan":"free","monthly_quotas":{"chat":50,"completions":2000},"limited_user_quotas":{"chat":12},"limited_user_reset_date":"2026-11-05"}`))
+	if free.Unit != "chat-requests" || free.Limit != 50 || free.Remaining != 12 || free.Exhausted {
+		t.Fatalf("free report = %+v", free)
+	}
+	if want := time.Date(2026, 11, 5, 0, 0, 0, 0, time.UTC); !free.ResetAt.Equal(want) {
+		t.Fatalf("free ResetAt = %v, want %v", free.ResetAt, want)
+	}
+}
+
+func TestParseClaudeAndCodexUsageReportTheFullestWindow(t *testing.T) {
+	t.Parallel()
+
+	claude := parseClaudeUsage([]byte(`{"five_hour":{"utilization":30,"resets_at":"2026-10-19T15:00:00Z"},"seven_day":{"utilization":85,"resets_at":"2026-10-22T00:00:00Z"},"seven_day_opus":null}`))
+	if claude.Limit != 100 || claude.Remaining != 15 || claude.Exhausted {
+		t.Fatalf("claude report = %+v", claude)
+	}
+	if want := time.Date(2026, 10, 22, 0, 0, 0, 0, time.UTC); !claude.ResetAt.Equal(want) {
+		t.Fatalf("claude ResetAt = %v, want %v", claude.ResetAt, want)
+	}
+
+	codex := parseCodexUsage([]byte(`{"plan_type":"plus","rate_limit":{"limit_reached":true,"primary_window":{"used_percent":100,"reset_at":1760900000},"secondary_window":{"used_percent":40,"reset_at":1761400000}}}`))
+	if codex.PlanTier != "plus" || codex.Remaining != 0 || !codex.Exhausted || codex.ResetAt.Unix() != 1760900000 {
+		t.Fatalf("codex report = %+v", codex)
+	}
+}
+
+func TestAntigravityProbeQuotaFallsBackToCredits(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		if r.URL.Path != antigravityModelsPath {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"models":{"gemini-3-pro-high":{"quotaInfo":{"resetTime":"2026-10-19T18:00:00Z"}},"claude-sonnet-4-5":{"quotaInfo":{"remainingFraction":0,"resetTime":"2026-10-19T20:00:00Z"}}}}`))
+	}))
+	defer server.Close()
+
+	originalOrder := antigravityBaseURLFallbackOrder
+	defer func() { antigravityBaseURLFallbackOrder = originalOrder }()
+	antigravityBaseURLFallbackOrder = func(*cliproxyauth.Auth) []string { return []string{server.URL} }
+
+	auth := &cliproxyauth.Auth{ID: "ag-quota", Provider: "antigravity", Metadata: map[string]any{
+		"access_token": "token",
+		"expired":      time.Now().Add(time.Hour).Format(time.RFC3339),
+		"project_id":   "proj",
+	}}
+	report, err := NewAntigravityExecutor(&config.Config{}).ProbeQuota(context.Background(), auth)
+	if err != nil {
+		t.Fatalf("ProbeQuota() error = %v", err)
+	}
+	if gotAuth != "Bearer token" || !report.Exhausted || report.Limit != 100 {
+		t.Fatalf("report = %+v, auth header %q", report, gotAuth)
+	}
+	if want := time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC); !report.ResetAt.Equal(want) {
+		t.Fatalf("ResetAt = %v, want %v", report.ResetAt, want)
+	}
+
+	cfg := &config.Config{}
+	cfg.QuotaExceeded.AntigravityCredits = true
+	report, err = NewAntigravityExecutor(cfg).ProbeQuota(context.Background(), auth)
+	if err != nil || report.Exhausted || report.Unit != "google-one-ai-credits" {
+		t.Fatalf("credits report = %+v, %v", report, err)
+	}
+	markAntigravityCreditsExhausted(auth, time.Now())
+	defer clearAntigravityCreditsExhausted(auth)
+	if report, _ = NewAntigravityExecutor(cfg).ProbeQuota(context.Background(), auth); !report.Exhausted {
+		t.Fatalf("report with credits used up = %+v", report)
+	}
+}
+
+func TestQuotaProbesNeverRefreshExpiredTokens(t *testing.T) {
+	t.Parallel()
+
+	auth := &cliproxyauth.Auth{ID: "expired", Provider: "claude", Metadata: map[string]any{
+		"access_token":  "sk-ant-oat01-expired",
+		"refresh_token": "single-use",
+		"expired":       time.Now().Add(-time.Minute).Format(time.RFC3339),
+	}}
+	if _, err := NewClaudeExecutor(&config.Config{}).ProbeQuota(context.Background(), auth); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("ProbeQuota() error = %v, want expired token", err)
+	}
+	apiKey := &cliproxyauth.Auth{ID: "key", Provider: "claude", Attributes: map[string]string{"api_key": "sk-ant-api03-key"}}
+	if report, err := NewClaudeExecutor(&config.Config{}).ProbeQuota(context.Background(), apiKey); report != nil || err != nil {
+		t.Fatalf("API key ProbeQuota() = %+v, %v, want nothing", report, err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/go-git/go-git/v6/plumbing/object"
 	"github.com/go-git/go-git/v6/plumbing/transport"
 	"github.com/go-git/go-git/v6/plumbing/transport/http"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/confighistory"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 )
 
@@ -936,6 +937,76 @@ func (s *GitTokenStore) PersistConfig(_ context.Context) error {
 	return s.commitAndPushLocked("Update config", rel)
 }
 
+// SaveConfigVersion writes one config history version into config/history and
+// commits it, so versions survive the squashed single-commit history.
+func (s *GitTokenStore) SaveConfigVersion(ctx context.Context, version confighistory.Version, content []byte) error {
+	dir, err := s.configHistoryDir()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = confighistory.DirBackend(dir).SaveConfigVersion(ctx, version, content); err != nil {
+		return err
+	}
+	return s.commitConfigVersionLocked("Record config version "+version.ID, dir, version.ID)
+}
+
+// ListConfigVersions returns the metadata of every committed config version.
+func (s *GitTokenStore) ListConfigVersions(ctx context.Context) ([]confighistory.Version, error) {
+	dir, err := s.configHistoryDir()
+	if err != nil {
+		return nil, err
+	}
+	return confighistory.DirBackend(dir).ListConfigVersions(ctx)
+}
+
+// ConfigVersion returns one committed config version.
+func (s *GitTokenStore) ConfigVersion(ctx context.Context, id string) (confighistory.Version, []byte, error) {
+	dir, err := s.configHistoryDir()
+	if err != nil {
+		return confighistory.Version{}, nil, err
+	}
+	return confighistory.DirBackend(dir).ConfigVersion(ctx, id)
+}
+
+// DeleteConfigVersion removes one config version and commits the removal.
+func (s *GitTokenStore) DeleteConfigVersion(ctx context.Context, id string) error {
+	dir, err := s.configHistoryDir()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = confighistory.DirBackend(dir).DeleteConfigVersion(ctx, id); err != nil {
+		return err
+	}
+	return s.commitConfigVersionLocked("Drop config version "+id, dir, id)
+}
+
+func (s *GitTokenStore) configHistoryDir() (string, error) {
+	if err := s.EnsureRepository(); err != nil {
+		return "", err
+	}
+	configPath := s.ConfigPath()
+	if configPath == "" {
+		return "", fmt.Errorf("git token store: config path not configured")
+	}
+	return filepath.Join(filepath.Dir(configPath), "history"), nil
+}
+
+func (s *GitTokenStore) commitConfigVersionLocked(message, dir, id string) error {
+	var rels []string
+	for _, name := range []string{id + ".yaml", id + ".json"} {
+		rel, err := s.relativeToRepo(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		rels = append(rels, rel)
+	}
+	return s.commitAndPushLocked(message, rels...)
+}
+
 func ensureEmptyFile(path string) error {
 	if _, err := os.Stat(path); err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
@@ -17,14 +17,16 @@ import (
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/confighistory"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
-	objectStoreConfigKey  = "config/config.yaml"
-	objectStoreAuthPrefix = "auths"
+	objectStoreConfigKey     = "config/config.yaml"
+	objectStoreAuthPrefix    = "auths"
+	objectStoreHistoryPrefix = "config/history"
 )
 
 // ObjectStoreConfig captures configuration for the object storage-backed token store.
@@ -324,6 +326,103 @@ func (s *ObjectTokenStore) PersistConfig(ctx context.Context) error {
 	return s.putObject(ctx, objectStoreConfigKey, data, "application/x-yaml")
 }
 
+// SaveConfigVersion uploads one config history version as <id>.yaml with <id>.json metadata.
+func (s *ObjectTokenStore) SaveConfigVersion(ctx context.Context, version confighistory.Version, content []byte) error {
+	meta, err := json.Marshal(version)
+	if err != nil {
+		return fmt.Errorf("object store: encode config version: %w", err)
+	}
+	// putObject treats empty data as a delete, so upload directly.
+	for _, object := range []struct {
+		key         string
+		data        []byte
+		contentType string
+	}{
+		{s.historyKey(version.ID, ".yaml"), content, "application/x-yaml"},
+		{s.historyKey(version.ID, ".json"), meta, "application/json"},
+	} {
+		_, err = s.client.PutObject(ctx, s.cfg.Bucket, object.key, bytes.NewReader(object.data), int64(len(object.data)), minio.PutObjectOptions{
+			ContentType: object.contentType,
+		})
+		if err != nil {
+			return fmt.Errorf("object store: put object %s: %w", object.key, err)
+		}
+	}
+	return nil
+}
+
+// ListConfigVersions returns the metadata of every stored config version.
+func (s *ObjectTokenStore) ListConfigVersions(ctx context.Context) ([]confighistory.Version, error) {
+	prefix := s.prefixedKey(objectStoreHistoryPrefix + "/")
+	var versions []confighistory.Version
+	for object := range s.client.ListObjects(ctx, s.cfg.Bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("object store: list config versions: %w", object.Err)
+		}
+		id, isMeta := strings.CutSuffix(strings.TrimPrefix(object.Key, prefix), ".json")
+		if !isMeta || !confighistory.ValidID(id) {
+			continue
+		}
+		meta, err := s.getObject(ctx, object.Key)
+		if err != nil {
+			return nil, err
+		}
+		var version confighistory.Version
+		if err = json.Unmarshal(meta, &version); err != nil || version.ID != id {
+			log.WithField("key", object.Key).Warn("object store: skipping undecodable config version")
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// ConfigVersion downloads one stored config version.
+func (s *ObjectTokenStore) ConfigVersion(ctx context.Context, id string) (confighistory.Version, []byte, error) {
+	var version confighistory.Version
+	meta, err := s.getObject(ctx, s.historyKey(id, ".json"))
+	if err != nil {
+		return version, nil, err
+	}
+	if err = json.Unmarshal(meta, &version); err != nil {
+		return version, nil, fmt.Errorf("object store: decode config version %s: %w", id, err)
+	}
+	content, err := s.getObject(ctx, s.historyKey(id, ".yaml"))
+	if err != nil {
+		return version, nil, err
+	}
+	return version, content, nil
+}
+
+// DeleteConfigVersion removes one stored config version.
+func (s *ObjectTokenStore) DeleteConfigVersion(ctx context.Context, id string) error {
+	if err := s.deleteObject(ctx, objectStoreHistoryPrefix+"/"+id+".yaml"); err != nil {
+		return err
+	}
+	return s.deleteObject(ctx, objectStoreHistoryPrefix+"/"+id+".json")
+}
+
+func (s *ObjectTokenStore) historyKey(id, ext string) string {
+	return s.prefixedKey(objectStoreHistoryPrefix + "/" + id + ext)
+}
+
+// getObject downloads a fully prefixed key, mapping a missing object to confighistory.ErrNotFound.
+func (s *ObjectTokenStore) getObject(ctx context.Context, fullKey string) ([]byte, error) {
+	reader, err := s.client.GetObject(ctx, s.cfg.Bucket, fullKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("object store: download %s: %w", fullKey, err)
+	}
+	defer func() { _ = reader.Close() }()
+	data, err := io.ReadAll(reader)
+	if isObjectNotFound(err) {
+		return nil, confighistory.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("object store: read %s: %w", fullKey, err)
+	}
+	return data, nil
+}
+
 func (s *ObjectTokenStore) ensureBucket(ctx context.Context) error {
 	exists, err := s.client.BucketExists(ctx, s.cfg.Bucket)
 	if err != nil {
@@ -0,0 +1,710 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLeaseTable          = "auth_refresh_lease"
+	defaultStateTable          = "auth_runtime_state"
+	defaultTombstoneTable      = "auth_tombstone"
+	defaultClusterPoll         = 5 * time.Second
+	clusterNotifyChannel       = "cliproxy_cluster"
+	clusterStateFlush          = time.Second
+	clusterTombstoneTTL        = 30 * time.Second
+	clusterTombstoneRetention  = 24 * time.Hour
+	clusterListenRetry         = 5 * time.Second
+	clusterCursorOverlap       = 5 * time.Second
+	clusterNotifyAuthPayload   = "auth"
+	clusterNotifyConfigPayload = "config"
+)
+
+// PostgresClusterConfig tunes cluster coordination for replicas sharing one PostgresStore.
+type PostgresClusterConfig struct {
+	// NodeID identifies this replica; a hostname-derived value is used when empty.
+	NodeID string
+	// PollInterval is the fallback period for pulling remote changes when no
+	// notification arrives. Defaults to five seconds.
+	PollInterval time.Duration
+	LeaseTable   string
+	StateTable   string
+	// TombstoneTable records deleted auth ids so replicas can drop their copies
+	// without listing every row.
+	TombstoneTable string
+}
+
+// PostgresCluster lets several proxy replicas share one PostgreSQL-backed credential
+// pool. It mirrors remote auth and config changes into the local spool (where the
+// file watcher picks them up), elects a single refresher per auth through leases,
+// and replicates cooldown and quota state between replicas.
+//
+// It embeds the store so it can be registered as the token store directly.
+type PostgresCluster struct {
+	*PostgresStore
+	clusterCfg PostgresClusterConfig
+
+	// Guarded by PostgresStore.mu so spool writes and versioned upserts stay ordered.
+	versions      map[string]time.Time
+	tombstones    map[string]time.Time
+	authCursor    time.Time
+	configVersion time.Time
+
+	stateMu     sync.Mutex
+	handler     func(ctx context.Context, authID string, state cliproxyauth.RuntimeState)
+	pending     map[string]cliproxyauth.RuntimeState
+	published   map[string]string
+	stateCursor time.Time
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewPostgresCluster enables cluster coordination on top of an initialized store.
+func NewPostgresCluster(store *PostgresStore, cfg PostgresClusterConfig) (*PostgresCluster, error) {
+	if store == nil || store.db == nil {
+		return nil, fmt.Errorf("postgres cluster: store not initialized")
+	}
+	cfg.NodeID = strings.TrimSpace(cfg.NodeID)
+	if cfg.NodeID == "" {
+		host, _ := os.Hostname()
+		if host == "" {
+			host = "node"
+		}
+		cfg.NodeID = host + "-" + uuid.NewString()[:8]
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultClusterPoll
+	}
+	if cfg.LeaseTable == "" {
+		cfg.LeaseTable = defaultLeaseTable
+	}
+	if cfg.StateTable == "" {
+		cfg.StateTable = defaultStateTable
+	}
+	if cfg.TombstoneTable == "" {
+		cfg.TombstoneTable = defaultTombstoneTable
+	}
+	c := &PostgresCluster{
+		PostgresStore: store,
+		clusterCfg:    cfg,
+		versions:      make(map[string]time.Time),
+		tombstones:    make(map[string]time.Time),
+		pending:       make(map[string]cliproxyauth.RuntimeState),
+		published:     make(map[string]string),
+		wake:          make(chan struct{}, 1),
+	}
+	store.mu.Lock()
+	store.cluster = c
+	store.mu.Unlock()
+	return c, nil
+}
+
+// NodeID returns the identifier this replica uses for leases and published state.
+func (c *PostgresCluster) NodeID() string {
+	if c == nil {
+		return ""
+	}
+	return c.clusterCfg.NodeID
+}
+
+// EnsureClusterSchema creates the lease, runtime state and tombstone tables.
+func (c *PostgresCluster) EnsureClusterSchema(ctx context.Context) error {
+	if _, err := c.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			holder TEXT NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL
+		)
+	`, c.fullTableName(c.clusterCfg.LeaseTable))); err != nil {
+		return fmt.Errorf("postgres cluster: create lease table: %w", err)
+	}
+	if _, err := c.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			node TEXT NOT NULL,
+			state JSONB NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`, c.fullTableName(c.clusterCfg.StateTable))); err != nil {
+		return fmt.Errorf("postgres cluster: create state table: %w", err)
+	}
+	if _, err := c.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			deleted_at TIMESTAMPTZ NOT NULL
+		)
+	`, c.fullTableName(c.clusterCfg.TombstoneTable))); err != nil {
+		return fmt.Errorf("postgres cluster: create tombstone table: %w", err)
+	}
+	return nil
+}
+
+// Start prepares the cluster tables, records the current auth and config versions and launches
+// the background sync, notification listener and state publisher.
+func (c *PostgresCluster) Start(ctx context.Context) error {
+	if err := c.EnsureClusterSchema(ctx); err != nil {
+		return err
+	}
+	if err := c.seedVersions(ctx); err != nil {
+		return err
+	}
+	runCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.wg.Add(3)
+	go c.runSync(runCtx)
+	go c.runListener(runCtx)
+	go c.runPublisher(runCtx)
+	log.Infof("postgres cluster mode enabled, node id: %s", c.clusterCfg.NodeID)
+	return nil
+}
+
+// Close stops background work, flushes pending state and closes the database.
+func (c *PostgresCluster) Close() error {
+	if c == nil {
+		return nil
+	}
+	if c.cancel != nil {
+		c.cancel()
+		c.wg.Wait()
+	}
+	return c.PostgresStore.Close()
+}
+
+// AcquireRefreshLease implements cliproxyauth.RefreshLeaser.
+func (c *PostgresCluster) AcquireRefreshLease(ctx context.Context, authID string, ttl time.Duration) (bool, error) {
+	table := c.fullTableName(c.clusterCfg.LeaseTable)
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (id, holder, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		ON CONFLICT (id)
+		DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE %[1]s.expires_at < NOW() OR %[1]s.holder = EXCLUDED.holder
+		RETURNING holder
+	`, table)
+	var holder string
+	err := c.db.QueryRowContext(ctx, query, authID, c.clusterCfg.NodeID, ttl.Seconds()).Scan(&holder)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("postgres cluster: acquire lease: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseRefreshLease implements cliproxyauth.RefreshLeaser. The lease is kept for
+// two poll intervals after release so other replicas receive the refreshed token
+// before they may try to refresh it themselves.
+func (c *PostgresCluster) ReleaseRefreshLease(ctx context.Context, authID string) error {
+	query := fmt.Sprintf(`
+		UPDATE %s SET expires_at = NOW() + make_interval(secs => $3)
+		WHERE id = $1 AND holder = $2
+	`, c.fullTableName(c.clusterCfg.LeaseTable))
+	settle := 2 * c.clusterCfg.PollInterval
+	if _, err := c.db.ExecContext(ctx, query, authID, c.clusterCfg.NodeID, settle.Seconds()); err != nil {
+		return fmt.Errorf("postgres cluster: release lease: %w", err)
+	}
+	return nil
+}
+
+// PublishAuthState implements cliproxyauth.StateReplicator. States are coalesced and
+// written in the background; unchanged states are not rewritten.
+func (c *PostgresCluster) PublishAuthState(_ context.Context, authID string, state cliproxyauth.RuntimeState) {
+	if c == nil || authID == "" {
+		return
+	}
+	c.stateMu.Lock()
+	c.pending[authID] = state
+	c.stateMu.Unlock()
+}
+
+// SetStateHandler implements cliproxyauth.StateReplicator.
+func (c *PostgresCluster) SetStateHandler(handler func(ctx context.Context, authID string, state cliproxyauth.RuntimeState)) {
+	c.stateMu.Lock()
+	c.handler = handler
+	c.stateMu.Unlock()
+}
+
+func (c *PostgresCluster) seedVersions(ctx context.Context) error {
+	var configVersion time.Time
+	err := c.db.QueryRowContext(ctx, fmt.Sprintf("SELECT updated_at FROM %s WHERE id = $1", c.fullTableName(c.PostgresStore.cfg.ConfigTable)), defaultConfigKey).Scan(&configVersion)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("postgres cluster: load config version: %w", err)
+	}
+	query := fmt.Sprintf("SELECT id, updated_at FROM %s", c.fullTableName(c.PostgresStore.cfg.AuthTable))
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("postgres cluster: load auth versions: %w", err)
+	}
+	defer rows.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.configVersion = configVersion
+	for rows.Next() {
+		var (
+			id        string
+			updatedAt time.Time
+		)
+		if err = rows.Scan(&id, &updatedAt); err != nil {
+			return fmt.Errorf("postgres cluster: scan auth version: %w", err)
+		}
+		c.versions[id] = updatedAt
+		if updatedAt.After(c.authCursor) {
+			c.authCursor = updatedAt
+		}
+	}
+	return rows.Err()
+}
+
+func (c *PostgresCluster) runSync(ctx context.Context) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.clusterCfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.wake:
+		}
+		if err := c.syncAuths(ctx); err != nil && ctx.Err() == nil {
+			log.WithError(err).Warn("postgres cluster: auth sync failed")
+		}
+		if err := c.syncConfig(ctx); err != nil && ctx.Err() == nil {
+			log.WithError(err).Warn("postgres cluster: config sync failed")
+		}
+		if err := c.syncStates(ctx); err != nil && ctx.Err() == nil {
+			log.WithError(err).Warn("postgres cluster: state sync failed")
+		}
+	}
+}
+
+// runListener turns LISTEN/NOTIFY events into immediate syncs. Polling keeps
+// replicas converging when the listener connection is down.
+func (c *PostgresCluster) runListener(ctx context.Context) {
+	defer c.wg.Done()
+	for ctx.Err() == nil {
+		if err := c.listen(ctx); err != nil && ctx.Err() == nil {
+			log.WithError(err).Debug("postgres cluster: notification listener disconnected")
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(clusterListenRetry):
+		}
+	}
+}
+
+func (c *PostgresCluster) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, c.PostgresStore.cfg.DSN)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+	if _, err = conn.Exec(ctx, "LISTEN "+clusterNotifyChannel); err != nil {
+		return err
+	}
+	for {
+		if _, err = conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *PostgresCluster) runPublisher(ctx context.Context) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(clusterStateFlush)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			c.flushStates(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			c.flushStates(ctx)
+		}
+	}
+}
+
+type authChange struct {
+	id        string
+	content   string
+	updatedAt time.Time
+	deleted   bool
+}
+
+// syncAuths mirrors auth rows changed by other replicas into the spool directory
+// and removes files whose rows were deleted. Both come from one query bounded by
+// the cursor; c.mu is held only while the results are applied.
+func (c *PostgresCluster) syncAuths(ctx context.Context) error {
+	c.mu.Lock()
+	since := c.authCursor.Add(-clusterCursorOverlap)
+	c.mu.Unlock()
+
+	query := fmt.Sprintf(`
+		SELECT id, content::text, updated_at, FALSE FROM %s WHERE updated_at > $1
+		UNION ALL
+		SELECT id, '', deleted_at, TRUE FROM %s WHERE deleted_at > $1
+		ORDER BY 3
+	`, c.fullTableName(c.PostgresStore.cfg.AuthTable), c.fullTableName(c.clusterCfg.TombstoneTable))
+	rows, err := c.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return fmt.Errorf("postgres cluster: load changed auth: %w", err)
+	}
+	var changes []authChange
+	for rows.Next() {
+		var change authChange
+		if err = rows.Scan(&change.id, &change.content, &change.updatedAt, &change.deleted); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("postgres cluster: scan changed auth: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("postgres cluster: iterate changed auth: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for _, change := range changes {
+		if change.updatedAt.After(c.authCursor) {
+			c.authCursor = change.updatedAt
+		}
+		// Versions recorded by a local write while the query ran are newer.
+		known, tracked := c.versions[change.id]
+		if tracked && !change.updatedAt.After(known) {
+			continue
+		}
+		if change.deleted {
+			if tracked {
+				c.removeSpoolAuth(change.id, now)
+			}
+			continue
+		}
+		c.versions[change.id] = change.updatedAt
+		delete(c.tombstones, change.id)
+		if errWrite := c.writeSpoolAuth(change.id, []byte(change.content)); errWrite != nil {
+			log.WithError(errWrite).Warnf("postgres cluster: failed to mirror auth %s", change.id)
+		}
+	}
+	for id, deletedAt := range c.tombstones {
+		if now.Sub(deletedAt) >= clusterTombstoneTTL {
+			delete(c.tombstones, id)
+		}
+	}
+	return nil
+}
+
+// removeSpoolAuth drops the mirrored file of an auth deleted by another replica.
+// The caller must hold c.mu.
+func (c *PostgresCluster) removeSpoolAuth(relID string, now time.Time) {
+	delete(c.versions, relID)
+	c.tombstones[relID] = now
+	path, err := c.absoluteAuthPath(relID)
+	if err != nil {
+		return
+	}
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.WithError(err).Warnf("postgres cluster: failed to remove auth %s", relID)
+	} else {
+		log.Debugf("postgres cluster: removed auth %s deleted by another replica", relID)
+	}
+}
+
+// syncConfig writes a config saved by another replica to the local config path,
+// where the file watcher reloads it.
+func (c *PostgresCluster) syncConfig(ctx context.Context) error {
+	c.mu.Lock()
+	since := c.configVersion
+	c.mu.Unlock()
+
+	var (
+		content   string
+		updatedAt time.Time
+	)
+	query := fmt.Sprintf("SELECT content, updated_at FROM %s WHERE id = $1 AND updated_at > $2", c.fullTableName(c.PostgresStore.cfg.ConfigTable))
+	err := c.db.QueryRowContext(ctx, query, defaultConfigKey, since).Scan(&content, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("postgres cluster: load config: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !updatedAt.After(c.configVersion) {
+		return nil
+	}
+	c.configVersion = updatedAt
+	if err = writeFileIfChanged(c.configPath, []byte(normalizeLineEndings(content))); err != nil {
+		return fmt.Errorf("postgres cluster: write config: %w", err)
+	}
+	log.Debug("postgres cluster: mirrored config saved by another replica")
+	return nil
+}
+
+// writeSpoolAuth replaces the mirrored file when its content differs. The caller
+// must hold c.mu.
+func (c *PostgresCluster) writeSpoolAuth(relID string, data []byte) error {
+	path, err := c.absoluteAuthPath(relID)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return writeFileIfChanged(path, data)
+}
+
+// persistAuth performs a compare-and-swap upsert so a replica holding a stale copy
+// cannot overwrite a token refreshed elsewhere. The caller must hold c.mu.
+func (c *PostgresCluster) persistAuth(ctx context.Context, relID string, data []byte) error {
+	if deletedAt, ok := c.tombstones[relID]; ok && time.Since(deletedAt) < clusterTombstoneTTL {
+		log.Debugf("postgres cluster: skipping write for recently deleted auth %s", relID)
+		return nil
+	}
+	table := c.fullTableName(c.PostgresStore.cfg.AuthTable)
+	var (
+		query string
+		args  = []any{relID, json.RawMessage(data)}
+	)
+	if version, known := c.versions[relID]; known {
+		query = fmt.Sprintf(`
+			INSERT INTO %[1]s (id, content, created_at, updated_at)
+			VALUES ($1, $2, NOW(), NOW())
+			ON CONFLICT (id)
+			DO UPDATE SET content = EXCLUDED.content, updated_at = NOW()
+			WHERE %[1]s.content IS DISTINCT FROM EXCLUDED.content AND %[1]s.updated_at <= $3
+			RETURNING updated_at
+		`, table)
+		args = append(args, version)
+	} else {
+		query = fmt.Sprintf(`
+			INSERT INTO %s (id, content, created_at, updated_at)
+			VALUES ($1, $2, NOW(), NOW())
+			ON CONFLICT (id) DO NOTHING
+			RETURNING updated_at
+		`, table)
+	}
+	var updatedAt time.Time
+	err := c.db.QueryRowContext(ctx, query, args...).Scan(&updatedAt)
+	if err == nil {
+		c.versions[relID] = updatedAt
+		delete(c.tombstones, relID)
+		c.notify(ctx, clusterNotifyAuthPayload)
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("postgres cluster: upsert auth record: %w", err)
+	}
+
+	// Nothing was written: either the content is unchanged or another replica
+	// updated the row after the version this replica last saw.
+	var current string
+	err = c.db.QueryRowContext(ctx, fmt.Sprintf("SELECT content, updated_at FROM %s WHERE id = $1", table), relID).Scan(&current, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("postgres cluster: load auth record: %w", err)
+	}
+	c.versions[relID] = updatedAt
+	if jsonEqual([]byte(current), data) {
+		return nil
+	}
+	log.Warnf("postgres cluster: auth %s was updated by another replica, keeping the newer copy", relID)
+	return c.writeSpoolAuth(relID, []byte(current))
+}
+
+// persistConfig upserts the config only when its content changed, so replicas
+// writing back a config they just mirrored do not wake each other again. The
+// caller must hold c.mu.
+func (c *PostgresCluster) persistConfig(ctx context.Context, data []byte) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (id, content, created_at, updated_at)
+		VALUES ($1, $2, NOW(), NOW())
+		ON CONFLICT (id)
+		DO UPDATE SET content = EXCLUDED.content, updated_at = NOW()
+		WHERE %[1]s.content IS DISTINCT FROM EXCLUDED.content
+		RETURNING updated_at
+	`, c.fullTableName(c.PostgresStore.cfg.ConfigTable))
+	var updatedAt time.Time
+	err := c.db.QueryRowContext(ctx, query, defaultConfigKey, normalizeLineEndings(string(data))).Scan(&updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("postgres cluster: upsert config: %w", err)
+	}
+	c.configVersion = updatedAt
+	c.notify(ctx, clusterNotifyConfigPayload)
+	return nil
+}
+
+// deleteAuth removes the row of an auth deleted by this replica and leaves a
+// tombstone for the others. The caller must hold c.mu.
+func (c *PostgresCluster) deleteAuth(ctx context.Context, relID string) error {
+	tombstones := c.fullTableName(c.clusterCfg.TombstoneTable)
+	query := fmt.Sprintf(`
+		WITH removed AS (DELETE FROM %s WHERE id = $1 RETURNING id)
+		INSERT INTO %s (id, deleted_at) SELECT id, NOW() FROM removed
+		ON CONFLICT (id) DO UPDATE SET deleted_at = EXCLUDED.deleted_at
+	`, c.fullTableName(c.PostgresStore.cfg.AuthTable), tombstones)
+	if _, err := c.db.ExecContext(ctx, query, relID); err != nil {
+		return fmt.Errorf("postgres cluster: delete auth record: %w", err)
+	}
+	prune := fmt.Sprintf("DELETE FROM %s WHERE deleted_at < NOW() - make_interval(secs => $1)", tombstones)
+	if _, err := c.db.ExecContext(ctx, prune, clusterTombstoneRetention.Seconds()); err != nil {
+		log.WithError(err).Debug("postgres cluster: failed to prune tombstones")
+	}
+	delete(c.versions, relID)
+	c.notify(ctx, clusterNotifyAuthPayload)
+	return nil
+}
+
+func (c *PostgresCluster) notify(ctx context.Context, payload string) {
+	if _, err := c.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", clusterNotifyChannel, payload); err != nil {
+		log.WithError(err).Debug("postgres cluster: notify failed")
+	}
+}
+
+func (c *PostgresCluster) flushStates(ctx context.Context) {
+	c.stateMu.Lock()
+	if len(c.pending) == 0 {
+		c.stateMu.Unlock()
+		return
+	}
+	batch := c.pending
+	c.pending = make(map[string]cliproxyauth.RuntimeState)
+	c.stateMu.Unlock()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, node, state, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (id)
+		DO UPDATE SET node = EXCLUDED.node, state = EXCLUDED.state, updated_at = NOW()
+	`, c.fullTableName(c.clusterCfg.StateTable))
+	written := false
+	for authID, state := range batch {
+		fingerprint := stateFingerprint(state)
+		c.stateMu.Lock()
+		unchanged := c.published[authID] == fingerprint
+		c.stateMu.Unlock()
+		if unchanged {
+			continue
+		}
+		payload, err := json.Marshal(state)
+		if err != nil {
+			continue
+		}
+		if _, err = c.db.ExecContext(ctx, query, authID, c.clusterCfg.NodeID, json.RawMessage(payload)); err != nil {
+			log.WithError(err).Warnf("postgres cluster: failed to publish state for %s", authID)
+			continue
+		}
+		c.stateMu.Lock()
+		c.published[authID] = fingerprint
+		c.stateMu.Unlock()
+		written = true
+	}
+	if written {
+		c.notify(ctx, clusterNotifyAuthPayload)
+	}
+}
+
+// syncStates delivers runtime state published by other replicas to the handler.
+func (c *PostgresCluster) syncStates(ctx context.Context) error {
+	c.stateMu.Lock()
+	handler := c.handler
+	cursor := c.stateCursor
+	c.stateMu.Unlock()
+	if handler == nil {
+		return nil
+	}
+	query := fmt.Sprintf("SELECT id, state, updated_at FROM %s WHERE updated_at > $1 AND node <> $2 ORDER BY updated_at", c.fullTableName(c.clusterCfg.StateTable))
+	since := cursor
+	if !since.IsZero() {
+		since = since.Add(-clusterCursorOverlap)
+	}
+	rows, err := c.db.QueryContext(ctx, query, since, c.clusterCfg.NodeID)
+	if err != nil {
+		return fmt.Errorf("postgres cluster: load runtime state: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id        string
+			payload   string
+			updatedAt time.Time
+			state     cliproxyauth.RuntimeState
+		)
+		if err = rows.Scan(&id, &payload, &updatedAt); err != nil {
+			return fmt.Errorf("postgres cluster: scan runtime state: %w", err)
+		}
+		if updatedAt.After(cursor) {
+			cursor = updatedAt
+		}
+		if err = json.Unmarshal([]byte(payload), &state); err != nil {
+			log.WithError(err).Warnf("postgres cluster: ignoring invalid state for %s", id)
+			continue
+		}
+		// Remember the remote state so it is not echoed back unchanged.
+		c.stateMu.Lock()
+		c.published[id] = stateFingerprint(state)
+		c.stateMu.Unlock()
+		handler(ctx, id, state)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("postgres cluster: iterate runtime state: %w", err)
+	}
+	c.stateMu.Lock()
+	c.stateCursor = cursor
+	c.stateMu.Unlock()
+	return nil
+}
+
+// stateFingerprint identifies a runtime state while ignoring timestamps that move
+// on every successful request.
+func stateFingerprint(state cliproxyauth.RuntimeState) string {
+	state.UpdatedAt = time.Time{}
+	if len(state.ModelStates) > 0 {
+		models := make(map[string]*cliproxyauth.ModelState, len(state.ModelStates))
+		for name, ms := range state.ModelStates {
+			if ms == nil {
+				continue
+			}
+			clone := *ms
+			clone.UpdatedAt = time.Time{}
+			models[name] = &clone
+		}
+		state.ModelStates = models
+	}
+	raw, _ := json.Marshal(state)
+	return string(raw)
+}
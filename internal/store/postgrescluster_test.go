@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// Cluster integration tests run against a disposable local database, e.g.
+// CLIPROXY_TEST_PGSTORE_DSN=postgres://postgres@localhost:5432/postgres?sslmode=disable
+const clusterTestDSNEnv = "CLIPROXY_TEST_PGSTORE_DSN"
+
+func TestStateFingerprintIgnoresTimestamps(t *testing.T) {
+	base := cliproxyauth.RuntimeState{
+		Status:      cliproxyauth.StatusActive,
+		UpdatedAt:   time.Now(),
+		ModelStates: map[string]*cliproxyauth.ModelState{"m1": {Status: cliproxyauth.StatusActive, UpdatedAt: time.Now()}},
+	}
+	later := base
+	later.UpdatedAt = base.UpdatedAt.Add(time.Minute)
+	later.ModelStates = map[string]*cliproxyauth.ModelState{"m1": {Status: cliproxyauth.StatusActive, UpdatedAt: time.Now().Add(time.Minute)}}
+	if stateFingerprint(base) != stateFingerprint(later) {
+		t.Fatal("fingerprint changed for timestamp-only update")
+	}
+	if base.ModelStates["m1"].UpdatedAt.IsZero() {
+		t.Fatal("stateFingerprint() mutated the input state")
+	}
+	later.ModelStates["m1"].Unavailable = true
+	if stateFingerprint(base) == stateFingerprint(later) {
+		t.Fatal("fingerprint ignored an availability change")
+	}
+}
+
+func TestPostgresClusterReplicasShareAuthState(t *testing.T) {
+	dsn := strings.TrimSpace(os.Getenv(clusterTestDSNEnv))
+	if dsn == "" {
+		t.Skipf("%s not set", clusterTestDSNEnv)
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("cliproxy_cluster_test_%d", time.Now().UnixNano())
+	first := newTestCluster(t, dsn, schema, "node-a")
+	second := newTestCluster(t, dsn, schema, "node-b")
+	t.Cleanup(func() {
+		_, _ = first.db.ExecContext(context.Background(), "DROP SCHEMA "+quoteIdentifier(schema)+" CASCADE")
+	})
+
+	// Only one replica may hold the refresh lease at a time.
+	if ok, err := first.AcquireRefreshLease(ctx, "claude.json", time.Minute); err != nil || !ok {
+		t.Fatalf("first AcquireRefreshLease() = %v, %v", ok, err)
+	}
+	if ok, err := second.AcquireRefreshLease(ctx, "claude.json", time.Minute); err != nil || ok {
+		t.Fatalf("second AcquireRefreshLease() = %v, %v; want denied", ok, err)
+	}
+
+	// A token written on one replica reaches the other's spool.
+	auth := &cliproxyauth.Auth{ID: "claude.json", FileName: "claude.json", Provider: "claude", Metadata: map[string]any{"type": "claude", "refresh_token": "v1"}}
+	if _, err := first.Save(ctx, auth); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := second.syncAuths(ctx); err != nil {
+		t.Fatalf("syncAuths() error = %v", err)
+	}
+	mirrored, err := os.ReadFile(filepath.Join(second.AuthDir(), "claude.json"))
+	if err != nil || !strings.Contains(string(mirrored), `"v1"`) {
+		t.Fatalf("mirrored auth = %q, %v", mirrored, err)
+	}
+
+	// The first replica refreshes again; a stale write from the second is rejected.
+	auth.Metadata["refresh_token"] = "v2"
+	if _, err = first.Save(ctx, auth); err != nil {
+		t.Fatalf("Save(v2) error = %v", err)
+	}
+	stale := &cliproxyauth.Auth{ID: "claude.json", FileName: "claude.json", Provider: "claude", Metadata: map[string]any{"type": "claude", "refresh_token": "v1-stale"}}
+	if _, err = second.Save(ctx, stale); err != nil {
+		t.Fatalf("Save(stale) error = %v", err)
+	}
+	var content string
+	if err = first.db.QueryRowContext(ctx, fmt.Sprintf("SELECT content FROM %s WHERE id = $1", first.fullTableName(first.PostgresStore.cfg.AuthTable)), "claude.json").Scan(&content); err != nil {
+		t.Fatalf("load record: %v", err)
+	}
+	if !strings.Contains(content, `"v2"`) {
+		t.Fatalf("stale replica overwrote the refreshed token: %s", content)
+	}
+	if mirrored, _ = os.ReadFile(filepath.Join(second.AuthDir(), "claude.json")); !strings.Contains(string(mirrored), `"v2"`) {
+		t.Fatalf("rejected write did not restore the current token locally: %s", mirrored)
+	}
+
+	// Cooldown state published by one replica is delivered to the other.
+	received := make(map[string]cliproxyauth.RuntimeState)
+	second.SetStateHandler(func(_ context.Context, authID string, state cliproxyauth.RuntimeState) {
+		received[authID] = state
+	})
+	first.PublishAuthState(ctx, "claude.json", cliproxyauth.RuntimeState{Status: cliproxyauth.StatusError, Unavailable: true, UpdatedAt: time.Now()})
+	first.flushStates(ctx)
+	if err = second.syncStates(ctx); err != nil {
+		t.Fatalf("syncStates() error = %v", err)
+	}
+	if state, ok := received["claude.json"]; !ok || !state.Unavailable {
+		t.Fatalf("replicated state = %+v, %v", state, ok)
+	}
+}
+
+func TestPostgresClusterReplicasShareConfigAndDeletions(t *testing.T) {
+	dsn := strings.TrimSpace(os.Getenv(clusterTestDSNEnv))
+	if dsn == "" {
+		t.Skipf("%s not set", clusterTestDSNEnv)
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("cliproxy_cluster_test_%d", time.Now().UnixNano())
+	first := newTestCluster(t, dsn, schema, "node-a")
+	second := newTestCluster(t, dsn, schema, "node-b")
+	t.Cleanup(func() {
+		_, _ = first.db.ExecContext(context.Background(), "DROP SCHEMA "+quoteIdentifier(schema)+" CASCADE")
+	})
+
+	// A config edit on one replica lands on the other's config path for its watcher.
+	edited := "port: 8418\nrequest-retry: 2\n"
+	if err := os.WriteFile(first.ConfigPath(), []byte(edited), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.PersistConfig(ctx); err != nil {
+		t.Fatalf("PersistConfig() error = %v", err)
+	}
+	if err := second.syncConfig(ctx); err != nil {
+		t.Fatalf("syncConfig() error = %v", err)
+	}
+	if mirrored, err := os.ReadFile(second.ConfigPath()); err != nil || string(mirrored) != edited {
+		t.Fatalf("mirrored config = %q, %v; want %q", mirrored, err, edited)
+	}
+	// Writing the mirrored config back is a no-op and does not move the version.
+	before := second.configVersion
+	if err := second.PersistConfig(ctx); err != nil {
+		t.Fatalf("PersistConfig(mirrored) error = %v", err)
+	}
+	if !second.configVersion.Equal(before) {
+		t.Fatal("unchanged config was written back as a new version")
+	}
+
+	// A deletion on one replica removes the other's copy through the tombstone.
+	auth := &cliproxyauth.Auth{ID: "codex.json", FileName: "codex.json", Provider: "codex", Metadata: map[string]any{"type": "codex"}}
+	if _, err := first.Save(ctx, auth); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := second.syncAuths(ctx); err != nil {
+		t.Fatalf("syncAuths() error = %v", err)
+	}
+	mirroredPath := filepath.Join(second.AuthDir(), "codex.json")
+	if _, err := os.Stat(mirroredPath); err != nil {
+		t.Fatalf("auth was not mirrored: %v", err)
+	}
+	if err := first.Delete(ctx, "codex.json"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := second.syncAuths(ctx); err != nil {
+		t.Fatalf("syncAuths() error = %v", err)
+	}
+	if _, err := os.Stat(mirroredPath); !os.IsNotExist(err) {
+		t.Fatalf("deleted auth still mirrored: %v", err)
+	}
+}
+
+func newTestCluster(t *testing.T, dsn, schema, node string) *PostgresCluster {
+	t.Helper()
+	ctx := context.Background()
+	pg, err := NewPostgresStore(ctx, PostgresStoreConfig{DSN: dsn, Schema: schema, SpoolDir: filepath.Join(t.TempDir(), "pgstore")})
+	if err != nil {
+		t.Fatalf("NewPostgresStore() error = %v", err)
+	}
+	if err = pg.Bootstrap(ctx, ""); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+	cluster, err := NewPostgresCluster(pg, PostgresClusterConfig{NodeID: node})
+	if err != nil {
+		t.Fatalf("NewPostgresCluster() error = %v", err)
+	}
+	// Drive syncs explicitly instead of starting the background loops.
+	if err = cluster.EnsureClusterSchema(ctx); err != nil {
+		t.Fatalf("EnsureClusterSchema() error = %v", err)
+	}
+	if err = cluster.seedVersions(ctx); err != nil {
+		t.Fatalf("seedVersions() error = %v", err)
+	}
+	t.Cleanup(func() { _ = cluster.Close() })
+	return cluster
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/confighistory"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
@@ -43,6 +44,8 @@ type PostgresStore struct {
 	configPath string
 	authDir    string
 	mu         sync.Mutex
+	// cluster is set when replicas share this store; see PostgresCluster.
+	cluster *PostgresCluster
 }
 
 // NewPostgresStore establishes a connection to PostgreSQL and prepares the local workspace.
@@ -140,6 +143,15 @@ func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
 	`, authTable)); err != nil {
 		return fmt.Errorf("postgres store: create auth table: %w", err)
 	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			meta JSONB NOT NULL,
+			content TEXT NOT NULL
+		)
+	`, s.configHistoryTable())); err != nil {
+		return fmt.Errorf("postgres store: create config history table: %w", err)
+	}
 	return nil
 }
 
@@ -392,6 +404,75 @@ func (s *PostgresStore) PersistConfig(ctx context.Context) error {
 	return s.persistConfig(ctx, data)
 }
 
+// SaveConfigVersion stores one config history version.
+func (s *PostgresStore) SaveConfigVersion(ctx context.Context, version confighistory.Version, content []byte) error {
+	meta, err := json.Marshal(version)
+	if err != nil {
+		return fmt.Errorf("postgres store: encode config version: %w", err)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (id, meta, content) VALUES ($1, $2, $3)", s.configHistoryTable())
+	if _, err = s.db.ExecContext(ctx, query, version.ID, string(meta), string(content)); err != nil {
+		return fmt.Errorf("postgres store: insert config version: %w", err)
+	}
+	return nil
+}
+
+// ListConfigVersions returns the metadata of every stored config version.
+func (s *PostgresStore) ListConfigVersions(ctx context.Context) ([]confighistory.Version, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT meta FROM %s", s.configHistoryTable()))
+	if err != nil {
+		return nil, fmt.Errorf("postgres store: list config versions: %w", err)
+	}
+	defer rows.Close()
+	var versions []confighistory.Version
+	for rows.Next() {
+		var meta []byte
+		if err = rows.Scan(&meta); err != nil {
+			return nil, fmt.Errorf("postgres store: scan config version: %w", err)
+		}
+		var version confighistory.Version
+		if err = json.Unmarshal(meta, &version); err != nil {
+			log.WithError(err).Warn("postgres store: skipping undecodable config version")
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("postgres store: iterate config versions: %w", err)
+	}
+	return versions, nil
+}
+
+// ConfigVersion returns one stored config version.
+func (s *PostgresStore) ConfigVersion(ctx context.Context, id string) (confighistory.Version, []byte, error) {
+	var (
+		meta    []byte
+		content string
+		version confighistory.Version
+	)
+	query := fmt.Sprintf("SELECT meta, content FROM %s WHERE id = $1", s.configHistoryTable())
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&meta, &content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return version, nil, confighistory.ErrNotFound
+	}
+	if err != nil {
+		return version, nil, fmt.Errorf("postgres store: load config version: %w", err)
+	}
+	if err = json.Unmarshal(meta, &version); err != nil {
+		return version, nil, fmt.Errorf("postgres store: decode config version %s: %w", id, err)
+	}
+	return version, []byte(content), nil
+}
+
+// DeleteConfigVersion removes one stored config version.
+func (s *PostgresStore) DeleteConfigVersion(ctx context.Context, id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", s.configHistoryTable())
+	if _, err := s.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("postgres store: delete config version: %w", err)
+	}
+	return nil
+}
+
 // syncConfigFromDatabase writes the database-stored config to disk or seeds the database from template.
 func (s *PostgresStore) syncConfigFromDatabase(ctx context.Context, exampleConfigPath string) error {
 	query := fmt.Sprintf("SELECT content FROM %s WHERE id = $1", s.fullTableName(s.cfg.ConfigTable))
@@ -502,6 +583,9 @@ func (s *PostgresStore) upsertAuthRecord(ctx context.Context, relID, path string
 }
 
 func (s *PostgresStore) persistAuth(ctx context.Context, relID string, data []byte) error {
+	if s.cluster != nil {
+		return s.cluster.persistAuth(ctx, relID, data)
+	}
 	jsonPayload := json.RawMessage(data)
 	query := fmt.Sprintf(`
 		INSERT INTO %s (id, content, created_at, updated_at)
@@ -516,6 +600,9 @@ func (s *PostgresStore) persistAuth(ctx context.Context, relID string, data []by
 }
 
 func (s *PostgresStore) deleteAuthRecord(ctx context.Context, relID string) error {
+	if s.cluster != nil {
+		return s.cluster.deleteAuth(ctx, relID)
+	}
 	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", s.fullTableName(s.cfg.AuthTable))
 	if _, err := s.db.ExecContext(ctx, query, relID); err != nil {
 		return fmt.Errorf("postgres store: delete auth record: %w", err)
@@ -524,6 +611,9 @@ func (s *PostgresStore) deleteAuthRecord(ctx context.Context, relID string) erro
 }
 
 func (s *PostgresStore) persistConfig(ctx context.Context, data []byte) error {
+	if s.cluster != nil {
+		return s.cluster.persistConfig(ctx, data)
+	}
 	query := fmt.Sprintf(`
 		INSERT INTO %s (id, content, created_at, updated_at)
 		VALUES ($1, $2, NOW(), NOW())
@@ -620,6 +710,11 @@ func (s *PostgresStore) fullTableName(name string) string {
 	return quoteIdentifier(s.cfg.Schema) + "." + quoteIdentifier(name)
 }
 
+// configHistoryTable names the table holding config history versions.
+func (s *PostgresStore) configHistoryTable() string {
+	return s.fullTableName(s.cfg.ConfigTable + "_history")
+}
+
 func quoteIdentifier(identifier string) string {
 	replaced := strings.ReplaceAll(identifier, "\"", "\"\"")
 	return "\"" + replaced + "\""
@@ -0,0 +1,875 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/confighistory"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+// defaultSQLiteHistoryLimit bounds the number of versions kept per auth record and for the config.
+const defaultSQLiteHistoryLimit = 50
+
+// SQLiteStoreConfig captures configuration required to initialize a SQLite-backed store.
+type SQLiteStoreConfig struct {
+	// Path is the database file; it is created when missing.
+	Path string
+	// SpoolDir holds the mirrored config and auth files used by the file watcher.
+	SpoolDir string
+	// HistoryLimit caps retained versions per record. Zero selects the default.
+	HistoryLimit int
+}
+
+// SQLiteRecordVersion describes one historical version of an auth record or the config.
+type SQLiteRecordVersion struct {
+	ID        string    `json:"id"`
+	Version   int64     `json:"version"`
+	Content   string    `json:"content,omitempty"`
+	Deleted   bool      `json:"deleted,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SQLiteStore persists configuration and authentication metadata in a local SQLite
+// database, keeping a bounded version history for every change. Like the other
+// remote-backed stores it mirrors data into a spool directory so file-based
+// workflows, including the watcher, keep operating unchanged.
+type SQLiteStore struct {
+	db           *sql.DB
+	cfg          SQLiteStoreConfig
+	spoolRoot    string
+	configPath   string
+	authDir      string
+	historyLimit int
+	mu           sync.Mutex
+}
+
+// NewSQLiteStore opens (or creates) the database and prepares the local workspace.
+func NewSQLiteStore(ctx context.Context, cfg SQLiteStoreConfig) (*SQLiteStore, error) {
+	dbPath := strings.TrimSpace(cfg.Path)
+	if dbPath == "" {
+		return nil, fmt.Errorf("sqlite store: database path is required")
+	}
+	absDB, err := filepath.Abs(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite store: resolve database path: %w", err)
+	}
+	cfg.Path = absDB
+
+	spoolRoot := strings.TrimSpace(cfg.SpoolDir)
+	if spoolRoot == "" {
+		spoolRoot = filepath.Join(filepath.Dir(absDB), "sqlitestore")
+	}
+	absSpool, err := filepath.Abs(spoolRoot)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite store: resolve spool directory: %w", err)
+	}
+	configDir := filepath.Join(absSpool, "config")
+	authDir := filepath.Join(absSpool, "auths")
+	for _, dir := range []string{filepath.Dir(absDB), configDir, authDir} {
+		if err = os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("sqlite store: create directory %s: %w", dir, err)
+		}
+	}
+
+	dsn := "file:" + filepath.ToSlash(absDB) + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite store: open database: %w", err)
+	}
+	// SQLite serializes writers; a single connection avoids SQLITE_BUSY between our own goroutines.
+	db.SetMaxOpenConns(1)
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("sqlite store: ping database: %w", err)
+	}
+
+	historyLimit := cfg.HistoryLimit
+	if historyLimit <= 0 {
+		historyLimit = defaultSQLiteHistoryLimit
+	}
+	return &SQLiteStore{
+		db:           db,
+		cfg:          cfg,
+		spoolRoot:    absSpool,
+		configPath:   filepath.Join(configDir, "config.yaml"),
+		authDir:      authDir,
+		historyLimit: historyLimit,
+	}, nil
+}
+
+// Close releases the underlying database handle.
+func (s *SQLiteStore) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// EnsureSchema creates the record and history tables.
+func (s *SQLiteStore) EnsureSchema(ctx context.Context) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("sqlite store: not initialized")
+	}
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS auth_store (
+			id TEXT PRIMARY KEY,
+			content TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS auth_history (
+			id TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			content TEXT NOT NULL,
+			deleted INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER NOT NULL,
+			PRIMARY KEY (id, version)
+		)`,
+		`CREATE TABLE IF NOT EXISTS config_store (
+			id TEXT PRIMARY KEY,
+			content TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS config_history (
+			version INTEGER PRIMARY KEY,
+			content TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS config_versions (
+			id TEXT PRIMARY KEY,
+			meta TEXT NOT NULL,
+			content TEXT NOT NULL
+		)`,
+	}
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("sqlite store: create schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// Bootstrap synchronizes configuration and auth records between the database and the local workspace.
+func (s *SQLiteStore) Bootstrap(ctx context.Context, exampleConfigPath string) error {
+	if err := s.EnsureSchema(ctx); err != nil {
+		return err
+	}
+	if err := s.syncConfigFromDatabase(ctx, exampleConfigPath); err != nil {
+		return err
+	}
+	return s.syncAuthFromDatabase(ctx)
+}
+
+// ConfigPath returns the managed configuration file path inside the spool directory.
+func (s *SQLiteStore) ConfigPath() string {
+	if s == nil {
+		return ""
+	}
+	return s.configPath
+}
+
+// AuthDir returns the local directory containing mirrored auth files.
+func (s *SQLiteStore) AuthDir() string {
+	if s == nil {
+		return ""
+	}
+	return s.authDir
+}
+
+// WorkDir exposes the root spool directory used for mirroring.
+func (s *SQLiteStore) WorkDir() string {
+	if s == nil {
+		return ""
+	}
+	return s.spoolRoot
+}
+
+// SetBaseDir implements the optional interface used by authenticators; it is a no-op because
+// the SQLite-backed store controls its own workspace.
+func (s *SQLiteStore) SetBaseDir(string) {}
+
+// Save persists authentication metadata to disk and the database.
+func (s *SQLiteStore) Save(ctx context.Context, auth *cliproxyauth.Auth) (string, error) {
+	if auth == nil {
+		return "", fmt.Errorf("sqlite store: auth is nil")
+	}
+	path, err := s.resolveAuthPath(auth)
+	if err != nil {
+		return "", err
+	}
+	if auth.Disabled {
+		if _, statErr := os.Stat(path); errors.Is(statErr, fs.ErrNotExist) {
+			return "", nil
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("sqlite store: create auth directory: %w", err)
+	}
+	switch {
+	case auth.Storage != nil:
+		if err = auth.Storage.SaveTokenToFile(path); err != nil {
+			return "", err
+		}
+	case auth.Metadata != nil:
+		raw, errMarshal := json.Marshal(auth.Metadata)
+		if errMarshal != nil {
+			return "", fmt.Errorf("sqlite store: marshal metadata: %w", errMarshal)
+		}
+		if errWrite := writeFileIfChanged(path, raw); errWrite != nil {
+			return "", fmt.Errorf("sqlite store: write auth file: %w", errWrite)
+		}
+	default:
+		return "", fmt.Errorf("sqlite store: nothing to persist for %s", auth.ID)
+	}
+
+	if auth.Attributes == nil {
+		auth.Attributes = make(map[string]string)
+	}
+	auth.Attributes["path"] = path
+	if strings.TrimSpace(auth.FileName) == "" {
+		auth.FileName = auth.ID
+	}
+
+	relID, err := s.relativeAuthID(path)
+	if err != nil {
+		return "", err
+	}
+	if err = s.syncAuthFile(ctx, relID, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// List enumerates all auth records stored in the database.
+func (s *SQLiteStore) List(ctx context.Context) ([]*cliproxyauth.Auth, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, content, created_at, updated_at FROM auth_store ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("sqlite store: list auth: %w", err)
+	}
+	defer rows.Close()
+
+	auths := make([]*cliproxyauth.Auth, 0, 32)
+	for rows.Next() {
+		var (
+			id        string
+			payload   string
+			createdAt int64
+			updatedAt int64
+		)
+		if err = rows.Scan(&id, &payload, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("sqlite store: scan auth row: %w", err)
+		}
+		path, errPath := s.absoluteAuthPath(id)
+		if errPath != nil {
+			log.WithError(errPath).Warnf("sqlite store: skipping auth %s outside spool", id)
+			continue
+		}
+		metadata := make(map[string]any)
+		if err = json.Unmarshal([]byte(payload), &metadata); err != nil {
+			log.WithError(err).Warnf("sqlite store: skipping auth %s with invalid json", id)
+			continue
+		}
+		provider := strings.TrimSpace(valueAsString(metadata["type"]))
+		if provider == "" {
+			provider = "unknown"
+		}
+		attr := map[string]string{"path": path}
+		if email := strings.TrimSpace(valueAsString(metadata["email"])); email != "" {
+			attr["email"] = email
+		}
+		auth := &cliproxyauth.Auth{
+			ID:         normalizeAuthID(id),
+			Provider:   provider,
+			FileName:   normalizeAuthID(id),
+			Label:      labelFor(metadata),
+			Status:     cliproxyauth.StatusActive,
+			Attributes: attr,
+			Metadata:   metadata,
+			CreatedAt:  time.UnixMilli(createdAt).UTC(),
+			UpdatedAt:  time.UnixMilli(updatedAt).UTC(),
+		}
+		cliproxyauth.ApplyCustomHeadersFromMetadata(auth)
+		auths = append(auths, auth)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite store: iterate auth rows: %w", err)
+	}
+	return auths, nil
+}
+
+// Delete removes an auth file and the corresponding database record. The deletion
+// is kept in the record's history.
+func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return fmt.Errorf("sqlite store: id is empty")
+	}
+	path := id
+	if !strings.ContainsRune(id, os.PathSeparator) && !filepath.IsAbs(id) {
+		path = filepath.Join(s.authDir, filepath.FromSlash(id))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("sqlite store: delete auth file: %w", err)
+	}
+	relID, err := s.relativeAuthID(path)
+	if err != nil {
+		return err
+	}
+	return s.deleteAuthRecord(ctx, relID)
+}
+
+// PersistAuthFiles stores the provided auth file changes in the database.
+func (s *SQLiteStore) PersistAuthFiles(ctx context.Context, _ string, paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range paths {
+		trimmed := strings.TrimSpace(p)
+		if trimmed == "" {
+			continue
+		}
+		if !filepath.IsAbs(trimmed) {
+			trimmed = filepath.Join(s.authDir, trimmed)
+		}
+		relID, err := s.relativeAuthID(trimmed)
+		if err != nil {
+			log.WithError(err).Warnf("sqlite store: ignoring auth path %s", trimmed)
+			continue
+		}
+		if err = s.syncAuthFile(ctx, relID, trimmed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PersistConfig records the local configuration file as a new config version.
+func (s *SQLiteStore) PersistConfig(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("sqlite store: read config file: %w", err)
+	}
+	return s.persistConfig(ctx, data)
+}
+
+// AuthHistory returns the stored versions of one auth record, newest first.
+func (s *SQLiteStore) AuthHistory(ctx context.Context, id string) ([]SQLiteRecordVersion, error) {
+	relID := normalizeAuthID(strings.TrimSpace(id))
+	rows, err := s.db.QueryContext(ctx, "SELECT id, version, content, deleted, created_at FROM auth_history WHERE id = ? ORDER BY version DESC", relID)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite store: load auth history: %w", err)
+	}
+	defer rows.Close()
+	return scanSQLiteVersions(rows)
+}
+
+// ConfigHistory returns the stored config versions, newest first.
+func (s *SQLiteStore) ConfigHistory(ctx context.Context) ([]SQLiteRecordVersion, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT ?, version, content, 0, created_at FROM config_history ORDER BY version DESC", defaultConfigKey)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite store: load config history: %w", err)
+	}
+	defer rows.Close()
+	return scanSQLiteVersions(rows)
+}
+
+// SaveConfigVersion stores one version of the management config history. Unlike
+// config_history, which tracks every write to the stored config, these versions
+// carry the author and source shown by the management API.
+func (s *SQLiteStore) SaveConfigVersion(ctx context.Context, version confighistory.Version, content []byte) error {
+	meta, err := json.Marshal(version)
+	if err != nil {
+		return fmt.Errorf("sqlite store: encode config version: %w", err)
+	}
+	if _, err = s.db.ExecContext(ctx, "INSERT INTO config_versions (id, meta, content) VALUES (?, ?, ?)", version.ID, string(meta), string(content)); err != nil {
+		return fmt.Errorf("sqlite store: insert config version: %w", err)
+	}
+	return nil
+}
+
+// ListConfigVersions returns the metadata of every stored config version.
+func (s *SQLiteStore) ListConfigVersions(ctx context.Context) ([]confighistory.Version, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT meta FROM config_versions")
+	if err != nil {
+		return nil, fmt.Errorf("sqlite store: list config versions: %w", err)
+	}
+	defer rows.Close()
+	var versions []confighistory.Version
+	for rows.Next() {
+		var meta string
+		if err = rows.Scan(&meta); err != nil {
+			return nil, fmt.Errorf("sqlite store: scan config version: %w", err)
+		}
+		var version confighistory.Version
+		if err = json.Unmarshal([]byte(meta), &version); err != nil {
+			log.WithError(err).Warn("sqlite store: skipping undecodable config version")
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite store: iterate config versions: %w", err)
+	}
+	return versions, nil
+}
+
+// ConfigVersion returns one stored config version.
+func (s *SQLiteStore) ConfigVersion(ctx context.Context, id string) (confighistory.Version, []byte, error) {
+	var (
+		meta, content string
+		version       confighistory.Version
+	)
+	err := s.db.QueryRowContext(ctx, "SELECT meta, content FROM config_versions WHERE id = ?", id).Scan(&meta, &content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return version, nil, confighistory.ErrNotFound
+	}
+	if err != nil {
+		return version, nil, fmt.Errorf("sqlite store: load config version: %w", err)
+	}
+	if err = json.Unmarshal([]byte(meta), &version); err != nil {
+		return version, nil, fmt.Errorf("sqlite store: decode config version %s: %w", id, err)
+	}
+	return version, []byte(content), nil
+}
+
+// DeleteConfigVersion removes one stored config version.
+func (s *SQLiteStore) DeleteConfigVersion(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM config_versions WHERE id = ?", id); err != nil {
+		return fmt.Errorf("sqlite store: delete config version: %w", err)
+	}
+	return nil
+}
+
+// ImportDirectory migrates an existing auth directory into the database. Every
+// *.json file below dir becomes an auth record keyed by its relative path; a
+// config.yaml at the top level, when present, becomes the current config. The
+// spool is refreshed so a running watcher picks up the imported records.
+func (s *SQLiteStore) ImportDirectory(ctx context.Context, dir string) (int, error) {
+	root, err := filepath.Abs(strings.TrimSpace(dir))
+	if err != nil {
+		return 0, fmt.Errorf("sqlite store: resolve import directory: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	imported := 0
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() || !strings.HasSuffix(strings.ToLower(d.Name()), ".json") {
+			return nil
+		}
+		data, errRead := os.ReadFile(path)
+		if errRead != nil {
+			return fmt.Errorf("sqlite store: read %s: %w", path, errRead)
+		}
+		if !json.Valid(data) {
+			log.Warnf("sqlite store: skipping %s: invalid json", path)
+			return nil
+		}
+		rel, errRel := filepath.Rel(root, path)
+		if errRel != nil {
+			return errRel
+		}
+		relID := filepath.ToSlash(rel)
+		if errPersist := s.persistAuth(ctx, relID, data); errPersist != nil {
+			return errPersist
+		}
+		spoolPath, errPath := s.absoluteAuthPath(relID)
+		if errPath != nil {
+			return errPath
+		}
+		if errMkdir := os.MkdirAll(filepath.Dir(spoolPath), 0o700); errMkdir != nil {
+			return fmt.Errorf("sqlite store: create auth subdir: %w", errMkdir)
+		}
+		if errWrite := writeFileIfChanged(spoolPath, data); errWrite != nil {
+			return fmt.Errorf("sqlite store: write auth file: %w", errWrite)
+		}
+		imported++
+		return nil
+	})
+	if err != nil {
+		return imported, err
+	}
+	if data, errRead := os.ReadFile(filepath.Join(root, "config.yaml")); errRead == nil {
+		if err = s.persistConfig(ctx, data); err != nil {
+			return imported, err
+		}
+		if err = os.WriteFile(s.configPath, []byte(normalizeLineEndings(string(data))), 0o600); err != nil {
+			return imported, fmt.Errorf("sqlite store: write config to spool: %w", err)
+		}
+	}
+	return imported, nil
+}
+
+// ExportDirectory writes the current auth records, and the config as config.yaml,
+// into dir so the data can be used with the file-based store again.
+func (s *SQLiteStore) ExportDirectory(ctx context.Context, dir string) (int, error) {
+	root, err := filepath.Abs(strings.TrimSpace(dir))
+	if err != nil {
+		return 0, fmt.Errorf("sqlite store: resolve export directory: %w", err)
+	}
+	if err = os.MkdirAll(root, 0o700); err != nil {
+		return 0, fmt.Errorf("sqlite store: create export directory: %w", err)
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT id, content FROM auth_store ORDER BY id")
+	if err != nil {
+		return 0, fmt.Errorf("sqlite store: load auth records: %w", err)
+	}
+	defer rows.Close()
+
+	exported := 0
+	for rows.Next() {
+		var id, payload string
+		if err = rows.Scan(&id, &payload); err != nil {
+			return exported, fmt.Errorf("sqlite store: scan auth row: %w", err)
+		}
+		clean := filepath.Clean(filepath.FromSlash(id))
+		if filepath.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+			log.Warnf("sqlite store: skipping auth %s outside export directory", id)
+			continue
+		}
+		target := filepath.Join(root, clean)
+		if err = os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+			return exported, fmt.Errorf("sqlite store: create export subdir: %w", err)
+		}
+		if err = os.WriteFile(target, []byte(payload), 0o600); err != nil {
+			return exported, fmt.Errorf("sqlite store: write %s: %w", target, err)
+		}
+		exported++
+	}
+	if err = rows.Err(); err != nil {
+		return exported, fmt.Errorf("sqlite store: iterate auth rows: %w", err)
+	}
+
+	var content string
+	err = s.db.QueryRowContext(ctx, "SELECT content FROM config_store WHERE id = ?", defaultConfigKey).Scan(&content)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return exported, fmt.Errorf("sqlite store: load config: %w", err)
+	default:
+		if err = os.WriteFile(filepath.Join(root, "config.yaml"), []byte(content), 0o600); err != nil {
+			return exported, fmt.Errorf("sqlite store: write config: %w", err)
+		}
+	}
+	return exported, nil
+}
+
+// syncConfigFromDatabase writes the stored config to disk or seeds the database from the template.
+func (s *SQLiteStore) syncConfigFromDatabase(ctx context.Context, exampleConfigPath string) error {
+	var content string
+	err := s.db.QueryRowContext(ctx, "SELECT content FROM config_store WHERE id = ?", defaultConfigKey).Scan(&content)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if _, errStat := os.Stat(s.configPath); errors.Is(errStat, fs.ErrNotExist) {
+			if exampleConfigPath != "" {
+				if errCopy := misc.CopyConfigTemplate(exampleConfigPath, s.configPath); errCopy != nil {
+					return fmt.Errorf("sqlite store: copy example config: %w", errCopy)
+				}
+			} else if errWrite := os.WriteFile(s.configPath, []byte{}, 0o600); errWrite != nil {
+				return fmt.Errorf("sqlite store: create empty config: %w", errWrite)
+			}
+		}
+		data, errRead := os.ReadFile(s.configPath)
+		if errRead != nil {
+			return fmt.Errorf("sqlite store: read local config: %w", errRead)
+		}
+		return s.persistConfig(ctx, data)
+	case err != nil:
+		return fmt.Errorf("sqlite store: load config from database: %w", err)
+	default:
+		if err = os.WriteFile(s.configPath, []byte(normalizeLineEndings(content)), 0o600); err != nil {
+			return fmt.Errorf("sqlite store: write config to spool: %w", err)
+		}
+	}
+	return nil
+}
+
+// syncAuthFromDatabase populates the local auth directory from the database.
+func (s *SQLiteStore) syncAuthFromDatabase(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, content FROM auth_store")
+	if err != nil {
+		return fmt.Errorf("sqlite store: load auth from database: %w", err)
+	}
+	defer rows.Close()
+
+	if err = os.RemoveAll(s.authDir); err != nil {
+		return fmt.Errorf("sqlite store: reset auth directory: %w", err)
+	}
+	if err = os.MkdirAll(s.authDir, 0o700); err != nil {
+		return fmt.Errorf("sqlite store: recreate auth directory: %w", err)
+	}
+	for rows.Next() {
+		var id, payload string
+		if err = rows.Scan(&id, &payload); err != nil {
+			return fmt.Errorf("sqlite store: scan auth row: %w", err)
+		}
+		path, errPath := s.absoluteAuthPath(id)
+		if errPath != nil {
+			log.WithError(errPath).Warnf("sqlite store: skipping auth %s outside spool", id)
+			continue
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return fmt.Errorf("sqlite store: create auth subdir: %w", err)
+		}
+		if err = os.WriteFile(path, []byte(payload), 0o600); err != nil {
+			return fmt.Errorf("sqlite store: write auth file: %w", err)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("sqlite store: iterate auth rows: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) syncAuthFile(ctx context.Context, relID, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s.deleteAuthRecord(ctx, relID)
+		}
+		return fmt.Errorf("sqlite store: read auth file: %w", err)
+	}
+	if len(data) == 0 {
+		return s.deleteAuthRecord(ctx, relID)
+	}
+	return s.persistAuth(ctx, relID, data)
+}
+
+// persistAuth stores data as the next version of relID. Semantically identical
+// JSON is not recorded again.
+func (s *SQLiteStore) persistAuth(ctx context.Context, relID string, data []byte) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite store: begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var (
+		current string
+		version int64
+	)
+	err = tx.QueryRowContext(ctx, "SELECT content, version FROM auth_store WHERE id = ?", relID).Scan(&current, &version)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// First write; continue numbering after any deleted history.
+		if err = tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM auth_history WHERE id = ?", relID).Scan(&version); err != nil {
+			return fmt.Errorf("sqlite store: load auth version: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("sqlite store: load auth record: %w", err)
+	case jsonEqual([]byte(current), data):
+		return nil
+	}
+	now := time.Now().UnixMilli()
+	version++
+	if _, err = tx.ExecContext(ctx, `
+		INSERT INTO auth_store (id, content, version, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET content = excluded.content, version = excluded.version, updated_at = excluded.updated_at
+	`, relID, string(data), version, now, now); err != nil {
+		return fmt.Errorf("sqlite store: upsert auth record: %w", err)
+	}
+	if err = s.appendAuthHistory(ctx, tx, relID, version, string(data), false, now); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite store: commit auth record: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) deleteAuthRecord(ctx context.Context, relID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite store: begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var version int64
+	err = tx.QueryRowContext(ctx, "SELECT version FROM auth_store WHERE id = ?", relID).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("sqlite store: load auth record: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM auth_store WHERE id = ?", relID); err != nil {
+		return fmt.Errorf("sqlite store: delete auth record: %w", err)
+	}
+	if err = s.appendAuthHistory(ctx, tx, relID, version+1, "", true, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite store: commit auth deletion: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) appendAuthHistory(ctx context.Context, tx *sql.Tx, relID string, version int64, content string, deleted bool, now int64) error {
+	deletedFlag := 0
+	if deleted {
+		deletedFlag = 1
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO auth_history (id, version, content, deleted, created_at) VALUES (?, ?, ?, ?, ?)", relID, version, content, deletedFlag, now); err != nil {
+		return fmt.Errorf("sqlite store: record auth history: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM auth_history WHERE id = ? AND version <= ?", relID, version-int64(s.historyLimit)); err != nil {
+		return fmt.Errorf("sqlite store: trim auth history: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) persistConfig(ctx context.Context, data []byte) error {
+	normalized := normalizeLineEndings(string(data))
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite store: begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var (
+		current string
+		version int64
+	)
+	err = tx.QueryRowContext(ctx, "SELECT content, version FROM config_store WHERE id = ?", defaultConfigKey).Scan(&current, &version)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return fmt.Errorf("sqlite store: load config: %w", err)
+	case current == normalized:
+		return nil
+	}
+	now := time.Now().UnixMilli()
+	version++
+	if _, err = tx.ExecContext(ctx, `
+		INSERT INTO config_store (id, content, version, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET content = excluded.content, version = excluded.version, updated_at = excluded.updated_at
+	`, defaultConfigKey, normalized, version, now, now); err != nil {
+		return fmt.Errorf("sqlite store: upsert config: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, "INSERT INTO config_history (version, content, created_at) VALUES (?, ?, ?)", version, normalized, now); err != nil {
+		return fmt.Errorf("sqlite store: record config history: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM config_history WHERE version <= ?", version-int64(s.historyLimit)); err != nil {
+		return fmt.Errorf("sqlite store: trim config history: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite store: commit config: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) resolveAuthPath(auth *cliproxyauth.Auth) (string, error) {
+	if auth.Attributes != nil {
+		if p := strings.TrimSpace(auth.Attributes["path"]); p != "" {
+			return p, nil
+		}
+	}
+	if fileName := strings.TrimSpace(auth.FileName); fileName != "" {
+		if filepath.IsAbs(fileName) {
+			return fileName, nil
+		}
+		return filepath.Join(s.authDir, fileName), nil
+	}
+	if auth.ID == "" {
+		return "", fmt.Errorf("sqlite store: missing id")
+	}
+	if filepath.IsAbs(auth.ID) {
+		return auth.ID, nil
+	}
+	return filepath.Join(s.authDir, filepath.FromSlash(auth.ID)), nil
+}
+
+func (s *SQLiteStore) relativeAuthID(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.authDir, path)
+	}
+	rel, err := filepath.Rel(s.authDir, filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("sqlite store: compute relative path: %w", err)
+	}
+	if strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("sqlite store: path %s outside managed directory", path)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (s *SQLiteStore) absoluteAuthPath(id string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(id))
+	if filepath.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+		return "", fmt.Errorf("sqlite store: invalid auth identifier %s", id)
+	}
+	return filepath.Join(s.authDir, clean), nil
+}
+
+func scanSQLiteVersions(rows *sql.Rows) ([]SQLiteRecordVersion, error) {
+	var versions []SQLiteRecordVersion
+	for rows.Next() {
+		var (
+			entry     SQLiteRecordVersion
+			deleted   int
+			createdAt int64
+		)
+		if err := rows.Scan(&entry.ID, &entry.Version, &entry.Content, &deleted, &createdAt); err != nil {
+			return nil, fmt.Errorf("sqlite store: scan history row: %w", err)
+		}
+		entry.Deleted = deleted != 0
+		entry.CreatedAt = time.UnixMilli(createdAt).UTC()
+		versions = append(versions, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite store: iterate history rows: %w", err)
+	}
+	return versions, nil
+}
+
+// writeFileIfChanged atomically replaces path unless it already holds the same bytes
+// or equivalent JSON.
+func writeFileIfChanged(path string, data []byte) error {
+	if existing, err := os.ReadFile(path); err == nil && (bytes.Equal(existing, data) || jsonEqual(existing, data)) {
+		return nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
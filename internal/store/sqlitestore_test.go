@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/confighistory"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func newTestSQLiteStore(t *testing.T, historyLimit int) *SQLiteStore {
+	t.Helper()
+	root := t.TempDir()
+	s, err := NewSQLiteStore(context.Background(), SQLiteStoreConfig{
+		Path:         filepath.Join(root, "cliproxy.db"),
+		SpoolDir:     filepath.Join(root, "spool"),
+		HistoryLimit: historyLimit,
+	})
+	if err != nil {
+		t.Fatalf("NewSQLiteStore() error = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	if err = s.Bootstrap(context.Background(), ""); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+	return s
+}
+
+func TestSQLiteStoreVersionsAuthRecords(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLiteStore(t, 2)
+
+	auth := &cliproxyauth.Auth{ID: "claude.json", FileName: "claude.json", Metadata: map[string]any{"type": "claude", "email": "a@example.com", "token": "v1"}}
+	for _, token := range []string{"v1", "v1", "v2", "v3"} {
+		auth.Metadata["token"] = token
+		if _, err := s.Save(ctx, auth); err != nil {
+			t.Fatalf("Save(%s) error = %v", token, err)
+		}
+	}
+
+	listed, err := s.List(ctx)
+	if err != nil || len(listed) != 1 {
+		t.Fatalf("List() = %v, %v", listed, err)
+	}
+	if listed[0].Provider != "claude" || listed[0].Metadata["token"] != "v3" || listed[0].Attributes["email"] != "a@example.com" {
+		t.Fatalf("listed auth = %+v", listed[0])
+	}
+
+	history, err := s.AuthHistory(ctx, "claude.json")
+	if err != nil {
+		t.Fatalf("AuthHistory() error = %v", err)
+	}
+	// Unchanged saves are not versioned and the limit keeps the two newest entries.
+	if len(history) != 2 || history[0].Version != 3 || history[1].Version != 2 {
+		t.Fatalf("history = %+v, want versions 3 and 2", history)
+	}
+
+	if err = s.Delete(ctx, "claude.json"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, errStat := os.Stat(filepath.Join(s.AuthDir(), "claude.json")); !os.IsNotExist(errStat) {
+		t.Fatalf("auth file still present after Delete(): %v", errStat)
+	}
+	history, _ = s.AuthHistory(ctx, "claude.json")
+	if len(history) == 0 || !history[0].Deleted || history[0].Version != 4 {
+		t.Fatalf("history after delete = %+v", history)
+	}
+}
+
+func TestSQLiteStoreImportExportAndConfigHistory(t *testing.T) {
+	ctx := context.Background()
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "team"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"codex.json":         `{"type":"codex","email":"c@example.com"}`,
+		"team/gemini.json":   `{"type":"gemini"}`,
+		"notes.txt":          "ignored",
+		"broken.json":        "{not json",
+		"config.yaml":        "port: 8317\n",
+		"team/nested.backup": "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, filepath.FromSlash(name)), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestSQLiteStore(t, 0)
+	imported, err := s.ImportDirectory(ctx, src)
+	if err != nil || imported != 2 {
+		t.Fatalf("ImportDirectory() = %d, %v; want 2", imported, err)
+	}
+	if _, err = os.Stat(filepath.Join(s.AuthDir(), "team", "gemini.json")); err != nil {
+		t.Fatalf("imported auth not mirrored to spool: %v", err)
+	}
+
+	if err = os.WriteFile(s.ConfigPath(), []byte("port: 9000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.PersistConfig(ctx); err != nil {
+		t.Fatalf("PersistConfig() error = %v", err)
+	}
+	configs, err := s.ConfigHistory(ctx)
+	if err != nil || len(configs) != 3 || !strings.Contains(configs[0].Content, "9000") {
+		t.Fatalf("ConfigHistory() = %+v, %v", configs, err)
+	}
+
+	dst := t.TempDir()
+	exported, err := s.ExportDirectory(ctx, dst)
+	if err != nil || exported != 2 {
+		t.Fatalf("ExportDirectory() = %d, %v; want 2", exported, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "team", "gemini.json"))
+	if err != nil || !jsonEqual(data, []byte(files["team/gemini.json"])) {
+		t.Fatalf("exported auth = %q, %v", data, err)
+	}
+	if cfg, _ := os.ReadFile(filepath.Join(dst, "config.yaml")); string(cfg) != "port: 9000\n" {
+		t.Fatalf("exported config = %q", cfg)
+	}
+}
+
+func TestSQLiteStoreKeepsConfigHistoryVersions(t *testing.T) {
+	s := newTestSQLiteStore(t, 0)
+	h := confighistory.New(s, 2)
+
+	first, _, err := h.Record([]byte("port: 1\n"), "tui@127.0.0.1", confighistory.SourceManagement, "")
+	if err != nil {
+		t.Fatalf("Record(first) error = %v", err)
+	}
+	second, _, _ := h.Record([]byte("port: 2\n"), "file", confighistory.SourceFile, "")
+	third, _, _ := h.Record([]byte("port: 3\n"), "tui@127.0.0.1", confighistory.SourceRollback, "rollback of "+second.ID)
+
+	versions, err := confighistory.New(s, 2).List()
+	if err != nil || len(versions) != 2 || versions[0].ID != third.ID || versions[1].ID != second.ID {
+		t.Fatalf("List() = %+v, %v; want the two newest versions", versions, err)
+	}
+	if _, _, err = h.Get(first.ID); !errors.Is(err, confighistory.ErrNotFound) {
+		t.Fatalf("Get(trimmed) error = %v, want ErrNotFound", err)
+	}
+	version, content, err := h.Get(third.ID)
+	if err != nil || string(content) != "port: 3\n" || version.Author != "tui@127.0.0.1" || version.Note != "rollback of "+second.ID {
+		t.Fatalf("Get() = %+v, %q, %v", version, content, err)
+	}
+}
@@ -0,0 +1,268 @@
+// Package structuredoutput enforces OpenAI response_format and Responses text.format
+// JSON schemas for backends that ignore them. The schema is conveyed to the model
+// through a system instruction or a forced synthetic tool, the final output is
+// validated against the schema, and invalid output triggers corrective follow-up
+// requests so that clients only ever receive valid JSON.
+package structuredoutput
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/toolrepair"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ToolName is the synthetic tool the model is forced to call in tool mode.
+const ToolName = "emit_structured_output"
+
+const (
+	formatChat      = "openai"
+	formatResponses = "openai-response"
+)
+
+// Enforcer enforces the response schema of a single request.
+type Enforcer struct {
+	format     string
+	name       string
+	schema     gjson.Result
+	toolMode   bool
+	maxRetries int
+	usage      bool
+}
+
+// New returns an enforcer for a request in the given client format, or nil when
+// enforcement is disabled, does not apply to the model or the request asks for no
+// JSON output.
+func New(cfg *config.StructuredOutputConfig, format, model string, rawJSON []byte) *Enforcer {
+	if cfg == nil || !cfg.Enabled || !util.MatchAnyWildcardFold(cfg.Models, model) {
+		return nil
+	}
+	var spec gjson.Result
+	var name, schemaPath string
+	switch format {
+	case formatChat:
+		spec = gjson.GetBytes(rawJSON, "response_format")
+		name = spec.Get("json_schema.name").String()
+		schemaPath = "json_schema.schema"
+	case formatResponses:
+		spec = gjson.GetBytes(rawJSON, "text.format")
+		name = spec.Get("name").String()
+		schemaPath = "schema"
+	default:
+		return nil
+	}
+	e := &Enforcer{
+		format:     format,
+		name:       name,
+		maxRetries: cfg.MaxRetries,
+		usage:      gjson.GetBytes(rawJSON, "stream_options.include_usage").Bool(),
+	}
+	switch spec.Get("type").String() {
+	case "json_schema":
+		e.schema = spec.Get(schemaPath)
+	case "json_object":
+	default:
+		return nil
+	}
+	if e.name == "" {
+		e.name = "response"
+	}
+	// Tool mode needs a schema and must not compete with the client's own tools.
+	e.toolMode = cfg.Mode == config.StructuredOutputModeTool && format == formatChat &&
+		e.schema.IsObject() && len(gjson.GetBytes(rawJSON, "tools").Array()) == 0
+	return e
+}
+
+// MaxRetries returns the number of corrective follow-ups allowed.
+func (e *Enforcer) MaxRetries() int {
+	if e == nil {
+		return 0
+	}
+	return e.maxRetries
+}
+
+// PrepareRequest conveys the schema to the model, either as a system instruction or
+// as a forced synthetic tool.
+func (e *Enforcer) PrepareRequest(rawJSON []byte) []byte {
+	if e == nil {
+		return rawJSON
+	}
+	if e.toolMode {
+		tool := `{"type":"function","function":{}}`
+		tool, _ = sjson.Set(tool, "function.name", ToolName)
+		tool, _ = sjson.Set(tool, "function.description", "Return the final answer as the arguments of this function.")
+		tool, _ = sjson.SetRaw(tool, "function.parameters", e.schema.Raw)
+		out, _ := sjson.SetRawBytes(rawJSON, "tools", []byte("["+tool+"]"))
+		out, _ = sjson.SetRawBytes(out, "tool_choice", []byte(`{"type":"function","function":{"name":"`+ToolName+`"}}`))
+		out, _ = sjson.DeleteBytes(out, "response_format")
+		return out
+	}
+	return e.addInstruction(rawJSON, e.instruction())
+}
+
+// instruction describes the expected output. The schema is simplified with the same
+// cleaning used for Gemini tool schemas so that it stays compact.
+func (e *Enforcer) instruction() string {
+	var b strings.Builder
+	b.WriteString("Respond only with a single JSON object")
+	if e.schema.IsObject() {
+		b.WriteString(" that validates against this JSON schema:\n")
+		b.WriteString(util.CleanJSONSchemaForGemini(e.schema.Raw))
+		b.WriteString("\n")
+	} else {
+		b.WriteString(". ")
+	}
+	b.WriteString("Do not add any text before or after the JSON and do not wrap it in markdown code fences.")
+	return b.String()
+}
+
+func (e *Enforcer) addInstruction(rawJSON []byte, text string) []byte {
+	if e.format == formatResponses {
+		if existing := gjson.GetBytes(rawJSON, "instructions").String(); existing != "" {
+			text = existing + "\n\n" + text
+		}
+		out, _ := sjson.SetBytes(rawJSON, "instructions", text)
+		return out
+	}
+	first := gjson.GetBytes(rawJSON, "messages.0")
+	if role := first.Get("role").String(); role == "system" || role == "developer" {
+		content := first.Get("content")
+		if content.IsArray() {
+			part, _ := sjson.Set(`{"type":"text"}`, "text", text)
+			out, _ := sjson.SetRawBytes(rawJSON, "messages.0.content.-1", []byte(part))
+			return out
+		}
+		if existing := content.String(); existing != "" {
+			text = existing + "\n\n" + text
+		}
+		out, _ := sjson.SetBytes(rawJSON, "messages.0.content", text)
+		return out
+	}
+	message, _ := sjson.Set(`{"role":"system"}`, "content", text)
+	messages := gjson.GetBytes(rawJSON, "messages")
+	items := []string{message}
+	messages.ForEach(func(_, m gjson.Result) bool {
+		items = append(items, m.Raw)
+		return true
+	})
+	out, _ := sjson.SetRawBytes(rawJSON, "messages", []byte("["+strings.Join(items, ",")+"]"))
+	return out
+}
+
+// Validate extracts the output from a complete response, validates it and returns
+// the response with the output replaced by the normalized JSON document.
+func (e *Enforcer) Validate(payload []byte) ([]byte, error) {
+	text := strings.TrimSpace(e.output(payload))
+	if text == "" {
+		return nil, errors.New("the reply contained no JSON output")
+	}
+	doc, _, err := toolrepair.ValidateObject(e.schema, text)
+	if err != nil {
+		return nil, err
+	}
+	return e.replaceOutput(payload, doc), nil
+}
+
+// output returns the text the schema applies to.
+func (e *Enforcer) output(payload []byte) string {
+	if e.format == formatResponses {
+		var b strings.Builder
+		gjson.GetBytes(payload, "output").ForEach(func(_, item gjson.Result) bool {
+			if item.Get("type").String() == "message" {
+				item.Get("content").ForEach(func(_, part gjson.Result) bool {
+					if part.Get("type").String() == "output_text" {
+						b.WriteString(part.Get("text").String())
+					}
+					return true
+				})
+			}
+			return true
+		})
+		return b.String()
+	}
+	message := gjson.GetBytes(payload, "choices.0.message")
+	if e.toolMode {
+		for _, call := range message.Get("tool_calls").Array() {
+			if call.Get("function.name").String() == ToolName {
+				return call.Get("function.arguments").String()
+			}
+		}
+	}
+	content := message.Get("content")
+	if !content.IsArray() {
+		return content.String()
+	}
+	var b strings.Builder
+	content.ForEach(func(_, part gjson.Result) bool {
+		b.WriteString(part.Get("text").String())
+		return true
+	})
+	return b.String()
+}
+
+func (e *Enforcer) replaceOutput(payload []byte, doc string) []byte {
+	if e.format == formatResponses {
+		var items []string
+		replaced := false
+		gjson.GetBytes(payload, "output").ForEach(func(_, item gjson.Result) bool {
+			if item.Get("type").String() != "message" {
+				items = append(items, item.Raw)
+				return true
+			}
+			if replaced {
+				return true
+			}
+			replaced = true
+			msg, _ := sjson.SetRaw(item.Raw, "content", `[{"type":"output_text","annotations":[]}]`)
+			msg, _ = sjson.Set(msg, "content.0.text", doc)
+			items = append(items, msg)
+			return true
+		})
+		out, _ := sjson.SetRawBytes(payload, "output", []byte("["+strings.Join(items, ",")+"]"))
+		if gjson.GetBytes(out, "output_text").Exists() {
+			out, _ = sjson.SetBytes(out, "output_text", doc)
+		}
+		return out
+	}
+	out, _ := sjson.SetBytes(payload, "choices.0.message.content", doc)
+	if e.toolMode {
+		out, _ = sjson.DeleteBytes(out, "choices.0.message.tool_calls")
+		if gjson.GetBytes(out, "choices.0.finish_reason").String() == "tool_calls" {
+			out, _ = sjson.SetBytes(out, "choices.0.finish_reason", "stop")
+		}
+	}
+	return out
+}
+
+// FollowUp extends request with the rejected reply and a corrective user turn.
+func (e *Enforcer) FollowUp(request, payload []byte, validationErr error) []byte {
+	reply := e.output(payload)
+	if reply == "" {
+		reply = "(empty reply)"
+	}
+	correction := fmt.Sprintf("Your previous reply was rejected because it is not valid for the required format: %v. "+
+		"Reply again with only the corrected JSON object.", validationErr)
+	if e.format == formatResponses {
+		input := gjson.GetBytes(request, "input")
+		out := request
+		if input.Type == gjson.String {
+			first, _ := sjson.Set(`{"role":"user"}`, "content", input.String())
+			out, _ = sjson.SetRawBytes(out, "input", []byte("["+first+"]"))
+		}
+		assistant, _ := sjson.Set(`{"role":"assistant"}`, "content", reply)
+		user, _ := sjson.Set(`{"role":"user"}`, "content", correction)
+		out, _ = sjson.SetRawBytes(out, "input.-1", []byte(assistant))
+		out, _ = sjson.SetRawBytes(out, "input.-1", []byte(user))
+		return out
+	}
+	assistant, _ := sjson.Set(`{"role":"assistant"}`, "content", reply)
+	user, _ := sjson.Set(`{"role":"user"}`, "content", correction)
+	out, _ := sjson.SetRawBytes(request, "messages.-1", []byte(assistant))
+	out, _ = sjson.SetRawBytes(out, "messages.-1", []byte(user))
+	return out
+}
@@ -0,0 +1,107 @@
+package structuredoutput
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+const chatRequest = `{"model":"kiro-claude","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"Extract the city"}],
+	"response_format":{"type":"json_schema","json_schema":{"name":"city","schema":{"type":"object","properties":{"city":{"type":"string"},"population":{"type":"integer"}},"required":["city"]}}}}`
+
+func newEnforcer(t *testing.T, mode, format, request string) *Enforcer {
+	t.Helper()
+	cfg := &config.StructuredOutputConfig{Enabled: true, Mode: mode, MaxRetries: 2}
+	e := New(cfg, format, "kiro-claude", []byte(request))
+	if e == nil {
+		t.Fatal("expected enforcer")
+	}
+	return e
+}
+
+func TestNewSkipsRequestsWithoutJSONFormat(t *testing.T) {
+	cfg := &config.StructuredOutputConfig{Enabled: true, Mode: config.StructuredOutputModeInstruction}
+	if New(cfg, "openai", "gpt", []byte(`{"messages":[],"response_format":{"type":"text"}}`)) != nil {
+		t.Fatal("text response format must not be enforced")
+	}
+	cfg.Models = []string{"kiro-*"}
+	if New(cfg, "openai", "gpt-4o", []byte(chatRequest)) != nil {
+		t.Fatal("model filter must apply")
+	}
+}
+
+func TestPrepareRequestAddsInstruction(t *testing.T) {
+	e := newEnforcer(t, config.StructuredOutputModeInstruction, "openai", chatRequest)
+	out := e.PrepareRequest([]byte(chatRequest))
+	first := gjson.GetBytes(out, "messages.0")
+	if first.Get("role").String() != "system" || !strings.Contains(first.Get("content").String(), `"population"`) {
+		t.Fatalf("expected schema instruction as first message: %s", out)
+	}
+	if gjson.GetBytes(out, "messages.#").Int() != 2 {
+		t.Fatalf("expected original message to be kept: %s", out)
+	}
+}
+
+func TestToolModeForcesSyntheticTool(t *testing.T) {
+	e := newEnforcer(t, config.StructuredOutputModeTool, "openai", chatRequest)
+	out := e.PrepareRequest([]byte(chatRequest))
+	if gjson.GetBytes(out, "tools.0.function.name").String() != ToolName || gjson.GetBytes(out, "tool_choice.function.name").String() != ToolName {
+		t.Fatalf("expected forced synthetic tool: %s", out)
+	}
+	resp := `{"choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","tool_calls":[{"id":"1","type":"function","function":{"name":"` + ToolName + `","arguments":"{\"city\":\"Paris\"}"}}]}}]}`
+	valid, err := e.Validate([]byte(resp))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gjson.GetBytes(valid, "choices.0.message.content").String() != `{"city":"Paris"}` ||
+		gjson.GetBytes(valid, "choices.0.message.tool_calls").Exists() ||
+		gjson.GetBytes(valid, "choices.0.finish_reason").String() != "stop" {
+		t.Fatalf("unexpected finalized response: %s", valid)
+	}
+}
+
+func TestValidateRepairsAndRejects(t *testing.T) {
+	e := newEnforcer(t, config.StructuredOutputModeInstruction, "openai", chatRequest)
+	fenced := `{"choices":[{"index":0,"message":{"role":"assistant","content":"` + "```json\\n{\\\"city\\\": \\\"Oslo\\\", \\\"population\\\": \\\"700000\\\"}\\n```" + `"}}]}`
+	valid, err := e.Validate([]byte(fenced))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content := gjson.GetBytes(valid, "choices.0.message.content").String()
+	if gjson.Get(content, "population").Type != gjson.Number || gjson.Get(content, "city").String() != "Oslo" {
+		t.Fatalf("unexpected normalized content: %s", content)
+	}
+	if _, err = e.Validate([]byte(`{"choices":[{"message":{"content":"{\"population\": 3}"}}]}`)); err == nil {
+		t.Fatal("expected missing required property to fail")
+	}
+	if _, err = e.Validate([]byte(`{"choices":[{"message":{"content":""}}]}`)); err == nil {
+		t.Fatal("expected empty reply to fail")
+	}
+}
+
+func TestFollowUpAppendsCorrection(t *testing.T) {
+	e := newEnforcer(t, config.StructuredOutputModeInstruction, "openai-response", `{"input":"hi","text":{"format":{"type":"json_object"}}}`)
+	reply := `{"output":[{"type":"message","content":[{"type":"output_text","text":"Sure! here it is"}]}]}`
+	out := e.FollowUp([]byte(`{"input":"hi"}`), []byte(reply), errors.New("no JSON object found"))
+	input := gjson.GetBytes(out, "input").Array()
+	if len(input) != 3 || input[1].Get("content").String() != "Sure! here it is" || !strings.Contains(input[2].Get("content").String(), "no JSON object found") {
+		t.Fatalf("unexpected follow-up: %s", out)
+	}
+}
+
+func TestStreamChunksReplayChatResponse(t *testing.T) {
+	e := newEnforcer(t, config.StructuredOutputModeInstruction, "openai", chatRequest)
+	payload := `{"id":"x","model":"kiro-claude","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"{\"city\":\"Rome\"}"}}],"usage":{"total_tokens":5}}`
+	chunks := e.StreamChunks([]byte(payload))
+	if len(chunks) != 3 {
+		t.Fatalf("expected content, finish and usage chunks, got %d", len(chunks))
+	}
+	if gjson.GetBytes(chunks[0], "choices.0.delta.content").String() != `{"city":"Rome"}` ||
+		gjson.GetBytes(chunks[1], "choices.0.finish_reason").String() != "stop" ||
+		gjson.GetBytes(chunks[2], "usage.total_tokens").Int() != 5 {
+		t.Fatalf("unexpected chunks: %s", chunks)
+	}
+}
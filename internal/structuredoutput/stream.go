@@ -0,0 +1,107 @@
+package structuredoutput
+
+import (
+	"fmt"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// StreamChunks replays a validated non-streaming response as the stream chunks of
+// the client format. Streaming requests are buffered this way so that nothing
+// reaches the client before the output has validated.
+func (e *Enforcer) StreamChunks(payload []byte) [][]byte {
+	if e == nil {
+		return [][]byte{payload}
+	}
+	if e.format == formatResponses {
+		return responsesChunks(payload)
+	}
+	return e.chatChunks(payload)
+}
+
+func (e *Enforcer) chatChunks(payload []byte) [][]byte {
+	base := `{"object":"chat.completion.chunk","choices":[]}`
+	for _, key := range []string{"id", "created", "model", "system_fingerprint"} {
+		if v := gjson.GetBytes(payload, key); v.Exists() {
+			base, _ = sjson.SetRaw(base, key, v.Raw)
+		}
+	}
+	content, _ := sjson.Set(base, "choices.-1", map[string]any{
+		"index":         0,
+		"delta":         map[string]any{"role": "assistant", "content": gjson.GetBytes(payload, "choices.0.message.content").String()},
+		"finish_reason": nil,
+	})
+	reason := gjson.GetBytes(payload, "choices.0.finish_reason").String()
+	if reason == "" {
+		reason = "stop"
+	}
+	finish, _ := sjson.Set(base, "choices.-1", map[string]any{
+		"index":         0,
+		"delta":         map[string]any{},
+		"finish_reason": reason,
+	})
+	chunks := [][]byte{[]byte(content), []byte(finish)}
+	if usage := gjson.GetBytes(payload, "usage"); e.usage && usage.IsObject() {
+		final, _ := sjson.SetRaw(base, "usage", usage.Raw)
+		chunks = append(chunks, []byte(final))
+	}
+	return chunks
+}
+
+// responsesChunks replays a Responses API object as its streaming event sequence.
+func responsesChunks(payload []byte) [][]byte {
+	response := gjson.ParseBytes(payload)
+	var chunks [][]byte
+	seq := 0
+	emit := func(eventType, data string) {
+		data, _ = sjson.Set(data, "type", eventType)
+		data, _ = sjson.Set(data, "sequence_number", seq)
+		seq++
+		chunks = append(chunks, []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", eventType, data)))
+	}
+
+	pending, _ := sjson.Set(response.Raw, "status", "in_progress")
+	pending, _ = sjson.SetRaw(pending, "output", `[]`)
+	pending, _ = sjson.Delete(pending, "usage")
+	created, _ := sjson.SetRaw(`{}`, "response", pending)
+	emit("response.created", created)
+	emit("response.in_progress", created)
+
+	response.Get("output").ForEach(func(idx, item gjson.Result) bool {
+		index := idx.Int()
+		itemID := item.Get("id").String()
+		added, _ := sjson.Set(item.Raw, "status", "in_progress")
+		isMessage := item.Get("type").String() == "message"
+		if isMessage {
+			added, _ = sjson.SetRaw(added, "content", `[]`)
+		}
+		event, _ := sjson.Set(`{}`, "output_index", index)
+		addedEvent, _ := sjson.SetRaw(event, "item", added)
+		emit("response.output_item.added", addedEvent)
+		if isMessage {
+			item.Get("content").ForEach(func(partIdx, part gjson.Result) bool {
+				partEvent, _ := sjson.Set(event, "item_id", itemID)
+				partEvent, _ = sjson.Set(partEvent, "content_index", partIdx.Int())
+				text := part.Get("text").String()
+				emptyPart, _ := sjson.Set(part.Raw, "text", "")
+				added, _ := sjson.SetRaw(partEvent, "part", emptyPart)
+				emit("response.content_part.added", added)
+				delta, _ := sjson.Set(partEvent, "delta", text)
+				emit("response.output_text.delta", delta)
+				done, _ := sjson.Set(partEvent, "text", text)
+				emit("response.output_text.done", done)
+				partDone, _ := sjson.SetRaw(partEvent, "part", part.Raw)
+				emit("response.content_part.done", partDone)
+				return true
+			})
+		}
+		doneEvent, _ := sjson.SetRaw(event, "item", item.Raw)
+		emit("response.output_item.done", doneEvent)
+		return true
+	})
+
+	completed, _ := sjson.SetRaw(`{}`, "response", response.Raw)
+	emit("response.completed", completed)
+	return chunks
+}
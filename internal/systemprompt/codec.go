@@ -0,0 +1,221 @@
+package systemprompt
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// codec edits the system prompt of one request format while preserving the rest of
+// the payload, including provider-specific fields such as Claude cache_control.
+type codec interface {
+	// insert adds text before (atEnd=false) or after (atEnd=true) the existing system prompt.
+	insert(payload []byte, text string, atEnd bool) []byte
+	// remove drops the whole system prompt.
+	remove(payload []byte) []byte
+}
+
+func codecFor(format string) codec {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "openai":
+		return openAIChatCodec{}
+	case "openai-response":
+		return responsesCodec{}
+	case "claude":
+		return claudeCodec{}
+	case "gemini":
+		return geminiCodec{}
+	case "gemini-cli":
+		return geminiCodec{root: "request"}
+	default:
+		return nil
+	}
+}
+
+func isSystemRole(role string) bool {
+	return role == "system" || role == "developer"
+}
+
+// openAIChatCodec handles chat completions, where system prompts are messages with the
+// system or developer role.
+type openAIChatCodec struct{}
+
+func (openAIChatCodec) insert(payload []byte, text string, atEnd bool) []byte {
+	messages := gjson.GetBytes(payload, "messages")
+	if messages.Exists() && !messages.IsArray() {
+		return payload
+	}
+	at := 0
+	if atEnd {
+		for i, msg := range messages.Array() {
+			if !isSystemRole(msg.Get("role").String()) {
+				break
+			}
+			at = i + 1
+		}
+	}
+	entry, _ := sjson.SetBytes([]byte(`{"role":"system"}`), "content", text)
+	return insertArrayItem(payload, "messages", at, entry)
+}
+
+func (openAIChatCodec) remove(payload []byte) []byte {
+	return removeArrayItems(payload, "messages", func(item gjson.Result) bool {
+		return isSystemRole(item.Get("role").String())
+	})
+}
+
+// responsesCodec handles the Responses API, where the system prompt is the
+// instructions field plus any system or developer input items.
+type responsesCodec struct{}
+
+func (responsesCodec) insert(payload []byte, text string, atEnd bool) []byte {
+	existing := gjson.GetBytes(payload, "instructions").String()
+	combined := joinText(text, existing, atEnd)
+	out, err := sjson.SetBytes(payload, "instructions", combined)
+	if err != nil {
+		return payload
+	}
+	return out
+}
+
+func (responsesCodec) remove(payload []byte) []byte {
+	out, err := sjson.DeleteBytes(payload, "instructions")
+	if err != nil {
+		out = payload
+	}
+	if !gjson.GetBytes(out, "input").IsArray() {
+		return out
+	}
+	return removeArrayItems(out, "input", func(item gjson.Result) bool {
+		return isSystemRole(item.Get("role").String())
+	})
+}
+
+// claudeCodec handles the Messages API system field, which is either a string or an
+// array of text blocks.
+type claudeCodec struct{}
+
+func (claudeCodec) insert(payload []byte, text string, atEnd bool) []byte {
+	system := gjson.GetBytes(payload, "system")
+	if system.IsArray() {
+		block, _ := sjson.SetBytes([]byte(`{"type":"text"}`), "text", text)
+		at := 0
+		if atEnd {
+			at = len(system.Array())
+		}
+		return insertArrayItem(payload, "system", at, block)
+	}
+	out, err := sjson.SetBytes(payload, "system", joinText(text, system.String(), atEnd))
+	if err != nil {
+		return payload
+	}
+	return out
+}
+
+func (claudeCodec) remove(payload []byte) []byte {
+	out, err := sjson.DeleteBytes(payload, "system")
+	if err != nil {
+		return payload
+	}
+	return out
+}
+
+// geminiCodec handles systemInstruction parts, optionally below a wrapper such as the
+// Gemini CLI "request" object.
+type geminiCodec struct {
+	root string
+}
+
+func (c geminiCodec) path(key string) string {
+	if c.root == "" {
+		return key
+	}
+	return c.root + "." + key
+}
+
+func (c geminiCodec) insert(payload []byte, text string, atEnd bool) []byte {
+	key := c.path("systemInstruction")
+	if !gjson.GetBytes(payload, key).Exists() {
+		if alt := c.path("system_instruction"); gjson.GetBytes(payload, alt).Exists() {
+			key = alt
+		}
+	}
+	partsPath := key + ".parts"
+	parts := gjson.GetBytes(payload, partsPath)
+	if parts.Exists() && !parts.IsArray() {
+		return payload
+	}
+	at := 0
+	if atEnd {
+		at = len(parts.Array())
+	}
+	part, _ := sjson.SetBytes([]byte(`{}`), "text", text)
+	return insertArrayItem(payload, partsPath, at, part)
+}
+
+func (c geminiCodec) remove(payload []byte) []byte {
+	out := payload
+	for _, key := range []string{"systemInstruction", "system_instruction"} {
+		if updated, err := sjson.DeleteBytes(out, c.path(key)); err == nil {
+			out = updated
+		}
+	}
+	return out
+}
+
+func joinText(text, existing string, atEnd bool) string {
+	if strings.TrimSpace(existing) == "" {
+		return text
+	}
+	if atEnd {
+		return existing + "\n\n" + text
+	}
+	return text + "\n\n" + existing
+}
+
+// insertArrayItem inserts raw at index at of the array at path, creating the array
+// when it is missing.
+func insertArrayItem(payload []byte, path string, at int, raw []byte) []byte {
+	array := gjson.GetBytes(payload, path)
+	items := make([]string, 0, len(array.Array())+1)
+	for i, item := range array.Array() {
+		if i == at {
+			items = append(items, string(raw))
+		}
+		items = append(items, item.Raw)
+	}
+	if at >= len(array.Array()) {
+		items = append(items, string(raw))
+	}
+	out, err := sjson.SetRawBytes(payload, path, []byte("["+strings.Join(items, ",")+"]"))
+	if err != nil {
+		return payload
+	}
+	return out
+}
+
+// removeArrayItems drops the elements of the array at path for which drop is true.
+func removeArrayItems(payload []byte, path string, drop func(gjson.Result) bool) []byte {
+	array := gjson.GetBytes(payload, path)
+	if !array.IsArray() {
+		return payload
+	}
+	kept := make([]string, 0, len(array.Array()))
+	removed := false
+	for _, item := range array.Array() {
+		if drop(item) {
+			removed = true
+			continue
+		}
+		kept = append(kept, item.Raw)
+	}
+	if !removed {
+		return payload
+	}
+	out, err := sjson.SetRawBytes(payload, path, []byte("["+strings.Join(kept, ",")+"]"))
+	if err != nil {
+		return payload
+	}
+	return out
+}
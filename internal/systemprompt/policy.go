@@ -0,0 +1,129 @@
+// Package systemprompt applies configured system prompt policies to inbound requests.
+// Policies prepend, append, replace or strip system prompt text for selected client
+// keys, models and request formats. They run on the client payload before translation,
+// so one policy behaves the same for OpenAI chat, Responses, Claude and Gemini clients.
+package systemprompt
+
+import (
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// Scope describes the request a policy is evaluated against.
+type Scope struct {
+	// Format is the client request format, for example "openai" or "claude".
+	Format string
+	// Model is the requested model name.
+	Model string
+	// APIKey is the client API key.
+	APIKey string
+	// Client is a display label for the caller, used by the {{client}} variable.
+	Client string
+	// Tenant is the caller's tenant name, if any.
+	Tenant string
+	// Now is the evaluation time; zero means time.Now.
+	Now time.Time
+}
+
+// Apply runs the matching policies in order and returns the rewritten payload with
+// the names of the policies that changed it. Unsupported formats are returned as-is.
+func Apply(policies []config.SystemPromptPolicy, scope Scope, payload []byte) ([]byte, []string) {
+	if len(policies) == 0 || len(payload) == 0 {
+		return payload, nil
+	}
+	codec := codecFor(scope.Format)
+	if codec == nil {
+		return payload, nil
+	}
+	var applied []string
+	for i := range policies {
+		policy := &policies[i]
+		if !matches(policy, scope) {
+			continue
+		}
+		text := expand(policy.Text, scope)
+		out := payload
+		switch policy.Mode {
+		case config.SystemPromptModeAppend:
+			out = codec.insert(out, text, true)
+		case config.SystemPromptModeReplace:
+			out = codec.insert(codec.remove(out), text, false)
+		case config.SystemPromptModeStrip:
+			out = codec.remove(out)
+		default:
+			out = codec.insert(out, text, false)
+		}
+		if string(out) != string(payload) {
+			applied = append(applied, policy.Name)
+			payload = out
+		}
+	}
+	return payload, applied
+}
+
+func matches(policy *config.SystemPromptPolicy, scope Scope) bool {
+	if len(policy.Formats) > 0 && !containsFold(policy.Formats, scope.Format) {
+		return false
+	}
+	if len(policy.APIKeys) > 0 && !matchAny(policy.APIKeys, scope.APIKey) {
+		return false
+	}
+	if len(policy.Models) > 0 && !matchAny(policy.Models, scope.Model) {
+		return false
+	}
+	return true
+}
+
+// expand substitutes the template variables supported in policy text.
+func expand(text string, scope Scope) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	now := scope.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	now = now.UTC()
+	return strings.NewReplacer(
+		"{{date}}", now.Format("2006-01-02"),
+		"{{time}}", now.Format(time.RFC3339),
+		"{{model}}", scope.Model,
+		"{{client}}", scope.Client,
+		"{{tenant}}", scope.Tenant,
+		"{{format}}", scope.Format,
+	).Replace(text)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if util.MatchWildcardFold(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendText appends text to the system prompt of a payload in the given request
+// format. Payloads in unsupported formats are returned unchanged.
+func AppendText(format string, payload []byte, text string) []byte {
+	codec := codecFor(format)
+	if codec == nil || text == "" {
+		return payload
+	}
+	return codec.insert(payload, text, true)
+}
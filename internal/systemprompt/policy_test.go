@@ -0,0 +1,106 @@
+package systemprompt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyAcrossFormats(t *testing.T) {
+	policies := []config.SystemPromptPolicy{{Name: "policy", Mode: config.SystemPromptModePrepend, Text: "Policy for {{client}} on {{model}} ({{date}})"}}
+	scope := Scope{Model: "m1", Client: "acme", Now: time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)}
+	const want = "Policy for acme on m1 (2026-03-04)"
+
+	cases := []struct {
+		format, payload, path string
+	}{
+		{"openai", `{"messages":[{"role":"system","content":"orig"},{"role":"user","content":"hi"}]}`, "messages.0.content"},
+		{"openai-response", `{"instructions":"orig","input":"hi"}`, "instructions"},
+		{"claude", `{"system":[{"type":"text","text":"orig","cache_control":{"type":"ephemeral"}}],"messages":[]}`, "system.0.text"},
+		{"gemini", `{"systemInstruction":{"parts":[{"text":"orig"}]},"contents":[]}`, "systemInstruction.parts.0.text"},
+		{"gemini-cli", `{"request":{"contents":[]}}`, "request.systemInstruction.parts.0.text"},
+	}
+	for _, tc := range cases {
+		scope.Format = tc.format
+		out, applied := Apply(policies, scope, []byte(tc.payload))
+		if len(applied) != 1 {
+			t.Fatalf("%s: applied = %v", tc.format, applied)
+		}
+		got := gjson.GetBytes(out, tc.path).String()
+		if tc.format == "openai-response" {
+			if got != want+"\n\norig" {
+				t.Fatalf("%s: instructions = %q", tc.format, got)
+			}
+			continue
+		}
+		if got != want {
+			t.Fatalf("%s: %s = %q in %s", tc.format, tc.path, got, out)
+		}
+	}
+}
+
+func TestApplyModes(t *testing.T) {
+	payload := []byte(`{"messages":[{"role":"system","content":"a"},{"role":"developer","content":"b"},{"role":"user","content":"hi"}]}`)
+	scope := Scope{Format: "openai", Model: "gpt-5", APIKey: "team-a-1"}
+	cases := []struct {
+		mode string
+		want string
+	}{
+		{config.SystemPromptModeAppend, `["system:a","developer:b","system:P","user:hi"]`},
+		{config.SystemPromptModeReplace, `["system:P","user:hi"]`},
+		{config.SystemPromptModeStrip, `["user:hi"]`},
+	}
+	for _, tc := range cases {
+		out, _ := Apply([]config.SystemPromptPolicy{{Name: tc.mode, Mode: tc.mode, Text: "P"}}, scope, payload)
+		var got []string
+		gjson.GetBytes(out, "messages").ForEach(func(_, msg gjson.Result) bool {
+			got = append(got, msg.Get("role").String()+":"+msg.Get("content").String())
+			return true
+		})
+		if encoded := toJSON(got); encoded != tc.want {
+			t.Errorf("%s: messages = %s, want %s", tc.mode, encoded, tc.want)
+		}
+	}
+
+	claude, _ := Apply([]config.SystemPromptPolicy{{Mode: config.SystemPromptModeAppend, Text: "P"}}, Scope{Format: "claude"}, []byte(`{"system":"orig"}`))
+	if got := gjson.GetBytes(claude, "system").String(); got != "orig\n\nP" {
+		t.Fatalf("claude append = %q", got)
+	}
+}
+
+func TestApplyScoping(t *testing.T) {
+	policies := []config.SystemPromptPolicy{{
+		Name:    "scoped",
+		Mode:    config.SystemPromptModePrepend,
+		Text:    "P",
+		APIKeys: []string{"team-a-*"},
+		Models:  []string{"claude-*"},
+		Formats: []string{"claude"},
+	}}
+	payload := []byte(`{"messages":[]}`)
+	for _, scope := range []Scope{
+		{Format: "claude", Model: "claude-opus", APIKey: "team-b"},
+		{Format: "claude", Model: "gpt-5", APIKey: "team-a-1"},
+		{Format: "openai", Model: "claude-opus", APIKey: "team-a-1"},
+	} {
+		if _, applied := Apply(policies, scope, payload); len(applied) != 0 {
+			t.Fatalf("policy applied to %+v", scope)
+		}
+	}
+	if _, applied := Apply(policies, Scope{Format: "claude", Model: "Claude-Opus", APIKey: "team-a-1"}, payload); len(applied) != 1 {
+		t.Fatal("policy did not apply to a matching request")
+	}
+}
+
+func toJSON(values []string) string {
+	out := "["
+	for i, v := range values {
+		if i > 0 {
+			out += ","
+		}
+		out += `"` + v + `"`
+	}
+	return out + "]"
+}
@@ -0,0 +1,274 @@
+package tokencount
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// content is the countable material of a request, independent of its wire format.
+type content struct {
+	texts     []string
+	tools     []string
+	messages  int
+	images    []media
+	documents []media
+}
+
+// media describes an inline image or document. data holds base64 content when the
+// request embeds it; remote references carry only the mime type.
+type media struct {
+	mimeType string
+	data     string
+	detail   string
+}
+
+func (c *content) addText(s string) {
+	if s = strings.TrimSpace(s); s != "" {
+		c.texts = append(c.texts, s)
+	}
+}
+
+func (c *content) addRaw(value gjson.Result) {
+	if value.Exists() && value.Type != gjson.Null {
+		c.addText(value.Raw)
+	}
+}
+
+func (c *content) addMedia(m media) {
+	if strings.HasPrefix(m.mimeType, "image/") || m.mimeType == "" {
+		c.images = append(c.images, m)
+		return
+	}
+	c.documents = append(c.documents, m)
+}
+
+func (c *content) empty() bool {
+	return len(c.texts) == 0 && len(c.tools) == 0 && len(c.images) == 0 && len(c.documents) == 0
+}
+
+// extract parses payload according to the client request format.
+func extract(format string, payload []byte) *content {
+	root := gjson.ParseBytes(payload)
+	c := &content{}
+	switch format {
+	case "claude":
+		extractClaude(root, c)
+	case "gemini":
+		extractGemini(root, c)
+	case "gemini-cli", "antigravity":
+		extractGemini(root.Get("request"), c)
+	case "openai-response", "codex":
+		extractResponses(root, c)
+	default:
+		extractOpenAI(root, c)
+	}
+	return c
+}
+
+// dataURL splits a data: URL into its mime type and base64 payload.
+func dataURL(url string) media {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return media{mimeType: mimeFromURL(url)}
+	}
+	header, data, _ := strings.Cut(rest, ",")
+	mimeType, _, _ := strings.Cut(header, ";")
+	return media{mimeType: mimeType, data: data}
+}
+
+func mimeFromURL(url string) string {
+	lower := strings.ToLower(url)
+	if i := strings.IndexAny(lower, "?#"); i >= 0 {
+		lower = lower[:i]
+	}
+	if strings.HasSuffix(lower, ".pdf") {
+		return "application/pdf"
+	}
+	return "image/*"
+}
+
+func extractOpenAI(root gjson.Result, c *content) {
+	for _, msg := range root.Get("messages").Array() {
+		c.messages++
+		c.addText(msg.Get("name").String())
+		extractOpenAIContent(msg.Get("content"), c)
+		for _, call := range msg.Get("tool_calls").Array() {
+			c.addText(call.Get("function.name").String())
+			c.addText(call.Get("function.arguments").String())
+		}
+		if call := msg.Get("function_call"); call.Exists() {
+			c.addText(call.Get("name").String())
+			c.addText(call.Get("arguments").String())
+		}
+	}
+	c.addText(root.Get("prompt").String())
+	for _, tool := range root.Get("tools").Array() {
+		c.tools = append(c.tools, tool.Raw)
+	}
+	for _, fn := range root.Get("functions").Array() {
+		c.tools = append(c.tools, fn.Raw)
+	}
+	c.addRaw(root.Get("response_format.json_schema"))
+}
+
+func extractOpenAIContent(value gjson.Result, c *content) {
+	if value.Type == gjson.String {
+		c.addText(value.Str)
+		return
+	}
+	for _, part := range value.Array() {
+		switch part.Get("type").String() {
+		case "text", "input_text", "output_text":
+			c.addText(part.Get("text").String())
+		case "image_url":
+			m := dataURL(part.Get("image_url.url").String())
+			m.detail = part.Get("image_url.detail").String()
+			c.addMedia(m)
+		case "input_image":
+			m := dataURL(part.Get("image_url").String())
+			m.detail = part.Get("detail").String()
+			c.addMedia(m)
+		case "file", "input_file":
+			data := part.Get("file.file_data").String()
+			if data == "" {
+				data = part.Get("file_data").String()
+			}
+			m := dataURL(data)
+			if m.mimeType == "" || m.mimeType == "image/*" {
+				m.mimeType = "application/pdf"
+			}
+			c.addMedia(m)
+		case "refusal":
+			c.addText(part.Get("refusal").String())
+		}
+	}
+}
+
+func extractResponses(root gjson.Result, c *content) {
+	c.addText(root.Get("instructions").String())
+	input := root.Get("input")
+	if input.Type == gjson.String {
+		c.messages++
+		c.addText(input.Str)
+	}
+	for _, item := range input.Array() {
+		switch item.Get("type").String() {
+		case "function_call", "custom_tool_call":
+			c.addText(item.Get("name").String())
+			c.addText(item.Get("arguments").String())
+			c.addText(item.Get("input").String())
+		case "function_call_output", "custom_tool_call_output":
+			extractOpenAIContent(item.Get("output"), c)
+		case "reasoning":
+			for _, summary := range item.Get("summary").Array() {
+				c.addText(summary.Get("text").String())
+			}
+		default:
+			c.messages++
+			extractOpenAIContent(item.Get("content"), c)
+		}
+	}
+	for _, tool := range root.Get("tools").Array() {
+		c.tools = append(c.tools, tool.Raw)
+	}
+	c.addRaw(root.Get("text.format.schema"))
+}
+
+func extractClaude(root gjson.Result, c *content) {
+	extractClaudeBlocks(root.Get("system"), c)
+	for _, msg := range root.Get("messages").Array() {
+		c.messages++
+		extractClaudeBlocks(msg.Get("content"), c)
+	}
+	for _, tool := range root.Get("tools").Array() {
+		c.tools = append(c.tools, tool.Raw)
+	}
+}
+
+func extractClaudeBlocks(value gjson.Result, c *content) {
+	if value.Type == gjson.String {
+		c.addText(value.Str)
+		return
+	}
+	for _, block := range value.Array() {
+		switch block.Get("type").String() {
+		case "text":
+			c.addText(block.Get("text").String())
+		case "thinking":
+			c.addText(block.Get("thinking").String())
+		case "tool_use", "server_tool_use":
+			c.addText(block.Get("name").String())
+			c.addRaw(block.Get("input"))
+		case "tool_result", "web_search_tool_result":
+			extractClaudeBlocks(block.Get("content"), c)
+		case "image":
+			c.addMedia(claudeSource(block.Get("source"), "image/*"))
+		case "document":
+			source := block.Get("source")
+			if source.Get("type").String() == "text" {
+				c.addText(source.Get("data").String())
+				continue
+			}
+			if source.Get("type").String() == "content" {
+				extractClaudeBlocks(source.Get("content"), c)
+				continue
+			}
+			c.addMedia(claudeSource(source, "application/pdf"))
+		}
+	}
+}
+
+func claudeSource(source gjson.Result, fallback string) media {
+	switch source.Get("type").String() {
+	case "base64":
+		return media{mimeType: source.Get("media_type").String(), data: source.Get("data").String()}
+	case "url":
+		m := media{mimeType: mimeFromURL(source.Get("url").String())}
+		if fallback != "image/*" {
+			m.mimeType = fallback
+		}
+		return m
+	}
+	return media{mimeType: fallback}
+}
+
+func extractGemini(root gjson.Result, c *content) {
+	for _, key := range []string{"systemInstruction", "system_instruction"} {
+		extractGeminiParts(root.Get(key+".parts"), c)
+	}
+	for _, msg := range root.Get("contents").Array() {
+		c.messages++
+		extractGeminiParts(msg.Get("parts"), c)
+	}
+	for _, tool := range root.Get("tools").Array() {
+		for _, key := range []string{"functionDeclarations", "function_declarations"} {
+			for _, decl := range tool.Get(key).Array() {
+				c.tools = append(c.tools, decl.Raw)
+			}
+		}
+	}
+	c.addRaw(root.Get("generationConfig.responseSchema"))
+	c.addRaw(root.Get("generationConfig.responseJsonSchema"))
+}
+
+func extractGeminiParts(parts gjson.Result, c *content) {
+	for _, part := range parts.Array() {
+		switch {
+		case part.Get("text").Exists():
+			c.addText(part.Get("text").String())
+		case part.Get("inlineData").Exists():
+			c.addMedia(media{mimeType: part.Get("inlineData.mimeType").String(), data: part.Get("inlineData.data").String()})
+		case part.Get("inline_data").Exists():
+			c.addMedia(media{mimeType: part.Get("inline_data.mime_type").String(), data: part.Get("inline_data.data").String()})
+		case part.Get("fileData").Exists():
+			c.addMedia(media{mimeType: part.Get("fileData.mimeType").String()})
+		case part.Get("functionCall").Exists():
+			c.addText(part.Get("functionCall.name").String())
+			c.addRaw(part.Get("functionCall.args"))
+		case part.Get("functionResponse").Exists():
+			c.addText(part.Get("functionResponse.name").String())
+			c.addRaw(part.Get("functionResponse.response"))
+		}
+	}
+}
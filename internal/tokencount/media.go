@@ -0,0 +1,89 @@
+package tokencount
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// maxHeaderBytes bounds how much of an inline image is decoded to read its size.
+const maxHeaderBytes = 64 << 10
+
+// imageSize returns the pixel dimensions of a base64-encoded image, or ok=false when
+// the image is remote or its format is not recognised.
+func imageSize(data string) (width, height int, ok bool) {
+	if data == "" {
+		return 0, 0, false
+	}
+	head := decodePrefix(data, maxHeaderBytes)
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(head)); err == nil {
+		return cfg.Width, cfg.Height, true
+	}
+	return webpSize(head)
+}
+
+// decodePrefix decodes up to n bytes of base64 data, tolerating either alphabet.
+func decodePrefix(data string, n int) []byte {
+	data = strings.TrimSpace(data)
+	// Decode in multiples of four characters so truncation does not break padding.
+	limit := min(len(data), (n/3+1)*4)
+	chunk := data[:limit-limit%4]
+	enc := base64.StdEncoding
+	if strings.ContainsAny(chunk, "-_") {
+		enc = base64.URLEncoding
+	}
+	out, err := io.ReadAll(io.LimitReader(base64.NewDecoder(enc, strings.NewReader(chunk)), int64(n)))
+	if err != nil && len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// webpSize reads the canvas size of a WebP image, which the standard library cannot decode.
+func webpSize(b []byte) (int, int, bool) {
+	if len(b) < 30 || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return 0, 0, false
+	}
+	switch string(b[12:16]) {
+	case "VP8X":
+		w := int(b[24]) | int(b[25])<<8 | int(b[26])<<16
+		h := int(b[27]) | int(b[28])<<8 | int(b[29])<<16
+		return w + 1, h + 1, true
+	case "VP8 ":
+		w := int(binary.LittleEndian.Uint16(b[26:28]) & 0x3fff)
+		h := int(binary.LittleEndian.Uint16(b[28:30]) & 0x3fff)
+		return w, h, true
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(b[21:25])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, true
+	}
+	return 0, 0, false
+}
+
+var pdfPagePattern = regexp.MustCompile(`/Type\s*/Page[^s]`)
+
+// pdfPages counts the pages of a base64-encoded PDF. Compressed object streams can
+// hide page objects, in which case the page count is estimated from the file size.
+func pdfPages(data string) int {
+	if data == "" {
+		return 0
+	}
+	raw := decodePrefix(data, len(data))
+	if pages := len(pdfPagePattern.FindAllIndex(raw, -1)); pages > 0 {
+		return pages
+	}
+	// Typical text PDFs average around 50 KB per page.
+	return max(1, len(raw)/(50<<10))
+}
+
+// decodedText returns the text of a base64-encoded text document.
+func decodedText(data string) string {
+	return string(decodePrefix(data, len(data)))
+}
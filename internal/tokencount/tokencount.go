@@ -0,0 +1,309 @@
+// Package tokencount estimates prompt tokens locally for every supported request format.
+//
+// Each model family has a profile: a base BPE encoding, a text scale correcting for the
+// family's own vocabulary, per-message and tool-definition overheads, and the image and
+// document pricing documented by the provider. Only OpenAI models share the exact
+// tokenizer; the other families are approximations whose expected error is reported in
+// Result.Accuracy so callers can state how far to trust the number.
+package tokencount
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// Family groups models that share a tokenizer.
+type Family string
+
+// Supported model families.
+const (
+	FamilyOpenAI  Family = "openai"
+	FamilyClaude  Family = "claude"
+	FamilyGemini  Family = "gemini"
+	FamilyQwen    Family = "qwen"
+	FamilyGLM     Family = "glm"
+	FamilyGeneric Family = "generic"
+)
+
+// Result is a local token estimate with its breakdown.
+type Result struct {
+	Tokens    int64  `json:"tokens"`
+	Family    Family `json:"family"`
+	Text      int64  `json:"text"`
+	Tools     int64  `json:"tools"`
+	Images    int64  `json:"images"`
+	Documents int64  `json:"documents"`
+	Overhead  int64  `json:"overhead"`
+	// Accuracy is the expected relative error, e.g. 0.1 for ±10%.
+	Accuracy float64 `json:"accuracy"`
+}
+
+// AccuracyLabel renders Accuracy as a percentage such as "±10%".
+func (r Result) AccuracyLabel() string {
+	return fmt.Sprintf("±%d%%", int(math.Round(r.Accuracy*100)))
+}
+
+// Claude framing constants, fitted to recorded count_tokens responses. Anthropic
+// documents a 346-token tool-use system prompt; the recorded counts come out 17
+// tokens lower because the definition is counted here in its JSON form, which
+// carries more punctuation than the rendered prompt.
+const (
+	claudeRequestOverhead = 1
+	claudeMessageOverhead = 4
+	claudeToolsPreamble   = 329
+)
+
+type profile struct {
+	encoding tokenizer.Encoding
+	// textScale converts base-encoding counts to the family's tokenizer.
+	textScale float64
+	// perRequest and perMessage cover chat template tokens (role markers, separators).
+	perRequest int64
+	perMessage int64
+	// toolsPreamble is the hidden system prompt added once when tools are declared;
+	// perTool covers the framing around each definition.
+	toolsPreamble int64
+	perTool       int64
+	image         func(m media) int64
+	// documentPage is charged per PDF page.
+	documentPage int64
+	accuracy     float64
+}
+
+var profiles = map[Family]profile{
+	// OpenAI chat format: 3 tokens per message plus 3 to prime the reply.
+	FamilyOpenAI: {
+		encoding:   tokenizer.O200kBase,
+		textScale:  1,
+		perRequest: 3,
+		perMessage: 3,
+		perTool:    8,
+		image:      openAIImageTokens,
+		// PDFs are sent as extracted text plus one image per page.
+		documentPage: 1000,
+		accuracy:     0.03,
+	},
+	// Claude's vocabulary yields roughly 10% more tokens than cl100k on mixed prose and
+	// code. The framing and tool constants are calibrated against the count_tokens
+	// responses recorded in testdata/calibration.json.
+	FamilyClaude: {
+		encoding:      tokenizer.Cl100kBase,
+		textScale:     1.1,
+		perRequest:    claudeRequestOverhead,
+		perMessage:    claudeMessageOverhead,
+		toolsPreamble: claudeToolsPreamble,
+		image:         claudeImageTokens,
+		// Each PDF page is billed as its text (1.5k-3k tokens) plus a page image.
+		documentPage: 2300,
+		accuracy:     0.1,
+	},
+	// Gemini uses a 256k SentencePiece vocabulary that tracks o200k closely; images and
+	// PDF pages cost a fixed 258 tokens per 768x768 tile or page.
+	FamilyGemini: {
+		encoding:     tokenizer.O200kBase,
+		textScale:    1,
+		perMessage:   1,
+		perTool:      4,
+		image:        geminiImageTokens,
+		documentPage: 258,
+		accuracy:     0.15,
+	},
+	// Qwen's 151k BPE vocabulary with ChatML framing (<|im_start|>role ... <|im_end|>).
+	FamilyQwen: {
+		encoding:      tokenizer.O200kBase,
+		textScale:     1.05,
+		perRequest:    3,
+		perMessage:    5,
+		perTool:       10,
+		toolsPreamble: 60,
+		image:         qwenImageTokens,
+		documentPage:  1500,
+		accuracy:      0.12,
+	},
+	// GLM-4 family: 151k vocabulary, [gMASK]<sop> prefix and <|role|> markers.
+	FamilyGLM: {
+		encoding:     tokenizer.O200kBase,
+		textScale:    1.05,
+		perRequest:   2,
+		perMessage:   3,
+		perTool:      10,
+		image:        fixedImageTokens(1600),
+		documentPage: 1500,
+		accuracy:     0.15,
+	},
+	FamilyGeneric: {
+		encoding:     tokenizer.O200kBase,
+		textScale:    1.1,
+		perRequest:   3,
+		perMessage:   4,
+		perTool:      10,
+		image:        fixedImageTokens(1000),
+		documentPage: 1500,
+		accuracy:     0.2,
+	},
+}
+
+// FamilyForModel maps a model name to its tokenizer family.
+func FamilyForModel(model string) Family {
+	m := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	switch {
+	case strings.Contains(m, "claude"), strings.HasPrefix(m, "kiro-"), strings.HasPrefix(m, "amazonq-"):
+		return FamilyClaude
+	case strings.Contains(m, "gemini"), strings.Contains(m, "gemma"):
+		return FamilyGemini
+	case strings.Contains(m, "qwen"), strings.HasPrefix(m, "qwq"):
+		return FamilyQwen
+	case strings.Contains(m, "glm"), strings.Contains(m, "chatglm"):
+		return FamilyGLM
+	case strings.HasPrefix(m, "gpt-"), strings.HasPrefix(m, "chatgpt"), strings.HasPrefix(m, "codex"),
+		strings.HasPrefix(m, "o1"), strings.HasPrefix(m, "o3"), strings.HasPrefix(m, "o4"):
+		return FamilyOpenAI
+	default:
+		return FamilyGeneric
+	}
+}
+
+var codecs sync.Map
+
+func codecFor(model string, family Family, p profile) (tokenizer.Codec, error) {
+	key := string(p.encoding)
+	// OpenAI models pick their exact encoding (cl100k for GPT-4, o200k for newer models).
+	if family == FamilyOpenAI {
+		m := strings.ToLower(model)
+		if strings.HasPrefix(m, "gpt-4") && !strings.HasPrefix(m, "gpt-4o") && !strings.HasPrefix(m, "gpt-4.1") || strings.HasPrefix(m, "gpt-3") {
+			key = string(tokenizer.Cl100kBase)
+		}
+	}
+	if cached, ok := codecs.Load(key); ok {
+		return cached.(tokenizer.Codec), nil
+	}
+	enc, err := tokenizer.Get(tokenizer.Encoding(key))
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := codecs.LoadOrStore(key, enc)
+	return actual.(tokenizer.Codec), nil
+}
+
+// Count estimates the prompt tokens of payload, a request in the given client format
+// ("openai", "openai-response", "claude", "gemini", "gemini-cli"), for model.
+// Requests without any content count as zero.
+func Count(model, format string, payload []byte) (Result, error) {
+	family := FamilyForModel(model)
+	p := profiles[family]
+	result := Result{Family: family, Accuracy: p.accuracy}
+	if len(payload) == 0 {
+		return result, nil
+	}
+	c := extract(strings.ToLower(strings.TrimSpace(format)), payload)
+	if c.empty() {
+		return result, nil
+	}
+	enc, err := codecFor(model, family, p)
+	if err != nil {
+		return result, fmt.Errorf("tokencount: load tokenizer: %w", err)
+	}
+
+	count := func(texts []string) (int64, error) {
+		if len(texts) == 0 {
+			return 0, nil
+		}
+		n, errCount := enc.Count(strings.Join(texts, "\n"))
+		return int64(math.Ceil(float64(n) * p.textScale)), errCount
+	}
+
+	texts := c.texts
+	for _, doc := range c.documents {
+		if strings.HasPrefix(doc.mimeType, "text/") {
+			texts = append(texts, decodedText(doc.data))
+		}
+	}
+	if result.Text, err = count(texts); err != nil {
+		return result, fmt.Errorf("tokencount: count text: %w", err)
+	}
+	if len(c.tools) > 0 {
+		if result.Tools, err = count(c.tools); err != nil {
+			return result, fmt.Errorf("tokencount: count tools: %w", err)
+		}
+		result.Tools += p.toolsPreamble + p.perTool*int64(len(c.tools))
+	}
+	for _, img := range c.images {
+		result.Images += p.image(img)
+	}
+	for _, doc := range c.documents {
+		if strings.HasPrefix(doc.mimeType, "text/") {
+			continue
+		}
+		result.Documents += p.documentPage * int64(max(1, pdfPages(doc.data)))
+	}
+	result.Overhead = p.perRequest + p.perMessage*int64(c.messages)
+	result.Tokens = result.Text + result.Tools + result.Images + result.Documents + result.Overhead
+	return result, nil
+}
+
+func fixedImageTokens(n int64) func(media) int64 {
+	return func(media) int64 { return n }
+}
+
+// claudeImageTokens follows Anthropic's (width*height)/750 rule after the image is
+// scaled to fit 1568px on its long edge; unknown sizes are charged the ~1600 maximum.
+func claudeImageTokens(m media) int64 {
+	w, h, ok := imageSize(m.data)
+	if !ok {
+		return 1600
+	}
+	if long := max(w, h); long > 1568 {
+		scale := 1568 / float64(long)
+		w, h = int(float64(w)*scale), int(float64(h)*scale)
+	}
+	return max(1, int64(math.Ceil(float64(w*h)/750)))
+}
+
+// openAIImageTokens prices images at 85 tokens plus 170 per 512px tile after fitting
+// within 2048x2048 and scaling the short side to 768px. Low detail costs 85.
+func openAIImageTokens(m media) int64 {
+	if m.detail == "low" {
+		return 85
+	}
+	w, h, ok := imageSize(m.data)
+	if !ok {
+		return 765
+	}
+	fw, fh := float64(w), float64(h)
+	if long := math.Max(fw, fh); long > 2048 {
+		fw, fh = fw*2048/long, fh*2048/long
+	}
+	if short := math.Min(fw, fh); short > 768 {
+		fw, fh = fw*768/short, fh*768/short
+	}
+	tiles := math.Ceil(fw/512) * math.Ceil(fh/512)
+	return 85 + 170*int64(tiles)
+}
+
+// geminiImageTokens charges 258 tokens for images up to 384px and 258 per 768x768
+// tile above that.
+func geminiImageTokens(m media) int64 {
+	w, h, ok := imageSize(m.data)
+	if !ok || (w <= 384 && h <= 384) {
+		return 258
+	}
+	tiles := math.Ceil(float64(w)/768) * math.Ceil(float64(h)/768)
+	return 258 * int64(tiles)
+}
+
+// qwenImageTokens counts one token per 28x28 patch, clamped to Qwen-VL's 4..16384 range.
+func qwenImageTokens(m media) int64 {
+	w, h, ok := imageSize(m.data)
+	if !ok {
+		return 1024
+	}
+	patches := int64(math.Ceil(float64(w)/28) * math.Ceil(float64(h)/28))
+	return min(max(patches, 4), 16384)
+}
@@ -0,0 +1,148 @@
+package toolemulation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/toolrepair"
+	"github.com/tidwall/gjson"
+)
+
+// Call is a tool invocation parsed out of model text.
+type Call struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// Parse splits model text into plain text and tool calls. Malformed blocks whose
+// JSON cannot be repaired are kept as text.
+func Parse(text string) (string, []Call) {
+	p := &StreamParser{}
+	out := p.Feed(text)
+	rest, calls := p.Flush()
+	out.Text += rest
+	out.Calls = append(out.Calls, calls...)
+	return strings.TrimSpace(out.Text), out.Calls
+}
+
+// Segment is the output of one StreamParser step.
+type Segment struct {
+	Text  string
+	Calls []Call
+}
+
+// StreamParser incrementally extracts tool calls from streamed model text. Text
+// that may be the start of a <tool_call> tag is held back until it can be decided.
+type StreamParser struct {
+	buf    strings.Builder
+	inCall bool
+	seq    int
+}
+
+// Feed consumes the next piece of model text.
+func (p *StreamParser) Feed(text string) Segment {
+	p.buf.WriteString(text)
+	var out Segment
+	var plain strings.Builder
+	for {
+		pending := p.buf.String()
+		if p.inCall {
+			end := strings.Index(pending, callClose)
+			if end < 0 {
+				break
+			}
+			body := pending[:end]
+			p.reset(pending[end+len(callClose):])
+			p.inCall = false
+			if call, ok := p.parseCall(body); ok {
+				out.Calls = append(out.Calls, call)
+			} else {
+				plain.WriteString(callOpen + body + callClose)
+			}
+			continue
+		}
+		start := strings.Index(pending, callOpen)
+		if start >= 0 {
+			plain.WriteString(pending[:start])
+			p.reset(pending[start+len(callOpen):])
+			p.inCall = true
+			continue
+		}
+		keep := partialPrefix(pending, callOpen)
+		plain.WriteString(pending[:len(pending)-keep])
+		p.reset(pending[len(pending)-keep:])
+		break
+	}
+	out.Text = trimCallWhitespace(plain.String(), len(out.Calls) > 0)
+	return out
+}
+
+// Flush returns any text still held back at the end of the stream. An
+// unterminated tool call is parsed if its JSON is complete, otherwise it is
+// returned as text.
+func (p *StreamParser) Flush() (string, []Call) {
+	pending := p.buf.String()
+	p.reset("")
+	if !p.inCall {
+		return pending, nil
+	}
+	p.inCall = false
+	if call, ok := p.parseCall(pending); ok {
+		return "", []Call{call}
+	}
+	return callOpen + pending, nil
+}
+
+func (p *StreamParser) reset(rest string) {
+	p.buf.Reset()
+	p.buf.WriteString(rest)
+}
+
+func (p *StreamParser) parseCall(body string) (Call, bool) {
+	body = strings.TrimSpace(body)
+	body = strings.TrimPrefix(body, "```json")
+	body = strings.TrimPrefix(body, "```")
+	body = strings.TrimSpace(strings.TrimSuffix(body, "```"))
+	fixed, _, err := toolrepair.ValidateObject(gjson.Result{}, body)
+	if err != nil {
+		return Call{}, false
+	}
+	parsed := gjson.Parse(fixed)
+	name := strings.TrimSpace(parsed.Get("name").String())
+	if name == "" {
+		return Call{}, false
+	}
+	args := parsed.Get("arguments")
+	if !args.Exists() {
+		args = parsed.Get("parameters")
+	}
+	arguments := "{}"
+	switch {
+	case args.IsObject():
+		arguments = args.Raw
+	case args.Type == gjson.String && gjson.Valid(args.String()):
+		arguments = args.String()
+	}
+	p.seq++
+	return Call{ID: fmt.Sprintf("call_emu_%d", p.seq), Name: name, Arguments: arguments}, true
+}
+
+// partialPrefix returns the length of the longest suffix of s that is a proper
+// prefix of tag.
+func partialPrefix(s, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if n <= len(s) && strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// trimCallWhitespace drops the blank padding models put between call blocks.
+func trimCallWhitespace(text string, hadCalls bool) string {
+	if hadCalls && strings.TrimSpace(text) == "" {
+		return ""
+	}
+	return text
+}
@@ -0,0 +1,187 @@
+package toolemulation
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ApplyToResponse parses tool calls out of the message content of a non-streaming
+// OpenAI chat completion and moves them into message.tool_calls.
+func ApplyToResponse(resp []byte) []byte {
+	out := resp
+	gjson.GetBytes(resp, "choices").ForEach(func(idx, choice gjson.Result) bool {
+		content := choice.Get("message.content")
+		if content.Type != gjson.String || !strings.Contains(content.String(), callOpen) {
+			return true
+		}
+		text, calls := Parse(content.String())
+		if len(calls) == 0 {
+			return true
+		}
+		prefix := "choices." + idx.String()
+		if text == "" {
+			out, _ = sjson.SetRawBytes(out, prefix+".message.content", []byte("null"))
+		} else {
+			out, _ = sjson.SetBytes(out, prefix+".message.content", text)
+		}
+		for i, call := range calls {
+			out, _ = sjson.SetRawBytes(out, prefix+".message.tool_calls.-1", []byte(toolCallJSON(i, call, false)))
+		}
+		out, _ = sjson.SetBytes(out, prefix+".finish_reason", "tool_calls")
+		return true
+	})
+	return out
+}
+
+// StreamConverter rewrites a stream of OpenAI chat completion chunks, each line
+// optionally prefixed with "data: ", so tool calls written as text are emitted as
+// tool_calls deltas.
+type StreamConverter struct {
+	parser   StreamParser
+	calls    int
+	template string
+	finished bool
+}
+
+// NewStreamConverter returns a converter for a single response stream.
+func NewStreamConverter() *StreamConverter {
+	return &StreamConverter{}
+}
+
+// Process converts one chunk and returns the chunks to forward in its place.
+func (c *StreamConverter) Process(chunk []byte) [][]byte {
+	payload, prefixed := splitData(chunk)
+	if bytes.Equal(bytes.TrimSpace(payload), []byte("[DONE]")) {
+		return append(c.finish(prefixed), chunk)
+	}
+	if !gjson.ValidBytes(payload) || !gjson.GetBytes(payload, "choices").Exists() {
+		return [][]byte{chunk}
+	}
+	c.template = string(payload)
+
+	var out [][]byte
+	current := payload
+	content := gjson.GetBytes(payload, "choices.0.delta.content")
+	if content.Type == gjson.String {
+		seg := c.parser.Feed(content.String())
+		if seg.Text == "" {
+			current, _ = sjson.DeleteBytes(current, "choices.0.delta.content")
+		} else {
+			current, _ = sjson.SetBytes(current, "choices.0.delta.content", seg.Text)
+		}
+		if len(seg.Calls) > 0 {
+			if hasDelta(current) {
+				out = append(out, withData(stripFinish(current), prefixed))
+			}
+			out = append(out, c.callChunks(seg.Calls, prefixed)...)
+			current, _ = sjson.DeleteBytes(current, "choices.0.delta.content")
+		}
+	}
+
+	if finish := gjson.GetBytes(payload, "choices.0.finish_reason"); finish.Exists() && finish.Type != gjson.Null {
+		if hasDelta(current) {
+			out = append(out, withData(stripFinish(current), prefixed))
+			current, _ = sjson.SetRawBytes(current, "choices.0.delta", []byte("{}"))
+		}
+		out = append(out, c.flush(prefixed)...)
+		if c.calls > 0 {
+			current, _ = sjson.SetBytes(current, "choices.0.finish_reason", "tool_calls")
+		}
+		c.finished = true
+		return append(out, withData(current, prefixed))
+	}
+	if hasDelta(current) || len(out) == 0 && !gjson.GetBytes(payload, "choices.0.delta.content").Exists() {
+		out = append(out, withData(current, prefixed))
+	}
+	return out
+}
+
+// Flush emits any text or tool call still held back when the upstream stream
+// ends without a finish_reason or [DONE] marker.
+func (c *StreamConverter) Flush() [][]byte {
+	return c.finish(true)
+}
+
+func (c *StreamConverter) finish(prefixed bool) [][]byte {
+	if c.finished {
+		return nil
+	}
+	c.finished = true
+	out := c.flush(prefixed)
+	if c.calls > 0 && c.template != "" {
+		final := c.base()
+		final, _ = sjson.SetBytes(final, "choices.0.finish_reason", "tool_calls")
+		out = append(out, withData(final, prefixed))
+	}
+	return out
+}
+
+func (c *StreamConverter) flush(prefixed bool) [][]byte {
+	text, calls := c.parser.Flush()
+	var out [][]byte
+	if text != "" && c.template != "" {
+		chunk, _ := sjson.SetBytes(c.base(), "choices.0.delta.content", text)
+		out = append(out, withData(chunk, prefixed))
+	}
+	return append(out, c.callChunks(calls, prefixed)...)
+}
+
+func (c *StreamConverter) callChunks(calls []Call, prefixed bool) [][]byte {
+	out := make([][]byte, 0, len(calls))
+	for _, call := range calls {
+		chunk, _ := sjson.SetRawBytes(c.base(), "choices.0.delta.tool_calls", []byte("["+toolCallJSON(c.calls, call, true)+"]"))
+		out = append(out, withData(chunk, prefixed))
+		c.calls++
+	}
+	return out
+}
+
+// base returns an empty chunk carrying the identity fields of the last upstream chunk.
+func (c *StreamConverter) base() []byte {
+	chunk := []byte(`{"choices":[{"index":0,"delta":{},"finish_reason":null}]}`)
+	for _, key := range []string{"id", "object", "created", "model"} {
+		if v := gjson.Get(c.template, key); v.Exists() {
+			chunk, _ = sjson.SetRawBytes(chunk, key, []byte(v.Raw))
+		}
+	}
+	return chunk
+}
+
+func toolCallJSON(index int, call Call, stream bool) string {
+	out := `{"type":"function"}`
+	if stream {
+		out, _ = sjson.Set(out, "index", index)
+	}
+	out, _ = sjson.Set(out, "id", call.ID)
+	out, _ = sjson.Set(out, "function.name", call.Name)
+	out, _ = sjson.Set(out, "function.arguments", call.Arguments)
+	return out
+}
+
+func hasDelta(chunk []byte) bool {
+	delta := gjson.GetBytes(chunk, "choices.0.delta")
+	return delta.Exists() && len(delta.Map()) > 0
+}
+
+func stripFinish(chunk []byte) []byte {
+	out, _ := sjson.SetRawBytes(chunk, "choices.0.finish_reason", []byte("null"))
+	return out
+}
+
+func splitData(chunk []byte) ([]byte, bool) {
+	trimmed := bytes.TrimSpace(chunk)
+	if bytes.HasPrefix(trimmed, []byte("data:")) {
+		return bytes.TrimSpace(trimmed[len("data:"):]), true
+	}
+	return trimmed, false
+}
+
+func withData(payload []byte, prefixed bool) []byte {
+	if !prefixed {
+		return payload
+	}
+	return append([]byte("data: "), payload...)
+}
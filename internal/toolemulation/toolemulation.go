@@ -0,0 +1,204 @@
+// Package toolemulation adds function calling to backends that only exchange
+// text. Declared tools and prior tool calls and results are rendered into a
+// tagged prompt protocol, and tool invocations are parsed back out of the model's
+// text, including while streaming. The layer works on OpenAI chat completion
+// payloads, the intermediate format of the text-only executors, so the regular
+// response translators turn the parsed calls into tool_use, tool_calls or
+// function_call events for every client dialect.
+package toolemulation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Protocol tags wrapping tool invocations and tool results in the prompt.
+const (
+	callOpen    = "<tool_call>"
+	callClose   = "</tool_call>"
+	resultOpen  = "<tool_result"
+	resultClose = "</tool_result>"
+)
+
+// maxResultChars bounds how much of a single tool result is rendered into the prompt.
+const maxResultChars = 16000
+
+// Enabled reports whether a tool emulation rule opts provider and model in.
+func Enabled(rules []config.ToolEmulationRule, provider, model string) bool {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	for _, rule := range rules {
+		if rule.Provider != provider {
+			continue
+		}
+		if util.MatchAnyWildcardFold(rule.Models, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasTools reports whether an OpenAI chat request declares function tools or
+// carries tool calls in its history.
+func HasTools(payload []byte) bool {
+	if len(gjson.GetBytes(payload, "tools").Array()) > 0 {
+		return true
+	}
+	found := false
+	gjson.GetBytes(payload, "messages").ForEach(func(_, msg gjson.Result) bool {
+		found = msg.Get("role").String() == "tool" || len(msg.Get("tool_calls").Array()) > 0
+		return !found
+	})
+	return found
+}
+
+// RewriteRequest turns an OpenAI chat request with tools into a text-only request:
+// the tool protocol and schemas are added to the system prompt, assistant tool
+// calls become <tool_call> blocks and tool messages become <tool_result> blocks in
+// user turns.
+func RewriteRequest(payload []byte) []byte {
+	tools := gjson.GetBytes(payload, "tools").Array()
+	choice := gjson.GetBytes(payload, "tool_choice")
+	if choice.String() == "none" {
+		tools = nil
+	}
+
+	names := make(map[string]string)
+	messages := make([]string, 0, len(gjson.GetBytes(payload, "messages").Array())+1)
+	var pendingResults []string
+	flushResults := func() {
+		if len(pendingResults) == 0 {
+			return
+		}
+		msg, _ := sjson.Set(`{"role":"user"}`, "content", strings.Join(pendingResults, "\n\n"))
+		messages = append(messages, msg)
+		pendingResults = nil
+	}
+	gjson.GetBytes(payload, "messages").ForEach(func(_, msg gjson.Result) bool {
+		switch msg.Get("role").String() {
+		case "tool":
+			id := msg.Get("tool_call_id").String()
+			pendingResults = append(pendingResults, FormatToolResult(id, names[id], contentText(msg.Get("content"))))
+			return true
+		case "assistant":
+			flushResults()
+			calls := msg.Get("tool_calls").Array()
+			if len(calls) == 0 {
+				messages = append(messages, msg.Raw)
+				return true
+			}
+			parts := make([]string, 0, len(calls)+1)
+			if text := contentText(msg.Get("content")); text != "" {
+				parts = append(parts, text)
+			}
+			for _, call := range calls {
+				name := call.Get("function.name").String()
+				names[call.Get("id").String()] = name
+				parts = append(parts, FormatToolCall(name, call.Get("function.arguments").String()))
+			}
+			rewritten, _ := sjson.Delete(msg.Raw, "tool_calls")
+			rewritten, _ = sjson.Set(rewritten, "content", strings.Join(parts, "\n"))
+			messages = append(messages, rewritten)
+		default:
+			flushResults()
+			messages = append(messages, msg.Raw)
+		}
+		return true
+	})
+	flushResults()
+
+	if len(tools) > 0 {
+		protocol := protocolPrompt(tools, choice)
+		if len(messages) > 0 && gjson.Get(messages[0], "role").String() == "system" {
+			text := contentText(gjson.Get(messages[0], "content"))
+			messages[0], _ = sjson.Set(messages[0], "content", strings.TrimSpace(text+"\n\n"+protocol))
+		} else {
+			system, _ := sjson.Set(`{"role":"system"}`, "content", protocol)
+			messages = append([]string{system}, messages...)
+		}
+	}
+
+	out, _ := sjson.SetRawBytes(payload, "messages", []byte("["+strings.Join(messages, ",")+"]"))
+	for _, key := range []string{"tools", "tool_choice", "parallel_tool_calls"} {
+		out, _ = sjson.DeleteBytes(out, key)
+	}
+	return out
+}
+
+// FormatToolCall renders a tool invocation in the prompt protocol.
+func FormatToolCall(name, arguments string) string {
+	args := strings.TrimSpace(arguments)
+	if args == "" || !gjson.Valid(args) {
+		args = "{}"
+	}
+	call, _ := sjson.Set(`{}`, "name", name)
+	call, _ = sjson.SetRaw(call, "arguments", args)
+	return callOpen + "\n" + call + "\n" + callClose
+}
+
+// FormatToolResult renders the result of a tool invocation in the prompt protocol.
+func FormatToolResult(callID, name, content string) string {
+	if len(content) > maxResultChars {
+		content = content[:maxResultChars] + "\n... [truncated]"
+	}
+	var attrs strings.Builder
+	if name != "" {
+		fmt.Fprintf(&attrs, " name=%q", name)
+	}
+	if callID != "" {
+		fmt.Fprintf(&attrs, " id=%q", callID)
+	}
+	return resultOpen + attrs.String() + ">\n" + content + "\n" + resultClose
+}
+
+func protocolPrompt(tools []gjson.Result, choice gjson.Result) string {
+	var b strings.Builder
+	b.WriteString("# Tools\n\n")
+	b.WriteString("You can call tools. To call a tool, write a block in exactly this form:\n")
+	b.WriteString(callOpen + "\n{\"name\": \"<tool name>\", \"arguments\": {<arguments as a JSON object>}}\n" + callClose + "\n")
+	b.WriteString("You may write several blocks to call several tools. After your tool calls, stop and wait: ")
+	b.WriteString("the results are returned in " + resultOpen + "> blocks in the next message. ")
+	b.WriteString("Never write " + resultOpen + "> blocks yourself and only call the tools listed below.\n")
+	switch {
+	case choice.String() == "required":
+		b.WriteString("You must call at least one tool in your reply.\n")
+	case choice.Get("function.name").String() != "":
+		fmt.Fprintf(&b, "You must call the tool %q in your reply.\n", choice.Get("function.name").String())
+	}
+	b.WriteString("\nAvailable tools:\n")
+	for _, tool := range tools {
+		fn := tool.Get("function")
+		if !fn.Exists() {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n", fn.Get("name").String())
+		if desc := strings.TrimSpace(fn.Get("description").String()); desc != "" {
+			b.WriteString(desc)
+			b.WriteString("\n")
+		}
+		if params := fn.Get("parameters"); params.IsObject() {
+			b.WriteString("Parameters (JSON schema): ")
+			b.WriteString(util.CleanJSONSchemaForGemini(params.Raw))
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func contentText(content gjson.Result) string {
+	if !content.IsArray() {
+		return content.String()
+	}
+	var parts []string
+	content.ForEach(func(_, part gjson.Result) bool {
+		if text := part.Get("text").String(); text != "" {
+			parts = append(parts, text)
+		}
+		return true
+	})
+	return strings.Join(parts, "\n")
+}
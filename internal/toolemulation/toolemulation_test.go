@@ -0,0 +1,115 @@
+package toolemulation
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestEnabled(t *testing.T) {
+	rules := []config.ToolEmulationRule{{Provider: "gitlab", Models: []string{"duo-chat-*"}}}
+	if !Enabled(rules, "GitLab", "duo-chat-sonnet") {
+		t.Fatal("expected rule to match")
+	}
+	if Enabled(rules, "gitlab", "code-suggestions") || Enabled(rules, "cursor", "duo-chat-sonnet") {
+		t.Fatal("unexpected match")
+	}
+	if !Enabled([]config.ToolEmulationRule{{Provider: "cursor"}}, "cursor", "anything") {
+		t.Fatal("rule without models should match every model")
+	}
+}
+
+func TestRewriteRequest(t *testing.T) {
+	req := `{"model":"m","tool_choice":"required","parallel_tool_calls":true,
+		"tools":[{"type":"function","function":{"name":"read_file","description":"Read a file.","parameters":{"type":"object","properties":{"path":{"type":"string"}},"required":["path"]}}}],
+		"messages":[
+			{"role":"system","content":"Be terse."},
+			{"role":"user","content":"open a.txt"},
+			{"role":"assistant","content":null,"tool_calls":[{"id":"c1","type":"function","function":{"name":"read_file","arguments":"{\"path\":\"a.txt\"}"}}]},
+			{"role":"tool","tool_call_id":"c1","content":"hello"},
+			{"role":"user","content":"thanks"}
+		]}`
+	out := RewriteRequest([]byte(req))
+	for _, key := range []string{"tools", "tool_choice", "parallel_tool_calls"} {
+		if gjson.GetBytes(out, key).Exists() {
+			t.Fatalf("%s should be removed: %s", key, out)
+		}
+	}
+	msgs := gjson.GetBytes(out, "messages").Array()
+	if len(msgs) != 5 {
+		t.Fatalf("messages = %d: %s", len(msgs), out)
+	}
+	system := msgs[0].Get("content").String()
+	if !strings.HasPrefix(system, "Be terse.") || !strings.Contains(system, "## read_file") || !strings.Contains(system, "must call at least one tool") {
+		t.Fatalf("system prompt = %q", system)
+	}
+	if got := msgs[2].Get("content").String(); !strings.Contains(got, `{"name":"read_file","arguments":{"path":"a.txt"}}`) || msgs[2].Get("tool_calls").Exists() {
+		t.Fatalf("assistant turn = %s", msgs[2].Raw)
+	}
+	if msgs[3].Get("role").String() != "user" || !strings.Contains(msgs[3].Get("content").String(), `<tool_result name="read_file" id="c1">`) {
+		t.Fatalf("tool result turn = %s", msgs[3].Raw)
+	}
+}
+
+func TestParse(t *testing.T) {
+	text, calls := Parse("Let me look.\n<tool_call>\n{\"name\": \"read_file\", \"arguments\": {'path': 'a.txt',}}\n</tool_call>\n<tool_call>{\"name\":\"ls\"}")
+	if text != "Let me look." {
+		t.Fatalf("text = %q", text)
+	}
+	if len(calls) != 2 || calls[0].Name != "read_file" || calls[0].Arguments != `{"path": "a.txt"}` || calls[1].Name != "ls" || calls[1].Arguments != "{}" {
+		t.Fatalf("calls = %+v", calls)
+	}
+	text, calls = Parse("<tool_call>not json</tool_call>")
+	if len(calls) != 0 || text != "<tool_call>not json</tool_call>" {
+		t.Fatalf("malformed block: %q %+v", text, calls)
+	}
+}
+
+func TestApplyToResponse(t *testing.T) {
+	resp := `{"id":"x","choices":[{"index":0,"message":{"role":"assistant","content":"<tool_call>{\"name\":\"ls\",\"arguments\":{\"dir\":\".\"}}</tool_call>"},"finish_reason":"stop"}]}`
+	out := ApplyToResponse([]byte(resp))
+	if gjson.GetBytes(out, "choices.0.message.content").Type != gjson.Null {
+		t.Fatalf("content should be null: %s", out)
+	}
+	if gjson.GetBytes(out, "choices.0.message.tool_calls.0.function.name").String() != "ls" ||
+		gjson.GetBytes(out, "choices.0.finish_reason").String() != "tool_calls" {
+		t.Fatalf("unexpected response: %s", out)
+	}
+}
+
+func TestStreamConverter(t *testing.T) {
+	pieces := []string{"Sure. <to", "ol_call>{\"name\":\"ls\",", "\"arguments\":{}}</tool", "_call>"}
+	conv := NewStreamConverter()
+	var out []string
+	for _, piece := range pieces {
+		chunk := `data: {"id":"x","model":"m","choices":[{"index":0,"delta":{"content":` + strconv.Quote(piece) + `},"finish_reason":null}]}`
+		for _, c := range conv.Process([]byte(chunk)) {
+			out = append(out, string(c))
+		}
+	}
+	for _, c := range conv.Process([]byte(`data: {"id":"x","model":"m","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`)) {
+		out = append(out, string(c))
+	}
+	var text strings.Builder
+	var names []string
+	finish := ""
+	for _, c := range out {
+		if !strings.HasPrefix(c, "data: ") {
+			t.Fatalf("chunk lost its prefix: %q", c)
+		}
+		payload := gjson.Parse(strings.TrimPrefix(c, "data: "))
+		text.WriteString(payload.Get("choices.0.delta.content").String())
+		if name := payload.Get("choices.0.delta.tool_calls.0.function.name").String(); name != "" {
+			names = append(names, name)
+		}
+		if f := payload.Get("choices.0.finish_reason").String(); f != "" {
+			finish = f
+		}
+	}
+	if text.String() != "Sure. " || len(names) != 1 || names[0] != "ls" || finish != "tool_calls" {
+		t.Fatalf("text=%q names=%v finish=%q chunks=%v", text.String(), names, finish, out)
+	}
+}
@@ -0,0 +1,410 @@
+package toolrepair
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Repair labels reported in Call.Fixes.
+const (
+	fixEmptyArguments  = "empty-arguments"
+	fixCodeFence       = "code-fence"
+	fixLeadingText     = "leading-text"
+	fixTrailingText    = "trailing-text"
+	fixSingleQuotes    = "single-quotes"
+	fixUnquotedKeys    = "unquoted-keys"
+	fixUnquotedValues  = "unquoted-values"
+	fixLiterals        = "literals"
+	fixTrailingCommas  = "trailing-commas"
+	fixControlChars    = "control-characters"
+	fixInvalidEscapes  = "invalid-escapes"
+	fixComments        = "comments"
+	fixBrackets        = "mismatched-brackets"
+	fixStrayCharacters = "stray-characters"
+	fixTruncated       = "truncated"
+	fixDoubleEncoded   = "double-encoded"
+)
+
+var errNotObject = errors.New("arguments must be a JSON object")
+
+// repairJSON returns arguments as a JSON object, repairing the defects models
+// commonly produce: code fences, surrounding prose, single quotes, unquoted keys,
+// Python literals, trailing commas, raw control characters, comments and output
+// truncated before every string and bracket was closed.
+func repairJSON(raw string) (string, []string, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "{}", []string{fixEmptyArguments}, nil
+	}
+	if json.Valid([]byte(s)) {
+		return objectArguments(s, nil)
+	}
+	var fixes []string
+	if inner, ok := stripCodeFence(s); ok {
+		fixes = append(fixes, fixCodeFence)
+		s = inner
+		if json.Valid([]byte(s)) {
+			return objectArguments(s, fixes)
+		}
+	}
+	start := strings.IndexAny(s, "{[")
+	if start < 0 {
+		return "", fixes, errors.New("no JSON object found")
+	}
+	if start > 0 {
+		fixes = append(fixes, fixLeadingText)
+		s = s[start:]
+	}
+	sc := &jsonScanner{src: s}
+	out := sc.run()
+	for _, fix := range sc.fixes {
+		if !containsString(fixes, fix) {
+			fixes = append(fixes, fix)
+		}
+	}
+	if !json.Valid(out) {
+		return "", fixes, errors.New("unrecoverable JSON syntax")
+	}
+	return objectArguments(string(out), fixes)
+}
+
+// objectArguments accepts s when it is a JSON object, unwrapping objects that were
+// encoded a second time as a JSON string.
+func objectArguments(s string, fixes []string) (string, []string, error) {
+	switch s[0] {
+	case '{':
+		return s, fixes, nil
+	case '"':
+		var inner string
+		if err := json.Unmarshal([]byte(s), &inner); err == nil {
+			inner = strings.TrimSpace(inner)
+			if strings.HasPrefix(inner, "{") {
+				repaired, innerFixes, err := repairJSON(inner)
+				if err == nil {
+					return repaired, append(append(fixes, fixDoubleEncoded), innerFixes...), nil
+				}
+			}
+		}
+	}
+	return "", fixes, errNotObject
+}
+
+func stripCodeFence(s string) (string, bool) {
+	if !strings.HasPrefix(s, "```") {
+		return s, false
+	}
+	body := s[3:]
+	if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+		body = body[nl+1:]
+	} else {
+		return s, false
+	}
+	if end := strings.LastIndex(body, "```"); end >= 0 {
+		body = body[:end]
+	}
+	return strings.TrimSpace(body), true
+}
+
+// jsonScanner rewrites almost-JSON into JSON in a single pass.
+type jsonScanner struct {
+	src   string
+	out   []byte
+	stack []byte
+	fixes []string
+	// keyOpen is set when the last string written in an object was a key.
+	keyOpen bool
+}
+
+func (s *jsonScanner) fix(label string) {
+	if !containsString(s.fixes, label) {
+		s.fixes = append(s.fixes, label)
+	}
+}
+
+func (s *jsonScanner) run() []byte {
+	src := s.src
+	started := false
+	i := 0
+	for i < len(src) {
+		if started && len(s.stack) == 0 {
+			if strings.TrimSpace(src[i:]) != "" {
+				s.fix(fixTrailingText)
+			}
+			break
+		}
+		c := src[i]
+		switch {
+		case c == '"' || c == '\'':
+			i = s.readString(i)
+		case c == '{' || c == '[':
+			started = true
+			s.stack = append(s.stack, closerFor(c))
+			s.out = append(s.out, c)
+			s.keyOpen = false
+			i++
+		case c == '}' || c == ']':
+			s.trimTrailingComma()
+			want := s.stack[len(s.stack)-1]
+			s.stack = s.stack[:len(s.stack)-1]
+			if want != c {
+				s.fix(fixBrackets)
+			}
+			s.out = append(s.out, want)
+			i++
+		case c == ',':
+			if last := s.lastNonSpace(); last == ',' || last == '{' || last == '[' {
+				s.fix(fixTrailingCommas)
+			} else {
+				s.out = append(s.out, c)
+			}
+			i++
+		case c == ':':
+			s.out = append(s.out, c)
+			s.keyOpen = false
+			i++
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			s.out = append(s.out, c)
+			i++
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			s.fix(fixComments)
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end
+			}
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			s.fix(fixComments)
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end + 4
+			}
+		default:
+			i = s.readBare(i)
+		}
+	}
+	if len(s.stack) > 0 {
+		s.fix(fixTruncated)
+		s.closeDangling()
+		for len(s.stack) > 0 {
+			s.trimTrailingComma()
+			s.out = append(s.out, s.stack[len(s.stack)-1])
+			s.stack = s.stack[:len(s.stack)-1]
+		}
+	}
+	return s.out
+}
+
+// readString copies the string starting at src[i], converting it to a double-quoted
+// JSON string, and returns the index after it.
+func (s *jsonScanner) readString(i int) int {
+	src := s.src
+	quote := src[i]
+	if quote == '\'' {
+		s.fix(fixSingleQuotes)
+	}
+	isKey := s.inObject() && s.keyPosition()
+	s.out = append(s.out, '"')
+	i++
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(src) {
+				i++
+				continue
+			}
+			next := src[i+1]
+			switch next {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				s.out = append(s.out, c, next)
+			case 'u':
+				if i+6 > len(src) || !isHex(src[i+2:i+6]) {
+					s.fix(fixInvalidEscapes)
+					s.out = append(s.out, '\\', '\\', 'u')
+				} else {
+					s.out = append(s.out, src[i:i+6]...)
+					i += 4
+				}
+			case '\'':
+				s.out = append(s.out, '\'')
+			default:
+				s.fix(fixInvalidEscapes)
+				s.out = append(s.out, '\\', '\\', next)
+			}
+			i += 2
+		case c == quote:
+			s.out = append(s.out, '"')
+			s.keyOpen = isKey
+			return i + 1
+		case c == '"':
+			s.out = append(s.out, '\\', '"')
+			i++
+		case c < 0x20:
+			s.fix(fixControlChars)
+			s.out = append(s.out, controlEscape(c)...)
+			i++
+		default:
+			s.out = append(s.out, c)
+			i++
+		}
+	}
+	// The string was cut off; close it so the caller can close the brackets.
+	s.out = append(s.out, '"')
+	s.keyOpen = isKey
+	return i
+}
+
+// readBare copies an unquoted token: a number, a literal, an unquoted key or an
+// unquoted string value.
+func (s *jsonScanner) readBare(i int) int {
+	src := s.src
+	j := i
+	for j < len(src) && isBareChar(src[j]) {
+		j++
+	}
+	if j == i {
+		s.fix(fixStrayCharacters)
+		return i + 1
+	}
+	token := src[i:j]
+	k := j
+	for k < len(src) && (src[k] == ' ' || src[k] == '\t') {
+		k++
+	}
+	if s.inObject() && s.keyPosition() && (k == len(src) || src[k] == ':') {
+		s.fix(fixUnquotedKeys)
+		s.out = appendQuoted(s.out, token)
+		s.keyOpen = true
+		return j
+	}
+	if lit, ok := jsonLiteral(token); ok {
+		if lit != token {
+			s.fix(fixLiterals)
+		}
+		s.out = append(s.out, lit...)
+		return j
+	}
+	if isNumber(token) {
+		s.out = append(s.out, token...)
+		return j
+	}
+	if j == len(src) && len(token) > 0 && (token[0] == '-' || (token[0] >= '0' && token[0] <= '9')) {
+		if trimmed := strings.TrimRight(token, ".eE+-"); isNumber(trimmed) {
+			s.out = append(s.out, trimmed...)
+			return j
+		}
+	}
+	s.fix(fixUnquotedValues)
+	s.out = appendQuoted(s.out, token)
+	return j
+}
+
+// closeDangling completes a member left half-written by truncation.
+func (s *jsonScanner) closeDangling() {
+	s.out = bytes.TrimRight(s.out, " \t\r\n")
+	switch s.lastNonSpace() {
+	case ',':
+		s.out = s.out[:len(s.out)-1]
+	case ':':
+		s.out = append(s.out, "null"...)
+	case '"':
+		if s.inObject() && s.keyOpen {
+			s.out = append(s.out, ":null"...)
+		}
+	}
+}
+
+func (s *jsonScanner) trimTrailingComma() {
+	trimmed := bytes.TrimRight(s.out, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[len(trimmed)-1] == ',' {
+		s.fix(fixTrailingCommas)
+		s.out = trimmed[:len(trimmed)-1]
+	}
+}
+
+func (s *jsonScanner) lastNonSpace() byte {
+	trimmed := bytes.TrimRight(s.out, " \t\r\n")
+	if len(trimmed) == 0 {
+		return 0
+	}
+	return trimmed[len(trimmed)-1]
+}
+
+func (s *jsonScanner) inObject() bool {
+	return len(s.stack) > 0 && s.stack[len(s.stack)-1] == '}'
+}
+
+// keyPosition reports whether the next token in the current object is a member name.
+func (s *jsonScanner) keyPosition() bool {
+	last := s.lastNonSpace()
+	return last == '{' || last == ','
+}
+
+func closerFor(c byte) byte {
+	if c == '{' {
+		return '}'
+	}
+	return ']'
+}
+
+func jsonLiteral(token string) (string, bool) {
+	switch token {
+	case "true", "false", "null":
+		return token, true
+	case "True", "TRUE":
+		return "true", true
+	case "False", "FALSE":
+		return "false", true
+	case "None", "NULL", "Null", "nil", "undefined", "NaN", "Infinity", "-Infinity":
+		return "null", true
+	}
+	return "", false
+}
+
+func isNumber(token string) bool {
+	if token == "" {
+		return false
+	}
+	if c := token[0]; c != '-' && (c < '0' || c > '9') {
+		return false
+	}
+	return json.Valid([]byte(token))
+}
+
+func isBareChar(c byte) bool {
+	return c == '_' || c == '-' || c == '+' || c == '.' || c == '$' ||
+		(c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
+
+func controlEscape(c byte) []byte {
+	switch c {
+	case '\n':
+		return []byte(`\n`)
+	case '\r':
+		return []byte(`\r`)
+	case '\t':
+		return []byte(`\t`)
+	}
+	const hex = "0123456789abcdef"
+	return []byte{'\\', 'u', '0', '0', hex[c>>4], hex[c&0xf]}
+}
+
+func appendQuoted(dst []byte, s string) []byte {
+	encoded, _ := json.Marshal(s)
+	return append(dst, encoded...)
+}
@@ -0,0 +1,83 @@
+package toolrepair
+
+import (
+	"strings"
+	"unicode"
+)
+
+// namespacePrefixes are prefixes some models put in front of declared tool names.
+var namespacePrefixes = []string{"functions.", "function.", "tools.", "tool.", "default_api.", "default_api:"}
+
+// resolveName maps an emitted tool name onto a declared one. Exact matches win,
+// then matches ignoring case, separators and namespace prefixes, then the unique
+// declared name within the configured edit distance.
+func (r *Repairer) resolveName(name string) (string, bool) {
+	if _, ok := r.tools[name]; ok {
+		return name, true
+	}
+	trimmed := strings.TrimSpace(name)
+	for _, prefix := range namespacePrefixes {
+		if len(trimmed) > len(prefix) && strings.EqualFold(trimmed[:len(prefix)], prefix) {
+			trimmed = trimmed[len(prefix):]
+			break
+		}
+	}
+	if _, ok := r.tools[trimmed]; ok {
+		return trimmed, true
+	}
+	key := canonicalName(trimmed)
+	if key == "" {
+		return "", false
+	}
+	for _, declared := range r.names {
+		if canonicalName(declared) == key {
+			return declared, true
+		}
+	}
+	best, bestDistance, ties := "", r.maxDistance+1, 0
+	for _, declared := range r.names {
+		d := editDistance(key, canonicalName(declared))
+		switch {
+		case d < bestDistance:
+			best, bestDistance, ties = declared, d, 0
+		case d == bestDistance:
+			ties++
+		}
+	}
+	if best == "" || ties > 0 {
+		return "", false
+	}
+	return best, true
+}
+
+// canonicalName lowercases name and drops everything except letters and digits.
+func canonicalName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,223 @@
+package toolrepair
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// RepairResponse repairs the tool calls in a complete (non-streaming) response
+// payload. The payload is in the client format the repairer was created for.
+// Calls that cannot be repaired are replaced with text describing the failure.
+func (r *Repairer) RepairResponse(payload []byte) ([]byte, Report) {
+	var report Report
+	if r == nil || len(payload) == 0 {
+		return payload, report
+	}
+	switch r.format {
+	case "openai":
+		return r.repairOpenAIChat(payload, "choices", "message")
+	case "openai-response":
+		return r.repairResponsesObject(payload)
+	case "claude":
+		return r.repairClaudeMessage(payload)
+	case "gemini", "gemini-cli":
+		return r.repairGemini(payload)
+	}
+	return payload, report
+}
+
+// repairOpenAIChat repairs choices[].<field>.tool_calls of a chat completion.
+func (r *Repairer) repairOpenAIChat(payload []byte, choicesPath, field string) ([]byte, Report) {
+	var report Report
+	out := payload
+	gjson.GetBytes(payload, choicesPath).ForEach(func(idx, choice gjson.Result) bool {
+		calls := choice.Get(field + ".tool_calls")
+		if !calls.IsArray() {
+			return true
+		}
+		base := fmt.Sprintf("%s.%d.%s", choicesPath, idx.Int(), field)
+		kept := make([]string, 0, len(calls.Array()))
+		var failures []string
+		var choiceReport Report
+		calls.ForEach(func(_, call gjson.Result) bool {
+			repaired := r.Repair(call.Get("function.name").String(), call.Get("function.arguments").String())
+			choiceReport.record(repaired)
+			if repaired.Err != nil {
+				failures = append(failures, failureText(repaired))
+				return true
+			}
+			raw := call.Raw
+			if repaired.Changed() {
+				raw, _ = sjson.Set(raw, "function.name", repaired.Name)
+				raw, _ = sjson.Set(raw, "function.arguments", repaired.Arguments)
+			}
+			kept = append(kept, raw)
+			return true
+		})
+		report.merge(choiceReport)
+		if !choiceReport.Changed() {
+			return true
+		}
+		if len(kept) > 0 {
+			out, _ = sjson.SetRawBytes(out, base+".tool_calls", []byte("["+strings.Join(kept, ",")+"]"))
+		} else {
+			out, _ = sjson.DeleteBytes(out, base+".tool_calls")
+			if choice.Get("finish_reason").String() == "tool_calls" {
+				out, _ = sjson.SetBytes(out, fmt.Sprintf("%s.%d.finish_reason", choicesPath, idx.Int()), "stop")
+			}
+		}
+		if len(failures) > 0 {
+			text := strings.Join(failures, "\n")
+			if existing := choice.Get(field + ".content").String(); existing != "" {
+				text = existing + "\n" + text
+			}
+			out, _ = sjson.SetBytes(out, base+".content", text)
+		}
+		return true
+	})
+	return out, report
+}
+
+// repairResponsesObject repairs function_call items in the output of a Responses API object.
+func (r *Repairer) repairResponsesObject(payload []byte) ([]byte, Report) {
+	var report Report
+	output := gjson.GetBytes(payload, "output")
+	if !output.IsArray() {
+		return payload, report
+	}
+	items := make([]string, 0, len(output.Array()))
+	output.ForEach(func(_, item gjson.Result) bool {
+		if item.Get("type").String() != "function_call" {
+			items = append(items, item.Raw)
+			return true
+		}
+		raw, call := r.repairResponsesItem(item)
+		report.record(call)
+		items = append(items, raw)
+		return true
+	})
+	if !report.Changed() {
+		return payload, report
+	}
+	out, _ := sjson.SetRawBytes(payload, "output", []byte("["+strings.Join(items, ",")+"]"))
+	return out, report
+}
+
+// repairResponsesItem repairs a single function_call output item. Unrepairable
+// calls become an assistant message item.
+func (r *Repairer) repairResponsesItem(item gjson.Result) (string, Call) {
+	call := r.Repair(item.Get("name").String(), item.Get("arguments").String())
+	if call.Err != nil {
+		return responsesFailureItem(item, call), call
+	}
+	raw := item.Raw
+	if call.Changed() {
+		raw, _ = sjson.Set(raw, "name", call.Name)
+		raw, _ = sjson.Set(raw, "arguments", call.Arguments)
+	}
+	return raw, call
+}
+
+func responsesFailureItem(item gjson.Result, call Call) string {
+	raw := `{"type":"message","role":"assistant","status":"completed","content":[{"type":"output_text","annotations":[]}]}`
+	if id := item.Get("id").String(); id != "" {
+		raw, _ = sjson.Set(raw, "id", id)
+	}
+	raw, _ = sjson.Set(raw, "content.0.text", failureText(call))
+	return raw
+}
+
+// repairClaudeMessage repairs tool_use blocks of a Claude message.
+func (r *Repairer) repairClaudeMessage(payload []byte) ([]byte, Report) {
+	var report Report
+	content := gjson.GetBytes(payload, "content")
+	if !content.IsArray() {
+		return payload, report
+	}
+	blocks := make([]string, 0, len(content.Array()))
+	remaining := 0
+	content.ForEach(func(_, block gjson.Result) bool {
+		if block.Get("type").String() != "tool_use" {
+			blocks = append(blocks, block.Raw)
+			return true
+		}
+		input := block.Get("input")
+		args := input.Raw
+		if input.Type == gjson.String {
+			args = input.String()
+		}
+		call := r.Repair(block.Get("name").String(), args)
+		report.record(call)
+		if call.Err != nil {
+			text, _ := sjson.Set(`{"type":"text"}`, "text", failureText(call))
+			blocks = append(blocks, text)
+			return true
+		}
+		remaining++
+		raw := block.Raw
+		if call.Changed() || input.Type == gjson.String {
+			raw, _ = sjson.Set(raw, "name", call.Name)
+			raw, _ = sjson.SetRaw(raw, "input", call.Arguments)
+		}
+		blocks = append(blocks, raw)
+		return true
+	})
+	if !report.Changed() {
+		return payload, report
+	}
+	out, _ := sjson.SetRawBytes(payload, "content", []byte("["+strings.Join(blocks, ",")+"]"))
+	if remaining == 0 && gjson.GetBytes(out, "stop_reason").String() == "tool_use" {
+		out, _ = sjson.SetBytes(out, "stop_reason", "end_turn")
+	}
+	return out, report
+}
+
+// repairGemini repairs functionCall parts of a Gemini response. Gemini delivers
+// complete calls in every chunk, so the same function serves streaming.
+func (r *Repairer) repairGemini(payload []byte) ([]byte, Report) {
+	var report Report
+	root := ""
+	if r.format == "gemini-cli" && gjson.GetBytes(payload, "response").Exists() {
+		root = "response."
+	}
+	out := payload
+	gjson.GetBytes(payload, root+"candidates").ForEach(func(idx, candidate gjson.Result) bool {
+		parts := candidate.Get("content.parts")
+		if !parts.IsArray() {
+			return true
+		}
+		var partReport Report
+		rewritten := make([]string, 0, len(parts.Array()))
+		parts.ForEach(func(_, part gjson.Result) bool {
+			fc := part.Get("functionCall")
+			if !fc.Exists() {
+				rewritten = append(rewritten, part.Raw)
+				return true
+			}
+			call := r.Repair(fc.Get("name").String(), fc.Get("args").Raw)
+			partReport.record(call)
+			if call.Err != nil {
+				text, _ := sjson.Set(`{}`, "text", failureText(call))
+				rewritten = append(rewritten, text)
+				return true
+			}
+			raw := part.Raw
+			if call.Changed() {
+				raw, _ = sjson.Set(raw, "functionCall.name", call.Name)
+				raw, _ = sjson.SetRaw(raw, "functionCall.args", call.Arguments)
+			}
+			rewritten = append(rewritten, raw)
+			return true
+		})
+		if partReport.Changed() {
+			path := fmt.Sprintf("%scandidates.%d.content.parts", root, idx.Int())
+			out, _ = sjson.SetRawBytes(out, path, []byte("["+strings.Join(rewritten, ",")+"]"))
+		}
+		report.merge(partReport)
+		return true
+	})
+	return out, report
+}
@@ -0,0 +1,310 @@
+package toolrepair
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Schema repair labels reported in Call.Fixes.
+const (
+	fixCoercedTypes      = "coerced-types"
+	fixEnumCase          = "enum-case"
+	fixNullOptional      = "null-optional"
+	fixUnknownProperties = "unknown-properties"
+)
+
+// maxSchemaErrors bounds how many violations are quoted in an error.
+const maxSchemaErrors = 3
+
+// conform validates args against a JSON schema and coerces values of the wrong
+// type where the intent is unambiguous, such as "3" for an integer or a bare value
+// where an array is expected. Only the schema keywords that matter for tool
+// arguments are evaluated: type, properties, required, additionalProperties,
+// items, enum, anyOf, oneOf and allOf.
+func conform(schema gjson.Result, args string) (string, []string, error) {
+	if !schema.IsObject() {
+		return args, nil, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(args))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return args, nil, err
+	}
+	w := &schemaWalker{}
+	value = w.walk(schema, value, "$")
+	if len(w.errs) > 0 {
+		errs := w.errs
+		if len(errs) > maxSchemaErrors {
+			errs = append(errs[:maxSchemaErrors:maxSchemaErrors], fmt.Sprintf("and %d more", len(w.errs)-maxSchemaErrors))
+		}
+		return args, w.fixes, errors.New(strings.Join(errs, "; "))
+	}
+	if len(w.fixes) == 0 {
+		return args, nil, nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return args, w.fixes, err
+	}
+	return strings.TrimSpace(buf.String()), w.fixes, nil
+}
+
+type schemaWalker struct {
+	fixes []string
+	errs  []string
+}
+
+func (w *schemaWalker) fix(label string) {
+	if !containsString(w.fixes, label) {
+		w.fixes = append(w.fixes, label)
+	}
+}
+
+func (w *schemaWalker) errorf(path, format string, args ...any) {
+	w.errs = append(w.errs, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (w *schemaWalker) walk(schema gjson.Result, value any, path string) any {
+	if !schema.IsObject() {
+		return value
+	}
+	schema.Get("allOf").ForEach(func(_, sub gjson.Result) bool {
+		value = w.walk(sub, value, path)
+		return true
+	})
+	for _, key := range []string{"anyOf", "oneOf"} {
+		branches := schema.Get(key)
+		if !branches.IsArray() {
+			continue
+		}
+		matched := false
+		branches.ForEach(func(_, sub gjson.Result) bool {
+			trial := &schemaWalker{}
+			out := trial.walk(sub, value, path)
+			if len(trial.errs) > 0 {
+				return true
+			}
+			for _, fix := range trial.fixes {
+				w.fix(fix)
+			}
+			value, matched = out, true
+			return false
+		})
+		if !matched {
+			w.errorf(path, "does not match any allowed schema")
+			return value
+		}
+	}
+
+	types := schemaTypes(schema)
+	if len(types) > 0 && !containsString(types, valueType(value)) {
+		coerced, ok := coerce(value, types)
+		if !ok {
+			w.errorf(path, "expected %s, got %s", strings.Join(types, " or "), valueType(value))
+			return value
+		}
+		w.fix(fixCoercedTypes)
+		value = coerced
+	}
+	if enum := schema.Get("enum"); enum.IsArray() {
+		value = w.checkEnum(enum, value, path)
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		w.walkObject(schema, v, path)
+	case []any:
+		if items := schema.Get("items"); items.IsObject() {
+			for i := range v {
+				v[i] = w.walk(items, v[i], fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+	return value
+}
+
+func (w *schemaWalker) walkObject(schema gjson.Result, obj map[string]any, path string) {
+	props := schema.Get("properties")
+	required := make(map[string]bool)
+	schema.Get("required").ForEach(func(_, name gjson.Result) bool {
+		required[name.String()] = true
+		return true
+	})
+	for name, val := range obj {
+		prop := props.Get(gjson.Escape(name))
+		if !prop.Exists() {
+			if additional := schema.Get("additionalProperties"); additional.Type == gjson.False {
+				w.fix(fixUnknownProperties)
+				delete(obj, name)
+			} else if additional.IsObject() {
+				obj[name] = w.walk(additional, val, path+"."+name)
+			}
+			continue
+		}
+		if val == nil && !required[name] && !allowsNull(prop) {
+			w.fix(fixNullOptional)
+			delete(obj, name)
+			continue
+		}
+		obj[name] = w.walk(prop, val, path+"."+name)
+	}
+	for name := range required {
+		if _, ok := obj[name]; !ok {
+			w.errorf(path, "missing required property %q", name)
+		}
+	}
+}
+
+func (w *schemaWalker) checkEnum(enum gjson.Result, value any, path string) any {
+	encoded, _ := json.Marshal(value)
+	found := false
+	var folded any
+	enum.ForEach(func(_, option gjson.Result) bool {
+		if option.Raw == string(encoded) || (option.Type == gjson.Number && isNumberValue(value) && option.Num == numberOf(value)) {
+			found = true
+			return false
+		}
+		if s, ok := value.(string); ok && option.Type == gjson.String && strings.EqualFold(option.Str, s) && folded == nil {
+			folded = option.Str
+		}
+		return true
+	})
+	if found {
+		return value
+	}
+	if folded != nil {
+		w.fix(fixEnumCase)
+		return folded
+	}
+	w.errorf(path, "value %s is not one of %s", encoded, enum.Raw)
+	return value
+}
+
+func schemaTypes(schema gjson.Result) []string {
+	var types []string
+	t := schema.Get("type")
+	if t.IsArray() {
+		t.ForEach(func(_, v gjson.Result) bool {
+			types = append(types, strings.ToLower(v.String()))
+			return true
+		})
+	} else if t.String() != "" {
+		types = append(types, strings.ToLower(t.String()))
+	}
+	if len(types) > 0 && schema.Get("nullable").Bool() {
+		types = append(types, "null")
+	}
+	// An integer is also a number.
+	if containsString(types, "number") && !containsString(types, "integer") {
+		types = append(types, "integer")
+	}
+	return types
+}
+
+func allowsNull(schema gjson.Result) bool {
+	types := schemaTypes(schema)
+	return len(types) == 0 || containsString(types, "null")
+}
+
+func valueType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+func isNumberValue(value any) bool {
+	_, ok := value.(json.Number)
+	return ok
+}
+
+func numberOf(value any) float64 {
+	n, _ := value.(json.Number).Float64()
+	return n
+}
+
+// coerce converts value to the first of types it maps onto without guessing.
+func coerce(value any, types []string) (any, bool) {
+	for _, t := range types {
+		switch t {
+		case "integer":
+			switch v := value.(type) {
+			case json.Number:
+				if f, err := v.Float64(); err == nil && f == float64(int64(f)) {
+					return json.Number(strconv.FormatInt(int64(f), 10)), true
+				}
+			case string:
+				if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+					return json.Number(strconv.FormatInt(n, 10)), true
+				}
+			}
+		case "number":
+			if s, ok := value.(string); ok {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+					return json.Number(strconv.FormatFloat(f, 'f', -1, 64)), true
+				}
+			}
+		case "boolean":
+			if s, ok := value.(string); ok {
+				if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+					return b, true
+				}
+			}
+		case "string":
+			switch v := value.(type) {
+			case json.Number:
+				return v.String(), true
+			case bool:
+				return strconv.FormatBool(v), true
+			}
+		case "array":
+			if s, ok := value.(string); ok && strings.HasPrefix(strings.TrimSpace(s), "[") {
+				if parsed, ok := decodeValue(s); ok {
+					return parsed, true
+				}
+			}
+			if value != nil {
+				return []any{value}, true
+			}
+		case "object":
+			if s, ok := value.(string); ok && strings.HasPrefix(strings.TrimSpace(s), "{") {
+				if parsed, ok := decodeValue(s); ok {
+					return parsed, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+func decodeValue(s string) (any, bool) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
@@ -0,0 +1,511 @@
+package toolrepair
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// StreamRepairer repairs tool calls in a streaming response. Only tool call
+// deltas are held back: text and every other event pass through immediately,
+// while the fragments of each tool call are buffered until the call is complete
+// and then re-emitted as a single repaired call.
+type StreamRepairer struct {
+	r *Repairer
+
+	// OpenAI chat completions: buffered calls per choice, keyed by tool index.
+	chatCalls    map[int64]map[int64]*bufferedCall
+	chatTemplate string
+
+	// Claude messages and OpenAI Responses: buffered calls keyed by block or output index.
+	calls  map[int64]*bufferedCall
+	kept   int
+	failed int
+}
+
+type bufferedCall struct {
+	index    int64
+	id       string
+	name     string
+	args     strings.Builder
+	argsDone string
+	// raw is the event or item that opened the call.
+	raw string
+	// seq holds Responses sequence numbers: added, first delta, arguments done, item done.
+	seq [4]int64
+}
+
+// NewStream returns a stream repairer for a single streaming response.
+func (r *Repairer) NewStream() *StreamRepairer {
+	if r == nil {
+		return nil
+	}
+	return &StreamRepairer{
+		r:         r,
+		chatCalls: make(map[int64]map[int64]*bufferedCall),
+		calls:     make(map[int64]*bufferedCall),
+	}
+}
+
+// Process consumes one stream chunk and returns the chunks to forward in its
+// place together with a report for the calls completed by it. The result may be
+// empty while a tool call is being buffered.
+func (s *StreamRepairer) Process(chunk []byte) ([][]byte, Report) {
+	if s == nil || len(chunk) == 0 {
+		return [][]byte{chunk}, Report{}
+	}
+	switch s.r.format {
+	case "openai":
+		return s.processChat(chunk)
+	case "claude", "openai-response":
+		out, report := s.processSSE(chunk)
+		if len(out) == 0 {
+			return nil, report
+		}
+		return [][]byte{out}, report
+	case "gemini", "gemini-cli":
+		if !gjson.ValidBytes(chunk) {
+			return [][]byte{chunk}, Report{}
+		}
+		out, report := s.r.repairGemini(chunk)
+		return [][]byte{out}, report
+	}
+	return [][]byte{chunk}, Report{}
+}
+
+// Flush emits calls still buffered when the upstream stream ended, typically
+// because it was truncated in the middle of the arguments.
+func (s *StreamRepairer) Flush() ([][]byte, Report) {
+	if s == nil {
+		return nil, Report{}
+	}
+	switch s.r.format {
+	case "openai":
+		var out [][]byte
+		var report Report
+		for _, choice := range sortedKeys(s.chatCalls) {
+			chunk, choiceReport := s.finishChatChoice(choice, nil)
+			report.merge(choiceReport)
+			if chunk != nil {
+				out = append(out, chunk)
+			}
+		}
+		return out, report
+	case "claude", "openai-response":
+		var buf bytes.Buffer
+		report := s.flushCalls(&buf)
+		if buf.Len() == 0 {
+			return nil, report
+		}
+		return [][]byte{buf.Bytes()}, report
+	}
+	return nil, Report{}
+}
+
+// processChat handles OpenAI chat completion chunks, which arrive as bare JSON.
+func (s *StreamRepairer) processChat(chunk []byte) ([][]byte, Report) {
+	trimmed := bytes.TrimSpace(chunk)
+	if bytes.Equal(trimmed, []byte("[DONE]")) {
+		out, report := s.Flush()
+		return append(out, chunk), report
+	}
+	if !gjson.ValidBytes(trimmed) {
+		return [][]byte{chunk}, Report{}
+	}
+	s.chatTemplate = chatTemplate(trimmed)
+	out := trimmed
+	var pre [][]byte
+	var report Report
+	stripped := false
+	gjson.GetBytes(trimmed, "choices").ForEach(func(pos, choice gjson.Result) bool {
+		choiceIndex := choice.Get("index").Int()
+		if deltas := choice.Get("delta.tool_calls"); deltas.IsArray() {
+			calls := s.chatCalls[choiceIndex]
+			if calls == nil {
+				calls = make(map[int64]*bufferedCall)
+				s.chatCalls[choiceIndex] = calls
+			}
+			deltas.ForEach(func(pos, delta gjson.Result) bool {
+				index := pos.Int()
+				if v := delta.Get("index"); v.Exists() {
+					index = v.Int()
+				}
+				call := calls[index]
+				if call == nil {
+					call = &bufferedCall{index: index}
+					calls[index] = call
+				}
+				if id := delta.Get("id").String(); id != "" && call.id == "" {
+					call.id = id
+				}
+				if name := delta.Get("function.name").String(); name != "" && call.name == "" {
+					call.name = name
+				}
+				call.args.WriteString(delta.Get("function.arguments").String())
+				return true
+			})
+			out, _ = sjson.DeleteBytes(out, fmt.Sprintf("choices.%d.delta.tool_calls", pos.Int()))
+			stripped = true
+		}
+		if reason := choice.Get("finish_reason").String(); reason != "" {
+			repaired, choiceReport := s.finishChatChoice(choiceIndex, &out)
+			report.merge(choiceReport)
+			if repaired != nil {
+				pre = append(pre, repaired)
+			}
+		}
+		return true
+	})
+	if stripped && !chatChunkHasContent(out) {
+		return pre, report
+	}
+	return append(pre, out), report
+}
+
+// finishChatChoice builds a chunk carrying the repaired calls of a choice. When
+// finishChunk is set and no call survived, its finish_reason is changed to stop.
+func (s *StreamRepairer) finishChatChoice(choiceIndex int64, finishChunk *[]byte) ([]byte, Report) {
+	var report Report
+	calls := s.chatCalls[choiceIndex]
+	delete(s.chatCalls, choiceIndex)
+	if len(calls) == 0 {
+		return nil, report
+	}
+	var kept []string
+	var failures []string
+	for _, index := range sortedKeys(calls) {
+		buffered := calls[index]
+		call := s.r.Repair(buffered.name, buffered.args.String())
+		report.record(call)
+		if call.Err != nil {
+			failures = append(failures, failureText(call))
+			continue
+		}
+		raw := `{"type":"function","function":{}}`
+		raw, _ = sjson.Set(raw, "index", len(kept))
+		if buffered.id != "" {
+			raw, _ = sjson.Set(raw, "id", buffered.id)
+		}
+		raw, _ = sjson.Set(raw, "function.name", call.Name)
+		raw, _ = sjson.Set(raw, "function.arguments", call.Arguments)
+		kept = append(kept, raw)
+	}
+	chunk := []byte(s.chatTemplate)
+	chunk, _ = sjson.SetBytes(chunk, "choices.0.index", choiceIndex)
+	chunk, _ = sjson.SetRawBytes(chunk, "choices.0.delta", []byte(`{}`))
+	chunk, _ = sjson.SetRawBytes(chunk, "choices.0.finish_reason", []byte(`null`))
+	if len(failures) > 0 {
+		chunk, _ = sjson.SetBytes(chunk, "choices.0.delta.content", strings.Join(failures, "\n"))
+	}
+	if len(kept) > 0 {
+		chunk, _ = sjson.SetRawBytes(chunk, "choices.0.delta.tool_calls", []byte("["+strings.Join(kept, ",")+"]"))
+	} else if finishChunk != nil {
+		gjson.GetBytes(*finishChunk, "choices").ForEach(func(pos, choice gjson.Result) bool {
+			if choice.Get("index").Int() == choiceIndex && choice.Get("finish_reason").String() == "tool_calls" {
+				*finishChunk, _ = sjson.SetBytes(*finishChunk, fmt.Sprintf("choices.%d.finish_reason", pos.Int()), "stop")
+			}
+			return true
+		})
+	}
+	return chunk, report
+}
+
+func chatTemplate(chunk []byte) string {
+	template := `{"object":"chat.completion.chunk","choices":[{}]}`
+	for _, key := range []string{"id", "object", "created", "model", "system_fingerprint"} {
+		if v := gjson.GetBytes(chunk, key); v.Exists() {
+			template, _ = sjson.SetRaw(template, key, v.Raw)
+		}
+	}
+	return template
+}
+
+func chatChunkHasContent(chunk []byte) bool {
+	if gjson.GetBytes(chunk, "usage").IsObject() {
+		return true
+	}
+	has := false
+	gjson.GetBytes(chunk, "choices").ForEach(func(_, choice gjson.Result) bool {
+		if choice.Get("finish_reason").String() != "" {
+			has = true
+			return false
+		}
+		choice.Get("delta").ForEach(func(_, v gjson.Result) bool {
+			if v.Type != gjson.Null && v.Raw != `""` {
+				has = true
+			}
+			return !has
+		})
+		return !has
+	})
+	return has
+}
+
+// processSSE handles Claude and Responses streams, whose chunks carry SSE events.
+func (s *StreamRepairer) processSSE(chunk []byte) ([]byte, Report) {
+	var report Report
+	var out bytes.Buffer
+	for _, event := range splitEvents(chunk) {
+		data := eventData(event)
+		if data == "" || !gjson.Valid(data) {
+			out.Write(event)
+			continue
+		}
+		parsed := gjson.Parse(data)
+		var handled bool
+		var eventReport Report
+		if s.r.format == "claude" {
+			handled, eventReport = s.handleClaudeEvent(parsed, &out)
+		} else {
+			handled, eventReport = s.handleResponsesEvent(parsed, &out)
+		}
+		report.merge(eventReport)
+		if !handled {
+			out.Write(event)
+		}
+	}
+	return out.Bytes(), report
+}
+
+func (s *StreamRepairer) handleClaudeEvent(event gjson.Result, out *bytes.Buffer) (bool, Report) {
+	index := event.Get("index").Int()
+	switch event.Get("type").String() {
+	case "content_block_start":
+		if event.Get("content_block.type").String() != "tool_use" {
+			return false, Report{}
+		}
+		s.calls[index] = &bufferedCall{
+			index: index,
+			id:    event.Get("content_block.id").String(),
+			name:  event.Get("content_block.name").String(),
+			raw:   event.Raw,
+		}
+		return true, Report{}
+	case "content_block_delta":
+		call := s.calls[index]
+		if call == nil {
+			return false, Report{}
+		}
+		call.args.WriteString(event.Get("delta.partial_json").String())
+		return true, Report{}
+	case "content_block_stop":
+		call := s.calls[index]
+		if call == nil {
+			return false, Report{}
+		}
+		delete(s.calls, index)
+		var report Report
+		report.record(s.emitClaudeCall(call, out))
+		return true, report
+	case "message_delta":
+		report := s.flushCalls(out)
+		if s.kept == 0 && s.failed > 0 && event.Get("delta.stop_reason").String() == "tool_use" {
+			raw, _ := sjson.Set(event.Raw, "delta.stop_reason", "end_turn")
+			writeEvent(out, "message_delta", raw)
+			return true, report
+		}
+		return false, report
+	case "message_stop":
+		return false, s.flushCalls(out)
+	}
+	return false, Report{}
+}
+
+func (s *StreamRepairer) emitClaudeCall(buffered *bufferedCall, out *bytes.Buffer) Call {
+	call := s.r.Repair(buffered.name, buffered.args.String())
+	if call.Err != nil {
+		s.failed++
+		start := fmt.Sprintf(`{"type":"content_block_start","index":%d,"content_block":{"type":"text","text":""}}`, buffered.index)
+		delta := fmt.Sprintf(`{"type":"content_block_delta","index":%d,"delta":{"type":"text_delta"}}`, buffered.index)
+		delta, _ = sjson.Set(delta, "delta.text", failureText(call))
+		writeEvent(out, "content_block_start", start)
+		writeEvent(out, "content_block_delta", delta)
+	} else {
+		s.kept++
+		start, _ := sjson.Set(buffered.raw, "content_block.name", call.Name)
+		start, _ = sjson.SetRaw(start, "content_block.input", `{}`)
+		delta := fmt.Sprintf(`{"type":"content_block_delta","index":%d,"delta":{"type":"input_json_delta"}}`, buffered.index)
+		delta, _ = sjson.Set(delta, "delta.partial_json", call.Arguments)
+		writeEvent(out, "content_block_start", start)
+		writeEvent(out, "content_block_delta", delta)
+	}
+	writeEvent(out, "content_block_stop", fmt.Sprintf(`{"type":"content_block_stop","index":%d}`, buffered.index))
+	return call
+}
+
+func (s *StreamRepairer) handleResponsesEvent(event gjson.Result, out *bytes.Buffer) (bool, Report) {
+	index := event.Get("output_index").Int()
+	seq := event.Get("sequence_number").Int()
+	switch event.Get("type").String() {
+	case "response.output_item.added":
+		if event.Get("item.type").String() != "function_call" {
+			return false, Report{}
+		}
+		call := &bufferedCall{index: index, raw: event.Get("item").Raw}
+		call.seq[0] = seq
+		s.calls[index] = call
+		return true, Report{}
+	case "response.function_call_arguments.delta":
+		call := s.calls[index]
+		if call == nil {
+			return false, Report{}
+		}
+		if call.seq[1] == 0 {
+			call.seq[1] = seq
+		}
+		call.args.WriteString(event.Get("delta").String())
+		return true, Report{}
+	case "response.function_call_arguments.done":
+		call := s.calls[index]
+		if call == nil {
+			return false, Report{}
+		}
+		call.seq[2] = seq
+		call.argsDone = event.Get("arguments").String()
+		return true, Report{}
+	case "response.output_item.done":
+		call := s.calls[index]
+		if call == nil {
+			return false, Report{}
+		}
+		delete(s.calls, index)
+		call.seq[3] = seq
+		if item := event.Get("item"); item.IsObject() {
+			call.raw = item.Raw
+			if args := item.Get("arguments").String(); args != "" {
+				call.argsDone = args
+			}
+		}
+		var report Report
+		report.record(s.emitResponsesCall(call, out))
+		return true, report
+	case "response.completed", "response.incomplete", "response.failed":
+		report := s.flushCalls(out)
+		if response := event.Get("response"); response.IsObject() {
+			repaired, final := s.r.repairResponsesObject([]byte(response.Raw))
+			if final.Changed() {
+				raw, _ := sjson.SetRaw(event.Raw, "response", string(repaired))
+				writeEvent(out, event.Get("type").String(), raw)
+				return true, report
+			}
+		}
+		return false, report
+	}
+	return false, Report{}
+}
+
+func (s *StreamRepairer) emitResponsesCall(buffered *bufferedCall, out *bytes.Buffer) Call {
+	item := gjson.Parse(buffered.raw)
+	args := buffered.argsDone
+	if args == "" {
+		args = buffered.args.String()
+	}
+	call := s.r.Repair(item.Get("name").String(), args)
+	withSeq := func(raw string, seq int64) string {
+		if seq > 0 {
+			raw, _ = sjson.Set(raw, "sequence_number", seq)
+		}
+		return raw
+	}
+	if call.Err != nil {
+		s.failed++
+		done := responsesFailureItem(item, call)
+		added, _ := sjson.Set(done, "status", "in_progress")
+		added, _ = sjson.SetRaw(added, "content", `[]`)
+		writeEvent(out, "response.output_item.added", withSeq(responsesItemEvent("response.output_item.added", buffered.index, added), buffered.seq[0]))
+		writeEvent(out, "response.output_item.done", withSeq(responsesItemEvent("response.output_item.done", buffered.index, done), buffered.seq[3]))
+		return call
+	}
+	s.kept++
+	done, _ := sjson.Set(item.Raw, "name", call.Name)
+	done, _ = sjson.Set(done, "arguments", call.Arguments)
+	done, _ = sjson.Set(done, "status", "completed")
+	added, _ := sjson.Set(done, "arguments", "")
+	added, _ = sjson.Set(added, "status", "in_progress")
+	itemID := item.Get("id").String()
+	writeEvent(out, "response.output_item.added", withSeq(responsesItemEvent("response.output_item.added", buffered.index, added), buffered.seq[0]))
+	if buffered.seq[1] > 0 {
+		delta := fmt.Sprintf(`{"type":"response.function_call_arguments.delta","output_index":%d}`, buffered.index)
+		delta, _ = sjson.Set(delta, "item_id", itemID)
+		delta, _ = sjson.Set(delta, "delta", call.Arguments)
+		writeEvent(out, "response.function_call_arguments.delta", withSeq(delta, buffered.seq[1]))
+	}
+	argsDone := fmt.Sprintf(`{"type":"response.function_call_arguments.done","output_index":%d}`, buffered.index)
+	argsDone, _ = sjson.Set(argsDone, "item_id", itemID)
+	argsDone, _ = sjson.Set(argsDone, "arguments", call.Arguments)
+	writeEvent(out, "response.function_call_arguments.done", withSeq(argsDone, buffered.seq[2]))
+	writeEvent(out, "response.output_item.done", withSeq(responsesItemEvent("response.output_item.done", buffered.index, done), buffered.seq[3]))
+	return call
+}
+
+func responsesItemEvent(eventType string, index int64, item string) string {
+	raw := fmt.Sprintf(`{"type":%q,"output_index":%d}`, eventType, index)
+	raw, _ = sjson.SetRaw(raw, "item", item)
+	return raw
+}
+
+// flushCalls emits every buffered Claude or Responses call in index order.
+func (s *StreamRepairer) flushCalls(out *bytes.Buffer) Report {
+	var report Report
+	for _, index := range sortedKeys(s.calls) {
+		call := s.calls[index]
+		delete(s.calls, index)
+		if s.r.format == "claude" {
+			report.record(s.emitClaudeCall(call, out))
+		} else {
+			report.record(s.emitResponsesCall(call, out))
+		}
+	}
+	return report
+}
+
+// splitEvents splits an SSE chunk into events, each keeping its trailing separator.
+func splitEvents(chunk []byte) [][]byte {
+	var events [][]byte
+	for len(chunk) > 0 {
+		idx := bytes.Index(chunk, []byte("\n\n"))
+		if idx < 0 {
+			events = append(events, chunk)
+			break
+		}
+		end := idx + 2
+		for end < len(chunk) && chunk[end] == '\n' {
+			end++
+		}
+		events = append(events, chunk[:end])
+		chunk = chunk[end:]
+	}
+	return events
+}
+
+// eventData returns the data payload of an SSE event.
+func eventData(event []byte) string {
+	for _, line := range bytes.Split(event, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("data:")) {
+			return string(bytes.TrimSpace(line[5:]))
+		}
+	}
+	return ""
+}
+
+func writeEvent(out *bytes.Buffer, name, data string) {
+	out.WriteString("event: ")
+	out.WriteString(name)
+	out.WriteString("\ndata: ")
+	out.WriteString(data)
+	out.WriteString("\n\n")
+}
+
+func sortedKeys[V any](m map[int64]V) []int64 {
+	keys := make([]int64, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
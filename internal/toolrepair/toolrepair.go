@@ -0,0 +1,244 @@
+// Package toolrepair validates tool calls emitted by upstream models against the
+// tools declared in the client request and repairs the common defects: arguments
+// that are not valid JSON, arguments truncated mid-stream, values of the wrong
+// type and tool names that only nearly match a declared tool. Calls that cannot
+// be repaired are reported so the caller can retry or replace them with an error.
+package toolrepair
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+)
+
+// HeaderName is the response header summarizing repairs applied to a non-streaming response.
+const HeaderName = "X-CPA-Tool-Repair"
+
+// Tool is a tool declared in the client request.
+type Tool struct {
+	Name   string
+	Schema gjson.Result
+}
+
+// Repairer repairs the tool calls of a single exchange.
+type Repairer struct {
+	format      string
+	model       string
+	tools       map[string]Tool
+	names       []string
+	maxDistance int
+	retry       bool
+}
+
+// New returns a repairer for a request in the given client format, or nil when
+// repair is disabled, does not apply to the model or the request declares no tools.
+func New(cfg *config.ToolCallRepairConfig, format, model string, rawJSON []byte) *Repairer {
+	if cfg == nil || !cfg.Enabled || !util.MatchAnyWildcardFold(cfg.Models, model) {
+		return nil
+	}
+	tools := DeclaredTools(format, rawJSON)
+	if len(tools) == 0 {
+		return nil
+	}
+	r := &Repairer{
+		format:      format,
+		model:       model,
+		tools:       make(map[string]Tool, len(tools)),
+		names:       make([]string, 0, len(tools)),
+		maxDistance: cfg.MaxNameDistance,
+		retry:       cfg.OnFailure == config.ToolRepairOnFailureRetry,
+	}
+	if r.maxDistance <= 0 {
+		r.maxDistance = config.DefaultToolRepairMaxNameDistance
+	}
+	for _, tool := range tools {
+		if _, exists := r.tools[tool.Name]; exists {
+			continue
+		}
+		r.tools[tool.Name] = tool
+		r.names = append(r.names, tool.Name)
+	}
+	return r
+}
+
+// RetryOnFailure reports whether unrepairable calls should trigger a single retry.
+func (r *Repairer) RetryOnFailure() bool {
+	return r != nil && r.retry
+}
+
+// Model returns the model the repairer was created for.
+func (r *Repairer) Model() string {
+	if r == nil {
+		return ""
+	}
+	return r.model
+}
+
+// DeclaredTools returns the function tools declared in a client request.
+func DeclaredTools(format string, rawJSON []byte) []Tool {
+	var out []Tool
+	add := func(name string, schema gjson.Result) {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			out = append(out, Tool{Name: name, Schema: schema})
+		}
+	}
+	gjson.GetBytes(rawJSON, "tools").ForEach(func(_, tool gjson.Result) bool {
+		switch format {
+		case "openai":
+			if fn := tool.Get("function"); fn.Exists() {
+				add(fn.Get("name").String(), fn.Get("parameters"))
+			}
+		case "openai-response":
+			if t := tool.Get("type").String(); t == "" || t == "function" {
+				add(tool.Get("name").String(), tool.Get("parameters"))
+			}
+		case "claude":
+			if t := tool.Get("type").String(); t == "" || t == "custom" {
+				add(tool.Get("name").String(), tool.Get("input_schema"))
+			}
+		case "gemini", "gemini-cli":
+			decls := tool.Get("functionDeclarations")
+			if !decls.Exists() {
+				decls = tool.Get("function_declarations")
+			}
+			decls.ForEach(func(_, decl gjson.Result) bool {
+				schema := decl.Get("parametersJsonSchema")
+				if !schema.Exists() {
+					schema = decl.Get("parameters")
+				}
+				add(decl.Get("name").String(), schema)
+				return true
+			})
+		}
+		return true
+	})
+	return out
+}
+
+// Call is a tool call whose arguments have been validated or repaired.
+type Call struct {
+	// Name is the declared tool name the call resolved to.
+	Name string
+	// Arguments is the repaired JSON object.
+	Arguments string
+	// Fixes lists the repairs that were applied.
+	Fixes []string
+	// Err is set when the call could not be repaired.
+	Err error
+}
+
+// Changed reports whether any repair was applied.
+func (c Call) Changed() bool {
+	return len(c.Fixes) > 0
+}
+
+// Repair validates a single tool call and repairs it where possible.
+func (r *Repairer) Repair(name, arguments string) Call {
+	call := Call{Name: name, Arguments: arguments}
+	resolved, ok := r.resolveName(name)
+	if !ok {
+		call.Err = fmt.Errorf("unknown tool %q", name)
+		return call
+	}
+	if resolved != name {
+		call.Name = resolved
+		call.Fixes = append(call.Fixes, "tool-name")
+	}
+	args, fixes, err := repairJSON(arguments)
+	call.Fixes = append(call.Fixes, fixes...)
+	if err != nil {
+		call.Err = fmt.Errorf("arguments for %s are not valid JSON: %w", resolved, err)
+		return call
+	}
+	args, fixes, err = conform(r.tools[resolved].Schema, args)
+	call.Fixes = append(call.Fixes, fixes...)
+	if err != nil {
+		call.Err = fmt.Errorf("arguments for %s do not match its schema: %w", resolved, err)
+		return call
+	}
+	call.Arguments = args
+	return call
+}
+
+// ValidateObject repairs doc into a JSON object and validates it against schema,
+// returning the normalized document and the repairs that were applied. An empty
+// schema only requires a JSON object.
+func ValidateObject(schema gjson.Result, doc string) (string, []string, error) {
+	out, fixes, err := repairJSON(doc)
+	if err != nil {
+		return "", fixes, err
+	}
+	out, schemaFixes, err := conform(schema, out)
+	return out, append(fixes, schemaFixes...), err
+}
+
+// Report summarizes the tool calls seen in a response.
+type Report struct {
+	Calls    int
+	Repaired int
+	Failed   int
+	Fixes    []string
+	Errors   []string
+}
+
+// Changed reports whether any call was repaired or replaced.
+func (r Report) Changed() bool {
+	return r.Repaired > 0 || r.Failed > 0
+}
+
+// Header renders the report for the HeaderName response header.
+func (r Report) Header() string {
+	parts := []string{fmt.Sprintf("calls=%d", r.Calls), fmt.Sprintf("repaired=%d", r.Repaired), fmt.Sprintf("failed=%d", r.Failed)}
+	if len(r.Fixes) > 0 {
+		parts = append(parts, "fixes="+strings.Join(r.Fixes, ","))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (r *Report) record(call Call) {
+	r.Calls++
+	if call.Err != nil {
+		r.Failed++
+		r.Errors = append(r.Errors, call.Err.Error())
+		return
+	}
+	if !call.Changed() {
+		return
+	}
+	r.Repaired++
+	for _, fix := range call.Fixes {
+		if !containsString(r.Fixes, fix) {
+			r.Fixes = append(r.Fixes, fix)
+		}
+	}
+}
+
+func (r *Report) merge(other Report) {
+	r.Calls += other.Calls
+	r.Repaired += other.Repaired
+	r.Failed += other.Failed
+	r.Errors = append(r.Errors, other.Errors...)
+	for _, fix := range other.Fixes {
+		if !containsString(r.Fixes, fix) {
+			r.Fixes = append(r.Fixes, fix)
+		}
+	}
+}
+
+// failureText is the text substituted for a call that could not be repaired.
+func failureText(call Call) string {
+	return fmt.Sprintf("[tool call dropped by proxy: %v]", call.Err)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
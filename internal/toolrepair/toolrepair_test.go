@@ -0,0 +1,211 @@
+package toolrepair
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+const openAIRequest = `{"model":"gpt-4o","tools":[
+	{"type":"function","function":{"name":"read_file","parameters":{"type":"object","properties":{"path":{"type":"string"},"limit":{"type":"integer"},"mode":{"type":"string","enum":["text","binary"]}},"required":["path"]}}},
+	{"type":"function","function":{"name":"list_dir","parameters":{"type":"object","properties":{"paths":{"type":"array","items":{"type":"string"}}}}}}
+]}`
+
+func newRepairer(t *testing.T, format, request string) *Repairer {
+	t.Helper()
+	cfg := &config.ToolCallRepairConfig{Enabled: true, MaxNameDistance: 2}
+	r := New(cfg, format, "gpt-4o", []byte(request))
+	if r == nil {
+		t.Fatal("expected repairer")
+	}
+	return r
+}
+
+func TestRepairJSONDefects(t *testing.T) {
+	cases := map[string]string{
+		"```json\n{\"a\": 1}\n```":             `{"a": 1}`,
+		`Here you go: {"a": 1}`:                `{"a": 1}`,
+		`{'a': 'it\'s', b: True, c: None,}`:    `{"a": "it's", "b": true, "c": null}`,
+		`{"a": "line1` + "\n" + `line2"}`:      `{"a": "line1\nline2"}`,
+		`{"a": [1, 2,], // note` + "\n" + `}`:  `{"a": [1, 2]}`,
+		`{"path": "/tmp/x", "opts": {"deep": `: `{"path": "/tmp/x", "opts": {"deep": null}}`,
+		`{"path": "/tmp/unterminated`:          `{"path": "/tmp/unterminated"}`,
+		`{"pa`:                                 `{"pa":null}`,
+		`"{\"a\":1}"`:                          `{"a":1}`,
+		``:                                     `{}`,
+	}
+	for input, want := range cases {
+		got, _, err := repairJSON(input)
+		if err != nil {
+			t.Errorf("repairJSON(%q) error: %v", input, err)
+			continue
+		}
+		if !gjson.Valid(got) || compactJSON(got) != compactJSON(want) {
+			t.Errorf("repairJSON(%q) = %s, want %s", input, got, want)
+		}
+	}
+	if _, _, err := repairJSON(`[1,2]`); err == nil {
+		t.Error("expected arrays to be rejected")
+	}
+	if _, _, err := repairJSON(`no json here`); err == nil {
+		t.Error("expected prose to be rejected")
+	}
+}
+
+func TestRepairMapsNamesAndCoercesTypes(t *testing.T) {
+	r := newRepairer(t, "openai", openAIRequest)
+
+	call := r.Repair("functions.readFile", `{"path":"a.go","limit":"20","mode":"TEXT"}`)
+	if call.Err != nil {
+		t.Fatalf("unexpected error: %v", call.Err)
+	}
+	if call.Name != "read_file" {
+		t.Fatalf("name = %q, want read_file", call.Name)
+	}
+	if got := gjson.Get(call.Arguments, "limit"); got.Type != gjson.Number || got.Int() != 20 {
+		t.Fatalf("limit not coerced: %s", call.Arguments)
+	}
+	if got := gjson.Get(call.Arguments, "mode").String(); got != "text" {
+		t.Fatalf("mode = %q, want text", got)
+	}
+
+	call = r.Repair("list_dri", `{"paths":"src"}`)
+	if call.Err != nil || call.Name != "list_dir" || gjson.Get(call.Arguments, "paths.0").String() != "src" {
+		t.Fatalf("unexpected repair: %+v", call)
+	}
+
+	if call = r.Repair("delete_everything", `{}`); call.Err == nil {
+		t.Fatal("expected unknown tool to fail")
+	}
+	if call = r.Repair("read_file", `{"limit":3}`); call.Err == nil || !strings.Contains(call.Err.Error(), "path") {
+		t.Fatalf("expected missing required property error, got %v", call.Err)
+	}
+}
+
+func TestRepairResponseOpenAIReplacesUnrepairableCalls(t *testing.T) {
+	r := newRepairer(t, "openai", openAIRequest)
+	payload := []byte(`{"choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":null,"tool_calls":[
+		{"id":"a","type":"function","function":{"name":"read_file","arguments":"{'path': 'x'"}},
+		{"id":"b","type":"function","function":{"name":"rm_rf","arguments":"{}"}}
+	]}}]}`)
+	out, report := r.RepairResponse(payload)
+	if report.Calls != 2 || report.Repaired != 1 || report.Failed != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	calls := gjson.GetBytes(out, "choices.0.message.tool_calls").Array()
+	if len(calls) != 1 || calls[0].Get("function.arguments").String() != `{"path": "x"}` {
+		t.Fatalf("unexpected tool calls: %s", out)
+	}
+	if !strings.Contains(gjson.GetBytes(out, "choices.0.message.content").String(), "rm_rf") {
+		t.Fatalf("expected failure text in content: %s", out)
+	}
+}
+
+func TestStreamOpenAIBuffersToolDeltas(t *testing.T) {
+	s := newRepairer(t, "openai", openAIRequest).NewStream()
+	chunks := []string{
+		`{"id":"c1","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":"Reading"}}]}`,
+		`{"id":"c1","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read_file","arguments":""}}]}}]}`,
+		`{"id":"c1","model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\": \"main"}}]}}]}`,
+	}
+	var forwarded [][]byte
+	for _, chunk := range chunks {
+		out, _ := s.Process([]byte(chunk))
+		forwarded = append(forwarded, out...)
+	}
+	if len(forwarded) != 1 {
+		t.Fatalf("expected only the text chunk to pass through, got %d chunks", len(forwarded))
+	}
+	out, report := s.Process([]byte("[DONE]"))
+	if report.Repaired != 1 || len(out) != 2 {
+		t.Fatalf("unexpected flush: %d chunks, report %+v", len(out), report)
+	}
+	call := gjson.GetBytes(out[0], "choices.0.delta.tool_calls.0")
+	if call.Get("id").String() != "call_1" || call.Get("function.arguments").String() != `{"path": "main"}` {
+		t.Fatalf("unexpected repaired call: %s", out[0])
+	}
+	if string(out[1]) != "[DONE]" {
+		t.Fatalf("expected [DONE] last, got %s", out[1])
+	}
+}
+
+func TestStreamClaudeRepairsToolUseBlock(t *testing.T) {
+	request := `{"tools":[{"name":"get_weather","input_schema":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}}]}`
+	s := newRepairer(t, "claude", request).NewStream()
+	stream := "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n" +
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n" +
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"tu_1\",\"name\":\"GetWeather\",\"input\":{}}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{city: 'Paris',}\"}}\n\n"
+	out, report := s.Process([]byte(stream))
+	if report.Calls != 0 || bytes.Contains(out[0], []byte("tool_use")) {
+		t.Fatalf("tool call should still be buffered: %s", out[0])
+	}
+	out, report = s.Process([]byte("event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":1}\n\n"))
+	if report.Repaired != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	text := string(out[0])
+	if !strings.Contains(text, `"name":"get_weather"`) || !strings.Contains(text, `{\"city\": \"Paris\"}`) {
+		t.Fatalf("unexpected repaired events: %s", text)
+	}
+}
+
+func TestStreamResponsesRepairsFunctionCall(t *testing.T) {
+	request := `{"tools":[{"type":"function","name":"read_file","parameters":{"type":"object","properties":{"path":{"type":"string"},"limit":{"type":"integer"}},"required":["path"]}}]}`
+	s := newRepairer(t, "openai-response", request).NewStream()
+	events := []string{
+		`{"type":"response.output_item.added","sequence_number":3,"output_index":1,"item":{"id":"fc_1","type":"function_call","call_id":"call_1","name":"readFile","arguments":"","status":"in_progress"}}`,
+		`{"type":"response.function_call_arguments.delta","sequence_number":4,"output_index":1,"item_id":"fc_1","delta":"{'path': 'main.go', "}`,
+		`{"type":"response.function_call_arguments.delta","sequence_number":5,"output_index":1,"item_id":"fc_1","delta":"limit: '5'"}`,
+		`{"type":"response.function_call_arguments.done","sequence_number":6,"output_index":1,"item_id":"fc_1","arguments":"{'path': 'main.go', limit: '5'"}`,
+	}
+	for _, event := range events {
+		out, _ := s.Process([]byte("data: " + event + "\n\n"))
+		if len(out) != 0 {
+			t.Fatalf("function call events should be buffered, got %s", out)
+		}
+	}
+	out, report := s.Process([]byte("data: " +
+		`{"type":"response.output_item.done","sequence_number":7,"output_index":1,"item":{"id":"fc_1","type":"function_call","call_id":"call_1","name":"readFile","arguments":"{'path': 'main.go', limit: '5'","status":"completed"}}` + "\n\n"))
+	if report.Repaired != 1 || len(out) != 1 {
+		t.Fatalf("unexpected flush: %q, report %+v", out, report)
+	}
+	var done gjson.Result
+	for _, event := range splitEvents(out[0]) {
+		if data := gjson.Parse(eventData(event)); data.Get("type").String() == "response.output_item.done" {
+			done = data
+		}
+	}
+	if done.Get("item.name").String() != "read_file" || done.Get("sequence_number").Int() != 7 {
+		t.Fatalf("unexpected output_item.done: %s", out[0])
+	}
+	if args := done.Get("item.arguments").String(); gjson.Get(args, "path").String() != "main.go" || gjson.Get(args, "limit").Type != gjson.Number {
+		t.Fatalf("arguments not repaired: %s", args)
+	}
+}
+
+func TestStreamGeminiRepairsFunctionCall(t *testing.T) {
+	request := `{"tools":[{"functionDeclarations":[{"name":"get_weather","parameters":{"type":"object","properties":{"city":{"type":"string"},"days":{"type":"integer"}},"required":["city"]}}]}]}`
+	s := newRepairer(t, "gemini", request).NewStream()
+	chunk := `{"candidates":[{"content":{"role":"model","parts":[{"text":"Checking"},{"functionCall":{"name":"getWeather","args":{"city":"Paris","days":"3"}}}]}}]}`
+	out, report := s.Process([]byte(chunk))
+	if report.Repaired != 1 || len(out) != 1 {
+		t.Fatalf("unexpected result: %q, report %+v", out, report)
+	}
+	call := gjson.GetBytes(out[0], "candidates.0.content.parts.1.functionCall")
+	if call.Get("name").String() != "get_weather" || call.Get("args.days").Type != gjson.Number {
+		t.Fatalf("unexpected repaired call: %s", out[0])
+	}
+
+	out, report = s.Process([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"drop_tables","args":{}}}]}}]}`))
+	if report.Failed != 1 || gjson.GetBytes(out[0], "candidates.0.content.parts.0.functionCall").Exists() {
+		t.Fatalf("unknown tool should become text: %s", out[0])
+	}
+}
+
+func compactJSON(s string) string {
+	return gjson.Get(s, "@ugly").Raw
+}
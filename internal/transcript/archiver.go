@@ -0,0 +1,325 @@
+package transcript
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	queueSize     = 1024
+	pruneInterval = time.Hour
+	// maxCaptureBytes bounds the stream output buffered per request.
+	maxCaptureBytes = 8 << 20
+)
+
+// Archiver normalizes captured exchanges and writes them to a Store from a
+// background goroutine so request handling never waits on disk.
+type Archiver struct {
+	cfg   config.TranscriptsConfig
+	store *Store
+	keys  map[string]struct{}
+
+	queue    chan *Recorder
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Meta identifies the exchange being recorded.
+type Meta struct {
+	APIKey  string
+	Tenant  string
+	Session string
+	Model   string
+	Format  string
+	Stream  bool
+}
+
+// Recorder captures one exchange. Its methods are nil-safe so callers can record
+// unconditionally; a nil Recorder means archiving does not apply to the request.
+type Recorder struct {
+	archiver  *Archiver
+	meta      Meta
+	started   time.Time
+	latency   time.Duration
+	request   []byte
+	response  []byte
+	output    []byte
+	truncated bool
+	status    int
+	err       string
+	finished  bool
+}
+
+var current struct {
+	mu       sync.Mutex
+	archiver *Archiver
+	// failed remembers settings that could not be opened to avoid retrying per request.
+	failed *config.TranscriptsConfig
+}
+
+// lastLookup caches the ForConfig result for the most recent configuration
+// instance. A reload installs a new instance, so per-request calls skip the lock
+// and the settings comparison until the configuration actually changes.
+var lastLookup atomic.Pointer[archiverLookup]
+
+type archiverLookup struct {
+	src      *config.TranscriptsConfig
+	archiver *Archiver
+}
+
+// ForConfig returns the archiver for cfg, reopening it when the settings change and
+// closing it when archiving is disabled.
+func ForConfig(cfg *config.TranscriptsConfig) *Archiver {
+	if cached := lastLookup.Load(); cached != nil && cached.src == cfg {
+		return cached.archiver
+	}
+	current.mu.Lock()
+	defer current.mu.Unlock()
+	archiver := forConfigLocked(cfg)
+	lastLookup.Store(&archiverLookup{src: cfg, archiver: archiver})
+	return archiver
+}
+
+func forConfigLocked(cfg *config.TranscriptsConfig) *Archiver {
+	if cfg == nil || !cfg.Enabled {
+		if current.archiver != nil {
+			current.archiver.Close()
+			current.archiver = nil
+		}
+		current.failed = nil
+		return nil
+	}
+	if current.archiver != nil && reflect.DeepEqual(current.archiver.cfg, *cfg) {
+		return current.archiver
+	}
+	if current.failed != nil && reflect.DeepEqual(*current.failed, *cfg) {
+		return nil
+	}
+	if current.archiver != nil {
+		current.archiver.Close()
+		current.archiver = nil
+	}
+	archiver, err := New(*cfg)
+	if err != nil {
+		log.WithError(err).Error("transcript archive disabled: unable to open store")
+		failed := *cfg
+		current.failed = &failed
+		return nil
+	}
+	current.failed = nil
+	current.archiver = archiver
+	return archiver
+}
+
+// Shutdown closes the active archiver, sealing its open segment.
+func Shutdown() {
+	current.mu.Lock()
+	defer current.mu.Unlock()
+	lastLookup.Store(nil)
+	if current.archiver != nil {
+		current.archiver.Close()
+		current.archiver = nil
+	}
+}
+
+// ResolveDir returns the directory holding transcript segments for cfg.
+func ResolveDir(cfg config.TranscriptsConfig) string {
+	if cfg.Dir != "" {
+		return cfg.Dir
+	}
+	if base := util.WritablePath(); base != "" {
+		return filepath.Join(base, "transcripts")
+	}
+	return "transcripts"
+}
+
+// New opens the store described by cfg and starts the background writer.
+func New(cfg config.TranscriptsConfig) (*Archiver, error) {
+	retentionDays := cfg.RetentionDays
+	if retentionDays <= 0 {
+		retentionDays = config.DefaultTranscriptRetentionDays
+	}
+	segmentMB := cfg.SegmentMaxMB
+	if segmentMB <= 0 {
+		segmentMB = config.DefaultTranscriptSegmentMaxMB
+	}
+	store, err := OpenStore(ResolveDir(cfg), int64(segmentMB)<<20, time.Duration(retentionDays)*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	a := &Archiver{
+		cfg:   cfg,
+		store: store,
+		queue: make(chan *Recorder, queueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if len(cfg.APIKeys) > 0 {
+		a.keys = make(map[string]struct{}, len(cfg.APIKeys))
+		for _, key := range cfg.APIKeys {
+			a.keys[key] = struct{}{}
+		}
+	}
+	go a.run()
+	return a, nil
+}
+
+// Store exposes the underlying store for search and export.
+func (a *Archiver) Store() *Store {
+	if a == nil {
+		return nil
+	}
+	return a.store
+}
+
+// Close stops the writer after draining queued exchanges.
+func (a *Archiver) Close() {
+	if a == nil {
+		return
+	}
+	a.stopOnce.Do(func() { close(a.stop) })
+	<-a.done
+}
+
+func (a *Archiver) run() {
+	defer close(a.done)
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	a.prune()
+	for {
+		select {
+		case rec := <-a.queue:
+			a.write(rec)
+		case <-ticker.C:
+			a.prune()
+		case <-a.stop:
+			for {
+				select {
+				case rec := <-a.queue:
+					a.write(rec)
+				default:
+					if err := a.store.Close(); err != nil {
+						log.WithError(err).Warn("transcript: failed to seal active segment")
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+func (a *Archiver) prune() {
+	removed, err := a.store.Prune(time.Now())
+	if err != nil {
+		log.WithError(err).Warn("transcript: retention cleanup failed")
+		return
+	}
+	if removed > 0 {
+		log.Debugf("transcript: removed %d expired segment(s)", removed)
+	}
+}
+
+func (a *Archiver) write(rec *Recorder) {
+	t := rec.build()
+	if err := a.store.Append(t); err != nil {
+		log.WithError(err).Warn("transcript: failed to archive exchange")
+	}
+}
+
+// NewRecorder starts recording an exchange, or returns nil when the client is not archived.
+func (a *Archiver) NewRecorder(meta Meta, request []byte) *Recorder {
+	if a == nil {
+		return nil
+	}
+	if a.keys != nil {
+		if _, ok := a.keys[meta.APIKey]; !ok {
+			return nil
+		}
+	}
+	return &Recorder{
+		archiver: a,
+		meta:     meta,
+		started:  time.Now(),
+		request:  append([]byte(nil), request...),
+	}
+}
+
+// Observe appends a stream chunk sent to the client.
+func (r *Recorder) Observe(chunk []byte) {
+	if r == nil || r.truncated {
+		return
+	}
+	if len(r.output)+len(chunk) > maxCaptureBytes {
+		r.truncated = true
+		return
+	}
+	r.output = append(r.output, chunk...)
+}
+
+// Complete records the non-streaming response returned to the client.
+func (r *Recorder) Complete(payload []byte) {
+	if r == nil {
+		return
+	}
+	r.response = append([]byte(nil), payload...)
+}
+
+// Fail records the error returned to the client.
+func (r *Recorder) Fail(status int, err error) {
+	if r == nil {
+		return
+	}
+	r.status = status
+	if err != nil {
+		r.err = err.Error()
+	}
+}
+
+// Finish hands the exchange to the archiver. Later calls are ignored.
+func (r *Recorder) Finish() {
+	if r == nil || r.finished {
+		return
+	}
+	r.finished = true
+	r.latency = time.Since(r.started)
+	select {
+	case r.archiver.queue <- r:
+	default:
+		log.Warn("transcript: archive queue full, dropping exchange")
+	}
+}
+
+func (r *Recorder) build() *Transcript {
+	t := &Transcript{
+		ID:         uuid.NewString(),
+		Timestamp:  r.started.UTC(),
+		APIKey:     r.meta.APIKey,
+		Tenant:     r.meta.Tenant,
+		Session:    r.meta.Session,
+		Model:      r.meta.Model,
+		Format:     r.meta.Format,
+		Stream:     r.meta.Stream,
+		StatusCode: r.status,
+		Error:      r.err,
+		LatencyMs:  r.latency.Milliseconds(),
+	}
+	t.System, t.Messages = normalizeRequest(r.meta.Format, r.request)
+	if r.meta.Stream {
+		t.Output = assembleStream(r.meta.Format, r.output)
+	} else if len(r.response) > 0 {
+		t.Output = normalizeResponse(r.meta.Format, r.response)
+	}
+	if r.truncated && t.Error == "" {
+		t.Error = "output truncated"
+	}
+	return t
+}
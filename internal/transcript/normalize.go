@@ -0,0 +1,314 @@
+package transcript
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Client-facing formats, named after the API handler types.
+const (
+	formatOpenAI         = "openai"
+	formatOpenAIResponse = "openai-response"
+	formatClaude         = "claude"
+	formatGemini         = "gemini"
+	formatGeminiCLI      = "gemini-cli"
+)
+
+// normalizeRequest reconstructs the system prompt and conversation history from a
+// client request body.
+func normalizeRequest(format string, raw []byte) (string, []Message) {
+	root := gjson.ParseBytes(raw)
+	switch format {
+	case formatClaude:
+		return normalizeClaudeRequest(root)
+	case formatGemini, formatGeminiCLI:
+		if inner := root.Get("request"); inner.IsObject() {
+			root = inner
+		}
+		return normalizeGeminiRequest(root)
+	case formatOpenAIResponse:
+		return normalizeResponsesRequest(root)
+	default:
+		return normalizeOpenAIRequest(root)
+	}
+}
+
+// normalizeResponse extracts the assistant turn from a complete, non-streaming
+// client response body.
+func normalizeResponse(format string, payload []byte) *Message {
+	root := gjson.ParseBytes(payload)
+	var msg Message
+	switch format {
+	case formatClaude:
+		msg = claudeMessage("assistant", root.Get("content"))[0]
+	case formatGemini, formatGeminiCLI:
+		if inner := root.Get("response"); inner.IsObject() {
+			root = inner
+		}
+		msg = geminiMessages("model", root.Get("candidates.0.content.parts"))[0]
+	case formatOpenAIResponse:
+		msg = responsesOutput(root.Get("output"))
+	default:
+		msg = openAIMessage(root.Get("choices.0.message"))
+	}
+	msg.Role = "assistant"
+	if msg.empty() {
+		return nil
+	}
+	return &msg
+}
+
+func (m *Message) empty() bool {
+	return m.Content == "" && m.Reasoning == "" && len(m.ToolCalls) == 0
+}
+
+func normalizeOpenAIRequest(root gjson.Result) (string, []Message) {
+	var system []string
+	var messages []Message
+	root.Get("messages").ForEach(func(_, item gjson.Result) bool {
+		switch role := item.Get("role").String(); role {
+		case "system", "developer":
+			system = appendText(system, contentText(item.Get("content")))
+		default:
+			messages = append(messages, openAIMessage(item))
+		}
+		return true
+	})
+	return strings.Join(system, "\n\n"), messages
+}
+
+func openAIMessage(item gjson.Result) Message {
+	msg := Message{
+		Role:       item.Get("role").String(),
+		Content:    contentText(item.Get("content")),
+		Reasoning:  item.Get("reasoning_content").String(),
+		ToolCallID: item.Get("tool_call_id").String(),
+	}
+	item.Get("tool_calls").ForEach(func(_, call gjson.Result) bool {
+		msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+			ID:        call.Get("id").String(),
+			Name:      call.Get("function.name").String(),
+			Arguments: call.Get("function.arguments").String(),
+		})
+		return true
+	})
+	return msg
+}
+
+func normalizeClaudeRequest(root gjson.Result) (string, []Message) {
+	var messages []Message
+	root.Get("messages").ForEach(func(_, item gjson.Result) bool {
+		for _, msg := range claudeMessage(item.Get("role").String(), item.Get("content")) {
+			if !msg.empty() || msg.ToolCallID != "" {
+				messages = append(messages, msg)
+			}
+		}
+		return true
+	})
+	return contentText(root.Get("system")), messages
+}
+
+// claudeMessage converts a Claude content value into a turn followed by one tool
+// message per tool_result block. The first element is always the turn itself.
+func claudeMessage(role string, content gjson.Result) []Message {
+	out := []Message{{Role: role}}
+	if content.Type == gjson.String {
+		out[0].Content = content.String()
+		return out
+	}
+	var text, reasoning []string
+	content.ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").String() {
+		case "text":
+			text = appendText(text, block.Get("text").String())
+		case "thinking":
+			reasoning = appendText(reasoning, block.Get("thinking").String())
+		case "tool_use", "server_tool_use":
+			out[0].ToolCalls = append(out[0].ToolCalls, ToolCall{
+				ID:        block.Get("id").String(),
+				Name:      block.Get("name").String(),
+				Arguments: block.Get("input").Raw,
+			})
+		case "tool_result":
+			out = append(out, Message{
+				Role:       "tool",
+				ToolCallID: block.Get("tool_use_id").String(),
+				Content:    contentText(block.Get("content")),
+			})
+		case "image", "document":
+			text = append(text, "["+block.Get("type").String()+"]")
+		}
+		return true
+	})
+	out[0].Content = strings.Join(text, "\n")
+	out[0].Reasoning = strings.Join(reasoning, "\n")
+	return out
+}
+
+func normalizeGeminiRequest(root gjson.Result) (string, []Message) {
+	instruction := root.Get("systemInstruction")
+	if !instruction.Exists() {
+		instruction = root.Get("system_instruction")
+	}
+	var system []string
+	instruction.Get("parts").ForEach(func(_, part gjson.Result) bool {
+		system = appendText(system, part.Get("text").String())
+		return true
+	})
+
+	var messages []Message
+	root.Get("contents").ForEach(func(_, item gjson.Result) bool {
+		for _, msg := range geminiMessages(item.Get("role").String(), item.Get("parts")) {
+			if !msg.empty() || msg.ToolCallID != "" {
+				messages = append(messages, msg)
+			}
+		}
+		return true
+	})
+	return strings.Join(system, "\n\n"), messages
+}
+
+// geminiMessages converts Gemini parts into a turn followed by one tool message per
+// functionResponse part. The first element is always the turn itself.
+func geminiMessages(role string, parts gjson.Result) []Message {
+	if role == "model" {
+		role = "assistant"
+	}
+	out := []Message{{Role: role}}
+	var text, reasoning []string
+	parts.ForEach(func(_, part gjson.Result) bool {
+		switch {
+		case part.Get("functionCall").Exists():
+			call := part.Get("functionCall")
+			out[0].ToolCalls = append(out[0].ToolCalls, ToolCall{
+				ID:        call.Get("id").String(),
+				Name:      call.Get("name").String(),
+				Arguments: call.Get("args").Raw,
+			})
+		case part.Get("functionResponse").Exists():
+			resp := part.Get("functionResponse")
+			id := resp.Get("id").String()
+			if id == "" {
+				id = resp.Get("name").String()
+			}
+			out = append(out, Message{Role: "tool", ToolCallID: id, Content: resp.Get("response").Raw})
+		case part.Get("inlineData").Exists() || part.Get("fileData").Exists():
+			text = append(text, "[file]")
+		case part.Get("thought").Bool():
+			reasoning = appendText(reasoning, part.Get("text").String())
+		default:
+			text = appendText(text, part.Get("text").String())
+		}
+		return true
+	})
+	out[0].Content = strings.Join(text, "")
+	out[0].Reasoning = strings.Join(reasoning, "")
+	return out
+}
+
+func normalizeResponsesRequest(root gjson.Result) (string, []Message) {
+	var system []string
+	system = appendText(system, root.Get("instructions").String())
+
+	input := root.Get("input")
+	if input.Type == gjson.String {
+		return strings.Join(system, "\n\n"), []Message{{Role: "user", Content: input.String()}}
+	}
+	var messages []Message
+	input.ForEach(func(_, item gjson.Result) bool {
+		switch itemType := item.Get("type").String(); {
+		case itemType == "function_call":
+			call := ToolCall{
+				ID:        item.Get("call_id").String(),
+				Name:      item.Get("name").String(),
+				Arguments: item.Get("arguments").String(),
+			}
+			// Consecutive calls belong to the same assistant turn.
+			if n := len(messages); n > 0 && messages[n-1].Role == "assistant" && messages[n-1].Content == "" {
+				messages[n-1].ToolCalls = append(messages[n-1].ToolCalls, call)
+			} else {
+				messages = append(messages, Message{Role: "assistant", ToolCalls: []ToolCall{call}})
+			}
+		case itemType == "function_call_output":
+			messages = append(messages, Message{
+				Role:       "tool",
+				ToolCallID: item.Get("call_id").String(),
+				Content:    contentText(item.Get("output")),
+			})
+		case itemType == "message" || (itemType == "" && item.Get("role").Exists()):
+			role := item.Get("role").String()
+			if role == "system" || role == "developer" {
+				system = appendText(system, contentText(item.Get("content")))
+				return true
+			}
+			messages = append(messages, Message{Role: role, Content: contentText(item.Get("content"))})
+		}
+		return true
+	})
+	return strings.Join(system, "\n\n"), messages
+}
+
+func responsesOutput(output gjson.Result) Message {
+	msg := Message{Role: "assistant"}
+	var text, reasoning []string
+	output.ForEach(func(_, item gjson.Result) bool {
+		switch item.Get("type").String() {
+		case "message":
+			text = appendText(text, contentText(item.Get("content")))
+		case "reasoning":
+			item.Get("summary").ForEach(func(_, summary gjson.Result) bool {
+				reasoning = appendText(reasoning, summary.Get("text").String())
+				return true
+			})
+		case "function_call":
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+				ID:        item.Get("call_id").String(),
+				Name:      item.Get("name").String(),
+				Arguments: item.Get("arguments").String(),
+			})
+		}
+		return true
+	})
+	msg.Content = strings.Join(text, "\n")
+	msg.Reasoning = strings.Join(reasoning, "\n")
+	return msg
+}
+
+// contentText flattens a string or an array of content parts into plain text.
+// Non-text parts are replaced with a short placeholder.
+func contentText(value gjson.Result) string {
+	switch {
+	case !value.Exists():
+		return ""
+	case value.Type == gjson.String:
+		return value.String()
+	case !value.IsArray():
+		return value.Get("text").String()
+	}
+	var parts []string
+	value.ForEach(func(_, part gjson.Result) bool {
+		if part.Type == gjson.String {
+			parts = appendText(parts, part.String())
+			return true
+		}
+		switch partType := part.Get("type").String(); partType {
+		case "image", "image_url", "input_image":
+			parts = append(parts, "[image]")
+		case "file", "input_file", "document", "input_audio":
+			parts = append(parts, "[file]")
+		case "tool_use", "tool_result", "thinking", "redacted_thinking":
+		default:
+			parts = appendText(parts, part.Get("text").String())
+		}
+		return true
+	})
+	return strings.Join(parts, "\n")
+}
+
+func appendText(parts []string, text string) []string {
+	if strings.TrimSpace(text) == "" {
+		return parts
+	}
+	return append(parts, text)
+}
@@ -0,0 +1,39 @@
+package transcript
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// sessionHeaders lists request headers clients use to identify a conversation.
+var sessionHeaders = []string{"X-Session-Id", "Session_id", "X-Conversation-Id"}
+
+// SessionID derives the conversation identifier from request headers or well-known
+// body fields. It returns an empty string when the client supplies none.
+func SessionID(header http.Header, body []byte) string {
+	for _, name := range sessionHeaders {
+		if value := strings.TrimSpace(header.Get(name)); value != "" {
+			return value
+		}
+	}
+	for _, path := range []string{"session_id", "metadata.session_id", "prompt_cache_key", "conversation"} {
+		if value := gjson.GetBytes(body, path); value.Type == gjson.String && strings.TrimSpace(value.String()) != "" {
+			return strings.TrimSpace(value.String())
+		}
+	}
+	// Claude Code embeds the session in metadata.user_id, either as JSON or as a
+	// "..._session_<id>" suffix.
+	userID := strings.TrimSpace(gjson.GetBytes(body, "metadata.user_id").String())
+	if userID == "" {
+		return ""
+	}
+	if gjson.Valid(userID) {
+		return strings.TrimSpace(gjson.Get(userID, "session_id").String())
+	}
+	if _, session, found := strings.Cut(userID, "_session_"); found {
+		return session
+	}
+	return ""
+}
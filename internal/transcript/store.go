@@ -0,0 +1,326 @@
+package transcript
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentPrefix = "transcripts-"
+	activeSuffix  = ".jsonl"
+	sealedSuffix  = ".jsonl.gz"
+	segmentDay    = "20060102"
+
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// Store persists transcripts as JSON lines in day-partitioned segment files.
+// The active segment is plain text; once it reaches the size limit or the day
+// changes it is sealed into a gzip-compressed file.
+type Store struct {
+	dir       string
+	maxBytes  int64
+	retention time.Duration
+
+	mu         sync.Mutex
+	active     *os.File
+	activePath string
+	activeDay  string
+	activeSize int64
+}
+
+type segment struct {
+	path   string
+	day    time.Time
+	sealed bool
+}
+
+// OpenStore opens the segment directory, creating it when missing. Segments left
+// active by a previous process are sealed.
+func OpenStore(dir string, maxBytes int64, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("transcript: create directory: %w", err)
+	}
+	s := &Store{dir: dir, maxBytes: maxBytes, retention: retention}
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, seg := range segments {
+		if !seg.sealed {
+			if errSeal := sealFile(seg.path); errSeal != nil {
+				return nil, errSeal
+			}
+		}
+	}
+	return s, nil
+}
+
+// Append writes a transcript to the active segment.
+func (s *Store) Append(t *Transcript) error {
+	line, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("transcript: encode: %w", err)
+	}
+	line = append(line, '\n')
+	day := t.Timestamp.UTC().Format(segmentDay)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil && (s.activeDay != day || (s.activeSize > 0 && s.activeSize+int64(len(line)) > s.maxBytes)) {
+		if errSeal := s.sealActiveLocked(); errSeal != nil {
+			return errSeal
+		}
+	}
+	if s.active == nil {
+		name := segmentPrefix + day + "-" + time.Now().UTC().Format("150405.000000000") + activeSuffix
+		path := filepath.Join(s.dir, name)
+		file, errOpen := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if errOpen != nil {
+			return fmt.Errorf("transcript: open segment: %w", errOpen)
+		}
+		s.active, s.activePath, s.activeDay, s.activeSize = file, path, day, 0
+	}
+	n, err := s.active.Write(line)
+	s.activeSize += int64(n)
+	if err != nil {
+		return fmt.Errorf("transcript: write segment: %w", err)
+	}
+	return nil
+}
+
+// Close seals the active segment.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sealActiveLocked()
+}
+
+func (s *Store) sealActiveLocked() error {
+	if s.active == nil {
+		return nil
+	}
+	errClose := s.active.Close()
+	path := s.activePath
+	s.active, s.activePath, s.activeDay, s.activeSize = nil, "", "", 0
+	if errClose != nil {
+		return fmt.Errorf("transcript: close segment: %w", errClose)
+	}
+	return sealFile(path)
+}
+
+// sealFile compresses a plain segment and removes the original.
+func sealFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("transcript: open segment: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+
+	target := strings.TrimSuffix(path, activeSuffix) + sealedSuffix
+	tmp := target + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("transcript: create sealed segment: %w", err)
+	}
+	zw := gzip.NewWriter(dst)
+	_, errCopy := io.Copy(zw, src)
+	errZip := zw.Close()
+	errDst := dst.Close()
+	if err = errors.Join(errCopy, errZip, errDst); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("transcript: seal segment: %w", err)
+	}
+	if err = os.Rename(tmp, target); err != nil {
+		return fmt.Errorf("transcript: seal segment: %w", err)
+	}
+	return os.Remove(path)
+}
+
+// Prune removes segments older than the retention period and returns the number deleted.
+func (s *Store) Prune(now time.Time) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	segments, err := s.segments()
+	if err != nil {
+		return 0, err
+	}
+	// Segments hold a single UTC day and are removed once every entry is past the
+	// retention period.
+	cutoffDay := now.UTC().Add(-s.retention).Truncate(24 * time.Hour)
+
+	s.mu.Lock()
+	activePath := s.activePath
+	s.mu.Unlock()
+
+	removed := 0
+	for _, seg := range segments {
+		if seg.path == activePath || !seg.day.Before(cutoffDay) {
+			continue
+		}
+		if errRemove := os.Remove(seg.path); errRemove != nil && !os.IsNotExist(errRemove) {
+			return removed, fmt.Errorf("transcript: remove expired segment: %w", errRemove)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Search returns transcripts matching q, newest first, up to q.Limit entries.
+func (s *Store) Search(q Query) ([]Transcript, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	segments, err := s.segmentsInRange(q)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Transcript, 0)
+	for i := len(segments) - 1; i >= 0 && len(results) < limit; i-- {
+		var matched []Transcript
+		if err = readSegment(segments[i], func(t *Transcript) error {
+			if q.Matches(t) {
+				matched = append(matched, *t)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		for j := len(matched) - 1; j >= 0 && len(results) < limit; j-- {
+			results = append(results, matched[j])
+		}
+	}
+	return results, nil
+}
+
+// Export streams every transcript matching q to fn in chronological order.
+// q.Limit is ignored.
+func (s *Store) Export(q Query, fn func(*Transcript) error) error {
+	segments, err := s.segmentsInRange(q)
+	if err != nil {
+		return err
+	}
+	for _, seg := range segments {
+		if err = readSegment(seg, func(t *Transcript) error {
+			if !q.Matches(t) {
+				return nil
+			}
+			return fn(t)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) segmentsInRange(q Query) ([]segment, error) {
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	out := segments[:0]
+	for _, seg := range segments {
+		if !q.From.IsZero() && seg.day.Add(24*time.Hour).Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && seg.day.After(q.To) {
+			continue
+		}
+		out = append(out, seg)
+	}
+	return out, nil
+}
+
+// segments lists segment files in chronological order.
+func (s *Store) segments() ([]segment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("transcript: list segments: %w", err)
+	}
+	var out []segment
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) {
+			continue
+		}
+		sealed := strings.HasSuffix(name, sealedSuffix)
+		if !sealed && !strings.HasSuffix(name, activeSuffix) {
+			continue
+		}
+		rest := strings.TrimPrefix(name, segmentPrefix)
+		if len(rest) < len(segmentDay) {
+			continue
+		}
+		day, errParse := time.ParseInLocation(segmentDay, rest[:len(segmentDay)], time.UTC)
+		if errParse != nil {
+			continue
+		}
+		out = append(out, segment{path: filepath.Join(s.dir, name), day: day, sealed: sealed})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].path < out[j].path })
+	return out, nil
+}
+
+// readSegment decodes every transcript in a segment. Lines that fail to decode,
+// such as a partially written tail, are skipped.
+func readSegment(seg segment, fn func(*Transcript) error) error {
+	file, err := os.Open(seg.path)
+	if err != nil && os.IsNotExist(err) && !seg.sealed {
+		// The segment was sealed after it was listed.
+		seg.path = strings.TrimSuffix(seg.path, activeSuffix) + sealedSuffix
+		seg.sealed = true
+		file, err = os.Open(seg.path)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("transcript: open segment: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var reader io.Reader = file
+	if seg.sealed {
+		zr, errZip := gzip.NewReader(file)
+		if errZip != nil {
+			return fmt.Errorf("transcript: open sealed segment %s: %w", filepath.Base(seg.path), errZip)
+		}
+		defer func() { _ = zr.Close() }()
+		reader = zr
+	}
+
+	buffered := bufio.NewReader(reader)
+	for {
+		line, errRead := buffered.ReadBytes('\n')
+		if len(line) > 0 {
+			var t Transcript
+			if json.Unmarshal(line, &t) == nil {
+				if err = fn(&t); err != nil {
+					return err
+				}
+			}
+		}
+		if errRead != nil {
+			if errRead == io.EOF || errors.Is(errRead, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return fmt.Errorf("transcript: read segment %s: %w", filepath.Base(seg.path), errRead)
+		}
+	}
+}
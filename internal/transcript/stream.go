@@ -0,0 +1,137 @@
+package transcript
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// streamAssembler rebuilds the assistant turn from client-facing stream events.
+type streamAssembler struct {
+	format    string
+	content   strings.Builder
+	reasoning strings.Builder
+	calls     []ToolCall
+	// callIndex maps the stream's tool call (or content block) index to calls.
+	callIndex map[int64]int
+	// final is set when the stream carries a complete response, as the
+	// Responses API does with response.completed.
+	final *Message
+}
+
+// assembleStream parses the concatenated stream output sent to the client.
+func assembleStream(format string, data []byte) *Message {
+	a := &streamAssembler{format: format, callIndex: make(map[int64]int)}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if event, ok := streamEvent(line); ok {
+			a.consume(event)
+		}
+	}
+	if a.final != nil {
+		return a.final
+	}
+	msg := Message{
+		Role:      "assistant",
+		Content:   a.content.String(),
+		Reasoning: a.reasoning.String(),
+		ToolCalls: a.calls,
+	}
+	if msg.empty() {
+		return nil
+	}
+	return &msg
+}
+
+// streamEvent extracts the JSON event carried by an SSE data line. Gemini streams
+// without SSE framing emit a JSON array split across lines, so bare objects with
+// array punctuation are accepted too.
+func streamEvent(line []byte) (gjson.Result, bool) {
+	line = bytes.TrimSpace(line)
+	if bytes.HasPrefix(line, []byte("data:")) {
+		line = bytes.TrimSpace(line[5:])
+	} else {
+		line = bytes.Trim(line, "[],")
+	}
+	if len(line) == 0 || line[0] != '{' || !gjson.ValidBytes(line) {
+		return gjson.Result{}, false
+	}
+	return gjson.ParseBytes(line), true
+}
+
+func (a *streamAssembler) consume(event gjson.Result) {
+	switch a.format {
+	case formatClaude:
+		a.consumeClaude(event)
+	case formatGemini, formatGeminiCLI:
+		if inner := event.Get("response"); inner.IsObject() {
+			event = inner
+		}
+		msg := geminiMessages("model", event.Get("candidates.0.content.parts"))[0]
+		a.content.WriteString(msg.Content)
+		a.reasoning.WriteString(msg.Reasoning)
+		a.calls = append(a.calls, msg.ToolCalls...)
+	case formatOpenAIResponse:
+		switch event.Get("type").String() {
+		case "response.output_text.delta":
+			a.content.WriteString(event.Get("delta").String())
+		case "response.completed":
+			if msg := normalizeResponse(formatOpenAIResponse, []byte(event.Get("response").Raw)); msg != nil {
+				a.final = msg
+			}
+		}
+	default:
+		a.consumeOpenAI(event)
+	}
+}
+
+func (a *streamAssembler) consumeOpenAI(event gjson.Result) {
+	delta := event.Get("choices.0.delta")
+	a.content.WriteString(delta.Get("content").String())
+	a.reasoning.WriteString(delta.Get("reasoning_content").String())
+	delta.Get("tool_calls").ForEach(func(_, call gjson.Result) bool {
+		tc := a.toolCall(call.Get("index").Int())
+		if id := call.Get("id").String(); id != "" {
+			tc.ID = id
+		}
+		if name := call.Get("function.name").String(); name != "" {
+			tc.Name = name
+		}
+		tc.Arguments += call.Get("function.arguments").String()
+		return true
+	})
+}
+
+func (a *streamAssembler) consumeClaude(event gjson.Result) {
+	switch event.Get("type").String() {
+	case "content_block_start":
+		block := event.Get("content_block")
+		switch block.Get("type").String() {
+		case "tool_use", "server_tool_use":
+			tc := a.toolCall(event.Get("index").Int())
+			tc.ID = block.Get("id").String()
+			tc.Name = block.Get("name").String()
+		}
+	case "content_block_delta":
+		delta := event.Get("delta")
+		switch delta.Get("type").String() {
+		case "text_delta":
+			a.content.WriteString(delta.Get("text").String())
+		case "thinking_delta":
+			a.reasoning.WriteString(delta.Get("thinking").String())
+		case "input_json_delta":
+			tc := a.toolCall(event.Get("index").Int())
+			tc.Arguments += delta.Get("partial_json").String()
+		}
+	}
+}
+
+func (a *streamAssembler) toolCall(index int64) *ToolCall {
+	pos, ok := a.callIndex[index]
+	if !ok {
+		pos = len(a.calls)
+		a.calls = append(a.calls, ToolCall{})
+		a.callIndex[index] = pos
+	}
+	return &a.calls[pos]
+}
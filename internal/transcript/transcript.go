@@ -0,0 +1,112 @@
+// Package transcript archives normalized client conversations for audit retention.
+//
+// Requests and responses are captured in the client-facing format, reconstructed
+// into a format-neutral conversation, and appended to compressed segment files
+// that can be searched and exported through the management API.
+package transcript
+
+import (
+	"strings"
+	"time"
+)
+
+// Transcript is a single archived exchange between a client and a model.
+type Transcript struct {
+	ID         string    `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	APIKey     string    `json:"api_key"`
+	Tenant     string    `json:"tenant,omitempty"`
+	Session    string    `json:"session,omitempty"`
+	Model      string    `json:"model"`
+	Format     string    `json:"format"`
+	Stream     bool      `json:"stream,omitempty"`
+	System     string    `json:"system,omitempty"`
+	Messages   []Message `json:"messages"`
+	Output     *Message  `json:"output,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	LatencyMs  int64     `json:"latency_ms"`
+}
+
+// Message is one conversation turn. Tool results use the "tool" role and carry
+// the ID of the call they answer.
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content,omitempty"`
+	Reasoning  string     `json:"reasoning,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// ToolCall is a function invocation requested by the model.
+type ToolCall struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// Query selects archived transcripts. Zero-valued fields do not filter.
+type Query struct {
+	APIKey  string
+	Tenant  string
+	Session string
+	Model   string
+	From    time.Time
+	To      time.Time
+	// Text matches case-insensitively against system prompts, messages and output.
+	Text  string
+	Limit int
+}
+
+// Matches reports whether t satisfies every filter in q.
+func (q Query) Matches(t *Transcript) bool {
+	if t == nil {
+		return false
+	}
+	if q.APIKey != "" && t.APIKey != q.APIKey {
+		return false
+	}
+	if q.Tenant != "" && t.Tenant != q.Tenant {
+		return false
+	}
+	if q.Session != "" && t.Session != q.Session {
+		return false
+	}
+	if q.Model != "" && !strings.EqualFold(t.Model, q.Model) {
+		return false
+	}
+	if !q.From.IsZero() && t.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && t.Timestamp.After(q.To) {
+		return false
+	}
+	if q.Text != "" && !t.containsText(strings.ToLower(q.Text)) {
+		return false
+	}
+	return true
+}
+
+func (t *Transcript) containsText(needle string) bool {
+	if strings.Contains(strings.ToLower(t.System), needle) {
+		return true
+	}
+	for i := range t.Messages {
+		if t.Messages[i].containsText(needle) {
+			return true
+		}
+	}
+	return t.Output != nil && t.Output.containsText(needle)
+}
+
+func (m *Message) containsText(needle string) bool {
+	if strings.Contains(strings.ToLower(m.Content), needle) {
+		return true
+	}
+	for _, call := range m.ToolCalls {
+		if strings.Contains(strings.ToLower(call.Name), needle) || strings.Contains(strings.ToLower(call.Arguments), needle) {
+			return true
+		}
+	}
+	return false
+}
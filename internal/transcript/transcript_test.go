@@ -0,0 +1,203 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestNormalizeClaudeRequestWithTools(t *testing.T) {
+	raw := []byte(`{
+		"system": [{"type":"text","text":"Be brief."}],
+		"messages": [
+			{"role":"user","content":"Weather in Paris?"},
+			{"role":"assistant","content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"tu_1","name":"weather","input":{"city":"Paris"}}]},
+			{"role":"user","content":[{"type":"tool_result","tool_use_id":"tu_1","content":"18C"}]}
+		]
+	}`)
+	system, messages := normalizeRequest(formatClaude, raw)
+	if system != "Be brief." {
+		t.Fatalf("system = %q", system)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("messages = %+v, want 3 entries", messages)
+	}
+	call := messages[1].ToolCalls
+	if len(call) != 1 || call[0].ID != "tu_1" || call[0].Name != "weather" || call[0].Arguments != `{"city":"Paris"}` {
+		t.Fatalf("tool calls = %+v", call)
+	}
+	if messages[2].Role != "tool" || messages[2].ToolCallID != "tu_1" || messages[2].Content != "18C" {
+		t.Fatalf("tool result = %+v", messages[2])
+	}
+}
+
+func TestNormalizeResponsesRequest(t *testing.T) {
+	raw := []byte(`{
+		"instructions": "You are terse.",
+		"input": [
+			{"role":"developer","content":"Use metric units."},
+			{"type":"message","role":"user","content":[{"type":"input_text","text":"Weather?"}]},
+			{"type":"function_call","call_id":"c1","name":"weather","arguments":"{}"},
+			{"type":"function_call_output","call_id":"c1","output":"18C"}
+		]
+	}`)
+	system, messages := normalizeRequest(formatOpenAIResponse, raw)
+	if system != "You are terse.\n\nUse metric units." {
+		t.Fatalf("system = %q", system)
+	}
+	if len(messages) != 3 || messages[0].Content != "Weather?" || messages[1].ToolCalls[0].ID != "c1" || messages[2].ToolCallID != "c1" {
+		t.Fatalf("messages = %+v", messages)
+	}
+}
+
+func TestAssembleStream(t *testing.T) {
+	cases := []struct {
+		name   string
+		format string
+		stream string
+		want   Message
+	}{
+		{
+			name:   "openai",
+			format: formatOpenAI,
+			stream: "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"lo\",\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"function\":{\"name\":\"lookup\",\"arguments\":\"{\\\"q\\\":\"}}]}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"1}\"}}]}}]}\n\n" +
+				"data: [DONE]\n\n",
+			want: Message{Role: "assistant", Content: "Hello", ToolCalls: []ToolCall{{ID: "call_1", Name: "lookup", Arguments: `{"q":1}`}}},
+		},
+		{
+			name:   "claude",
+			format: formatClaude,
+			stream: "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n" +
+				"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"tu_1\",\"name\":\"run\"}}\n\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{}\"}}\n\n",
+			want: Message{Role: "assistant", Content: "Hi", ToolCalls: []ToolCall{{ID: "tu_1", Name: "run", Arguments: "{}"}}},
+		},
+		{
+			name:   "gemini without sse framing",
+			format: formatGemini,
+			stream: "[{\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Bon\"}]}}]}\n,{\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"jour\"}]}}]}\n]",
+			want:   Message{Role: "assistant", Content: "Bonjour"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := assembleStream(tc.format, []byte(tc.stream))
+			if got == nil {
+				t.Fatal("assembleStream() = nil")
+			}
+			if got.Content != tc.want.Content || len(got.ToolCalls) != len(tc.want.ToolCalls) {
+				t.Fatalf("assembleStream() = %+v, want %+v", got, tc.want)
+			}
+			for i := range got.ToolCalls {
+				if got.ToolCalls[i] != tc.want.ToolCalls[i] {
+					t.Fatalf("tool call %d = %+v, want %+v", i, got.ToolCalls[i], tc.want.ToolCalls[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSessionID(t *testing.T) {
+	body := []byte(`{"metadata":{"user_id":"user_abc_account__session_5f1c"}}`)
+	if got := SessionID(nil, body); got != "5f1c" {
+		t.Fatalf("SessionID() = %q, want 5f1c", got)
+	}
+}
+
+func TestStoreSearchExportAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenStore(dir, 512, 90*24*time.Hour)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	now := time.Now().UTC()
+	entries := []Transcript{
+		{ID: "old", Timestamp: now.Add(-120 * 24 * time.Hour), APIKey: "k1", Model: "gpt", Messages: []Message{{Role: "user", Content: "ancient"}}},
+		{ID: "a", Timestamp: now.Add(-2 * time.Minute), APIKey: "k1", Model: "gpt", Messages: []Message{{Role: "user", Content: "Invoice total?"}}},
+		{ID: "b", Timestamp: now.Add(-time.Minute), APIKey: "k2", Model: "claude", Messages: []Message{{Role: "user", Content: "hello"}}},
+		{ID: "c", Timestamp: now, APIKey: "k1", Model: "gpt", Output: &Message{Role: "assistant", Content: "The INVOICE is paid"}},
+	}
+	for i := range entries {
+		if err = store.Append(&entries[i]); err != nil {
+			t.Fatalf("Append(%s) error = %v", entries[i].ID, err)
+		}
+	}
+
+	got, err := store.Search(Query{APIKey: "k1", Text: "invoice"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "c" || got[1].ID != "a" {
+		t.Fatalf("Search() = %+v, want c then a", ids(got))
+	}
+
+	if err = store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	sealed, _ := filepath.Glob(filepath.Join(dir, "*"+sealedSuffix))
+	plain, _ := filepath.Glob(filepath.Join(dir, "*"+activeSuffix))
+	if len(sealed) == 0 || len(plain) != 0 {
+		t.Fatalf("segments after close: sealed=%v plain=%v", sealed, plain)
+	}
+
+	removed, err := store.Prune(now)
+	if err != nil || removed != 1 {
+		t.Fatalf("Prune() = %d, %v; want 1 removal", removed, err)
+	}
+	var exported []Transcript
+	if err = store.Export(Query{}, func(t *Transcript) error {
+		exported = append(exported, *t)
+		return nil
+	}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if strings.Join(ids(exported), ",") != "a,b,c" {
+		t.Fatalf("Export() = %v, want a,b,c", ids(exported))
+	}
+}
+
+func TestArchiverRecordsExchange(t *testing.T) {
+	dir := t.TempDir()
+	archiver, err := New(config.TranscriptsConfig{Enabled: true, Dir: dir, APIKeys: []string{"client"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if rec := archiver.NewRecorder(Meta{APIKey: "other"}, nil); rec != nil {
+		t.Fatal("NewRecorder() for a client outside api-keys should be nil")
+	}
+
+	rec := archiver.NewRecorder(Meta{APIKey: "client", Session: "s1", Model: "gpt", Format: formatOpenAI}, []byte(`{"messages":[{"role":"system","content":"sys"},{"role":"user","content":"ping"}]}`))
+	rec.Complete([]byte(`{"choices":[{"message":{"role":"assistant","content":"pong"}}]}`))
+	rec.Finish()
+	archiver.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("segments = %v, %v", entries, err)
+	}
+	store, err := OpenStore(dir, 1<<20, 0)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	got, err := store.Search(Query{Session: "s1"})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("Search() = %+v, %v", got, err)
+	}
+	if got[0].System != "sys" || got[0].Messages[0].Content != "ping" || got[0].Output == nil || got[0].Output.Content != "pong" {
+		t.Fatalf("archived transcript = %+v", got[0])
+	}
+}
+
+func ids(list []Transcript) []string {
+	out := make([]string, 0, len(list))
+	for _, t := range list {
+		out = append(out, t.ID)
+	}
+	return out
+}
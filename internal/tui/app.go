@@ -19,6 +19,7 @@ const (
 	tabAPIKeys
 	tabOAuth
 	tabUsage
+	tabQuota
 	tabLogs
 )
 
@@ -41,6 +42,7 @@ type App struct {
 	keys      keysTabModel
 	oauth     oauthTabModel
 	usage     usageTabModel
+	quota     quotaTabModel
 	logs      logsTabModel
 
 	client *Client
@@ -50,7 +52,7 @@ type App struct {
 	ready  bool
 
 	// Track which tabs have been initialized (fetched data)
-	initialized [7]bool
+	initialized [8]bool
 }
 
 type authConnectMsg struct {
@@ -82,9 +84,10 @@ func NewApp(port int, secretKey string, hook *LogHook) App {
 		keys:          newKeysTabModel(client),
 		oauth:         newOAuthTabModel(client),
 		usage:         newUsageTabModel(client),
+		quota:         newQuotaTabModel(client),
 		logs:          newLogsTabModel(client, hook),
 		client:        client,
-		initialized: [7]bool{
+		initialized: [8]bool{
 			tabDashboard: true,
 			tabLogs:      true,
 		},
@@ -92,7 +95,7 @@ func NewApp(port int, secretKey string, hook *LogHook) App {
 
 	app.refreshTabs()
 	if authRequired {
-		app.initialized = [7]bool{}
+		app.initialized = [8]bool{}
 	}
 	app.setAuthInputPrompt()
 	return app
@@ -129,6 +132,7 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.keys.SetSize(contentW, contentH)
 		a.oauth.SetSize(contentW, contentH)
 		a.usage.SetSize(contentW, contentH)
+		a.quota.SetSize(contentW, contentH)
 		a.logs.SetSize(contentW, contentH)
 		return a, nil
 
@@ -142,7 +146,7 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.authenticated = true
 		a.logsEnabled = a.standalone || isLogsEnabledFromConfig(msg.cfg)
 		a.refreshTabs()
-		a.initialized = [7]bool{}
+		a.initialized = [8]bool{}
 		a.initialized[tabDashboard] = true
 		cmds := []tea.Cmd{a.dashboard.Init()}
 		if a.logsEnabled {
@@ -260,6 +264,8 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.oauth, cmd = a.oauth.Update(msg)
 	case tabUsage:
 		a.usage, cmd = a.usage.Update(msg)
+	case tabQuota:
+		a.quota, cmd = a.quota.Update(msg)
 	case tabLogs:
 		a.logs, cmd = a.logs.Update(msg)
 	}
@@ -324,6 +330,8 @@ func (a *App) initTabIfNeeded(_ int) tea.Cmd {
 		return a.oauth.Init()
 	case tabUsage:
 		return a.usage.Init()
+	case tabQuota:
+		return a.quota.Init()
 	case tabLogs:
 		if !a.logsEnabled {
 			return nil
@@ -362,6 +370,8 @@ func (a App) View() string {
 		sb.WriteString(a.oauth.View())
 	case tabUsage:
 		sb.WriteString(a.usage.View())
+	case tabQuota:
+		sb.WriteString(a.quota.View())
 	case tabLogs:
 		if a.logsEnabled {
 			sb.WriteString(a.logs.View())
@@ -533,6 +543,10 @@ func (a App) broadcastToAllTabs(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if cmd != nil {
 		cmds = append(cmds, cmd)
 	}
+	a.quota, cmd = a.quota.Update(msg)
+	if cmd != nil {
+		cmds = append(cmds, cmd)
+	}
 	a.logs, cmd = a.logs.Update(msg)
 	if cmd != nil {
 		cmds = append(cmds, cmd)
@@ -43,6 +43,8 @@ func (c *Client) doRequest(method, path string, body io.Reader) ([]byte, int, er
 	if c.secretKey != "" {
 		req.Header.Set("Authorization", "Bearer "+c.secretKey)
 	}
+	// Label config versions written from the TUI in the config history.
+	req.Header.Set("X-Config-Author", "tui")
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
@@ -145,6 +147,25 @@ func (c *Client) GetUsage() (map[string]any, error) {
 	return c.getJSON("/v0/management/usage")
 }
 
+// GetQuota fetches the quota view; probe asks the server to re-probe first.
+// API returns {"polling": {...}, "credentials": [...]}.
+func (c *Client) GetQuota(probe bool) ([]map[string]any, map[string]any, error) {
+	path := "/v0/management/quota"
+	if probe {
+		path += "?refresh=true"
+	}
+	wrapper, err := c.getJSON(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	credentials, err := extractList(wrapper, "credentials")
+	if err != nil {
+		return nil, nil, err
+	}
+	polling, _ := wrapper["polling"].(map[string]any)
+	return credentials, polling, nil
+}
+
 // GetAuthFiles lists auth credential files.
 // API returns {"files": [...]}.
 func (c *Client) GetAuthFiles() ([]map[string]any, error) {
@@ -370,6 +391,46 @@ func (c *Client) GetAuthStatus(state string) (string, string, error) {
 	return status, errMsg, nil
 }
 
+// StartLogin starts a brokered login session for a provider and returns the
+// session, which carries an auth_url or a verification_url and user_code.
+func (c *Client) StartLogin(provider string) (map[string]any, error) {
+	body, _ := json.Marshal(map[string]any{"provider": provider})
+	data, code, err := c.doRequest("POST", "/v0/management/logins", strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	if code >= 400 {
+		return nil, fmt.Errorf("HTTP %d: %s", code, strings.TrimSpace(string(data)))
+	}
+	var session map[string]any
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetLogin fetches the current state of a login session.
+func (c *Client) GetLogin(id string) (map[string]any, error) {
+	return c.getJSON("/v0/management/logins/" + url.PathEscape(id))
+}
+
+// SubmitLoginCallback hands the pasted callback URL to a waiting login session.
+func (c *Client) SubmitLoginCallback(id, redirectURL string) error {
+	return c.postJSON("/v0/management/logins/"+url.PathEscape(id)+"/callback", map[string]string{"redirect_url": redirectURL})
+}
+
+// CancelLogin aborts a running login session.
+func (c *Client) CancelLogin(id string) error {
+	_, code, err := c.doRequest("DELETE", "/v0/management/logins/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	if code >= 400 {
+		return fmt.Errorf("cancel failed (HTTP %d)", code)
+	}
+	return nil
+}
+
 // ----- Config field update methods -----
 
 // PutBoolField updates a boolean config field.
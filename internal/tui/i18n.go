@@ -50,8 +50,8 @@ var locales = map[string]map[string]string{
 // ──────────────────────────────────────────
 // Tab names
 // ──────────────────────────────────────────
-var zhTabNames = []string{"仪表盘", "配置", "认证文件", "API 密钥", "OAuth", "使用统计", "日志"}
-var enTabNames = []string{"Dashboard", "Config", "Auth Files", "API Keys", "OAuth", "Usage", "Logs"}
+var zhTabNames = []string{"仪表盘", "配置", "认证文件", "API 密钥", "OAuth", "使用统计", "配额", "日志"}
+var enTabNames = []string{"Dashboard", "Config", "Auth Files", "API Keys", "OAuth", "Usage", "Quota", "Logs"}
 
 // TabNames returns tab names in the current locale.
 func TabNames() []string {
@@ -171,7 +171,7 @@ var zhStrings = map[string]string{
 	"oauth_success":      "认证成功! 请刷新 Auth Files 标签查看新凭证。",
 	"oauth_completed":    "认证流程已完成。",
 	"oauth_failed":       "认证失败",
-	"oauth_timeout":      "OAuth 流程超时 (5 分钟)",
+	"oauth_timeout":      "OAuth 流程超时 (10 分钟)",
 	"oauth_press_esc":    "  按 [Esc] 取消",
 	"oauth_auth_url":     "  授权链接:",
 	"oauth_remote_hint":  "  远程浏览器模式：在浏览器中打开上述链接完成授权后，将回调 URL 粘贴到下方。",
@@ -181,6 +181,8 @@ var zhStrings = map[string]string{
 	"oauth_submit_ok":    "✓ 回调已提交，等待处理...",
 	"oauth_submit_fail":  "✗ 提交回调失败",
 	"oauth_waiting":      "  等待认证中...",
+	"oauth_user_code":    "  验证码:",
+	"oauth_device_hint":  "  在浏览器中打开上述链接并输入验证码，完成后将自动保存凭证。[Esc] 取消",
 
 	// ── Usage ──
 	"usage_title":         "📈 使用统计",
@@ -203,6 +205,24 @@ var zhStrings = map[string]string{
 	"usage_reasoning":     "思考",
 	"usage_time":          "时间",
 
+	// ── Quota ──
+	"quota_title":       "🔋 配额",
+	"quota_help":        " [r] 刷新 • [p] 立即探测 • [↑↓] 滚动",
+	"quota_no_data":     "  没有 OAuth 凭证",
+	"quota_polling_off": "  后台配额轮询未启用 (quota-polling.enabled)",
+	"quota_provider":    "提供商",
+	"quota_account":     "账号",
+	"quota_plan":        "套餐",
+	"quota_remaining":   "剩余",
+	"quota_reset":       "重置时间",
+	"quota_state":       "状态",
+	"quota_unlimited":   "无限",
+	"quota_disabled":    "已禁用",
+	"quota_low":         "配额不足",
+	"quota_cooling":     "冷却中",
+	"quota_no_probe":    "不支持探测",
+	"quota_ok":          "正常",
+
 	// ── Logs ──
 	"logs_title":       "📋 日志",
 	"logs_auto_scroll": "● 自动滚动",
@@ -323,7 +343,7 @@ var enStrings = map[string]string{
 	"oauth_success":      "Authentication successful! Refresh Auth Files tab to see the new credential.",
 	"oauth_completed":    "Authentication flow completed.",
 	"oauth_failed":       "Authentication failed",
-	"oauth_timeout":      "OAuth flow timed out (5 minutes)",
+	"oauth_timeout":      "OAuth flow timed out (10 minutes)",
 	"oauth_press_esc":    "  Press [Esc] to cancel",
 	"oauth_auth_url":     "  Authorization URL:",
 	"oauth_remote_hint":  "  Remote browser mode: Open the URL above in browser, paste the callback URL below after authorization.",
@@ -333,6 +353,8 @@ var enStrings = map[string]string{
 	"oauth_submit_ok":    "✓ Callback submitted, waiting...",
 	"oauth_submit_fail":  "✗ Callback submission failed",
 	"oauth_waiting":      "  Waiting for authentication...",
+	"oauth_user_code":    "  User code:",
+	"oauth_device_hint":  "  Open the URL above and enter the code; the credential is saved once approved. [Esc] to cancel",
 
 	// ── Usage ──
 	"usage_title":         "📈 Usage Statistics",
@@ -355,6 +377,24 @@ var enStrings = map[string]string{
 	"usage_reasoning":     "Reasoning",
 	"usage_time":          "Time",
 
+	// ── Quota ──
+	"quota_title":       "🔋 Quota",
+	"quota_help":        " [r] Refresh • [p] Probe now • [↑↓] Scroll",
+	"quota_no_data":     "  No OAuth credentials",
+	"quota_polling_off": "  Background quota polling is off (quota-polling.enabled)",
+	"quota_provider":    "Provider",
+	"quota_account":     "Account",
+	"quota_plan":        "Plan",
+	"quota_remaining":   "Remaining",
+	"quota_reset":       "Resets",
+	"quota_state":       "State",
+	"quota_unlimited":   "unlimited",
+	"quota_disabled":    "disabled",
+	"quota_low":         "low",
+	"quota_cooling":     "cooling down",
+	"quota_no_probe":    "no probe",
+	"quota_ok":          "ok",
+
 	// ── Logs ──
 	"logs_title":       "📋 Logs",
 	"logs_auto_scroll": "● AUTO-SCROLL",
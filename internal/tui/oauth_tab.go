@@ -13,19 +13,21 @@ import (
 
 // oauthProvider represents an OAuth provider option.
 type oauthProvider struct {
-	name    string
-	apiPath string // management API path
-	emoji   string
+	name  string
+	key   string // login broker provider key
+	emoji string
 }
 
 var oauthProviders = []oauthProvider{
-	{"Gemini CLI", "gemini-cli-auth-url", "🟦"},
-	{"Claude (Anthropic)", "anthropic-auth-url", "🟧"},
-	{"Codex (OpenAI)", "codex-auth-url", "🟩"},
-	{"Antigravity", "antigravity-auth-url", "🟪"},
-	{"Qwen", "qwen-auth-url", "🟨"},
-	{"Kimi", "kimi-auth-url", "🟫"},
-	{"IFlow", "iflow-auth-url", "⬜"},
+	{"Gemini CLI", "gemini", "🟦"},
+	{"Claude (Anthropic)", "claude", "🟧"},
+	{"Codex (OpenAI)", "codex", "🟩"},
+	{"Antigravity", "antigravity", "🟪"},
+	{"Qwen", "qwen", "🟨"},
+	{"Kimi", "kimi", "🟫"},
+	{"IFlow", "iflow", "⬜"},
+	{"GitHub Copilot", "github-copilot", "⬛"},
+	{"Kilo", "kilo", "🔶"},
 }
 
 // oauthTabModel handles OAuth login flows.
@@ -41,8 +43,9 @@ type oauthTabModel struct {
 	ready    bool
 
 	// Remote browser mode
-	authURL       string // auth URL to display
-	authState     string // OAuth state parameter
+	authURL       string // auth or verification URL to display
+	userCode      string // device code to enter at authURL, if any
+	loginID       string // login broker session ID
 	providerName  string // current provider name
 	callbackInput textinput.Model
 	inputActive   bool // true when user is typing callback URL
@@ -61,12 +64,14 @@ const (
 // Messages
 type oauthStartMsg struct {
 	url          string
-	state        string
+	userCode     string
+	loginID      string
 	providerName string
 	err          error
 }
 
 type oauthPollMsg struct {
+	loginID string
 	done    bool
 	message string
 	err     error
@@ -105,18 +110,27 @@ func (m oauthTabModel) Update(msg tea.Msg) (oauthTabModel, tea.Cmd) {
 			return m, nil
 		}
 		m.authURL = msg.url
-		m.authState = msg.state
+		m.userCode = msg.userCode
+		m.loginID = msg.loginID
 		m.providerName = msg.providerName
 		m.state = oauthRemote
 		m.callbackInput.SetValue("")
-		m.callbackInput.Focus()
-		m.inputActive = true
 		m.message = ""
+		// Device-code flows complete on their own; only browser flows take a callback URL.
+		cmds := []tea.Cmd{m.pollOAuthStatus(msg.loginID)}
+		if m.userCode == "" {
+			m.callbackInput.Focus()
+			m.inputActive = true
+			cmds = append(cmds, textinput.Blink)
+		}
 		m.viewport.SetContent(m.renderContent())
-		// Also start polling in the background
-		return m, tea.Batch(textinput.Blink, m.pollOAuthStatus(msg.state))
+		return m, tea.Batch(cmds...)
 
 	case oauthPollMsg:
+		if msg.loginID != m.loginID {
+			// Result of a session that was canceled or replaced.
+			return m, nil
+		}
 		if msg.err != nil {
 			m.state = oauthError
 			m.err = msg.err
@@ -174,18 +188,23 @@ func (m oauthTabModel) Update(msg tea.Msg) (oauthTabModel, tea.Cmd) {
 		if m.state == oauthRemote {
 			switch msg.String() {
 			case "c", "C":
+				if m.userCode != "" {
+					return m, nil
+				}
 				// Re-activate input
 				m.inputActive = true
 				m.callbackInput.Focus()
 				m.viewport.SetContent(m.renderContent())
 				return m, textinput.Blink
 			case "esc":
+				cancel := m.cancelLogin(m.loginID)
 				m.state = oauthIdle
 				m.message = ""
 				m.authURL = ""
-				m.authState = ""
+				m.userCode = ""
+				m.loginID = ""
 				m.viewport.SetContent(m.renderContent())
-				return m, nil
+				return m, cancel
 			}
 			var cmd tea.Cmd
 			m.viewport, cmd = m.viewport.Update(msg)
@@ -245,98 +264,89 @@ func (m oauthTabModel) Update(msg tea.Msg) (oauthTabModel, tea.Cmd) {
 
 func (m oauthTabModel) startOAuth(provider oauthProvider) tea.Cmd {
 	return func() tea.Msg {
-		// Call the auth URL endpoint with is_webui=true
-		data, err := m.client.getJSON("/v0/management/" + provider.apiPath + "?is_webui=true")
+		session, err := m.client.StartLogin(provider.key)
 		if err != nil {
 			return oauthStartMsg{err: fmt.Errorf("failed to start %s login: %w", provider.name, err)}
 		}
+		loginID := getString(session, "id")
+
+		// The broker returns once the provider announced its challenge; keep
+		// polling briefly in case the provider was slow to respond.
+		deadline := time.Now().Add(30 * time.Second)
+		for getString(session, "status") == "pending" && time.Now().Before(deadline) {
+			time.Sleep(time.Second)
+			if next, errGet := m.client.GetLogin(loginID); errGet == nil {
+				session = next
+			}
+		}
+		if getString(session, "status") == "failed" {
+			return oauthStartMsg{err: fmt.Errorf("%s: %s", T("oauth_failed"), getString(session, "error"))}
+		}
 
-		authURL := getString(data, "url")
-		state := getString(data, "state")
+		authURL := getString(session, "auth_url")
+		userCode := getString(session, "user_code")
+		if authURL == "" {
+			authURL = getString(session, "verification_url")
+		}
 		if authURL == "" {
+			_ = m.client.CancelLogin(loginID)
 			return oauthStartMsg{err: fmt.Errorf("no auth URL returned for %s", provider.name)}
 		}
 
 		// Try to open browser (best effort)
 		_ = openBrowser(authURL)
 
-		return oauthStartMsg{url: authURL, state: state, providerName: provider.name}
+		return oauthStartMsg{url: authURL, userCode: userCode, loginID: loginID, providerName: provider.name}
 	}
 }
 
 func (m oauthTabModel) submitCallback(callbackURL string) tea.Cmd {
 	return func() tea.Msg {
-		// Determine provider from current context
-		providerKey := ""
-		for _, p := range oauthProviders {
-			if p.name == m.providerName {
-				// Map provider name to the canonical key the API expects
-				switch p.apiPath {
-				case "gemini-cli-auth-url":
-					providerKey = "gemini"
-				case "anthropic-auth-url":
-					providerKey = "anthropic"
-				case "codex-auth-url":
-					providerKey = "codex"
-				case "antigravity-auth-url":
-					providerKey = "antigravity"
-				case "qwen-auth-url":
-					providerKey = "qwen"
-				case "kimi-auth-url":
-					providerKey = "kimi"
-				case "iflow-auth-url":
-					providerKey = "iflow"
-				}
-				break
-			}
-		}
-
-		body := map[string]string{
-			"provider":     providerKey,
-			"redirect_url": callbackURL,
-			"state":        m.authState,
-		}
-		err := m.client.postJSON("/v0/management/oauth-callback", body)
-		if err != nil {
+		if err := m.client.SubmitLoginCallback(m.loginID, callbackURL); err != nil {
 			return oauthCallbackSubmitMsg{err: err}
 		}
 		return oauthCallbackSubmitMsg{}
 	}
 }
 
-func (m oauthTabModel) pollOAuthStatus(state string) tea.Cmd {
+func (m oauthTabModel) cancelLogin(loginID string) tea.Cmd {
+	if loginID == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		_ = m.client.CancelLogin(loginID)
+		return nil
+	}
+}
+
+func (m oauthTabModel) pollOAuthStatus(loginID string) tea.Cmd {
 	return func() tea.Msg {
-		// Poll session status for up to 5 minutes
-		deadline := time.Now().Add(5 * time.Minute)
+		// Poll the login session until the broker's own timeout has passed
+		deadline := time.Now().Add(10 * time.Minute)
 		for {
 			if time.Now().After(deadline) {
-				return oauthPollMsg{done: false, err: fmt.Errorf("%s", T("oauth_timeout"))}
+				return oauthPollMsg{loginID: loginID, done: false, err: fmt.Errorf("%s", T("oauth_timeout"))}
 			}
 
 			time.Sleep(2 * time.Second)
 
-			status, errMsg, err := m.client.GetAuthStatus(state)
+			session, err := m.client.GetLogin(loginID)
 			if err != nil {
 				continue // Ignore transient errors
 			}
 
-			switch status {
-			case "ok":
+			switch getString(session, "status") {
+			case "succeeded":
 				return oauthPollMsg{
+					loginID: loginID,
 					done:    true,
 					message: T("oauth_success"),
 				}
-			case "error":
+			case "failed", "canceled":
 				return oauthPollMsg{
-					done: false,
-					err:  fmt.Errorf("%s: %s", T("oauth_failed"), errMsg),
-				}
-			case "wait":
-				continue
-			default:
-				return oauthPollMsg{
-					done:    true,
-					message: T("oauth_completed"),
+					loginID: loginID,
+					done:    false,
+					err:     fmt.Errorf("%s: %s", T("oauth_failed"), getString(session, "error")),
 				}
 			}
 		}
@@ -435,6 +445,16 @@ func (m oauthTabModel) renderRemoteMode() string {
 	}
 	sb.WriteString("\n")
 
+	if m.userCode != "" {
+		sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(colorInfo).Render(T("oauth_user_code")))
+		sb.WriteString("  " + lipgloss.NewStyle().Bold(true).Foreground(colorHighlight).Render(m.userCode))
+		sb.WriteString("\n\n")
+		sb.WriteString(helpStyle.Render(T("oauth_device_hint")))
+		sb.WriteString("\n\n")
+		sb.WriteString(warningStyle.Render(T("oauth_waiting")))
+		return sb.String()
+	}
+
 	sb.WriteString(helpStyle.Render(T("oauth_remote_hint")))
 	sb.WriteString("\n\n")
 
@@ -0,0 +1,196 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// quotaTabModel lists the latest quota reading for every OAuth credential.
+type quotaTabModel struct {
+	client      *Client
+	viewport    viewport.Model
+	credentials []map[string]any
+	polling     map[string]any
+	err         error
+	width       int
+	height      int
+	ready       bool
+}
+
+type quotaDataMsg struct {
+	credentials []map[string]any
+	polling     map[string]any
+	err         error
+}
+
+func newQuotaTabModel(client *Client) quotaTabModel {
+	return quotaTabModel{
+		client: client,
+	}
+}
+
+func (m quotaTabModel) Init() tea.Cmd {
+	return m.fetchData(false)
+}
+
+func (m quotaTabModel) fetchData(probe bool) tea.Cmd {
+	return func() tea.Msg {
+		credentials, polling, err := m.client.GetQuota(probe)
+		return quotaDataMsg{credentials: credentials, polling: polling, err: err}
+	}
+}
+
+func (m quotaTabModel) Update(msg tea.Msg) (quotaTabModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case localeChangedMsg:
+		m.viewport.SetContent(m.renderContent())
+		return m, nil
+	case quotaDataMsg:
+		if msg.err != nil {
+			m.err = msg.err
+		} else {
+			m.err = nil
+			m.credentials = msg.credentials
+			m.polling = msg.polling
+		}
+		m.viewport.SetContent(m.renderContent())
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "r":
+			return m, m.fetchData(false)
+		case "p":
+			return m, m.fetchData(true)
+		}
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m *quotaTabModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	if !m.ready {
+		m.viewport = viewport.New(w, h)
+		m.viewport.SetContent(m.renderContent())
+		m.ready = true
+	} else {
+		m.viewport.Width = w
+		m.viewport.Height = h
+	}
+}
+
+func (m quotaTabModel) View() string {
+	if !m.ready {
+		return T("loading")
+	}
+	return m.viewport.View()
+}
+
+func (m quotaTabModel) renderContent() string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render(T("quota_title")))
+	sb.WriteString("\n")
+	sb.WriteString(helpStyle.Render(T("quota_help")))
+	sb.WriteString("\n\n")
+
+	if m.err != nil {
+		sb.WriteString(errorStyle.Render("⚠ Error: " + m.err.Error()))
+		sb.WriteString("\n")
+		return sb.String()
+	}
+
+	if enabled, _ := m.polling["enabled"].(bool); !enabled {
+		sb.WriteString(warningStyle.Render(T("quota_polling_off")))
+		sb.WriteString("\n\n")
+	}
+
+	if len(m.credentials) == 0 {
+		sb.WriteString(subtitleStyle.Render(T("quota_no_data")))
+		sb.WriteString("\n")
+		return sb.String()
+	}
+
+	header := fmt.Sprintf("  %-16s %-28s %-14s %-20s %-17s %s",
+		T("quota_provider"), T("quota_account"), T("quota_plan"), T("quota_remaining"), T("quota_reset"), T("quota_state"))
+	sb.WriteString(tableHeaderStyle.Render(header))
+	sb.WriteString("\n")
+
+	for _, cred := range m.credentials {
+		quota, _ := cred["quota"].(map[string]any)
+		if quota == nil {
+			quota = map[string]any{}
+		}
+		account, _ := cred["account"].(string)
+		if account == "" {
+			account, _ = cred["label"].(string)
+		}
+		if account == "" {
+			account, _ = cred["id"].(string)
+		}
+		provider, _ := cred["provider"].(string)
+		plan, _ := quota["plan_tier"].(string)
+
+		row := fmt.Sprintf("  %-16s %-28s %-14s %-20s %-17s ",
+			truncate(provider, 16), truncate(account, 28), truncate(plan, 14),
+			truncate(formatQuotaRemaining(quota), 20), formatQuotaTime(quota["reset_at"]))
+		state, style := quotaState(cred, quota)
+		sb.WriteString(tableCellStyle.Render(row))
+		sb.WriteString(style.Render(state))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func formatQuotaRemaining(quota map[string]any) string {
+	if formatQuotaTime(quota["checked_at"]) == "-" {
+		return "-"
+	}
+	remaining := getFloat(quota, "remaining")
+	limit := getFloat(quota, "limit")
+	unit, _ := quota["unit"].(string)
+	if limit <= 0 {
+		return strings.TrimSpace(T("quota_unlimited") + " " + unit)
+	}
+	return fmt.Sprintf("%.0f/%.0f (%.0f%%)", remaining, limit, remaining/limit*100)
+}
+
+func formatQuotaTime(value any) string {
+	raw, _ := value.(string)
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil || t.IsZero() || t.Year() <= 1 {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func quotaState(cred, quota map[string]any) (string, lipgloss.Style) {
+	if disabled, _ := cred["disabled"].(bool); disabled {
+		return T("quota_disabled"), helpStyle
+	}
+	if low, _ := quota["low"].(bool); low {
+		return T("quota_low"), errorStyle
+	}
+	if exceeded, _ := quota["exceeded"].(bool); exceeded {
+		return T("quota_cooling"), warningStyle
+	}
+	if probeErr, _ := quota["probe_error"].(string); probeErr != "" {
+		return truncate(probeErr, 40), warningStyle
+	}
+	if probe, _ := cred["probe"].(bool); !probe {
+		return T("quota_no_probe"), helpStyle
+	}
+	return T("quota_ok"), successStyle
+}
@@ -95,6 +95,7 @@ type RequestDetail struct {
 	AuthIndex string     `json:"auth_index"`
 	Tokens    TokenStats `json:"tokens"`
 	Failed    bool       `json:"failed"`
+	Tenant    string     `json:"tenant,omitempty"`
 }
 
 // TokenStats captures the token usage breakdown for a request.
@@ -204,6 +205,7 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 		AuthIndex: record.AuthIndex,
 		Tokens:    detail,
 		Failed:    failed,
+		Tenant:    resolveTenant(ctx),
 	})
 
 	s.requestsByDay[dayKey]++
@@ -284,6 +286,57 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 	return result
 }
 
+// FilterTenant returns a snapshot restricted to requests attributed to tenant.
+// Totals and time buckets are recomputed from the matching request details.
+func (s StatisticsSnapshot) FilterTenant(tenant string) StatisticsSnapshot {
+	result := StatisticsSnapshot{
+		APIs:           make(map[string]APISnapshot),
+		RequestsByDay:  make(map[string]int64),
+		RequestsByHour: make(map[string]int64),
+		TokensByDay:    make(map[string]int64),
+		TokensByHour:   make(map[string]int64),
+	}
+	for apiName, apiSnapshot := range s.APIs {
+		filteredAPI := APISnapshot{Models: make(map[string]ModelSnapshot)}
+		for modelName, modelSnapshot := range apiSnapshot.Models {
+			filteredModel := ModelSnapshot{}
+			for _, detail := range modelSnapshot.Details {
+				if detail.Tenant != tenant {
+					continue
+				}
+				tokens := detail.Tokens.TotalTokens
+				filteredModel.TotalRequests++
+				filteredModel.TotalTokens += tokens
+				filteredModel.Details = append(filteredModel.Details, detail)
+
+				result.TotalRequests++
+				if detail.Failed {
+					result.FailureCount++
+				} else {
+					result.SuccessCount++
+				}
+				result.TotalTokens += tokens
+				dayKey := detail.Timestamp.Format("2006-01-02")
+				hourKey := formatHour(detail.Timestamp.Hour())
+				result.RequestsByDay[dayKey]++
+				result.RequestsByHour[hourKey]++
+				result.TokensByDay[dayKey] += tokens
+				result.TokensByHour[hourKey] += tokens
+			}
+			if filteredModel.TotalRequests == 0 {
+				continue
+			}
+			filteredAPI.TotalRequests += filteredModel.TotalRequests
+			filteredAPI.TotalTokens += filteredModel.TotalTokens
+			filteredAPI.Models[modelName] = filteredModel
+		}
+		if filteredAPI.TotalRequests > 0 {
+			result.APIs[apiName] = filteredAPI
+		}
+	}
+	return result
+}
+
 type MergeResult struct {
 	Added   int64 `json:"added"`
 	Skipped int64 `json:"skipped"`
@@ -424,6 +477,18 @@ func resolveAPIIdentifier(ctx context.Context, record coreusage.Record) string {
 	return "unknown"
 }
 
+// resolveTenant returns the tenant the request handler resolved for the caller.
+func resolveTenant(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return ""
+	}
+	return ginCtx.GetString("tenant")
+}
+
 func resolveSuccess(ctx context.Context) bool {
 	if ctx == nil {
 		return true
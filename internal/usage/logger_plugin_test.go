@@ -94,3 +94,30 @@ func TestRequestStatisticsMergeSnapshotDedupIgnoresLatency(t *testing.T) {
 		t.Fatalf("details len = %d, want 1", len(details))
 	}
 }
+
+func TestStatisticsSnapshotFilterTenant(t *testing.T) {
+	stats := NewRequestStatistics()
+	now := time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC)
+	stats.MergeSnapshot(StatisticsSnapshot{
+		APIs: map[string]APISnapshot{
+			"key-a": {Models: map[string]ModelSnapshot{"gpt": {Details: []RequestDetail{
+				{Timestamp: now, Tenant: "team-a", Tokens: TokenStats{TotalTokens: 10}},
+				{Timestamp: now.Add(time.Minute), Tenant: "team-b", Tokens: TokenStats{TotalTokens: 5}, Failed: true},
+			}}}},
+			"key-b": {Models: map[string]ModelSnapshot{"claude": {Details: []RequestDetail{
+				{Timestamp: now, Tokens: TokenStats{TotalTokens: 7}},
+			}}}},
+		},
+	})
+
+	filtered := stats.Snapshot().FilterTenant("team-a")
+	if filtered.TotalRequests != 1 || filtered.TotalTokens != 10 || filtered.FailureCount != 0 {
+		t.Fatalf("filtered totals = %+v", filtered)
+	}
+	if len(filtered.APIs) != 1 || filtered.APIs["key-a"].Models["gpt"].TotalRequests != 1 {
+		t.Fatalf("filtered APIs = %+v", filtered.APIs)
+	}
+	if filtered.RequestsByDay["2026-03-20"] != 1 || filtered.TokensByHour["12"] != 10 {
+		t.Fatalf("filtered buckets = %+v %+v", filtered.RequestsByDay, filtered.TokensByHour)
+	}
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyFromContext returns the authenticated client key of the request whose gin
+// context is stored on ctx under "gin", or "" when there is none.
+func APIKeyFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	return APIKeyFromGin(ginCtx)
+}
+
+// APIKeyFromGin returns the authenticated client key the access middleware stored
+// on c under "apiKey", or "" when there is none.
+func APIKeyFromGin(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	v, exists := c.Get("apiKey")
+	if !exists {
+		return ""
+	}
+	switch value := v.(type) {
+	case string:
+		return value
+	case fmt.Stringer:
+		return value.String()
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
@@ -0,0 +1,28 @@
+package util
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIKeyFromContext(t *testing.T) {
+	if got := APIKeyFromContext(context.Background()); got != "" {
+		t.Fatalf("APIKeyFromContext without gin = %q, want empty", got)
+	}
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+	if got := APIKeyFromContext(ctx); got != "" {
+		t.Fatalf("APIKeyFromContext without key = %q, want empty", got)
+	}
+	ginCtx.Set("apiKey", "sk-client")
+	if got := APIKeyFromContext(ctx); got != "sk-client" {
+		t.Fatalf("APIKeyFromContext = %q, want sk-client", got)
+	}
+	ginCtx.Set("apiKey", 42)
+	if got := APIKeyFromGin(ginCtx); got != "42" {
+		t.Fatalf("APIKeyFromGin = %q, want 42", got)
+	}
+}
@@ -0,0 +1,68 @@
+package util
+
+import "strings"
+
+// MatchWildcard performs simple wildcard matching where '*' matches zero or more characters.
+// Surrounding whitespace is ignored and an empty pattern matches nothing.
+// Examples:
+//
+//	"*-5" matches "gpt-5"
+//	"gpt-*" matches "gpt-5" and "gpt-4"
+//	"gemini-*-pro" matches "gemini-2.5-pro" and "gemini-3-pro".
+func MatchWildcard(pattern, value string) bool {
+	pattern = strings.TrimSpace(pattern)
+	value = strings.TrimSpace(value)
+	if pattern == "" {
+		return false
+	}
+	if pattern == "*" {
+		return true
+	}
+	// Iterative glob-style matcher supporting only '*' wildcard.
+	pi, si := 0, 0
+	starIdx := -1
+	matchIdx := 0
+	for si < len(value) {
+		if pi < len(pattern) && (pattern[pi] == value[si]) {
+			pi++
+			si++
+			continue
+		}
+		if pi < len(pattern) && pattern[pi] == '*' {
+			starIdx = pi
+			matchIdx = si
+			pi++
+			continue
+		}
+		if starIdx != -1 {
+			pi = starIdx + 1
+			matchIdx++
+			si = matchIdx
+			continue
+		}
+		return false
+	}
+	for pi < len(pattern) && pattern[pi] == '*' {
+		pi++
+	}
+	return pi == len(pattern)
+}
+
+// MatchWildcardFold is MatchWildcard ignoring case, for model patterns written by operators.
+func MatchWildcardFold(pattern, value string) bool {
+	return MatchWildcard(strings.ToLower(pattern), strings.ToLower(value))
+}
+
+// MatchAnyWildcardFold reports whether value matches one of patterns ignoring case.
+// An empty pattern list matches every value.
+func MatchAnyWildcardFold(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if MatchWildcardFold(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
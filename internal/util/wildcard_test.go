@@ -0,0 +1,33 @@
+package util
+
+import "testing"
+
+func TestMatchWildcard(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"*", "gpt-5", true},
+		{"gpt-5", "gpt-5", true},
+		{"gpt-5", "gpt-5-mini", false},
+		{"gpt-*", "gpt-5", true},
+		{"*-5", "gpt-5", true},
+		{"gemini-*-pro", "gemini-2.5-pro", true},
+		{"gemini-*-pro", "gemini-2.5-flash", false},
+		{"*a*a", "banana", true},
+		{"", "gpt-5", false},
+		{" gpt-* ", "gpt-5", true},
+		{"GPT-*", "gpt-5", false},
+	}
+	for _, tc := range cases {
+		if got := MatchWildcard(tc.pattern, tc.value); got != tc.want {
+			t.Errorf("MatchWildcard(%q, %q) = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+	}
+	if !MatchWildcardFold("Claude-*", "claude-sonnet-4") {
+		t.Error("MatchWildcardFold should ignore case")
+	}
+	if !MatchAnyWildcardFold(nil, "gpt-5") || MatchAnyWildcardFold([]string{"claude-*"}, "gpt-5") {
+		t.Error("MatchAnyWildcardFold returned the wrong result")
+	}
+}
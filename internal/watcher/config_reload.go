@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/confighistory"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/notify"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
 	"gopkg.in/yaml.v3"
@@ -73,10 +75,24 @@ func (w *Watcher) reloadConfigIfChanged() {
 		w.clientsMutex.Lock()
 		w.lastConfigHash = finalHash
 		w.clientsMutex.Unlock()
+		w.recordConfigVersion(confighistory.SourceFile)
 		w.persistConfigAsync()
 	}
 }
 
+// recordConfigVersion adds the on-disk config to the version history. Writes made
+// through the management API record themselves before releasing the history, so
+// their content is already the latest version here and only out-of-band edits
+// produce new versions.
+func (w *Watcher) recordConfigVersion(source string) {
+	w.clientsMutex.RLock()
+	cfg := w.config
+	w.clientsMutex.RUnlock()
+	if _, _, err := confighistory.For(cfg, w.configPath).RecordFile(w.configPath, "file", source, ""); err != nil {
+		log.WithError(err).Warn("config history: failed to record version")
+	}
+}
+
 func (w *Watcher) reloadConfig() bool {
 	log.Debug("=========================== CONFIG RELOAD ============================")
 	log.Debugf("starting config reload from: %s", w.configPath)
@@ -84,6 +100,14 @@ func (w *Watcher) reloadConfig() bool {
 	newConfig, errLoadConfig := config.LoadConfig(w.configPath)
 	if errLoadConfig != nil {
 		log.Errorf("failed to reload config: %v", errLoadConfig)
+		notify.Emit(notify.Event{
+			Type:     notify.EventConfigReloadFailed,
+			Severity: notify.SeverityCritical,
+			Key:      w.configPath,
+			Title:    "Config reload failed",
+			Message:  errLoadConfig.Error(),
+			Fields:   map[string]string{"path": w.configPath},
+		})
 		return false
 	}
 
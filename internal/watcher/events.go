@@ -14,6 +14,7 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	kiroauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/confighistory"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -44,6 +45,7 @@ func (w *Watcher) start(ctx context.Context) error {
 
 	go w.processEvents(ctx)
 
+	w.recordConfigVersion(confighistory.SourceStartup)
 	w.reloadClients(true, nil, false)
 	return nil
 }
@@ -157,6 +157,10 @@ func synthesizeFileAuths(ctx *SynthesisContext, fullPath string, data []byte) []
 			}
 		}
 	}
+	// Read tenant tags from auth file.
+	if tenants := coreauth.TenantTagsFromValue(metadata[coreauth.TenantsAttributeKey]); len(tenants) > 0 {
+		a.Attributes[coreauth.TenantsAttributeKey] = strings.Join(tenants, ",")
+	}
 	coreauth.ApplyCustomHeadersFromMetadata(a)
 	ApplyAuthExcludedModelsMeta(a, cfg, perAccountExcluded, "oauth")
 	// For codex auth files, extract plan_type from the JWT id_token.
@@ -234,6 +238,10 @@ func SynthesizeGeminiVirtualAuths(primary *coreauth.Auth, metadata map[string]an
 		if noteVal, hasNote := primary.Attributes["note"]; hasNote && noteVal != "" {
 			attrs["note"] = noteVal
 		}
+		// Propagate tenant tags so virtual auths stay in the primary's pool
+		if tenantsVal, hasTenants := primary.Attributes[coreauth.TenantsAttributeKey]; hasTenants && tenantsVal != "" {
+			attrs[coreauth.TenantsAttributeKey] = tenantsVal
+		}
 		for k, v := range primary.Attributes {
 			if strings.HasPrefix(k, "header:") && strings.TrimSpace(v) != "" {
 				attrs[k] = v
@@ -28,6 +28,9 @@ const (
 	// AccessProviderTypeConfigAPIKey is the built-in provider validating inline API keys.
 	AccessProviderTypeConfigAPIKey = "config-api-key"
 
+	// AccessProviderTypeOIDCJWT is the built-in provider validating OIDC-issued bearer JWTs.
+	AccessProviderTypeOIDCJWT = "oidc-jwt"
+
 	// DefaultAccessProviderName is applied when no provider name is supplied.
 	DefaultAccessProviderName = "config-inline"
 )
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/contextwindow"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"golang.org/x/net/context"
+)
+
+const contextSummaryInstruction = "You condense conversation history for another assistant. " +
+	"Summarize the transcript below: keep the user's goals, decisions, constraints, file names, " +
+	"identifiers, tool results that are still relevant and any open tasks. Reply with the summary only."
+
+// applyContextWindow shrinks the client payload when a context window rule matches the
+// model and the request exceeds its input limit. What was removed is reported in the
+// contextwindow.HeaderName response header.
+func (h *BaseAPIHandler) applyContextWindow(ctx context.Context, handlerType, modelName string, rawJSON []byte) []byte {
+	if h == nil || h.Cfg == nil || len(h.Cfg.ContextWindow) == 0 {
+		return rawJSON
+	}
+	rule := contextwindow.RuleFor(h.Cfg.ContextWindow, modelName)
+	if rule == nil {
+		return rawJSON
+	}
+	limit := contextwindow.Limit(rule, modelName)
+	if limit <= 0 {
+		return rawJSON
+	}
+	var summarize contextwindow.Summarizer
+	if summaryModel := rule.SummaryModel; summaryModel != "" {
+		summarize = func(ctx context.Context, transcript string) (string, error) {
+			return h.summarizeTranscript(ctx, summaryModel, transcript)
+		}
+	}
+	out, report := contextwindow.Fit(ctx, rule, handlerType, modelName, rawJSON, limit, summarize)
+	if !report.Changed() {
+		return rawJSON
+	}
+	log.WithFields(log.Fields{
+		"model":                modelName,
+		"tokens_before":        report.OriginalTokens,
+		"tokens_after":         report.FinalTokens,
+		"limit":                report.Limit,
+		"dropped_turns":        report.DroppedTurns,
+		"summarized_turns":     report.SummarizedTurns,
+		"trimmed_tool_outputs": report.TrimmedToolOutputs,
+	}).Info("context window: request shrunk to fit")
+	if ctx != nil {
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+			ginCtx.Header(contextwindow.HeaderName, report.Header())
+		}
+	}
+	return out
+}
+
+// summarizeTranscript asks model, through the auth manager, for a summary of transcript.
+func (h *BaseAPIHandler) summarizeTranscript(ctx context.Context, model, transcript string) (string, error) {
+	providers, normalizedModel, errMsg := h.getRequestDetails(model)
+	if errMsg != nil {
+		return "", errMsg.Error
+	}
+	// Keep the tail of very long transcripts within the summary model's own window,
+	// assuming roughly three characters per token.
+	if limit := contextwindow.Limit(nil, normalizedModel); limit > 0 {
+		if maxChars := int(limit) * 3; len(transcript) > maxChars {
+			transcript = transcript[len(transcript)-maxChars:]
+		}
+	}
+	payload := []byte(`{"messages":[{"role":"system"},{"role":"user"}],"stream":false}`)
+	payload, _ = sjson.SetBytes(payload, "model", normalizedModel)
+	payload, _ = sjson.SetBytes(payload, "messages.0.content", contextSummaryInstruction)
+	payload, _ = sjson.SetBytes(payload, "messages.1.content", transcript)
+
+	meta := map[string]any{
+		idempotencyKeyMetadataKey:              uuid.NewString(),
+		coreexecutor.RequestedModelMetadataKey: normalizedModel,
+	}
+	h.applyTenantScope(ctx, meta)
+	resp, err := h.AuthManager.Execute(coreexecutor.WithInternalRequest(ctx), providers, coreexecutor.Request{Model: normalizedModel, Payload: payload}, coreexecutor.Options{
+		OriginalRequest: payload,
+		SourceFormat:    sdktranslator.FromString("openai"),
+		Metadata:        meta,
+	})
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(gjson.GetBytes(resp.Payload, "choices.0.message.content").String())
+	if summary == "" {
+		return "", fmt.Errorf("summary model %s returned no content", normalizedModel)
+	}
+	return summary, nil
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/guardrails"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"golang.org/x/net/context"
+)
+
+// guardrailEngine returns the compiled guardrail engine for the active configuration.
+func (h *BaseAPIHandler) guardrailEngine() *guardrails.Engine {
+	if h == nil || h.Cfg == nil {
+		return nil
+	}
+	return guardrails.ForConfig(&h.Cfg.Guardrails)
+}
+
+// applyRequestGuardrails inspects the inbound payload and returns the payload to forward upstream.
+func (h *BaseAPIHandler) applyRequestGuardrails(ctx context.Context, modelName string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+	engine := h.guardrailEngine()
+	if engine == nil {
+		return rawJSON, nil
+	}
+	scope := guardrails.Scope{Direction: guardrails.DirectionRequest, APIKey: util.APIKeyFromContext(ctx), Model: modelName}
+	out, result := engine.InspectPayload(scope, rawJSON)
+	guardrails.Audit(scope, result)
+	if result.Blocked {
+		return nil, guardrailErrorMessage(result, guardrails.DirectionRequest)
+	}
+	return out, nil
+}
+
+// applyResponseGuardrails inspects a non-streaming response payload before it is returned to the client.
+func (h *BaseAPIHandler) applyResponseGuardrails(ctx context.Context, modelName string, payload []byte) ([]byte, *interfaces.ErrorMessage) {
+	engine := h.guardrailEngine()
+	if engine == nil {
+		return payload, nil
+	}
+	scope := guardrails.Scope{Direction: guardrails.DirectionResponse, APIKey: util.APIKeyFromContext(ctx), Model: modelName}
+	out, result := engine.InspectPayload(scope, payload)
+	guardrails.Audit(scope, result)
+	if result.Blocked {
+		return nil, guardrailErrorMessage(result, guardrails.DirectionResponse)
+	}
+	return out, nil
+}
+
+// newGuardrailStreamScanner returns an incremental scanner for streaming output, or nil when no rule applies.
+func (h *BaseAPIHandler) newGuardrailStreamScanner(ctx context.Context, modelName string) *guardrails.StreamScanner {
+	engine := h.guardrailEngine()
+	if engine == nil {
+		return nil
+	}
+	return engine.NewStreamScanner(guardrails.Scope{APIKey: util.APIKeyFromContext(ctx), Model: modelName})
+}
+
+func guardrailErrorMessage(result guardrails.Result, direction guardrails.Direction) *interfaces.ErrorMessage {
+	return &interfaces.ErrorMessage{
+		StatusCode: http.StatusBadRequest,
+		Error:      &guardrails.BlockedError{Rule: result.BlockRule, Direction: direction},
+	}
+}
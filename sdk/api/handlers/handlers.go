@@ -5,6 +5,8 @@ package handlers
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,6 +17,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/guardrails"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
@@ -209,9 +212,23 @@ func requestExecutionMetadata(ctx context.Context) map[string]any {
 	if executionSessionID := executionSessionIDFromContext(ctx); executionSessionID != "" {
 		meta[coreexecutor.ExecutionSessionMetadataKey] = executionSessionID
 	}
+	if clientKey := clientKeyFromContext(ctx); clientKey != "" {
+		meta[coreexecutor.ClientKeyMetadataKey] = clientKey
+	}
 	return meta
 }
 
+// clientKeyFromContext returns a short hash of the authenticated API key so sticky
+// traffic routing can tell clients apart without the key reaching executors.
+func clientKeyFromContext(ctx context.Context) string {
+	apiKey := strings.TrimSpace(util.APIKeyFromContext(ctx))
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:8])
+}
+
 func pinnedAuthIDFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
@@ -475,8 +492,24 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 	if errMsg != nil {
 		return nil, nil, errMsg
 	}
+	rawJSON, errMsg = h.applyRequestGuardrails(ctx, normalizedModel, rawJSON)
+	if errMsg != nil {
+		return nil, nil, errMsg
+	}
+	rawJSON = h.applySystemPromptPolicies(ctx, handlerType, normalizedModel, rawJSON)
+	rawJSON = h.applyContextWindow(ctx, handlerType, normalizedModel, rawJSON)
+	structuredOutput := h.newStructuredOutput(handlerType, normalizedModel, rawJSON)
+	rawJSON = structuredOutput.PrepareRequest(rawJSON)
+	rawJSON, errMsg = applyRequestMiddleware(ctx, handlerType, normalizedModel, rawJSON, false)
+	if errMsg != nil {
+		return nil, nil, errMsg
+	}
 	reqMeta := requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
+	h.applyTenantScope(ctx, reqMeta)
+	recorder := h.newTranscriptRecorder(ctx, handlerType, normalizedModel, rawJSON, false)
+	defer recorder.Finish()
+	toolRepairer := h.newToolRepairer(handlerType, normalizedModel, rawJSON)
 	payload := rawJSON
 	if len(payload) == 0 {
 		payload = nil
@@ -507,12 +540,38 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 				addon = hdr.Clone()
 			}
 		}
+		recorder.Fail(status, err)
 		return nil, nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
 	}
+	responsePayload, errMsg := applyResponseMiddleware(ctx, handlerType, normalizedModel, resp.Payload)
+	if errMsg == nil && toolRepairer != nil {
+		var retry bool
+		responsePayload, retry = repairToolCalls(ctx, toolRepairer, responsePayload)
+		if retry {
+			// Retry once; if the retry is still unrepairable its repaired form is returned.
+			if retryResp, retryErr := h.AuthManager.Execute(coreexecutor.WithInternalRequest(ctx), providers, req, opts); retryErr == nil {
+				if retryPayload, retryErrMsg := applyResponseMiddleware(ctx, handlerType, normalizedModel, retryResp.Payload); retryErrMsg == nil {
+					resp = retryResp
+					responsePayload, _ = repairToolCalls(ctx, toolRepairer, retryPayload)
+				}
+			}
+		}
+	}
+	if errMsg == nil && structuredOutput != nil {
+		responsePayload, errMsg = h.enforceStructuredOutput(ctx, structuredOutput, handlerType, normalizedModel, providers, req, opts, responsePayload)
+	}
+	if errMsg == nil {
+		responsePayload, errMsg = h.applyResponseGuardrails(ctx, normalizedModel, responsePayload)
+	}
+	if errMsg != nil {
+		recorder.Fail(errMsg.StatusCode, errMsg.Error)
+		return nil, nil, errMsg
+	}
+	recorder.Complete(responsePayload)
 	if !PassthroughHeadersEnabled(h.Cfg) {
-		return resp.Payload, nil, nil
+		return responsePayload, nil, nil
 	}
-	return resp.Payload, FilterUpstreamHeaders(resp.Headers), nil
+	return responsePayload, FilterUpstreamHeaders(resp.Headers), nil
 }
 
 // ExecuteCountWithAuthManager executes a non-streaming request via the core auth manager.
@@ -522,8 +581,21 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 	if errMsg != nil {
 		return nil, nil, errMsg
 	}
+	rawJSON, errMsg = h.applyRequestGuardrails(ctx, normalizedModel, rawJSON)
+	if errMsg != nil {
+		return nil, nil, errMsg
+	}
+	rawJSON = h.applySystemPromptPolicies(ctx, handlerType, normalizedModel, rawJSON)
+	rawJSON, errMsg = applyRequestMiddleware(ctx, handlerType, normalizedModel, rawJSON, false)
+	if errMsg != nil {
+		return nil, nil, errMsg
+	}
+	if h.Cfg != nil && h.Cfg.TokenCounting.Local() {
+		return h.countTokensLocally(ctx, handlerType, normalizedModel, rawJSON)
+	}
 	reqMeta := requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
+	h.applyTenantScope(ctx, reqMeta)
 	payload := rawJSON
 	if len(payload) == 0 {
 		payload = nil
@@ -573,8 +645,25 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		close(errChan)
 		return nil, nil, errChan
 	}
+	if structuredOutput := h.newStructuredOutput(handlerType, normalizedModel, rawJSON); structuredOutput != nil {
+		return h.streamStructuredOutput(ctx, handlerType, modelName, rawJSON, alt, structuredOutput)
+	}
+	rawJSON, errMsg = h.applyRequestGuardrails(ctx, normalizedModel, rawJSON)
+	if errMsg == nil {
+		rawJSON = h.applySystemPromptPolicies(ctx, handlerType, normalizedModel, rawJSON)
+		rawJSON = h.applyContextWindow(ctx, handlerType, normalizedModel, rawJSON)
+		rawJSON, errMsg = applyRequestMiddleware(ctx, handlerType, normalizedModel, rawJSON, true)
+	}
+	if errMsg != nil {
+		errChan := make(chan *interfaces.ErrorMessage, 1)
+		errChan <- errMsg
+		close(errChan)
+		return nil, nil, errChan
+	}
 	reqMeta := requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
+	h.applyTenantScope(ctx, reqMeta)
+	recorder := h.newTranscriptRecorder(ctx, handlerType, normalizedModel, rawJSON, true)
 	payload := rawJSON
 	if len(payload) == 0 {
 		payload = nil
@@ -590,7 +679,22 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
 	opts.Metadata = reqMeta
-	streamResult, err := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
+	// Each upstream attempt runs under its own context so an attempt abandoned
+	// for a retry is cancelled instead of streaming on with nobody reading it.
+	attemptParent := ctx
+	if attemptParent == nil {
+		attemptParent = context.Background()
+	}
+	executeAttempt := func(parent context.Context) (*coreexecutor.StreamResult, context.CancelFunc, error) {
+		attemptCtx, cancel := context.WithCancel(parent)
+		result, errExec := h.AuthManager.ExecuteStream(attemptCtx, providers, req, opts)
+		if errExec != nil {
+			cancel()
+			return nil, nil, errExec
+		}
+		return result, cancel, nil
+	}
+	streamResult, cancelAttempt, err := executeAttempt(attemptParent)
 	if err != nil {
 		err = enrichAuthSelectionError(err, providers, normalizedModel)
 		errChan := make(chan *interfaces.ErrorMessage, 1)
@@ -606,6 +710,8 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 				addon = hdr.Clone()
 			}
 		}
+		recorder.Fail(status, err)
+		recorder.Finish()
 		errChan <- &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
 		close(errChan)
 		return nil, nil, errChan
@@ -621,16 +727,23 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		}
 	}
 	chunks := streamResult.Chunks
+	guardrailScanner := h.newGuardrailStreamScanner(ctx, normalizedModel)
+	toolRepairer := h.newToolRepairer(handlerType, normalizedModel, rawJSON)
+	toolStream := toolRepairer.NewStream()
 	dataChan := make(chan []byte)
 	errChan := make(chan *interfaces.ErrorMessage, 1)
 	go func() {
 		defer close(dataChan)
 		defer close(errChan)
+		defer recorder.Finish()
+		defer func() { cancelAttempt() }()
 		sentPayload := false
+		toolRetried := false
 		bootstrapRetries := 0
 		maxBootstrapRetries := StreamingBootstrapRetries(h.Cfg)
 
 		sendErr := func(msg *interfaces.ErrorMessage) bool {
+			recorder.Fail(msg.StatusCode, msg.Error)
 			if ctx == nil {
 				errChan <- msg
 				return true
@@ -656,6 +769,49 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 			}
 		}
 
+		send := func(pieces [][]byte) bool {
+			for _, piece := range pieces {
+				sentPayload = true
+				if okSendData := sendData(cloneBytes(piece)); !okSendData {
+					return false
+				}
+				recorder.Observe(piece)
+			}
+			return true
+		}
+
+		// forward runs a chunk through the guardrails and sends it to the client.
+		// The guardrail scanner may hold text back until it cannot be part of a match.
+		forward := func(outPayload []byte) bool {
+			if len(outPayload) == 0 {
+				return true
+			}
+			if guardrailScanner == nil {
+				return send([][]byte{outPayload})
+			}
+			scanned, result := guardrailScanner.Scan(outPayload)
+			guardrails.Audit(guardrailScanner.Scope(), result)
+			if result.Blocked {
+				_ = sendErr(guardrailErrorMessage(result, guardrails.DirectionResponse))
+				return false
+			}
+			return send(scanned)
+		}
+
+		// flushHeld releases text the guardrail scanner is still holding back.
+		flushHeld := func() bool {
+			if guardrailScanner == nil {
+				return true
+			}
+			held, result := guardrailScanner.Flush()
+			guardrails.Audit(guardrailScanner.Scope(), result)
+			if result.Blocked {
+				_ = sendErr(guardrailErrorMessage(result, guardrails.DirectionResponse))
+				return false
+			}
+			return send(held)
+		}
+
 		bootstrapEligible := func(err error) bool {
 			status := statusFromError(err)
 			if status == 0 {
@@ -685,6 +841,16 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 					chunk, ok = <-chunks
 				}
 				if !ok {
+					if toolStream != nil {
+						pending, report := toolStream.Flush()
+						logToolRepair(toolRepairer, report)
+						for _, piece := range pending {
+							if !forward(piece) {
+								return
+							}
+						}
+					}
+					_ = flushHeld()
 					return
 				}
 				if chunk.Err != nil {
@@ -694,17 +860,23 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 					if !sentPayload {
 						if bootstrapRetries < maxBootstrapRetries && bootstrapEligible(streamErr) {
 							bootstrapRetries++
-							retryResult, retryErr := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
+							cancelAttempt()
+							retryResult, cancelRetry, retryErr := executeAttempt(attemptParent)
 							if retryErr == nil {
+								cancelAttempt = cancelRetry
 								if passthroughHeadersEnabled {
 									replaceHeader(upstreamHeaders, FilterUpstreamHeaders(retryResult.Headers))
 								}
 								chunks = retryResult.Chunks
+								guardrailScanner.Reset()
 								continue outer
 							}
 							streamErr = enrichAuthSelectionError(retryErr, providers, normalizedModel)
 						}
 					}
+					if !flushHeld() {
+						return
+					}
 
 					status := http.StatusInternalServerError
 					if se, ok := streamErr.(interface{ StatusCode() int }); ok && se != nil {
@@ -728,10 +900,45 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 							return
 						}
 					}
-					sentPayload = true
-					if okSendData := sendData(cloneBytes(chunk.Payload)); !okSendData {
+					outPayload, errMsg := applyStreamResponseMiddleware(ctx, handlerType, normalizedModel, chunk.Payload)
+					if errMsg != nil {
+						_ = sendErr(errMsg)
 						return
 					}
+					if len(outPayload) == 0 {
+						continue
+					}
+					if toolStream == nil {
+						if !forward(outPayload) {
+							return
+						}
+						continue
+					}
+					pieces, report := toolStream.Process(outPayload)
+					logToolRepair(toolRepairer, report)
+					// Unrepairable calls can only be retried while nothing has reached the client.
+					if report.Failed > 0 && toolRepairer.RetryOnFailure() && !sentPayload && !toolRetried {
+						toolRetried = true
+						// The current attempt is kept until the retry starts so a failed
+						// retry can still deliver the best-effort repair.
+						retryResult, cancelRetry, retryErr := executeAttempt(coreexecutor.WithInternalRequest(attemptParent))
+						if retryErr == nil {
+							cancelAttempt()
+							cancelAttempt = cancelRetry
+							if passthroughHeadersEnabled {
+								replaceHeader(upstreamHeaders, FilterUpstreamHeaders(retryResult.Headers))
+							}
+							chunks = retryResult.Chunks
+							toolStream = toolRepairer.NewStream()
+							guardrailScanner.Reset()
+							continue outer
+						}
+					}
+					for _, piece := range pieces {
+						if !forward(piece) {
+							return
+						}
+					}
 				}
 			}
 		}
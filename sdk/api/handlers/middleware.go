@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"golang.org/x/net/context"
+)
+
+// applyRequestMiddleware runs process-wide request middleware (e.g. plugin transforms)
+// against the inbound client payload.
+func applyRequestMiddleware(ctx context.Context, handlerType, modelName string, rawJSON []byte, stream bool) ([]byte, *interfaces.ErrorMessage) {
+	out, err := sdktranslator.ApplyRequestMiddleware(ctx, sdktranslator.RequestEnvelope{
+		Format: sdktranslator.FromString(handlerType),
+		Model:  modelName,
+		Stream: stream,
+		Body:   rawJSON,
+	})
+	if err != nil {
+		return nil, middlewareErrorMessage(err, http.StatusBadRequest)
+	}
+	return out.Body, nil
+}
+
+// applyResponseMiddleware runs process-wide response middleware against a complete
+// non-streaming response payload.
+func applyResponseMiddleware(ctx context.Context, handlerType, modelName string, payload []byte) ([]byte, *interfaces.ErrorMessage) {
+	if !sdktranslator.HasResponseMiddleware() {
+		return payload, nil
+	}
+	out, err := sdktranslator.ApplyResponseMiddleware(ctx, sdktranslator.ResponseEnvelope{
+		Format: sdktranslator.FromString(handlerType),
+		Model:  modelName,
+		Body:   payload,
+	})
+	if err != nil {
+		return nil, middlewareErrorMessage(err, http.StatusBadGateway)
+	}
+	return out.Body, nil
+}
+
+// applyStreamResponseMiddleware runs process-wide response middleware against a single
+// stream chunk. Middleware may split or drop the chunk; the results are concatenated.
+func applyStreamResponseMiddleware(ctx context.Context, handlerType, modelName string, chunk []byte) ([]byte, *interfaces.ErrorMessage) {
+	if !sdktranslator.HasResponseMiddleware() {
+		return chunk, nil
+	}
+	out, err := sdktranslator.ApplyResponseMiddleware(ctx, sdktranslator.ResponseEnvelope{
+		Format: sdktranslator.FromString(handlerType),
+		Model:  modelName,
+		Stream: true,
+		Chunks: [][]byte{chunk},
+	})
+	if err != nil {
+		return nil, middlewareErrorMessage(err, http.StatusBadGateway)
+	}
+	if len(out.Chunks) == 1 {
+		return out.Chunks[0], nil
+	}
+	return bytes.Join(out.Chunks, nil), nil
+}
+
+func middlewareErrorMessage(err error, fallback int) *interfaces.ErrorMessage {
+	status := statusFromError(err)
+	if status <= 0 {
+		status = fallback
+	}
+	return &interfaces.ErrorMessage{StatusCode: status, Error: err}
+}
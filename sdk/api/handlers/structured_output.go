@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/structuredoutput"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/sjson"
+	"golang.org/x/net/context"
+)
+
+// newStructuredOutput returns the structured output enforcer for a request, or nil
+// when enforcement is disabled, does not apply to the model or the request asks for
+// no JSON output.
+func (h *BaseAPIHandler) newStructuredOutput(handlerType, modelName string, rawJSON []byte) *structuredoutput.Enforcer {
+	if h == nil || h.Cfg == nil || !h.Cfg.StructuredOutput.Enabled {
+		return nil
+	}
+	return structuredoutput.New(&h.Cfg.StructuredOutput, handlerType, modelName, rawJSON)
+}
+
+// enforceStructuredOutput validates a complete response against the requested schema
+// and sends corrective follow-ups until it validates or the retries are exhausted.
+func (h *BaseAPIHandler) enforceStructuredOutput(ctx context.Context, enforcer *structuredoutput.Enforcer, handlerType, modelName string, providers []string, req coreexecutor.Request, opts coreexecutor.Options, payload []byte) ([]byte, *interfaces.ErrorMessage) {
+	for attempt := 0; ; attempt++ {
+		valid, err := enforcer.Validate(payload)
+		if err == nil {
+			return valid, nil
+		}
+		if attempt >= enforcer.MaxRetries() {
+			return nil, &interfaces.ErrorMessage{
+				StatusCode: http.StatusBadGateway,
+				Error:      fmt.Errorf("structured output did not match the requested format after %d attempts: %w", attempt+1, err),
+			}
+		}
+		log.WithFields(log.Fields{"model": modelName, "attempt": attempt + 1, "error": err.Error()}).Info("structured output: invalid reply, sending corrective follow-up")
+		req.Payload = enforcer.FollowUp(req.Payload, payload, err)
+		opts.OriginalRequest = req.Payload
+		resp, execErr := h.AuthManager.Execute(coreexecutor.WithInternalRequest(ctx), providers, req, opts)
+		if execErr != nil {
+			status := statusFromError(execErr)
+			if status <= 0 {
+				status = http.StatusInternalServerError
+			}
+			return nil, &interfaces.ErrorMessage{StatusCode: status, Error: execErr}
+		}
+		var errMsg *interfaces.ErrorMessage
+		payload, errMsg = applyResponseMiddleware(ctx, handlerType, modelName, resp.Payload)
+		if errMsg != nil {
+			return nil, errMsg
+		}
+	}
+}
+
+// streamStructuredOutput serves a streaming request whose output must match a schema.
+// The request is executed without streaming so the output can be validated and
+// retried, and the validated response is then replayed as stream chunks.
+func (h *BaseAPIHandler) streamStructuredOutput(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string, enforcer *structuredoutput.Enforcer) (<-chan []byte, http.Header, <-chan *interfaces.ErrorMessage) {
+	nonStream, _ := sjson.SetBytes(rawJSON, "stream", false)
+	nonStream, _ = sjson.DeleteBytes(nonStream, "stream_options")
+	var upstreamHeaders http.Header
+	if PassthroughHeadersEnabled(h.Cfg) {
+		upstreamHeaders = make(http.Header)
+	}
+	dataChan := make(chan []byte)
+	errChan := make(chan *interfaces.ErrorMessage, 1)
+	go func() {
+		defer close(dataChan)
+		defer close(errChan)
+		payload, headers, errMsg := h.ExecuteWithAuthManager(ctx, handlerType, modelName, nonStream, alt)
+		if errMsg != nil {
+			errChan <- errMsg
+			return
+		}
+		if upstreamHeaders != nil {
+			replaceHeader(upstreamHeaders, headers)
+		}
+		for _, chunk := range enforcer.StreamChunks(payload) {
+			if ctx == nil {
+				dataChan <- chunk
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case dataChan <- chunk:
+			}
+		}
+	}()
+	return dataChan, upstreamHeaders, errChan
+}
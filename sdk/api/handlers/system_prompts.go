@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/systemprompt"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// applySystemPromptPolicies rewrites the system prompt of the client payload according
+// to the configured policies. It runs before translation so rawJSON is still in the
+// client's own format.
+func (h *BaseAPIHandler) applySystemPromptPolicies(ctx context.Context, handlerType, modelName string, rawJSON []byte) []byte {
+	if h == nil || h.Cfg == nil || len(h.Cfg.SystemPrompts) == 0 {
+		return rawJSON
+	}
+	var ginCtx *gin.Context
+	if ctx != nil {
+		ginCtx, _ = ctx.Value("gin").(*gin.Context)
+	}
+	principal, groups := callerIdentity(ginCtx)
+	scope := systemprompt.Scope{
+		Format: handlerType,
+		Model:  modelName,
+		APIKey: util.APIKeyFromContext(ctx),
+		Client: util.HideAPIKey(principal),
+		Now:    time.Now(),
+	}
+	if tenant := h.Cfg.TenantForPrincipal(principal, groups); tenant != nil {
+		scope.Tenant = tenant.Name
+		scope.Client = tenant.Name
+	}
+	out, applied := systemprompt.Apply(h.Cfg.SystemPrompts, scope, rawJSON)
+	if len(applied) > 0 {
+		log.WithFields(log.Fields{"model": modelName, "format": handlerType, "policies": applied}).Debug("system prompt policies applied")
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"golang.org/x/net/context"
+)
+
+// tenantContextKey is the gin context key holding the resolved tenant name.
+const tenantContextKey = "tenant"
+
+// applyTenantScope restricts credential selection to the caller's tenant when
+// tenants are configured. Callers outside every tenant still receive a scope so
+// they cannot reach tenant-owned credentials.
+func (h *BaseAPIHandler) applyTenantScope(ctx context.Context, meta map[string]any) {
+	if h == nil || h.Cfg == nil || len(h.Cfg.Tenants) == 0 || meta == nil {
+		return
+	}
+	var ginCtx *gin.Context
+	if ctx != nil {
+		ginCtx, _ = ctx.Value("gin").(*gin.Context)
+	}
+	principal, groups := callerIdentity(ginCtx)
+
+	scope := coreauth.TenantScope{}
+	if tenant := h.Cfg.TenantForPrincipal(principal, groups); tenant != nil {
+		scope = coreauth.TenantScope{Name: tenant.Name, AllowShared: tenant.AllowShared}
+		if ginCtx != nil {
+			ginCtx.Set(tenantContextKey, tenant.Name)
+		}
+	}
+	meta[coreexecutor.TenantMetadataKey] = scope
+}
+
+// callerIdentity returns the authenticated principal and any identity-provider
+// groups recorded by the access middleware.
+func callerIdentity(ginCtx *gin.Context) (string, []string) {
+	if ginCtx == nil {
+		return "", nil
+	}
+	principal := util.APIKeyFromGin(ginCtx)
+	var groups []string
+	if v, exists := ginCtx.Get("accessMetadata"); exists {
+		if metadata, ok := v.(map[string]string); ok {
+			for _, group := range strings.Split(metadata["groups"], ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					groups = append(groups, group)
+				}
+			}
+		}
+	}
+	return principal, groups
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor/helps"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"golang.org/x/net/context"
+)
+
+// tokenCountHeader tells clients that a count was estimated locally and how accurate it is.
+const tokenCountHeader = "X-CPA-Token-Count"
+
+// countTokensLocally answers a count_tokens request without contacting any provider.
+func (h *BaseAPIHandler) countTokensLocally(ctx context.Context, handlerType, modelName string, rawJSON []byte) ([]byte, http.Header, *interfaces.ErrorMessage) {
+	baseModel := thinking.ParseSuffix(modelName).ModelName
+	payload, result, err := helps.LocalTokenCountResponse(ctx, baseModel, sdktranslator.FromString(handlerType), rawJSON)
+	if err != nil {
+		return nil, nil, &interfaces.ErrorMessage{StatusCode: http.StatusInternalServerError, Error: err}
+	}
+	if ctx != nil {
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+			ginCtx.Header(tokenCountHeader, fmt.Sprintf("local; family=%s; accuracy=%s", result.Family, result.AccuracyLabel()))
+		}
+	}
+	return payload, nil, nil
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/toolrepair"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// newToolRepairer returns the tool call repairer for a request, or nil when repair
+// is disabled, does not apply to the model or the request declares no tools.
+func (h *BaseAPIHandler) newToolRepairer(handlerType, modelName string, rawJSON []byte) *toolrepair.Repairer {
+	if h == nil || h.Cfg == nil || !h.Cfg.ToolCallRepair.Enabled {
+		return nil
+	}
+	return toolrepair.New(&h.Cfg.ToolCallRepair, handlerType, modelName, rawJSON)
+}
+
+// repairToolCalls repairs the tool calls of a non-streaming response. The retry
+// flag is set when the configuration asks for unrepairable calls to be retried.
+func repairToolCalls(ctx context.Context, repairer *toolrepair.Repairer, payload []byte) (out []byte, retry bool) {
+	if repairer == nil {
+		return payload, false
+	}
+	out, report := repairer.RepairResponse(payload)
+	logToolRepair(repairer, report)
+	if report.Changed() && ctx != nil {
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+			ginCtx.Header(toolrepair.HeaderName, report.Header())
+		}
+	}
+	return out, report.Failed > 0 && repairer.RetryOnFailure()
+}
+
+func logToolRepair(repairer *toolrepair.Repairer, report toolrepair.Report) {
+	if !report.Changed() {
+		return
+	}
+	entry := log.WithFields(log.Fields{
+		"model":    repairer.Model(),
+		"calls":    report.Calls,
+		"repaired": report.Repaired,
+		"failed":   report.Failed,
+		"fixes":    report.Fixes,
+	})
+	if report.Failed > 0 {
+		entry.WithField("errors", report.Errors).Warn("tool call repair: unrepairable tool calls")
+		return
+	}
+	entry.Info("tool call repair: tool calls repaired")
+}
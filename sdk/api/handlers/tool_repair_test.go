@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// unrepairableOnceStreamExecutor streams a call to an undeclared tool on the first
+// attempt and keeps that stream open until its context is cancelled. Later
+// attempts answer with text.
+type unrepairableOnceStreamExecutor struct {
+	mu        sync.Mutex
+	calls     int
+	cancelled chan struct{}
+}
+
+func (e *unrepairableOnceStreamExecutor) Identifier() string { return "codex" }
+
+func (e *unrepairableOnceStreamExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "Execute not implemented"}
+}
+
+func (e *unrepairableOnceStreamExecutor) ExecuteStream(ctx context.Context, _ *coreauth.Auth, _ coreexecutor.Request, _ coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	e.mu.Lock()
+	e.calls++
+	call := e.calls
+	e.mu.Unlock()
+
+	ch := make(chan coreexecutor.StreamChunk)
+	go func() {
+		defer close(ch)
+		if call > 1 {
+			ch <- coreexecutor.StreamChunk{Payload: []byte(`{"id":"c2","model":"test-model","choices":[{"index":0,"delta":{"content":"done"}}]}`)}
+			return
+		}
+		chunks := []string{
+			`{"id":"c1","model":"test-model","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"drop_tables","arguments":"{}"}}]}}]}`,
+			`{"id":"c1","model":"test-model","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		}
+		for _, chunk := range chunks {
+			select {
+			case ch <- coreexecutor.StreamChunk{Payload: []byte(chunk)}:
+			case <-ctx.Done():
+			}
+		}
+		<-ctx.Done()
+		close(e.cancelled)
+	}()
+	return &coreexecutor.StreamResult{Chunks: ch}, nil
+}
+
+func (e *unrepairableOnceStreamExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *unrepairableOnceStreamExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (e *unrepairableOnceStreamExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented", HTTPStatus: http.StatusNotImplemented}
+}
+
+func TestExecuteStreamWithAuthManager_ToolRepairRetryCancelsAbandonedAttempt(t *testing.T) {
+	executor := &unrepairableOnceStreamExecutor{cancelled: make(chan struct{})}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "repair-auth", Provider: "codex", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "test-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{
+		ToolCallRepair: internalconfig.ToolCallRepairConfig{
+			Enabled:   true,
+			OnFailure: internalconfig.ToolRepairOnFailureRetry,
+		},
+	}, manager)
+	request := `{"model":"test-model","stream":true,"tools":[{"type":"function","function":{"name":"read_file","parameters":{"type":"object"}}}]}`
+	dataChan, _, errChan := handler.ExecuteStreamWithAuthManager(context.Background(), "openai", "test-model", []byte(request), "")
+
+	var got strings.Builder
+	for chunk := range dataChan {
+		got.Write(chunk)
+	}
+	for msg := range errChan {
+		if msg != nil {
+			t.Fatalf("unexpected error: %+v", msg)
+		}
+	}
+	if !strings.Contains(got.String(), `"content":"done"`) || strings.Contains(got.String(), "drop_tables") {
+		t.Fatalf("expected only the retried response, got %s", got.String())
+	}
+	select {
+	case <-executor.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the abandoned upstream stream was not cancelled")
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/transcript"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"golang.org/x/net/context"
+)
+
+// newTranscriptRecorder starts recording the exchange when transcript archiving is
+// enabled. The returned recorder is nil-safe.
+func (h *BaseAPIHandler) newTranscriptRecorder(ctx context.Context, handlerType, modelName string, rawJSON []byte, stream bool) *transcript.Recorder {
+	if h == nil || h.Cfg == nil || !h.Cfg.Transcripts.Enabled {
+		return nil
+	}
+	archiver := transcript.ForConfig(&h.Cfg.Transcripts)
+	if archiver == nil {
+		return nil
+	}
+	meta := transcript.Meta{
+		APIKey: util.APIKeyFromContext(ctx),
+		Model:  modelName,
+		Format: handlerType,
+		Stream: stream,
+	}
+	var header http.Header
+	if ctx != nil {
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+			meta.Tenant = ginCtx.GetString(tenantContextKey)
+			if ginCtx.Request != nil {
+				header = ginCtx.Request.Header
+			}
+		}
+	}
+	meta.Session = transcript.SessionID(header, rawJSON)
+	return archiver.NewRecorder(meta, rawJSON)
+}
@@ -68,6 +68,8 @@ func (AntigravityAuthenticator) Login(ctx context.Context, cfg *config.Config, o
 	redirectURI := fmt.Sprintf("http://localhost:%d/oauth-callback", port)
 	authURL := authSvc.BuildAuthURL(state, redirectURI)
 
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{AuthURL: authURL})
+
 	if !opts.NoBrowser {
 		fmt.Println("Opening browser for antigravity authentication")
 		if !browser.IsAvailable() {
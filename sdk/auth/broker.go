@@ -0,0 +1,453 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// LoginStatus is the lifecycle state of a brokered login session.
+type LoginStatus string
+
+const (
+	// LoginPending means the provider flow is starting and has no challenge yet.
+	LoginPending LoginStatus = "pending"
+	// LoginAwaitingUser means the user must open the authorize URL, enter the
+	// device code, or paste the callback URL.
+	LoginAwaitingUser LoginStatus = "awaiting_user"
+	// LoginSucceeded means the credential was obtained and saved.
+	LoginSucceeded LoginStatus = "succeeded"
+	// LoginFailed means the provider flow or the save returned an error.
+	LoginFailed LoginStatus = "failed"
+	// LoginCanceled means the session was canceled before it completed.
+	LoginCanceled LoginStatus = "canceled"
+)
+
+const (
+	// DefaultLoginSessionTimeout bounds how long a brokered login may run.
+	DefaultLoginSessionTimeout = 10 * time.Minute
+	// loginChallengeWait is how long Start waits for the provider to announce
+	// its authorize URL or device code before returning a pending session.
+	loginChallengeWait = 20 * time.Second
+	// loginSessionRetention keeps finished sessions queryable for this long.
+	loginSessionRetention = 30 * time.Minute
+)
+
+var (
+	// ErrLoginSessionNotFound is returned for unknown or expired session IDs.
+	ErrLoginSessionNotFound = errors.New("cliproxy auth: login session not found")
+	// ErrLoginSessionDone is returned when input is sent to a finished session.
+	ErrLoginSessionDone = errors.New("cliproxy auth: login session already finished")
+	// ErrLoginProviderUnsupported is returned when no authenticator serves the provider.
+	ErrLoginProviderUnsupported = errors.New("cliproxy auth: login provider not supported")
+	// ErrLoginInputPending is returned when earlier input has not been consumed yet.
+	ErrLoginInputPending = errors.New("cliproxy auth: login session already has pending input")
+)
+
+// loginProviderAliases maps common provider spellings onto authenticator keys.
+var loginProviderAliases = map[string]string{
+	"anthropic":  "claude",
+	"openai":     "codex",
+	"gemini-cli": "gemini",
+	"google":     "gemini",
+	"github":     "github-copilot",
+	"copilot":    "github-copilot",
+}
+
+// NormalizeLoginProvider lower-cases a provider name and resolves aliases.
+func NormalizeLoginProvider(provider string) string {
+	key := strings.ToLower(strings.TrimSpace(provider))
+	if alias, ok := loginProviderAliases[key]; ok {
+		return alias
+	}
+	return key
+}
+
+// LoginSession is a snapshot of a brokered login.
+type LoginSession struct {
+	ID       string      `json:"id"`
+	Provider string      `json:"provider"`
+	Status   LoginStatus `json:"status"`
+	// AuthURL is the authorize URL for browser-based flows.
+	AuthURL string `json:"auth_url,omitempty"`
+	// VerificationURL and UserCode are set for device-code flows.
+	VerificationURL string `json:"verification_url,omitempty"`
+	UserCode        string `json:"user_code,omitempty"`
+	// Prompt is the text of the input the provider flow is currently waiting for,
+	// usually the callback URL of a browser flow.
+	Prompt    string    `json:"prompt,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	AuthID    string    `json:"auth_id,omitempty"`
+	SavedPath string    `json:"saved_path,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Done reports whether the session reached a terminal state.
+func (s LoginSession) Done() bool {
+	switch s.Status {
+	case LoginSucceeded, LoginFailed, LoginCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+type loginSession struct {
+	state       LoginSession
+	input       chan string
+	cancel      context.CancelFunc
+	canceled    bool
+	done        chan struct{}
+	record      *coreauth.Auth
+	err         error
+	subscribers map[chan LoginSession]struct{}
+}
+
+// LoginBroker runs provider login flows as sessions that can be driven without a
+// terminal: the challenge is exposed on the session, pasted callback URLs are fed
+// back through Submit, and the resulting credential is saved through the manager.
+type LoginBroker struct {
+	manager *Manager
+
+	// BeforeSave, when set, runs on every obtained record before it is saved.
+	// An error fails the session.
+	BeforeSave func(ctx context.Context, record *coreauth.Auth) error
+
+	// Timeout bounds each session. Defaults to DefaultLoginSessionTimeout.
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*loginSession
+}
+
+// NewLoginBroker returns a broker that logs in through the manager's authenticators
+// and persists results through its store.
+func NewLoginBroker(manager *Manager) *LoginBroker {
+	return &LoginBroker{
+		manager:  manager,
+		Timeout:  DefaultLoginSessionTimeout,
+		sessions: make(map[string]*loginSession),
+	}
+}
+
+// Start begins a headless login for provider. Browsers are never opened; the
+// returned session carries the authorize URL or device code once the provider
+// announced it, and callback URLs are accepted through Submit.
+func (b *LoginBroker) Start(provider string, cfg *config.Config, opts *LoginOptions) (LoginSession, error) {
+	var loginOpts LoginOptions
+	if opts != nil {
+		loginOpts = *opts
+	}
+	loginOpts.NoBrowser = true
+	session, err := b.begin(provider, cfg, &loginOpts, true)
+	if err != nil {
+		return LoginSession{}, err
+	}
+
+	timer := time.NewTimer(loginChallengeWait)
+	defer timer.Stop()
+	updates, unsubscribe, _ := b.Subscribe(session.ID)
+	defer unsubscribe()
+	for {
+		select {
+		case snapshot, ok := <-updates:
+			if !ok {
+				return b.Get(session.ID)
+			}
+			if snapshot.Status != LoginPending {
+				return snapshot, nil
+			}
+		case <-timer.C:
+			return b.Get(session.ID)
+		}
+	}
+}
+
+// Login runs the provider flow to completion with the caller's options, as the
+// interactive CLI does, and returns the saved record and its storage path.
+func (b *LoginBroker) Login(ctx context.Context, provider string, cfg *config.Config, opts *LoginOptions) (*coreauth.Auth, string, error) {
+	if opts == nil {
+		opts = &LoginOptions{}
+	}
+	session, err := b.begin(provider, cfg, opts, false)
+	if err != nil {
+		return nil, "", err
+	}
+	b.mu.Lock()
+	entry := b.sessions[session.ID]
+	b.mu.Unlock()
+	select {
+	case <-entry.done:
+	case <-ctx.Done():
+		_ = b.Cancel(session.ID)
+		<-entry.done
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return entry.record, entry.state.SavedPath, entry.err
+}
+
+// begin registers a session and starts the provider flow in the background. Headless
+// sessions take their prompt input from Submit instead of the caller's prompt.
+func (b *LoginBroker) begin(provider string, cfg *config.Config, opts *LoginOptions, headless bool) (LoginSession, error) {
+	if b == nil || b.manager == nil {
+		return LoginSession{}, fmt.Errorf("cliproxy auth: login broker not initialized")
+	}
+	provider = NormalizeLoginProvider(provider)
+	authenticator, ok := b.manager.Authenticator(provider)
+	if !ok {
+		return LoginSession{}, fmt.Errorf("%w: %s", ErrLoginProviderUnsupported, provider)
+	}
+	id, err := newLoginSessionID()
+	if err != nil {
+		return LoginSession{}, err
+	}
+	timeout := b.Timeout
+	if timeout <= 0 {
+		timeout = DefaultLoginSessionTimeout
+	}
+	now := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	entry := &loginSession{
+		state: LoginSession{
+			ID:        id,
+			Provider:  provider,
+			Status:    LoginPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+			ExpiresAt: now.Add(timeout),
+		},
+		input:       make(chan string, 1),
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		subscribers: make(map[chan LoginSession]struct{}),
+	}
+
+	loginOpts := *opts
+	if headless {
+		loginOpts.Prompt = func(prompt string) (string, error) {
+			return b.awaitInput(ctx, entry, prompt)
+		}
+	}
+	ctx = misc.WithLoginChallengeHandler(ctx, func(challenge misc.LoginChallenge) {
+		b.update(entry, func(state *LoginSession) {
+			state.Status = LoginAwaitingUser
+			state.AuthURL = challenge.AuthURL
+			state.VerificationURL = challenge.VerificationURL
+			state.UserCode = challenge.UserCode
+		})
+	})
+
+	b.mu.Lock()
+	b.pruneLocked(now)
+	b.sessions[id] = entry
+	snapshot := entry.state
+	b.mu.Unlock()
+
+	go b.run(ctx, entry, authenticator, cfg, &loginOpts)
+	return snapshot, nil
+}
+
+func (b *LoginBroker) run(ctx context.Context, entry *loginSession, authenticator Authenticator, cfg *config.Config, opts *LoginOptions) {
+	defer entry.cancel()
+	record, err := authenticator.Login(ctx, cfg, opts)
+	if err == nil && record == nil {
+		err = fmt.Errorf("cliproxy auth: authenticator %s returned nil record", entry.state.Provider)
+	}
+	savedPath := ""
+	if err == nil && b.BeforeSave != nil {
+		if errHook := b.BeforeSave(ctx, record); errHook != nil {
+			err = fmt.Errorf("post-auth hook failed: %w", errHook)
+		}
+	}
+	if err == nil && b.manager.store != nil {
+		savedPath, err = b.manager.SaveAuth(record, cfg)
+	}
+
+	b.mu.Lock()
+	entry.record = record
+	entry.err = err
+	state := &entry.state
+	state.Prompt = ""
+	state.UpdatedAt = time.Now()
+	switch {
+	case entry.canceled:
+		state.Status = LoginCanceled
+		state.Error = "login canceled"
+	case err != nil:
+		state.Status = LoginFailed
+		state.Error = err.Error()
+	default:
+		state.Status = LoginSucceeded
+		state.AuthID = record.ID
+		state.SavedPath = savedPath
+	}
+	snapshot := *state
+	for ch := range entry.subscribers {
+		publish(ch, snapshot)
+		close(ch)
+	}
+	entry.subscribers = nil
+	close(entry.done)
+	b.mu.Unlock()
+}
+
+// awaitInput blocks the provider flow until Submit delivers input or the session ends.
+func (b *LoginBroker) awaitInput(ctx context.Context, entry *loginSession, prompt string) (string, error) {
+	b.update(entry, func(state *LoginSession) {
+		state.Status = LoginAwaitingUser
+		state.Prompt = strings.TrimSpace(prompt)
+	})
+	select {
+	case value := <-entry.input:
+		b.update(entry, func(state *LoginSession) { state.Prompt = "" })
+		return value, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (b *LoginBroker) update(entry *loginSession, fn func(state *LoginSession)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if entry.state.Done() {
+		return
+	}
+	fn(&entry.state)
+	entry.state.UpdatedAt = time.Now()
+	for ch := range entry.subscribers {
+		publish(ch, entry.state)
+	}
+}
+
+// publish delivers the snapshot without blocking, replacing a stale queued one.
+func publish(ch chan LoginSession, snapshot LoginSession) {
+	select {
+	case ch <- snapshot:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- snapshot:
+	default:
+	}
+}
+
+// Get returns the current snapshot of a session.
+func (b *LoginBroker) Get(id string) (LoginSession, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.sessions[id]
+	if !ok {
+		return LoginSession{}, ErrLoginSessionNotFound
+	}
+	return entry.state, nil
+}
+
+// List returns snapshots of all retained sessions, newest first.
+func (b *LoginBroker) List() []LoginSession {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pruneLocked(time.Now())
+	out := make([]LoginSession, 0, len(b.sessions))
+	for _, entry := range b.sessions {
+		out = append(out, entry.state)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+// Submit feeds user input, normally the callback URL the provider redirected the
+// browser to, into a waiting session.
+func (b *LoginBroker) Submit(id, value string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.sessions[id]
+	if !ok {
+		return ErrLoginSessionNotFound
+	}
+	if entry.state.Done() {
+		return ErrLoginSessionDone
+	}
+	select {
+	case entry.input <- value:
+		return nil
+	default:
+		return ErrLoginInputPending
+	}
+}
+
+// Cancel aborts a running session.
+func (b *LoginBroker) Cancel(id string) error {
+	b.mu.Lock()
+	entry, ok := b.sessions[id]
+	if ok && !entry.state.Done() {
+		entry.canceled = true
+	}
+	b.mu.Unlock()
+	if !ok {
+		return ErrLoginSessionNotFound
+	}
+	entry.cancel()
+	return nil
+}
+
+// Subscribe streams snapshots of a session as it changes. The channel receives the
+// current state first and is closed after the terminal state. The returned func
+// releases the subscription early.
+func (b *LoginBroker) Subscribe(id string) (<-chan LoginSession, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.sessions[id]
+	if !ok {
+		return nil, func() {}, ErrLoginSessionNotFound
+	}
+	ch := make(chan LoginSession, 4)
+	ch <- entry.state
+	if entry.state.Done() {
+		close(ch)
+		return ch, func() {}, nil
+	}
+	entry.subscribers[ch] = struct{}{}
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, exists := entry.subscribers[ch]; exists {
+			delete(entry.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe, nil
+}
+
+func (b *LoginBroker) pruneLocked(now time.Time) {
+	for id, entry := range b.sessions {
+		if entry.state.Done() && now.Sub(entry.state.UpdatedAt) > loginSessionRetention {
+			delete(b.sessions, id)
+		}
+	}
+}
+
+func newLoginSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("cliproxy auth: generate login session id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
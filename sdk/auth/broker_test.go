@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// callbackAuthenticator mimics a browser OAuth flow: it announces an authorize
+// URL and waits for the callback URL to be pasted through the prompt.
+type callbackAuthenticator struct{}
+
+func (callbackAuthenticator) Provider() string            { return "claude" }
+func (callbackAuthenticator) RefreshLead() *time.Duration { return nil }
+
+func (callbackAuthenticator) Login(ctx context.Context, _ *config.Config, opts *LoginOptions) (*coreauth.Auth, error) {
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{AuthURL: "https://example.com/authorize?state=s1"})
+	input, err := opts.Prompt("Paste the callback URL: ")
+	if err != nil {
+		return nil, err
+	}
+	callback, err := misc.ParseOAuthCallback(input)
+	if err != nil {
+		return nil, err
+	}
+	if callback.State != "s1" {
+		return nil, fmt.Errorf("state mismatch: %q", callback.State)
+	}
+	return &coreauth.Auth{ID: "claude-" + callback.Code + ".json", Provider: "claude", Metadata: map[string]any{"code": callback.Code}}, nil
+}
+
+type brokerMemoryStore struct {
+	mu    sync.Mutex
+	saved map[string]*coreauth.Auth
+}
+
+func (s *brokerMemoryStore) List(context.Context) ([]*coreauth.Auth, error) { return nil, nil }
+func (s *brokerMemoryStore) Delete(context.Context, string) error           { return nil }
+
+func (s *brokerMemoryStore) Save(_ context.Context, auth *coreauth.Auth) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved[auth.ID] = auth
+	return "/auths/" + auth.ID, nil
+}
+
+func waitLoginDone(t *testing.T, broker *LoginBroker, id string) LoginSession {
+	t.Helper()
+	updates, unsubscribe, err := broker.Subscribe(id)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer unsubscribe()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case session, ok := <-updates:
+			if !ok {
+				final, _ := broker.Get(id)
+				return final
+			}
+			if session.Done() {
+				return session
+			}
+		case <-timeout:
+			t.Fatal("login session did not finish")
+		}
+	}
+}
+
+func TestLoginBrokerCompletesHeadlessLoginFromSubmittedCallback(t *testing.T) {
+	store := &brokerMemoryStore{saved: make(map[string]*coreauth.Auth)}
+	broker := NewLoginBroker(NewManager(store, callbackAuthenticator{}))
+	var hooked []string
+	broker.BeforeSave = func(_ context.Context, record *coreauth.Auth) error {
+		hooked = append(hooked, record.ID)
+		return nil
+	}
+
+	session, err := broker.Start("Anthropic", &config.Config{}, nil)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if session.Provider != "claude" || session.Status != LoginAwaitingUser || session.AuthURL != "https://example.com/authorize?state=s1" {
+		t.Fatalf("session = %+v, want claude awaiting the user with the authorize URL", session)
+	}
+
+	if err = broker.Submit(session.ID, "http://localhost:54545/callback?code=abc&state=s1"); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	final := waitLoginDone(t, broker, session.ID)
+	if final.Status != LoginSucceeded || final.AuthID != "claude-abc.json" || final.SavedPath != "/auths/claude-abc.json" {
+		t.Fatalf("final session = %+v, want saved claude-abc.json", final)
+	}
+	if store.saved["claude-abc.json"] == nil || len(hooked) != 1 {
+		t.Fatalf("saved = %v, hooked = %v, want one saved and hooked record", store.saved, hooked)
+	}
+	if err = broker.Submit(session.ID, "late"); !errors.Is(err, ErrLoginSessionDone) {
+		t.Fatalf("Submit after completion = %v, want ErrLoginSessionDone", err)
+	}
+	if sessions := broker.List(); len(sessions) != 1 || sessions[0].ID != session.ID {
+		t.Fatalf("List = %+v, want the finished session", sessions)
+	}
+}
+
+func TestLoginBrokerCancelAndUnknownProvider(t *testing.T) {
+	store := &brokerMemoryStore{saved: make(map[string]*coreauth.Auth)}
+	broker := NewLoginBroker(NewManager(store, callbackAuthenticator{}))
+
+	if _, err := broker.Start("nope", &config.Config{}, nil); !errors.Is(err, ErrLoginProviderUnsupported) {
+		t.Fatalf("Start(nope) = %v, want ErrLoginProviderUnsupported", err)
+	}
+
+	session, err := broker.Start("claude", &config.Config{}, nil)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err = broker.Cancel(session.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if final := waitLoginDone(t, broker, session.ID); final.Status != LoginCanceled {
+		t.Fatalf("final session = %+v, want canceled", final)
+	}
+	if len(store.saved) != 0 {
+		t.Fatalf("saved = %v, want nothing saved", store.saved)
+	}
+	if _, err = broker.Get("missing"); !errors.Is(err, ErrLoginSessionNotFound) {
+		t.Fatalf("Get(missing) = %v, want ErrLoginSessionNotFound", err)
+	}
+}
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Bundle formats accepted by EncodeBundle.
+const (
+	BundleFormatZip   = "zip"
+	BundleFormatJSONL = "jsonl"
+)
+
+const (
+	bundleKDFIterations = 600000
+	bundleSaltSize      = 16
+	// maxBundleEntrySize caps a single decompressed credential file.
+	maxBundleEntrySize = 4 << 20
+)
+
+// bundleMagic prefixes passphrase-encrypted bundles.
+var bundleMagic = []byte("CPABUNDLE1")
+
+var (
+	// ErrBundlePassphraseRequired is returned when an encrypted bundle is read without a passphrase.
+	ErrBundlePassphraseRequired = errors.New("cliproxy auth: bundle is encrypted, passphrase required")
+	// ErrBundleDecrypt is returned when the passphrase is wrong or the bundle is corrupted.
+	ErrBundleDecrypt = errors.New("cliproxy auth: bundle decryption failed, wrong passphrase or corrupted data")
+)
+
+// BundleEntry is one credential file inside an import/export bundle. Data holds the
+// file contents in this proxy's auth file format.
+type BundleEntry struct {
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+	// Source names the format the entry was converted from, "native" for auth files.
+	Source string `json:"-"`
+}
+
+// EncodeBundle packs entries as a zip archive or as JSON lines, encrypting the
+// result with AES-GCM when passphrase is not empty.
+func EncodeBundle(entries []BundleEntry, format, passphrase string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", BundleFormatZip:
+		zw := zip.NewWriter(&buf)
+		for _, entry := range entries {
+			w, err := zw.Create(path.Base(entry.Name))
+			if err != nil {
+				return nil, fmt.Errorf("cliproxy auth: add %s to bundle: %w", entry.Name, err)
+			}
+			if _, err = w.Write(entry.Data); err != nil {
+				return nil, fmt.Errorf("cliproxy auth: add %s to bundle: %w", entry.Name, err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("cliproxy auth: close bundle: %w", err)
+		}
+	case BundleFormatJSONL:
+		enc := json.NewEncoder(&buf)
+		for _, entry := range entries {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, entry.Data); err != nil {
+				return nil, fmt.Errorf("cliproxy auth: %s is not valid JSON: %w", entry.Name, err)
+			}
+			if err := enc.Encode(BundleEntry{Name: entry.Name, Data: compact.Bytes()}); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("cliproxy auth: unsupported bundle format %q", format)
+	}
+	if passphrase == "" {
+		return buf.Bytes(), nil
+	}
+	return encryptBundle(buf.Bytes(), passphrase)
+}
+
+// DecodeBundle reads credentials from a zip or JSONL bundle, optionally encrypted,
+// or from a single credential file. Files in external formats, such as the Codex
+// CLI auth.json or the GitHub Copilot hosts.json, are converted to auth files.
+// name is the uploaded file name and helps recognize single files.
+func DecodeBundle(name string, data []byte, passphrase string) ([]BundleEntry, error) {
+	if bytes.HasPrefix(data, bundleMagic) {
+		if passphrase == "" {
+			return nil, ErrBundlePassphraseRequired
+		}
+		plain, err := decryptBundle(data, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		data = plain
+	}
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return decodeZipBundle(data)
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("cliproxy auth: %s is empty", name)
+	}
+	var single map[string]any
+	if err := json.Unmarshal(trimmed, &single); err == nil {
+		return ConvertCredential(name, trimmed, nil)
+	}
+	return decodeJSONLBundle(trimmed)
+}
+
+func decodeZipBundle(data []byte) ([]BundleEntry, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("cliproxy auth: read zip bundle: %w", err)
+	}
+	files := make(map[string][]byte)
+	order := make([]string, 0, len(zr.File))
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() || !strings.HasSuffix(strings.ToLower(file.Name), ".json") {
+			continue
+		}
+		rc, errOpen := file.Open()
+		if errOpen != nil {
+			return nil, fmt.Errorf("cliproxy auth: open %s: %w", file.Name, errOpen)
+		}
+		content, errRead := io.ReadAll(io.LimitReader(rc, maxBundleEntrySize+1))
+		_ = rc.Close()
+		if errRead != nil {
+			return nil, fmt.Errorf("cliproxy auth: read %s: %w", file.Name, errRead)
+		}
+		if len(content) > maxBundleEntrySize {
+			return nil, fmt.Errorf("cliproxy auth: %s exceeds %d bytes", file.Name, maxBundleEntrySize)
+		}
+		base := path.Base(file.Name)
+		files[base] = content
+		order = append(order, base)
+	}
+	return ConvertCredentialFiles(order, files)
+}
+
+// ConvertCredentialFiles converts a set of credential files, given by name in
+// order, letting files reference each other like the Kiro IDE token and its
+// device registration do.
+func ConvertCredentialFiles(order []string, files map[string][]byte) ([]BundleEntry, error) {
+	var entries []BundleEntry
+	for _, name := range order {
+		converted, err := ConvertCredential(name, files[name], files)
+		if err != nil {
+			if errors.Is(err, errCompanionFile) {
+				continue
+			}
+			return nil, err
+		}
+		entries = append(entries, converted...)
+	}
+	return entries, nil
+}
+
+func decodeJSONLBundle(data []byte) ([]BundleEntry, error) {
+	var entries []BundleEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBundleEntrySize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var entry BundleEntry
+		if err := json.Unmarshal(raw, &entry); err != nil || entry.Name == "" || len(entry.Data) == 0 {
+			return nil, fmt.Errorf("cliproxy auth: invalid bundle line %d", line)
+		}
+		converted, err := ConvertCredential(entry.Name, entry.Data, nil)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, converted...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cliproxy auth: read bundle: %w", err)
+	}
+	return entries, nil
+}
+
+func bundleKey(passphrase string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, bundleKDFIterations, 32)
+}
+
+// encryptBundle seals data as magic | salt | nonce | AES-256-GCM ciphertext.
+func encryptBundle(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, bundleSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := bundleKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(bundleMagic)+len(salt)+len(nonce)+len(data)+gcm.Overhead())
+	out = append(out, bundleMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, bundleMagic), nil
+}
+
+func decryptBundle(data []byte, passphrase string) ([]byte, error) {
+	rest := data[len(bundleMagic):]
+	if len(rest) < bundleSaltSize {
+		return nil, ErrBundleDecrypt
+	}
+	key, err := bundleKey(passphrase, rest[:bundleSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	rest = rest[bundleSaltSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, ErrBundleDecrypt
+	}
+	plain, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], bundleMagic)
+	if err != nil {
+		return nil, ErrBundleDecrypt
+	}
+	return plain, nil
+}
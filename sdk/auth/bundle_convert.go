@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/codex"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/gemini"
+	kiroauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
+	"github.com/tidwall/gjson"
+)
+
+// Source formats recognized by ConvertCredential.
+const (
+	CredentialSourceNative       = "native"
+	CredentialSourceCodexCLI     = "codex-cli"
+	CredentialSourceGeminiCLI    = "gemini-cli"
+	CredentialSourceClaudeCode   = "claude-code"
+	CredentialSourceKiroIDE      = "kiro-ide"
+	CredentialSourceCopilotHosts = "copilot-hosts"
+)
+
+// errCompanionFile marks files that only supplement another credential, such as
+// the Kiro IDE device registration, and are not imported on their own.
+var errCompanionFile = errors.New("cliproxy auth: device registration file, import it together with its token")
+
+// ConvertCredential turns one credential file into auth files. Auth files of this
+// proxy, recognized by their "type" field, pass through unchanged; the Codex CLI
+// auth.json, Gemini CLI oauth_creds.json, Claude Code .credentials.json, Kiro IDE
+// kiro-auth-token.json and GitHub Copilot hosts.json/apps.json are converted.
+// siblings holds the other files of the same bundle by base name, used to resolve
+// the Kiro device registration.
+func ConvertCredential(name string, data []byte, siblings map[string][]byte) ([]BundleEntry, error) {
+	name = path.Base(strings.TrimSpace(name))
+	if !gjson.ValidBytes(data) || !gjson.ParseBytes(data).IsObject() {
+		return nil, fmt.Errorf("cliproxy auth: %s is not a JSON object", name)
+	}
+	root := gjson.ParseBytes(data)
+	switch {
+	case root.Get("type").Type == gjson.String:
+		if isUnsafeBundleName(name) {
+			return nil, fmt.Errorf("cliproxy auth: invalid file name %q", name)
+		}
+		return []BundleEntry{{Name: name, Data: data, Source: CredentialSourceNative}}, nil
+	case root.Get("tokens.refresh_token").Exists():
+		entry, err := convertCodexCLI(root)
+		return wrapConverted(name, entry, err)
+	case root.Get("claudeAiOauth").IsObject():
+		entry, err := convertClaudeCode(root)
+		return wrapConverted(name, entry, err)
+	case root.Get("accessToken").Exists() && root.Get("refreshToken").Exists():
+		entry, err := convertKiroIDE(root, siblings)
+		return wrapConverted(name, entry, err)
+	case root.Get("clientId").Exists() && root.Get("clientSecret").Exists():
+		return nil, errCompanionFile
+	case root.Get("refresh_token").Exists() && (root.Get("expiry_date").Exists() || strings.Contains(root.Get("scope").String(), "googleapis.com")):
+		entry, err := convertGeminiCLI(root)
+		return wrapConverted(name, entry, err)
+	}
+	if entries := convertCopilotHosts(root); len(entries) > 0 {
+		return entries, nil
+	}
+	return nil, fmt.Errorf("cliproxy auth: %s is not a recognized credential format", name)
+}
+
+func wrapConverted(name string, entry BundleEntry, err error) ([]BundleEntry, error) {
+	if err != nil {
+		return nil, fmt.Errorf("cliproxy auth: convert %s: %w", name, err)
+	}
+	return []BundleEntry{entry}, nil
+}
+
+func nativeEntry(name, source string, metadata map[string]any) (BundleEntry, error) {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return BundleEntry{}, err
+	}
+	return BundleEntry{Name: name, Data: data, Source: source}, nil
+}
+
+// convertCodexCLI converts ~/.codex/auth.json.
+func convertCodexCLI(root gjson.Result) (BundleEntry, error) {
+	tokens := root.Get("tokens")
+	idToken := tokens.Get("id_token").String()
+	claims, err := codex.ParseJWTToken(idToken)
+	if err != nil || claims == nil || strings.TrimSpace(claims.Email) == "" {
+		return BundleEntry{}, fmt.Errorf("id_token carries no account email")
+	}
+	planType := strings.TrimSpace(claims.CodexAuthInfo.ChatgptPlanType)
+	accountID := strings.TrimSpace(tokens.Get("account_id").String())
+	if accountID == "" {
+		accountID = strings.TrimSpace(claims.CodexAuthInfo.ChatgptAccountID)
+	}
+	hashAccountID := ""
+	if accountID != "" {
+		digest := sha256.Sum256([]byte(accountID))
+		hashAccountID = hex.EncodeToString(digest[:])[:8]
+	}
+	metadata := map[string]any{
+		"type":          "codex",
+		"id_token":      idToken,
+		"access_token":  tokens.Get("access_token").String(),
+		"refresh_token": tokens.Get("refresh_token").String(),
+		"account_id":    accountID,
+		"email":         claims.Email,
+		"last_refresh":  root.Get("last_refresh").String(),
+	}
+	if access, errAccess := codex.ParseJWTToken(tokens.Get("access_token").String()); errAccess == nil && access.Exp > 0 {
+		metadata["expired"] = time.Unix(int64(access.Exp), 0).UTC().Format(time.RFC3339)
+	}
+	return nativeEntry(codex.CredentialFileName(claims.Email, planType, hashAccountID, true), CredentialSourceCodexCLI, metadata)
+}
+
+// convertGeminiCLI converts ~/.gemini/oauth_creds.json. The file has no project,
+// so the imported credential uses the account's default Code Assist project.
+func convertGeminiCLI(root gjson.Result) (BundleEntry, error) {
+	email := jwtClaim(root.Get("id_token").String(), "email")
+	if email == "" {
+		return BundleEntry{}, fmt.Errorf("id_token carries no account email")
+	}
+	token := map[string]any{
+		"access_token":    root.Get("access_token").String(),
+		"refresh_token":   root.Get("refresh_token").String(),
+		"token_type":      root.Get("token_type").String(),
+		"token_uri":       "https://oauth2.googleapis.com/token",
+		"client_id":       gemini.ClientID,
+		"client_secret":   gemini.ClientSecret,
+		"scopes":          gemini.Scopes,
+		"universe_domain": "googleapis.com",
+	}
+	if expiry := root.Get("expiry_date").Int(); expiry > 0 {
+		token["expiry"] = time.UnixMilli(expiry).UTC().Format(time.RFC3339)
+	}
+	metadata := map[string]any{
+		"type":       "gemini",
+		"token":      token,
+		"project_id": "",
+		"email":      email,
+		"auto":       false,
+		"checked":    false,
+	}
+	return nativeEntry(fmt.Sprintf("gemini-%s.json", email), CredentialSourceGeminiCLI, metadata)
+}
+
+// convertClaudeCode converts ~/.claude/.credentials.json. It carries no email, so
+// the file is named after a digest of the refresh token.
+func convertClaudeCode(root gjson.Result) (BundleEntry, error) {
+	oauth := root.Get("claudeAiOauth")
+	refreshToken := oauth.Get("refreshToken").String()
+	if refreshToken == "" {
+		return BundleEntry{}, fmt.Errorf("claudeAiOauth has no refresh token")
+	}
+	metadata := map[string]any{
+		"type":          "claude",
+		"access_token":  oauth.Get("accessToken").String(),
+		"refresh_token": refreshToken,
+		"email":         "",
+		"last_refresh":  time.Now().UTC().Format(time.RFC3339),
+	}
+	if expiresAt := oauth.Get("expiresAt").Int(); expiresAt > 0 {
+		metadata["expired"] = time.UnixMilli(expiresAt).UTC().Format(time.RFC3339)
+	}
+	if subscription := oauth.Get("subscriptionType").String(); subscription != "" {
+		metadata["subscription_type"] = subscription
+	}
+	digest := sha256.Sum256([]byte(refreshToken))
+	return nativeEntry(fmt.Sprintf("claude-code-%s.json", hex.EncodeToString(digest[:])[:8]), CredentialSourceClaudeCode, metadata)
+}
+
+// convertKiroIDE converts the Kiro IDE token cache. Enterprise tokens reference
+// their OIDC client through clientIdHash, resolved from a sibling file of the bundle.
+func convertKiroIDE(root gjson.Result, siblings map[string][]byte) (BundleEntry, error) {
+	var tokenData kiroauth.KiroTokenData
+	if err := json.Unmarshal([]byte(root.Raw), &tokenData); err != nil {
+		return BundleEntry{}, err
+	}
+	tokenData.AuthMethod = strings.ToLower(tokenData.AuthMethod)
+	if hash := tokenData.ClientIDHash; hash != "" && tokenData.ClientID == "" {
+		if registration, ok := siblings[hash+".json"]; ok {
+			tokenData.ClientID = gjson.GetBytes(registration, "clientId").String()
+			tokenData.ClientSecret = gjson.GetBytes(registration, "clientSecret").String()
+		}
+	}
+	record := kiroIDEImportRecord(&tokenData)
+	return nativeEntry(record.FileName, CredentialSourceKiroIDE, record.Metadata)
+}
+
+// convertCopilotHosts converts the GitHub Copilot hosts.json or apps.json, which map
+// a host key to the user and OAuth token of each signed-in account.
+func convertCopilotHosts(root gjson.Result) []BundleEntry {
+	var entries []BundleEntry
+	root.ForEach(func(key, value gjson.Result) bool {
+		if !strings.HasPrefix(key.String(), "github.com") || !value.IsObject() {
+			return true
+		}
+		token := value.Get("oauth_token").String()
+		user := strings.TrimSpace(value.Get("user").String())
+		if token == "" || user == "" {
+			return true
+		}
+		entry, err := nativeEntry(fmt.Sprintf("github-copilot-%s.json", user), CredentialSourceCopilotHosts, map[string]any{
+			"type":         "github-copilot",
+			"username":     user,
+			"access_token": token,
+			"token_type":   "bearer",
+			"scope":        "",
+			"timestamp":    time.Now().UnixMilli(),
+		})
+		if err == nil {
+			entries = append(entries, entry)
+		}
+		return true
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// jwtClaim reads a string claim from an unverified JWT.
+func jwtClaim(token, claim string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(gjson.GetBytes(payload, claim).String())
+}
+
+func isUnsafeBundleName(name string) bool {
+	return name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || !strings.HasSuffix(strings.ToLower(name), ".json")
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// ImportAction is the outcome planned or applied for one imported credential.
+type ImportAction string
+
+const (
+	// ImportCreate adds a credential that is not present yet.
+	ImportCreate ImportAction = "create"
+	// ImportOverwrite replaces an auth file with the same name.
+	ImportOverwrite ImportAction = "overwrite"
+	// ImportDuplicate skips a credential whose account already exists under another name.
+	ImportDuplicate ImportAction = "duplicate"
+	// ImportFailed marks a credential that could not be written.
+	ImportFailed ImportAction = "failed"
+)
+
+// ImportItem reports what happens to one credential of a bundle.
+type ImportItem struct {
+	Name        string       `json:"name"`
+	Provider    string       `json:"provider"`
+	Account     string       `json:"account,omitempty"`
+	Source      string       `json:"source"`
+	Action      ImportAction `json:"action"`
+	DuplicateOf string       `json:"duplicate_of,omitempty"`
+	Error       string       `json:"error,omitempty"`
+
+	entry BundleEntry
+}
+
+// ImportReport summarizes a bundle import.
+type ImportReport struct {
+	DryRun      bool         `json:"dry_run"`
+	Created     int          `json:"created"`
+	Overwritten int          `json:"overwritten"`
+	Duplicates  int          `json:"duplicates"`
+	Failed      int          `json:"failed"`
+	Items       []ImportItem `json:"items"`
+}
+
+// PlanImport decides the action for each bundle entry against the existing auths.
+// An entry whose file name exists overwrites it; an entry whose account identity,
+// the provider and Auth.AccountInfo value, matches an existing auth or an earlier
+// entry under another name is a duplicate and skipped.
+func PlanImport(entries []BundleEntry, existing []*coreauth.Auth) *ImportReport {
+	names := make(map[string]bool, len(existing))
+	identities := make(map[string]string, len(existing))
+	for _, auth := range existing {
+		if auth == nil {
+			continue
+		}
+		name := auth.FileName
+		if name == "" {
+			name = auth.ID
+		}
+		name = filepath.Base(name)
+		names[name] = true
+		if identity := importIdentity(auth.Provider, auth.Metadata); identity != "" {
+			identities[identity] = name
+		}
+	}
+
+	report := &ImportReport{Items: make([]ImportItem, 0, len(entries))}
+	for _, entry := range entries {
+		item := ImportItem{Name: entry.Name, Source: entry.Source, Action: ImportCreate, entry: entry}
+		metadata := make(map[string]any)
+		if err := json.Unmarshal(entry.Data, &metadata); err != nil {
+			item.Action = ImportFailed
+			item.Error = fmt.Sprintf("invalid auth file: %v", err)
+			report.Items = append(report.Items, item)
+			continue
+		}
+		item.Provider, _ = metadata["type"].(string)
+		identity := importIdentity(item.Provider, metadata)
+		if identity != "" {
+			item.Account = identity[strings.Index(identity, "|")+1:]
+		}
+		if owner, ok := identities[identity]; ok && identity != "" && owner != entry.Name {
+			item.Action = ImportDuplicate
+			item.DuplicateOf = owner
+		} else if names[entry.Name] {
+			item.Action = ImportOverwrite
+		}
+		if item.Action != ImportDuplicate {
+			names[entry.Name] = true
+			if identity != "" {
+				identities[identity] = entry.Name
+			}
+		}
+		report.Items = append(report.Items, item)
+	}
+	report.tally()
+	return report
+}
+
+// Apply writes the planned creations and overwrites through write, recording
+// failures in the report. It does nothing for a dry run.
+func (r *ImportReport) Apply(write func(entry BundleEntry) error) {
+	if r == nil || r.DryRun || write == nil {
+		return
+	}
+	for i := range r.Items {
+		item := &r.Items[i]
+		if item.Action != ImportCreate && item.Action != ImportOverwrite {
+			continue
+		}
+		if err := write(item.entry); err != nil {
+			item.Action = ImportFailed
+			item.Error = err.Error()
+		}
+	}
+	r.tally()
+}
+
+func (r *ImportReport) tally() {
+	r.Created, r.Overwritten, r.Duplicates, r.Failed = 0, 0, 0, 0
+	for _, item := range r.Items {
+		switch item.Action {
+		case ImportCreate:
+			r.Created++
+		case ImportOverwrite:
+			r.Overwritten++
+		case ImportDuplicate:
+			r.Duplicates++
+		case ImportFailed:
+			r.Failed++
+		}
+	}
+}
+
+// importIdentity keys an account by provider and account info. Auth files store
+// Gemini CLI credentials under type "gemini" while the runtime uses "gemini-cli".
+func importIdentity(provider string, metadata map[string]any) string {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if provider == "gemini" {
+		provider = "gemini-cli"
+	}
+	_, account := (&coreauth.Auth{Provider: provider, Metadata: metadata}).AccountInfo()
+	account = strings.TrimSpace(account)
+	if provider == "" || account == "" {
+		return ""
+	}
+	return provider + "|" + strings.ToLower(account)
+}
+
+// ReadAuthDirEntries loads the auth files in dir as bundle entries, limited to
+// names when it is not empty.
+func ReadAuthDirEntries(dir string, names []string) ([]BundleEntry, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			wanted[filepath.Base(name)] = true
+		}
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cliproxy auth: read auth dir: %w", err)
+	}
+	entries := make([]BundleEntry, 0, len(files))
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(strings.ToLower(name), ".json") {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[name] {
+			continue
+		}
+		data, errRead := os.ReadFile(filepath.Join(dir, name))
+		if errRead != nil {
+			return nil, fmt.Errorf("cliproxy auth: read %s: %w", name, errRead)
+		}
+		entries = append(entries, BundleEntry{Name: name, Data: data, Source: CredentialSourceNative})
+	}
+	if len(entries) < len(wanted) {
+		found := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			found[entry.Name] = true
+		}
+		missing := make([]string, 0, len(wanted)-len(entries))
+		for name := range wanted {
+			if !found[name] {
+				missing = append(missing, name)
+			}
+		}
+		sort.Strings(missing)
+		return nil, fmt.Errorf("cliproxy auth: auth files not found: %s", strings.Join(missing, ", "))
+	}
+	return entries, nil
+}
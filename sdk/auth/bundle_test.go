@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func testJWT(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+func TestBundleEncryptedRoundTrip(t *testing.T) {
+	entries := []BundleEntry{
+		{Name: "claude-a@example.com.json", Data: json.RawMessage(`{"type":"claude","email":"a@example.com"}`)},
+		{Name: "codex-b@example.com.json", Data: json.RawMessage(`{"type":"codex","email":"b@example.com"}`)},
+	}
+	for _, format := range []string{BundleFormatZip, BundleFormatJSONL} {
+		bundle, err := EncodeBundle(entries, format, "s3cret")
+		if err != nil {
+			t.Fatalf("EncodeBundle(%s): %v", format, err)
+		}
+		if _, err = DecodeBundle("bundle", bundle, ""); !errors.Is(err, ErrBundlePassphraseRequired) {
+			t.Fatalf("DecodeBundle(%s) without passphrase = %v, want ErrBundlePassphraseRequired", format, err)
+		}
+		if _, err = DecodeBundle("bundle", bundle, "wrong"); !errors.Is(err, ErrBundleDecrypt) {
+			t.Fatalf("DecodeBundle(%s) with wrong passphrase = %v, want ErrBundleDecrypt", format, err)
+		}
+		decoded, err := DecodeBundle("bundle", bundle, "s3cret")
+		if err != nil {
+			t.Fatalf("DecodeBundle(%s): %v", format, err)
+		}
+		if len(decoded) != 2 || decoded[0].Name != entries[0].Name || decoded[1].Name != entries[1].Name {
+			t.Fatalf("DecodeBundle(%s) = %+v, want both entries", format, decoded)
+		}
+		if provider, _ := jsonField(decoded[1].Data, "type"); provider != "codex" || decoded[1].Source != CredentialSourceNative {
+			t.Fatalf("DecodeBundle(%s) second entry = %s (%s), want native codex", format, decoded[1].Data, decoded[1].Source)
+		}
+	}
+}
+
+func TestConvertCredentialExternalFormats(t *testing.T) {
+	idToken := testJWT(t, map[string]any{
+		"email": "dev@example.com",
+		"https://api.openai.com/auth": map[string]any{
+			"chatgpt_account_id": "acct-1",
+			"chatgpt_plan_type":  "plus",
+		},
+	})
+	codexCLI := `{"OPENAI_API_KEY":null,"tokens":{"id_token":"` + idToken + `","access_token":"at","refresh_token":"rt","account_id":"acct-1"},"last_refresh":"2026-01-02T03:04:05Z"}`
+	entries, err := ConvertCredential("auth.json", []byte(codexCLI), nil)
+	if err != nil {
+		t.Fatalf("ConvertCredential(codex): %v", err)
+	}
+	if len(entries) != 1 || entries[0].Source != CredentialSourceCodexCLI {
+		t.Fatalf("codex entries = %+v", entries)
+	}
+	if provider, _ := jsonField(entries[0].Data, "type"); provider != "codex" {
+		t.Fatalf("codex type = %q", provider)
+	}
+	if email, _ := jsonField(entries[0].Data, "email"); email != "dev@example.com" {
+		t.Fatalf("codex email = %q", email)
+	}
+
+	hosts := `{"github.com":{"user":"octocat","oauth_token":"gho_x"},"github.com:Iv1.b507a08c87ecfe98":{"user":"hubot","oauth_token":"ghu_y"}}`
+	entries, err = ConvertCredential("hosts.json", []byte(hosts), nil)
+	if err != nil {
+		t.Fatalf("ConvertCredential(copilot): %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name != "github-copilot-hubot.json" || entries[1].Name != "github-copilot-octocat.json" {
+		t.Fatalf("copilot entries = %+v", entries)
+	}
+
+	if _, err = ConvertCredential("notes.json", []byte(`{"hello":"world"}`), nil); err == nil {
+		t.Fatal("ConvertCredential(unknown) succeeded, want error")
+	}
+}
+
+func TestDecodeBundleResolvesKiroRegistration(t *testing.T) {
+	bundle, err := EncodeBundle([]BundleEntry{
+		{Name: "kiro-auth-token.json", Data: json.RawMessage(`{"accessToken":"at","refreshToken":"rt","authMethod":"IdC","provider":"Enterprise","clientIdHash":"abc123","region":"us-east-1"}`)},
+		{Name: "abc123.json", Data: json.RawMessage(`{"clientId":"cid","clientSecret":"csecret","expiresAt":"2030-01-01T00:00:00Z"}`)},
+	}, BundleFormatZip, "")
+	if err != nil {
+		t.Fatalf("EncodeBundle: %v", err)
+	}
+	entries, err := DecodeBundle("cache.zip", bundle, "")
+	if err != nil {
+		t.Fatalf("DecodeBundle: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Source != CredentialSourceKiroIDE {
+		t.Fatalf("entries = %+v, want only the kiro token", entries)
+	}
+	if clientID, _ := jsonField(entries[0].Data, "client_id"); clientID != "cid" {
+		t.Fatalf("client_id = %q, want it resolved from the registration", clientID)
+	}
+	if method, _ := jsonField(entries[0].Data, "auth_method"); method != "idc" {
+		t.Fatalf("auth_method = %q, want idc", method)
+	}
+}
+
+func TestPlanImportDetectsDuplicatesByAccount(t *testing.T) {
+	existing := []*coreauth.Auth{
+		{ID: "gemini-dev@example.com-proj.json", FileName: "gemini-dev@example.com-proj.json", Provider: "gemini-cli", Metadata: map[string]any{"type": "gemini", "email": "dev@example.com", "project_id": "proj"}},
+		{ID: "claude-a@example.com.json", FileName: "claude-a@example.com.json", Provider: "claude", Metadata: map[string]any{"type": "claude", "email": "a@example.com"}},
+	}
+	entries := []BundleEntry{
+		{Name: "gemini-copy.json", Data: json.RawMessage(`{"type":"gemini","email":"Dev@example.com","project_id":"proj"}`)},
+		{Name: "claude-a@example.com.json", Data: json.RawMessage(`{"type":"claude","email":"a@example.com"}`)},
+		{Name: "codex-new.json", Data: json.RawMessage(`{"type":"codex","email":"new@example.com"}`)},
+		{Name: "codex-new-again.json", Data: json.RawMessage(`{"type":"codex","email":"new@example.com"}`)},
+		{Name: "broken.json", Data: json.RawMessage(`[1,2]`)},
+	}
+	report := PlanImport(entries, existing)
+	want := []ImportAction{ImportDuplicate, ImportOverwrite, ImportCreate, ImportDuplicate, ImportFailed}
+	for i, action := range want {
+		if report.Items[i].Action != action {
+			t.Fatalf("item %d (%s) action = %s, want %s", i, report.Items[i].Name, report.Items[i].Action, action)
+		}
+	}
+	if report.Items[0].DuplicateOf != "gemini-dev@example.com-proj.json" || report.Items[3].DuplicateOf != "codex-new.json" {
+		t.Fatalf("duplicate_of = %q, %q", report.Items[0].DuplicateOf, report.Items[3].DuplicateOf)
+	}
+
+	report.DryRun = true
+	report.Apply(func(BundleEntry) error {
+		t.Fatal("dry run wrote an entry")
+		return nil
+	})
+
+	report.DryRun = false
+	var written []string
+	report.Apply(func(entry BundleEntry) error {
+		written = append(written, entry.Name)
+		if entry.Name == "codex-new.json" {
+			return errors.New("disk full")
+		}
+		return nil
+	})
+	if len(written) != 2 || report.Created != 0 || report.Overwritten != 1 || report.Duplicates != 2 || report.Failed != 2 {
+		t.Fatalf("written = %v, report = %+v", written, report)
+	}
+}
+
+func jsonField(data []byte, key string) (string, bool) {
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", false
+	}
+	value, ok := fields[key].(string)
+	return value, ok
+}
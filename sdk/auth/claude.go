@@ -84,6 +84,8 @@ func (a *ClaudeAuthenticator) Login(ctx context.Context, cfg *config.Config, opt
 	}
 	state = returnedState
 
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{AuthURL: authURL})
+
 	if !opts.NoBrowser {
 		fmt.Println("Opening browser for Claude authentication")
 		if !browser.IsAvailable() {
@@ -8,6 +8,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/codebuddy"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
 )
@@ -54,6 +55,7 @@ func (a CodeBuddyAuthenticator) Login(ctx context.Context, cfg *config.Config, o
 	}
 
 	fmt.Printf("\nPlease open the following URL in your browser to login:\n\n  %s\n\n", authState.AuthURL)
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{AuthURL: authState.AuthURL})
 	fmt.Println("Waiting for authorization...")
 
 	if !opts.NoBrowser {
@@ -87,6 +87,8 @@ func (a *CodexAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 		return nil, fmt.Errorf("codex authorization url generation failed: %w", err)
 	}
 
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{AuthURL: authURL})
+
 	if !opts.NoBrowser {
 		fmt.Println("Opening browser for Codex authentication")
 		if !browser.IsAvailable() {
@@ -16,6 +16,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/codex"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
@@ -87,6 +88,7 @@ func (a *CodexAuthenticator) loginWithDeviceFlow(ctx context.Context, cfg *confi
 	fmt.Println("Starting Codex device authentication...")
 	fmt.Printf("Codex device URL: %s\n", codexDeviceVerificationURL)
 	fmt.Printf("Codex device code: %s\n", deviceCode)
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{VerificationURL: codexDeviceVerificationURL, UserCode: deviceCode})
 
 	if !opts.NoBrowser {
 		if !browser.IsAvailable() {
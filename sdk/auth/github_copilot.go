@@ -8,6 +8,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/copilot"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
 )
@@ -52,6 +53,7 @@ func (a GitHubCopilotAuthenticator) Login(ctx context.Context, cfg *config.Confi
 	// Display the user code and verification URL
 	fmt.Printf("\nTo authenticate, please visit: %s\n", deviceCode.VerificationURI)
 	fmt.Printf("And enter the code: %s\n\n", deviceCode.UserCode)
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{VerificationURL: deviceCode.VerificationURI, UserCode: deviceCode.UserCode})
 
 	// Try to open the browser automatically
 	if !opts.NoBrowser {
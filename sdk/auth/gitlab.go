@@ -109,6 +109,8 @@ func (a *GitLabAuthenticator) loginOAuth(ctx context.Context, cfg *config.Config
 		return nil, err
 	}
 
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{AuthURL: authURL})
+
 	if !opts.NoBrowser {
 		fmt.Println("Opening browser for GitLab Duo authentication")
 		if !browser.IsAvailable() {
@@ -9,6 +9,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kimi"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
 )
@@ -63,6 +64,7 @@ func (a KimiAuthenticator) Login(ctx context.Context, cfg *config.Config, opts *
 	if deviceCode.UserCode != "" {
 		fmt.Printf("User code: %s\n\n", deviceCode.UserCode)
 	}
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{VerificationURL: verificationURL, UserCode: deviceCode.UserCode})
 
 	// Try to open the browser automatically
 	if !opts.NoBrowser {
@@ -273,7 +273,20 @@ func (a *KiroAuthenticator) ImportFromKiroIDE(ctx context.Context, cfg *config.C
 	if err != nil {
 		return nil, fmt.Errorf("failed to load Kiro IDE token: %w", err)
 	}
+	record := kiroIDEImportRecord(tokenData)
 
+	// Display the email if extracted
+	if tokenData.Email != "" {
+		fmt.Printf("\n✓ Imported Kiro token from IDE (Provider: %s, Account: %s)\n", tokenData.Provider, tokenData.Email)
+	} else {
+		fmt.Printf("\n✓ Imported Kiro token from IDE (Provider: %s)\n", tokenData.Provider)
+	}
+
+	return record, nil
+}
+
+// kiroIDEImportRecord builds the auth record for a token taken from the Kiro IDE cache.
+func kiroIDEImportRecord(tokenData *kiroauth.KiroTokenData) *coreauth.Auth {
 	// Parse expires_at
 	expiresAt, err := time.Parse(time.RFC3339, tokenData.ExpiresAt)
 	if err != nil {
@@ -328,15 +341,7 @@ func (a *KiroAuthenticator) ImportFromKiroIDE(ctx context.Context, cfg *config.C
 		// NextRefreshAfter: 20 minutes before expiry
 		NextRefreshAfter: expiresAt.Add(-20 * time.Minute),
 	}
-
-	// Display the email if extracted
-	if tokenData.Email != "" {
-		fmt.Printf("\n✓ Imported Kiro token from IDE (Provider: %s, Account: %s)\n", tokenData.Provider, tokenData.Email)
-	} else {
-		fmt.Printf("\n✓ Imported Kiro token from IDE (Provider: %s)\n", tokenData.Provider)
-	}
-
-	return record, nil
+	return record
 }
 
 // Refresh refreshes an expired Kiro token using AWS SSO OIDC.
@@ -27,6 +27,25 @@ func NewManager(store coreauth.Store, authenticators ...Authenticator) *Manager
 	return mgr
 }
 
+// DefaultAuthenticators returns one instance of every built-in authenticator.
+func DefaultAuthenticators() []Authenticator {
+	return []Authenticator{
+		NewGeminiAuthenticator(),
+		NewCodexAuthenticator(),
+		NewClaudeAuthenticator(),
+		NewQwenAuthenticator(),
+		NewIFlowAuthenticator(),
+		NewAntigravityAuthenticator(),
+		NewKimiAuthenticator(),
+		NewKiroAuthenticator(),
+		NewGitHubCopilotAuthenticator(),
+		NewKiloAuthenticator(),
+		NewGitLabAuthenticator(),
+		NewCodeBuddyAuthenticator(),
+		NewCursorAuthenticator(),
+	}
+}
+
 // Register adds or replaces an authenticator keyed by its provider identifier.
 func (m *Manager) Register(a Authenticator) {
 	if a == nil {
@@ -38,6 +57,15 @@ func (m *Manager) Register(a Authenticator) {
 	m.authenticators[a.Provider()] = a
 }
 
+// Authenticator returns the authenticator registered for provider.
+func (m *Manager) Authenticator(provider string) (Authenticator, bool) {
+	if m == nil {
+		return nil, false
+	}
+	a, ok := m.authenticators[provider]
+	return a, ok
+}
+
 // SetStore updates the token store used for persistence.
 func (m *Manager) SetStore(store coreauth.Store) {
 	m.store = store
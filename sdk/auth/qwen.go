@@ -10,6 +10,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
 	// legacy client removed
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
 )
@@ -49,6 +50,7 @@ func (a *QwenAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 	}
 
 	authURL := deviceFlow.VerificationURIComplete
+	misc.AnnounceLoginChallenge(ctx, misc.LoginChallenge{AuthURL: authURL})
 
 	if !opts.NoBrowser {
 		fmt.Println("Opening browser for Qwen authentication")
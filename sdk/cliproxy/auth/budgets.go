@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/notify"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// usageBudgetsMetadataKey stores the auth's share of each notification usage
+// budget next to its usage counters, so budgets survive restarts the same way caps do.
+const usageBudgetsMetadataKey = "usage_budgets"
+
+// budgetCounters returns a copy of the auth's share of each usage budget, keyed
+// by budget name.
+func (a *Auth) budgetCounters() map[string]UsageCounters {
+	if a == nil {
+		return nil
+	}
+	return maps.Clone(a.usageState().budgets)
+}
+
+func (a *Auth) setBudgetCounters(counters map[string]UsageCounters) {
+	if a.Metadata == nil {
+		a.Metadata = make(map[string]any)
+	}
+	// Metadata maps are shared between clones, so the value is replaced, never mutated.
+	value := make(map[string]any, len(counters))
+	for name, c := range counters {
+		value[name] = map[string]any{
+			"day":            c.Day,
+			"day_requests":   c.DayRequests,
+			"day_tokens":     c.DayTokens,
+			"month":          c.Month,
+			"month_requests": c.MonthRequests,
+			"month_tokens":   c.MonthTokens,
+		}
+	}
+	a.Metadata[usageBudgetsMetadataKey] = value
+	state := *a.usageState()
+	state.budgets = counters
+	a.usage = &state
+}
+
+// usageBudgets returns the budgets that count the record.
+func (m *Manager) usageBudgets(record usage.Record) []internalconfig.UsageBudget {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil {
+		return nil
+	}
+	var out []internalconfig.UsageBudget
+	for _, budget := range cfg.Notifications.UsageBudgets {
+		if budget.APIKey != "" && budget.APIKey != record.APIKey {
+			continue
+		}
+		if budget.Model != "" && !util.MatchWildcardFold(budget.Model, record.Model) {
+			continue
+		}
+		out = append(out, budget)
+	}
+	return out
+}
+
+// countBudgetsLocked adds a request to the auth's share of each budget and to the
+// budget totals, and returns an event for every budget that crossed a threshold.
+// Budget periods follow server local time. The caller holds m.mu.
+func (m *Manager) countBudgetsLocked(auth *Auth, budgets []internalconfig.UsageBudget, tokens int64, now time.Time) []notify.Event {
+	local := now.In(time.Local)
+	shares := auth.budgetCounters()
+	if shares == nil {
+		shares = make(map[string]UsageCounters, len(budgets))
+	}
+	if m.budgetTotals == nil {
+		m.budgetTotals = make(map[string]UsageCounters)
+	}
+	var events []notify.Event
+	for _, budget := range budgets {
+		total := m.budgetTotalLocked(budget.Name, local)
+		before := budgetShare(budget, total)
+		total = total.add(tokens)
+		m.budgetTotals[budget.Name] = total
+		shares[budget.Name] = shares[budget.Name].rollover(local).add(tokens)
+		if event, ok := budgetEvent(budget, total, before, budgetShare(budget, total)); ok {
+			events = append(events, event)
+		}
+	}
+	auth.setBudgetCounters(shares)
+	return events
+}
+
+// budgetTotalLocked returns the budget's counters for the current period, summing
+// the persisted shares of every auth the first time the budget is seen.
+func (m *Manager) budgetTotalLocked(name string, local time.Time) UsageCounters {
+	total, ok := m.budgetTotals[name]
+	if !ok {
+		total = UsageCounters{}.rollover(local)
+		for _, a := range m.auths {
+			share, found := a.budgetCounters()[name]
+			if !found {
+				continue
+			}
+			share = share.rollover(local)
+			total.DayRequests += share.DayRequests
+			total.DayTokens += share.DayTokens
+			total.MonthRequests += share.MonthRequests
+			total.MonthTokens += share.MonthTokens
+		}
+	}
+	return total.rollover(local)
+}
+
+// budgetShare returns how much of the budget is used, in percent of the larger
+// of its token and request allowances.
+func budgetShare(budget internalconfig.UsageBudget, c UsageCounters) float64 {
+	tokens, requests := c.DayTokens, c.DayRequests
+	if budget.Period == "month" {
+		tokens, requests = c.MonthTokens, c.MonthRequests
+	}
+	used := 0.0
+	if budget.MaxTokens > 0 {
+		used = float64(tokens) / float64(budget.MaxTokens) * 100
+	}
+	if budget.MaxRequests > 0 {
+		used = max(used, float64(requests)/float64(budget.MaxRequests)*100)
+	}
+	return used
+}
+
+// budgetEvent reports the highest threshold the budget crossed between the two
+// shares. Thresholds are derived from the persisted counters, so each fires once
+// per period even across restarts.
+func budgetEvent(budget internalconfig.UsageBudget, total UsageCounters, before, after float64) (notify.Event, bool) {
+	crossed := 0.0
+	for _, threshold := range budget.Thresholds {
+		if before < threshold && after >= threshold && threshold > crossed {
+			crossed = threshold
+		}
+	}
+	if crossed == 0 {
+		return notify.Event{}, false
+	}
+	period, tokens, requests := total.Day, total.DayTokens, total.DayRequests
+	if budget.Period == "month" {
+		period, tokens, requests = total.Month, total.MonthTokens, total.MonthRequests
+	}
+	severity := notify.SeverityWarning
+	if crossed >= 100 {
+		severity = notify.SeverityCritical
+	}
+	return notify.Event{
+		Type:     notify.EventUsageBudget,
+		Severity: severity,
+		Key:      fmt.Sprintf("%s|%s|%g", budget.Name, period, crossed),
+		Title:    fmt.Sprintf("Usage budget %s at %.0f%%", budget.Name, after),
+		Message:  fmt.Sprintf("Budget %s crossed %g%% for %s: %d tokens, %d requests.", budget.Name, crossed, period, tokens, requests),
+		Fields: map[string]string{
+			"budget":    budget.Name,
+			"period":    period,
+			"threshold": fmt.Sprintf("%g", crossed),
+			"tokens":    fmt.Sprint(tokens),
+			"requests":  fmt.Sprint(requests),
+		},
+	}, true
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/notify"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestUsageBudgetsPersistWithCredentialCounters(t *testing.T) {
+	cfg := &internalconfig.Config{Notifications: internalconfig.NotificationsConfig{
+		UsageBudgets: []internalconfig.UsageBudget{
+			{Name: "daily", MaxTokens: 1000, Thresholds: []float64{50, 100}},
+			{Name: "other-key", APIKey: "k2", MaxRequests: 1},
+		},
+	}}
+	cfg.SanitizeNotifications()
+	waitEvent := captureNotifications(t, cfg.Notifications)
+	ctx := context.Background()
+	store := &healthMemoryStore{items: make(map[string]*Auth)}
+	manager := NewManager(store, nil, nil)
+	manager.SetConfig(cfg)
+	for _, id := range []string{"a1", "a2"} {
+		if _, err := manager.Register(ctx, &Auth{ID: id, Provider: "claude", Metadata: map[string]any{"type": "claude"}}); err != nil {
+			t.Fatalf("Register(%s) error = %v", id, err)
+		}
+	}
+	record := func(m *Manager, authID string, tokens int64) {
+		m.HandleUsage(ctx, usage.Record{AuthID: authID, APIKey: "k1", Model: "m", Detail: usage.Detail{TotalTokens: tokens}})
+	}
+
+	// The budget spans every credential serving matching traffic.
+	record(manager, "a1", 400)
+	record(manager, "a2", 200)
+	event := waitEvent(notify.EventUsageBudget)
+	if fields, _ := event["fields"].(map[string]any); fields["budget"] != "daily" || fields["threshold"] != "50" || fields["tokens"] != "600" {
+		t.Fatalf("budget event fields = %v, want daily at 50%% with 600 tokens", event["fields"])
+	}
+	manager.StopUsageLimits()
+	if share := store.items["a1"].budgetCounters()["daily"]; share.DayTokens != 400 || share.DayRequests != 1 {
+		t.Fatalf("persisted share of a1 = %+v, want 1 request and 400 tokens", share)
+	}
+
+	// After a restart the budget resumes from the persisted shares: the 50%
+	// threshold does not fire again and 100% is reached at the combined total.
+	restarted := NewManager(store, nil, nil)
+	restarted.SetConfig(cfg)
+	if err := restarted.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	record(restarted, "a1", 100)
+	record(restarted, "a2", 300)
+	event = waitEvent(notify.EventUsageBudget)
+	if fields, _ := event["fields"].(map[string]any); fields["budget"] != "daily" || fields["threshold"] != "100" || fields["tokens"] != "1000" {
+		t.Fatalf("budget event fields = %v, want daily at 100%% with 1000 tokens", event["fields"])
+	}
+	if event["severity"] != string(notify.SeverityCritical) {
+		t.Fatalf("severity = %v, want critical", event["severity"])
+	}
+
+	// Shares of an earlier period do not count.
+	stale := map[string]UsageCounters{"daily": {Day: "2000-01-01", DayTokens: 999, Month: "2000-01", MonthTokens: 999}}
+	a1, _ := restarted.GetByID("a1")
+	a1.setBudgetCounters(stale)
+	fresh := NewManager(nil, nil, nil)
+	if _, err := fresh.Register(ctx, a1); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	fresh.mu.Lock()
+	total := fresh.budgetTotalLocked("daily", time.Now())
+	fresh.mu.Unlock()
+	if total.DayTokens != 0 || total.MonthTokens != 0 {
+		t.Fatalf("total = %+v, want nothing from an earlier period", total)
+	}
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// refreshLeaseTTL bounds how long a replica may hold the refresh lease for one auth.
+// It comfortably exceeds a single provider refresh round trip.
+const refreshLeaseTTL = 2 * time.Minute
+
+// RefreshLeaser coordinates token refreshes across replicas sharing one credential
+// pool so that rotating refresh tokens are only ever spent by a single node.
+type RefreshLeaser interface {
+	// AcquireRefreshLease reports whether this replica may refresh authID now.
+	AcquireRefreshLease(ctx context.Context, authID string, ttl time.Duration) (bool, error)
+	// ReleaseRefreshLease ends the lease once the refresh attempt has finished.
+	ReleaseRefreshLease(ctx context.Context, authID string) error
+}
+
+// StateReplicator shares the cooldown and quota state recorded by MarkResult with
+// other replicas and delivers their updates back to the Manager.
+type StateReplicator interface {
+	// PublishAuthState announces the local runtime state of authID.
+	PublishAuthState(ctx context.Context, authID string, state RuntimeState)
+	// SetStateHandler registers the callback invoked for state published by other replicas.
+	SetStateHandler(handler func(ctx context.Context, authID string, state RuntimeState))
+}
+
+// RuntimeState is the replicated subset of Auth describing execution health.
+type RuntimeState struct {
+	Status         Status                 `json:"status"`
+	StatusMessage  string                 `json:"status_message,omitempty"`
+	Unavailable    bool                   `json:"unavailable"`
+	NextRetryAfter time.Time              `json:"next_retry_after"`
+	Quota          QuotaState             `json:"quota"`
+	LastError      *Error                 `json:"last_error,omitempty"`
+	ModelStates    map[string]*ModelState `json:"model_states,omitempty"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+}
+
+// RuntimeState captures the replicated execution state of the auth.
+func (a *Auth) RuntimeState() RuntimeState {
+	if a == nil {
+		return RuntimeState{}
+	}
+	state := RuntimeState{
+		Status:         a.Status,
+		StatusMessage:  a.StatusMessage,
+		Unavailable:    a.Unavailable,
+		NextRetryAfter: a.NextRetryAfter,
+		Quota:          a.Quota,
+		LastError:      cloneError(a.LastError),
+		UpdatedAt:      a.UpdatedAt,
+	}
+	if len(a.ModelStates) > 0 {
+		state.ModelStates = make(map[string]*ModelState, len(a.ModelStates))
+		for model, ms := range a.ModelStates {
+			state.ModelStates[model] = ms.Clone()
+		}
+	}
+	return state
+}
+
+// SetRefreshLeaser installs the cluster-wide refresh lease. A nil leaser restores
+// single-node behaviour where every refresh proceeds immediately.
+func (m *Manager) SetRefreshLeaser(leaser RefreshLeaser) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.leaser = leaser
+	m.mu.Unlock()
+}
+
+// SetStateReplicator installs the replicator used to share runtime state and
+// subscribes the Manager to updates from other replicas.
+func (m *Manager) SetStateReplicator(replicator StateReplicator) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.replicator = replicator
+	m.mu.Unlock()
+	if replicator != nil {
+		replicator.SetStateHandler(m.ApplyRemoteState)
+	}
+}
+
+// ApplyRemoteState adopts runtime state published by another replica. Updates
+// older than the local state are ignored, and nothing is persisted or re-published.
+func (m *Manager) ApplyRemoteState(ctx context.Context, authID string, state RuntimeState) {
+	if m == nil || authID == "" {
+		return
+	}
+	var (
+		snapshot *Auth
+		previous map[string]*ModelState
+	)
+	m.mu.Lock()
+	if auth, ok := m.auths[authID]; ok && auth != nil && !state.UpdatedAt.Before(auth.UpdatedAt) {
+		previous = auth.ModelStates
+		auth.Status = state.Status
+		auth.StatusMessage = state.StatusMessage
+		auth.Unavailable = state.Unavailable
+		auth.NextRetryAfter = state.NextRetryAfter
+		auth.Quota = state.Quota
+		auth.LastError = cloneError(state.LastError)
+		auth.ModelStates = nil
+		if len(state.ModelStates) > 0 {
+			auth.ModelStates = make(map[string]*ModelState, len(state.ModelStates))
+			for model, ms := range state.ModelStates {
+				if ms != nil {
+					auth.ModelStates[model] = ms.Clone()
+				}
+			}
+		}
+		auth.UpdatedAt = state.UpdatedAt
+		snapshot = auth.Clone()
+	}
+	m.mu.Unlock()
+	if snapshot == nil {
+		return
+	}
+	if m.scheduler != nil {
+		m.scheduler.upsertAuth(snapshot)
+	}
+
+	now := time.Now()
+	reg := registry.GetGlobalRegistry()
+	for model, ms := range snapshot.ModelStates {
+		if ms.Unavailable && ms.NextRetryAfter.After(now) {
+			reason := strings.TrimSpace(ms.Quota.Reason)
+			if ms.Quota.Exceeded {
+				reg.SetModelQuotaExceeded(authID, model)
+			}
+			if reason == "" {
+				reason = "replicated"
+			}
+			reg.SuspendClientModel(authID, model, reason)
+			continue
+		}
+		reg.ClearModelQuotaExceeded(authID, model)
+		reg.ResumeClientModel(authID, model)
+	}
+	for model := range previous {
+		if _, ok := snapshot.ModelStates[model]; !ok {
+			reg.ClearModelQuotaExceeded(authID, model)
+			reg.ResumeClientModel(authID, model)
+		}
+	}
+	log.Debugf("applied replicated state for %s", authID)
+}
+
+func (m *Manager) publishState(ctx context.Context, auth *Auth) {
+	if auth == nil {
+		return
+	}
+	m.mu.RLock()
+	replicator := m.replicator
+	m.mu.RUnlock()
+	if replicator == nil {
+		return
+	}
+	replicator.PublishAuthState(ctx, auth.ID, auth.RuntimeState())
+}
+
+// acquireRefresh reports whether this replica should refresh id and returns the
+// function releasing the lease afterwards.
+func (m *Manager) acquireRefresh(ctx context.Context, id string) (bool, func()) {
+	m.mu.RLock()
+	leaser := m.leaser
+	m.mu.RUnlock()
+	if leaser == nil {
+		return true, func() {}
+	}
+	ok, err := leaser.AcquireRefreshLease(ctx, id, refreshLeaseTTL)
+	if err != nil {
+		log.Warnf("refresh lease unavailable for %s: %v", id, err)
+		return false, nil
+	}
+	if !ok {
+		return false, nil
+	}
+	return true, func() {
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if errRelease := leaser.ReleaseRefreshLease(releaseCtx, id); errRelease != nil {
+			log.Warnf("failed to release refresh lease for %s: %v", id, errRelease)
+		}
+	}
+}
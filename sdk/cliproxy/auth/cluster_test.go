@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingRefreshExecutor struct {
+	schedulerProviderTestExecutor
+	refreshes atomic.Int32
+}
+
+func (e *countingRefreshExecutor) Refresh(ctx context.Context, auth *Auth) (*Auth, error) {
+	e.refreshes.Add(1)
+	return auth, nil
+}
+
+type fakeLeaser struct {
+	grant    bool
+	acquired []string
+	released []string
+}
+
+func (l *fakeLeaser) AcquireRefreshLease(_ context.Context, authID string, _ time.Duration) (bool, error) {
+	l.acquired = append(l.acquired, authID)
+	return l.grant, nil
+}
+
+func (l *fakeLeaser) ReleaseRefreshLease(_ context.Context, authID string) error {
+	l.released = append(l.released, authID)
+	return nil
+}
+
+type fakeReplicator struct {
+	published map[string]RuntimeState
+	handler   func(ctx context.Context, authID string, state RuntimeState)
+}
+
+func (r *fakeReplicator) PublishAuthState(_ context.Context, authID string, state RuntimeState) {
+	if r.published == nil {
+		r.published = make(map[string]RuntimeState)
+	}
+	r.published[authID] = state
+}
+
+func (r *fakeReplicator) SetStateHandler(handler func(ctx context.Context, authID string, state RuntimeState)) {
+	r.handler = handler
+}
+
+func TestRefreshAuthHonoursClusterLease(t *testing.T) {
+	for _, grant := range []bool{false, true} {
+		exec := &countingRefreshExecutor{schedulerProviderTestExecutor: schedulerProviderTestExecutor{provider: "claude"}}
+		leaser := &fakeLeaser{grant: grant}
+		manager := NewManager(nil, nil, nil)
+		manager.RegisterExecutor(exec)
+		manager.SetRefreshLeaser(leaser)
+		if _, err := manager.Register(context.Background(), &Auth{ID: "a1", Provider: "claude", Metadata: map[string]any{"type": "claude"}}); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+
+		manager.refreshAuth(context.Background(), "a1")
+
+		wantRefreshes := int32(0)
+		if grant {
+			wantRefreshes = 1
+		}
+		if got := exec.refreshes.Load(); got != wantRefreshes {
+			t.Fatalf("grant=%v: refreshes = %d, want %d", grant, got, wantRefreshes)
+		}
+		if len(leaser.acquired) != 1 {
+			t.Fatalf("grant=%v: acquire calls = %v", grant, leaser.acquired)
+		}
+		if grant != (len(leaser.released) == 1) {
+			t.Fatalf("grant=%v: release calls = %v", grant, leaser.released)
+		}
+		current, _ := manager.GetByID("a1")
+		if !grant && !current.NextRefreshAfter.After(time.Now()) {
+			t.Fatalf("denied lease should defer the next refresh, got %v", current.NextRefreshAfter)
+		}
+	}
+}
+
+func TestMarkResultPublishesAndAppliesReplicatedState(t *testing.T) {
+	ctx := context.Background()
+	replicator := &fakeReplicator{}
+	manager := NewManager(nil, nil, nil)
+	manager.SetStateReplicator(replicator)
+	if replicator.handler == nil {
+		t.Fatal("SetStateReplicator() did not register the state handler")
+	}
+	if _, err := manager.Register(ctx, &Auth{ID: "a1", Provider: "claude"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	manager.MarkResult(ctx, Result{AuthID: "a1", Provider: "claude", Model: "m1", Error: &Error{HTTPStatus: 429, Message: "slow down"}})
+	state, ok := replicator.published["a1"]
+	if !ok {
+		t.Fatal("MarkResult() did not publish state")
+	}
+	if ms := state.ModelStates["m1"]; ms == nil || !ms.Quota.Exceeded || !ms.Unavailable {
+		t.Fatalf("published model state = %+v", ms)
+	}
+
+	// A second replica adopts the cooldown without persisting or republishing it.
+	peerReplicator := &fakeReplicator{}
+	peer := NewManager(nil, nil, nil)
+	peer.SetStateReplicator(peerReplicator)
+	if _, err := peer.Register(ctx, &Auth{ID: "a1", Provider: "claude"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	peerReplicator.handler(ctx, "a1", state)
+	got, _ := peer.GetByID("a1")
+	if ms := got.ModelStates["m1"]; ms == nil || !ms.Quota.Exceeded || got.Status != StatusError {
+		t.Fatalf("applied auth = %+v", got)
+	}
+	if len(peerReplicator.published) != 0 {
+		t.Fatalf("applied state was republished: %+v", peerReplicator.published)
+	}
+
+	stale := state
+	stale.ModelStates = nil
+	stale.Status = StatusActive
+	stale.UpdatedAt = state.UpdatedAt.Add(-time.Minute)
+	peer.ApplyRemoteState(ctx, "a1", stale)
+	if got, _ = peer.GetByID("a1"); got.ModelStates["m1"] == nil {
+		t.Fatal("older replicated state overwrote newer local state")
+	}
+}
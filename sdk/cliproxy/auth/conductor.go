@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
@@ -18,6 +19,7 @@ import (
 	"github.com/google/uuid"
 	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/notify"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
@@ -164,6 +166,26 @@ type Manager struct {
 	// Auto refresh state
 	refreshCancel    context.CancelFunc
 	refreshSemaphore chan struct{}
+
+	// Background quota polling state.
+	quotaCancel context.CancelFunc
+
+	// Background health check state.
+	healthCancel context.CancelFunc
+
+	// Usage limit state: the re-evaluation loop and auths whose counters await persisting.
+	limitsCancel context.CancelFunc
+	limitsDirty  map[string]struct{}
+	// budgetTotals sums the persisted per-auth budget counters, keyed by budget name.
+	budgetTotals map[string]UsageCounters
+
+	// Shadow traffic comparisons keyed by model and candidate provider.
+	shadowMu    sync.Mutex
+	shadowStats map[string]*ShadowStats
+
+	// Optional cluster coordination shared with other replicas.
+	leaser     RefreshLeaser
+	replicator StateReplicator
 }
 
 // NewManager constructs a manager with optional custom selector and hook.
@@ -587,37 +609,20 @@ func (m *Manager) availableAuthsForRouteModel(auths []*Auth, provider, routeMode
 	}
 
 	availableByPriority := make(map[int][]*Auth)
-	cooldownCount := 0
-	var earliest time.Time
+	var blocked blockSummary
 	for _, candidate := range auths {
 		checkModel := m.selectionModelForAuth(candidate, routeModel)
-		blocked, reason, next := isAuthBlockedForModel(candidate, checkModel, now)
-		if !blocked {
+		isBlocked, reason, next := isAuthBlockedForModel(candidate, checkModel, now)
+		if !isBlocked {
 			priority := authPriority(candidate)
 			availableByPriority[priority] = append(availableByPriority[priority], candidate)
 			continue
 		}
-		if reason == blockReasonCooldown {
-			cooldownCount++
-			if !next.IsZero() && (earliest.IsZero() || next.Before(earliest)) {
-				earliest = next
-			}
-		}
+		blocked.add(reason, next)
 	}
 
 	if len(availableByPriority) == 0 {
-		if cooldownCount == len(auths) && !earliest.IsZero() {
-			providerForError := provider
-			if providerForError == "mixed" {
-				providerForError = ""
-			}
-			resetIn := earliest.Sub(now)
-			if resetIn < 0 {
-				resetIn = 0
-			}
-			return nil, newModelCooldownError(routeModel, providerForError, resetIn)
-		}
-		return nil, &Error{Code: "auth_unavailable", Message: "no auth available"}
+		return nil, blocked.unavailableError(routeModel, provider, now)
 	}
 
 	bestPriority := 0
@@ -1080,6 +1085,8 @@ func (m *Manager) Register(ctx context.Context, auth *Auth) (*Auth, error) {
 		auth.ID = uuid.NewString()
 	}
 	auth.EnsureIndex()
+	applyQuarantineStatus(auth)
+	auth.cacheUsageState()
 	authClone := auth.Clone()
 	m.mu.Lock()
 	m.auths[auth.ID] = authClone
@@ -1098,6 +1105,7 @@ func (m *Manager) Update(ctx context.Context, auth *Auth) (*Auth, error) {
 	if auth == nil || auth.ID == "" {
 		return nil, nil
 	}
+	auth.cacheUsageState()
 	m.mu.Lock()
 	if existing, ok := m.auths[auth.ID]; ok && existing != nil {
 		if !auth.indexAssigned && auth.Index == "" {
@@ -1109,8 +1117,13 @@ func (m *Manager) Update(ctx context.Context, auth *Auth) (*Auth, error) {
 				auth.ModelStates = existing.ModelStates
 			}
 		}
+		if auth.Health.CheckedAt.IsZero() {
+			auth.Health = existing.Health
+		}
+		mergeUsageCounters(auth, existing)
 	}
 	auth.EnsureIndex()
+	applyQuarantineStatus(auth)
 	authClone := auth.Clone()
 	m.auths[auth.ID] = authClone
 	m.mu.Unlock()
@@ -1141,6 +1154,8 @@ func (m *Manager) Load(ctx context.Context) error {
 			continue
 		}
 		auth.EnsureIndex()
+		applyQuarantineStatus(auth)
+		auth.cacheUsageState()
 		m.auths[auth.ID] = auth.Clone()
 	}
 	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
@@ -1155,17 +1170,25 @@ func (m *Manager) Load(ctx context.Context) error {
 
 // Execute performs a non-streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
-func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+// A traffic route configured for the model picks the preferred provider by weight and
+// may mirror the request to a shadow provider.
+func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (_ cliproxyexecutor.Response, errPrimary error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
 
 	_, maxRetryCredentials, maxWait := m.retrySettings()
+	plan := m.planTraffic(normalized, req.Model, opts)
+	if plan.shadow != nil && !cliproxyexecutor.InternalRequest(ctx) {
+		sample := m.startShadow(ctx, plan.shadow, req, opts, false)
+		start := time.Now()
+		defer func() { sample.primaryDone(time.Since(start), errPrimary) }()
+	}
 
 	var lastErr error
 	for attempt := 0; ; attempt++ {
-		resp, errExec := m.executeMixedOnce(ctx, normalized, req, opts, maxRetryCredentials)
+		resp, errExec := m.executeMixedOnce(ctx, normalized, plan.preferred, req, opts, maxRetryCredentials)
 		if errExec == nil {
 			return resp, nil
 		}
@@ -1193,10 +1216,11 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 	}
 
 	_, maxRetryCredentials, maxWait := m.retrySettings()
+	plan := m.planTraffic(normalized, req.Model, opts)
 
 	var lastErr error
 	for attempt := 0; ; attempt++ {
-		resp, errExec := m.executeCountMixedOnce(ctx, normalized, req, opts, maxRetryCredentials)
+		resp, errExec := m.executeCountMixedOnce(ctx, normalized, plan.preferred, req, opts, maxRetryCredentials)
 		if errExec == nil {
 			return resp, nil
 		}
@@ -1217,18 +1241,34 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 
 // ExecuteStream performs a streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
-func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (*cliproxyexecutor.StreamResult, error) {
+func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (_ *cliproxyexecutor.StreamResult, errPrimary error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return nil, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
 
 	_, maxRetryCredentials, maxWait := m.retrySettings()
+	plan := m.planTraffic(normalized, req.Model, opts)
+	var sample *shadowSample
+	var start time.Time
+	if plan.shadow != nil && !cliproxyexecutor.InternalRequest(ctx) {
+		sample = m.startShadow(ctx, plan.shadow, req, opts, true)
+		start = time.Now()
+		// A stream that opened is recorded once its chunks are drained.
+		defer func() {
+			if errPrimary != nil {
+				sample.primaryDone(time.Since(start), errPrimary)
+			}
+		}()
+	}
 
 	var lastErr error
 	for attempt := 0; ; attempt++ {
-		result, errStream := m.executeStreamMixedOnce(ctx, normalized, req, opts, maxRetryCredentials)
+		result, errStream := m.executeStreamMixedOnce(ctx, normalized, plan.preferred, req, opts, maxRetryCredentials)
 		if errStream == nil {
+			if sample != nil {
+				return sample.observePrimaryStream(ctx, start, result), nil
+			}
 			return result, nil
 		}
 		lastErr = errStream
@@ -1246,7 +1286,7 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 	return nil, &Error{Code: "auth_not_found", Message: "no auth available"}
 }
 
-func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int) (cliproxyexecutor.Response, error) {
+func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, preferred string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int) (cliproxyexecutor.Response, error) {
 	if len(providers) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
@@ -1254,6 +1294,7 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 	opts = ensureRequestedModelMetadata(opts, routeModel)
 	tried := make(map[string]struct{})
 	attempted := make(map[string]struct{})
+	estimate := newPromptTokenEstimate(req, opts)
 	var lastErr error
 	for {
 		if maxRetryCredentials > 0 && len(attempted) >= maxRetryCredentials {
@@ -1262,7 +1303,7 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 			}
 			return cliproxyexecutor.Response{}, &Error{Code: "auth_not_found", Message: "no auth available"}
 		}
-		auth, executor, provider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+		auth, executor, provider, errPick := m.pickNextRouted(ctx, preferred, providers, routeModel, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				return cliproxyexecutor.Response{}, lastErr
@@ -1275,6 +1316,10 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 		publishSelectedAuthMetadata(opts.Metadata, auth.ID)
 
 		tried[auth.ID] = struct{}{}
+		if errAdmit := auth.admitTokens(estimate, time.Now()); errAdmit != nil {
+			lastErr = errAdmit
+			continue
+		}
 		execCtx := ctx
 		if rt := m.roundTripperFor(auth); rt != nil {
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
@@ -1324,7 +1369,7 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 	}
 }
 
-func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int) (cliproxyexecutor.Response, error) {
+func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string, preferred string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int) (cliproxyexecutor.Response, error) {
 	if len(providers) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
@@ -1340,7 +1385,7 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 			}
 			return cliproxyexecutor.Response{}, &Error{Code: "auth_not_found", Message: "no auth available"}
 		}
-		auth, executor, provider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+		auth, executor, provider, errPick := m.pickNextRouted(ctx, preferred, providers, routeModel, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				return cliproxyexecutor.Response{}, lastErr
@@ -1402,7 +1447,7 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 	}
 }
 
-func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int) (*cliproxyexecutor.StreamResult, error) {
+func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string, preferred string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int) (*cliproxyexecutor.StreamResult, error) {
 	if len(providers) == 0 {
 		return nil, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
@@ -1410,6 +1455,7 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 	opts = ensureRequestedModelMetadata(opts, routeModel)
 	tried := make(map[string]struct{})
 	attempted := make(map[string]struct{})
+	estimate := newPromptTokenEstimate(req, opts)
 	var lastErr error
 	for {
 		if maxRetryCredentials > 0 && len(attempted) >= maxRetryCredentials {
@@ -1422,7 +1468,7 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 			}
 			return nil, &Error{Code: "auth_not_found", Message: "no auth available"}
 		}
-		auth, executor, provider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+		auth, executor, provider, errPick := m.pickNextRouted(ctx, preferred, providers, routeModel, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				var bootstrapErr *streamBootstrapError
@@ -1439,6 +1485,10 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 		publishSelectedAuthMetadata(opts.Metadata, auth.ID)
 
 		tried[auth.ID] = struct{}{}
+		if errAdmit := auth.admitTokens(estimate, time.Now()); errAdmit != nil {
+			lastErr = errAdmit
+			continue
+		}
 		execCtx := ctx
 		if rt := m.roundTripperFor(auth); rt != nil {
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
@@ -1891,6 +1941,7 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	suspendReason := ""
 	clearModelQuota := false
 	setModelQuota := false
+	coolingDown := false
 	var authSnapshot *Auth
 
 	m.mu.Lock()
@@ -2004,6 +2055,7 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 					auth.Status = StatusError
 					auth.UpdatedAt = now
 					updateAggregatedAvailability(auth, now)
+					coolingDown = state.NextRetryAfter.After(now)
 				}
 			} else {
 				applyAuthFailureState(auth, result.Error, result.RetryAfter, now)
@@ -2029,6 +2081,15 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	} else if shouldSuspendModel {
 		registry.GetGlobalRegistry().SuspendClientModel(result.AuthID, result.Model, suspendReason)
 	}
+	m.publishState(ctx, authSnapshot)
+	if authSnapshot != nil && !result.Success && result.Error != nil && !isRequestScopedNotFoundResultError(result.Error) {
+		if status, reason := ClassifyHealthError(result.Error); status.Dead() {
+			notifyCredentialDead(authSnapshot, status, reason, "request")
+		}
+	}
+	if coolingDown {
+		m.notifyPoolCooldown(authSnapshot.Provider, result.Model)
+	}
 
 	m.hook.OnResult(ctx, result)
 }
@@ -2154,7 +2215,11 @@ func clearAggregatedAvailability(auth *Auth) {
 	}
 	auth.Unavailable = false
 	auth.NextRetryAfter = time.Time{}
-	auth.Quota = QuotaState{}
+	// Keep the probe reading; only the cooldown bookkeeping is reset.
+	auth.Quota.Exceeded = false
+	auth.Quota.Reason = ""
+	auth.Quota.NextRecoverAt = time.Time{}
+	auth.Quota.BackoffLevel = 0
 }
 
 func hasModelError(auth *Auth, now time.Time) bool {
@@ -2516,7 +2581,11 @@ func shouldRetrySchedulerPick(err error) bool {
 	if !errors.As(err, &authErr) || authErr == nil {
 		return false
 	}
-	return authErr.Code == "auth_not_found" || authErr.Code == "auth_unavailable"
+	switch authErr.Code {
+	case "auth_not_found", "auth_unavailable", "auth_outside_schedule", "auth_usage_cap_reached", "auth_usage_limited":
+		return true
+	}
+	return false
 }
 
 func (m *Manager) routeAwareSelectionRequired(auth *Auth, routeModel string) bool {
@@ -2528,6 +2597,7 @@ func (m *Manager) routeAwareSelectionRequired(auth *Auth, routeModel string) boo
 
 func (m *Manager) pickNextLegacy(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, error) {
 	pinnedAuthID := pinnedAuthIDFromMetadata(opts.Metadata)
+	visibleToTenant := tenantFilterFromMetadata(opts.Metadata)
 
 	m.mu.RLock()
 	executor, okExecutor := m.executors[provider]
@@ -2552,6 +2622,9 @@ func (m *Manager) pickNextLegacy(ctx context.Context, provider, model string, op
 		if pinnedAuthID != "" && candidate.ID != pinnedAuthID {
 			continue
 		}
+		if visibleToTenant != nil && !visibleToTenant(candidate) {
+			continue
+		}
 		if _, used := tried[candidate.ID]; used {
 			continue
 		}
@@ -2640,6 +2713,7 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 
 func (m *Manager) pickNextMixedLegacy(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
 	pinnedAuthID := pinnedAuthIDFromMetadata(opts.Metadata)
+	visibleToTenant := tenantFilterFromMetadata(opts.Metadata)
 
 	providerSet := make(map[string]struct{}, len(providers))
 	for _, provider := range providers {
@@ -2671,6 +2745,9 @@ func (m *Manager) pickNextMixedLegacy(ctx context.Context, providers []string, m
 		if pinnedAuthID != "" && candidate.ID != pinnedAuthID {
 			continue
 		}
+		if visibleToTenant != nil && !visibleToTenant(candidate) {
+			continue
+		}
 		providerKey := strings.TrimSpace(strings.ToLower(candidate.Provider))
 		if providerKey == "" {
 			continue
@@ -3138,6 +3215,17 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	if auth == nil || exec == nil {
 		return
 	}
+	acquired, release := m.acquireRefresh(ctx, id)
+	if !acquired {
+		// Another replica owns this refresh; its result arrives through the shared store.
+		m.mu.Lock()
+		if current := m.auths[id]; current != nil {
+			current.NextRefreshAfter = time.Now().Add(refreshPendingBackoff)
+		}
+		m.mu.Unlock()
+		return
+	}
+	defer release()
 	cloned := auth.Clone()
 	updated, err := exec.Refresh(ctx, cloned)
 	if err != nil && errors.Is(err, context.Canceled) {
@@ -3157,6 +3245,13 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 			}
 		}
 		m.mu.Unlock()
+		notify.Emit(notify.Event{
+			Type:    notify.EventRefreshFailed,
+			Key:     id,
+			Title:   fmt.Sprintf("Token refresh failed for %s credential %s", auth.Provider, auth.ID),
+			Message: err.Error(),
+			Fields:  map[string]string{"auth_id": auth.ID, "auth_index": auth.Index, "provider": auth.Provider},
+		})
 		return
 	}
 	if updated == nil {
@@ -0,0 +1,513 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/notify"
+	log "github.com/sirupsen/logrus"
+)
+
+// HealthStatus classifies the outcome of a credential health check.
+type HealthStatus string
+
+const (
+	// HealthHealthy means the credential answered an authenticated call.
+	HealthHealthy HealthStatus = "healthy"
+	// HealthNeedsRelogin means the provider rejected the credential itself, e.g. a
+	// revoked refresh token or an expired SSO session.
+	HealthNeedsRelogin HealthStatus = "needs-relogin"
+	// HealthSuspended means the provider accepted the credential but refuses the account.
+	HealthSuspended HealthStatus = "suspended"
+	// HealthUnknown covers transient failures that say nothing about the credential.
+	HealthUnknown HealthStatus = "unknown"
+)
+
+// Dead reports whether the status means the credential cannot serve requests.
+func (s HealthStatus) Dead() bool {
+	return s == HealthNeedsRelogin || s == HealthSuspended
+}
+
+// HealthState holds the latest health check result of an auth.
+type HealthState struct {
+	Status HealthStatus `json:"status,omitempty"`
+	// Reason is the provider error behind a non-healthy status.
+	Reason string `json:"reason,omitempty"`
+	// CheckedAt records the last check; zero means the credential was never checked.
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+	// Failures counts consecutive dead results.
+	Failures int `json:"failures,omitempty"`
+}
+
+// HealthProbe is implemented by executors that can verify a credential with a
+// cheap authenticated call that uses the current access token and never
+// refreshes it. The health checker relies on it between refreshes.
+type HealthProbe interface {
+	ProbeHealth(ctx context.Context, auth *Auth) error
+}
+
+// quarantineMetadataKey stores the quarantine record in auth metadata so it is
+// written with the credential and survives restarts.
+const quarantineMetadataKey = "quarantine"
+
+// Quarantine describes why a credential was taken out of scheduling.
+type Quarantine struct {
+	Status HealthStatus `json:"status"`
+	Reason string       `json:"reason,omitempty"`
+	Since  time.Time    `json:"since"`
+}
+
+// Quarantine returns the credential's quarantine record, if it is quarantined.
+func (a *Auth) Quarantine() (Quarantine, bool) {
+	if a == nil || a.Metadata == nil {
+		return Quarantine{}, false
+	}
+	raw, ok := a.Metadata[quarantineMetadataKey].(map[string]any)
+	if !ok {
+		return Quarantine{}, false
+	}
+	status, _ := raw["status"].(string)
+	if status == "" {
+		return Quarantine{}, false
+	}
+	q := Quarantine{Status: HealthStatus(status)}
+	q.Reason, _ = raw["reason"].(string)
+	if since, _ := raw["since"].(string); since != "" {
+		q.Since, _ = time.Parse(time.RFC3339, since)
+	}
+	return q, true
+}
+
+func (a *Auth) setQuarantine(q Quarantine) {
+	if a.Metadata == nil {
+		a.Metadata = make(map[string]any)
+	}
+	a.Metadata[quarantineMetadataKey] = map[string]any{
+		"status": string(q.Status),
+		"reason": q.Reason,
+		"since":  q.Since.UTC().Format(time.RFC3339),
+	}
+	a.Status = StatusError
+	a.StatusMessage = quarantineStatusMessage(q)
+}
+
+func (a *Auth) clearQuarantine() {
+	delete(a.Metadata, quarantineMetadataKey)
+	if a.Status == StatusError && strings.HasPrefix(a.StatusMessage, "quarantined") {
+		a.Status = StatusActive
+		a.StatusMessage = ""
+	}
+}
+
+func quarantineStatusMessage(q Quarantine) string {
+	if q.Reason == "" {
+		return "quarantined: " + string(q.Status)
+	}
+	return "quarantined: " + string(q.Status) + ": " + q.Reason
+}
+
+// applyQuarantineStatus restores the status of a quarantined auth loaded from
+// the store, whose status is otherwise derived from the disabled flag alone.
+func applyQuarantineStatus(auth *Auth) {
+	if auth == nil || auth.Disabled || auth.Status == StatusDisabled {
+		return
+	}
+	if q, ok := auth.Quarantine(); ok {
+		auth.Status = StatusError
+		auth.StatusMessage = quarantineStatusMessage(q)
+	}
+}
+
+const (
+	healthCheckTick        = time.Minute
+	healthCheckConcurrency = 4
+	healthCheckTimeout     = 60 * time.Second
+	healthReasonMaxLen     = 300
+)
+
+// StartHealthChecks launches a background loop that health-checks OAuth
+// credentials. The loop follows the health-checks config at every tick, so
+// enabling or tuning it needs no restart.
+func (m *Manager) StartHealthChecks(parent context.Context) {
+	if m.healthCancel != nil {
+		m.healthCancel()
+		m.healthCancel = nil
+	}
+	ctx, cancel := context.WithCancel(parent)
+	m.healthCancel = cancel
+	go func() {
+		ticker := time.NewTicker(healthCheckTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if m.healthChecksConfig().Enabled {
+					m.CheckHealth(ctx, false)
+				}
+			}
+		}
+	}()
+}
+
+// StopHealthChecks cancels the background health check loop, if running.
+func (m *Manager) StopHealthChecks() {
+	if m.healthCancel != nil {
+		m.healthCancel()
+		m.healthCancel = nil
+	}
+}
+
+// CheckHealth checks every OAuth credential whose last check is older than the
+// configured interval, or all of them when force is set, and waits for the
+// checks to finish. Quarantined credentials are checked too so they can recover.
+func (m *Manager) CheckHealth(ctx context.Context, force bool) {
+	cfg := m.healthChecksConfig()
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	now := time.Now()
+	sem := make(chan struct{}, healthCheckConcurrency)
+	var wg sync.WaitGroup
+	for _, a := range m.snapshotAuths() {
+		if a.Disabled || a.Status == StatusDisabled || a.Metadata == nil {
+			continue
+		}
+		if accountType, _ := a.AccountInfo(); accountType == "api_key" {
+			continue
+		}
+		if !force && !a.Health.CheckedAt.IsZero() && now.Sub(a.Health.CheckedAt) < interval {
+			continue
+		}
+		exec, ok := m.Executor(executorKeyFromAuth(a))
+		if !ok {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(a *Auth) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			status, reason, ok := m.probeHealth(checkCtx, exec, a)
+			if !ok {
+				return
+			}
+			m.applyHealthResult(ctx, a.ID, status, reason, time.Now())
+		}(a)
+	}
+	wg.Wait()
+}
+
+// probeHealth checks one credential without spending its refresh token: it only
+// refreshes when the token is due anyway, since providers with single-use
+// refresh tokens reject a token that was rotated behind the caller's back. The
+// check itself is the executor's read-only probe. ok is false when the check
+// was skipped, including for executors without a probe whose token is not due.
+func (m *Manager) probeHealth(ctx context.Context, exec ProviderExecutor, auth *Auth) (HealthStatus, string, bool) {
+	probe, canProbe := exec.(HealthProbe)
+	current := auth
+	if m.shouldRefresh(auth, time.Now()) {
+		acquired, release := m.acquireRefresh(ctx, auth.ID)
+		if !acquired {
+			// Another replica is refreshing this credential; check it next round.
+			return "", "", false
+		}
+		updated, err := exec.Refresh(ctx, auth.Clone())
+		release()
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return "", "", false
+			}
+			status, reason := ClassifyHealthError(err)
+			return status, reason, true
+		}
+		if updated != nil {
+			if updated.Runtime == nil {
+				updated.Runtime = auth.Runtime
+			}
+			now := time.Now()
+			updated.LastRefreshedAt = now
+			updated.LastError = nil
+			updated.UpdatedAt = now
+			_, _ = m.Update(ctx, updated)
+			current = updated
+		}
+	} else if !canProbe {
+		return "", "", false
+	}
+	if canProbe {
+		if err := probe.ProbeHealth(ctx, current.Clone()); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return "", "", false
+			}
+			status, reason := ClassifyHealthError(err)
+			return status, reason, true
+		}
+	}
+	return HealthHealthy, "", true
+}
+
+var (
+	healthStatusCodePattern = regexp.MustCompile(`status(?: code)?[ :=(]*(\d{3})\b`)
+	transientMarkers        = []string{
+		"dial tcp",
+		"no such host",
+		"connection refused",
+		"connection reset",
+		"i/o timeout",
+		"tls handshake",
+		"unexpected eof",
+	}
+	needsReloginMarkers = []string{
+		"invalid_grant",
+		"invalid_token",
+		"invalid_client",
+		"unauthorized_client",
+		"expired_token",
+		"invalidgrantexception",
+		"unauthorizedexception",
+		"token has been expired or revoked",
+		"refresh token is invalid",
+		"refresh token has expired",
+		"refresh_token_reused",
+		"missing refresh token",
+		"session has expired",
+		"bad credentials",
+	}
+	suspendedMarkers = []string{
+		"suspended",
+		"banned",
+		"account has been disabled",
+		"account_disabled",
+		"account is disabled",
+		"organization has been disabled",
+		"terms of service",
+	}
+)
+
+// ClassifyHealthError maps a refresh or probe error to a health status and a
+// short reason. Errors without a clear credential signal are HealthUnknown.
+func ClassifyHealthError(err error) (HealthStatus, string) {
+	if err == nil {
+		return HealthHealthy, ""
+	}
+	reason := strings.TrimSpace(err.Error())
+	if len(reason) > healthReasonMaxLen {
+		reason = reason[:healthReasonMaxLen] + "..."
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return HealthUnknown, reason
+	}
+	lower := strings.ToLower(err.Error())
+	for _, marker := range transientMarkers {
+		if strings.Contains(lower, marker) {
+			return HealthUnknown, reason
+		}
+	}
+	code := statusCodeFromError(err)
+	if code == 0 {
+		code = statusCodeFromMessage(lower)
+	}
+	if code == http.StatusTooManyRequests || code >= http.StatusInternalServerError {
+		return HealthUnknown, reason
+	}
+	for _, marker := range suspendedMarkers {
+		if strings.Contains(lower, marker) {
+			return HealthSuspended, reason
+		}
+	}
+	for _, marker := range needsReloginMarkers {
+		if strings.Contains(lower, marker) {
+			return HealthNeedsRelogin, reason
+		}
+	}
+	if code == http.StatusUnauthorized {
+		return HealthNeedsRelogin, reason
+	}
+	return HealthUnknown, reason
+}
+
+// statusCodeFromMessage recovers the HTTP status from errors that only carry it
+// in their text, such as "token refresh failed with status 401".
+func statusCodeFromMessage(lower string) int {
+	match := healthStatusCodePattern.FindStringSubmatch(lower)
+	if len(match) < 2 {
+		return 0
+	}
+	code, _ := strconv.Atoi(match[1])
+	return code
+}
+
+// healthEvent is a health transition worth notifying about.
+type healthEvent int
+
+const (
+	eventUnhealthy healthEvent = iota + 1
+	eventQuarantined
+	eventRecovered
+)
+
+// applyHealthResult records a check on the auth, quarantines or releases it
+// when the outcome crosses the configured threshold, and notifies on transitions.
+func (m *Manager) applyHealthResult(ctx context.Context, id string, status HealthStatus, reason string, now time.Time) {
+	cfg := m.healthChecksConfig()
+	m.mu.Lock()
+	current := m.auths[id]
+	if current == nil {
+		m.mu.Unlock()
+		return
+	}
+	current.Health.Status = status
+	current.Health.Reason = reason
+	current.Health.CheckedAt = now
+	var event healthEvent
+	persist := false
+	_, quarantined := current.Quarantine()
+	switch {
+	case status.Dead():
+		current.Health.Failures++
+		if current.Health.Failures == cfg.FailureThreshold {
+			event = eventUnhealthy
+		}
+		if current.Health.Failures >= cfg.FailureThreshold && !cfg.ReportOnly && !quarantined {
+			current.setQuarantine(Quarantine{Status: status, Reason: reason, Since: now})
+			event = eventQuarantined
+			persist = true
+			log.Warnf("quarantined %s credential %s: %s", current.Provider, current.ID, current.StatusMessage)
+		}
+	case status == HealthHealthy:
+		current.Health.Failures = 0
+		current.Health.Reason = ""
+		if quarantined {
+			current.clearQuarantine()
+			event = eventRecovered
+			persist = true
+			log.Infof("released %s credential %s from quarantine", current.Provider, current.ID)
+		}
+	}
+	snapshot := current.Clone()
+	m.mu.Unlock()
+	if m.scheduler != nil {
+		m.scheduler.upsertAuth(snapshot)
+	}
+	if persist {
+		if err := m.persist(ctx, snapshot); err != nil {
+			log.Warnf("failed to persist quarantine state for %s: %v", id, err)
+		}
+	}
+	switch event {
+	case eventUnhealthy, eventQuarantined:
+		notifyCredentialDead(snapshot, snapshot.Health.Status, snapshot.Health.Reason, "health-check")
+	case eventRecovered:
+		notifyCredentialRecovered(snapshot)
+	}
+}
+
+// notifyCredentialDead reports a credential the provider rejected, either in a
+// health check or while serving a request; source names which.
+func notifyCredentialDead(auth *Auth, status HealthStatus, reason, source string) {
+	eventType := notify.EventCredentialNeedsRelogin
+	title := "Credential needs re-login"
+	if status == HealthSuspended {
+		eventType = notify.EventCredentialDisabled
+		title = "Credential suspended by provider"
+	}
+	_, account := auth.AccountInfo()
+	if account == "" {
+		account = auth.Label
+	}
+	_, quarantined := auth.Quarantine()
+	notify.Emit(notify.Event{
+		Type:     eventType,
+		Severity: notify.SeverityCritical,
+		Key:      auth.ID,
+		Title:    fmt.Sprintf("%s: %s %s", title, auth.Provider, account),
+		Message:  reason,
+		Fields: map[string]string{
+			"auth_id":     auth.ID,
+			"auth_index":  auth.Index,
+			"provider":    auth.Provider,
+			"account":     account,
+			"health":      string(status),
+			"quarantined": fmt.Sprint(quarantined),
+			"source":      source,
+		},
+	})
+}
+
+func notifyCredentialRecovered(auth *Auth) {
+	_, account := auth.AccountInfo()
+	if account == "" {
+		account = auth.Label
+	}
+	notify.Emit(notify.Event{
+		Type:     notify.EventCredentialRecovered,
+		Severity: notify.SeverityInfo,
+		Key:      auth.ID,
+		Title:    fmt.Sprintf("Credential recovered: %s %s", auth.Provider, account),
+		Message:  "The credential passed a health check and was released from quarantine.",
+		Fields: map[string]string{
+			"auth_id":    auth.ID,
+			"auth_index": auth.Index,
+			"provider":   auth.Provider,
+			"account":    account,
+			"health":     string(auth.Health.Status),
+		},
+	})
+}
+
+// ReleaseQuarantine puts a quarantined credential back into scheduling.
+func (m *Manager) ReleaseQuarantine(ctx context.Context, id string) (*Auth, error) {
+	m.mu.Lock()
+	current := m.auths[id]
+	if current == nil {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("auth %s not found", id)
+	}
+	if _, ok := current.Quarantine(); !ok {
+		m.mu.Unlock()
+		return current.Clone(), nil
+	}
+	current.clearQuarantine()
+	current.Health.Failures = 0
+	snapshot := current.Clone()
+	m.mu.Unlock()
+	if m.scheduler != nil {
+		m.scheduler.upsertAuth(snapshot)
+	}
+	if err := m.persist(ctx, snapshot); err != nil {
+		return snapshot, err
+	}
+	return snapshot, nil
+}
+
+func (m *Manager) healthChecksConfig() internalconfig.HealthChecksConfig {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	var h internalconfig.HealthChecksConfig
+	if cfg != nil {
+		h = cfg.HealthChecks
+	}
+	if h.IntervalSeconds <= 0 {
+		h.IntervalSeconds = internalconfig.DefaultHealthCheckIntervalSeconds
+	}
+	if h.FailureThreshold <= 0 {
+		h.FailureThreshold = internalconfig.DefaultHealthCheckFailureThreshold
+	}
+	return h
+}
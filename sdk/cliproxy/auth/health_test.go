@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/notify"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+)
+
+type healthTestExecutor struct {
+	schedulerProviderTestExecutor
+	mu        sync.Mutex
+	errors    map[string]error
+	refreshed []string
+}
+
+func (e *healthTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.refreshed = append(e.refreshed, auth.ID)
+	auth.Metadata["expired"] = time.Now().Add(2 * time.Hour).Format(time.RFC3339)
+	return auth, nil
+}
+
+func (e *healthTestExecutor) ProbeHealth(_ context.Context, auth *Auth) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.errors[auth.ID]
+}
+
+func (e *healthTestExecutor) refreshedIDs() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.refreshed...)
+}
+
+func (e *healthTestExecutor) setError(id string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errors[id] = err
+}
+
+type healthMemoryStore struct {
+	mu    sync.Mutex
+	items map[string]*Auth
+}
+
+func (s *healthMemoryStore) List(context.Context) ([]*Auth, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*Auth, 0, len(s.items))
+	for _, item := range s.items {
+		// Mirror the file store, which derives status from the disabled flag only.
+		loaded := &Auth{ID: item.ID, Provider: item.Provider, Status: StatusActive, Metadata: item.Clone().Metadata}
+		out = append(out, loaded)
+	}
+	return out, nil
+}
+
+func (s *healthMemoryStore) Save(_ context.Context, auth *Auth) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[auth.ID] = auth.Clone()
+	return auth.ID, nil
+}
+
+func (s *healthMemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, id)
+	return nil
+}
+
+// captureNotifications points the default notifier at a local webhook sink and
+// returns a function that waits for the next delivered event and checks its type.
+func captureNotifications(t *testing.T, cfg internalconfig.NotificationsConfig) func(wantType string) map[string]any {
+	t.Helper()
+	events := make(chan map[string]any, 8)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		events <- payload
+	}))
+	t.Cleanup(webhook.Close)
+	cfg.Enabled = true
+	cfg.Sinks = []internalconfig.NotificationSink{{Name: "hook", Type: internalconfig.NotificationSinkWebhook, URL: webhook.URL}}
+	notify.SetConfig(cfg)
+	t.Cleanup(func() { notify.SetConfig(internalconfig.NotificationsConfig{}) })
+	return func(wantType string) map[string]any {
+		t.Helper()
+		select {
+		case event := <-events:
+			if event["type"] != wantType {
+				t.Fatalf("notification = %v, want %s", event, wantType)
+			}
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s notification", wantType)
+			return nil
+		}
+	}
+}
+
+func TestClassifyHealthError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want HealthStatus
+	}{
+		{nil, HealthHealthy},
+		{errors.New(`token refresh failed with status 400: {"error":"invalid_grant","error_description":"refresh token revoked"}`), HealthNeedsRelogin},
+		{&Error{HTTPStatus: http.StatusUnauthorized, Message: "unauthorized"}, HealthNeedsRelogin},
+		{errors.New("API error (status 403): AccessDeniedException: account TEMPORARILY_SUSPENDED"), HealthSuspended},
+		{errors.New("API error (status 403): forbidden"), HealthUnknown},
+		{errors.New(`github-copilot token validation failed: Post "https://api.github.com": dial tcp: i/o timeout`), HealthUnknown},
+		{&Error{HTTPStatus: http.StatusTooManyRequests, Message: "rate limited"}, HealthUnknown},
+		{fmt.Errorf("refresh: %w", context.DeadlineExceeded), HealthUnknown},
+	}
+	for _, tc := range cases {
+		got, _ := ClassifyHealthError(tc.err)
+		if got != tc.want {
+			t.Errorf("ClassifyHealthError(%v) = %s, want %s", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestCheckHealthQuarantinesDeadCredentialsAcrossRestarts(t *testing.T) {
+	waitEvent := captureNotifications(t, internalconfig.NotificationsConfig{})
+
+	cfg := &internalconfig.Config{HealthChecks: internalconfig.HealthChecksConfig{
+		Enabled: true, IntervalSeconds: 3600, FailureThreshold: 2,
+	}}
+	exec := &healthTestExecutor{
+		schedulerProviderTestExecutor: schedulerProviderTestExecutor{provider: "claude"},
+		errors: map[string]error{
+			"revoked": errors.New(`claude quota: status 401: {"error":"invalid_token"}`),
+			"flaky":   errors.New("dial tcp: connection refused"),
+		},
+	}
+	store := &healthMemoryStore{items: make(map[string]*Auth)}
+	manager := NewManager(store, nil, nil)
+	manager.SetConfig(cfg)
+	manager.RegisterExecutor(exec)
+	for _, id := range []string{"revoked", "flaky", "good"} {
+		if _, err := manager.Register(context.Background(), &Auth{ID: id, Provider: "claude", Status: StatusActive, Metadata: map[string]any{"type": "claude"}}); err != nil {
+			t.Fatalf("Register(%s) error = %v", id, err)
+		}
+	}
+	due := &Auth{ID: "due", Provider: "claude", Status: StatusActive, Metadata: map[string]any{
+		"type":                     "claude",
+		"refresh_interval_seconds": 3600,
+		"expired":                  time.Now().Add(-time.Minute).Format(time.RFC3339),
+	}}
+	if _, err := manager.Register(context.Background(), due); err != nil {
+		t.Fatalf("Register(due) error = %v", err)
+	}
+
+	manager.CheckHealth(context.Background(), true)
+	if got := exec.refreshedIDs(); len(got) != 1 || got[0] != "due" {
+		t.Fatalf("refreshed %v, want only the credential whose token is due", got)
+	}
+	revoked, _ := manager.GetByID("revoked")
+	if revoked.Health.Status != HealthNeedsRelogin || revoked.Health.Failures != 1 {
+		t.Fatalf("revoked health = %+v, want one needs-relogin failure", revoked.Health)
+	}
+	if _, quarantined := revoked.Quarantine(); quarantined {
+		t.Fatal("credential quarantined before reaching the failure threshold")
+	}
+
+	manager.CheckHealth(context.Background(), true)
+	revoked, _ = manager.GetByID("revoked")
+	if _, quarantined := revoked.Quarantine(); !quarantined || revoked.Status != StatusError {
+		t.Fatalf("revoked status=%s message=%q, want quarantined", revoked.Status, revoked.StatusMessage)
+	}
+	if blocked, reason, _ := isAuthBlockedForModel(revoked, "", time.Now()); !blocked || reason != blockReasonDisabled {
+		t.Fatalf("quarantined credential blocked=%v reason=%v, want disabled", blocked, reason)
+	}
+	flaky, _ := manager.GetByID("flaky")
+	if flaky.Health.Status != HealthUnknown || flaky.Health.Failures != 0 {
+		t.Fatalf("flaky health = %+v, want unknown without failures", flaky.Health)
+	}
+	good, _ := manager.GetByID("good")
+	if good.Health.Status != HealthHealthy {
+		t.Fatalf("good health = %+v, want healthy", good.Health)
+	}
+	if got := exec.refreshedIDs(); len(got) != 1 {
+		t.Fatalf("refreshed %v after the second check, want no further refreshes", got)
+	}
+	event := waitEvent(notify.EventCredentialNeedsRelogin)
+	if fields, _ := event["fields"].(map[string]any); fields["auth_id"] != "revoked" || fields["quarantined"] != "true" {
+		t.Fatalf("notification fields = %v", event["fields"])
+	}
+
+	restarted := NewManager(store, nil, nil)
+	restarted.SetConfig(cfg)
+	restarted.RegisterExecutor(exec)
+	if err := restarted.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	reloaded, _ := restarted.GetByID("revoked")
+	if q, quarantined := reloaded.Quarantine(); !quarantined || q.Status != HealthNeedsRelogin || reloaded.Status != StatusError {
+		t.Fatalf("reloaded status=%s quarantine=%+v, want quarantine restored", reloaded.Status, q)
+	}
+
+	exec.setError("revoked", nil)
+	restarted.CheckHealth(context.Background(), true)
+	reloaded, _ = restarted.GetByID("revoked")
+	if _, quarantined := reloaded.Quarantine(); quarantined || reloaded.Status != StatusActive {
+		t.Fatalf("recovered status=%s message=%q, want released", reloaded.Status, reloaded.StatusMessage)
+	}
+	if _, quarantined := store.items["revoked"].Quarantine(); quarantined {
+		t.Fatal("release was not persisted")
+	}
+	waitEvent(notify.EventCredentialRecovered)
+}
+
+func TestMarkResultNotifiesCredentialsRejectedOnRequests(t *testing.T) {
+	waitEvent := captureNotifications(t, internalconfig.NotificationsConfig{})
+	manager := NewManager(nil, nil, nil)
+	ctx := context.Background()
+	for _, id := range []string{"limited", "revoked"} {
+		if _, err := manager.Register(ctx, &Auth{ID: id, Provider: "codex", Status: StatusActive}); err != nil {
+			t.Fatalf("Register(%s) error = %v", id, err)
+		}
+	}
+
+	manager.MarkResult(ctx, Result{AuthID: "limited", Model: "m", Error: &Error{HTTPStatus: http.StatusTooManyRequests, Message: "rate limited"}})
+	manager.MarkResult(ctx, Result{AuthID: "revoked", Model: "m", Error: &Error{HTTPStatus: http.StatusUnauthorized, Message: "token revoked"}})
+
+	event := waitEvent(notify.EventCredentialNeedsRelogin)
+	if fields, _ := event["fields"].(map[string]any); fields["auth_id"] != "revoked" || fields["source"] != "request" {
+		t.Fatalf("notification fields = %v, want the revoked credential from a request", event["fields"])
+	}
+}
+
+func TestMarkResultNotifiesOnlyWhenWholePoolCoolsDown(t *testing.T) {
+	waitEvent := captureNotifications(t, internalconfig.NotificationsConfig{})
+	manager := NewManager(nil, nil, nil)
+	ctx := context.Background()
+	reg := registry.GetGlobalRegistry()
+	for _, id := range []string{"pool-a", "pool-b"} {
+		reg.RegisterClient(id, "claude", []*registry.ModelInfo{{ID: "pool-model"}})
+		t.Cleanup(func() { reg.UnregisterClient(id) })
+		if _, err := manager.Register(ctx, &Auth{ID: id, Provider: "claude", Status: StatusActive}); err != nil {
+			t.Fatalf("Register(%s) error = %v", id, err)
+		}
+	}
+	limited := &Error{HTTPStatus: http.StatusTooManyRequests, Message: "rate limited"}
+
+	// One credential cooling down, and a cooldown error built for a filtered
+	// subset, leave the pool usable and must not notify.
+	manager.MarkResult(ctx, Result{AuthID: "pool-a", Provider: "claude", Model: "pool-model", Error: limited})
+	_ = blockSummary{total: 1, cooldown: 1, earliest: time.Now().Add(time.Minute)}.unavailableError("pool-model", "claude", time.Now())
+	notify.Emit(notify.Event{Type: "test.marker", Key: "pool"})
+	waitEvent("test.marker")
+
+	manager.MarkResult(ctx, Result{AuthID: "pool-b", Provider: "claude", Model: "pool-model", Error: limited})
+	event := waitEvent(notify.EventProviderCooldown)
+	if fields, _ := event["fields"].(map[string]any); fields["provider"] != "claude" || fields["model"] != "pool-model" {
+		t.Fatalf("notification fields = %v, want the claude pool for pool-model", event["fields"])
+	}
+}
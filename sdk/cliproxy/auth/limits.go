@@ -0,0 +1,687 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/notify"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencount"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ScheduleMetadataKey stores the active time windows of an auth.
+	ScheduleMetadataKey = "schedule"
+	// LimitsMetadataKey stores the request and token caps of an auth.
+	LimitsMetadataKey = "limits"
+	// usageCountersMetadataKey stores the counters behind the caps so they are
+	// written with the credential and survive restarts.
+	usageCountersMetadataKey = "usage_counters"
+
+	usageLimitsTick = time.Minute
+	// reserveRecheckDelay is how long a credential held back by its quota reserve
+	// stays out of rotation when the provider gives no reset time.
+	reserveRecheckDelay = 10 * time.Minute
+)
+
+// Schedule restricts an auth to time windows. Each window is a five-field cron
+// expression (minute hour day-of-month month day-of-week) and the auth is active
+// during every minute matched by any window, e.g. "* 9-17 * * 1-5" for 09:00 to
+// 17:59 on weekdays.
+type Schedule struct {
+	Windows []string `json:"windows"`
+	// Timezone is an IANA zone name; empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// UsageLimits caps what the proxy may consume through an auth. Zero disables a cap.
+type UsageLimits struct {
+	RequestsPerDay   int64 `json:"requests_per_day,omitempty"`
+	TokensPerDay     int64 `json:"tokens_per_day,omitempty"`
+	RequestsPerMonth int64 `json:"requests_per_month,omitempty"`
+	TokensPerMonth   int64 `json:"tokens_per_month,omitempty"`
+	// ReservePercent is kept back from every cap and from the provider quota
+	// reported by the quota poller.
+	ReservePercent float64 `json:"reserve_percent,omitempty"`
+	// Timezone sets where days and months start; empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// UsageCounters holds the requests and tokens counted against UsageLimits in the
+// current day and month.
+type UsageCounters struct {
+	Day           string `json:"day,omitempty"`
+	DayRequests   int64  `json:"day_requests,omitempty"`
+	DayTokens     int64  `json:"day_tokens,omitempty"`
+	Month         string `json:"month,omitempty"`
+	MonthRequests int64  `json:"month_requests,omitempty"`
+	MonthTokens   int64  `json:"month_tokens,omitempty"`
+}
+
+// Validate checks the windows and timezone of the schedule.
+func (s Schedule) Validate() error {
+	if _, err := loadLimitLocation(s.Timezone); err != nil {
+		return err
+	}
+	for _, window := range s.Windows {
+		if _, err := parseCronWindow(window); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the caps, reserve and timezone of the limits.
+func (l UsageLimits) Validate() error {
+	if l.RequestsPerDay < 0 || l.TokensPerDay < 0 || l.RequestsPerMonth < 0 || l.TokensPerMonth < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	if l.ReservePercent < 0 || l.ReservePercent >= 100 {
+		return fmt.Errorf("reserve_percent must be between 0 and 100")
+	}
+	_, err := loadLimitLocation(l.Timezone)
+	return err
+}
+
+func (l UsageLimits) hasCaps() bool {
+	return l.RequestsPerDay > 0 || l.TokensPerDay > 0 || l.RequestsPerMonth > 0 || l.TokensPerMonth > 0
+}
+
+// effective applies the reserve to a cap. A set cap never drops below 1, since
+// 0 means uncapped.
+func (l UsageLimits) effective(limit int64) int64 {
+	if limit <= 0 || l.ReservePercent <= 0 {
+		return limit
+	}
+	return max(1, int64(math.Floor(float64(limit)*(100-l.ReservePercent)/100)))
+}
+
+// usageState is the schedule, limits and counters decoded from auth metadata.
+// The manager decodes it once whenever it stores an auth, so picks do not
+// re-parse metadata. It is never mutated, only replaced.
+type usageState struct {
+	schedule    Schedule
+	hasSchedule bool
+	limits      UsageLimits
+	hasLimits   bool
+	counters    UsageCounters
+	budgets     map[string]UsageCounters
+}
+
+func decodeUsageState(metadata map[string]any) *usageState {
+	state := &usageState{}
+	if len(metadata) == 0 {
+		return state
+	}
+	state.hasSchedule = decodeMetadataValue(metadata, ScheduleMetadataKey, &state.schedule) && len(state.schedule.Windows) > 0
+	if !state.hasSchedule {
+		state.schedule = Schedule{}
+	}
+	state.hasLimits = decodeMetadataValue(metadata, LimitsMetadataKey, &state.limits) &&
+		(state.limits.hasCaps() || state.limits.ReservePercent > 0)
+	if !state.hasLimits {
+		state.limits = UsageLimits{}
+	}
+	decodeMetadataValue(metadata, usageCountersMetadataKey, &state.counters)
+	decodeMetadataValue(metadata, usageBudgetsMetadataKey, &state.budgets)
+	return state
+}
+
+// cacheUsageState decodes the usage state of an auth the manager is about to store.
+func (a *Auth) cacheUsageState() {
+	a.usage = decodeUsageState(a.Metadata)
+}
+
+// usageState returns the cached usage state, decoding it for auths the manager
+// has not stored.
+func (a *Auth) usageState() *usageState {
+	if a.usage != nil {
+		return a.usage
+	}
+	return decodeUsageState(a.Metadata)
+}
+
+// Schedule returns the auth's schedule, if one with windows is set.
+func (a *Auth) Schedule() (Schedule, bool) {
+	if a == nil {
+		return Schedule{}, false
+	}
+	state := a.usageState()
+	return state.schedule, state.hasSchedule
+}
+
+// UsageLimits returns the auth's caps, if any are set.
+func (a *Auth) UsageLimits() (UsageLimits, bool) {
+	if a == nil {
+		return UsageLimits{}, false
+	}
+	state := a.usageState()
+	return state.limits, state.hasLimits
+}
+
+// UsageCounters returns the auth's counters for the day and month containing now.
+func (a *Auth) UsageCounters(now time.Time) UsageCounters {
+	if a == nil {
+		return UsageCounters{}.rollover(now.UTC())
+	}
+	state := a.usageState()
+	loc, _ := loadLimitLocation(state.limits.Timezone)
+	return state.counters.rollover(now.In(loc))
+}
+
+func (a *Auth) setUsageCounters(c UsageCounters) {
+	if a.Metadata == nil {
+		a.Metadata = make(map[string]any)
+	}
+	// Metadata maps are shared between clones, so the value is replaced, never mutated.
+	a.Metadata[usageCountersMetadataKey] = map[string]any{
+		"day":            c.Day,
+		"day_requests":   c.DayRequests,
+		"day_tokens":     c.DayTokens,
+		"month":          c.Month,
+		"month_requests": c.MonthRequests,
+		"month_tokens":   c.MonthTokens,
+	}
+	state := *a.usageState()
+	state.counters = c
+	a.usage = &state
+}
+
+func (c UsageCounters) rollover(local time.Time) UsageCounters {
+	if day := local.Format("2006-01-02"); c.Day != day {
+		c.Day, c.DayRequests, c.DayTokens = day, 0, 0
+	}
+	if month := local.Format("2006-01"); c.Month != month {
+		c.Month, c.MonthRequests, c.MonthTokens = month, 0, 0
+	}
+	return c
+}
+
+// add counts one request of the given tokens in the current day and month.
+func (c UsageCounters) add(tokens int64) UsageCounters {
+	c.DayRequests++
+	c.MonthRequests++
+	c.DayTokens += tokens
+	c.MonthTokens += tokens
+	return c
+}
+
+// UsageBlock reports why the schedule, caps or quota reserve keep the auth out of
+// rotation at now, and when that ends. The reason is empty when the auth may serve.
+func (a *Auth) UsageBlock(now time.Time) (string, time.Time) {
+	_, reason, until := a.usageBlock(now)
+	return reason, until
+}
+
+// usageBlock is UsageBlock with the block reason the scheduler reports:
+// blockReasonOutsideSchedule for the schedule and blockReasonUsageCap for the
+// caps and the quota reserve.
+func (a *Auth) usageBlock(now time.Time) (blockReason, string, time.Time) {
+	if a == nil || a.Metadata == nil {
+		return blockReasonNone, "", time.Time{}
+	}
+	if schedule, ok := a.Schedule(); ok {
+		if reason, until := scheduleBlock(schedule, now); reason != "" {
+			return blockReasonOutsideSchedule, reason, until
+		}
+	}
+	limits, ok := a.UsageLimits()
+	if !ok {
+		return blockReasonNone, "", time.Time{}
+	}
+	loc, _ := loadLimitLocation(limits.Timezone)
+	local := now.In(loc)
+	counters := a.UsageCounters(now)
+	nextDay := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+	nextMonth := time.Date(local.Year(), local.Month()+1, 1, 0, 0, 0, 0, loc)
+	caps := []struct {
+		name  string
+		used  int64
+		limit int64
+		until time.Time
+	}{
+		{"daily request cap", counters.DayRequests, limits.effective(limits.RequestsPerDay), nextDay},
+		{"daily token cap", counters.DayTokens, limits.effective(limits.TokensPerDay), nextDay},
+		{"monthly request cap", counters.MonthRequests, limits.effective(limits.RequestsPerMonth), nextMonth},
+		{"monthly token cap", counters.MonthTokens, limits.effective(limits.TokensPerMonth), nextMonth},
+	}
+	for _, c := range caps {
+		if c.limit > 0 && c.used >= c.limit {
+			return blockReasonUsageCap, fmt.Sprintf("%s reached (%d/%d)", c.name, c.used, c.limit), c.until
+		}
+	}
+	if limits.ReservePercent > 0 && a.Quota.Limit > 0 && a.Quota.Remaining <= a.Quota.Limit*limits.ReservePercent/100 {
+		until := a.Quota.ResetAt
+		if !until.After(now) {
+			until = now.Add(reserveRecheckDelay)
+		}
+		return blockReasonUsageCap, fmt.Sprintf("quota reserve reached (%s of %s %s left, reserve %s%%)",
+			formatLimitNumber(a.Quota.Remaining), formatLimitNumber(a.Quota.Limit), a.Quota.Unit, formatLimitNumber(limits.ReservePercent)), until
+	}
+	return blockReasonNone, "", time.Time{}
+}
+
+// tokenHeadroom returns how many more tokens the auth's token caps allow at now,
+// and false when no token cap is set.
+func (a *Auth) tokenHeadroom(now time.Time) (int64, bool) {
+	limits, ok := a.UsageLimits()
+	if !ok || (limits.TokensPerDay <= 0 && limits.TokensPerMonth <= 0) {
+		return 0, false
+	}
+	counters := a.UsageCounters(now)
+	headroom := int64(math.MaxInt64)
+	if limit := limits.effective(limits.TokensPerDay); limit > 0 {
+		headroom = min(headroom, limit-counters.DayTokens)
+	}
+	if limit := limits.effective(limits.TokensPerMonth); limit > 0 {
+		headroom = min(headroom, limit-counters.MonthTokens)
+	}
+	return max(headroom, 0), true
+}
+
+// promptTokenEstimate estimates the prompt tokens of a request with the local
+// token counter. The count runs once, on first use, so requests routed to auths
+// without token caps never pay for it.
+type promptTokenEstimate struct {
+	once   sync.Once
+	req    cliproxyexecutor.Request
+	format string
+	tokens int64
+}
+
+func newPromptTokenEstimate(req cliproxyexecutor.Request, opts cliproxyexecutor.Options) *promptTokenEstimate {
+	return &promptTokenEstimate{req: req, format: opts.SourceFormat.String()}
+}
+
+func (e *promptTokenEstimate) get() int64 {
+	e.once.Do(func() {
+		result, err := tokencount.Count(e.req.Model, e.format, e.req.Payload)
+		if err != nil {
+			log.Debugf("prompt token estimate failed: %v", err)
+			return
+		}
+		e.tokens = result.Tokens
+	})
+	return e.tokens
+}
+
+// admitTokens reports whether the auth's token caps leave room for the request's
+// estimated prompt tokens, and the error to surface when they do not.
+func (a *Auth) admitTokens(estimate *promptTokenEstimate, now time.Time) error {
+	headroom, ok := a.tokenHeadroom(now)
+	if !ok {
+		return nil
+	}
+	if need := estimate.get(); need > headroom {
+		return &Error{
+			Code:       "token_cap_exceeded",
+			Message:    fmt.Sprintf("estimated %d prompt tokens exceed the %d tokens left under the token caps of credential %s", need, headroom, a.ID),
+			HTTPStatus: http.StatusTooManyRequests,
+		}
+	}
+	return nil
+}
+
+func scheduleBlock(schedule Schedule, now time.Time) (string, time.Time) {
+	loc, err := loadLimitLocation(schedule.Timezone)
+	if err != nil {
+		return "invalid schedule: " + err.Error(), now.Add(usageLimitsTick)
+	}
+	local := now.In(loc)
+	var next time.Time
+	for _, raw := range schedule.Windows {
+		window, errParse := parseCronWindow(raw)
+		if errParse != nil {
+			return "invalid schedule: " + errParse.Error(), now.Add(usageLimitsTick)
+		}
+		if window.matches(local) {
+			return "", time.Time{}
+		}
+		if opens := window.next(local); !opens.IsZero() && (next.IsZero() || opens.Before(next)) {
+			next = opens
+		}
+	}
+	if next.IsZero() {
+		return "outside schedule windows", now.Add(24 * time.Hour)
+	}
+	return "outside schedule windows until " + next.Format(time.RFC3339), next
+}
+
+func formatLimitNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func decodeMetadataValue(metadata map[string]any, key string, out any) bool {
+	raw, ok := metadata[key]
+	if !ok || raw == nil {
+		return false
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, out) == nil
+}
+
+var limitLocations sync.Map
+
+func loadLimitLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, "UTC") {
+		return time.UTC, nil
+	}
+	if cached, ok := limitLocations.Load(name); ok {
+		return cached.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC, fmt.Errorf("invalid timezone %q", name)
+	}
+	limitLocations.Store(name, loc)
+	return loc, nil
+}
+
+// cronWindow is a parsed five-field cron expression. Fields are bitsets of the
+// values they match.
+type cronWindow struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+var cronWindows sync.Map
+
+func parseCronWindow(expr string) (*cronWindow, error) {
+	expr = strings.TrimSpace(expr)
+	if cached, ok := cronWindows.Load(expr); ok {
+		return cached.(*cronWindow), nil
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule window %q must have 5 fields", expr)
+	}
+	w := &cronWindow{}
+	var err error
+	if w.minute, _, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("schedule window %q: minute: %w", expr, err)
+	}
+	if w.hour, _, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("schedule window %q: hour: %w", expr, err)
+	}
+	if w.dom, w.domAny, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("schedule window %q: day of month: %w", expr, err)
+	}
+	if w.month, _, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("schedule window %q: month: %w", expr, err)
+	}
+	if w.dow, w.dowAny, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("schedule window %q: day of week: %w", expr, err)
+	}
+	// Sunday may be written as 0 or 7.
+	if w.dow&(1<<7) != 0 {
+		w.dow |= 1
+	}
+	cronWindows.Store(expr, w)
+	return w, nil
+}
+
+func parseCronField(field string, lo, hi int) (uint64, bool, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			rangePart = part[:idx]
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n <= 0 {
+				return 0, false, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+		start, end := lo, hi
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			a, errA := strconv.Atoi(bounds[0])
+			b, errB := strconv.Atoi(bounds[1])
+			if errA != nil || errB != nil || a > b {
+				return 0, false, fmt.Errorf("invalid range %q", rangePart)
+			}
+			start, end = a, b
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, false, fmt.Errorf("invalid value %q", rangePart)
+			}
+			start, end = n, n
+			if step > 1 {
+				end = hi
+			}
+		}
+		if start < lo || end > hi {
+			return 0, false, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, field == "*", nil
+}
+
+func (w *cronWindow) dayMatches(t time.Time) bool {
+	domMatch := w.dom&(1<<uint(t.Day())) != 0
+	dowMatch := w.dow&(1<<uint(t.Weekday())) != 0
+	// As in cron, a restricted day of month and day of week match either one.
+	if !w.domAny && !w.dowAny {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+func (w *cronWindow) matches(t time.Time) bool {
+	return w.month&(1<<uint(t.Month())) != 0 && w.dayMatches(t) &&
+		w.hour&(1<<uint(t.Hour())) != 0 && w.minute&(1<<uint(t.Minute())) != 0
+}
+
+// next returns the first minute after t matched by the window, searching a year ahead.
+func (w *cronWindow) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(1, 0, 1)
+	for t.Before(limit) {
+		switch {
+		case w.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !w.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case w.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case w.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// HandleUsage counts a finished request against the caps of its auth and the
+// notification usage budgets it matches. It lets the manager be registered as a
+// usage plugin.
+func (m *Manager) HandleUsage(ctx context.Context, record usage.Record) {
+	if m == nil || record.AuthID == "" || record.Failed {
+		return
+	}
+	tokens := record.Detail.TotalTokens
+	if tokens <= 0 {
+		tokens = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	}
+	budgets := m.usageBudgets(record)
+	now := time.Now()
+	m.mu.Lock()
+	current := m.auths[record.AuthID]
+	if current == nil {
+		m.mu.Unlock()
+		return
+	}
+	limits, capped := current.UsageLimits()
+	capped = capped && limits.hasCaps()
+	if !capped && len(budgets) == 0 {
+		m.mu.Unlock()
+		return
+	}
+	var before, after string
+	if capped {
+		before, _ = current.UsageBlock(now)
+		current.setUsageCounters(current.UsageCounters(now).add(tokens))
+		after, _ = current.UsageBlock(now)
+	}
+	var events []notify.Event
+	if len(budgets) > 0 {
+		events = m.countBudgetsLocked(current, budgets, tokens, now)
+	}
+	if m.limitsDirty == nil {
+		m.limitsDirty = make(map[string]struct{})
+	}
+	m.limitsDirty[current.ID] = struct{}{}
+	snapshot := current.Clone()
+	m.mu.Unlock()
+
+	for _, event := range events {
+		notify.Emit(event)
+	}
+	if before == after {
+		return
+	}
+	log.Infof("%s credential %s paused: %s", snapshot.Provider, snapshot.ID, after)
+	if m.scheduler != nil {
+		m.scheduler.upsertAuth(snapshot)
+	}
+	m.flushUsageCounters(ctx)
+}
+
+// StartUsageLimits launches a background loop that re-evaluates scheduled and
+// capped auths every minute, so windows open and close and periods roll over
+// without traffic, and writes changed usage counters to the store.
+func (m *Manager) StartUsageLimits(parent context.Context) {
+	if m.limitsCancel != nil {
+		m.limitsCancel()
+		m.limitsCancel = nil
+	}
+	ctx, cancel := context.WithCancel(parent)
+	m.limitsCancel = cancel
+	go func() {
+		ticker := time.NewTicker(usageLimitsTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.refreshUsageLimits()
+				m.flushUsageCounters(ctx)
+			}
+		}
+	}()
+}
+
+// StopUsageLimits cancels the background loop and writes pending usage counters.
+func (m *Manager) StopUsageLimits() {
+	if m.limitsCancel != nil {
+		m.limitsCancel()
+		m.limitsCancel = nil
+	}
+	m.flushUsageCounters(context.Background())
+}
+
+// refreshUsageLimits pushes auths with a schedule or limits back into the
+// scheduler, which only re-evaluates entries when they change.
+func (m *Manager) refreshUsageLimits() {
+	if m.scheduler == nil {
+		return
+	}
+	for _, a := range m.snapshotAuths() {
+		if _, ok := a.Schedule(); ok {
+			m.scheduler.upsertAuth(a)
+			continue
+		}
+		if _, ok := a.UsageLimits(); ok {
+			m.scheduler.upsertAuth(a)
+		}
+	}
+}
+
+func (m *Manager) flushUsageCounters(ctx context.Context) {
+	m.mu.Lock()
+	if len(m.limitsDirty) == 0 {
+		m.mu.Unlock()
+		return
+	}
+	pending := make([]*Auth, 0, len(m.limitsDirty))
+	for id := range m.limitsDirty {
+		if current := m.auths[id]; current != nil {
+			pending = append(pending, current.Clone())
+		}
+	}
+	m.limitsDirty = nil
+	m.mu.Unlock()
+	for _, a := range pending {
+		if err := m.persist(ctx, a); err != nil {
+			log.Warnf("failed to persist usage counters for %s: %v", a.ID, err)
+		}
+	}
+}
+
+// mergeUsageCounters keeps the higher counters of the same period when an auth
+// is replaced, e.g. by the watcher reloading a file written before the latest
+// requests were counted. Budget shares are merged the same way.
+func mergeUsageCounters(auth, existing *Auth) {
+	prev, next := existing.usageState(), auth.usageState()
+	if merged := next.counters.merge(prev.counters); merged != next.counters {
+		auth.setUsageCounters(merged)
+	}
+	if len(prev.budgets) == 0 {
+		return
+	}
+	merged := maps.Clone(next.budgets)
+	if merged == nil {
+		merged = make(map[string]UsageCounters, len(prev.budgets))
+	}
+	changed := false
+	for name, c := range prev.budgets {
+		if combined := merged[name].merge(c); combined != merged[name] {
+			merged[name] = combined
+			changed = true
+		}
+	}
+	if changed {
+		auth.setBudgetCounters(merged)
+	}
+}
+
+// merge keeps the later period of each counter pair, and the higher counts when
+// both cover the same period.
+func (c UsageCounters) merge(prev UsageCounters) UsageCounters {
+	if prev.Day > c.Day {
+		c.Day, c.DayRequests, c.DayTokens = prev.Day, prev.DayRequests, prev.DayTokens
+	} else if prev.Day == c.Day {
+		c.DayRequests = max(c.DayRequests, prev.DayRequests)
+		c.DayTokens = max(c.DayTokens, prev.DayTokens)
+	}
+	if prev.Month > c.Month {
+		c.Month, c.MonthRequests, c.MonthTokens = prev.Month, prev.MonthRequests, prev.MonthTokens
+	} else if prev.Month == c.Month {
+		c.MonthRequests = max(c.MonthRequests, prev.MonthRequests)
+		c.MonthTokens = max(c.MonthTokens, prev.MonthTokens)
+	}
+	return c
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+func TestScheduleWindowsBlockOutsideBusinessHours(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	auth := &Auth{ID: "a", Provider: "claude", Metadata: map[string]any{
+		"schedule": map[string]any{"windows": []any{"* 18-23,0-7 * * 1-5", "* * * * 0,6"}, "timezone": "Europe/Berlin"},
+	}}
+
+	// Tuesday 10:30 in Berlin is business hours, when humans use the account.
+	busy := time.Date(2026, 10, 20, 10, 30, 0, 0, berlin)
+	reason, until := auth.UsageBlock(busy)
+	if !strings.HasPrefix(reason, "outside schedule windows") {
+		t.Fatalf("reason = %q, want outside schedule windows", reason)
+	}
+	if want := time.Date(2026, 10, 20, 18, 0, 0, 0, berlin); !until.Equal(want) {
+		t.Fatalf("until = %v, want %v", until, want)
+	}
+	if blocked, blockReason, next := isAuthBlockedForModel(auth, "", busy); !blocked || blockReason != blockReasonOutsideSchedule || !next.Equal(until) {
+		t.Fatalf("blocked=%v reason=%v next=%v, want outside schedule until the window opens", blocked, blockReason, next)
+	}
+
+	for _, free := range []time.Time{
+		time.Date(2026, 10, 20, 19, 0, 0, 0, berlin),
+		time.Date(2026, 10, 24, 12, 0, 0, 0, berlin),
+	} {
+		if reason, _ = auth.UsageBlock(free); reason != "" {
+			t.Fatalf("UsageBlock(%v) = %q, want available", free, reason)
+		}
+	}
+
+	if err = (Schedule{Windows: []string{"* 25 * * *"}}).Validate(); err == nil {
+		t.Fatal("Validate accepted hour 25")
+	}
+	if err = (Schedule{Windows: []string{"* * * * *"}, Timezone: "Mars/Olympus"}).Validate(); err == nil {
+		t.Fatal("Validate accepted an unknown timezone")
+	}
+}
+
+func TestUsageCapsPauseCredentialAndPersistCounters(t *testing.T) {
+	store := &healthMemoryStore{items: make(map[string]*Auth)}
+	manager := NewManager(store, nil, nil)
+	ctx := context.Background()
+	if _, err := manager.Register(ctx, &Auth{ID: "capped", Provider: "claude", Metadata: map[string]any{
+		"type":   "claude",
+		"limits": map[string]any{"requests_per_day": 10, "tokens_per_month": 1000000, "reserve_percent": 20},
+	}}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// A 20% reserve leaves 8 of the 10 daily requests usable.
+	for i := 0; i < 8; i++ {
+		auth, _ := manager.GetByID("capped")
+		if reason, _ := auth.UsageBlock(time.Now()); reason != "" {
+			t.Fatalf("request %d: credential paused early: %s", i, reason)
+		}
+		manager.HandleUsage(ctx, usage.Record{AuthID: "capped", Detail: usage.Detail{TotalTokens: 100}})
+	}
+	manager.HandleUsage(ctx, usage.Record{AuthID: "capped", Failed: true})
+
+	auth, _ := manager.GetByID("capped")
+	reason, until := auth.UsageBlock(time.Now())
+	if reason != "daily request cap reached (8/8)" || !until.After(time.Now()) {
+		t.Fatalf("UsageBlock = %q until %v, want the daily cap", reason, until)
+	}
+	if counters := auth.UsageCounters(time.Now()); counters.MonthTokens != 800 || counters.DayRequests != 8 {
+		t.Fatalf("counters = %+v, want 8 requests and 800 tokens", counters)
+	}
+	saved := store.items["capped"]
+	if saved == nil || saved.UsageCounters(time.Now()).DayRequests != 8 {
+		t.Fatal("crossing the cap should persist the counters")
+	}
+
+	// A reload of an older file, as the watcher does after a write, must not lose counts.
+	stale := auth.Clone()
+	stale.Metadata = map[string]any{"type": "claude", "limits": auth.Metadata["limits"]}
+	if _, err := manager.Update(WithSkipPersist(ctx), stale); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if reloaded, _ := manager.GetByID("capped"); reloaded.UsageCounters(time.Now()).DayRequests != 8 {
+		t.Fatalf("counters after reload = %+v", reloaded.UsageCounters(time.Now()))
+	}
+
+	// Counters from an earlier day do not count today.
+	old := UsageCounters{Day: "2000-01-01", DayRequests: 99, Month: "2000-01", MonthTokens: 5}
+	if got := old.rollover(time.Now().UTC()); got.DayRequests != 0 || got.MonthTokens != 0 {
+		t.Fatalf("rollover = %+v, want fresh counters", got)
+	}
+}
+
+func TestUnavailableErrorNamesScheduleAndCapBlocks(t *testing.T) {
+	now := time.Now().UTC()
+	closed := &Auth{ID: "closed", Provider: "claude", Metadata: map[string]any{
+		"schedule": map[string]any{"windows": []any{fmt.Sprintf("%d * * * *", (now.Minute()+30)%60)}},
+	}}
+	capped := &Auth{ID: "capped", Provider: "claude", Metadata: map[string]any{
+		"limits":         map[string]any{"requests_per_day": 1},
+		"usage_counters": map[string]any{"day": now.Format("2006-01-02"), "day_requests": 1},
+	}}
+	cases := []struct {
+		auths []*Auth
+		code  string
+		cause string
+	}{
+		{[]*Auth{closed}, "auth_outside_schedule", "are outside their schedule windows"},
+		{[]*Auth{capped}, "auth_usage_cap_reached", "have reached their usage caps"},
+		{[]*Auth{closed, capped}, "auth_usage_limited", "outside their schedule windows or have reached their usage caps"},
+	}
+	for _, tc := range cases {
+		for _, a := range tc.auths {
+			a.cacheUsageState()
+		}
+		_, errPick := newSchedulerForTest(&RoundRobinSelector{}, tc.auths...).pickSingle(context.Background(), "claude", "", cliproxyexecutor.Options{}, nil)
+		_, errSelect := getAvailableAuths(tc.auths, "claude", "m", now)
+		for _, err := range []error{errPick, errSelect} {
+			var authErr *Error
+			if !errors.As(err, &authErr) || authErr.Code != tc.code || authErr.HTTPStatus != http.StatusTooManyRequests || !strings.Contains(authErr.Message, tc.cause) {
+				t.Fatalf("error = %v, want %s (%s)", err, tc.code, tc.cause)
+			}
+		}
+	}
+}
+
+func TestUsageLimitsReserveAppliesToProviderQuota(t *testing.T) {
+	resetAt := time.Now().Add(3 * time.Hour)
+	auth := &Auth{
+		ID:       "q",
+		Provider: "github-copilot",
+		Metadata: map[string]any{"limits": map[string]any{"reserve_percent": 15}},
+		Quota:    QuotaState{Remaining: 40, Limit: 300, Unit: "requests", ResetAt: resetAt},
+	}
+	reason, until := auth.UsageBlock(time.Now())
+	if !strings.HasPrefix(reason, "quota reserve reached") || !until.Equal(resetAt) {
+		t.Fatalf("UsageBlock = %q until %v, want the reserve until reset", reason, until)
+	}
+	auth.Quota.Remaining = 60
+	if reason, _ = auth.UsageBlock(time.Now()); reason != "" {
+		t.Fatalf("UsageBlock = %q, want available above the reserve", reason)
+	}
+}
+
+func TestUsageLimitsReserveKeepsSmallCapsCapped(t *testing.T) {
+	limits := UsageLimits{RequestsPerDay: 1, TokensPerDay: 3, ReservePercent: 50}
+	if got := limits.effective(limits.RequestsPerDay); got != 1 {
+		t.Fatalf("effective(1) = %d, want 1", got)
+	}
+	if got := limits.effective(limits.TokensPerDay); got != 1 {
+		t.Fatalf("effective(3) = %d, want 1", got)
+	}
+
+	now := time.Now()
+	auth := &Auth{ID: "small", Provider: "claude", Metadata: map[string]any{
+		"limits": map[string]any{"requests_per_day": 1, "reserve_percent": 50},
+	}}
+	counters := UsageCounters{}.rollover(now.UTC())
+	counters.DayRequests = 1
+	auth.setUsageCounters(counters)
+	if reason, _ := auth.UsageBlock(now); !strings.HasPrefix(reason, "daily request cap reached") {
+		t.Fatalf("UsageBlock = %q, want the daily request cap", reason)
+	}
+}
+
+func TestTokenCapsSkipCredentialWithoutRoomForPrompt(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	executor := &authFallbackExecutor{id: "claude"}
+	m.RegisterExecutor(executor)
+
+	model := "claude-sonnet-4-5"
+	capped := &Auth{ID: "aa-capped", Provider: "claude", Metadata: map[string]any{
+		"limits": map[string]any{"tokens_per_day": 50},
+	}}
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient(capped.ID, "claude", []*registry.ModelInfo{{ID: model}})
+	t.Cleanup(func() { reg.UnregisterClient(capped.ID) })
+	if _, err := m.Register(context.Background(), capped); err != nil {
+		t.Fatalf("register capped auth: %v", err)
+	}
+
+	prompt := strings.Repeat("Explain the tradeoffs of this design in detail. ", 20)
+	request := cliproxyexecutor.Request{Model: model, Payload: []byte(`{"messages":[{"role":"user","content":"` + prompt + `"}]}`)}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude")}
+	_, err := m.Execute(context.Background(), []string{"claude"}, request, opts)
+	var authErr *Error
+	if !errors.As(err, &authErr) || authErr.Code != "token_cap_exceeded" || authErr.HTTPStatus != http.StatusTooManyRequests {
+		t.Fatalf("Execute() error = %v, want token_cap_exceeded", err)
+	}
+	if calls := executor.ExecuteCalls(); len(calls) != 0 {
+		t.Fatalf("execute calls = %v, want none", calls)
+	}
+
+	open := &Auth{ID: "bb-open", Provider: "claude"}
+	reg.RegisterClient(open.ID, "claude", []*registry.ModelInfo{{ID: model}})
+	t.Cleanup(func() { reg.UnregisterClient(open.ID) })
+	if _, err = m.Register(context.Background(), open); err != nil {
+		t.Fatalf("register open auth: %v", err)
+	}
+	resp, err := m.Execute(context.Background(), []string{"claude"}, request, opts)
+	if err != nil || string(resp.Payload) != open.ID {
+		t.Fatalf("Execute() = %q, %v, want the uncapped credential", resp.Payload, err)
+	}
+
+	request.Payload = []byte(`{"messages":[{"role":"user","content":"Hi"}]}`)
+	for i := 0; i < 2; i++ {
+		if _, err = m.Execute(context.Background(), []string{"claude"}, request, opts); err != nil {
+			t.Fatalf("Execute() small prompt error = %v", err)
+		}
+	}
+	if calls := executor.ExecuteCalls(); !slices.Contains(calls, capped.ID) {
+		t.Fatalf("execute calls = %v, want the capped credential to serve a small prompt", calls)
+	}
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// QuotaProbe is implemented by executors that can read a credential's remaining
+// quota from the provider without spending it.
+type QuotaProbe interface {
+	ProbeQuota(ctx context.Context, auth *Auth) (*QuotaReport, error)
+}
+
+// QuotaReport is a single reading returned by a QuotaProbe.
+type QuotaReport struct {
+	// Remaining is the quota left, in Unit.
+	Remaining float64
+	// Limit is the total allowance; zero when unknown or unlimited.
+	Limit float64
+	// Unit names what is counted, e.g. "requests" or "credits".
+	Unit string
+	// ResetAt is when the quota is replenished, if the provider says.
+	ResetAt time.Time
+	// PlanTier is the subscription plan of the account.
+	PlanTier string
+	// Exhausted is set when the provider reports no usable quota regardless of the numbers.
+	Exhausted bool
+}
+
+const (
+	quotaPollTick        = 30 * time.Second
+	quotaPollConcurrency = 4
+	quotaProbeTimeout    = 30 * time.Second
+)
+
+// StartQuotaPolling launches a background loop that probes credentials whose
+// executor implements QuotaProbe. The loop follows the quota-polling config at
+// every tick, so enabling or tuning it needs no restart.
+func (m *Manager) StartQuotaPolling(parent context.Context) {
+	if m.quotaCancel != nil {
+		m.quotaCancel()
+		m.quotaCancel = nil
+	}
+	ctx, cancel := context.WithCancel(parent)
+	m.quotaCancel = cancel
+	go func() {
+		ticker := time.NewTicker(quotaPollTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if m.quotaPollingConfig().Enabled {
+					m.PollQuota(ctx, false)
+				}
+			}
+		}
+	}()
+}
+
+// StopQuotaPolling cancels the background quota loop, if running.
+func (m *Manager) StopQuotaPolling() {
+	if m.quotaCancel != nil {
+		m.quotaCancel()
+		m.quotaCancel = nil
+	}
+}
+
+// PollQuota probes every eligible credential whose last reading is older than
+// the configured interval, or all of them when force is set, and waits for the
+// probes to finish.
+func (m *Manager) PollQuota(ctx context.Context, force bool) {
+	cfg := m.quotaPollingConfig()
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	now := time.Now()
+	sem := make(chan struct{}, quotaPollConcurrency)
+	var wg sync.WaitGroup
+	for _, a := range m.snapshotAuths() {
+		if a.Disabled || a.Status == StatusDisabled {
+			continue
+		}
+		if !force && !a.Quota.CheckedAt.IsZero() && now.Sub(a.Quota.CheckedAt) < interval {
+			continue
+		}
+		probe, ok := m.QuotaProbeFor(a)
+		if !ok {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(a *Auth) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			probeCtx, cancel := context.WithTimeout(ctx, quotaProbeTimeout)
+			report, err := probe.ProbeQuota(probeCtx, a)
+			cancel()
+			if err != nil && errors.Is(err, context.Canceled) {
+				return
+			}
+			m.applyQuotaReport(a.ID, report, err, time.Now())
+		}(a)
+	}
+	wg.Wait()
+}
+
+// QuotaProbeFor returns the quota probe of the executor serving the auth.
+func (m *Manager) QuotaProbeFor(auth *Auth) (QuotaProbe, bool) {
+	if auth == nil {
+		return nil, false
+	}
+	exec, ok := m.Executor(executorKeyFromAuth(auth))
+	if !ok {
+		return nil, false
+	}
+	probe, ok := exec.(QuotaProbe)
+	return probe, ok
+}
+
+func (m *Manager) quotaPollingConfig() internalconfig.QuotaPollingConfig {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil {
+		return internalconfig.QuotaPollingConfig{IntervalSeconds: internalconfig.DefaultQuotaPollIntervalSeconds}
+	}
+	q := cfg.QuotaPolling
+	if q.IntervalSeconds <= 0 {
+		q.IntervalSeconds = internalconfig.DefaultQuotaPollIntervalSeconds
+	}
+	return q
+}
+
+// applyQuotaReport stores a probe result on the auth and refreshes its
+// scheduler entry so low credentials drop out of rotation immediately.
+func (m *Manager) applyQuotaReport(id string, report *QuotaReport, probeErr error, now time.Time) {
+	cfg := m.quotaPollingConfig()
+	m.mu.Lock()
+	current := m.auths[id]
+	if current == nil {
+		m.mu.Unlock()
+		return
+	}
+	current.Quota.CheckedAt = now
+	if probeErr != nil || report == nil {
+		if probeErr != nil {
+			current.Quota.ProbeError = probeErr.Error()
+			log.Debugf("quota probe failed for %s, %s: %v", current.Provider, current.ID, probeErr)
+		}
+	} else {
+		current.Quota.ProbeError = ""
+		current.Quota.Remaining = report.Remaining
+		current.Quota.Limit = report.Limit
+		current.Quota.Unit = report.Unit
+		current.Quota.PlanTier = report.PlanTier
+		current.Quota.ResetAt = report.ResetAt
+		current.Quota.Low = quotaReportIsLow(report, cfg.ReservePercent)
+		current.Quota.LowUntil = time.Time{}
+		if current.Quota.Low && cfg.SkipLow {
+			current.Quota.LowUntil = now.Add(time.Duration(cfg.IntervalSeconds) * time.Second)
+			if report.ResetAt.After(now) {
+				current.Quota.LowUntil = report.ResetAt
+			}
+		}
+	}
+	snapshot := current.Clone()
+	m.mu.Unlock()
+	if m.scheduler != nil {
+		m.scheduler.upsertAuth(snapshot)
+	}
+}
+
+func quotaReportIsLow(report *QuotaReport, reservePercent float64) bool {
+	if report.Exhausted {
+		return true
+	}
+	if report.Limit <= 0 {
+		return false
+	}
+	return report.Remaining <= report.Limit*reservePercent/100
+}
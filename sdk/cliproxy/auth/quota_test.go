@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+type quotaProbeTestExecutor struct {
+	schedulerProviderTestExecutor
+	reports map[string]*QuotaReport
+}
+
+func (e *quotaProbeTestExecutor) ProbeQuota(_ context.Context, auth *Auth) (*QuotaReport, error) {
+	if report, ok := e.reports[auth.ID]; ok {
+		return report, nil
+	}
+	return nil, errors.New("probe failed")
+}
+
+func TestPollQuotaRecordsReadingsAndSkipsLowCredentials(t *testing.T) {
+	resetAt := time.Now().Add(6 * time.Hour).Truncate(time.Second)
+	exec := &quotaProbeTestExecutor{
+		schedulerProviderTestExecutor: schedulerProviderTestExecutor{provider: "claude"},
+		reports: map[string]*QuotaReport{
+			"low":  {Remaining: 5, Limit: 300, Unit: "requests", PlanTier: "pro", ResetAt: resetAt},
+			"full": {Remaining: 250, Limit: 300, Unit: "requests", PlanTier: "pro"},
+		},
+	}
+	manager := NewManager(nil, nil, nil)
+	manager.SetConfig(&internalconfig.Config{QuotaPolling: internalconfig.QuotaPollingConfig{
+		Enabled: true, IntervalSeconds: 300, ReservePercent: 10, SkipLow: true,
+	}})
+	manager.RegisterExecutor(exec)
+	for _, id := range []string{"low", "full", "broken"} {
+		if _, err := manager.Register(context.Background(), &Auth{ID: id, Provider: "claude", Metadata: map[string]any{"type": "claude"}}); err != nil {
+			t.Fatalf("Register(%s) error = %v", id, err)
+		}
+	}
+
+	manager.PollQuota(context.Background(), false)
+
+	low, _ := manager.GetByID("low")
+	if !low.Quota.Low || !low.Quota.LowUntil.Equal(resetAt) || low.Quota.PlanTier != "pro" || low.Quota.Remaining != 5 {
+		t.Fatalf("low quota = %+v, want low until %v", low.Quota, resetAt)
+	}
+	if blocked, reason, next := isAuthBlockedForModel(low, "", time.Now()); !blocked || reason != blockReasonCooldown || !next.Equal(resetAt) {
+		t.Fatalf("low credential blocked=%v reason=%v next=%v, want cooldown until reset", blocked, reason, next)
+	}
+	full, _ := manager.GetByID("full")
+	if full.Quota.Low || full.Quota.CheckedAt.IsZero() {
+		t.Fatalf("full quota = %+v, want a non-low reading", full.Quota)
+	}
+	if blocked, _, _ := isAuthBlockedForModel(full, "", time.Now()); blocked {
+		t.Fatal("credential above the reserve should stay schedulable")
+	}
+	broken, _ := manager.GetByID("broken")
+	if broken.Quota.ProbeError == "" || broken.Quota.Low {
+		t.Fatalf("broken quota = %+v, want probe error only", broken.Quota)
+	}
+
+	// A second poll inside the interval must not probe again.
+	exec.reports["full"] = &QuotaReport{Remaining: 0, Limit: 300}
+	manager.PollQuota(context.Background(), false)
+	if full, _ = manager.GetByID("full"); full.Quota.Remaining != 250 {
+		t.Fatalf("credential was re-probed before its interval elapsed: %+v", full.Quota)
+	}
+	manager.PollQuota(context.Background(), true)
+	if full, _ = manager.GetByID("full"); !full.Quota.Low {
+		t.Fatalf("forced poll should pick up the exhausted reading: %+v", full.Quota)
+	}
+}
+
+func TestQuotaReportIsLowWithoutSkipKeepsCredentialSchedulable(t *testing.T) {
+	manager := NewManager(nil, nil, nil)
+	manager.SetConfig(&internalconfig.Config{QuotaPolling: internalconfig.QuotaPollingConfig{Enabled: true, IntervalSeconds: 300}})
+	if _, err := manager.Register(context.Background(), &Auth{ID: "a", Provider: "claude"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	manager.applyQuotaReport("a", &QuotaReport{Exhausted: true}, nil, time.Now())
+
+	auth, _ := manager.GetByID("a")
+	if !auth.Quota.Low || !auth.Quota.LowUntil.IsZero() {
+		t.Fatalf("quota = %+v, want low without a skip window", auth.Quota)
+	}
+}
@@ -70,6 +70,7 @@ type scheduledAuth struct {
 	meta        *scheduledAuthMeta
 	auth        *Auth
 	state       scheduledState
+	reason      blockReason
 	nextRetryAt time.Time
 }
 
@@ -178,6 +179,7 @@ func (s *authScheduler) pickSingle(ctx context.Context, provider, model string,
 	providerKey := strings.ToLower(strings.TrimSpace(provider))
 	modelKey := canonicalModelKey(model)
 	pinnedAuthID := pinnedAuthIDFromMetadata(opts.Metadata)
+	visibleToTenant := tenantFilterFromMetadata(opts.Metadata)
 	preferWebsocket := cliproxyexecutor.DownstreamWebsocket(ctx) && providerKey == "codex" && pinnedAuthID == ""
 
 	s.mu.Lock()
@@ -197,6 +199,9 @@ func (s *authScheduler) pickSingle(ctx context.Context, provider, model string,
 		if pinnedAuthID != "" && entry.auth.ID != pinnedAuthID {
 			return false
 		}
+		if visibleToTenant != nil && !visibleToTenant(entry.auth) {
+			return false
+		}
 		if len(tried) > 0 {
 			if _, ok := tried[entry.auth.ID]; ok {
 				return false
@@ -233,6 +238,7 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 		return picked, providerKey, nil
 	}
 	pinnedAuthID := pinnedAuthIDFromMetadata(opts.Metadata)
+	visibleToTenant := tenantFilterFromMetadata(opts.Metadata)
 	modelKey := canonicalModelKey(model)
 
 	s.mu.Lock()
@@ -251,6 +257,9 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 			if entry == nil || entry.auth == nil || entry.auth.ID != pinnedAuthID {
 				return false
 			}
+			if visibleToTenant != nil && !visibleToTenant(entry.auth) {
+				return false
+			}
 			if len(tried) == 0 {
 				return true
 			}
@@ -263,7 +272,7 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 		return nil, "", shard.unavailableErrorLocked("mixed", model, predicate)
 	}
 
-	predicate := triedPredicate(tried)
+	predicate := tenantScopedPredicate(triedPredicate(tried), visibleToTenant)
 	candidateShards := make([]*modelScheduler, len(normalized))
 	bestPriority := 0
 	hasCandidate := false
@@ -288,7 +297,7 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 		}
 	}
 	if !hasCandidate {
-		return nil, "", s.mixedUnavailableErrorLocked(normalized, model, tried)
+		return nil, "", s.mixedUnavailableErrorLocked(normalized, model, predicate)
 	}
 
 	if s.strategy == schedulerStrategyFillFirst {
@@ -302,7 +311,7 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 				return picked, providerKey, nil
 			}
 		}
-		return nil, "", s.mixedUnavailableErrorLocked(normalized, model, tried)
+		return nil, "", s.mixedUnavailableErrorLocked(normalized, model, predicate)
 	}
 
 	cursorKey := strings.Join(normalized, ",") + ":" + modelKey
@@ -319,7 +328,7 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 		segmentEnds[providerIndex] = totalWeight
 	}
 	if totalWeight == 0 {
-		return nil, "", s.mixedUnavailableErrorLocked(normalized, model, tried)
+		return nil, "", s.mixedUnavailableErrorLocked(normalized, model, predicate)
 	}
 
 	startSlot := s.mixedCursors[cursorKey] % totalWeight
@@ -334,7 +343,7 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 		}
 	}
 	if startProviderIndex < 0 {
-		return nil, "", s.mixedUnavailableErrorLocked(normalized, model, tried)
+		return nil, "", s.mixedUnavailableErrorLocked(normalized, model, predicate)
 	}
 
 	slot := startSlot
@@ -358,15 +367,13 @@ func (s *authScheduler) pickMixed(ctx context.Context, providers []string, model
 		s.mixedCursors[cursorKey] = slot + 1
 		return picked, providerKey, nil
 	}
-	return nil, "", s.mixedUnavailableErrorLocked(normalized, model, tried)
+	return nil, "", s.mixedUnavailableErrorLocked(normalized, model, predicate)
 }
 
 // mixedUnavailableErrorLocked synthesizes the mixed-provider cooldown or unavailable error.
-func (s *authScheduler) mixedUnavailableErrorLocked(providers []string, model string, tried map[string]struct{}) error {
+func (s *authScheduler) mixedUnavailableErrorLocked(providers []string, model string, predicate func(*scheduledAuth) bool) error {
 	now := time.Now()
-	total := 0
-	cooldownCount := 0
-	earliest := time.Time{}
+	var blocked blockSummary
 	for _, providerKey := range providers {
 		providerState := s.providers[providerKey]
 		if providerState == nil {
@@ -376,24 +383,12 @@ func (s *authScheduler) mixedUnavailableErrorLocked(providers []string, model st
 		if shard == nil {
 			continue
 		}
-		localTotal, localCooldownCount, localEarliest := shard.availabilitySummaryLocked(triedPredicate(tried))
-		total += localTotal
-		cooldownCount += localCooldownCount
-		if !localEarliest.IsZero() && (earliest.IsZero() || localEarliest.Before(earliest)) {
-			earliest = localEarliest
-		}
+		shard.availabilitySummaryLocked(predicate, &blocked)
 	}
-	if total == 0 {
+	if blocked.total == 0 {
 		return &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
-	if cooldownCount == total && !earliest.IsZero() {
-		resetIn := earliest.Sub(now)
-		if resetIn < 0 {
-			resetIn = 0
-		}
-		return newModelCooldownError(model, "", resetIn)
-	}
-	return &Error{Code: "auth_unavailable", Message: "no auth available"}
+	return blocked.unavailableError(model, "", now)
 }
 
 // triedPredicate builds a filter that excludes auths already attempted for the current request.
@@ -410,6 +405,16 @@ func triedPredicate(tried map[string]struct{}) func(*scheduledAuth) bool {
 	}
 }
 
+// tenantScopedPredicate narrows base to auths visible to the request's tenant.
+func tenantScopedPredicate(base func(*scheduledAuth) bool, visible func(*Auth) bool) func(*scheduledAuth) bool {
+	if visible == nil {
+		return base
+	}
+	return func(entry *scheduledAuth) bool {
+		return base(entry) && visible(entry.auth)
+	}
+}
+
 // normalizeProviderKeys lowercases, trims, and de-duplicates provider keys while preserving order.
 func normalizeProviderKeys(providers []string) []string {
 	seen := make(map[string]struct{}, len(providers))
@@ -629,6 +634,7 @@ func (m *modelScheduler) upsertEntryLocked(meta *scheduledAuthMeta, now time.Tim
 	entry.auth = meta.auth
 	entry.nextRetryAt = time.Time{}
 	blocked, reason, next := isAuthBlockedForModel(meta.auth, m.modelKey, now)
+	entry.reason = reason
 	switch {
 	case !blocked:
 		entry.state = scheduledStateReady
@@ -674,6 +680,7 @@ func (m *modelScheduler) promoteExpiredLocked(now time.Time) {
 			continue
 		}
 		blocked, reason, next := isAuthBlockedForModel(entry.auth, m.modelKey, now)
+		entry.reason = reason
 		switch {
 		case !blocked:
 			entry.state = scheduledStateReady
@@ -779,52 +786,31 @@ func (m *modelScheduler) readyCountAtPriorityLocked(preferWebsocket bool, priori
 	return len(bucket.all.flat)
 }
 
-// unavailableErrorLocked returns the correct unavailable or cooldown error for the shard.
+// unavailableErrorLocked returns the correct unavailable, cooldown or usage limit error for the shard.
 func (m *modelScheduler) unavailableErrorLocked(provider, model string, predicate func(*scheduledAuth) bool) error {
-	now := time.Now()
-	total, cooldownCount, earliest := m.availabilitySummaryLocked(predicate)
-	if total == 0 {
+	var blocked blockSummary
+	m.availabilitySummaryLocked(predicate, &blocked)
+	if blocked.total == 0 {
 		return &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
-	if cooldownCount == total && !earliest.IsZero() {
-		providerForError := provider
-		if providerForError == "mixed" {
-			providerForError = ""
-		}
-		resetIn := earliest.Sub(now)
-		if resetIn < 0 {
-			resetIn = 0
-		}
-		return newModelCooldownError(model, providerForError, resetIn)
-	}
-	return &Error{Code: "auth_unavailable", Message: "no auth available"}
+	return blocked.unavailableError(model, provider, time.Now())
 }
 
-// availabilitySummaryLocked summarizes total candidates, cooldown count, and earliest retry time.
-func (m *modelScheduler) availabilitySummaryLocked(predicate func(*scheduledAuth) bool) (int, int, time.Time) {
+// availabilitySummaryLocked adds the candidates matching predicate to the summary.
+func (m *modelScheduler) availabilitySummaryLocked(predicate func(*scheduledAuth) bool, blocked *blockSummary) {
 	if m == nil {
-		return 0, 0, time.Time{}
+		return
 	}
-	total := 0
-	cooldownCount := 0
-	earliest := time.Time{}
 	for _, entry := range m.entries {
 		if predicate != nil && !predicate(entry) {
 			continue
 		}
-		total++
-		if entry == nil || entry.auth == nil {
+		if entry == nil || entry.auth == nil || entry.state == scheduledStateReady {
+			blocked.add(blockReasonNone, time.Time{})
 			continue
 		}
-		if entry.state != scheduledStateCooldown {
-			continue
-		}
-		cooldownCount++
-		if !entry.nextRetryAt.IsZero() && (earliest.IsZero() || entry.nextRetryAt.Before(earliest)) {
-			earliest = entry.nextRetryAt
-		}
+		blocked.add(entry.reason, entry.nextRetryAt)
 	}
-	return total, cooldownCount, earliest
 }
 
 // rebuildIndexesLocked reconstructs ready and blocked views from the current entry map.
@@ -13,6 +13,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/notify"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 )
@@ -36,8 +38,82 @@ const (
 	blockReasonCooldown
 	blockReasonDisabled
 	blockReasonOther
+	// blockReasonOutsideSchedule and blockReasonUsageCap come from the auth's own
+	// schedule windows and usage caps rather than from upstream errors.
+	blockReasonOutsideSchedule
+	blockReasonUsageCap
 )
 
+// blockSummary counts why candidates cannot serve, so the error returned when
+// none is available names the actual cause.
+type blockSummary struct {
+	total         int
+	cooldown      int
+	schedule      int
+	capped        int
+	earliest      time.Time
+	earliestLimit time.Time
+}
+
+func (s *blockSummary) add(reason blockReason, next time.Time) {
+	s.total++
+	switch reason {
+	case blockReasonCooldown:
+		s.cooldown++
+		if !next.IsZero() && (s.earliest.IsZero() || next.Before(s.earliest)) {
+			s.earliest = next
+		}
+		return
+	case blockReasonOutsideSchedule:
+		s.schedule++
+	case blockReasonUsageCap:
+		s.capped++
+	default:
+		return
+	}
+	if !next.IsZero() && (s.earliestLimit.IsZero() || next.Before(s.earliestLimit)) {
+		s.earliestLimit = next
+	}
+}
+
+// unavailableError returns the cooldown error when every candidate cools down,
+// a schedule or usage cap error when every candidate is held back by its own
+// limits, and auth_unavailable otherwise.
+func (s blockSummary) unavailableError(model, provider string, now time.Time) error {
+	if provider == "mixed" {
+		provider = ""
+	}
+	if s.cooldown == s.total && !s.earliest.IsZero() {
+		return newModelCooldownError(model, provider, s.earliest.Sub(now))
+	}
+	limited := s.schedule + s.capped
+	if limited == 0 || limited+s.cooldown != s.total {
+		return &Error{Code: "auth_unavailable", Message: "no auth available"}
+	}
+	modelName := model
+	if modelName == "" {
+		modelName = "requested model"
+	}
+	code, cause := "auth_usage_cap_reached", "have reached their usage caps"
+	switch {
+	case s.capped == 0:
+		code, cause = "auth_outside_schedule", "are outside their schedule windows"
+	case s.schedule > 0:
+		code, cause = "auth_usage_limited", "are outside their schedule windows or have reached their usage caps"
+	}
+	message := fmt.Sprintf("All credentials for model %s %s", modelName, cause)
+	if provider != "" {
+		message = fmt.Sprintf("All credentials for model %s via provider %s %s", modelName, provider, cause)
+	}
+	if next := s.earliestLimit; !next.IsZero() {
+		if !s.earliest.IsZero() && s.earliest.Before(next) {
+			next = s.earliest
+		}
+		message += "; next available at " + next.UTC().Format(time.RFC3339)
+	}
+	return &Error{Code: code, Message: message, HTTPStatus: http.StatusTooManyRequests}
+}
+
 type modelCooldownError struct {
 	model    string
 	resetIn  time.Duration
@@ -55,6 +131,50 @@ func newModelCooldownError(model, provider string, resetIn time.Duration) *model
 	}
 }
 
+// notifyPoolCooldown reports when every credential of provider serving model is
+// cooling down. It looks at the whole pool, not at the share one tenant may use
+// or the credentials a request has not tried yet.
+func (m *Manager) notifyPoolCooldown(provider, model string) {
+	if provider == "" || model == "" || !notify.Default().Enabled() {
+		return
+	}
+	now := time.Now()
+	registryRef := registry.GetGlobalRegistry()
+	var blocked blockSummary
+	m.mu.RLock()
+	for _, candidate := range m.auths {
+		if candidate == nil || candidate.Provider != provider || candidate.Disabled {
+			continue
+		}
+		if !m.authSupportsRouteModel(registryRef, candidate, model) {
+			continue
+		}
+		isBlocked, reason, next := isAuthBlockedForModel(candidate, m.selectionModelForAuth(candidate, model), now)
+		if !isBlocked {
+			m.mu.RUnlock()
+			return
+		}
+		blocked.add(reason, next)
+	}
+	m.mu.RUnlock()
+	if blocked.total == 0 || blocked.cooldown != blocked.total || blocked.earliest.IsZero() {
+		return
+	}
+	resetIn := blocked.earliest.Sub(now)
+	notify.Emit(notify.Event{
+		Type:     notify.EventProviderCooldown,
+		Severity: notify.SeverityCritical,
+		Key:      provider + "|" + model,
+		Title:    fmt.Sprintf("All credentials cooling down for %s", model),
+		Message:  fmt.Sprintf("Every credential of %s serving %s is in cooldown; next one resets in %s.", provider, model, resetIn.Round(time.Second)),
+		Fields: map[string]string{
+			"model":    model,
+			"provider": provider,
+			"reset_in": resetIn.Round(time.Second).String(),
+		},
+	})
+}
+
 func (e *modelCooldownError) Error() string {
 	modelName := e.model
 	if modelName == "" {
@@ -191,24 +311,19 @@ func preferCodexWebsocketAuths(ctx context.Context, provider string, available [
 	return available
 }
 
-func collectAvailableByPriority(auths []*Auth, model string, now time.Time) (available map[int][]*Auth, cooldownCount int, earliest time.Time) {
+func collectAvailableByPriority(auths []*Auth, model string, now time.Time) (available map[int][]*Auth, blocked blockSummary) {
 	available = make(map[int][]*Auth)
 	for i := 0; i < len(auths); i++ {
 		candidate := auths[i]
-		blocked, reason, next := isAuthBlockedForModel(candidate, model, now)
-		if !blocked {
+		isBlocked, reason, next := isAuthBlockedForModel(candidate, model, now)
+		if !isBlocked {
 			priority := authPriority(candidate)
 			available[priority] = append(available[priority], candidate)
 			continue
 		}
-		if reason == blockReasonCooldown {
-			cooldownCount++
-			if !next.IsZero() && (earliest.IsZero() || next.Before(earliest)) {
-				earliest = next
-			}
-		}
+		blocked.add(reason, next)
 	}
-	return available, cooldownCount, earliest
+	return available, blocked
 }
 
 func getAvailableAuths(auths []*Auth, provider, model string, now time.Time) ([]*Auth, error) {
@@ -216,20 +331,9 @@ func getAvailableAuths(auths []*Auth, provider, model string, now time.Time) ([]
 		return nil, &Error{Code: "auth_not_found", Message: "no auth candidates"}
 	}
 
-	availableByPriority, cooldownCount, earliest := collectAvailableByPriority(auths, model, now)
+	availableByPriority, blocked := collectAvailableByPriority(auths, model, now)
 	if len(availableByPriority) == 0 {
-		if cooldownCount == len(auths) && !earliest.IsZero() {
-			providerForError := provider
-			if providerForError == "mixed" {
-				providerForError = ""
-			}
-			resetIn := earliest.Sub(now)
-			if resetIn < 0 {
-				resetIn = 0
-			}
-			return nil, newModelCooldownError(model, providerForError, resetIn)
-		}
-		return nil, &Error{Code: "auth_unavailable", Message: "no auth available"}
+		return nil, blocked.unavailableError(model, provider, now)
 	}
 
 	bestPriority := 0
@@ -369,6 +473,15 @@ func isAuthBlockedForModel(auth *Auth, model string, now time.Time) (bool, block
 	if auth.Disabled || auth.Status == StatusDisabled {
 		return true, blockReasonDisabled, time.Time{}
 	}
+	if _, quarantined := auth.Quarantine(); quarantined {
+		return true, blockReasonDisabled, time.Time{}
+	}
+	if auth.Quota.LowUntil.After(now) {
+		return true, blockReasonCooldown, auth.Quota.LowUntil
+	}
+	if kind, reason, until := auth.usageBlock(now); reason != "" {
+		return true, kind, until
+	}
 	if model != "" {
 		if len(auth.ModelStates) > 0 {
 			state, ok := auth.ModelStates[model]
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"strings"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	// SharedTenant tags credentials belonging to the shared pool.
+	SharedTenant = "*"
+	// TenantsAttributeKey stores the comma-separated tenant tags of an auth.
+	TenantsAttributeKey = "tenants"
+)
+
+// TenantScope describes which credentials a caller may use when tenants are configured.
+// An empty Name denotes a caller outside every tenant.
+type TenantScope struct {
+	Name        string
+	AllowShared bool
+}
+
+// Tenants returns the tenant tags assigned to the auth. Tags are read from the
+// "tenants" attribute and fall back to the "tenants" metadata field, which accepts
+// a list or a comma-separated string.
+func (a *Auth) Tenants() []string {
+	if a == nil {
+		return nil
+	}
+	if a.Attributes != nil {
+		if raw := strings.TrimSpace(a.Attributes[TenantsAttributeKey]); raw != "" {
+			return splitTenantTags(raw)
+		}
+	}
+	if a.Metadata == nil {
+		return nil
+	}
+	return TenantTagsFromValue(a.Metadata[TenantsAttributeKey])
+}
+
+// TenantTagsFromValue parses tenant tags from a metadata value.
+func TenantTagsFromValue(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return splitTenantTags(v)
+	case []string:
+		return normalizeTenantTags(v)
+	case []any:
+		tags := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				tags = append(tags, s)
+			}
+		}
+		return normalizeTenantTags(tags)
+	default:
+		return nil
+	}
+}
+
+func splitTenantTags(raw string) []string {
+	return normalizeTenantTags(strings.Split(raw, ","))
+}
+
+func normalizeTenantTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		out = append(out, tag)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// VisibleToTenant reports whether the auth may serve a caller in scope.
+//
+// Tenant callers see credentials tagged with their tenant, plus the shared pool
+// when the tenant opted in. Callers outside every tenant keep using untagged
+// credentials and the shared pool.
+func (a *Auth) VisibleToTenant(scope TenantScope) bool {
+	if a == nil {
+		return false
+	}
+	tags := a.Tenants()
+	if scope.Name == "" {
+		if len(tags) == 0 {
+			return true
+		}
+		for _, tag := range tags {
+			if tag == SharedTenant {
+				return true
+			}
+		}
+		return false
+	}
+	for _, tag := range tags {
+		if tag == scope.Name {
+			return true
+		}
+		if tag == SharedTenant && scope.AllowShared {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantScopeFromMetadata extracts the tenant scope set by the request handler.
+func tenantScopeFromMetadata(meta map[string]any) (TenantScope, bool) {
+	if len(meta) == 0 {
+		return TenantScope{}, false
+	}
+	switch v := meta[cliproxyexecutor.TenantMetadataKey].(type) {
+	case TenantScope:
+		return v, true
+	case *TenantScope:
+		if v != nil {
+			return *v, true
+		}
+	}
+	return TenantScope{}, false
+}
+
+// tenantFilterFromMetadata returns a visibility check for the request's tenant,
+// or nil when tenant isolation does not apply.
+func tenantFilterFromMetadata(meta map[string]any) func(*Auth) bool {
+	scope, ok := tenantScopeFromMetadata(meta)
+	if !ok {
+		return nil
+	}
+	return func(a *Auth) bool { return a.VisibleToTenant(scope) }
+}
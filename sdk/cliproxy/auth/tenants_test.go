@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestAuthVisibleToTenant(t *testing.T) {
+	t.Parallel()
+
+	untagged := &Auth{ID: "untagged"}
+	teamA := &Auth{ID: "team-a", Attributes: map[string]string{TenantsAttributeKey: "team-a, team-b"}}
+	shared := &Auth{ID: "shared", Metadata: map[string]any{TenantsAttributeKey: []any{"*"}}}
+
+	cases := []struct {
+		name  string
+		auth  *Auth
+		scope TenantScope
+		want  bool
+	}{
+		{"untagged outside tenants", untagged, TenantScope{}, true},
+		{"shared outside tenants", shared, TenantScope{}, true},
+		{"tagged outside tenants", teamA, TenantScope{}, false},
+		{"own tenant", teamA, TenantScope{Name: "team-b"}, true},
+		{"foreign tenant", teamA, TenantScope{Name: "team-c"}, false},
+		{"untagged for tenant", untagged, TenantScope{Name: "team-a", AllowShared: true}, false},
+		{"shared without opt-in", shared, TenantScope{Name: "team-a"}, false},
+		{"shared with opt-in", shared, TenantScope{Name: "team-a", AllowShared: true}, true},
+	}
+	for _, tc := range cases {
+		if got := tc.auth.VisibleToTenant(tc.scope); got != tc.want {
+			t.Errorf("%s: VisibleToTenant() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSchedulerPick_RespectsTenantScope(t *testing.T) {
+	t.Parallel()
+
+	scheduler := newSchedulerForTest(
+		&RoundRobinSelector{},
+		&Auth{ID: "a-team", Provider: "gemini", Attributes: map[string]string{TenantsAttributeKey: "team-a"}},
+		&Auth{ID: "b-team", Provider: "gemini", Attributes: map[string]string{TenantsAttributeKey: "team-b"}},
+		&Auth{ID: "c-shared", Provider: "gemini", Attributes: map[string]string{TenantsAttributeKey: "*"}},
+	)
+
+	optsFor := func(scope TenantScope) cliproxyexecutor.Options {
+		return cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.TenantMetadataKey: scope}}
+	}
+
+	for index := 0; index < 3; index++ {
+		got, errPick := scheduler.pickSingle(context.Background(), "gemini", "", optsFor(TenantScope{Name: "team-b"}), nil)
+		if errPick != nil {
+			t.Fatalf("pickSingle() #%d error = %v", index, errPick)
+		}
+		if got == nil || got.ID != "b-team" {
+			t.Fatalf("pickSingle() #%d auth = %v, want b-team", index, got)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for index := 0; index < 4; index++ {
+		got, errPick := scheduler.pickSingle(context.Background(), "gemini", "", optsFor(TenantScope{Name: "team-a", AllowShared: true}), nil)
+		if errPick != nil {
+			t.Fatalf("pickSingle() shared #%d error = %v", index, errPick)
+		}
+		seen[got.ID] = true
+	}
+	if !seen["a-team"] || !seen["c-shared"] || seen["b-team"] {
+		t.Fatalf("shared tenant picks = %v, want a-team and c-shared only", seen)
+	}
+
+	if got, errPick := scheduler.pickSingle(context.Background(), "gemini", "", optsFor(TenantScope{Name: "team-c"}), nil); errPick == nil {
+		t.Fatalf("pickSingle() for tenant without credentials = %v, want error", got)
+	}
+}
+
+func TestManager_PickNextLegacyRespectsTenantScope(t *testing.T) {
+	t.Parallel()
+
+	selector := &trackingSelector{}
+	manager := NewManager(nil, selector, nil)
+	manager.executors["gemini"] = schedulerTestExecutor{}
+	manager.auths["tenant"] = &Auth{ID: "tenant", Provider: "gemini", Metadata: map[string]any{TenantsAttributeKey: "team-a"}}
+	manager.auths["global"] = &Auth{ID: "global", Provider: "gemini"}
+
+	opts := cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.TenantMetadataKey: TenantScope{Name: "team-a"}}}
+	got, _, errPick := manager.pickNext(context.Background(), "gemini", "", opts, map[string]struct{}{})
+	if errPick != nil {
+		t.Fatalf("pickNext() error = %v", errPick)
+	}
+	if got == nil || got.ID != "tenant" {
+		t.Fatalf("pickNext() auth = %v, want tenant", got)
+	}
+	if len(selector.lastAuthID) != 1 {
+		t.Fatalf("selector candidates = %v, want only the tenant auth", selector.lastAuthID)
+	}
+
+	_, _, errPick = manager.pickNext(context.Background(), "gemini", "", cliproxyexecutor.Options{}, map[string]struct{}{})
+	if errPick != nil {
+		t.Fatalf("pickNext() without scope error = %v", errPick)
+	}
+	if len(selector.lastAuthID) != 2 {
+		t.Fatalf("selector candidates without scope = %v, want both auths", selector.lastAuthID)
+	}
+}
@@ -0,0 +1,357 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// trafficPlan is the routing decision for one request under a traffic route.
+type trafficPlan struct {
+	// preferred is the provider drawn from the route targets; empty leaves the
+	// choice to the scheduler.
+	preferred string
+	// shadow is set when the request was sampled for mirroring.
+	shadow *internalconfig.TrafficShadow
+}
+
+// planTraffic applies the traffic route configured for model, if any. Targets
+// that do not serve the model are ignored so weights split what is available.
+func (m *Manager) planTraffic(providers []string, model string, opts cliproxyexecutor.Options) trafficPlan {
+	var plan trafficPlan
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	base := strings.TrimSpace(thinking.ParseSuffix(model).ModelName)
+	route := cfg.TrafficRouteFor(base)
+	if route == nil {
+		return plan
+	}
+	available := make(map[string]struct{}, len(providers))
+	for _, provider := range providers {
+		available[provider] = struct{}{}
+	}
+
+	targets := make([]internalconfig.TrafficTarget, 0, len(route.Targets))
+	total := 0
+	for _, target := range route.Targets {
+		if _, ok := available[target.Provider]; !ok || target.Weight <= 0 {
+			continue
+		}
+		targets = append(targets, target)
+		total += target.Weight
+	}
+	if total > 0 {
+		var draw int
+		clientKey := metadataString(opts.Metadata, cliproxyexecutor.ClientKeyMetadataKey)
+		if route.Sticky && clientKey != "" {
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(clientKey + "|" + strings.ToLower(base)))
+			draw = int(h.Sum32() % uint32(total))
+		} else {
+			draw = rand.IntN(total)
+		}
+		for _, target := range targets {
+			if draw < target.Weight {
+				plan.preferred = target.Provider
+				break
+			}
+			draw -= target.Weight
+		}
+	}
+
+	if shadow := route.Shadow; shadow != nil && shadow.Provider != plan.preferred {
+		if rand.Float64()*100 < shadow.SamplePercent {
+			plan.shadow = shadow
+		}
+	}
+	return plan
+}
+
+// pickNextRouted picks from the preferred provider first and falls back to every
+// provider serving the model once the preferred one has no usable credential.
+func (m *Manager) pickNextRouted(ctx context.Context, preferred string, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
+	if preferred != "" && len(providers) > 1 {
+		auth, executor, provider, errPick := m.pickNextMixed(ctx, []string{preferred}, model, opts, tried)
+		if errPick == nil {
+			return auth, executor, provider, nil
+		}
+	}
+	return m.pickNextMixed(ctx, providers, model, opts, tried)
+}
+
+func metadataString(meta map[string]any, key string) string {
+	if len(meta) == 0 {
+		return ""
+	}
+	value, _ := meta[key].(string)
+	return strings.TrimSpace(value)
+}
+
+// ShadowStats compares a candidate provider against the primary responses for
+// the requests mirrored to it.
+type ShadowStats struct {
+	Model    string `json:"model"`
+	Provider string `json:"provider"`
+	// Samples counts mirrored requests.
+	Samples int64 `json:"samples"`
+	// PrimaryErrors and ShadowErrors count failures on each side.
+	PrimaryErrors int64 `json:"primary_errors"`
+	ShadowErrors  int64 `json:"shadow_errors"`
+	// ShadowOnlyErrors counts samples where only the candidate failed, and
+	// PrimaryOnlyErrors samples where only the primary failed.
+	ShadowOnlyErrors  int64 `json:"shadow_only_errors"`
+	PrimaryOnlyErrors int64 `json:"primary_only_errors"`
+	// Latencies are averaged over completed calls. Streams are measured to the first chunk.
+	PrimaryAvgLatencyMs int64     `json:"primary_avg_latency_ms"`
+	ShadowAvgLatencyMs  int64     `json:"shadow_avg_latency_ms"`
+	LastShadowError     string    `json:"last_shadow_error,omitempty"`
+	LastSampleAt        time.Time `json:"last_sample_at"`
+
+	primaryCalls   int64
+	shadowCalls    int64
+	primaryLatency time.Duration
+	shadowLatency  time.Duration
+}
+
+// ShadowStats returns the shadow comparisons recorded since start or the last reset.
+func (m *Manager) ShadowStats() []ShadowStats {
+	m.shadowMu.Lock()
+	defer m.shadowMu.Unlock()
+	out := make([]ShadowStats, 0, len(m.shadowStats))
+	for _, stats := range m.shadowStats {
+		snapshot := *stats
+		if snapshot.primaryCalls > 0 {
+			snapshot.PrimaryAvgLatencyMs = (snapshot.primaryLatency / time.Duration(snapshot.primaryCalls)).Milliseconds()
+		}
+		if snapshot.shadowCalls > 0 {
+			snapshot.ShadowAvgLatencyMs = (snapshot.shadowLatency / time.Duration(snapshot.shadowCalls)).Milliseconds()
+		}
+		out = append(out, snapshot)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Model != out[j].Model {
+			return out[i].Model < out[j].Model
+		}
+		return out[i].Provider < out[j].Provider
+	})
+	return out
+}
+
+// ResetShadowStats clears the recorded shadow comparisons.
+func (m *Manager) ResetShadowStats() {
+	m.shadowMu.Lock()
+	m.shadowStats = nil
+	m.shadowMu.Unlock()
+}
+
+// shadowSample pairs the primary and shadow outcomes of one mirrored request.
+type shadowSample struct {
+	m        *Manager
+	model    string
+	provider string
+
+	mu         sync.Mutex
+	sides      int
+	primaryErr bool
+	shadowErr  bool
+}
+
+func (m *Manager) newShadowSample(model, provider string) *shadowSample {
+	model = strings.TrimSpace(thinking.ParseSuffix(model).ModelName)
+	m.withShadowStats(model, provider, func(stats *ShadowStats) {
+		stats.Samples++
+		stats.LastSampleAt = time.Now()
+	})
+	return &shadowSample{m: m, model: model, provider: provider}
+}
+
+func (m *Manager) withShadowStats(model, provider string, fn func(*ShadowStats)) {
+	key := strings.ToLower(model) + "|" + provider
+	m.shadowMu.Lock()
+	defer m.shadowMu.Unlock()
+	if m.shadowStats == nil {
+		m.shadowStats = make(map[string]*ShadowStats)
+	}
+	stats := m.shadowStats[key]
+	if stats == nil {
+		stats = &ShadowStats{Model: model, Provider: provider}
+		m.shadowStats[key] = stats
+	}
+	fn(stats)
+}
+
+func (s *shadowSample) primaryDone(latency time.Duration, err error) {
+	s.m.withShadowStats(s.model, s.provider, func(stats *ShadowStats) {
+		stats.primaryCalls++
+		stats.primaryLatency += latency
+		if err != nil {
+			stats.PrimaryErrors++
+		}
+	})
+	s.done(true, err != nil)
+}
+
+func (s *shadowSample) shadowDone(latency time.Duration, err error) {
+	s.m.withShadowStats(s.model, s.provider, func(stats *ShadowStats) {
+		if err != nil {
+			stats.ShadowErrors++
+			stats.LastShadowError = err.Error()
+			return
+		}
+		stats.shadowCalls++
+		stats.shadowLatency += latency
+	})
+	s.done(false, err != nil)
+}
+
+// done records which side failed once both outcomes are known.
+func (s *shadowSample) done(primary, failed bool) {
+	s.mu.Lock()
+	if primary {
+		s.primaryErr = failed
+	} else {
+		s.shadowErr = failed
+	}
+	s.sides++
+	complete := s.sides == 2
+	primaryErr, shadowErr := s.primaryErr, s.shadowErr
+	s.mu.Unlock()
+	if !complete || primaryErr == shadowErr {
+		return
+	}
+	s.m.withShadowStats(s.model, s.provider, func(stats *ShadowStats) {
+		if shadowErr {
+			stats.ShadowOnlyErrors++
+		} else {
+			stats.PrimaryOnlyErrors++
+		}
+	})
+}
+
+// observePrimaryStream forwards the primary stream and records it on the sample
+// once the stream ends, measured like the shadow side: latency to the first chunk
+// and the first chunk error. Chunks are still drained after the client leaves so
+// the outcome is recorded.
+func (s *shadowSample) observePrimaryStream(ctx context.Context, start time.Time, result *cliproxyexecutor.StreamResult) *cliproxyexecutor.StreamResult {
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		var latency time.Duration
+		var streamErr error
+		forward := true
+		for chunk := range result.Chunks {
+			if latency == 0 {
+				latency = time.Since(start)
+			}
+			if chunk.Err != nil && streamErr == nil {
+				streamErr = chunk.Err
+			}
+			if !forward {
+				continue
+			}
+			if ctx == nil {
+				out <- chunk
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				forward = false
+			case out <- chunk:
+			}
+		}
+		if latency == 0 {
+			latency = time.Since(start)
+		}
+		s.primaryDone(latency, streamErr)
+	}()
+	return &cliproxyexecutor.StreamResult{Headers: result.Headers, Chunks: out}
+}
+
+// startShadow mirrors req to the shadow provider in the background. The shadow
+// call is detached from the client request, does not update credential state
+// and its response is discarded.
+func (m *Manager) startShadow(ctx context.Context, shadow *internalconfig.TrafficShadow, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, stream bool) *shadowSample {
+	sample := m.newShadowSample(req.Model, shadow.Provider)
+
+	// The inbound context carries the gin context, which is recycled once the
+	// client response is written, so only the request ID is carried over.
+	shadowCtx := context.Background()
+	if reqID := logging.GetRequestID(ctx); reqID != "" {
+		shadowCtx = logging.WithRequestID(shadowCtx, reqID)
+	}
+	req.Payload = bytes.Clone(req.Payload)
+	opts.OriginalRequest = bytes.Clone(opts.OriginalRequest)
+	opts.Headers = opts.Headers.Clone()
+	meta := make(map[string]any, len(opts.Metadata))
+	for k, v := range opts.Metadata {
+		meta[k] = v
+	}
+	delete(meta, cliproxyexecutor.PinnedAuthMetadataKey)
+	delete(meta, cliproxyexecutor.SelectedAuthCallbackMetadataKey)
+	delete(meta, cliproxyexecutor.ExecutionSessionMetadataKey)
+	opts.Metadata = meta
+
+	go func() {
+		timeout := time.Duration(shadow.TimeoutSeconds) * time.Second
+		if timeout <= 0 {
+			timeout = internalconfig.DefaultShadowTimeoutSeconds * time.Second
+		}
+		runCtx, cancel := context.WithTimeout(shadowCtx, timeout)
+		defer cancel()
+		latency, err := m.executeShadow(runCtx, shadow.Provider, req, opts, stream)
+		if err != nil {
+			logEntryWithRequestID(runCtx).Debugf("shadow request to %s for %s failed: %v", shadow.Provider, req.Model, err)
+		}
+		sample.shadowDone(latency, err)
+	}()
+	return sample
+}
+
+func (m *Manager) executeShadow(ctx context.Context, provider string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, stream bool) (time.Duration, error) {
+	auth, executor, _, errPick := m.pickNextMixed(ctx, []string{provider}, req.Model, opts, make(map[string]struct{}))
+	if errPick != nil {
+		return 0, errPick
+	}
+	if rt := m.roundTripperFor(auth); rt != nil {
+		ctx = context.WithValue(ctx, roundTripperContextKey{}, rt)
+		ctx = context.WithValue(ctx, "cliproxy.roundtripper", rt)
+	}
+	models, _ := m.preparedExecutionModels(auth, req.Model)
+	if len(models) == 0 {
+		return 0, &Error{Code: "model_not_found", Message: "shadow provider does not serve the model"}
+	}
+	req.Model = models[0]
+
+	start := time.Now()
+	if !stream {
+		_, err := executor.Execute(ctx, auth, req, opts)
+		return time.Since(start), err
+	}
+	result, err := executor.ExecuteStream(ctx, auth, req, opts)
+	if err != nil {
+		return time.Since(start), err
+	}
+	var latency time.Duration
+	var streamErr error
+	for chunk := range result.Chunks {
+		if latency == 0 {
+			latency = time.Since(start)
+		}
+		if chunk.Err != nil && streamErr == nil {
+			streamErr = chunk.Err
+		}
+	}
+	if latency == 0 {
+		latency = time.Since(start)
+	}
+	return latency, streamErr
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func newTrafficTestManager(t *testing.T, routes []internalconfig.TrafficRoute) (*Manager, map[string]*authFallbackExecutor, map[string]*Auth) {
+	t.Helper()
+	cfg := &internalconfig.Config{TrafficRouting: routes}
+	cfg.SanitizeTrafficRouting()
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(cfg)
+
+	executors := make(map[string]*authFallbackExecutor)
+	auths := make(map[string]*Auth)
+	reg := registry.GetGlobalRegistry()
+	for _, provider := range []string{"claude", "kiro"} {
+		executor := &authFallbackExecutor{id: provider, executeErrors: map[string]error{}}
+		m.RegisterExecutor(executor)
+		executors[provider] = executor
+		auth := &Auth{ID: provider + "-" + uuid.NewString(), Provider: provider}
+		reg.RegisterClient(auth.ID, provider, []*registry.ModelInfo{{ID: "claude-sonnet"}})
+		t.Cleanup(func() { reg.UnregisterClient(auth.ID) })
+		if _, err := m.Register(context.Background(), auth); err != nil {
+			t.Fatalf("register %s: %v", provider, err)
+		}
+		auths[provider] = auth
+	}
+	return m, executors, auths
+}
+
+func TestTrafficRoutingSplitsByWeightAndSticksToClientKey(t *testing.T) {
+	m, _, _ := newTrafficTestManager(t, []internalconfig.TrafficRoute{{
+		Model:   "Claude-Sonnet",
+		Sticky:  true,
+		Targets: []internalconfig.TrafficTarget{{Provider: "Claude", Weight: 90}, {Provider: "kiro", Weight: 10}},
+	}})
+	providers := []string{"claude", "kiro"}
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		opts := cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.ClientKeyMetadataKey: fmt.Sprintf("client-%d", i)}}
+		first := m.planTraffic(providers, "claude-sonnet(high)", opts).preferred
+		for j := 0; j < 3; j++ {
+			if again := m.planTraffic(providers, "claude-sonnet", opts).preferred; again != first {
+				t.Fatalf("client-%d moved from %s to %s", i, first, again)
+			}
+		}
+		counts[first]++
+	}
+	if counts["kiro"] < 50 || counts["kiro"] > 160 || counts["claude"]+counts["kiro"] != 1000 {
+		t.Fatalf("split = %v, want about 10%% kiro", counts)
+	}
+
+	// Targets that do not serve the model are left out of the split.
+	for i := 0; i < 20; i++ {
+		if got := m.planTraffic([]string{"claude"}, "claude-sonnet", cliproxyexecutor.Options{}).preferred; got != "claude" {
+			t.Fatalf("preferred = %q, want claude when kiro does not serve the model", got)
+		}
+	}
+	if got := m.planTraffic(providers, "other-model", cliproxyexecutor.Options{}); got.preferred != "" || got.shadow != nil {
+		t.Fatalf("plan for unrouted model = %+v", got)
+	}
+}
+
+func TestTrafficRoutingFallsBackWhenTargetUnavailable(t *testing.T) {
+	m, executors, auths := newTrafficTestManager(t, []internalconfig.TrafficRoute{{
+		Model:   "claude-sonnet",
+		Targets: []internalconfig.TrafficTarget{{Provider: "kiro", Weight: 1}},
+	}})
+	ctx := context.Background()
+	req := cliproxyexecutor.Request{Model: "claude-sonnet"}
+
+	resp, err := m.Execute(ctx, []string{"claude", "kiro"}, req, cliproxyexecutor.Options{})
+	if err != nil || string(resp.Payload) != auths["kiro"].ID {
+		t.Fatalf("Execute = %q, %v; want the kiro target", resp.Payload, err)
+	}
+
+	disabled := auths["kiro"].Clone()
+	disabled.Disabled = true
+	disabled.Status = StatusDisabled
+	if _, err = m.Update(ctx, disabled); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	resp, err = m.Execute(ctx, []string{"claude", "kiro"}, req, cliproxyexecutor.Options{})
+	if err != nil || string(resp.Payload) != auths["claude"].ID {
+		t.Fatalf("Execute = %q, %v; want fallback to claude", resp.Payload, err)
+	}
+	if calls := executors["kiro"].ExecuteCalls(); len(calls) != 1 {
+		t.Fatalf("kiro calls = %v, want only the first request", calls)
+	}
+}
+
+func TestTrafficShadowMirrorsWithoutAffectingResponse(t *testing.T) {
+	m, executors, auths := newTrafficTestManager(t, []internalconfig.TrafficRoute{{
+		Model:   "claude-sonnet",
+		Targets: []internalconfig.TrafficTarget{{Provider: "claude", Weight: 1}},
+		Shadow:  &internalconfig.TrafficShadow{Provider: "kiro", SamplePercent: 100},
+	}})
+	executors["kiro"].executeErrors[auths["kiro"].ID] = &Error{HTTPStatus: 500, Message: "candidate failed"}
+
+	resp, err := m.Execute(context.Background(), []string{"claude", "kiro"}, cliproxyexecutor.Request{Model: "claude-sonnet"}, cliproxyexecutor.Options{})
+	if err != nil || string(resp.Payload) != auths["claude"].ID {
+		t.Fatalf("Execute = %q, %v; want the primary response", resp.Payload, err)
+	}
+
+	var stats []ShadowStats
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stats = m.ShadowStats()
+		if len(stats) == 1 && stats[0].ShadowOnlyErrors == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("shadow stats = %+v, want one shadow-only error", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := stats[0]
+	if got.Model != "claude-sonnet" || got.Provider != "kiro" || got.Samples != 1 || got.PrimaryErrors != 0 || got.LastShadowError != "candidate failed" {
+		t.Fatalf("shadow stats = %+v", got)
+	}
+
+	// The shadow failure must not cool down the candidate credential.
+	if kiro, _ := m.GetByID(auths["kiro"].ID); len(kiro.ModelStates) != 0 || kiro.Unavailable {
+		t.Fatalf("shadow call changed credential state: %+v", kiro.ModelStates)
+	}
+
+	m.ResetShadowStats()
+	if len(m.ShadowStats()) != 0 {
+		t.Fatal("ResetShadowStats kept entries")
+	}
+}
+
+func TestTrafficShadowRecordsPrimaryStreamWhenDrainedAndSkipsInternalCalls(t *testing.T) {
+	m, _, auths := newTrafficTestManager(t, []internalconfig.TrafficRoute{{
+		Model:   "claude-sonnet",
+		Targets: []internalconfig.TrafficTarget{{Provider: "claude", Weight: 1}},
+		Shadow:  &internalconfig.TrafficShadow{Provider: "kiro", SamplePercent: 100},
+	}})
+	providers := []string{"claude", "kiro"}
+	req := cliproxyexecutor.Request{Model: "claude-sonnet"}
+	primaryCalls := func() int64 {
+		m.shadowMu.Lock()
+		defer m.shadowMu.Unlock()
+		if stats := m.shadowStats["claude-sonnet|kiro"]; stats != nil {
+			return stats.primaryCalls
+		}
+		return 0
+	}
+
+	result, err := m.ExecuteStream(context.Background(), providers, req, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	if got := primaryCalls(); got != 0 {
+		t.Fatalf("primary calls = %d before the stream was read, want 0", got)
+	}
+	var payload string
+	for chunk := range result.Chunks {
+		payload += string(chunk.Payload)
+	}
+	if payload != auths["claude"].ID {
+		t.Fatalf("stream payload = %q, want the primary response", payload)
+	}
+	if got := primaryCalls(); got != 1 {
+		t.Fatalf("primary calls = %d after the stream closed, want 1", got)
+	}
+
+	internal := cliproxyexecutor.WithInternalRequest(context.Background())
+	if _, err = m.Execute(internal, providers, req, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result, err = m.ExecuteStream(internal, providers, req, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	for range result.Chunks {
+	}
+	if stats := m.ShadowStats(); len(stats) != 1 || stats[0].Samples != 1 {
+		t.Fatalf("shadow stats = %+v, want only the client request sampled", stats)
+	}
+}
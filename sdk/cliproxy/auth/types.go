@@ -74,6 +74,8 @@ type Auth struct {
 	Metadata map[string]any `json:"metadata,omitempty"`
 	// Quota captures recent quota information for load balancers.
 	Quota QuotaState `json:"quota"`
+	// Health holds the latest scheduled health check result.
+	Health HealthState `json:"health"`
 	// LastError stores the last failure encountered while executing or refreshing.
 	LastError *Error `json:"last_error,omitempty"`
 	// CreatedAt is the creation timestamp in UTC.
@@ -93,6 +95,9 @@ type Auth struct {
 	Runtime any `json:"-"`
 
 	indexAssigned bool `json:"-"`
+
+	// usage caches the schedule, limits and counters decoded from Metadata.
+	usage *usageState
 }
 
 // QuotaState contains limiter tracking data for a credential.
@@ -105,6 +110,25 @@ type QuotaState struct {
 	NextRecoverAt time.Time `json:"next_recover_at"`
 	// BackoffLevel stores the progressive cooldown exponent used for rate limits.
 	BackoffLevel int `json:"backoff_level,omitempty"`
+
+	// The fields below hold the latest reading from the executor's QuotaProbe.
+
+	// Remaining is the quota left in Unit; Limit is zero when unknown or unlimited.
+	Remaining float64 `json:"remaining,omitempty"`
+	Limit     float64 `json:"limit,omitempty"`
+	Unit      string  `json:"unit,omitempty"`
+	// PlanTier is the subscription plan reported by the provider.
+	PlanTier string `json:"plan_tier,omitempty"`
+	// ResetAt is when the provider replenishes the quota.
+	ResetAt time.Time `json:"reset_at,omitempty"`
+	// Low reports that the remaining quota is at or below the configured reserve.
+	Low bool `json:"low,omitempty"`
+	// LowUntil keeps the scheduler off the credential while Low; zero when skipping is off.
+	LowUntil time.Time `json:"low_until,omitempty"`
+	// CheckedAt records the last probe; zero means the credential was never probed.
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+	// ProbeError holds the last probe failure, if any.
+	ProbeError string `json:"probe_error,omitempty"`
 }
 
 // ModelState captures the execution state for a specific model under an auth entry.
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
+	oidcjwt "github.com/router-for-me/CLIProxyAPI/v6/internal/access/oidc_jwt"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
@@ -198,6 +199,7 @@ func (b *Builder) Build() (*Service, error) {
 	}
 
 	configaccess.Register(&b.cfg.SDKConfig)
+	oidcjwt.Register(&b.cfg.SDKConfig)
 	accessManager.SetProviders(sdkaccess.RegisteredProviders())
 
 	coreManager := b.coreManager
@@ -220,6 +222,13 @@ func (b *Builder) Build() (*Service, error) {
 		}
 
 		coreManager = coreauth.NewManager(tokenStore, selector, nil)
+		// Stores shared by several replicas coordinate refreshes and runtime state.
+		if leaser, ok := tokenStore.(coreauth.RefreshLeaser); ok {
+			coreManager.SetRefreshLeaser(leaser)
+		}
+		if replicator, ok := tokenStore.(coreauth.StateReplicator); ok {
+			coreManager.SetStateReplicator(replicator)
+		}
 	}
 	// Attach a default RoundTripper provider so providers can opt-in per-auth transports.
 	coreManager.SetRoundTripperProvider(newDefaultRoundTripperProvider())
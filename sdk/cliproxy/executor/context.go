@@ -21,3 +21,24 @@ func DownstreamWebsocket(ctx context.Context) bool {
 	enabled, ok := raw.(bool)
 	return ok && enabled
 }
+
+type internalRequestContextKey struct{}
+
+// WithInternalRequest marks upstream calls the proxy makes on its own behalf, such as
+// summaries, corrective follow-ups and repair retries, so they are not mirrored to
+// shadow providers.
+func WithInternalRequest(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, internalRequestContextKey{}, true)
+}
+
+// InternalRequest reports whether the current call was made by the proxy itself.
+func InternalRequest(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	enabled, ok := ctx.Value(internalRequestContextKey{}).(bool)
+	return ok && enabled
+}
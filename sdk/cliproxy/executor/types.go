@@ -19,6 +19,10 @@ const (
 	SelectedAuthCallbackMetadataKey = "selected_auth_callback"
 	// ExecutionSessionMetadataKey identifies a long-lived downstream execution session.
 	ExecutionSessionMetadataKey = "execution_session_id"
+	// TenantMetadataKey carries the caller's tenant scope (an auth.TenantScope) when tenants are configured.
+	TenantMetadataKey = "tenant_scope"
+	// ClientKeyMetadataKey carries a hash of the caller's API key for sticky traffic routing.
+	ClientKeyMetadataKey = "client_key"
 )
 
 // Request encapsulates the translated payload that will be sent to a provider executor.
@@ -0,0 +1,146 @@
+package cliproxy
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/plugin"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+)
+
+// pluginAuthID returns the runtime auth identifier backing a plugin executor.
+func pluginAuthID(name string) string {
+	return plugin.AccessProviderPrefix + name
+}
+
+func isPluginAuth(a *coreauth.Auth) bool {
+	return a != nil && a.Attributes != nil && strings.TrimSpace(a.Attributes[plugin.AuthAttribute]) != ""
+}
+
+func (s *Service) applyPluginConfig(cfg *config.Config) {
+	if s == nil || cfg == nil {
+		return
+	}
+	if s.plugins == nil {
+		if len(cfg.Plugins) == 0 {
+			return
+		}
+		s.plugins = plugin.NewSupervisor(cfg.AuthDir, plugin.Hooks{
+			OnReady: s.onPluginReady,
+			OnLost:  s.onPluginLost,
+		})
+	}
+	s.plugins.Apply(cfg.Plugins)
+}
+
+func (s *Service) shutdownPlugins() {
+	if s == nil || s.plugins == nil {
+		return
+	}
+	s.plugins.Stop()
+}
+
+// onPluginReady registers the capabilities advertised by a plugin.
+func (s *Service) onPluginReady(name string, client *plugin.Client, desc plugin.Description) {
+	if desc.Access {
+		sdkaccess.RegisterProvider(plugin.AccessProviderPrefix+name, plugin.NewAccessProvider(name, client))
+		s.refreshAccessProviders()
+	}
+	if desc.Executor != nil {
+		s.registerPluginExecutor(name, client, desc.Executor)
+	}
+	if desc.Transforms != nil {
+		if desc.Transforms.Request {
+			sdktranslator.RegisterRequestMiddleware(plugin.AccessProviderPrefix+name, plugin.RequestMiddleware(client))
+		}
+		if desc.Transforms.Response {
+			sdktranslator.RegisterResponseMiddleware(plugin.AccessProviderPrefix+name, plugin.ResponseMiddleware(client))
+		}
+	}
+}
+
+// onPluginLost removes everything onPluginReady registered for the plugin.
+func (s *Service) onPluginLost(name string, desc plugin.Description) {
+	if desc.Access {
+		sdkaccess.UnregisterProvider(plugin.AccessProviderPrefix + name)
+		s.refreshAccessProviders()
+	}
+	if desc.Executor != nil {
+		s.unregisterPluginExecutor(name, desc.Executor)
+	}
+	sdktranslator.UnregisterRequestMiddleware(plugin.AccessProviderPrefix + name)
+	sdktranslator.UnregisterResponseMiddleware(plugin.AccessProviderPrefix + name)
+	log.Infof("plugin %s capabilities removed", name)
+}
+
+func (s *Service) refreshAccessProviders() {
+	if s.accessManager == nil {
+		return
+	}
+	s.accessManager.SetProviders(sdkaccess.RegisteredProviders())
+}
+
+func (s *Service) registerPluginExecutor(name string, client *plugin.Client, desc *plugin.ExecutorDescription) {
+	if s.coreManager == nil {
+		return
+	}
+	provider := strings.ToLower(strings.TrimSpace(desc.Provider))
+	if provider == "" {
+		log.Warnf("plugin %s: executor declared without provider; ignoring", name)
+		return
+	}
+	if existing, ok := s.coreManager.Executor(provider); ok {
+		if _, isPlugin := existing.(*plugin.Executor); !isPlugin {
+			log.Warnf("plugin %s: provider %s is served by a built-in executor; ignoring plugin executor", name, provider)
+			return
+		}
+	}
+	s.coreManager.RegisterExecutor(plugin.NewExecutor(provider, client))
+
+	ctx := context.Background()
+	id := pluginAuthID(name)
+	now := time.Now()
+	auth := &coreauth.Auth{
+		ID:        id,
+		Provider:  provider,
+		Label:     name,
+		Status:    coreauth.StatusActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Attributes: map[string]string{
+			"runtime_only":       "true",
+			plugin.AuthAttribute: name,
+		},
+	}
+	var err error
+	if existing, ok := s.coreManager.GetByID(id); ok && existing != nil {
+		auth.CreatedAt = existing.CreatedAt
+		_, err = s.coreManager.Update(ctx, auth)
+	} else {
+		_, err = s.coreManager.Register(ctx, auth)
+	}
+	if err != nil {
+		log.Errorf("plugin %s: failed to register auth: %v", name, err)
+		return
+	}
+	GlobalModelRegistry().RegisterClient(id, provider, desc.Models)
+	s.coreManager.RefreshSchedulerEntry(id)
+}
+
+func (s *Service) unregisterPluginExecutor(name string, desc *plugin.ExecutorDescription) {
+	if s.coreManager == nil {
+		return
+	}
+	s.applyCoreAuthRemoval(context.Background(), pluginAuthID(name))
+	provider := strings.ToLower(strings.TrimSpace(desc.Provider))
+	if existing, ok := s.coreManager.Executor(provider); ok {
+		if _, isPlugin := existing.(*plugin.Executor); isPlugin {
+			s.coreManager.UnregisterExecutor(provider)
+		}
+	}
+}
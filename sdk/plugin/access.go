@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+)
+
+// AccessProviderPrefix prefixes the identifier of plugin-backed access providers.
+const AccessProviderPrefix = "plugin:"
+
+// AccessProvider authenticates requests through a plugin. It implements sdkaccess.Provider.
+type AccessProvider struct {
+	name   string
+	client *Client
+}
+
+// NewAccessProvider returns an access provider backed by the named plugin.
+func NewAccessProvider(name string, client *Client) *AccessProvider {
+	return &AccessProvider{name: name, client: client}
+}
+
+// Identifier implements sdkaccess.Provider.
+func (p *AccessProvider) Identifier() string { return AccessProviderPrefix + p.name }
+
+// Authenticate implements sdkaccess.Provider.
+func (p *AccessProvider) Authenticate(ctx context.Context, r *http.Request) (*sdkaccess.Result, *sdkaccess.AuthError) {
+	if r == nil {
+		return nil, sdkaccess.NewNotHandledError()
+	}
+	params := AuthenticateParams{
+		Method:     r.Method,
+		Headers:    r.Header,
+		RemoteAddr: r.RemoteAddr,
+	}
+	if r.URL != nil {
+		params.Path = r.URL.Path
+		params.Query = r.URL.RawQuery
+	}
+	var result AuthenticateResult
+	if err := p.client.Call(ctx, MethodAuthenticate, params, &result); err != nil {
+		return nil, sdkaccess.NewInternalAuthError(fmt.Sprintf("plugin %s authentication failed", p.name), err)
+	}
+	switch strings.ToLower(strings.TrimSpace(result.Status)) {
+	case AuthStatusOK:
+		return &sdkaccess.Result{
+			Provider:  p.Identifier(),
+			Principal: result.Principal,
+			Metadata:  result.Metadata,
+		}, nil
+	case AuthStatusNoCredentials:
+		return nil, sdkaccess.NewNoCredentialsError()
+	case AuthStatusInvalidCredential:
+		return nil, sdkaccess.NewInvalidCredentialError()
+	default:
+		return nil, sdkaccess.NewNotHandledError()
+	}
+}
@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// maxMessageSize bounds a single JSON-RPC message read from a plugin.
+const maxMessageSize = 64 << 20
+
+// Client issues JSON-RPC calls to a plugin socket. Each call uses its own
+// connection so streaming calls never block unrelated requests.
+type Client struct {
+	socket  string
+	timeout time.Duration
+	nextID  atomic.Int64
+}
+
+// NewClient returns a client for the plugin listening on socket.
+// timeout bounds each call when the context carries no earlier deadline.
+func NewClient(socket string, timeout time.Duration) *Client {
+	return &Client{socket: socket, timeout: timeout}
+}
+
+// Socket returns the socket path the client dials.
+func (c *Client) Socket() string { return c.socket }
+
+// Call invokes method and decodes the result into result (which may be nil).
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	return c.CallStream(ctx, method, params, nil, result)
+}
+
+// CallStream invokes method and passes every notification received before the
+// final response to onNotify. Returning an error from onNotify aborts the call.
+func (c *Client) CallStream(ctx context.Context, method string, params any, onNotify func(method string, params json.RawMessage) error, result any) error {
+	if c == nil {
+		return errors.New("plugin: client is nil")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if c.timeout > 0 {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.socket)
+	if err != nil {
+		return fmt.Errorf("plugin: dial %s: %w", c.socket, err)
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("plugin: encode params: %w", err)
+	}
+	id := c.nextID.Add(1)
+	request, err := json.Marshal(message{JSONRPC: jsonRPCVersion, ID: &id, Method: method, Params: rawParams})
+	if err != nil {
+		return fmt.Errorf("plugin: encode request: %w", err)
+	}
+	if _, err = conn.Write(append(request, '\n')); err != nil {
+		return c.wrapIOError(ctx, err)
+	}
+
+	reader := bufio.NewReaderSize(conn, 64<<10)
+	for {
+		line, errRead := readLine(reader)
+		if errRead != nil {
+			return c.wrapIOError(ctx, errRead)
+		}
+		if len(line) == 0 {
+			continue
+		}
+		var msg message
+		if errUnmarshal := json.Unmarshal(line, &msg); errUnmarshal != nil {
+			return fmt.Errorf("plugin: decode message: %w", errUnmarshal)
+		}
+		if msg.ID == nil {
+			if msg.Method != "" && onNotify != nil {
+				if errNotify := onNotify(msg.Method, msg.Params); errNotify != nil {
+					return errNotify
+				}
+			}
+			continue
+		}
+		if *msg.ID != id {
+			continue
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil && len(msg.Result) > 0 {
+			if errUnmarshal := json.Unmarshal(msg.Result, result); errUnmarshal != nil {
+				return fmt.Errorf("plugin: decode result: %w", errUnmarshal)
+			}
+		}
+		return nil
+	}
+}
+
+func (c *Client) wrapIOError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return fmt.Errorf("plugin: %s: %w", c.socket, err)
+}
+
+// readLine reads a newline-terminated message, enforcing maxMessageSize.
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxMessageSize {
+			return nil, fmt.Errorf("message exceeds %d bytes", maxMessageSize)
+		}
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// Executor forwards provider execution to a plugin. It implements coreauth.ProviderExecutor.
+type Executor struct {
+	provider string
+	client   *Client
+}
+
+// NewExecutor returns an executor serving provider through the plugin client.
+func NewExecutor(provider string, client *Client) *Executor {
+	return &Executor{provider: strings.ToLower(strings.TrimSpace(provider)), client: client}
+}
+
+// Identifier implements coreauth.ProviderExecutor.
+func (e *Executor) Identifier() string { return e.provider }
+
+// Execute implements coreauth.ProviderExecutor.
+func (e *Executor) Execute(ctx context.Context, auth *coreauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	started := time.Now()
+	var result ExecuteResult
+	err := e.client.Call(ctx, MethodExecute, e.params(auth, req, opts), &result)
+	e.publishUsage(ctx, auth, req.Model, started, result.Usage, err)
+	if err != nil {
+		return cliproxyexecutor.Response{}, err
+	}
+	return cliproxyexecutor.Response{Payload: []byte(result.Payload), Headers: http.Header(result.Headers)}, nil
+}
+
+// ExecuteStream implements coreauth.ProviderExecutor. The call stays open while
+// chunks are relayed; the first chunk (or failure) is awaited before returning so
+// bootstrap errors surface synchronously like other executors.
+func (e *Executor) ExecuteStream(ctx context.Context, auth *coreauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (*cliproxyexecutor.StreamResult, error) {
+	started := time.Now()
+	chunks := make(chan cliproxyexecutor.StreamChunk)
+	firstEvent := make(chan error, 1)
+	params := e.params(auth, req, opts)
+
+	go func() {
+		defer close(chunks)
+		first := true
+		var result ExecuteResult
+		err := e.client.CallStream(ctx, MethodExecuteStream, params, func(method string, raw json.RawMessage) error {
+			if method != NotificationStreamChunk {
+				return nil
+			}
+			var chunk StreamChunk
+			if errUnmarshal := json.Unmarshal(raw, &chunk); errUnmarshal != nil {
+				return fmt.Errorf("plugin: decode stream chunk: %w", errUnmarshal)
+			}
+			if first {
+				first = false
+				firstEvent <- nil
+			}
+			select {
+			case chunks <- cliproxyexecutor.StreamChunk{Payload: []byte(chunk.Payload)}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, &result)
+		e.publishUsage(ctx, auth, req.Model, started, result.Usage, err)
+		if first {
+			firstEvent <- err
+			return
+		}
+		if err != nil {
+			select {
+			case chunks <- cliproxyexecutor.StreamChunk{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	select {
+	case err := <-firstEvent:
+		if err != nil {
+			return nil, err
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &cliproxyexecutor.StreamResult{Chunks: chunks}, nil
+}
+
+// CountTokens implements coreauth.ProviderExecutor.
+func (e *Executor) CountTokens(ctx context.Context, auth *coreauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	var result ExecuteResult
+	if err := e.client.Call(ctx, MethodCountTokens, e.params(auth, req, opts), &result); err != nil {
+		return cliproxyexecutor.Response{}, err
+	}
+	return cliproxyexecutor.Response{Payload: []byte(result.Payload), Headers: http.Header(result.Headers)}, nil
+}
+
+// Refresh implements coreauth.ProviderExecutor. Plugins manage their own credentials.
+func (e *Executor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+// HttpRequest implements coreauth.ProviderExecutor. Plugins do not expose raw HTTP access.
+func (e *Executor) HttpRequest(_ context.Context, _ *coreauth.Auth, _ *http.Request) (*http.Response, error) {
+	return nil, errors.New("plugin: raw HTTP requests are not supported by plugin executors")
+}
+
+func (e *Executor) params(auth *coreauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) ExecuteParams {
+	params := ExecuteParams{
+		Provider:        e.provider,
+		Model:           req.Model,
+		Payload:         string(req.Payload),
+		SourceFormat:    opts.SourceFormat.String(),
+		Stream:          opts.Stream,
+		Alt:             opts.Alt,
+		Headers:         opts.Headers,
+		OriginalRequest: string(opts.OriginalRequest),
+	}
+	if auth != nil {
+		params.AuthID = auth.ID
+	}
+	if len(opts.Metadata) > 0 {
+		params.Metadata = make(map[string]string, len(opts.Metadata))
+		for key, value := range opts.Metadata {
+			if str, ok := value.(string); ok {
+				params.Metadata[key] = str
+			}
+		}
+	}
+	return params
+}
+
+func (e *Executor) publishUsage(ctx context.Context, auth *coreauth.Auth, model string, started time.Time, detail *Usage, err error) {
+	record := usage.Record{
+		Provider:    e.provider,
+		Model:       model,
+		APIKey:      util.APIKeyFromContext(ctx),
+		Source:      e.provider,
+		RequestedAt: started,
+		Latency:     time.Since(started),
+		Failed:      err != nil,
+	}
+	if auth != nil {
+		record.AuthID = auth.ID
+		record.AuthIndex = auth.Index
+	}
+	if detail != nil {
+		record.Detail = usage.Detail{
+			InputTokens:     detail.InputTokens,
+			OutputTokens:    detail.OutputTokens,
+			ReasoningTokens: detail.ReasoningTokens,
+			CachedTokens:    detail.CachedTokens,
+			TotalTokens:     detail.TotalTokens,
+		}
+		if record.Detail.TotalTokens == 0 {
+			record.Detail.TotalTokens = detail.InputTokens + detail.OutputTokens + detail.ReasoningTokens
+		}
+	}
+	usage.PublishRecord(ctx, record)
+}
@@ -0,0 +1,254 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+type testPlugin struct{}
+
+func (testPlugin) Describe(context.Context) (Description, error) {
+	return Description{
+		Name:       "test",
+		Version:    "1.0.0",
+		Access:     true,
+		Executor:   &ExecutorDescription{Provider: "Echo", Models: []*registry.ModelInfo{{ID: "echo-1"}}},
+		Transforms: &TransformsDescription{Request: true, Response: true},
+	}, nil
+}
+
+func (testPlugin) Authenticate(_ context.Context, params AuthenticateParams) (AuthenticateResult, error) {
+	values := http.Header(params.Headers).Values("X-Token")
+	switch {
+	case len(values) == 0:
+		return AuthenticateResult{Status: AuthStatusNoCredentials}, nil
+	case values[0] == "good":
+		return AuthenticateResult{Status: AuthStatusOK, Principal: "alice", Metadata: map[string]string{"path": params.Path}}, nil
+	default:
+		return AuthenticateResult{Status: AuthStatusInvalidCredential}, nil
+	}
+}
+
+func (testPlugin) Execute(_ context.Context, params ExecuteParams) (ExecuteResult, error) {
+	if params.Model == "fail" {
+		return ExecuteResult{}, NewError(http.StatusTooManyRequests, "slow down")
+	}
+	return ExecuteResult{Payload: "echo:" + params.Payload, Usage: &Usage{InputTokens: 1, OutputTokens: 2}}, nil
+}
+
+func (testPlugin) ExecuteStream(_ context.Context, params ExecuteParams, emit func(StreamChunk) error) (ExecuteResult, error) {
+	if params.Model == "fail" {
+		return ExecuteResult{}, NewError(http.StatusBadGateway, "upstream down")
+	}
+	for _, part := range strings.Split(params.Payload, " ") {
+		if err := emit(StreamChunk{Payload: part}); err != nil {
+			return ExecuteResult{}, err
+		}
+	}
+	return ExecuteResult{}, nil
+}
+
+func (testPlugin) CountTokens(_ context.Context, params ExecuteParams) (ExecuteResult, error) {
+	return ExecuteResult{Payload: `{"tokens":1}`}, nil
+}
+
+func (testPlugin) TransformRequest(_ context.Context, params TransformParams) (TransformResult, error) {
+	return TransformResult{Body: strings.ToUpper(params.Body)}, nil
+}
+
+func (testPlugin) TransformResponse(_ context.Context, params TransformParams) (TransformResult, error) {
+	if params.Stream {
+		out := make([]string, 0, len(params.Chunks))
+		for _, chunk := range params.Chunks {
+			out = append(out, "<"+chunk+">")
+		}
+		return TransformResult{Chunks: out}, nil
+	}
+	return TransformResult{Body: params.Body + "!"}, nil
+}
+
+// startTestPlugin serves testPlugin on a fresh Unix socket and returns its path.
+func startTestPlugin(t *testing.T) string {
+	t.Helper()
+	// Keep the path short: Unix socket paths are limited to ~100 bytes.
+	dir, err := os.MkdirTemp("", "plg")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	socket := filepath.Join(dir, "p.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = Serve(ctx, listener, testPlugin{})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		_ = os.RemoveAll(dir)
+	})
+	return socket
+}
+
+func TestClientDescribe(t *testing.T) {
+	client := NewClient(startTestPlugin(t), 5*time.Second)
+	var desc Description
+	if err := client.Call(context.Background(), MethodDescribe, nil, &desc); err != nil {
+		t.Fatalf("describe: %v", err)
+	}
+	if desc.Name != "test" || !desc.Access || desc.Executor == nil || desc.Executor.Provider != "Echo" {
+		t.Fatalf("unexpected description: %+v", desc)
+	}
+	if len(desc.Executor.Models) != 1 || desc.Executor.Models[0].ID != "echo-1" {
+		t.Fatalf("unexpected models: %+v", desc.Executor.Models)
+	}
+
+	err := client.Call(context.Background(), "unknown.method", nil, nil)
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeMethodNotFound {
+		t.Fatalf("expected method-not-found error, got %v", err)
+	}
+}
+
+func TestAccessProvider(t *testing.T) {
+	provider := NewAccessProvider("test", NewClient(startTestPlugin(t), 5*time.Second))
+	if got := provider.Identifier(); got != "plugin:test" {
+		t.Fatalf("Identifier() = %q", got)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	if _, authErr := provider.Authenticate(context.Background(), req); authErr == nil || authErr.Code != "no_credentials" {
+		t.Fatalf("expected no_credentials, got %+v", authErr)
+	}
+
+	req.Header.Set("X-Token", "bad")
+	if _, authErr := provider.Authenticate(context.Background(), req); authErr == nil || authErr.Code != "invalid_credential" {
+		t.Fatalf("expected invalid_credential, got %+v", authErr)
+	}
+
+	req.Header.Set("X-Token", "good")
+	result, authErr := provider.Authenticate(context.Background(), req)
+	if authErr != nil {
+		t.Fatalf("unexpected auth error: %+v", authErr)
+	}
+	if result.Principal != "alice" || result.Metadata["path"] != "/v1/chat/completions" || result.Provider != "plugin:test" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestExecutor(t *testing.T) {
+	exec := NewExecutor("Echo", NewClient(startTestPlugin(t), 5*time.Second))
+	if exec.Identifier() != "echo" {
+		t.Fatalf("Identifier() = %q", exec.Identifier())
+	}
+	auth := &coreauth.Auth{ID: "plugin:test", Provider: "echo"}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI}
+
+	resp, err := exec.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "echo-1", Payload: []byte("hi")}, opts)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if string(resp.Payload) != "echo:hi" {
+		t.Fatalf("Execute payload = %q", resp.Payload)
+	}
+
+	_, err = exec.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "fail"}, opts)
+	var status interface{ StatusCode() int }
+	if !errors.As(err, &status) || status.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 error, got %v", err)
+	}
+
+	opts.Stream = true
+	stream, err := exec.ExecuteStream(context.Background(), auth, cliproxyexecutor.Request{Model: "echo-1", Payload: []byte("a b c")}, opts)
+	if err != nil {
+		t.Fatalf("ExecuteStream: %v", err)
+	}
+	var parts []string
+	for chunk := range stream.Chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream chunk error: %v", chunk.Err)
+		}
+		parts = append(parts, string(chunk.Payload))
+	}
+	if strings.Join(parts, ",") != "a,b,c" {
+		t.Fatalf("stream chunks = %v", parts)
+	}
+
+	if _, err = exec.ExecuteStream(context.Background(), auth, cliproxyexecutor.Request{Model: "fail"}, opts); !errors.As(err, &status) || status.StatusCode() != http.StatusBadGateway {
+		t.Fatalf("expected bootstrap status 502 error, got %v", err)
+	}
+}
+
+func TestTransformMiddlewares(t *testing.T) {
+	client := NewClient(startTestPlugin(t), 5*time.Second)
+	ctx := context.Background()
+
+	req, err := RequestMiddleware(client)(ctx, sdktranslator.RequestEnvelope{Body: []byte("abc")}, func(_ context.Context, r sdktranslator.RequestEnvelope) (sdktranslator.RequestEnvelope, error) {
+		return r, nil
+	})
+	if err != nil || string(req.Body) != "ABC" {
+		t.Fatalf("request transform = %q, %v", req.Body, err)
+	}
+
+	identity := func(_ context.Context, r sdktranslator.ResponseEnvelope) (sdktranslator.ResponseEnvelope, error) {
+		return r, nil
+	}
+	resp, err := ResponseMiddleware(client)(ctx, sdktranslator.ResponseEnvelope{Body: []byte("done")}, identity)
+	if err != nil || string(resp.Body) != "done!" {
+		t.Fatalf("response transform = %q, %v", resp.Body, err)
+	}
+	resp, err = ResponseMiddleware(client)(ctx, sdktranslator.ResponseEnvelope{Stream: true, Chunks: [][]byte{[]byte("x"), []byte("y")}}, identity)
+	if err != nil || len(resp.Chunks) != 2 || string(resp.Chunks[1]) != "<y>" {
+		t.Fatalf("stream response transform = %q, %v", resp.Chunks, err)
+	}
+}
+
+func TestSupervisorExternalPlugin(t *testing.T) {
+	socket := startTestPlugin(t)
+	ready := make(chan Description, 1)
+	lost := make(chan string, 1)
+	sup := NewSupervisor(t.TempDir(), Hooks{
+		OnReady: func(_ string, _ *Client, desc Description) { ready <- desc },
+		OnLost:  func(name string, _ Description) { lost <- name },
+	})
+	sup.Apply([]config.PluginConfig{{Name: "ext", Socket: socket, StartupTimeout: 5, HealthCheckInterval: 1, RequestTimeout: 5}})
+
+	select {
+	case desc := <-ready:
+		if desc.Executor == nil || desc.Executor.Provider != "Echo" {
+			t.Fatalf("unexpected description: %+v", desc)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("plugin did not become ready")
+	}
+
+	// Removing the declaration must release the plugin's capabilities.
+	sup.Apply(nil)
+	select {
+	case name := <-lost:
+		if name != "ext" {
+			t.Fatalf("OnLost name = %q", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnLost not called after removal")
+	}
+	sup.Stop()
+}
@@ -0,0 +1,201 @@
+// Package plugin implements out-of-process plugins for CLIProxyAPI.
+//
+// A plugin is a separate process listening on a local Unix socket and speaking
+// JSON-RPC 2.0 with newline-delimited messages. On startup the service calls
+// plugin.describe to discover which capabilities the plugin provides:
+//
+//   - access: request authentication, exposed as an sdk/access Provider
+//   - executor: a provider executor (Execute, ExecuteStream, CountTokens)
+//     together with the models it serves
+//   - transforms: request/response payload transforms applied around execution
+//
+// Streaming executions deliver chunks as executor.stream_chunk notifications
+// on the same connection before the final response. Plugins written in Go can
+// use Serve to implement the protocol.
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+)
+
+// SocketEnv carries the socket path a supervised plugin must listen on.
+const SocketEnv = "CLIPROXY_PLUGIN_SOCKET"
+
+// AuthAttribute marks runtime auth entries owned by a plugin executor.
+const AuthAttribute = "plugin"
+
+// JSON-RPC methods understood by plugins.
+const (
+	MethodDescribe          = "plugin.describe"
+	MethodHealth            = "plugin.health"
+	MethodAuthenticate      = "access.authenticate"
+	MethodExecute           = "executor.execute"
+	MethodExecuteStream     = "executor.execute_stream"
+	MethodCountTokens       = "executor.count_tokens"
+	MethodTransformRequest  = "transform.request"
+	MethodTransformResponse = "transform.response"
+
+	// NotificationStreamChunk is sent by the plugin for every streamed chunk.
+	NotificationStreamChunk = "executor.stream_chunk"
+)
+
+// Authentication outcomes reported in AuthenticateResult.Status.
+const (
+	AuthStatusOK                = "ok"
+	AuthStatusNotHandled        = "not_handled"
+	AuthStatusNoCredentials     = "no_credentials"
+	AuthStatusInvalidCredential = "invalid_credential"
+)
+
+// Standard JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+const jsonRPCVersion = "2.0"
+
+// message is the JSON-RPC 2.0 envelope used for requests, responses and notifications.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object. Plugins may attach an HTTP status in Data
+// so executor failures drive the usual cooldown and retry handling.
+type Error struct {
+	Code    int        `json:"code"`
+	Message string     `json:"message"`
+	Data    *ErrorData `json:"data,omitempty"`
+}
+
+// ErrorData carries optional structured error details.
+type ErrorData struct {
+	Status int `json:"status,omitempty"`
+}
+
+// NewError builds an error reporting the given HTTP status to the proxy.
+func NewError(status int, message string) *Error {
+	return &Error{Code: CodeInternalError, Message: message, Data: &ErrorData{Status: status}}
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e == nil {
+		return ""
+	}
+	return fmt.Sprintf("plugin error %d: %s", e.Code, e.Message)
+}
+
+// StatusCode returns the HTTP status attached by the plugin, if any.
+func (e *Error) StatusCode() int {
+	if e == nil || e.Data == nil {
+		return 0
+	}
+	return e.Data.Status
+}
+
+// Description is returned by plugin.describe.
+type Description struct {
+	Name       string                 `json:"name"`
+	Version    string                 `json:"version,omitempty"`
+	Access     bool                   `json:"access,omitempty"`
+	Executor   *ExecutorDescription   `json:"executor,omitempty"`
+	Transforms *TransformsDescription `json:"transforms,omitempty"`
+}
+
+// ExecutorDescription declares the provider key and models served by a plugin executor.
+type ExecutorDescription struct {
+	Provider string                `json:"provider"`
+	Models   []*registry.ModelInfo `json:"models,omitempty"`
+}
+
+// TransformsDescription declares which payload transforms a plugin implements.
+type TransformsDescription struct {
+	Request  bool `json:"request,omitempty"`
+	Response bool `json:"response,omitempty"`
+}
+
+// HealthResult is returned by plugin.health.
+type HealthResult struct {
+	Status string `json:"status"`
+}
+
+// AuthenticateParams describes the inbound HTTP request being authenticated.
+type AuthenticateParams struct {
+	Method     string              `json:"method"`
+	Path       string              `json:"path"`
+	Query      string              `json:"query,omitempty"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	RemoteAddr string              `json:"remote_addr,omitempty"`
+}
+
+// AuthenticateResult reports the authentication outcome.
+type AuthenticateResult struct {
+	Status    string            `json:"status"`
+	Principal string            `json:"principal,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Message   string            `json:"message,omitempty"`
+}
+
+// ExecuteParams carries a single execution request. Payload is the inbound
+// client payload in SourceFormat; the plugin returns responses in the same format.
+type ExecuteParams struct {
+	AuthID          string              `json:"auth_id,omitempty"`
+	Provider        string              `json:"provider"`
+	Model           string              `json:"model"`
+	Payload         string              `json:"payload"`
+	SourceFormat    string              `json:"source_format,omitempty"`
+	Stream          bool                `json:"stream,omitempty"`
+	Alt             string              `json:"alt,omitempty"`
+	Headers         map[string][]string `json:"headers,omitempty"`
+	OriginalRequest string              `json:"original_request,omitempty"`
+	Metadata        map[string]string   `json:"metadata,omitempty"`
+}
+
+// ExecuteResult carries a non-streaming response or the trailer of a stream.
+type ExecuteResult struct {
+	Payload string              `json:"payload,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Usage   *Usage              `json:"usage,omitempty"`
+}
+
+// Usage reports token accounting for an execution so it appears in usage statistics.
+type Usage struct {
+	InputTokens     int64 `json:"input_tokens,omitempty"`
+	OutputTokens    int64 `json:"output_tokens,omitempty"`
+	ReasoningTokens int64 `json:"reasoning_tokens,omitempty"`
+	CachedTokens    int64 `json:"cached_tokens,omitempty"`
+	TotalTokens     int64 `json:"total_tokens,omitempty"`
+}
+
+// StreamChunk is the payload of an executor.stream_chunk notification.
+type StreamChunk struct {
+	Payload string `json:"payload"`
+}
+
+// TransformParams carries a payload through a plugin transform.
+type TransformParams struct {
+	Format string   `json:"format"`
+	Model  string   `json:"model"`
+	Stream bool     `json:"stream,omitempty"`
+	Body   string   `json:"body,omitempty"`
+	Chunks []string `json:"chunks,omitempty"`
+	APIKey string   `json:"api_key,omitempty"`
+}
+
+// TransformResult returns the transformed payload.
+type TransformResult struct {
+	Body   string   `json:"body,omitempty"`
+	Chunks []string `json:"chunks,omitempty"`
+}
@@ -0,0 +1,194 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+)
+
+// Handler is implemented by every plugin served with Serve.
+type Handler interface {
+	Describe(ctx context.Context) (Description, error)
+}
+
+// AccessHandler is implemented by plugins that authenticate inbound requests.
+type AccessHandler interface {
+	Authenticate(ctx context.Context, params AuthenticateParams) (AuthenticateResult, error)
+}
+
+// ExecutorHandler is implemented by plugins that execute model requests.
+type ExecutorHandler interface {
+	Execute(ctx context.Context, params ExecuteParams) (ExecuteResult, error)
+	ExecuteStream(ctx context.Context, params ExecuteParams, emit func(StreamChunk) error) (ExecuteResult, error)
+	CountTokens(ctx context.Context, params ExecuteParams) (ExecuteResult, error)
+}
+
+// TransformHandler is implemented by plugins that rewrite request or response payloads.
+type TransformHandler interface {
+	TransformRequest(ctx context.Context, params TransformParams) (TransformResult, error)
+	TransformResponse(ctx context.Context, params TransformParams) (TransformResult, error)
+}
+
+// Serve answers JSON-RPC requests on listener using handler until ctx is
+// cancelled or the listener fails. Optional capabilities are detected through
+// the AccessHandler, ExecutorHandler and TransformHandler interfaces.
+func Serve(ctx context.Context, listener net.Listener, handler Handler) error {
+	if listener == nil || handler == nil {
+		return errors.New("plugin: listener and handler are required")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	stop := context.AfterFunc(ctx, func() { _ = listener.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveConn(ctx, conn, handler)
+		}()
+	}
+}
+
+func serveConn(ctx context.Context, conn net.Conn, handler Handler) {
+	defer func() { _ = conn.Close() }()
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(connCtx, func() { _ = conn.Close() })
+	defer stop()
+
+	var writeMu sync.Mutex
+	write := func(msg message) error {
+		msg.JSONRPC = jsonRPCVersion
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_, err = conn.Write(append(data, '\n'))
+		return err
+	}
+
+	reader := bufio.NewReaderSize(conn, 64<<10)
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			return
+		}
+		if len(line) == 0 {
+			continue
+		}
+		var req message
+		if errUnmarshal := json.Unmarshal(line, &req); errUnmarshal != nil {
+			_ = write(message{Error: &Error{Code: CodeParseError, Message: errUnmarshal.Error()}})
+			return
+		}
+		if req.ID == nil {
+			continue
+		}
+		result, errCall := dispatch(connCtx, handler, req, func(chunk StreamChunk) error {
+			params, errMarshal := json.Marshal(chunk)
+			if errMarshal != nil {
+				return errMarshal
+			}
+			return write(message{Method: NotificationStreamChunk, Params: params})
+		})
+		resp := message{ID: req.ID}
+		if errCall != nil {
+			var rpcErr *Error
+			if !errors.As(errCall, &rpcErr) {
+				rpcErr = &Error{Code: CodeInternalError, Message: errCall.Error()}
+			}
+			resp.Error = rpcErr
+		} else {
+			raw, errMarshal := json.Marshal(result)
+			if errMarshal != nil {
+				resp.Error = &Error{Code: CodeInternalError, Message: errMarshal.Error()}
+			} else {
+				resp.Result = raw
+			}
+		}
+		if errWrite := write(resp); errWrite != nil {
+			return
+		}
+	}
+}
+
+func dispatch(ctx context.Context, handler Handler, req message, emit func(StreamChunk) error) (any, error) {
+	switch req.Method {
+	case MethodDescribe:
+		return handler.Describe(ctx)
+	case MethodHealth:
+		return HealthResult{Status: "ok"}, nil
+	case MethodAuthenticate:
+		access, ok := handler.(AccessHandler)
+		if !ok {
+			return nil, methodNotFound(req.Method)
+		}
+		var params AuthenticateParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return access.Authenticate(ctx, params)
+	case MethodExecute, MethodExecuteStream, MethodCountTokens:
+		exec, ok := handler.(ExecutorHandler)
+		if !ok {
+			return nil, methodNotFound(req.Method)
+		}
+		var params ExecuteParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		switch req.Method {
+		case MethodExecute:
+			return exec.Execute(ctx, params)
+		case MethodExecuteStream:
+			return exec.ExecuteStream(ctx, params, emit)
+		default:
+			return exec.CountTokens(ctx, params)
+		}
+	case MethodTransformRequest, MethodTransformResponse:
+		transform, ok := handler.(TransformHandler)
+		if !ok {
+			return nil, methodNotFound(req.Method)
+		}
+		var params TransformParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if req.Method == MethodTransformRequest {
+			return transform.TransformRequest(ctx, params)
+		}
+		return transform.TransformResponse(ctx, params)
+	default:
+		return nil, methodNotFound(req.Method)
+	}
+}
+
+func decodeParams(raw json.RawMessage, dst any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func methodNotFound(method string) *Error {
+	return &Error{Code: CodeMethodNotFound, Message: "method not found: " + method}
+}
@@ -0,0 +1,311 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	healthFailureThreshold = 3
+	restartBackoffInitial  = time.Second
+	restartBackoffMax      = time.Minute
+	stopTimeout            = 10 * time.Second
+)
+
+// Hooks receives plugin lifecycle notifications from a Supervisor.
+type Hooks struct {
+	// OnReady is called once a plugin answered plugin.describe; capabilities
+	// should be registered here.
+	OnReady func(name string, client *Client, desc Description)
+	// OnLost is called when a ready plugin stops, fails health checks, or is
+	// removed from configuration; capabilities should be unregistered here.
+	OnLost func(name string, desc Description)
+}
+
+// Supervisor starts configured plugins, probes their health and restarts
+// them with exponential backoff when they exit or stop answering.
+type Supervisor struct {
+	baseDir string
+	hooks   Hooks
+
+	mu        sync.Mutex
+	instances map[string]*instance
+}
+
+type instance struct {
+	cfg    config.PluginConfig
+	socket string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewSupervisor returns a supervisor placing default plugin sockets under baseDir.
+func NewSupervisor(baseDir string, hooks Hooks) *Supervisor {
+	return &Supervisor{baseDir: baseDir, hooks: hooks, instances: make(map[string]*instance)}
+}
+
+// Apply reconciles running plugins with the configured declarations: new
+// plugins are started, removed or disabled ones stopped, and changed ones restarted.
+func (s *Supervisor) Apply(plugins []config.PluginConfig) {
+	if s == nil {
+		return
+	}
+	desired := make(map[string]config.PluginConfig, len(plugins))
+	for _, p := range plugins {
+		if p.Disabled || strings.TrimSpace(p.Name) == "" {
+			continue
+		}
+		desired[p.Name] = p
+	}
+
+	s.mu.Lock()
+	var stopping []*instance
+	for name, inst := range s.instances {
+		next, keep := desired[name]
+		if keep && reflect.DeepEqual(next, inst.cfg) {
+			delete(desired, name)
+			continue
+		}
+		stopping = append(stopping, inst)
+		delete(s.instances, name)
+	}
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	s.mu.Unlock()
+
+	for _, inst := range stopping {
+		inst.stop()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range names {
+		cfg := desired[name]
+		ctx, cancel := context.WithCancel(context.Background())
+		inst := &instance{cfg: cfg, socket: s.socketPath(cfg), cancel: cancel, done: make(chan struct{})}
+		s.instances[name] = inst
+		go inst.run(ctx, s.hooks)
+	}
+}
+
+// Stop terminates all supervised plugins.
+func (s *Supervisor) Stop() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	instances := make([]*instance, 0, len(s.instances))
+	for name, inst := range s.instances {
+		instances = append(instances, inst)
+		delete(s.instances, name)
+	}
+	s.mu.Unlock()
+	for _, inst := range instances {
+		inst.stop()
+	}
+}
+
+func (s *Supervisor) socketPath(cfg config.PluginConfig) string {
+	if cfg.Socket != "" {
+		return cfg.Socket
+	}
+	return filepath.Join(s.baseDir, "plugins", cfg.Name+".sock")
+}
+
+func (i *instance) stop() {
+	i.cancel()
+	select {
+	case <-i.done:
+	case <-time.After(stopTimeout):
+		log.Warnf("plugin %s: did not stop within %s", i.cfg.Name, stopTimeout)
+	}
+}
+
+func (i *instance) run(ctx context.Context, hooks Hooks) {
+	defer close(i.done)
+	backoff := restartBackoffInitial
+	for {
+		started := time.Now()
+		err := i.runOnce(ctx, hooks)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > restartBackoffMax {
+			backoff = restartBackoffInitial
+		}
+		log.Warnf("plugin %s: %v; restarting in %s", i.cfg.Name, err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > restartBackoffMax {
+			backoff = restartBackoffMax
+		}
+	}
+}
+
+// runOnce starts the plugin process (when supervised), waits for it to become
+// ready, and then health-checks it until it fails or ctx is cancelled.
+func (i *instance) runOnce(ctx context.Context, hooks Hooks) error {
+	var exited chan error
+	if i.cfg.Command != "" {
+		procCtx, cancelProc := context.WithCancel(ctx)
+		var (
+			procDone chan struct{}
+			errStart error
+		)
+		exited, procDone, errStart = i.startProcess(procCtx)
+		if errStart != nil {
+			cancelProc()
+			return errStart
+		}
+		defer func() {
+			cancelProc()
+			<-procDone
+		}()
+	}
+
+	client := NewClient(i.socket, time.Duration(i.cfg.RequestTimeout)*time.Second)
+	desc, err := i.waitReady(ctx, client, exited)
+	if err != nil {
+		return err
+	}
+	if desc.Name == "" {
+		desc.Name = i.cfg.Name
+	}
+	log.Infof("plugin %s ready (version=%s access=%t executor=%t transforms=%t)", i.cfg.Name, desc.Version, desc.Access, desc.Executor != nil, desc.Transforms != nil)
+	if hooks.OnReady != nil {
+		hooks.OnReady(i.cfg.Name, client, desc)
+	}
+	if hooks.OnLost != nil {
+		defer hooks.OnLost(i.cfg.Name, desc)
+	}
+
+	interval := time.Duration(i.cfg.HealthCheckInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Duration(config.DefaultPluginHealthCheckInterval) * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case errExit := <-exited:
+			return fmt.Errorf("process exited: %v", errExit)
+		case <-ticker.C:
+			probeCtx, cancel := context.WithTimeout(ctx, minDuration(interval, 10*time.Second))
+			errHealth := client.Call(probeCtx, MethodHealth, nil, nil)
+			cancel()
+			if errHealth == nil {
+				failures = 0
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			failures++
+			log.Warnf("plugin %s: health check failed (%d/%d): %v", i.cfg.Name, failures, healthFailureThreshold, errHealth)
+			if failures >= healthFailureThreshold {
+				return errors.New("health checks failed")
+			}
+		}
+	}
+}
+
+// startProcess launches the plugin command. The process is killed when ctx is
+// cancelled; exited yields its exit status and done is closed once it has been reaped.
+func (i *instance) startProcess(ctx context.Context) (exited chan error, done chan struct{}, err error) {
+	if err = os.MkdirAll(filepath.Dir(i.socket), 0o700); err != nil {
+		return nil, nil, fmt.Errorf("create socket directory: %w", err)
+	}
+	_ = os.Remove(i.socket)
+
+	cmd := exec.CommandContext(ctx, i.cfg.Command, i.cfg.Args...)
+	cmd.Env = append(os.Environ(), SocketEnv+"="+i.socket)
+	envKeys := make([]string, 0, len(i.cfg.Env))
+	for key := range i.cfg.Env {
+		envKeys = append(envKeys, key)
+	}
+	sort.Strings(envKeys)
+	for _, key := range envKeys {
+		cmd.Env = append(cmd.Env, key+"="+i.cfg.Env[key])
+	}
+	logger := log.WithField("plugin", i.cfg.Name)
+	stdout := logger.WriterLevel(log.InfoLevel)
+	stderr := logger.WriterLevel(log.WarnLevel)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = 5 * time.Second
+	if err = cmd.Start(); err != nil {
+		_ = stdout.Close()
+		_ = stderr.Close()
+		return nil, nil, fmt.Errorf("start %s: %w", i.cfg.Command, err)
+	}
+
+	exited = make(chan error, 1)
+	done = make(chan struct{})
+	go func() {
+		errWait := cmd.Wait()
+		_ = stdout.Close()
+		_ = stderr.Close()
+		_ = os.Remove(i.socket)
+		exited <- errWait
+		close(done)
+	}()
+	return exited, done, nil
+}
+
+// waitReady polls plugin.describe until it answers, the process exits, or the startup timeout elapses.
+func (i *instance) waitReady(ctx context.Context, client *Client, exited chan error) (Description, error) {
+	timeout := time.Duration(i.cfg.StartupTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = time.Duration(config.DefaultPluginStartupTimeout) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for {
+		probeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		var desc Description
+		lastErr = client.Call(probeCtx, MethodDescribe, nil, &desc)
+		cancel()
+		if lastErr == nil {
+			return desc, nil
+		}
+		if time.Now().After(deadline) {
+			return Description{}, fmt.Errorf("not ready after %s: %w", timeout, lastErr)
+		}
+		select {
+		case <-ctx.Done():
+			return Description{}, ctx.Err()
+		case errExit := <-exited:
+			return Description{}, fmt.Errorf("process exited during startup: %v", errExit)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+// RequestMiddleware forwards inbound request payloads to the plugin's request transform.
+func RequestMiddleware(client *Client) sdktranslator.RequestMiddleware {
+	return func(ctx context.Context, req sdktranslator.RequestEnvelope, next sdktranslator.RequestHandler) (sdktranslator.RequestEnvelope, error) {
+		var result TransformResult
+		params := TransformParams{
+			Format: req.Format.String(),
+			Model:  req.Model,
+			Stream: req.Stream,
+			Body:   string(req.Body),
+			APIKey: util.APIKeyFromContext(ctx),
+		}
+		if err := client.Call(ctx, MethodTransformRequest, params, &result); err != nil {
+			return req, err
+		}
+		req.Body = []byte(result.Body)
+		return next(ctx, req)
+	}
+}
+
+// ResponseMiddleware forwards response payloads (or stream chunks) to the plugin's response transform.
+func ResponseMiddleware(client *Client) sdktranslator.ResponseMiddleware {
+	return func(ctx context.Context, resp sdktranslator.ResponseEnvelope, next sdktranslator.ResponseHandler) (sdktranslator.ResponseEnvelope, error) {
+		out, err := next(ctx, resp)
+		if err != nil {
+			return out, err
+		}
+		params := TransformParams{
+			Format: out.Format.String(),
+			Model:  out.Model,
+			Stream: out.Stream,
+			APIKey: util.APIKeyFromContext(ctx),
+		}
+		if out.Stream {
+			params.Chunks = make([]string, len(out.Chunks))
+			for i := range out.Chunks {
+				params.Chunks[i] = string(out.Chunks[i])
+			}
+		} else {
+			params.Body = string(out.Body)
+		}
+		var result TransformResult
+		if err = client.Call(ctx, MethodTransformResponse, params, &result); err != nil {
+			return out, err
+		}
+		if out.Stream {
+			out.Chunks = make([][]byte, 0, len(result.Chunks))
+			for _, chunk := range result.Chunks {
+				out.Chunks = append(out.Chunks, []byte(chunk))
+			}
+		} else {
+			out.Body = []byte(result.Body)
+		}
+		return out, nil
+	}
+}
@@ -0,0 +1,130 @@
+package translator
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Process-wide middleware registered here wraps every request handled by the
+// API handlers. Unlike Pipeline middleware, no format translation happens in
+// between: request middleware sees the inbound client payload before execution
+// and response middleware sees the client-facing payload (or stream chunk)
+// before it is written out.
+var (
+	hooksMu           sync.RWMutex
+	requestHooks      = make(map[string]RequestMiddleware)
+	requestHookOrder  []string
+	responseHooks     = make(map[string]ResponseMiddleware)
+	responseHookOrder []string
+)
+
+// RegisterRequestMiddleware registers a named process-wide request middleware.
+// Registering an existing name replaces it in place.
+func RegisterRequestMiddleware(name string, mw RequestMiddleware) {
+	name = strings.TrimSpace(name)
+	if name == "" || mw == nil {
+		return
+	}
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	if _, exists := requestHooks[name]; !exists {
+		requestHookOrder = append(requestHookOrder, name)
+	}
+	requestHooks[name] = mw
+}
+
+// UnregisterRequestMiddleware removes a named process-wide request middleware.
+func UnregisterRequestMiddleware(name string) {
+	name = strings.TrimSpace(name)
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	if _, exists := requestHooks[name]; !exists {
+		return
+	}
+	delete(requestHooks, name)
+	requestHookOrder = removeHookName(requestHookOrder, name)
+}
+
+// RegisterResponseMiddleware registers a named process-wide response middleware.
+// Registering an existing name replaces it in place.
+func RegisterResponseMiddleware(name string, mw ResponseMiddleware) {
+	name = strings.TrimSpace(name)
+	if name == "" || mw == nil {
+		return
+	}
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	if _, exists := responseHooks[name]; !exists {
+		responseHookOrder = append(responseHookOrder, name)
+	}
+	responseHooks[name] = mw
+}
+
+// UnregisterResponseMiddleware removes a named process-wide response middleware.
+func UnregisterResponseMiddleware(name string) {
+	name = strings.TrimSpace(name)
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	if _, exists := responseHooks[name]; !exists {
+		return
+	}
+	delete(responseHooks, name)
+	responseHookOrder = removeHookName(responseHookOrder, name)
+}
+
+// HasResponseMiddleware reports whether any process-wide response middleware is registered.
+func HasResponseMiddleware() bool {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	return len(responseHookOrder) > 0
+}
+
+// ApplyRequestMiddleware runs the registered request middleware in registration order.
+func ApplyRequestMiddleware(ctx context.Context, req RequestEnvelope) (RequestEnvelope, error) {
+	hooksMu.RLock()
+	chain := make([]RequestMiddleware, 0, len(requestHookOrder))
+	for _, name := range requestHookOrder {
+		chain = append(chain, requestHooks[name])
+	}
+	hooksMu.RUnlock()
+
+	handler := RequestHandler(func(_ context.Context, r RequestEnvelope) (RequestEnvelope, error) { return r, nil })
+	for i := len(chain) - 1; i >= 0; i-- {
+		mw := chain[i]
+		next := handler
+		handler = func(ctx context.Context, r RequestEnvelope) (RequestEnvelope, error) {
+			return mw(ctx, r, next)
+		}
+	}
+	return handler(ctx, req)
+}
+
+// ApplyResponseMiddleware runs the registered response middleware in registration order.
+func ApplyResponseMiddleware(ctx context.Context, resp ResponseEnvelope) (ResponseEnvelope, error) {
+	hooksMu.RLock()
+	chain := make([]ResponseMiddleware, 0, len(responseHookOrder))
+	for _, name := range responseHookOrder {
+		chain = append(chain, responseHooks[name])
+	}
+	hooksMu.RUnlock()
+
+	handler := ResponseHandler(func(_ context.Context, r ResponseEnvelope) (ResponseEnvelope, error) { return r, nil })
+	for i := len(chain) - 1; i >= 0; i-- {
+		mw := chain[i]
+		next := handler
+		handler = func(ctx context.Context, r ResponseEnvelope) (ResponseEnvelope, error) {
+			return mw(ctx, r, next)
+		}
+	}
+	return handler(ctx, resp)
+}
+
+func removeHookName(order []string, name string) []string {
+	for i := range order {
+		if order[i] == name {
+			return append(order[:i], order[i+1:]...)
+		}
+	}
+	return order
+}